@@ -35,7 +35,11 @@ type containerCache struct {
 	ref         info.ContainerReference
 	recentStats *utils.TimedStore
 	maxAge      time.Duration
-	lock        sync.RWMutex
+	// Stats kept longer than the recent stats, one per resolution, nil if
+	// none.
+	history    *utils.TimedStore
+	resolution time.Duration
+	lock       sync.RWMutex
 }
 
 func (c *containerCache) AddStats(stats *info.ContainerStats) error {
@@ -44,6 +48,12 @@ func (c *containerCache) AddStats(stats *info.ContainerStats) error {
 
 	// Add the stat to storage.
 	c.recentStats.Add(stats.Timestamp, stats)
+	if c.history != nil {
+		size := c.history.Size()
+		if size == 0 || !stats.Timestamp.Before(c.history.Get(0).(*info.ContainerStats).Timestamp.Add(c.resolution)) {
+			c.history.Add(stats.Timestamp, stats)
+		}
+	}
 	return nil
 }
 
@@ -51,6 +61,22 @@ func (c *containerCache) RecentStats(start, end time.Time, maxStats int) ([]*inf
 	c.lock.RLock()
 	defer c.lock.RUnlock()
 	result := c.recentStats.InTimeRange(start, end, maxStats)
+	if c.history != nil && (maxStats == -1 || len(result) < maxStats) {
+		// The history completes the recent stats with the older ones.
+		if size := c.recentStats.Size(); size > 0 {
+			oldest := c.recentStats.Get(size - 1).(*info.ContainerStats).Timestamp
+			if end.IsZero() || !end.Before(oldest) {
+				end = oldest.Add(-time.Nanosecond)
+			}
+		}
+		if start.IsZero() || !start.After(end) {
+			historyMaxStats := -1
+			if maxStats != -1 {
+				historyMaxStats = maxStats - len(result)
+			}
+			result = append(c.history.InTimeRange(start, end, historyMaxStats), result...)
+		}
+	}
 	converted := make([]*info.ContainerStats, len(result))
 	for i, el := range result {
 		converted[i] = el.(*info.ContainerStats)
@@ -58,12 +84,24 @@ func (c *containerCache) RecentStats(start, end time.Time, maxStats int) ([]*inf
 	return converted, nil
 }
 
-func newContainerStore(ref info.ContainerReference, maxAge time.Duration) *containerCache {
-	return &containerCache{
+func newContainerStore(ref info.ContainerReference, maxAge time.Duration, history *historyPolicy) *containerCache {
+	c := &containerCache{
 		ref:         ref,
 		recentStats: utils.NewTimedStore(maxAge, -1),
 		maxAge:      maxAge,
 	}
+	if history != nil {
+		c.history = utils.NewTimedStore(history.duration, -1)
+		c.resolution = history.resolution
+	}
+	return c
+}
+
+// historyPolicy is how long and at which resolution the stats of a container
+// are kept beyond the recent stats.
+type historyPolicy struct {
+	duration   time.Duration
+	resolution time.Duration
 }
 
 type InMemoryCache struct {
@@ -71,6 +109,29 @@ type InMemoryCache struct {
 	containerCacheMap map[string]*containerCache
 	maxAge            time.Duration
 	backend           []storage.StorageDriver
+	// History policies by container name.
+	history map[string]*historyPolicy
+}
+
+// RetainHistory keeps the stats of the named container for duration, beyond
+// the recent stats, at the given resolution: a sample is kept only if it was
+// taken at least resolution after the previous sample kept. It applies to the
+// stats added after the call, including those of a container added later
+// under the same name.
+func (c *InMemoryCache) RetainHistory(containerName string, duration, resolution time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.history == nil {
+		c.history = make(map[string]*historyPolicy)
+	}
+	policy := &historyPolicy{duration: duration, resolution: resolution}
+	c.history[containerName] = policy
+	if cstore, ok := c.containerCacheMap[containerName]; ok {
+		cstore.lock.Lock()
+		cstore.history = utils.NewTimedStore(policy.duration, -1)
+		cstore.resolution = policy.resolution
+		cstore.lock.Unlock()
+	}
 }
 
 func (c *InMemoryCache) AddStats(cInfo *info.ContainerInfo, stats *info.ContainerStats) error {
@@ -81,7 +142,7 @@ func (c *InMemoryCache) AddStats(cInfo *info.ContainerInfo, stats *info.Containe
 		c.lock.Lock()
 		defer c.lock.Unlock()
 		if cstore, ok = c.containerCacheMap[cInfo.ContainerReference.Name]; !ok {
-			cstore = newContainerStore(cInfo.ContainerReference, c.maxAge)
+			cstore = newContainerStore(cInfo.ContainerReference, c.maxAge, c.history[cInfo.ContainerReference.Name])
 			c.containerCacheMap[cInfo.ContainerReference.Name] = cstore
 		}
 	}()
@@ -100,3 +100,40 @@ func TestRecentStatsGetAllStats(t *testing.T) {
 
 	assert.Len(t, getRecentStats(t, memoryCache, -1), 10)
 }
+
+func TestRetainHistory(t *testing.T) {
+	memoryCache := New(10*time.Second, nil)
+	memoryCache.RetainHistory(containerName, 100*time.Second, 5*time.Second)
+	for i := 0; i < 60; i++ {
+		require.NoError(t, memoryCache.AddStats(&cInfo, makeStat(i)))
+	}
+	loads := func(stats []*info.ContainerStats) []int32 {
+		var l []int32
+		for _, s := range stats {
+			l = append(l, s.Cpu.LoadAverage)
+		}
+		return l
+	}
+
+	// The recent stats of the last 10 seconds, completed by one sample every
+	// 5 seconds before.
+	assert.Equal(t, []int32{0, 5, 10, 15, 20, 25, 30, 35, 40, 45, 50, 51, 52, 53, 54, 55, 56, 57, 58, 59}, loads(getRecentStats(t, memoryCache, -1)))
+	assert.Equal(t, []int32{40, 45, 50, 51, 52, 53, 54, 55, 56, 57, 58, 59}, loads(getRecentStats(t, memoryCache, 12)))
+	assert.Equal(t, []int32{57, 58, 59}, loads(getRecentStats(t, memoryCache, 3)))
+
+	stats, err := memoryCache.RecentStats(containerName, zero.Add(20*time.Second), zero.Add(52*time.Second), -1)
+	require.NoError(t, err)
+	assert.Equal(t, []int32{20, 25, 30, 35, 40, 45, 50, 51, 52}, loads(stats))
+	stats, err = memoryCache.RecentStats(containerName, zero.Add(30*time.Second), zero.Add(20*time.Second), -1)
+	require.NoError(t, err)
+	assert.Empty(t, stats)
+
+	// Other containers only keep their recent stats.
+	other := info.ContainerInfo{ContainerReference: info.ContainerReference{Name: "/other"}}
+	for i := 0; i < 60; i++ {
+		require.NoError(t, memoryCache.AddStats(&other, makeStat(i)))
+	}
+	stats, err = memoryCache.RecentStats("/other", zero, zero, -1)
+	require.NoError(t, err)
+	assert.Len(t, stats, 10)
+}
@@ -22,6 +22,7 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -30,10 +31,22 @@ import (
 	"strings"
 
 	v1 "github.com/yidoyoon/cadvisor-lite/info/v1"
-
-	"k8s.io/klog/v2"
 )
 
+// Interface is implemented by Client, and by the fake client of the
+// client/fake package for use in tests.
+type Interface interface {
+	EventStaticInfo(name string) ([]*v1.Event, error)
+	EventStreamingInfo(name string, einfo chan *v1.Event) error
+	MachineInfo() (*v1.MachineInfo, error)
+	ContainerInfo(name string, query *v1.ContainerInfoRequest) (*v1.ContainerInfo, error)
+	SubcontainersInfo(name string, query *v1.ContainerInfoRequest) ([]v1.ContainerInfo, error)
+	DockerContainer(name string, query *v1.ContainerInfoRequest) (v1.ContainerInfo, error)
+	AllDockerContainers(query *v1.ContainerInfoRequest) ([]v1.ContainerInfo, error)
+}
+
+var _ Interface = &Client{}
+
 // Client represents the base URL for a cAdvisor client.
 type Client struct {
 	baseURL    string
@@ -70,11 +83,14 @@ func (c *Client) EventStaticInfo(name string) (einfo []*v1.Event, err error) {
 // Streams all events that occur that satisfy the request into the channel
 // that is passed
 func (c *Client) EventStreamingInfo(name string, einfo chan *v1.Event) (err error) {
+	return c.EventStreamingInfoContext(context.Background(), name, einfo)
+}
+
+// EventStreamingInfoContext is EventStreamingInfo until ctx is done, when it
+// closes the stream and returns the error of ctx.
+func (c *Client) EventStreamingInfoContext(ctx context.Context, name string, einfo chan *v1.Event) error {
 	u := c.eventsInfoURL(name)
-	if err = c.getEventStreamingData(u, einfo); err != nil {
-		return
-	}
-	return nil
+	return c.getEventStreamingData(ctx, u, einfo)
 }
 
 // MachineInfo returns the JSON machine information for this client.
@@ -201,8 +217,8 @@ func (c *Client) httpGetJSONData(data, postData interface{}, url, infoName strin
 	return nil
 }
 
-func (c *Client) getEventStreamingData(url string, einfo chan *v1.Event) error {
-	req, err := http.NewRequest("GET", url, nil)
+func (c *Client) getEventStreamingData(ctx context.Context, url string, einfo chan *v1.Event) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return err
 	}
@@ -210,22 +226,32 @@ func (c *Client) getEventStreamingData(url string, einfo chan *v1.Event) error {
 	if err != nil {
 		return err
 	}
+	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("Status code is not OK: %v (%s)", resp.StatusCode, resp.Status)
 	}
 
 	dec := json.NewDecoder(resp.Body)
-	var m *v1.Event = &v1.Event{}
 	for {
+		// Every event is sent, so decode each of them in a new value.
+		m := &v1.Event{}
 		err := dec.Decode(m)
 		if err != nil {
 			if err == io.EOF {
 				break
 			}
-			// if called without &stream=true will not be able to parse event and will trigger fatal
-			klog.Fatalf("Received error %v", err)
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			// E.g. if called without &stream=true, or if the stream
+			// was cut.
+			return fmt.Errorf("failed to decode event: %v", err)
+		}
+		select {
+		case einfo <- m:
+		case <-ctx.Done():
+			return ctx.Err()
 		}
-		einfo <- m
 	}
 	return nil
 }
@@ -174,3 +174,17 @@ func TestGetSubcontainersInfo(t *testing.T) {
 		t.Error("received unexpected ContainerInfo")
 	}
 }
+
+func TestEventStreamingInfoDecodeError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"container_name": "/a", "event_type": "oom"}`+"\n{invalid")
+	}))
+	defer ts.Close()
+	client, err := NewClient(ts.URL)
+	assert.NoError(t, err)
+
+	events := make(chan *info.Event, 1)
+	err = client.EventStreamingInfo("?stream=true", events)
+	assert.Error(t, err)
+	assert.Equal(t, "/a", (<-events).ContainerName)
+}
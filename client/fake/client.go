@@ -0,0 +1,166 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fake provides a client.Interface served by a fake manager, for use
+// in tests of cAdvisor API consumers without an HTTP server.
+package fake
+
+import (
+	"context"
+	"net/url"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/yidoyoon/cadvisor-lite/client"
+	"github.com/yidoyoon/cadvisor-lite/events"
+	v1 "github.com/yidoyoon/cadvisor-lite/info/v1"
+	"github.com/yidoyoon/cadvisor-lite/manager/fake"
+)
+
+var _ client.Interface = &Client{}
+
+// Client is a fake client.Interface returning the containers, stats and
+// events programmed in its Manager.
+type Client struct {
+	Manager *fake.Manager
+}
+
+// NewClient returns a client of a new fake manager.
+func NewClient() *Client {
+	return &Client{Manager: fake.NewManager()}
+}
+
+// Parses the name of an events request, a container path optionally followed
+// by the query parameters of the events API, e.g. "docker?oom_events=true".
+func eventRequest(name string) (*events.Request, bool, error) {
+	u, err := url.Parse(name)
+	if err != nil {
+		return nil, false, err
+	}
+	request := events.NewRequest()
+	request.ContainerName = path.Join("/", u.Path)
+	query := u.Query()
+	parseBool := func(key string) bool {
+		b, _ := strconv.ParseBool(query.Get(key))
+		return b
+	}
+
+	request.IncludeSubcontainers = parseBool("subcontainers")
+	allEventTypes := parseBool("all_events")
+	for opt, eventType := range events.TypeOptions {
+		if allEventTypes || parseBool(opt) {
+			request.EventType[eventType] = true
+		}
+	}
+	if n, err := strconv.Atoi(query.Get("max_events")); err == nil {
+		request.MaxEventsReturned = n
+	}
+	if t, err := time.Parse(time.RFC3339, query.Get("start_time")); err == nil {
+		request.StartTime = t
+	}
+	if t, err := time.Parse(time.RFC3339, query.Get("end_time")); err == nil {
+		request.EndTime = t
+	}
+	return request, parseBool("stream"), nil
+}
+
+func (c *Client) EventStaticInfo(name string) ([]*v1.Event, error) {
+	request, _, err := eventRequest(name)
+	if err != nil {
+		return nil, err
+	}
+	return c.Manager.GetPastEvents(request)
+}
+
+// EventStreamingInfo sends the past events to einfo, or with "stream=true"
+// the new events until the fake manager is stopped.
+func (c *Client) EventStreamingInfo(name string, einfo chan *v1.Event) error {
+	return c.EventStreamingInfoContext(context.Background(), name, einfo)
+}
+
+// EventStreamingInfoContext is EventStreamingInfo until ctx is done, when it
+// closes the watch and returns the error of ctx.
+func (c *Client) EventStreamingInfoContext(ctx context.Context, name string, einfo chan *v1.Event) error {
+	request, stream, err := eventRequest(name)
+	if err != nil {
+		return err
+	}
+	if !stream {
+		past, err := c.Manager.GetPastEvents(request)
+		if err != nil {
+			return err
+		}
+		for _, event := range past {
+			einfo <- event
+		}
+		return nil
+	}
+	eventChannel, err := c.Manager.WatchForEvents(request)
+	if err != nil {
+		return err
+	}
+	defer c.Manager.CloseEventChannel(eventChannel.GetWatchId())
+	for {
+		select {
+		case event, ok := <-eventChannel.GetChannel():
+			if !ok {
+				return nil
+			}
+			select {
+			case einfo <- event:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (c *Client) MachineInfo() (*v1.MachineInfo, error) {
+	return c.Manager.GetMachineInfo()
+}
+
+func (c *Client) ContainerInfo(name string, query *v1.ContainerInfoRequest) (*v1.ContainerInfo, error) {
+	return c.Manager.GetContainerInfo(path.Join("/", name), query)
+}
+
+func (c *Client) SubcontainersInfo(name string, query *v1.ContainerInfoRequest) ([]v1.ContainerInfo, error) {
+	containers, err := c.Manager.SubcontainersInfo(path.Join("/", name), query)
+	if err != nil {
+		return []v1.ContainerInfo{}, err
+	}
+	result := make([]v1.ContainerInfo, 0, len(containers))
+	for _, cont := range containers {
+		result = append(result, *cont)
+	}
+	return result, nil
+}
+
+func (c *Client) DockerContainer(name string, query *v1.ContainerInfoRequest) (v1.ContainerInfo, error) {
+	return c.Manager.DockerContainer(name, query)
+}
+
+func (c *Client) AllDockerContainers(query *v1.ContainerInfoRequest) ([]v1.ContainerInfo, error) {
+	containers, err := c.Manager.AllDockerContainers(query)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]v1.ContainerInfo, 0, len(containers))
+	for _, cont := range containers {
+		result = append(result, cont)
+	}
+	return result, nil
+}
@@ -0,0 +1,73 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fake
+
+import (
+	"testing"
+	"time"
+
+	v1 "github.com/yidoyoon/cadvisor-lite/info/v1"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient(t *testing.T) {
+	c := NewClient()
+	now := time.Now()
+	c.Manager.AddContainer(v1.ContainerReference{Name: "/docker/abcdef", Aliases: []string{"web"}, Namespace: "docker"}, v1.ContainerSpec{HasMemory: true})
+	for i := 0; i < 3; i++ {
+		require.NoError(t, c.Manager.AddStats("/docker/abcdef", &v1.ContainerStats{
+			Timestamp: now.Add(time.Duration(i) * time.Second),
+			Memory:    v1.MemoryStats{Usage: uint64(i)},
+		}))
+	}
+	assert.Error(t, c.Manager.AddStats("/unknown", &v1.ContainerStats{}))
+
+	cont, err := c.ContainerInfo("docker/abcdef", &v1.ContainerInfoRequest{NumStats: 2})
+	require.NoError(t, err)
+	require.Len(t, cont.Stats, 2)
+	assert.Equal(t, uint64(2), cont.Stats[1].Memory.Usage)
+
+	root, err := c.ContainerInfo("/", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []v1.ContainerReference{{Name: "/docker"}}, root.Subcontainers)
+
+	subcontainers, err := c.SubcontainersInfo("/docker", nil)
+	require.NoError(t, err)
+	assert.Len(t, subcontainers, 2)
+
+	docker, err := c.DockerContainer("web", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "/docker/abcdef", docker.Name)
+	docker, err = c.DockerContainer("abc", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "/docker/abcdef", docker.Name)
+	all, err := c.AllDockerContainers(nil)
+	require.NoError(t, err)
+	assert.Len(t, all, 1)
+
+	events, err := c.EventStaticInfo("?creation_events=true&subcontainers=true")
+	require.NoError(t, err)
+	assert.Len(t, events, 3)
+
+	streamed := make(chan *v1.Event, 10)
+	require.NoError(t, c.EventStreamingInfo("docker?all_events=true", streamed))
+	event := <-streamed
+	assert.Equal(t, "/docker", event.ContainerName)
+
+	c.Manager.RemoveContainer("/docker")
+	assert.False(t, c.Manager.Exists("/docker/abcdef"))
+}
@@ -0,0 +1,293 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package informer maintains a cache of the containers of a cAdvisor instance
+// and their latest stats, combining the list API with the event stream, and
+// notifies handlers of the changes.
+package informer
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/yidoyoon/cadvisor-lite/client"
+	v1 "github.com/yidoyoon/cadvisor-lite/info/v1"
+
+	"k8s.io/klog/v2"
+)
+
+// Events request streaming the creation and deletion of all containers.
+const watchRequest = "?creation_events=true&deletion_events=true&subcontainers=true&stream=true"
+
+// contextEventStreamer is implemented by the clients whose event stream can
+// be closed, such as client.Client. The streams of other clients can only be
+// left behind.
+type contextEventStreamer interface {
+	EventStreamingInfoContext(ctx context.Context, name string, einfo chan *v1.Event) error
+}
+
+// Only the latest stats of the containers are cached.
+var latestStats = &v1.ContainerInfoRequest{NumStats: 1}
+
+// Handler is notified of the changes of the cached containers. Any of the
+// functions may be nil. They are called sequentially, from the goroutine
+// running the informer.
+type Handler struct {
+	// Called when a container is added to the cache.
+	OnAdd func(cont *v1.ContainerInfo)
+	// Called when the spec or the latest stats of a cached container change.
+	OnUpdate func(oldCont, newCont *v1.ContainerInfo)
+	// Called with the last known state of a container removed from the cache.
+	OnDelete func(cont *v1.ContainerInfo)
+}
+
+// Informer caches the containers of a cAdvisor instance. Containers are
+// added and deleted as soon as their creation and deletion events are
+// received, and every resync period all containers are listed to refresh
+// their stats and catch up on missed events.
+type Informer struct {
+	client       client.Interface
+	resyncPeriod time.Duration
+
+	lock       sync.RWMutex
+	handlers   []Handler
+	containers map[string]*v1.ContainerInfo
+	synced     bool
+}
+
+// New returns an informer of the cAdvisor instance of c, listing all its
+// containers every resyncPeriod.
+func New(c client.Interface, resyncPeriod time.Duration) *Informer {
+	return &Informer{
+		client:       c,
+		resyncPeriod: resyncPeriod,
+		containers:   make(map[string]*v1.ContainerInfo),
+	}
+}
+
+// AddHandler adds a handler notified of the changes from now on.
+func (i *Informer) AddHandler(handler Handler) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	i.handlers = append(i.handlers, handler)
+}
+
+// HasSynced returns whether the containers have been listed at least once.
+func (i *Informer) HasSynced() bool {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+	return i.synced
+}
+
+// Get returns the cached container with the given name.
+func (i *Informer) Get(name string) (*v1.ContainerInfo, bool) {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+	cont, ok := i.containers[name]
+	return cont, ok
+}
+
+// List returns the cached containers, sorted by name.
+func (i *Informer) List() []*v1.ContainerInfo {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+	result := make([]*v1.ContainerInfo, 0, len(i.containers))
+	for _, cont := range i.containers {
+		result = append(result, cont)
+	}
+	sort.Slice(result, func(a, b int) bool { return result[a].Name < result[b].Name })
+	return result
+}
+
+// Run keeps the cache up to date until stop is closed, and then returns
+// once the event stream is closed. The stream is opened again after the
+// resync period whenever it ends or fails, the cache being resynced in the
+// meantime.
+func (i *Informer) Run(stop <-chan struct{}) {
+	ctx, cancel := context.WithCancel(context.Background())
+	events := make(chan *v1.Event, 16)
+	watching := make(chan struct{})
+	go func() {
+		defer close(watching)
+		i.watch(ctx, events)
+	}()
+	defer func() {
+		cancel()
+		<-watching
+	}()
+
+	i.resync()
+	ticker := time.NewTicker(i.resyncPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case event := <-events:
+			i.handleEvent(event)
+		case <-ticker.C:
+			i.resync()
+		}
+	}
+}
+
+// Streams the container events to events until ctx is done, reconnecting
+// after every resync period if the stream ends.
+func (i *Informer) watch(ctx context.Context, events chan<- *v1.Event) {
+	for {
+		err := i.stream(ctx, events)
+		if err != nil && ctx.Err() == nil {
+			klog.Errorf("Failed to watch container events: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(i.resyncPeriod):
+		}
+	}
+}
+
+// Forwards the events of a stream to events until it ends or ctx is done,
+// and then closes it.
+func (i *Informer) stream(ctx context.Context, events chan<- *v1.Event) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream := make(chan *v1.Event, 16)
+	result := make(chan error, 1)
+	go func() {
+		if c, ok := i.client.(contextEventStreamer); ok {
+			result <- c.EventStreamingInfoContext(ctx, watchRequest, stream)
+		} else {
+			result <- i.client.EventStreamingInfo(watchRequest, stream)
+		}
+	}()
+
+	forward := func(event *v1.Event) bool {
+		select {
+		case events <- event:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+	for {
+		select {
+		case event := <-stream:
+			if !forward(event) {
+				return ctx.Err()
+			}
+		case err := <-result:
+			// Forward the events sent before the stream ended.
+			for {
+				select {
+				case event := <-stream:
+					if !forward(event) {
+						return ctx.Err()
+					}
+				default:
+					return err
+				}
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (i *Informer) handleEvent(event *v1.Event) {
+	switch event.EventType {
+	case v1.EventContainerCreation:
+		cont, err := i.client.ContainerInfo(event.ContainerName, latestStats)
+		if err != nil {
+			// The container may be gone already, the next resync catches up.
+			klog.V(4).Infof("Failed to get info of created container %q: %v", event.ContainerName, err)
+			return
+		}
+		i.upsert(cont)
+	case v1.EventContainerDeletion:
+		i.delete(event.ContainerName)
+	}
+}
+
+// Lists all containers and reconciles the cache with them.
+func (i *Informer) resync() {
+	containers, err := i.client.SubcontainersInfo("/", latestStats)
+	if err != nil {
+		klog.Errorf("Failed to list containers: %v", err)
+		return
+	}
+	listed := make(map[string]bool, len(containers))
+	for idx := range containers {
+		listed[containers[idx].Name] = true
+		i.upsert(&containers[idx])
+	}
+	for _, cont := range i.List() {
+		if !listed[cont.Name] {
+			i.delete(cont.Name)
+		}
+	}
+
+	i.lock.Lock()
+	i.synced = true
+	i.lock.Unlock()
+}
+
+// Returns the timestamp of the latest stats of a container, if any.
+func latestTimestamp(cont *v1.ContainerInfo) time.Time {
+	if len(cont.Stats) == 0 {
+		return time.Time{}
+	}
+	return cont.Stats[len(cont.Stats)-1].Timestamp
+}
+
+func (i *Informer) upsert(cont *v1.ContainerInfo) {
+	i.lock.Lock()
+	old, ok := i.containers[cont.Name]
+	if ok && latestTimestamp(old).Equal(latestTimestamp(cont)) && reflect.DeepEqual(old.Spec, cont.Spec) {
+		i.lock.Unlock()
+		return
+	}
+	i.containers[cont.Name] = cont
+	handlers := i.handlers
+	i.lock.Unlock()
+
+	for _, h := range handlers {
+		if !ok && h.OnAdd != nil {
+			h.OnAdd(cont)
+		} else if ok && h.OnUpdate != nil {
+			h.OnUpdate(old, cont)
+		}
+	}
+}
+
+func (i *Informer) delete(name string) {
+	i.lock.Lock()
+	old, ok := i.containers[name]
+	if !ok {
+		i.lock.Unlock()
+		return
+	}
+	delete(i.containers, name)
+	handlers := i.handlers
+	i.lock.Unlock()
+
+	for _, h := range handlers {
+		if h.OnDelete != nil {
+			h.OnDelete(old)
+		}
+	}
+}
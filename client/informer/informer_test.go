@@ -0,0 +1,143 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package informer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/yidoyoon/cadvisor-lite/client/fake"
+	v1 "github.com/yidoyoon/cadvisor-lite/info/v1"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type change struct {
+	kind string
+	name string
+}
+
+func receive(t *testing.T, changes <-chan change) change {
+	select {
+	case c := <-changes:
+		return c
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a change")
+		return change{}
+	}
+}
+
+func TestInformer(t *testing.T) {
+	c := fake.NewClient()
+	c.Manager.AddContainer(v1.ContainerReference{Name: "/a"}, v1.ContainerSpec{})
+
+	changes := make(chan change, 16)
+	informer := New(c, 50*time.Millisecond)
+	informer.AddHandler(Handler{
+		OnAdd: func(cont *v1.ContainerInfo) {
+			changes <- change{"add", cont.Name}
+		},
+		OnUpdate: func(oldCont, newCont *v1.ContainerInfo) {
+			changes <- change{"update", newCont.Name}
+		},
+		OnDelete: func(cont *v1.ContainerInfo) {
+			changes <- change{"delete", cont.Name}
+		},
+	})
+	stop := make(chan struct{})
+	defer close(stop)
+	go informer.Run(stop)
+
+	assert.Equal(t, change{"add", "/"}, receive(t, changes))
+	assert.Equal(t, change{"add", "/a"}, receive(t, changes))
+	require.Eventually(t, informer.HasSynced, 5*time.Second, time.Millisecond)
+	assert.Len(t, informer.List(), 2)
+
+	c.Manager.AddContainer(v1.ContainerReference{Name: "/b"}, v1.ContainerSpec{})
+	assert.Equal(t, change{"add", "/b"}, receive(t, changes))
+
+	require.NoError(t, c.Manager.AddStats("/b", &v1.ContainerStats{Timestamp: time.Now()}))
+	assert.Equal(t, change{"update", "/b"}, receive(t, changes))
+	cont, ok := informer.Get("/b")
+	require.True(t, ok)
+	assert.Len(t, cont.Stats, 1)
+
+	c.Manager.RemoveContainer("/a")
+	assert.Equal(t, change{"delete", "/a"}, receive(t, changes))
+	_, ok = informer.Get("/a")
+	assert.False(t, ok)
+}
+
+// streamingClient records the streams of events of a fake client.
+type streamingClient struct {
+	*fake.Client
+	streams chan context.Context
+	err     error
+}
+
+func (c *streamingClient) EventStreamingInfoContext(ctx context.Context, name string, einfo chan *v1.Event) error {
+	c.streams <- ctx
+	if c.err != nil {
+		return c.err
+	}
+	return c.Client.EventStreamingInfoContext(ctx, name, einfo)
+}
+
+func receiveStream(t *testing.T, streams <-chan context.Context) context.Context {
+	select {
+	case ctx := <-streams:
+		return ctx
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a stream")
+		return nil
+	}
+}
+
+func TestInformerStopClosesStream(t *testing.T) {
+	c := &streamingClient{Client: fake.NewClient(), streams: make(chan context.Context, 16)}
+	informer := New(c, time.Hour)
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		informer.Run(stop)
+		close(stopped)
+	}()
+
+	ctx := receiveStream(t, c.streams)
+	close(stop)
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the informer to stop")
+	}
+	// The watch goroutine has exited and closed the stream.
+	assert.Error(t, ctx.Err())
+}
+
+func TestInformerStreamError(t *testing.T) {
+	c := &streamingClient{Client: fake.NewClient(), streams: make(chan context.Context, 16), err: errors.New("stream failed")}
+	informer := New(c, 10*time.Millisecond)
+	stop := make(chan struct{})
+	defer close(stop)
+	go informer.Run(stop)
+
+	// The failed stream is closed, and a new one is opened.
+	ctx := receiveStream(t, c.streams)
+	receiveStream(t, c.streams)
+	assert.Error(t, ctx.Err())
+}
@@ -25,6 +25,7 @@ import (
 	"path"
 	"strconv"
 	"strings"
+	"time"
 
 	v1 "github.com/yidoyoon/cadvisor-lite/info/v1"
 	v2 "github.com/yidoyoon/cadvisor-lite/info/v2"
@@ -99,6 +100,24 @@ func (c *Client) Stats(name string, request *v2.RequestOptions) (map[string]v2.C
 	if request.MaxAge != nil {
 		data.Set("max_age", request.MaxAge.String())
 	}
+	if !request.Since.IsZero() {
+		data.Set("since", request.Since.Format(time.RFC3339Nano))
+	}
+	if !request.Start.IsZero() {
+		data.Set("start", request.Start.Format(time.RFC3339Nano))
+	}
+	if !request.End.IsZero() {
+		data.Set("end", request.End.Format(time.RFC3339Nano))
+	}
+	if request.Aligned {
+		data.Set("aligned", "true")
+	}
+	if request.Step > 0 {
+		data.Set("step", request.Step.String())
+		if request.Agg != "" {
+			data.Set("agg", request.Agg)
+		}
+	}
 
 	u = fmt.Sprintf("%s?%s", u, data.Encode())
 	if err := c.httpGetJSONData(&ret, nil, u, "stats"); err != nil {
@@ -152,7 +171,7 @@ func (c *Client) httpGetResponse(postData interface{}, urlPath, infoName string)
 		err = fmt.Errorf("unable to read all %q from %q: %v", infoName, urlPath, err)
 		return nil, err
 	}
-	if resp.StatusCode != 200 {
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
 		return nil, fmt.Errorf("request %q failed with error: %q", urlPath, strings.TrimSpace(string(body)))
 	}
 	return body, nil
@@ -171,6 +190,10 @@ func (c *Client) httpGetJSONData(data, postData interface{}, url, infoName strin
 	if err != nil {
 		return err
 	}
+	// Nothing is returned, e.g. no stats were sampled since the requested time.
+	if len(body) == 0 {
+		return nil
+	}
 	if err = json.Unmarshal(body, data); err != nil {
 		err = fmt.Errorf("unable to unmarshal %q (Body: %q) from %q with error: %v", infoName, string(body), url, err)
 		return err
@@ -202,3 +202,39 @@ func TestRequestFails(t *testing.T) {
 		t.Fatalf("Expected error %q but received %q", expectedError, err)
 	}
 }
+
+func TestStatsSinceNoContent(t *testing.T) {
+	since := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v2.1/stats/docker/a", r.URL.Path)
+		assert.Equal(t, since.Format(time.RFC3339Nano), r.URL.Query().Get("since"))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+	client, err := NewClient(ts.URL)
+	assert.NoError(t, err)
+
+	stats, err := client.Stats("docker/a", &v2.RequestOptions{IdType: v2.TypeName, Count: 10, Since: since})
+	assert.NoError(t, err)
+	assert.Empty(t, stats)
+}
+
+func TestStatsRange(t *testing.T) {
+	start := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	end := start.Add(time.Minute)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, start.Format(time.RFC3339Nano), r.URL.Query().Get("start"))
+		assert.Equal(t, end.Format(time.RFC3339Nano), r.URL.Query().Get("end"))
+		assert.Empty(t, r.URL.Query().Get("since"))
+		assert.Equal(t, "30s", r.URL.Query().Get("step"))
+		assert.Equal(t, v2.AggMax, r.URL.Query().Get("agg"))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+	client, err := NewClient(ts.URL)
+	assert.NoError(t, err)
+
+	stats, err := client.Stats("docker/a", &v2.RequestOptions{IdType: v2.TypeName, Count: -1, Start: start, End: end, Step: 30 * time.Second, Agg: v2.AggMax})
+	assert.NoError(t, err)
+	assert.Empty(t, stats)
+}
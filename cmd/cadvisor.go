@@ -18,6 +18,8 @@ import (
 	"crypto/tls"
 	"flag"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"net/http/pprof"
 	"os"
@@ -25,13 +27,27 @@ import (
 	"runtime"
 	"strings"
 	"syscall"
+	"time"
 
+	"github.com/yidoyoon/cadvisor-lite/cmd/internal/alerting"
+	"github.com/yidoyoon/cadvisor-lite/cmd/internal/anomaly"
+	"github.com/yidoyoon/cadvisor-lite/cmd/internal/api"
+	"github.com/yidoyoon/cadvisor-lite/cmd/internal/federation"
+	cadvisorgrpc "github.com/yidoyoon/cadvisor-lite/cmd/internal/grpc"
 	cadvisorhttp "github.com/yidoyoon/cadvisor-lite/cmd/internal/http"
+	"github.com/yidoyoon/cadvisor-lite/cmd/internal/imagepull"
+	"github.com/yidoyoon/cadvisor-lite/cmd/internal/standby"
+	"github.com/yidoyoon/cadvisor-lite/cmd/internal/wasm"
 	"github.com/yidoyoon/cadvisor-lite/container"
+	"github.com/yidoyoon/cadvisor-lite/container/docker"
+	"github.com/yidoyoon/cadvisor-lite/container/record"
 	"github.com/yidoyoon/cadvisor-lite/manager"
 	"github.com/yidoyoon/cadvisor-lite/metrics"
+	"github.com/yidoyoon/cadvisor-lite/stats"
+	"github.com/yidoyoon/cadvisor-lite/utils/relabel"
 	"github.com/yidoyoon/cadvisor-lite/utils/sysfs"
 	"github.com/yidoyoon/cadvisor-lite/version"
+	"github.com/yidoyoon/cadvisor-lite/watcher"
 
 	// Register container providers
 	_ "github.com/yidoyoon/cadvisor-lite/cmd/internal/container/install"
@@ -42,10 +58,14 @@ import (
 	_ "github.com/yidoyoon/cadvisor-lite/utils/cloudinfo/gce"
 
 	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
 )
 
 var argIP = flag.String("listen_ip", "", "IP to listen on, defaults to all IPs")
 var argPort = flag.Int("port", 8080, "port to listen")
+var grpcPort = flag.Int("grpc_port", 0, "port to serve the gRPC API on, on the IP of --grpc_listen_ip. Zero value disables the gRPC API.")
+var grpcListenIP = flag.String("grpc_listen_ip", "127.0.0.1", "IP to serve the gRPC API on, localhost by default. Empty value listens on all IPs. The API is secured by the same TLS, client certificate, bearer token, authorization, audit and rate limit flags as the HTTP API.")
+var criStatsSocket = flag.String("cri_stats_socket", "", "Path of a unix socket to serve the stats methods of the CRI runtime service on, for CRI clients such as the kubelet or crictl. Empty value disables the CRI stats service.")
 var maxProcs = flag.Int("max_procs", 0, "max number of CPUs that can be used simultaneously. Less than 1 for default (number of cores).")
 
 var versionFlag = flag.Bool("version", false, "print cAdvisor version and exit")
@@ -55,7 +75,36 @@ var httpAuthRealm = flag.String("http_auth_realm", "localhost", "HTTP auth realm
 var httpDigestFile = flag.String("http_digest_file", "", "HTTP digest file for the web UI")
 var httpDigestRealm = flag.String("http_digest_realm", "localhost", "HTTP digest file for the web UI")
 
+var httpTokenIssuer = flag.String("http_token_issuer", "", "Issuer of the OpenID Connect bearer tokens, e.g. https://accounts.example.com, which are then required by all the endpoints but the health checks /healthz, /livez and /readyz. The keys verifying the tokens are found through the discovery document of the issuer. Requires --http_token_audience. Empty value does not authenticate clients with tokens, unless --http_token_jwks_url is set.")
+var httpTokenAudience = flag.String("http_token_audience", "", "Audience the bearer tokens must be meant for, e.g. the client ID of cAdvisor at the issuer.")
+var httpTokenJWKSURL = flag.String("http_token_jwks_url", "", "URL of the JSON Web Key Set verifying the bearer tokens, instead of the one of the discovery document of --http_token_issuer. The issuer of the tokens is not checked if --http_token_issuer is empty.")
+
+var apiAuthorizationFile = flag.String("api_authorization_file", "", "Path to a JSON file of rules allowing the clients, by the identity of their certificate or bearer token, to access request types of the API. Empty value allows all clients to access all request types.")
+var apiAuditLog = flag.String("api_audit_log", "", "Path to a file the API requests are recorded in, as JSON lines, or \"syslog\" to send the records to the local syslog daemon. Empty value disables the audit log.")
+var apiAuditLogMaxSize = flag.Int64("api_audit_log_max_size", 100, "Size in megabytes beyond which the file of --api_audit_log is rotated. Zero value never rotates it.")
+var apiAuditLogMaxBackups = flag.Int("api_audit_log_max_backups", 5, "Number of rotated files of --api_audit_log kept.")
+var apiAuditLogSampleRate = flag.Float64("api_audit_log_sample_rate", 1, "Fraction, between 0 and 1, of the successful API requests recorded in --api_audit_log. The requests failing or denied are always recorded.")
+var apiTokenFile = flag.String("api_token_file", "", "Path to a file of static bearer tokens, one per line optionally followed by the name of its holder, which are then required by all the endpoints but the health checks /healthz, /livez and /readyz. The file is loaded again when it changes. Empty value does not authenticate clients with static tokens.")
+
+var httpClientRateLimit = flag.Float64("http_client_rate_limit", 0, "Requests per second allowed from a client IP, beyond which requests are rejected with 429 Too Many Requests. Zero value disables the limit.")
+var httpClientRateBurst = flag.Int("http_client_rate_burst", 10, "Number of requests a client IP may make at once, on top of --http_client_rate_limit.")
+var httpMaxInFlight = flag.Int("http_max_in_flight_requests", 0, "Maximum number of HTTP requests served at once, beyond which requests are rejected with 503 Service Unavailable. Streaming requests and health checks are not counted. Zero value disables the limit.")
+
+var tlsCertFile = flag.String("tls_cert_file", "", "Path to the certificate, with its intermediate certificates, served over HTTPS. The file is loaded again when it changes. Requires --tls_key_file. Empty value serves plain HTTP.")
+var tlsKeyFile = flag.String("tls_key_file", "", "Path to the key of --tls_cert_file.")
+var tlsClientCAFile = flag.String("tls_client_ca_file", "", "Path to the CA certificates verifying the client certificates, which are then required by all the endpoints but the health checks /healthz, /livez and /readyz. Requires --tls_cert_file or --tls_self_signed. Empty value does not authenticate clients.")
+var tlsSelfSigned = flag.Bool("tls_self_signed", false, "Serve HTTPS with a self-signed certificate generated at startup, for the host name, localhost and --listen_ip. Ignored if --tls_cert_file is set.")
+
+var corsAllowedOrigins = flag.String("cors_allowed_origins", "", "Comma-separated list of the origins, e.g. https://dashboard.example.com, whose scripts may call the JSON API, or * for any. WebSocket streams are only accepted from these origins and from the origin of cAdvisor. Empty value disables CORS.")
+var corsAllowedMethods = flag.String("cors_allowed_methods", "GET,POST", "Comma-separated list of the methods allowed in cross-origin requests to the JSON API.")
+var corsAllowedHeaders = flag.String("cors_allowed_headers", "Content-Type,If-None-Match,If-Modified-Since", "Comma-separated list of the headers allowed in cross-origin requests to the JSON API.")
+var corsMaxAge = flag.Duration("cors_max_age", 10*time.Minute, "Duration for which browsers may cache the result of a cross-origin preflight request.")
+
+var enableAdminAPI = flag.Bool("admin_api", false, "Enable the authenticated admin API under /admin/ for changing settings at runtime. Requires --http_auth_file or --http_digest_file.")
+
 var prometheusEndpoint = flag.String("prometheus_endpoint", "/metrics", "Endpoint to expose Prometheus metrics on")
+var prometheusOpenMetrics = flag.Bool("prometheus_openmetrics", false, "Serve the Prometheus metrics in the OpenMetrics format to the scrapers accepting it, with the _created series of the counters of the containers.")
+var prometheusExemplars = flag.Bool("prometheus_exemplars", false, "Add exemplars to the counters served in the OpenMetrics format, e.g. the id of the event of the latest OOM to container_oom_events_total. Requires --prometheus_openmetrics.")
 
 var enableProfiling = flag.Bool("profiling", false, "Enable profiling via web interface host:port/debug/pprof/")
 
@@ -66,6 +115,7 @@ var storeContainerLabels = flag.Bool("store_container_labels", true, "convert co
 var whitelistedContainerLabels = flag.String("whitelisted_container_labels", "", "comma separated list of container labels to be converted to labels on prometheus metrics for each container. store_container_labels must be set to false for this to take effect.")
 
 var envMetadataWhiteList = flag.String("env_metadata_whitelist", "", "a comma-separated list of environment variable keys matched with specified prefix that needs to be collected for containers, only support containerd and docker runtime for now.")
+var envMetadataWhiteListFile = flag.String("env_metadata_whitelist_file", "", "Path to a file listing environment variable key prefixes to collect for containers, one per line, in addition to --env_metadata_whitelist. The list can be changed at runtime with the admin API.")
 
 var urlBasePrefix = flag.String("url_base_prefix", "", "prefix path that will be prepended to all paths to support some reverse proxies")
 
@@ -73,6 +123,28 @@ var rawCgroupPrefixWhiteList = flag.String("raw_cgroup_prefix_whitelist", "", "A
 
 var perfEvents = flag.String("perf_events_config", "", "Path to a JSON file containing configuration of perf events to measure. Empty value disabled perf events measuring.")
 
+var standbyLockFile = flag.String("standby_lock_file", "", "Path to a lock file shared by cAdvisor instances on this host. Only the instance holding the lock exports and serves stats; the others collect them as hot standbys and take over when it exits. Empty value disables standby mode.")
+
+var federatedRemotes = flag.String("federate", "", "Comma-separated list of remote cAdvisor URLs, optionally as name=URL, whose containers are served under /federated/<name> alongside the local ones. Empty value disables federation.")
+var federationInterval = flag.Duration("federation_interval", 10*time.Second, "Interval between scrapes of the remote cAdvisor instances set by --federate.")
+
+var wasmTransformers = flag.String("wasm_transformers", "", "Comma-separated list of paths to WebAssembly modules transforming the stats of every container before they are stored and exported. Empty value disables WASM transformers.")
+var wasmTransformTimeout = flag.Duration("wasm_transform_timeout", 50*time.Millisecond, "Maximum duration of a single invocation of a WASM transformer.")
+
+var alertingRulesFile = flag.String("alerting_rules_file", "", "Path to a JSON file containing alerting rules and notification channels. Empty value disables alerting.")
+var alertingInterval = flag.Duration("alerting_interval", 15*time.Second, "Interval between evaluations of the alerting rules.")
+
+var anomalyThreshold = flag.Float64("anomaly_zscore_threshold", 0, "Number of standard deviations from its baseline beyond which a container metric is reported as an anomaly event. Zero value disables anomaly detection.")
+var anomalyAlpha = flag.Float64("anomaly_ewma_alpha", 0.1, "Weight of new samples in the exponentially weighted baselines used for anomaly detection, between 0 and 1.")
+var anomalyInterval = flag.Duration("anomaly_interval", 10*time.Second, "Interval between anomaly detection runs.")
+
+var imagePullEvents = flag.String("image_pull_events", "", "Comma-separated list of the container runtimes, among docker and containerd, whose image pulls are reported as image pull events. Empty value disables the events.")
+
+var recordFile = flag.String("record_file", "", "Path to a gzip-compressed archive to which the cgroup and procfs files read by the container handlers and the inspections of the docker containers are recorded, for later use with --replay_file. Empty value disables recording.")
+var replayFile = flag.String("replay_file", "", "Path to an archive created with --record_file whose containers are monitored instead of the ones of this host, their stats being read from the recorded files. Empty value disables replay.")
+
+var relabelConfigFile = flag.String("relabel_config", "", "Path to a JSON file containing the relabeling rules applied to the series of the Prometheus endpoint and to the labels of the containers written by the storage drivers. Empty value disables relabeling.")
+
 var resctrlInterval = flag.Duration("resctrl_interval", 0, "Resctrl mon groups updating interval. Zero value disables updating mon groups.")
 
 var (
@@ -83,6 +155,10 @@ var (
 		container.NetworkTcpUsageMetrics:         struct{}{},
 		container.NetworkUdpUsageMetrics:         struct{}{},
 		container.NetworkAdvancedTcpUsageMetrics: struct{}{},
+		container.NetworkConntrackMetrics:        struct{}{},
+		container.NetfilterMetrics:               struct{}{},
+		container.NetworkTcMetrics:               struct{}{},
+		container.NodeMetrics:                    struct{}{},
 		container.ProcessSchedulerMetrics:        struct{}{},
 		container.ProcessMetrics:                 struct{}{},
 		container.HugetlbUsageMetrics:            struct{}{},
@@ -124,20 +200,107 @@ func main() {
 	klog.V(1).Infof("enabled metrics: %s", includedMetrics.String())
 	setMaxProcs()
 
-	memoryStorage, err := NewMemoryStorage()
+	relabelConfig := &relabel.Config{}
+	if *relabelConfigFile != "" {
+		var err error
+		relabelConfig, err = relabel.LoadConfig(*relabelConfigFile)
+		if err != nil {
+			klog.Fatalf("Failed to load relabel config: %v", err)
+		}
+	}
+	metricsRelabeler, err := relabel.New(relabelConfig.Metrics)
+	if err != nil {
+		klog.Fatalf("Invalid metrics relabeling rules: %v", err)
+	}
+	storageRelabeler, err := relabel.New(relabelConfig.Storage)
+	if err != nil {
+		klog.Fatalf("Invalid storage relabeling rules: %v", err)
+	}
+
+	// In standby mode, the instance collects stats while another instance on
+	// this host holds the lock, but only exports and serves them once it
+	// holds the lock, until exit.
+	var active <-chan struct{}
+	standbyErrs := make(chan error, 1)
+	if *standbyLockFile != "" {
+		active = standby.AcquireInBackground(*standbyLockFile, standbyErrs)
+	}
+
+	memoryStorage, err := NewMemoryStorage(storageRelabeler, active)
 	if err != nil {
 		klog.Fatalf("Failed to initialize storage driver: %s", err)
 	}
 
+	for _, path := range strings.Split(*wasmTransformers, ",") {
+		if path == "" {
+			continue
+		}
+		transformer, err := wasm.NewTransformerFromFile(path, *wasmTransformTimeout)
+		if err != nil {
+			klog.Fatalf("Failed to load WASM transformer %q: %v", path, err)
+		}
+		stats.RegisterTransformer(path, transformer)
+	}
+
+	var closers []io.Closer
+	if *recordFile != "" && *replayFile != "" {
+		klog.Fatal("The record_file and replay_file flags are mutually exclusive.")
+	}
+	if *recordFile != "" {
+		writer, err := record.NewWriter(*recordFile)
+		if err != nil {
+			klog.Fatalf("Failed to create record file: %v", err)
+		}
+		closers = append(closers, writer)
+		// The root of the host is mounted at /rootfs when cAdvisor runs
+		// in a container, as assumed by the manager.
+		rootFs := "/"
+		if _, err := os.Stat("/rootfs/proc"); err == nil {
+			rootFs = "/rootfs"
+		}
+		recorder := record.NewRecorder(writer, rootFs)
+		container.SetHandlerWrapper(recorder.Wrap)
+		docker.SetTransportWrapper(recorder.WrapTransport)
+	}
+	if *replayFile != "" {
+		factory, err := record.NewReplayFactory(*replayFile)
+		if err != nil {
+			klog.Fatalf("Failed to load replay file: %v", err)
+		}
+		closers = append(closers, factory)
+		// Registered before the manager starts so that it takes precedence
+		// over the factories of the host.
+		container.RegisterContainerHandlerFactory(factory, []watcher.ContainerWatchSource{watcher.Raw})
+		if err := container.RegisterPlugin("replay", factory); err != nil {
+			klog.Fatalf("Failed to register the replay plugin: %v", err)
+		}
+	}
+
 	sysFs := sysfs.NewRealSysFs()
 
 	collectorHTTPClient := createCollectorHTTPClient(*collectorCert, *collectorKey)
 
-	resourceManager, err := manager.New(memoryStorage, sysFs, manager.HousekeepingConfigFlags, includedMetrics, &collectorHTTPClient, strings.Split(*rawCgroupPrefixWhiteList, ","), strings.Split(*envMetadataWhiteList, ","), *perfEvents, *resctrlInterval)
+	envMetadataAllowList, err := readEnvMetadataAllowList(*envMetadataWhiteList, *envMetadataWhiteListFile)
+	if err != nil {
+		klog.Fatalf("Failed to read the environment variable allow list: %v", err)
+	}
+
+	resourceManager, err := manager.New(memoryStorage, sysFs, manager.HousekeepingConfigFlags, includedMetrics, &collectorHTTPClient, strings.Split(*rawCgroupPrefixWhiteList, ","), envMetadataAllowList, *perfEvents, *resctrlInterval)
 	if err != nil {
 		klog.Fatalf("Failed to create a manager: %s", err)
 	}
 
+	if *federatedRemotes != "" {
+		remotes, err := federation.ParseRemotes(*federatedRemotes)
+		if err != nil {
+			klog.Fatalf("Failed to parse --federate: %v", err)
+		}
+		resourceManager, err = federation.New(resourceManager, remotes, *federationInterval)
+		if err != nil {
+			klog.Fatalf("Failed to create a federated manager: %v", err)
+		}
+	}
+
 	mux := http.NewServeMux()
 
 	if *enableProfiling {
@@ -147,8 +310,22 @@ func main() {
 		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
 	}
 
+	var auditLog *api.AuditLog
+	if *apiAuditLog != "" {
+		auditLog, err = api.NewAuditLog(api.AuditConfig{
+			Path:       *apiAuditLog,
+			MaxSize:    *apiAuditLogMaxSize << 20,
+			MaxBackups: *apiAuditLogMaxBackups,
+			SampleRate: *apiAuditLogSampleRate,
+		})
+		if err != nil {
+			klog.Fatalf("Failed to open the API audit log: %v", err)
+		}
+		closers = append(closers, auditLog)
+	}
+
 	// Register all HTTP handlers.
-	err = cadvisorhttp.RegisterHandlers(mux, resourceManager, *httpAuthFile, *httpAuthRealm, *httpDigestFile, *httpDigestRealm, *urlBasePrefix)
+	err = cadvisorhttp.RegisterHandlers(mux, resourceManager, *httpAuthFile, *httpAuthRealm, *httpDigestFile, *httpDigestRealm, *urlBasePrefix, *enableAdminAPI, corsConfig(), *apiAuthorizationFile, auditLog)
 	if err != nil {
 		klog.Fatalf("Failed to register HTTP handlers: %v", err)
 	}
@@ -164,7 +341,7 @@ func main() {
 	}
 
 	// Register Prometheus collector to gather information about containers, Go runtime, processes, and machine
-	cadvisorhttp.RegisterPrometheusHandler(mux, resourceManager, *prometheusEndpoint, containerLabelFunc, includedMetrics)
+	cadvisorhttp.RegisterPrometheusHandler(mux, resourceManager, *prometheusEndpoint, containerLabelFunc, includedMetrics, metricsRelabeler, *prometheusOpenMetrics, *prometheusExemplars)
 
 	// Start the manager.
 	if err := resourceManager.Start(); err != nil {
@@ -172,15 +349,222 @@ func main() {
 	}
 
 	// Install signal handler.
-	installSignalHandler(resourceManager)
+	installSignalHandler(resourceManager, closers...)
+
+	// In standby mode, wait until no other instance on this host holds the lock
+	// before notifying or serving anything.
+	if active != nil {
+		select {
+		case <-active:
+		case err := <-standbyErrs:
+			klog.Fatalf("Failed to acquire standby lock: %v", err)
+		}
+	}
+
+	if *alertingRulesFile != "" {
+		config, err := alerting.ParseConfig(*alertingRulesFile)
+		if err != nil {
+			klog.Fatalf("Failed to load alerting rules: %v", err)
+		}
+		evaluator, err := alerting.NewEvaluator(resourceManager, config, clock.RealClock{})
+		if err != nil {
+			klog.Fatalf("Failed to create alerting evaluator: %v", err)
+		}
+		evaluator.Start(*alertingInterval)
+	}
+
+	if *anomalyThreshold != 0 {
+		detector, err := anomaly.NewDetector(resourceManager, *anomalyThreshold, *anomalyAlpha)
+		if err != nil {
+			klog.Fatalf("Failed to create anomaly detector: %v", err)
+		}
+		detector.Start(*anomalyInterval)
+	}
+
+	if runtimes := splitList(*imagePullEvents); len(runtimes) > 0 {
+		watcher, err := imagepull.NewWatcher(resourceManager, runtimes)
+		if err != nil {
+			klog.Fatalf("Failed to watch image pulls: %v", err)
+		}
+		watcher.Start()
+	}
+
+	tlsConfig, err := serverTLSConfig()
+	if err != nil {
+		klog.Fatalf("Failed to configure HTTPS: %v", err)
+	}
+	tokenVerifiers, err := tokenVerifiers()
+	if err != nil {
+		klog.Fatalf("Failed to configure bearer token authentication: %v", err)
+	}
+	limits := cadvisorhttp.Limits{
+		ClientRate:  *httpClientRateLimit,
+		ClientBurst: *httpClientRateBurst,
+		MaxInFlight: *httpMaxInFlight,
+	}
+
+	if *grpcPort != 0 {
+		security := cadvisorgrpc.Security{TLS: tlsConfig, TokenVerifiers: tokenVerifiers, AuditLog: auditLog, Limits: limits}
+		if *apiAuthorizationFile != "" {
+			security.Policy, err = api.ParseAuthorizationPolicy(*apiAuthorizationFile)
+			if err != nil {
+				klog.Fatalf("Failed to load the API authorization policy: %v", err)
+			}
+		}
+		listener, err := net.Listen("tcp", fmt.Sprintf("%s:%d", *grpcListenIP, *grpcPort))
+		if err != nil {
+			klog.Fatalf("Failed to listen for the gRPC API: %v", err)
+		}
+		klog.V(1).Infof("Serving the gRPC API on %s", listener.Addr())
+		go func() {
+			klog.Fatal(cadvisorgrpc.NewServer(resourceManager, *manager.HousekeepingInterval, security.ServerOptions()...).Serve(listener))
+		}()
+	}
+
+	if *criStatsSocket != "" {
+		// Remove the socket left by a previous run.
+		if err := os.Remove(*criStatsSocket); err != nil && !os.IsNotExist(err) {
+			klog.Fatalf("Failed to remove the CRI stats socket: %v", err)
+		}
+		listener, err := net.Listen("unix", *criStatsSocket)
+		if err != nil {
+			klog.Fatalf("Failed to listen for the CRI stats service: %v", err)
+		}
+		klog.V(1).Infof("Serving the CRI stats service on %s", *criStatsSocket)
+		go func() {
+			klog.Fatal(cadvisorgrpc.NewCRIServer(resourceManager).Serve(listener))
+		}()
+	}
 
 	klog.V(1).Infof("Starting cAdvisor version: %s-%s on port %d", version.Info["version"], version.Info["revision"], *argPort)
 
+	addr := fmt.Sprintf("%s:%d", *argIP, *argPort)
+	// The handlers below match the paths stripped of the URL base prefix.
+	var handler http.Handler = mux
+	if len(tokenVerifiers) > 0 {
+		if tlsConfig == nil {
+			klog.Warningf("Bearer tokens are sent in clear over plain HTTP, see --tls_cert_file")
+		}
+		handler = cadvisorhttp.TokenAuthHandler(handler, corsConfig(), tokenVerifiers...)
+	}
+	if *tlsClientCAFile != "" {
+		handler = cadvisorhttp.ClientCertHandler(handler)
+	}
+	handler = cadvisorhttp.LimitHandler(handler, limits)
 	rootMux := http.NewServeMux()
-	rootMux.Handle(*urlBasePrefix+"/", http.StripPrefix(*urlBasePrefix, mux))
+	rootMux.Handle(*urlBasePrefix+"/", http.StripPrefix(*urlBasePrefix, handler))
+	handler = cadvisorhttp.CompressHandler(rootMux)
+	if tlsConfig == nil {
+		klog.Fatal(http.ListenAndServe(addr, handler))
+	}
+	server := &http.Server{Addr: addr, Handler: handler, TLSConfig: tlsConfig}
+	klog.Fatal(server.ListenAndServeTLS("", ""))
+}
 
-	addr := fmt.Sprintf("%s:%d", *argIP, *argPort)
-	klog.Fatal(http.ListenAndServe(addr, rootMux))
+// tokenVerifiers returns the verifiers of the bearer tokens set by the
+// http_token_* and api_token_file flags, none if tokens are not required.
+func tokenVerifiers() ([]cadvisorhttp.TokenVerifier, error) {
+	var verifiers []cadvisorhttp.TokenVerifier
+	if *httpTokenIssuer != "" || *httpTokenJWKSURL != "" {
+		authenticator, err := cadvisorhttp.NewTokenAuthenticator(cadvisorhttp.TokenAuthConfig{
+			Issuer:   *httpTokenIssuer,
+			Audience: *httpTokenAudience,
+			JWKSURL:  *httpTokenJWKSURL,
+		})
+		if err != nil {
+			return nil, err
+		}
+		verifiers = append(verifiers, authenticator)
+	}
+	if *apiTokenFile != "" {
+		tokens, err := cadvisorhttp.NewAPITokens(*apiTokenFile)
+		if err != nil {
+			return nil, err
+		}
+		verifiers = append(verifiers, tokens)
+	}
+	return verifiers, nil
+}
+
+// serverTLSConfig returns the TLS config set by the tls_* flags, or nil to
+// serve plain HTTP.
+func serverTLSConfig() (*tls.Config, error) {
+	config, err := serverCertConfig()
+	if err != nil || *tlsClientCAFile == "" {
+		return config, err
+	}
+	if config == nil {
+		return nil, fmt.Errorf("--tls_client_ca_file requires --tls_cert_file or --tls_self_signed")
+	}
+	if err := cadvisorhttp.VerifyClientCerts(config, *tlsClientCAFile); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// serverCertConfig returns the TLS config presenting the certificate set by
+// the tls_* flags, or nil to serve plain HTTP.
+func serverCertConfig() (*tls.Config, error) {
+	switch {
+	case *tlsCertFile != "" || *tlsKeyFile != "":
+		if *tlsCertFile == "" || *tlsKeyFile == "" {
+			return nil, fmt.Errorf("--tls_cert_file and --tls_key_file must be set together")
+		}
+		return cadvisorhttp.TLSConfig(*tlsCertFile, *tlsKeyFile)
+	case *tlsSelfSigned:
+		hosts := []string{"localhost", "127.0.0.1", "::1"}
+		if hostname, err := os.Hostname(); err == nil {
+			hosts = append(hosts, hostname)
+		}
+		if *argIP != "" {
+			hosts = append(hosts, *argIP)
+		}
+		return cadvisorhttp.SelfSignedTLSConfig(hosts)
+	}
+	return nil, nil
+}
+
+// splitList returns the non-empty values of a comma-separated list.
+func splitList(list string) []string {
+	var values []string
+	for _, value := range strings.Split(list, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// corsConfig returns the CORS config set by the cors_* flags.
+func corsConfig() cadvisorhttp.CORSConfig {
+	return cadvisorhttp.CORSConfig{
+		AllowedOrigins: splitList(*corsAllowedOrigins),
+		AllowedMethods: splitList(*corsAllowedMethods),
+		AllowedHeaders: splitList(*corsAllowedHeaders),
+		MaxAge:         *corsMaxAge,
+	}
+}
+
+// readEnvMetadataAllowList returns the comma-separated prefixes of the flag
+// followed by the ones listed in the file, if any. Blank lines and lines
+// starting with # are ignored.
+func readEnvMetadataAllowList(prefixes, path string) ([]string, error) {
+	allowList := strings.Split(prefixes, ",")
+	if path == "" {
+		return allowList, nil
+	}
+	out, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		allowList = append(allowList, line)
+	}
+	return allowList, nil
 }
 
 func setMaxProcs() {
@@ -201,7 +585,7 @@ func setMaxProcs() {
 	}
 }
 
-func installSignalHandler(containerManager manager.Manager) {
+func installSignalHandler(containerManager manager.Manager, closers ...io.Closer) {
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 
@@ -211,6 +595,11 @@ func installSignalHandler(containerManager manager.Manager) {
 		if err := containerManager.Stop(); err != nil {
 			klog.Errorf("Failed to stop container manager: %v", err)
 		}
+		for _, closer := range closers {
+			if err := closer.Close(); err != nil {
+				klog.Errorf("Failed to close %T: %v", closer, err)
+			}
+		}
 		klog.Infof("Exiting given signal: %v", sig)
 		os.Exit(0)
 	}()
@@ -16,9 +16,12 @@ package main
 
 import (
 	"flag"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/yidoyoon/cadvisor-lite/container"
 )
@@ -41,6 +44,12 @@ func TestUdpMetricsAreDisabledByDefault(t *testing.T) {
 	assert.True(t, ignoreMetrics.Has(container.NetworkUdpUsageMetrics))
 }
 
+func TestConntrackMetricsAreDisabledByDefault(t *testing.T) {
+	assert.True(t, ignoreMetrics.Has(container.NetworkConntrackMetrics))
+	flag.Parse()
+	assert.True(t, ignoreMetrics.Has(container.NetworkConntrackMetrics))
+}
+
 func TestReferencedMemoryMetricsIsDisabledByDefault(t *testing.T) {
 	assert.True(t, ignoreMetrics.Has(container.ReferencedMemoryMetrics))
 	flag.Parse()
@@ -103,6 +112,10 @@ func TestToIncludedMetrics(t *testing.T) {
 			container.NetworkTcpUsageMetrics:         struct{}{},
 			container.NetworkAdvancedTcpUsageMetrics: struct{}{},
 			container.NetworkUdpUsageMetrics:         struct{}{},
+			container.NetworkConntrackMetrics:        struct{}{},
+			container.NetfilterMetrics:               struct{}{},
+			container.NetworkTcMetrics:               struct{}{},
+			container.NodeMetrics:                    struct{}{},
 			container.ProcessMetrics:                 struct{}{},
 			container.AppMetrics:                     struct{}{},
 			container.HugetlbUsageMetrics:            struct{}{},
@@ -122,3 +135,18 @@ func TestToIncludedMetrics(t *testing.T) {
 		assert.Equal(t, actual, expected[idx])
 	}
 }
+
+func TestReadEnvMetadataAllowList(t *testing.T) {
+	allowList, err := readEnvMetadataAllowList("", "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{""}, allowList)
+
+	path := filepath.Join(t.TempDir(), "env_allow_list")
+	require.NoError(t, os.WriteFile(path, []byte("# Deployment metadata\nSERVICE_VERSION\n\n  TEAM_ \n"), 0o644))
+	allowList, err = readEnvMetadataAllowList("APP_,OWNER", path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"APP_", "OWNER", "SERVICE_VERSION", "TEAM_"}, allowList)
+
+	_, err = readEnvMetadataAllowList("", filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,83 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package admin provides authenticated handlers under /admin/ that change the
+// behavior of a running cAdvisor without restarting it.
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/yidoyoon/cadvisor-lite/cmd/internal/http/identity"
+	httpmux "github.com/yidoyoon/cadvisor-lite/cmd/internal/http/mux"
+	"github.com/yidoyoon/cadvisor-lite/manager"
+
+	auth "github.com/abbot/go-http-auth"
+	"k8s.io/klog/v2"
+)
+
+const (
+	adminResource   = "/admin/"
+	loggingPage     = adminResource + "logging"
+	rediscoverPage  = adminResource + "rediscover"
+	envMetadataPage = adminResource + "env_metadata"
+	gcPage          = adminResource + "gc"
+)
+
+// RegisterHandlers registers the admin handlers on the mux. All handlers are
+// wrapped by the authenticator, which is therefore required.
+func RegisterHandlers(mux httpmux.Mux, m manager.Manager, authenticator auth.AuthenticatorInterface) error {
+	if authenticator == nil {
+		return fmt.Errorf("the admin API requires --http_auth_file or --http_digest_file to be set")
+	}
+	mux.HandleFunc(loggingPage, authenticator.Wrap(handleLogging))
+	mux.HandleFunc(rediscoverPage, authenticator.Wrap(rediscoverHandler(m)))
+	mux.HandleFunc(envMetadataPage, authenticator.Wrap(envMetadataHandler(m)))
+	mux.HandleFunc(gcPage, authenticator.Wrap(gcHandler()))
+	return nil
+}
+
+// Restricts a handler to GET requests and requests that modify state.
+func allowedMethod(w http.ResponseWriter, r *auth.AuthenticatedRequest) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodPost, http.MethodPut:
+		return true
+	}
+	w.Header().Set("Allow", "GET, POST, PUT")
+	http.Error(w, fmt.Sprintf("method %s not allowed", r.Method), http.StatusMethodNotAllowed)
+	return false
+}
+
+// requester returns who made r, for the logs: the authenticated user, and
+// the identity of the client certificate or bearer token if any.
+func requester(r *auth.AuthenticatedRequest) string {
+	if id, ok := identity.FromRequest(&r.Request); ok {
+		return fmt.Sprintf("%s (client %s)", r.Username, id)
+	}
+	return r.Username
+}
+
+func writeResult(res interface{}, w http.ResponseWriter) {
+	out, err := json.Marshal(res)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to marshall response %+v with error: %s", res, err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(out); err != nil {
+		klog.Errorf("failed to write admin response: %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/yidoyoon/cadvisor-lite/manager"
+
+	auth "github.com/abbot/go-http-auth"
+	"k8s.io/klog/v2"
+)
+
+// EnvMetadataConfig is the configuration reported and accepted by
+// /admin/env_metadata.
+type EnvMetadataConfig struct {
+	// Prefixes of the environment variables collected as metadata of the
+	// containers, as set by -env_metadata_whitelist.
+	AllowList []string `json:"allow_list"`
+}
+
+// ParseAllowList splits a comma-separated list of prefixes, dropping the
+// empty ones.
+func ParseAllowList(s string) []string {
+	allowList := []string{}
+	for _, prefix := range strings.Split(s, ",") {
+		if prefix = strings.TrimSpace(prefix); prefix != "" {
+			allowList = append(allowList, prefix)
+		}
+	}
+	return allowList
+}
+
+// The allow list set by the flag holds an empty prefix when unset.
+func currentAllowList(m manager.Manager) []string {
+	return ParseAllowList(strings.Join(m.GetEnvMetadataAllowList(), ","))
+}
+
+// envMetadataHandler reports the environment variable allow list on GET and
+// replaces it on POST or PUT, e.g. POST /admin/env_metadata?allow_list=SERVICE_,TEAM.
+// The new list applies to the containers discovered afterwards.
+func envMetadataHandler(m manager.Manager) auth.AuthenticatedHandlerFunc {
+	return func(w http.ResponseWriter, r *auth.AuthenticatedRequest) {
+		if !allowedMethod(w, r) {
+			return
+		}
+		if r.Method != http.MethodGet {
+			if err := r.ParseForm(); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if _, ok := r.Form["allow_list"]; !ok {
+				http.Error(w, "missing 'allow_list' option", http.StatusBadRequest)
+				return
+			}
+			old := currentAllowList(m)
+			m.SetEnvMetadataAllowList(ParseAllowList(r.Form.Get("allow_list")))
+			klog.Infof("Environment variable allow list changed by %q from %q to %q", requester(r), old, currentAllowList(m))
+		}
+		writeResult(EnvMetadataConfig{AllowList: currentAllowList(m)}, w)
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/yidoyoon/cadvisor-lite/manager/fake"
+
+	auth "github.com/abbot/go-http-auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func doEnvMetadataRequest(h auth.AuthenticatedHandlerFunc, method, url string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, url, nil)
+	w := httptest.NewRecorder()
+	h(w, &auth.AuthenticatedRequest{Request: *r, Username: "admin"})
+	return w
+}
+
+func TestEnvMetadata(t *testing.T) {
+	m := fake.NewManager()
+	// As set by an empty flag.
+	m.SetEnvMetadataAllowList([]string{""})
+	h := envMetadataHandler(m)
+
+	w := doEnvMetadataRequest(h, http.MethodGet, "/admin/env_metadata")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"allow_list": []}`, w.Body.String())
+
+	w = doEnvMetadataRequest(h, http.MethodPost, "/admin/env_metadata?allow_list=SERVICE_VERSION,%20TEAM_,")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var config EnvMetadataConfig
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &config))
+	assert.Equal(t, []string{"SERVICE_VERSION", "TEAM_"}, config.AllowList)
+	assert.Equal(t, []string{"SERVICE_VERSION", "TEAM_"}, m.GetEnvMetadataAllowList())
+
+	// The list is replaced, not merged.
+	w = doEnvMetadataRequest(h, http.MethodPut, "/admin/env_metadata?allow_list=")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Empty(t, m.GetEnvMetadataAllowList())
+
+	w = doEnvMetadataRequest(h, http.MethodPost, "/admin/env_metadata")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doEnvMetadataRequest(h, http.MethodDelete, "/admin/env_metadata")
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
@@ -0,0 +1,125 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/yidoyoon/cadvisor-lite/container/docker"
+	v2 "github.com/yidoyoon/cadvisor-lite/info/v2"
+
+	auth "github.com/abbot/go-http-auth"
+	"k8s.io/klog/v2"
+)
+
+// gcRuntime advises on and runs the garbage collection of the containers and
+// images of a container runtime.
+type gcRuntime struct {
+	advise  func(reclaim int64) (*v2.GCAdvice, error)
+	collect func(reclaim int64) (*v2.GCAdvice, error)
+}
+
+var gcRuntimes = map[string]gcRuntime{
+	"docker": {advise: docker.GCAdvice, collect: docker.CollectGarbage},
+}
+
+// Header required by the requests removing garbage, with any value. Browsers
+// only send custom headers to other origins after a CORS preflight, which the
+// admin API does not answer, so that a page of another origin cannot make such
+// requests with the credentials of the user.
+const requestedByHeader = "X-Requested-By"
+
+// gcHandler reports the stopped containers and unused images whose removal
+// reclaims the reclaim_bytes parameter on GET, all of them if unset, and
+// removes them on POST or PUT, e.g. POST /admin/gc?runtime=docker&reclaim_bytes=1073741824
+// with the X-Requested-By header. Removing requires either a positive
+// reclaim_bytes, or all=true for removing all of them.
+func gcHandler() auth.AuthenticatedHandlerFunc {
+	return func(w http.ResponseWriter, r *auth.AuthenticatedRequest) {
+		if !allowedMethod(w, r) {
+			return
+		}
+		if r.Method != http.MethodGet && r.Header.Get(requestedByHeader) == "" {
+			http.Error(w, fmt.Sprintf("missing %s header", requestedByHeader), http.StatusForbidden)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		name := r.Form.Get("runtime")
+		if name == "" {
+			name = "docker"
+		}
+		runtime, ok := gcRuntimes[name]
+		if !ok {
+			http.Error(w, fmt.Sprintf("garbage collection of container runtime %q is not available", name), http.StatusNotFound)
+			return
+		}
+		var reclaim int64
+		if val, ok := r.Form["reclaim_bytes"]; ok {
+			var err error
+			reclaim, err = strconv.ParseInt(val[0], 10, 64)
+			if err != nil || reclaim < 0 {
+				http.Error(w, fmt.Sprintf("invalid 'reclaim_bytes' option %q: must be a non-negative integer", val[0]), http.StatusBadRequest)
+				return
+			}
+		}
+		all := false
+		if val := r.Form.Get("all"); val != "" {
+			var err error
+			all, err = strconv.ParseBool(val)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("invalid 'all' option %q: must be a boolean", val), http.StatusBadRequest)
+				return
+			}
+		}
+		if r.Method != http.MethodGet {
+			switch {
+			case all && reclaim > 0:
+				http.Error(w, "the 'reclaim_bytes' and 'all' options are mutually exclusive", http.StatusBadRequest)
+				return
+			case !all && reclaim == 0:
+				http.Error(w, "missing positive 'reclaim_bytes' option, or 'all=true' for removing all the candidates", http.StatusBadRequest)
+				return
+			}
+		}
+
+		if r.Method == http.MethodGet {
+			advice, err := runtime.advise(reclaim)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			writeResult(advice, w)
+			return
+		}
+		advice, err := runtime.collect(reclaim)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		removed := 0
+		for _, candidate := range advice.Candidates {
+			if candidate.Removed {
+				removed++
+			}
+		}
+		klog.Infof("Garbage of %s collected by %q: %d of %d candidates removed, %d bytes reclaimed", name, requester(r), removed, len(advice.Candidates), advice.ReclaimedBytes)
+		writeResult(advice, w)
+	}
+}
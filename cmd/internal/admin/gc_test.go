@@ -0,0 +1,113 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	v2 "github.com/yidoyoon/cadvisor-lite/info/v2"
+
+	auth "github.com/abbot/go-http-auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func doGCRequest(method, target string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, nil)
+	r.Header.Set("X-Requested-By", "test")
+	w := httptest.NewRecorder()
+	gcHandler()(w, &auth.AuthenticatedRequest{Request: *r, Username: "admin"})
+	return w
+}
+
+func TestGC(t *testing.T) {
+	var advised, collected []int64
+	defer func(old map[string]gcRuntime) { gcRuntimes = old }(gcRuntimes)
+	gcRuntimes = map[string]gcRuntime{
+		"docker": {
+			advise: func(reclaim int64) (*v2.GCAdvice, error) {
+				advised = append(advised, reclaim)
+				return &v2.GCAdvice{RequestedBytes: reclaim}, nil
+			},
+			collect: func(reclaim int64) (*v2.GCAdvice, error) {
+				collected = append(collected, reclaim)
+				return &v2.GCAdvice{RequestedBytes: reclaim, Executed: true}, nil
+			},
+		},
+	}
+
+	w := doGCRequest(http.MethodGet, "/admin/gc?reclaim_bytes=100")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var advice v2.GCAdvice
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &advice))
+	assert.False(t, advice.Executed)
+
+	w = doGCRequest(http.MethodPost, "/admin/gc?runtime=docker&reclaim_bytes=200")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &advice))
+	assert.True(t, advice.Executed)
+	assert.Equal(t, int64(200), advice.RequestedBytes)
+
+	// Removing everything must be asked for explicitly.
+	w = doGCRequest(http.MethodPost, "/admin/gc")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doGCRequest(http.MethodPost, "/admin/gc?reclaim_bytes=0")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doGCRequest(http.MethodPost, "/admin/gc?reclaim_bytes=10&all=true")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doGCRequest(http.MethodPost, "/admin/gc?all=true")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = doGCRequest(http.MethodPut, "/admin/gc?reclaim_bytes=-1")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doGCRequest(http.MethodPost, "/admin/gc?runtime=containerd&all=true")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = doGCRequest(http.MethodDelete, "/admin/gc?all=true")
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+
+	assert.Equal(t, []int64{100}, advised)
+	assert.Equal(t, []int64{200, 0}, collected)
+}
+
+func TestGCRequiresRequestedByHeader(t *testing.T) {
+	defer func(old map[string]gcRuntime) { gcRuntimes = old }(gcRuntimes)
+	collected := false
+	gcRuntimes = map[string]gcRuntime{
+		"docker": {
+			advise: func(reclaim int64) (*v2.GCAdvice, error) { return &v2.GCAdvice{}, nil },
+			collect: func(reclaim int64) (*v2.GCAdvice, error) {
+				collected = true
+				return &v2.GCAdvice{}, nil
+			},
+		},
+	}
+
+	// As sent by a form of another origin.
+	r := httptest.NewRequest(http.MethodPost, "/admin/gc", strings.NewReader("reclaim_bytes=100"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	gcHandler()(w, &auth.AuthenticatedRequest{Request: *r, Username: "admin"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.False(t, collected)
+
+	// Reports do not change anything.
+	r = httptest.NewRequest(http.MethodGet, "/admin/gc", nil)
+	w = httptest.NewRecorder()
+	gcHandler()(w, &auth.AuthenticatedRequest{Request: *r, Username: "admin"})
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,87 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	auth "github.com/abbot/go-http-auth"
+	"k8s.io/klog/v2"
+)
+
+// LoggingConfig is the klog configuration reported and accepted by /admin/logging.
+type LoggingConfig struct {
+	// Global verbosity, as set by -v.
+	Verbosity string `json:"v"`
+	// Per-file verbosity, as set by -vmodule (e.g. "handler=4,factory=3").
+	VModule string `json:"vmodule"`
+}
+
+// The flag set klog registered its flags on. Overridden in tests.
+var logFlags = flag.CommandLine
+
+func currentLoggingConfig() LoggingConfig {
+	config := LoggingConfig{}
+	if f := logFlags.Lookup("v"); f != nil {
+		config.Verbosity = f.Value.String()
+	}
+	if f := logFlags.Lookup("vmodule"); f != nil {
+		config.VModule = f.Value.String()
+	}
+	return config
+}
+
+// Applies the "v" and "vmodule" parameters of the request, if present.
+func setLoggingConfig(r *http.Request) error {
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+	if _, ok := r.Form["v"]; ok {
+		v := r.Form.Get("v")
+		level, err := strconv.Atoi(v)
+		if err != nil || level < 0 {
+			return fmt.Errorf("invalid 'v' option %q: must be a non-negative integer", v)
+		}
+		if err := logFlags.Set("v", v); err != nil {
+			return fmt.Errorf("failed to set verbosity: %v", err)
+		}
+	}
+	if _, ok := r.Form["vmodule"]; ok {
+		if err := logFlags.Set("vmodule", r.Form.Get("vmodule")); err != nil {
+			return fmt.Errorf("invalid 'vmodule' option: %v", err)
+		}
+	}
+	return nil
+}
+
+// handleLogging reports the klog configuration on GET and updates it on POST
+// or PUT, e.g. POST /admin/logging?v=4&vmodule=handler=5
+func handleLogging(w http.ResponseWriter, r *auth.AuthenticatedRequest) {
+	if !allowedMethod(w, r) {
+		return
+	}
+	if r.Method != http.MethodGet {
+		old := currentLoggingConfig()
+		if err := setLoggingConfig(&r.Request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		klog.Infof("Logging configuration changed by %q from %+v to %+v", requester(r), old, currentLoggingConfig())
+	}
+	writeResult(currentLoggingConfig(), w)
+}
@@ -0,0 +1,83 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin
+
+import (
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	auth "github.com/abbot/go-http-auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/klog/v2"
+)
+
+func setupLogFlags(t *testing.T) {
+	fs := flag.NewFlagSet("klog", flag.ContinueOnError)
+	klog.InitFlags(fs)
+	old := logFlags
+	logFlags = fs
+	t.Cleanup(func() {
+		_ = fs.Set("v", "0")
+		_ = fs.Set("vmodule", "")
+		logFlags = old
+	})
+}
+
+func doLoggingRequest(method, url string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, url, nil)
+	w := httptest.NewRecorder()
+	handleLogging(w, &auth.AuthenticatedRequest{Request: *r, Username: "admin"})
+	return w
+}
+
+func TestHandleLogging(t *testing.T) {
+	setupLogFlags(t)
+
+	w := doLoggingRequest(http.MethodPost, "/admin/logging?v=4&vmodule=handler=6")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var config LoggingConfig
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &config))
+	assert.Equal(t, LoggingConfig{Verbosity: "4", VModule: "handler=6"}, config)
+	assert.True(t, bool(klog.V(4).Enabled()))
+
+	// Omitted parameters are left unchanged.
+	w = doLoggingRequest(http.MethodPut, "/admin/logging?v=1")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = doLoggingRequest(http.MethodGet, "/admin/logging")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &config))
+	assert.Equal(t, LoggingConfig{Verbosity: "1", VModule: "handler=6"}, config)
+}
+
+func TestHandleLoggingInvalid(t *testing.T) {
+	setupLogFlags(t)
+
+	for _, url := range []string{
+		"/admin/logging?v=-1",
+		"/admin/logging?v=verbose",
+		"/admin/logging?vmodule=handler",
+	} {
+		w := doLoggingRequest(http.MethodPost, url)
+		assert.Equal(t, http.StatusBadRequest, w.Code, url)
+	}
+	assert.Equal(t, LoggingConfig{Verbosity: "0"}, currentLoggingConfig())
+
+	w := doLoggingRequest(http.MethodDelete, "/admin/logging")
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
@@ -0,0 +1,52 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/yidoyoon/cadvisor-lite/manager"
+
+	auth "github.com/abbot/go-http-auth"
+	"k8s.io/klog/v2"
+)
+
+// RediscoverResult lists the containers found by /admin/rediscover.
+type RediscoverResult struct {
+	// Containers found that were not monitored yet.
+	Added []string `json:"added"`
+	// Monitored containers that are gone.
+	Removed []string `json:"removed"`
+}
+
+// rediscoverHandler rescans the containers on POST or PUT, instead of waiting
+// for the next global housekeeping, e.g. after a container runtime restarted.
+func rediscoverHandler(m manager.Manager) auth.AuthenticatedHandlerFunc {
+	return func(w http.ResponseWriter, r *auth.AuthenticatedRequest) {
+		if r.Method != http.MethodPost && r.Method != http.MethodPut {
+			w.Header().Set("Allow", "POST, PUT")
+			http.Error(w, fmt.Sprintf("method %s not allowed", r.Method), http.StatusMethodNotAllowed)
+			return
+		}
+		added, removed, err := m.RediscoverContainers()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		klog.Infof("Containers rediscovered by %q: %d added, %d removed", requester(r), len(added), len(removed))
+		writeResult(RediscoverResult{Added: added, Removed: removed}, w)
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/yidoyoon/cadvisor-lite/manager"
+
+	auth "github.com/abbot/go-http-auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type rediscoveringManager struct {
+	manager.Manager
+
+	calls int
+	err   error
+}
+
+func (m *rediscoveringManager) RediscoverContainers() ([]string, []string, error) {
+	m.calls++
+	if m.err != nil {
+		return nil, nil, m.err
+	}
+	return []string{"/docker/abc"}, []string{"/docker/def"}, nil
+}
+
+func doRediscoverRequest(m manager.Manager, method string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, "/admin/rediscover", nil)
+	w := httptest.NewRecorder()
+	rediscoverHandler(m)(w, &auth.AuthenticatedRequest{Request: *r, Username: "admin"})
+	return w
+}
+
+func TestRediscover(t *testing.T) {
+	m := &rediscoveringManager{}
+
+	w := doRediscoverRequest(m, http.MethodPost)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var result RediscoverResult
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, RediscoverResult{Added: []string{"/docker/abc"}, Removed: []string{"/docker/def"}}, result)
+
+	// Reads do not trigger a rescan.
+	w = doRediscoverRequest(m, http.MethodGet)
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	assert.Equal(t, 1, m.calls)
+
+	m.err = fmt.Errorf("failed to list containers")
+	w = doRediscoverRequest(m, http.MethodPut)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
@@ -0,0 +1,213 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package alerting evaluates threshold rules against container stats and
+// sends notifications when alerts fire or resolve. Alerts are also added to
+// the events of the manager.
+package alerting
+
+import (
+	"fmt"
+	"time"
+
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+	v2 "github.com/yidoyoon/cadvisor-lite/info/v2"
+	"github.com/yidoyoon/cadvisor-lite/manager"
+
+	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+)
+
+const (
+	StateFiring   = "firing"
+	StateResolved = "resolved"
+)
+
+// Alert is a firing or resolved alert, as sent to notifiers.
+type Alert struct {
+	Rule        string    `json:"rule"`
+	Container   string    `json:"container"`
+	State       string    `json:"state"`
+	Metric      string    `json:"metric"`
+	Value       float64   `json:"value"`
+	Threshold   float64   `json:"threshold"`
+	Timestamp   time.Time `json:"timestamp"`
+	Description string    `json:"description"`
+}
+
+type alertKey struct {
+	rule      string
+	container string
+}
+
+type alertState struct {
+	// When the rule started to match.
+	pendingSince time.Time
+	firing       bool
+	lastValue    float64
+}
+
+// Evaluator periodically evaluates rules against the stats of all containers.
+type Evaluator struct {
+	manager   manager.Manager
+	rules     []Rule
+	notifiers []Notifier
+	clock     clock.Clock
+
+	alerts map[alertKey]*alertState
+}
+
+// NewEvaluator returns an Evaluator of the rules and notifiers of config.
+func NewEvaluator(m manager.Manager, config *Config, clock clock.Clock) (*Evaluator, error) {
+	e := &Evaluator{
+		manager: m,
+		rules:   config.Rules,
+		clock:   clock,
+		alerts:  make(map[alertKey]*alertState),
+	}
+	for _, n := range config.Notifiers {
+		notifier, err := newNotifier(n)
+		if err != nil {
+			return nil, err
+		}
+		e.notifiers = append(e.notifiers, notifier)
+	}
+	return e, nil
+}
+
+// Start evaluates the rules every interval.
+func (e *Evaluator) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			e.Evaluate()
+		}
+	}()
+}
+
+// Evaluate evaluates all rules once against the latest stats.
+func (e *Evaluator) Evaluate() {
+	containers, err := e.manager.GetRequestedContainersInfo("/", v2.RequestOptions{
+		IdType:    v2.TypeName,
+		Count:     2,
+		Recursive: true,
+	})
+	if err != nil {
+		// Partial failures still return the available containers.
+		klog.V(4).Infof("Failed to get stats for alerting: %v", err)
+	}
+
+	now := e.clock.Now()
+	seen := make(map[alertKey]bool)
+	for name, cont := range containers {
+		if cont == nil || len(cont.Stats) == 0 {
+			continue
+		}
+		cur := cont.Stats[len(cont.Stats)-1]
+		var prev *info.ContainerStats
+		if len(cont.Stats) > 1 {
+			prev = cont.Stats[len(cont.Stats)-2]
+		}
+		for i := range e.rules {
+			rule := &e.rules[i]
+			if !rule.matches(name) {
+				continue
+			}
+			value, ok := metricFuncs[rule.Metric](&cont.Spec, prev, cur)
+			if !ok {
+				continue
+			}
+			key := alertKey{rule: rule.Name, container: name}
+			state, ok := e.alerts[key]
+			if !operators[rule.Operator](value, rule.Threshold) {
+				if ok && state.firing {
+					e.notify(rule, name, StateResolved, value, now)
+				}
+				delete(e.alerts, key)
+				continue
+			}
+			seen[key] = true
+			if !ok {
+				state = &alertState{pendingSince: now}
+				e.alerts[key] = state
+			}
+			state.lastValue = value
+			if !state.firing && now.Sub(state.pendingSince) >= time.Duration(rule.For) {
+				state.firing = true
+				e.notify(rule, name, StateFiring, value, now)
+			}
+		}
+	}
+
+	// Resolve the alerts of containers that are gone or whose metric is no
+	// longer available.
+	for key, state := range e.alerts {
+		if seen[key] {
+			continue
+		}
+		if state.firing {
+			e.notify(e.rule(key.rule), key.container, StateResolved, state.lastValue, now)
+		}
+		delete(e.alerts, key)
+	}
+}
+
+func (e *Evaluator) rule(name string) *Rule {
+	for i := range e.rules {
+		if e.rules[i].Name == name {
+			return &e.rules[i]
+		}
+	}
+	return nil
+}
+
+func (e *Evaluator) notify(rule *Rule, container, state string, value float64, now time.Time) {
+	alert := &Alert{
+		Rule:        rule.Name,
+		Container:   container,
+		State:       state,
+		Metric:      rule.Metric,
+		Value:       value,
+		Threshold:   rule.Threshold,
+		Timestamp:   now,
+		Description: fmt.Sprintf("[%s] %s on %s: %s is %.2f (threshold %s %.2f)", state, rule.Name, container, rule.Metric, value, rule.Operator, rule.Threshold),
+	}
+	klog.Infof("Alert %s", alert.Description)
+
+	err := e.manager.AddEvent(&info.Event{
+		ContainerName: container,
+		Timestamp:     now,
+		EventType:     info.EventAlert,
+		EventData: info.EventData{
+			Alert: &info.AlertEventData{
+				Rule:        alert.Rule,
+				State:       alert.State,
+				Metric:      alert.Metric,
+				Value:       alert.Value,
+				Threshold:   alert.Threshold,
+				Description: alert.Description,
+			},
+		},
+	})
+	if err != nil {
+		klog.Errorf("Failed to add alert event: %v", err)
+	}
+
+	for _, n := range e.notifiers {
+		if err := n.Notify(alert); err != nil {
+			klog.Errorf("Failed to send alert %q for %q: %v", rule.Name, container, err)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package alerting
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+	v2 "github.com/yidoyoon/cadvisor-lite/info/v2"
+	"github.com/yidoyoon/cadvisor-lite/manager"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/utils/clock"
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+type fakeManager struct {
+	manager.Manager
+	containers map[string]*info.ContainerInfo
+	events     []*info.Event
+}
+
+func (m *fakeManager) GetRequestedContainersInfo(name string, options v2.RequestOptions) (map[string]*info.ContainerInfo, error) {
+	return m.containers, nil
+}
+
+func (m *fakeManager) AddEvent(event *info.Event) error {
+	m.events = append(m.events, event)
+	return nil
+}
+
+type recordingNotifier struct {
+	alerts []*Alert
+}
+
+func (n *recordingNotifier) Notify(alert *Alert) error {
+	n.alerts = append(n.alerts, alert)
+	return nil
+}
+
+func memoryContainer(workingSet uint64) *info.ContainerInfo {
+	return &info.ContainerInfo{
+		Spec: info.ContainerSpec{
+			HasMemory: true,
+			Memory:    info.MemorySpec{Limit: 1000},
+		},
+		Stats: []*info.ContainerStats{{Memory: info.MemoryStats{WorkingSet: workingSet}}},
+	}
+}
+
+func newTestEvaluator(t *testing.T, rule Rule, clock clock.Clock) (*Evaluator, *fakeManager, *recordingNotifier) {
+	m := &fakeManager{}
+	e, err := NewEvaluator(m, &Config{Rules: []Rule{rule}}, clock)
+	require.NoError(t, err)
+	n := &recordingNotifier{}
+	e.notifiers = []Notifier{n}
+	return e, m, n
+}
+
+func TestEvaluate(t *testing.T) {
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	rule := Rule{Name: "HighMemory", Metric: "memory_usage_percent", Operator: ">", Threshold: 90, For: Duration(2 * time.Minute)}
+	e, m, n := newTestEvaluator(t, rule, fakeClock)
+
+	m.containers = map[string]*info.ContainerInfo{"/a": memoryContainer(950), "/b": memoryContainer(100)}
+	e.Evaluate()
+	assert.Empty(t, n.alerts, "alert fired before its duration")
+
+	fakeClock.Step(2 * time.Minute)
+	e.Evaluate()
+	require.Len(t, n.alerts, 1)
+	assert.Equal(t, "/a", n.alerts[0].Container)
+	assert.Equal(t, StateFiring, n.alerts[0].State)
+	assert.Equal(t, 95.0, n.alerts[0].Value)
+
+	// Firing alerts are not sent again.
+	fakeClock.Step(time.Minute)
+	e.Evaluate()
+	assert.Len(t, n.alerts, 1)
+
+	m.containers["/a"] = memoryContainer(500)
+	e.Evaluate()
+	require.Len(t, n.alerts, 2)
+	assert.Equal(t, StateResolved, n.alerts[1].State)
+	assert.Equal(t, 50.0, n.alerts[1].Value)
+
+	require.Len(t, m.events, 2)
+	assert.Equal(t, info.EventAlert, m.events[0].EventType)
+	assert.Equal(t, "/a", m.events[0].ContainerName)
+	assert.Equal(t, StateResolved, m.events[1].EventData.Alert.State)
+}
+
+func TestEvaluateResetsPendingAlerts(t *testing.T) {
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	rule := Rule{Name: "HighMemory", Metric: "memory_usage_bytes", Operator: ">=", Threshold: 900, For: Duration(2 * time.Minute)}
+	e, m, n := newTestEvaluator(t, rule, fakeClock)
+
+	m.containers = map[string]*info.ContainerInfo{"/a": memoryContainer(950)}
+	e.Evaluate()
+	fakeClock.Step(time.Minute)
+	m.containers["/a"] = memoryContainer(100)
+	e.Evaluate()
+	fakeClock.Step(time.Minute)
+	m.containers["/a"] = memoryContainer(950)
+	e.Evaluate()
+	assert.Empty(t, n.alerts)
+}
+
+func TestCPUMetrics(t *testing.T) {
+	now := time.Now()
+	spec := &info.ContainerSpec{HasCpu: true}
+	prev := &info.ContainerStats{Timestamp: now}
+	prev.Cpu.Usage.Total = 1e9
+	prev.Cpu.CFS.Periods = 100
+	prev.Cpu.CFS.ThrottledPeriods = 10
+	cur := &info.ContainerStats{Timestamp: now.Add(2 * time.Second)}
+	cur.Cpu.Usage.Total = 4e9
+	cur.Cpu.CFS.Periods = 200
+	cur.Cpu.CFS.ThrottledPeriods = 70
+
+	cores, ok := cpuUsageCores(spec, prev, cur)
+	assert.True(t, ok)
+	assert.Equal(t, 1.5, cores)
+	throttled, ok := cpuThrottledPercent(spec, prev, cur)
+	assert.True(t, ok)
+	assert.Equal(t, 60.0, throttled)
+
+	_, ok = cpuUsageCores(spec, nil, cur)
+	assert.False(t, ok)
+}
+
+func TestParseConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	write := func(content string) {
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	write(`{
+		"rules": [{"name": "Throttled", "metric": "cpu_throttled_percent", "operator": ">", "threshold": 50, "for": "2m", "containers": "^/docker/"}],
+		"notifiers": [{"type": "slack", "url": "http://localhost/hook"}, {"type": "pagerduty", "routing_key": "key"}]
+	}`)
+	config, err := ParseConfig(path)
+	require.NoError(t, err)
+	require.Len(t, config.Rules, 1)
+	assert.Equal(t, Duration(2*time.Minute), config.Rules[0].For)
+	assert.True(t, config.Rules[0].matches("/docker/abc"))
+	assert.False(t, config.Rules[0].matches("/system.slice"))
+
+	for _, invalid := range []string{
+		`{"rules": [{"name": "a", "metric": "disk_usage", "operator": ">"}]}`,
+		`{"rules": [{"name": "a", "metric": "cpu_usage_cores", "operator": "!="}]}`,
+		`{"rules": [{"name": "a", "metric": "cpu_usage_cores", "operator": ">", "for": 120}]}`,
+		`{"rules": [{"name": "a", "metric": "cpu_usage_cores", "operator": ">", "containers": "("}]}`,
+		`{"notifiers": [{"type": "webhook"}]}`,
+	} {
+		write(invalid)
+		_, err := ParseConfig(path)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestPagerDutyNotifier(t *testing.T) {
+	var events []pagerDutyEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event pagerDutyEvent
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+		events = append(events, event)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	n, err := newNotifier(NotifierConfig{Type: "pagerduty", URL: server.URL, RoutingKey: "key"})
+	require.NoError(t, err)
+	require.NoError(t, n.Notify(&Alert{Rule: "r", Container: "/a", State: StateFiring, Description: "d"}))
+	require.NoError(t, n.Notify(&Alert{Rule: "r", Container: "/a", State: StateResolved}))
+
+	require.Len(t, events, 2)
+	assert.Equal(t, "trigger", events[0].EventAction)
+	assert.Equal(t, "d", events[0].Payload.Summary)
+	assert.Equal(t, "resolve", events[1].EventAction)
+	assert.Equal(t, events[0].DedupKey, events[1].DedupKey)
+}
@@ -0,0 +1,135 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package alerting
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"time"
+)
+
+// Config is the content of the alerting rules file.
+type Config struct {
+	// Rules evaluated against the stats of every container.
+	Rules []Rule `json:"rules"`
+
+	// Notification channels to which every firing and resolved alert is sent.
+	Notifiers []NotifierConfig `json:"notifiers,omitempty"`
+}
+
+// Rule fires when a metric of a container crosses a threshold for a duration.
+type Rule struct {
+	// Unique name of the rule.
+	Name string `json:"name"`
+
+	// Metric to evaluate, one of the keys of metricFuncs.
+	Metric string `json:"metric"`
+
+	// Comparison of the metric with the threshold: ">", ">=", "<" or "<=".
+	Operator string `json:"operator"`
+
+	Threshold float64 `json:"threshold"`
+
+	// How long the comparison must hold before the alert fires.
+	// Default: the alert fires on the first evaluation.
+	For Duration `json:"for,omitempty"`
+
+	// Regular expression matched against container names.
+	// Default: all containers.
+	Containers string `json:"containers,omitempty"`
+
+	containers *regexp.Regexp
+}
+
+// NotifierConfig configures a notification channel.
+type NotifierConfig struct {
+	// One of "webhook", "slack" or "pagerduty".
+	Type string `json:"type"`
+
+	// Endpoint notifications are posted to. Required for webhook and slack,
+	// defaults to the PagerDuty Events API v2 for pagerduty.
+	URL string `json:"url,omitempty"`
+
+	// Integration key of the PagerDuty service.
+	RoutingKey string `json:"routing_key,omitempty"`
+}
+
+// Duration is a time.Duration written as a string, e.g. "2m".
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string: %v", err)
+	}
+	dur, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(dur)
+	return nil
+}
+
+// ParseConfig reads and validates the alerting rules file at path.
+func ParseConfig(path string) (*Config, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read alerting rules: %v", err)
+	}
+	config := &Config{}
+	if err := json.Unmarshal(content, config); err != nil {
+		return nil, fmt.Errorf("failed to parse alerting rules: %v", err)
+	}
+
+	names := make(map[string]bool, len(config.Rules))
+	for i := range config.Rules {
+		rule := &config.Rules[i]
+		if rule.Name == "" || names[rule.Name] {
+			return nil, fmt.Errorf("rule %d: missing or duplicate name %q", i, rule.Name)
+		}
+		names[rule.Name] = true
+		if _, ok := metricFuncs[rule.Metric]; !ok {
+			return nil, fmt.Errorf("rule %q: unknown metric %q", rule.Name, rule.Metric)
+		}
+		if _, ok := operators[rule.Operator]; !ok {
+			return nil, fmt.Errorf("rule %q: unknown operator %q", rule.Name, rule.Operator)
+		}
+		if rule.Containers != "" {
+			rule.containers, err = regexp.Compile(rule.Containers)
+			if err != nil {
+				return nil, fmt.Errorf("rule %q: invalid containers: %v", rule.Name, err)
+			}
+		}
+	}
+	for i, n := range config.Notifiers {
+		if _, err := newNotifier(n); err != nil {
+			return nil, fmt.Errorf("notifier %d: %v", i, err)
+		}
+	}
+	return config, nil
+}
+
+var operators = map[string]func(value, threshold float64) bool{
+	">":  func(v, t float64) bool { return v > t },
+	">=": func(v, t float64) bool { return v >= t },
+	"<":  func(v, t float64) bool { return v < t },
+	"<=": func(v, t float64) bool { return v <= t },
+}
+
+func (r *Rule) matches(containerName string) bool {
+	return r.containers == nil || r.containers.MatchString(containerName)
+}
@@ -0,0 +1,66 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package alerting
+
+import (
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+)
+
+// Memory limits at or above this value mean the container is unlimited.
+const unlimitedMemory = 1 << 62
+
+// metricFunc computes a metric from the two latest stats of a container. prev
+// may be nil. Returns false if the metric is not available.
+type metricFunc func(spec *info.ContainerSpec, prev, cur *info.ContainerStats) (float64, bool)
+
+var metricFuncs = map[string]metricFunc{
+	"cpu_usage_cores":       cpuUsageCores,
+	"cpu_throttled_percent": cpuThrottledPercent,
+	"memory_usage_bytes":    memoryUsageBytes,
+	"memory_usage_percent":  memoryUsagePercent,
+}
+
+func cpuUsageCores(spec *info.ContainerSpec, prev, cur *info.ContainerStats) (float64, bool) {
+	if !spec.HasCpu || prev == nil || !cur.Timestamp.After(prev.Timestamp) || cur.Cpu.Usage.Total < prev.Cpu.Usage.Total {
+		return 0, false
+	}
+	elapsed := cur.Timestamp.Sub(prev.Timestamp).Nanoseconds()
+	return float64(cur.Cpu.Usage.Total-prev.Cpu.Usage.Total) / float64(elapsed), true
+}
+
+// Percentage of the CFS periods of the interval in which the container was throttled.
+func cpuThrottledPercent(spec *info.ContainerSpec, prev, cur *info.ContainerStats) (float64, bool) {
+	if !spec.HasCpu || prev == nil || cur.Cpu.CFS.Periods <= prev.Cpu.CFS.Periods || cur.Cpu.CFS.ThrottledPeriods < prev.Cpu.CFS.ThrottledPeriods {
+		return 0, false
+	}
+	periods := cur.Cpu.CFS.Periods - prev.Cpu.CFS.Periods
+	throttled := cur.Cpu.CFS.ThrottledPeriods - prev.Cpu.CFS.ThrottledPeriods
+	return 100 * float64(throttled) / float64(periods), true
+}
+
+// Working set, which is what the OOM killer acts on.
+func memoryUsageBytes(spec *info.ContainerSpec, prev, cur *info.ContainerStats) (float64, bool) {
+	if !spec.HasMemory {
+		return 0, false
+	}
+	return float64(cur.Memory.WorkingSet), true
+}
+
+func memoryUsagePercent(spec *info.ContainerSpec, prev, cur *info.ContainerStats) (float64, bool) {
+	if !spec.HasMemory || spec.Memory.Limit == 0 || spec.Memory.Limit >= unlimitedMemory {
+		return 0, false
+	}
+	return 100 * float64(cur.Memory.WorkingSet) / float64(spec.Memory.Limit), true
+}
@@ -0,0 +1,129 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package alerting
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+const pagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+// Notifier sends alerts to a notification channel.
+type Notifier interface {
+	Notify(alert *Alert) error
+}
+
+var notifierClient = &http.Client{Timeout: 10 * time.Second}
+
+func newNotifier(config NotifierConfig) (Notifier, error) {
+	switch config.Type {
+	case "webhook", "slack":
+		if config.URL == "" {
+			return nil, fmt.Errorf("%s notifier requires url", config.Type)
+		}
+		if config.Type == "slack" {
+			return &slackNotifier{url: config.URL}, nil
+		}
+		return &webhookNotifier{url: config.URL}, nil
+	case "pagerduty":
+		if config.RoutingKey == "" {
+			return nil, fmt.Errorf("pagerduty notifier requires routing_key")
+		}
+		url := config.URL
+		if url == "" {
+			url = pagerDutyEventsURL
+		}
+		return &pagerDutyNotifier{url: url, routingKey: config.RoutingKey}, nil
+	}
+	return nil, fmt.Errorf("unknown notifier type %q", config.Type)
+}
+
+func postJSON(url string, body interface{}) error {
+	content, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	resp, err := notifierClient.Post(url, "application/json", bytes.NewReader(content))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s responded with status %s", url, resp.Status)
+	}
+	return nil
+}
+
+// Posts the alert as JSON.
+type webhookNotifier struct {
+	url string
+}
+
+func (n *webhookNotifier) Notify(alert *Alert) error {
+	return postJSON(n.url, alert)
+}
+
+// Posts the alert to a Slack incoming webhook.
+type slackNotifier struct {
+	url string
+}
+
+func (n *slackNotifier) Notify(alert *Alert) error {
+	return postJSON(n.url, map[string]string{"text": alert.Description})
+}
+
+// Triggers and resolves PagerDuty incidents through the Events API v2.
+type pagerDutyNotifier struct {
+	url        string
+	routingKey string
+}
+
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+}
+
+type pagerDutyPayload struct {
+	Summary   string `json:"summary"`
+	Source    string `json:"source"`
+	Severity  string `json:"severity"`
+	Timestamp string `json:"timestamp"`
+}
+
+func (n *pagerDutyNotifier) Notify(alert *Alert) error {
+	event := pagerDutyEvent{
+		RoutingKey:  n.routingKey,
+		EventAction: "resolve",
+		DedupKey:    alert.Rule + ":" + alert.Container,
+	}
+	if alert.State == StateFiring {
+		hostname, _ := os.Hostname()
+		event.EventAction = "trigger"
+		event.Payload = &pagerDutyPayload{
+			Summary:   alert.Description,
+			Source:    hostname,
+			Severity:  "warning",
+			Timestamp: alert.Timestamp.Format(time.RFC3339),
+		}
+	}
+	return postJSON(n.url, event)
+}
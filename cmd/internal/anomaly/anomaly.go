@@ -0,0 +1,217 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package anomaly keeps rolling baselines of container metrics and adds an
+// anomaly event to the manager when a metric leaves its baseline band.
+//
+// The baseline of every metric is an exponentially weighted moving average
+// and variance. A sample is anomalous when its z-score against the baseline
+// exceeds the threshold.
+package anomaly
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+	v2 "github.com/yidoyoon/cadvisor-lite/info/v2"
+	"github.com/yidoyoon/cadvisor-lite/manager"
+
+	"k8s.io/klog/v2"
+)
+
+// Number of samples a baseline needs before anomalies are reported.
+const warmupSamples = 30
+
+// The standard deviation of a baseline is at least this fraction of its mean,
+// so that small changes of a nearly constant metric are not anomalies.
+const minRelativeStdDev = 0.01
+
+type metricFunc func(prev, cur *info.ContainerStats) (float64, bool)
+
+var metricFuncs = map[string]metricFunc{
+	"cpu_usage_cores": func(prev, cur *info.ContainerStats) (float64, bool) {
+		if prev == nil || !cur.Timestamp.After(prev.Timestamp) || cur.Cpu.Usage.Total < prev.Cpu.Usage.Total {
+			return 0, false
+		}
+		elapsed := cur.Timestamp.Sub(prev.Timestamp).Nanoseconds()
+		return float64(cur.Cpu.Usage.Total-prev.Cpu.Usage.Total) / float64(elapsed), true
+	},
+	"memory_working_set_bytes": func(prev, cur *info.ContainerStats) (float64, bool) {
+		return float64(cur.Memory.WorkingSet), true
+	},
+}
+
+// baseline is an exponentially weighted moving average and variance.
+type baseline struct {
+	samples  int
+	mean     float64
+	variance float64
+	// Whether the last sample was anomalous, to report every anomaly once.
+	anomalous bool
+}
+
+// Returns the z-score of x against the baseline, then adds x to the baseline.
+func (b *baseline) add(x, alpha float64) float64 {
+	var z float64
+	if b.samples == 0 {
+		b.mean = x
+	} else {
+		stdDev := math.Max(math.Sqrt(b.variance), minRelativeStdDev*math.Abs(b.mean))
+		if stdDev > 0 {
+			z = (x - b.mean) / stdDev
+		}
+		diff := x - b.mean
+		b.mean += alpha * diff
+		b.variance = (1 - alpha) * (b.variance + alpha*diff*diff)
+	}
+	b.samples++
+	return z
+}
+
+type baselineKey struct {
+	container string
+	metric    string
+}
+
+// Detector periodically checks the latest stats of all containers against
+// their baselines.
+type Detector struct {
+	manager   manager.Manager
+	threshold float64
+	alpha     float64
+
+	baselines map[baselineKey]*baseline
+	// Timestamp of the last stats processed for every container.
+	lastSeen map[string]time.Time
+}
+
+// NewDetector returns a Detector reporting samples whose z-score exceeds
+// threshold. alpha is the weight of new samples in the baselines.
+func NewDetector(m manager.Manager, threshold, alpha float64) (*Detector, error) {
+	if threshold <= 0 {
+		return nil, fmt.Errorf("z-score threshold must be positive, got %v", threshold)
+	}
+	if alpha <= 0 || alpha >= 1 {
+		return nil, fmt.Errorf("EWMA alpha must be between 0 and 1, got %v", alpha)
+	}
+	return &Detector{
+		manager:   m,
+		threshold: threshold,
+		alpha:     alpha,
+		baselines: make(map[baselineKey]*baseline),
+		lastSeen:  make(map[string]time.Time),
+	}, nil
+}
+
+// Start checks the stats of all containers every interval.
+func (d *Detector) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			d.Detect()
+		}
+	}()
+}
+
+// Detect adds the latest stats of all containers to their baselines and
+// reports the anomalous ones.
+func (d *Detector) Detect() {
+	containers, err := d.manager.GetRequestedContainersInfo("/", v2.RequestOptions{
+		IdType:    v2.TypeName,
+		Count:     2,
+		Recursive: true,
+	})
+	if err != nil {
+		// Partial failures still return the available containers.
+		klog.V(4).Infof("Failed to get stats for anomaly detection: %v", err)
+	}
+
+	for name, cont := range containers {
+		if cont == nil || len(cont.Stats) == 0 {
+			continue
+		}
+		cur := cont.Stats[len(cont.Stats)-1]
+		if !cur.Timestamp.After(d.lastSeen[name]) {
+			continue
+		}
+		d.lastSeen[name] = cur.Timestamp
+		var prev *info.ContainerStats
+		if len(cont.Stats) > 1 {
+			prev = cont.Stats[len(cont.Stats)-2]
+		}
+		for metric, f := range metricFuncs {
+			value, ok := f(prev, cur)
+			if !ok {
+				continue
+			}
+			d.check(name, metric, value, cur.Timestamp)
+		}
+	}
+
+	// Forget the containers that are gone.
+	for name := range d.lastSeen {
+		if _, ok := containers[name]; !ok {
+			delete(d.lastSeen, name)
+		}
+	}
+	for key := range d.baselines {
+		if _, ok := d.lastSeen[key.container]; !ok {
+			delete(d.baselines, key)
+		}
+	}
+}
+
+func (d *Detector) check(container, metric string, value float64, timestamp time.Time) {
+	key := baselineKey{container: container, metric: metric}
+	b, ok := d.baselines[key]
+	if !ok {
+		b = &baseline{}
+		d.baselines[key] = b
+	}
+	mean, stdDev := b.mean, math.Sqrt(b.variance)
+	z := b.add(value, d.alpha)
+	if b.samples <= warmupSamples {
+		return
+	}
+	if math.Abs(z) <= d.threshold {
+		b.anomalous = false
+		return
+	}
+	if b.anomalous {
+		return
+	}
+	b.anomalous = true
+
+	klog.V(1).Infof("Anomaly in %s of %q: %v deviates from %v by %.1f standard deviations", metric, container, value, mean, z)
+	err := d.manager.AddEvent(&info.Event{
+		ContainerName: container,
+		Timestamp:     timestamp,
+		EventType:     info.EventAnomaly,
+		EventData: info.EventData{
+			Anomaly: &info.AnomalyEventData{
+				Metric: metric,
+				Value:  value,
+				Mean:   mean,
+				StdDev: stdDev,
+				ZScore: z,
+			},
+		},
+	})
+	if err != nil {
+		klog.Errorf("Failed to add anomaly event: %v", err)
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package anomaly
+
+import (
+	"testing"
+	"time"
+
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+	v2 "github.com/yidoyoon/cadvisor-lite/info/v2"
+	"github.com/yidoyoon/cadvisor-lite/manager"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeManager struct {
+	manager.Manager
+	containers map[string]*info.ContainerInfo
+	events     []*info.Event
+}
+
+func (m *fakeManager) GetRequestedContainersInfo(name string, options v2.RequestOptions) (map[string]*info.ContainerInfo, error) {
+	return m.containers, nil
+}
+
+func (m *fakeManager) AddEvent(event *info.Event) error {
+	m.events = append(m.events, event)
+	return nil
+}
+
+func TestDetect(t *testing.T) {
+	m := &fakeManager{}
+	d, err := NewDetector(m, 3, 0.1)
+	require.NoError(t, err)
+
+	now := time.Now()
+	sample := func(workingSet uint64) {
+		now = now.Add(time.Second)
+		m.containers = map[string]*info.ContainerInfo{
+			"/a": {Stats: []*info.ContainerStats{{Timestamp: now, Memory: info.MemoryStats{WorkingSet: workingSet}}}},
+		}
+		d.Detect()
+	}
+
+	// Establish a baseline around 1000 bytes.
+	for i := 0; i < 2*warmupSamples; i++ {
+		sample(uint64(990 + 20*(i%2)))
+	}
+	assert.Empty(t, m.events)
+
+	// The same sample is only added once.
+	d.Detect()
+	assert.Equal(t, 2*warmupSamples, d.baselines[baselineKey{"/a", "memory_working_set_bytes"}].samples)
+
+	// A spike is reported once while it lasts.
+	sample(5000)
+	sample(5000)
+	require.Len(t, m.events, 1)
+	assert.Equal(t, info.EventAnomaly, m.events[0].EventType)
+	assert.Equal(t, "/a", m.events[0].ContainerName)
+	anomaly := m.events[0].EventData.Anomaly
+	assert.Equal(t, "memory_working_set_bytes", anomaly.Metric)
+	assert.Equal(t, 5000.0, anomaly.Value)
+	assert.InDelta(t, 1000, anomaly.Mean, 20)
+	assert.Greater(t, anomaly.ZScore, 3.0)
+
+	// Baselines of removed containers are dropped.
+	m.containers = nil
+	d.Detect()
+	assert.Empty(t, d.baselines)
+}
+
+func TestNewDetectorInvalid(t *testing.T) {
+	_, err := NewDetector(&fakeManager{}, 0, 0.1)
+	assert.Error(t, err)
+	_, err = NewDetector(&fakeManager{}, 3, 1)
+	assert.Error(t, err)
+}
@@ -0,0 +1,299 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/syslog"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/yidoyoon/cadvisor-lite/cmd/internal/http/identity"
+
+	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+)
+
+// AuditSyslog is the path of the audit log sending the records to the local
+// syslog daemon instead of a file.
+const AuditSyslog = "syslog"
+
+// AuditConfig configures the audit log of the API requests.
+type AuditConfig struct {
+	// Path of the file the records are appended to, or AuditSyslog.
+	Path string
+	// Size in bytes beyond which the file is rotated, zero to never rotate
+	// it.
+	MaxSize int64
+	// Number of rotated files kept, as Path.1 for the most recent one to
+	// Path.<MaxBackups> for the oldest one.
+	MaxBackups int
+	// Fraction of the successful requests recorded, between 0 and 1. The
+	// requests failing or denied are always recorded.
+	SampleRate float64
+}
+
+// AuditRecord is a line of the audit log, describing an API request once it
+// was served.
+type AuditRecord struct {
+	Time time.Time `json:"time"`
+	// Identity of the client, empty if not authenticated.
+	Identity    string `json:"identity,omitempty"`
+	RemoteAddr  string `json:"remote_addr"`
+	Method      string `json:"method"`
+	Path        string `json:"path"`
+	Query       string `json:"query,omitempty"`
+	Version     string `json:"api_version,omitempty"`
+	RequestType string `json:"request_type,omitempty"`
+	// Container named by the path of the request, if any.
+	Container string `json:"container,omitempty"`
+	Status    int    `json:"status"`
+	// Time taken to serve the request, until the end of the stream for
+	// streamed events.
+	LatencySeconds float64 `json:"latency_seconds"`
+}
+
+// AuditLog writes the records of the API requests as JSON lines, to a file
+// rotated by size or to syslog.
+type AuditLog struct {
+	config AuditConfig
+	clock  clock.PassiveClock
+	// Returns a number in [0, 1) to sample the successful requests.
+	random func() float64
+
+	lock sync.Mutex
+	// The syslog writer, or the file the records are appended to.
+	writer io.WriteCloser
+	file   *os.File
+	// Size of the file.
+	size int64
+	// Whether the last record could not be written, to warn once per
+	// failure.
+	failing bool
+}
+
+// NewAuditLog opens the audit log of config.
+func NewAuditLog(config AuditConfig) (*AuditLog, error) {
+	if config.SampleRate < 0 || config.SampleRate > 1 {
+		return nil, fmt.Errorf("audit log sample rate %v not between 0 and 1", config.SampleRate)
+	}
+	if config.MaxSize < 0 || config.MaxBackups < 0 {
+		return nil, fmt.Errorf("negative audit log size or backups")
+	}
+	l := &AuditLog{
+		config: config,
+		clock:  clock.RealClock{},
+		random: rand.Float64,
+	}
+	if config.Path == AuditSyslog {
+		writer, err := syslog.New(syslog.LOG_INFO|syslog.LOG_AUTH, "cadvisor")
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to syslog: %v", err)
+		}
+		l.writer = writer
+		return l, nil
+	}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// open opens the file of the audit log for appending. Must be called with
+// l.lock held, if l is shared.
+func (l *AuditLog) open() error {
+	file, err := os.OpenFile(l.config.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %v", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to open audit log: %v", err)
+	}
+	l.file, l.writer, l.size = file, file, info.Size()
+	return nil
+}
+
+// rotate renames the file of the audit log to Path.1, after shifting the
+// previous backups, and opens a new one. Must be called with l.lock held.
+func (l *AuditLog) rotate() error {
+	if err := l.file.Close(); err != nil {
+		klog.Warningf("Failed to close audit log: %v", err)
+	}
+	l.file, l.writer = nil, nil
+	if l.config.MaxBackups == 0 {
+		if err := os.Remove(l.config.Path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to rotate audit log: %v", err)
+		}
+	} else {
+		for i := l.config.MaxBackups - 1; i > 0; i-- {
+			err := os.Rename(fmt.Sprintf("%s.%d", l.config.Path, i), fmt.Sprintf("%s.%d", l.config.Path, i+1))
+			if err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to rotate audit log: %v", err)
+			}
+		}
+		if err := os.Rename(l.config.Path, l.config.Path+".1"); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to rotate audit log: %v", err)
+		}
+	}
+	return l.open()
+}
+
+// sampled returns whether a request which got status is recorded.
+func (l *AuditLog) sampled(status int) bool {
+	return status >= http.StatusBadRequest || l.config.SampleRate >= 1 || l.random() < l.config.SampleRate
+}
+
+// Record writes record to the audit log, unless it is not sampled.
+func (l *AuditLog) Record(record *AuditRecord) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if !l.sampled(record.Status) {
+		return
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		klog.Errorf("Failed to encode audit record: %v", err)
+		return
+	}
+	line = append(line, '\n')
+	err = l.write(line)
+	if err != nil && !l.failing {
+		klog.Warningf("Failed to write audit log, the requests are not recorded until it succeeds again: %v", err)
+	}
+	l.failing = err != nil
+}
+
+// write writes line, rotating the file before if it would exceed its
+// maximum size. Must be called with l.lock held.
+func (l *AuditLog) write(line []byte) error {
+	if l.config.Path != AuditSyslog {
+		if l.file == nil {
+			// Opening the file failed after the last rotation.
+			if err := l.open(); err != nil {
+				return err
+			}
+		}
+		if l.config.MaxSize > 0 && l.size > 0 && l.size+int64(len(line)) > l.config.MaxSize {
+			if err := l.rotate(); err != nil {
+				return err
+			}
+		}
+	}
+	n, err := l.writer.Write(line)
+	l.size += int64(n)
+	return err
+}
+
+// Close closes the file of the audit log or the connection to syslog.
+func (l *AuditLog) Close() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.writer == nil {
+		return nil
+	}
+	err := l.writer.Close()
+	l.file, l.writer = nil, nil
+	return err
+}
+
+// AuditHandler records the requests served by h in log, along with the
+// identity of the client, once they are served.
+func AuditHandler(h http.Handler, log *AuditLog) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := log.clock.Now()
+		aw := &auditWriter{ResponseWriter: w}
+		defer func() {
+			record := &AuditRecord{
+				Time:           start,
+				RemoteAddr:     r.RemoteAddr,
+				Method:         r.Method,
+				Path:           r.URL.Path,
+				Query:          r.URL.RawQuery,
+				Status:         aw.status,
+				LatencySeconds: log.clock.Since(start).Seconds(),
+			}
+			if record.Status == 0 {
+				// Nothing was written.
+				record.Status = http.StatusOK
+			}
+			record.Identity, _ = identity.FromRequest(r)
+			if requestElements := apiRegexp.FindStringSubmatch(r.URL.Path); len(requestElements) > 0 {
+				record.Version = requestElements[apiVersion]
+				record.RequestType = requestElements[apiRequestType]
+				if args := requestElements[apiRequestArgs]; args != "" && args != "/" {
+					record.Container = args
+				}
+			}
+			log.Record(record)
+		}()
+		h.ServeHTTP(aw, r)
+	})
+}
+
+// auditWriter keeps the status of a response.
+type auditWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *auditWriter) WriteHeader(code int) {
+	// Informational responses precede the actual one.
+	if w.status == 0 && code >= http.StatusOK {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *auditWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush lets the events be streamed through the writer.
+func (w *auditWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack lets the connection be upgraded, e.g. to stream stats over a
+// WebSocket, which is recorded as 101 Switching Protocols.
+func (w *auditWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("connection cannot be hijacked")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err == nil && w.status == 0 {
+		w.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *auditWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
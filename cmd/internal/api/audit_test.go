@@ -0,0 +1,173 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+	testingclock "k8s.io/utils/clock/testing"
+
+	"github.com/yidoyoon/cadvisor-lite/cmd/internal/http/identity"
+)
+
+func readAuditRecords(t *testing.T, path string) []AuditRecord {
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	var records []AuditRecord
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record AuditRecord
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		records = append(records, record)
+	}
+	require.NoError(t, scanner.Err())
+	return records
+}
+
+func TestAuditHandler(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	log, err := NewAuditLog(AuditConfig{Path: path, SampleRate: 1})
+	require.NoError(t, err)
+	defer log.Close()
+	fakeClock := testingclock.NewFakeClock(time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC))
+	log.clock = fakeClock
+
+	policy := &AuthorizationPolicy{Rules: []AuthorizationRule{
+		{Identities: []string{"prometheus"}, RequestTypes: []string{"stats"}},
+	}}
+	handler := AuditHandler(AuthorizationHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fakeClock.Step(250 * time.Millisecond)
+		_, _ = w.Write([]byte("{}"))
+	}), policy), log)
+	for _, id := range []string{"prometheus", ""} {
+		r := httptest.NewRequest(http.MethodGet, "/api/v2.0/stats/docker/abc?count=1", nil)
+		r.RemoteAddr = "10.0.0.1:1234"
+		if id != "" {
+			r = r.WithContext(identity.NewContext(r.Context(), id))
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	records := readAuditRecords(t, path)
+	require.Len(t, records, 2)
+	assert.Equal(t, AuditRecord{
+		Time:           time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC),
+		Identity:       "prometheus",
+		RemoteAddr:     "10.0.0.1:1234",
+		Method:         http.MethodGet,
+		Path:           "/api/v2.0/stats/docker/abc",
+		Query:          "count=1",
+		Version:        "v2.0",
+		RequestType:    "stats",
+		Container:      "/docker/abc",
+		Status:         http.StatusOK,
+		LatencySeconds: 0.25,
+	}, records[0])
+	// The denied requests are recorded too.
+	assert.Equal(t, "", records[1].Identity)
+	assert.Equal(t, http.StatusForbidden, records[1].Status)
+	assert.Equal(t, float64(0), records[1].LatencySeconds)
+}
+
+func TestAuditHandlerWebSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	log, err := NewAuditLog(AuditConfig{Path: path, SampleRate: 1})
+	require.NoError(t, err)
+	defer log.Close()
+
+	server := httptest.NewServer(AuditHandler(websocket.Server{Handler: func(ws *websocket.Conn) {
+		_ = websocket.Message.Send(ws, "hello")
+		ws.Close()
+	}}, log))
+	defer server.Close()
+	ws, err := websocket.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/api/v2.2/stats/docker?stream=true", "", server.URL)
+	require.NoError(t, err)
+	defer ws.Close()
+	var message string
+	require.NoError(t, websocket.Message.Receive(ws, &message))
+	assert.Equal(t, "hello", message)
+
+	require.Eventually(t, func() bool { return len(readAuditRecords(t, path)) == 1 }, 5*time.Second, 10*time.Millisecond)
+	record := readAuditRecords(t, path)[0]
+	assert.Equal(t, http.StatusSwitchingProtocols, record.Status)
+	assert.Equal(t, "stats", record.RequestType)
+}
+
+func TestAuditLogRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	log, err := NewAuditLog(AuditConfig{Path: path, MaxSize: 300, MaxBackups: 2, SampleRate: 1})
+	require.NoError(t, err)
+	defer log.Close()
+
+	record := &AuditRecord{Method: http.MethodGet, Path: "/api/v2.0/machine", Status: http.StatusOK}
+	line, err := json.Marshal(record)
+	require.NoError(t, err)
+	perFile := 300 / (len(line) + 1)
+	for i := 0; i < 4*perFile; i++ {
+		log.Record(record)
+	}
+	for _, p := range []string{path, path + ".1", path + ".2"} {
+		assert.Len(t, readAuditRecords(t, p), perFile, p)
+	}
+	// The oldest records are dropped.
+	_, err = os.Stat(path + ".3")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestAuditLogSampling(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	log, err := NewAuditLog(AuditConfig{Path: path, SampleRate: 0.5})
+	require.NoError(t, err)
+	defer log.Close()
+	random := []float64{0.2, 0.7, 0.9, 0.4}
+	log.random = func() float64 {
+		r := random[0]
+		random = random[1:]
+		return r
+	}
+
+	for _, status := range []int{http.StatusOK, http.StatusOK, http.StatusNotFound, http.StatusOK, http.StatusOK} {
+		log.Record(&AuditRecord{Status: status})
+	}
+	var statuses []int
+	for _, record := range readAuditRecords(t, path) {
+		statuses = append(statuses, record.Status)
+	}
+	// The failed requests are recorded without being sampled.
+	assert.Equal(t, []int{http.StatusOK, http.StatusNotFound, http.StatusOK}, statuses)
+}
+
+func TestNewAuditLogInvalidConfig(t *testing.T) {
+	for _, config := range []AuditConfig{
+		{Path: filepath.Join(t.TempDir(), "audit.log"), SampleRate: 1.5},
+		{Path: filepath.Join(t.TempDir(), "audit.log"), SampleRate: 1, MaxSize: -1},
+		{Path: "/missing/audit.log", SampleRate: 1},
+	} {
+		_, err := NewAuditLog(config)
+		assert.Error(t, err, config)
+	}
+}
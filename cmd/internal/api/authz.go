@@ -0,0 +1,124 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/yidoyoon/cadvisor-lite/cmd/internal/http/identity"
+
+	"k8s.io/klog/v2"
+)
+
+// Matches all the identities or request types in an authorization rule.
+const authorizationWildcard = "*"
+
+// AuthorizationPolicy is the content of the authorization policy file. It
+// restricts the request types of the API each client may access: a request
+// is allowed if any rule allows it.
+type AuthorizationPolicy struct {
+	Rules []AuthorizationRule `json:"rules"`
+}
+
+// AuthorizationRule allows clients to access request types.
+type AuthorizationRule struct {
+	// Identities of the clients, as authenticated by a client certificate or
+	// a bearer token, or "*" for all the clients, authenticated or not.
+	Identities []string `json:"identities"`
+
+	// Request types the clients may access, e.g. "stats", or "*" for all of
+	// them.
+	RequestTypes []string `json:"request_types"`
+}
+
+// ParseAuthorizationPolicy reads and validates the authorization policy file
+// at path.
+func ParseAuthorizationPolicy(path string) (*AuthorizationPolicy, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read authorization policy: %v", err)
+	}
+	policy := &AuthorizationPolicy{}
+	if err := json.Unmarshal(content, policy); err != nil {
+		return nil, fmt.Errorf("failed to parse authorization policy: %v", err)
+	}
+
+	requestTypes := map[string]bool{authorizationWildcard: true}
+	for _, v := range getAPIVersions() {
+		for _, requestType := range v.SupportedRequestTypes() {
+			requestTypes[requestType] = true
+		}
+	}
+	for i, rule := range policy.Rules {
+		if len(rule.Identities) == 0 {
+			return nil, fmt.Errorf("rule %d: missing identities", i)
+		}
+		if len(rule.RequestTypes) == 0 {
+			return nil, fmt.Errorf("rule %d: missing request types", i)
+		}
+		for _, requestType := range rule.RequestTypes {
+			if !requestTypes[requestType] {
+				return nil, fmt.Errorf("rule %d: unknown request type %q", i, requestType)
+			}
+		}
+	}
+	return policy, nil
+}
+
+// Allowed returns whether the client with the given identity, empty if not
+// authenticated, may access requestType.
+func (p *AuthorizationPolicy) Allowed(id, requestType string) bool {
+	for _, rule := range p.Rules {
+		if (id != "" && contains(rule.Identities, id) || contains(rule.Identities, authorizationWildcard)) &&
+			(contains(rule.RequestTypes, requestType) || contains(rule.RequestTypes, authorizationWildcard)) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// AuthorizationHandler rejects the API requests which the policy does not
+// allow the client to make with 403 Forbidden, before they are dispatched to
+// the handler of their request type. The listings of the API versions and of
+// the request types of a version are always allowed.
+func AuthorizationHandler(h http.Handler, policy *AuthorizationPolicy) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestElements := apiRegexp.FindStringSubmatch(r.URL.Path)
+		if len(requestElements) == 0 || requestElements[apiRequestType] == "" {
+			h.ServeHTTP(w, r)
+			return
+		}
+		requestType := requestElements[apiRequestType]
+		id, _ := identity.FromRequest(r)
+		if !policy.Allowed(id, requestType) {
+			klog.V(2).Infof("Denied %s %s from %s by %q", r.Method, r.URL.Path, r.RemoteAddr, id)
+			writeError(w, r, &requestError{status: http.StatusForbidden, code: ErrorForbidden, message: fmt.Sprintf("access to %q requests denied", requestType)})
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,91 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yidoyoon/cadvisor-lite/cmd/internal/http/identity"
+)
+
+func writePolicy(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestParseAuthorizationPolicy(t *testing.T) {
+	policy, err := ParseAuthorizationPolicy(writePolicy(t, `{"rules": [
+		{"identities": ["prometheus"], "request_types": ["stats", "summary"]},
+		{"identities": ["*"], "request_types": ["machine"]}
+	]}`))
+	require.NoError(t, err)
+	assert.Len(t, policy.Rules, 2)
+
+	for _, invalid := range []string{
+		`{"rules": [{"identities": ["prometheus"], "request_types": ["unknown"]}]}`,
+		`{"rules": [{"identities": [], "request_types": ["stats"]}]}`,
+		`{"rules": [{"identities": ["prometheus"]}]}`,
+		`not json`,
+	} {
+		_, err := ParseAuthorizationPolicy(writePolicy(t, invalid))
+		assert.Error(t, err, invalid)
+	}
+	_, err = ParseAuthorizationPolicy("/missing/policy.json")
+	assert.Error(t, err)
+}
+
+func TestAuthorizationHandler(t *testing.T) {
+	policy := &AuthorizationPolicy{Rules: []AuthorizationRule{
+		{Identities: []string{"prometheus"}, RequestTypes: []string{"stats", "summary"}},
+		{Identities: []string{"alice@example.com"}, RequestTypes: []string{"*"}},
+		{Identities: []string{"*"}, RequestTypes: []string{"machine"}},
+	}}
+	handler := AuthorizationHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), policy)
+
+	for _, tc := range []struct {
+		id       string
+		path     string
+		expected int
+	}{
+		{"prometheus", "/api/v2.0/stats/docker", http.StatusOK},
+		{"prometheus", "/api/v1.3/containers/", http.StatusForbidden},
+		{"prometheus", "/api/v2.1/events", http.StatusForbidden},
+		{"prometheus", "/api/v2.0/ps/", http.StatusForbidden},
+		{"alice@example.com", "/api/v2.0/ps/", http.StatusOK},
+		{"bob@example.com", "/api/v2.0/machine", http.StatusOK},
+		{"", "/api/v2.0/machine", http.StatusOK},
+		{"", "/api/v2.0/stats", http.StatusForbidden},
+		// The listings of the versions and request types are allowed.
+		{"", "/api", http.StatusOK},
+		{"", "/api/v2.0", http.StatusOK},
+		{"", "/api/spec.json", http.StatusOK},
+	} {
+		r := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		if tc.id != "" {
+			r = r.WithContext(identity.NewContext(r.Context(), tc.id))
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		assert.Equal(t, tc.expected, w.Code, "%q requesting %s", tc.id, tc.path)
+	}
+}
@@ -0,0 +1,206 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/utils/clock"
+)
+
+var responseCacheTTL = flag.Duration("api_response_cache_ttl", 0, "Duration for which the responses of the stats and machine stats endpoints of the API are cached, by path and query parameters, so that clients polling them with the same options share the work. Zero value disables caching.")
+
+// Maximum number of responses cached.
+const maxCachedResponses = 64
+
+// Request types whose responses are cached, unless they are streamed.
+var cachedRequestTypes = map[string]bool{
+	statsAPI:        true,
+	machineStatsAPI: true,
+}
+
+type cachedResponse struct {
+	header http.Header
+	status int
+	body   []byte
+	stored time.Time
+}
+
+// responseCache caches the successful responses of the API by request. It is
+// safe for concurrent use.
+type responseCache struct {
+	ttl   func() time.Duration
+	clock clock.Clock
+
+	lock      sync.Mutex
+	responses map[string]cachedResponse
+}
+
+var responses = newResponseCache(func() time.Duration { return *responseCacheTTL }, clock.RealClock{})
+
+func newResponseCache(ttl func() time.Duration, clock clock.Clock) *responseCache {
+	return &responseCache{
+		ttl:       ttl,
+		clock:     clock,
+		responses: make(map[string]cachedResponse),
+	}
+}
+
+// requestKey returns the key of the response to a request: its path and its
+// query parameters, sorted, and whether it accepts protobuf.
+func requestKey(r *http.Request) string {
+	key := r.URL.Path + "?" + r.URL.Query().Encode()
+	if acceptsProtobuf(r) {
+		key += " " + protobufContentType
+	}
+	return key
+}
+
+// serve writes the cached response to r if it has not expired. Otherwise it
+// writes the response of handle, and caches it if it succeeded.
+func (c *responseCache) serve(w http.ResponseWriter, r *http.Request, handle func(w http.ResponseWriter) error) error {
+	ttl := c.ttl()
+	if ttl <= 0 {
+		return handle(w)
+	}
+	key := requestKey(r)
+	c.lock.Lock()
+	response, ok := c.responses[key]
+	c.lock.Unlock()
+	now := c.clock.Now()
+	if !ok || now.Sub(response.stored) >= ttl {
+		buffer := &bufferedResponse{header: make(http.Header)}
+		if err := handle(buffer); err != nil {
+			return err
+		}
+		response = cachedResponse{header: buffer.header, status: buffer.status, body: buffer.body.Bytes(), stored: now}
+		if response.status == 0 {
+			response.status = http.StatusOK
+		}
+		if response.status == http.StatusOK || response.status == http.StatusNoContent {
+			c.store(key, response, ttl)
+		}
+	}
+	for name, values := range response.header {
+		w.Header()[name] = values
+	}
+	w.Header().Set("Age", strconv.Itoa(int(now.Sub(response.stored).Seconds())))
+	w.WriteHeader(response.status)
+	_, err := w.Write(response.body)
+	return err
+}
+
+// store caches a response, removing the expired ones when the cache is full,
+// or an arbitrary one if none has expired.
+func (c *responseCache) store(key string, response cachedResponse, ttl time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if _, ok := c.responses[key]; !ok && len(c.responses) >= maxCachedResponses {
+		for k, cached := range c.responses {
+			if response.stored.Sub(cached.stored) >= ttl {
+				delete(c.responses, k)
+			}
+		}
+		for k := range c.responses {
+			if len(c.responses) < maxCachedResponses {
+				break
+			}
+			delete(c.responses, k)
+		}
+	}
+	c.responses[key] = response
+}
+
+// bufferedResponse is a http.ResponseWriter keeping the response in memory.
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferedResponse) Header() http.Header {
+	return b.header
+}
+
+func (b *bufferedResponse) WriteHeader(status int) {
+	if b.status == 0 {
+		b.status = status
+	}
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	b.WriteHeader(http.StatusOK)
+	return b.body.Write(p)
+}
+
+// writeCacheableResult writes res like writeResult, along with its ETag and,
+// if lastModified is set, its Last-Modified time, so that clients can poll it
+// with conditional requests. A 304 Not Modified response is written instead if
+// the client already has it.
+func writeCacheableResult(res interface{}, lastModified time.Time, w http.ResponseWriter, r *http.Request) error {
+	out, contentType, err := encodeResult(res, w, r)
+	if err != nil {
+		return err
+	}
+	// Each encoding of the result has its own ETag.
+	sum := sha256.Sum256(out)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	header := w.Header()
+	header.Set("ETag", etag)
+	// Clients must revalidate the response before using it.
+	header.Set("Cache-Control", "no-cache")
+	if !lastModified.IsZero() {
+		header.Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+	if notModified(r, etag, lastModified) {
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+	header.Set("Content-Type", contentType)
+	_, err = w.Write(out)
+	return err
+}
+
+// notModified returns whether the conditional headers of r match the ETag or
+// the Last-Modified time of the response. If-None-Match takes precedence over
+// If-Modified-Since.
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		for _, tag := range strings.Split(match, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+			if tag == etag || tag == "*" {
+				return true
+			}
+		}
+		return false
+	}
+	if lastModified.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	return !lastModified.Truncate(time.Second).After(since)
+}
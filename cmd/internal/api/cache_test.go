@@ -0,0 +1,152 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	clock "k8s.io/utils/clock/testing"
+
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+	"github.com/yidoyoon/cadvisor-lite/manager/fake"
+	"github.com/yidoyoon/cadvisor-lite/utils/msgpack"
+)
+
+func TestMachineRequestConditional(t *testing.T) {
+	m := fake.NewManager()
+	timestamp := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	m.SetMachineInfo(info.MachineInfo{Timestamp: timestamp, NumCores: 4})
+	api := &version1_0{}
+
+	w := httptest.NewRecorder()
+	assert.NoError(t, api.HandleRequest(machineAPI, []string{}, m, w, makeHTTPRequest("http://localhost:8080/api/v1.0/machine", t)))
+	assert.Equal(t, http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+	assert.Equal(t, "Mon, 01 May 2023 10:00:00 GMT", w.Header().Get("Last-Modified"))
+
+	for _, tc := range []struct {
+		header, value string
+		expected      int
+	}{
+		{"If-None-Match", etag, http.StatusNotModified},
+		{"If-None-Match", `"other", W/` + etag, http.StatusNotModified},
+		{"If-None-Match", `"other"`, http.StatusOK},
+		{"If-Modified-Since", "Mon, 01 May 2023 10:00:00 GMT", http.StatusNotModified},
+		{"If-Modified-Since", "Mon, 01 May 2023 09:59:59 GMT", http.StatusOK},
+	} {
+		r := makeHTTPRequest("http://localhost:8080/api/v1.0/machine", t)
+		r.Header.Set(tc.header, tc.value)
+		w := httptest.NewRecorder()
+		assert.NoError(t, api.HandleRequest(machineAPI, []string{}, m, w, r))
+		assert.Equal(t, tc.expected, w.Code, "%s: %s", tc.header, tc.value)
+		assert.Equal(t, etag, w.Header().Get("ETag"))
+		if tc.expected == http.StatusNotModified {
+			assert.Empty(t, w.Body.String())
+		}
+	}
+
+	// The ETag changes along with the machine info.
+	m.SetMachineInfo(info.MachineInfo{Timestamp: timestamp, NumCores: 8})
+	r := makeHTTPRequest("http://localhost:8080/api/v1.0/machine", t)
+	r.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	assert.NoError(t, api.HandleRequest(machineAPI, []string{}, m, w, r))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEqual(t, etag, w.Header().Get("ETag"))
+}
+
+func TestMachineRequestMsgpack(t *testing.T) {
+	m := fake.NewManager()
+	m.SetMachineInfo(info.MachineInfo{Timestamp: time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC), NumCores: 4})
+	api := &version1_0{}
+
+	w := httptest.NewRecorder()
+	assert.NoError(t, api.HandleRequest(machineAPI, []string{}, m, w, makeHTTPRequest("http://localhost:8080/api/v1.0/machine", t)))
+	expected, err := msgpack.FromJSON(w.Body.Bytes())
+	require.NoError(t, err)
+	jsonETag := w.Header().Get("ETag")
+
+	// The format is honored, with an ETag of its own.
+	w = httptest.NewRecorder()
+	assert.NoError(t, api.HandleRequest(machineAPI, []string{}, m, w, makeHTTPRequest("http://localhost:8080/api/v1.0/machine?format=msgpack", t)))
+	assert.Equal(t, msgpackContentType, w.Header().Get("Content-Type"))
+	assert.Equal(t, expected, w.Body.Bytes())
+	assert.NotEqual(t, jsonETag, w.Header().Get("ETag"))
+
+	r := makeHTTPRequest("http://localhost:8080/api/v1.0/machine?format=xml", t)
+	err = api.HandleRequest(machineAPI, []string{}, m, httptest.NewRecorder(), r)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, newErrorResponse(r, err).Status)
+}
+
+func TestResponseCache(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC))
+	cache := newResponseCache(func() time.Duration { return 2 * time.Second }, fakeClock)
+	calls := 0
+	handle := func(w http.ResponseWriter) error {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		_, err := fmt.Fprintf(w, `{"calls":%d}`, calls)
+		return err
+	}
+	serve := func(url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		assert.NoError(t, cache.serve(w, makeHTTPRequest(url, t), handle))
+		return w
+	}
+
+	assert.Equal(t, `{"calls":1}`, serve("http://localhost:8080/api/v2.1/stats/?count=1&type=name").Body.String())
+	fakeClock.Step(time.Second)
+	// The same options in another order.
+	w := serve("http://localhost:8080/api/v2.1/stats/?type=name&count=1")
+	assert.Equal(t, `{"calls":1}`, w.Body.String())
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.Equal(t, "1", w.Header().Get("Age"))
+	assert.Equal(t, `{"calls":2}`, serve("http://localhost:8080/api/v2.1/stats/?count=2&type=name").Body.String())
+
+	// Expired.
+	fakeClock.Step(time.Second)
+	assert.Equal(t, `{"calls":3}`, serve("http://localhost:8080/api/v2.1/stats/?count=1&type=name").Body.String())
+
+	// Errors are not cached.
+	failing := func(w http.ResponseWriter) error {
+		calls++
+		return fmt.Errorf("unknown container")
+	}
+	for i := 0; i < 2; i++ {
+		assert.Error(t, cache.serve(httptest.NewRecorder(), makeHTTPRequest("http://localhost:8080/api/v2.1/stats/missing", t), failing))
+	}
+	assert.Equal(t, 5, calls)
+}
+
+func TestResponseCacheSize(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC))
+	cache := newResponseCache(func() time.Duration { return time.Minute }, fakeClock)
+	handle := func(w http.ResponseWriter) error {
+		_, err := w.Write([]byte("{}"))
+		return err
+	}
+	for i := 0; i < 2*maxCachedResponses; i++ {
+		assert.NoError(t, cache.serve(httptest.NewRecorder(), makeHTTPRequest(fmt.Sprintf("http://localhost:8080/api/v2.1/stats/c%d", i), t), handle))
+	}
+	assert.Len(t, cache.responses, maxCachedResponses)
+}
@@ -0,0 +1,83 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// deprecation tells since when a version is deprecated, when it is going to
+// be removed and which version replaces it.
+type deprecation struct {
+	since     time.Time
+	sunset    time.Time
+	successor string
+}
+
+// Deprecated versions. Their responses carry the Deprecation, Sunset and Link
+// headers of RFC 9745 and RFC 8594.
+var deprecations = map[string]deprecation{
+	"v1.0": {since: time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC), sunset: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), successor: "v1.3"},
+	"v1.1": {since: time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC), sunset: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), successor: "v1.3"},
+	"v1.2": {since: time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC), sunset: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), successor: "v1.3"},
+	"v2.0": {since: time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC), sunset: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), successor: "v2.1"},
+}
+
+// VersionInfo describes an API version, as listed by /api.
+type VersionInfo struct {
+	Version string `json:"version"`
+	// Request types supported by the version, sorted.
+	RequestTypes []string `json:"request_types"`
+	Deprecated   bool     `json:"deprecated"`
+	// Set for the deprecated versions: when the version is going to be
+	// removed, and the version to use instead.
+	Sunset    *time.Time `json:"sunset,omitempty"`
+	Successor string     `json:"successor,omitempty"`
+}
+
+// listVersions returns the versions, sorted, with their request types and
+// deprecation status.
+func listVersions(apiVersions []ApiVersion) []VersionInfo {
+	versions := make([]VersionInfo, 0, len(apiVersions))
+	for _, v := range apiVersions {
+		requestTypes := v.SupportedRequestTypes()
+		sort.Strings(requestTypes)
+		info := VersionInfo{Version: v.Version(), RequestTypes: requestTypes}
+		if d, ok := deprecations[v.Version()]; ok {
+			sunset := d.sunset
+			info.Deprecated = true
+			info.Sunset = &sunset
+			info.Successor = d.successor
+		}
+		versions = append(versions, info)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].Version < versions[j].Version })
+	return versions
+}
+
+// setDeprecationHeaders tells the clients of a deprecated version when it is
+// going to be removed and where its successor is.
+func setDeprecationHeaders(version string, w http.ResponseWriter) {
+	d, ok := deprecations[version]
+	if !ok {
+		return
+	}
+	w.Header().Set("Deprecation", fmt.Sprintf("@%d", d.since.Unix()))
+	w.Header().Set("Sunset", d.sunset.Format(http.TimeFormat))
+	w.Header().Set("Link", fmt.Sprintf("<%s%s/>; rel=\"successor-version\"", apiResource, d.successor))
+}
@@ -0,0 +1,67 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListVersions(t *testing.T) {
+	mux := http.NewServeMux()
+	require.NoError(t, RegisterHandlers(mux, nil))
+	for _, resource := range []string{"/api", "/api/"} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, resource, nil))
+		require.Equal(t, http.StatusOK, w.Code, resource)
+		assert.NotEmpty(t, w.Header().Get("ETag"))
+
+		var versions []VersionInfo
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &versions))
+		require.Len(t, versions, len(getAPIVersions()))
+		assert.Equal(t, "v1.0", versions[0].Version)
+		assert.True(t, versions[0].Deprecated)
+		assert.Equal(t, "v1.3", versions[0].Successor)
+		assert.True(t, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC).Equal(*versions[0].Sunset))
+
+		latest := versions[len(versions)-1]
+		assert.Equal(t, "v3.0", latest.Version)
+		assert.False(t, latest.Deprecated)
+		assert.Nil(t, latest.Sunset)
+		assert.Contains(t, latest.RequestTypes, statsAPI)
+	}
+}
+
+func TestDeprecationHeaders(t *testing.T) {
+	mux := http.NewServeMux()
+	require.NoError(t, RegisterHandlers(mux, nil))
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v2.0/", nil))
+	assert.Equal(t, "@1685577600", w.Header().Get("Deprecation"))
+	assert.Equal(t, "Sat, 01 Jun 2024 00:00:00 GMT", w.Header().Get("Sunset"))
+	assert.Equal(t, `</api/v2.1/>; rel="successor-version"`, w.Header().Get("Link"))
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v2.1/", nil))
+	assert.Empty(t, w.Header().Get("Deprecation"))
+	assert.Empty(t, w.Header().Get("Sunset"))
+}
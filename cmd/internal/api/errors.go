@@ -0,0 +1,154 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+
+	"k8s.io/klog/v2"
+
+	"github.com/yidoyoon/cadvisor-lite/manager"
+)
+
+var plainTextErrors = flag.Bool("api_plain_text_errors", false, "Answer the failed API requests with the message of the error as plain text, for the clients parsing it, instead of a JSON error object.")
+
+// Codes of the errors of the API, telling clients how to handle them
+// without parsing their message.
+const (
+	// A query parameter or field of the body is invalid.
+	ErrorInvalidOption = "invalid_option"
+	// The body of the request is invalid.
+	ErrorInvalidRequest = "invalid_request"
+	// The authorization policy does not allow the client to make the
+	// request.
+	ErrorForbidden = "forbidden"
+	// The body of the request exceeds its limit.
+	ErrorRequestTooLarge = "request_too_large"
+	// The version, request type or container runtime is unknown.
+	ErrorNotFound = "not_found"
+	// The container is unknown, e.g. it was removed.
+	ErrorContainerNotFound = "container_not_found"
+	// cAdvisor failed to serve the request.
+	ErrorInternal = "internal_error"
+)
+
+// ErrorResponse is the body of the responses of the failed API requests.
+type ErrorResponse struct {
+	// One of the Error constants.
+	Code   string `json:"code"`
+	Status int    `json:"status"`
+	// Text of the status, e.g. Bad Request.
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+	// Query parameter or field of the body at fault, if any.
+	Parameter string `json:"parameter,omitempty"`
+	// Container the request is about, if any.
+	Container string `json:"container,omitempty"`
+	// Whether the same request may succeed later.
+	Retryable bool `json:"retryable"`
+}
+
+// requestError is an error of the request of the client, e.g. an invalid
+// option, served with a 4xx status instead of 500 Internal Server Error.
+type requestError struct {
+	status int
+	code   string
+	// Query parameter or field of the body at fault, if any.
+	param   string
+	message string
+}
+
+func (e *requestError) Error() string {
+	return e.message
+}
+
+// badRequest returns a 400 Bad Request error about param, if not empty.
+func badRequest(param string, format string, args ...interface{}) error {
+	code := ErrorInvalidOption
+	if param == "" {
+		code = ErrorInvalidRequest
+	}
+	return &requestError{status: http.StatusBadRequest, code: code, param: param, message: fmt.Sprintf(format, args...)}
+}
+
+// notFound returns a 404 Not Found error.
+func notFound(format string, args ...interface{}) error {
+	return &requestError{status: http.StatusNotFound, code: ErrorNotFound, message: fmt.Sprintf(format, args...)}
+}
+
+// bodyError returns the error of decoding the body of a request, 413 Request
+// Entity Too Large if it exceeded its limit and 400 Bad Request otherwise.
+func bodyError(err error, format string, args ...interface{}) error {
+	message := fmt.Sprintf(format, args...) + ": " + err.Error()
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return &requestError{status: http.StatusRequestEntityTooLarge, code: ErrorRequestTooLarge, message: message}
+	}
+	return &requestError{status: http.StatusBadRequest, code: ErrorInvalidRequest, message: message}
+}
+
+// newErrorResponse returns the response to r failing with err: the status of
+// a requestError, 404 Not Found for an unknown container and a retryable 500
+// Internal Server Error otherwise.
+func newErrorResponse(r *http.Request, err error) *ErrorResponse {
+	resp := &ErrorResponse{
+		Code:      ErrorInternal,
+		Status:    http.StatusInternalServerError,
+		Message:   err.Error(),
+		Retryable: true,
+	}
+	var reqErr *requestError
+	var notFoundErr *manager.ContainerNotFoundError
+	switch {
+	case errors.As(err, &reqErr):
+		resp.Code, resp.Status, resp.Parameter, resp.Retryable = reqErr.code, reqErr.status, reqErr.param, false
+	case errors.As(err, &notFoundErr):
+		resp.Code, resp.Status, resp.Container, resp.Retryable = ErrorContainerNotFound, http.StatusNotFound, notFoundErr.Name, false
+	}
+	resp.Reason = http.StatusText(resp.Status)
+	if resp.Container == "" {
+		if requestElements := apiRegexp.FindStringSubmatch(r.URL.Path); len(requestElements) > 0 {
+			if args := requestElements[apiRequestArgs]; args != "" && args != "/" {
+				resp.Container = args
+			}
+		}
+	}
+	return resp
+}
+
+// writeError writes the ErrorResponse of r failing with err, or its message
+// as plain text with --api_plain_text_errors.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	resp := newErrorResponse(r, err)
+	if *plainTextErrors {
+		http.Error(w, resp.Message, resp.Status)
+		return
+	}
+	out, err := json.Marshal(resp)
+	if err != nil {
+		http.Error(w, resp.Message, resp.Status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(resp.Status)
+	if _, err := w.Write(out); err != nil {
+		klog.V(4).Infof("Failed to write error response: %v", err)
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yidoyoon/cadvisor-lite/manager/fake"
+)
+
+func TestErrorResponses(t *testing.T) {
+	mux := http.NewServeMux()
+	require.NoError(t, RegisterHandlers(mux, fake.NewManager()))
+
+	for _, tc := range []struct {
+		method, url, body string
+		expected          ErrorResponse
+	}{
+		{
+			method:   http.MethodGet,
+			url:      "/api/v2.0/stats/?count=-5",
+			expected: ErrorResponse{Code: ErrorInvalidOption, Status: http.StatusBadRequest, Reason: "Bad Request", Message: "invalid 'count' option: only -1 to 1048576 allowed, not -5", Parameter: "count"},
+		},
+		{
+			method:   http.MethodGet,
+			url:      "/api/v2.1/stats/?count=0&max_age=1s",
+			expected: ErrorResponse{Code: ErrorInvalidOption, Status: http.StatusBadRequest, Reason: "Bad Request", Message: "invalid 'max_age' option: no stats are returned with 'count' 0", Parameter: "max_age"},
+		},
+		{
+			method:   http.MethodGet,
+			url:      "/api/v2.1/stats/?max_age=-1s",
+			expected: ErrorResponse{Code: ErrorInvalidOption, Status: http.StatusBadRequest, Reason: "Bad Request", Message: "invalid 'max_age' option: negative duration -1s", Parameter: "max_age"},
+		},
+		{
+			method:   http.MethodGet,
+			url:      "/api/v2.1/stats/?aligned=true&count=3",
+			expected: ErrorResponse{Code: ErrorInvalidOption, Status: http.StatusBadRequest, Reason: "Bad Request", Message: "invalid 'count' option: ignored with 'aligned'", Parameter: "count"},
+		},
+		{
+			method:   http.MethodGet,
+			url:      "/api/v2.1/stats/?recursive=maybe",
+			expected: ErrorResponse{Code: ErrorInvalidOption, Status: http.StatusBadRequest, Reason: "Bad Request", Message: `invalid 'recursive' option "maybe": must be true or false`, Parameter: "recursive"},
+		},
+		{
+			method:   http.MethodGet,
+			url:      "/api/v1.3/events/?max_events=many",
+			expected: ErrorResponse{Code: ErrorInvalidOption, Status: http.StatusBadRequest, Reason: "Bad Request", Message: `invalid 'max_events' option "many": only -1 to 1048576 allowed`, Parameter: "max_events"},
+		},
+		{
+			method:   http.MethodPost,
+			url:      "/api/v1.3/containers/",
+			body:     `{"num_stats": 10, "count": 10}`,
+			expected: ErrorResponse{Code: ErrorInvalidRequest, Status: http.StatusBadRequest, Reason: "Bad Request", Message: `unable to decode the json value: json: unknown field "count"`},
+		},
+		{
+			method:   http.MethodPost,
+			url:      "/api/v1.3/containers/",
+			body:     `{"num_stats": -2}`,
+			expected: ErrorResponse{Code: ErrorInvalidOption, Status: http.StatusBadRequest, Reason: "Bad Request", Message: "invalid 'num_stats' -2: only -1 to 1048576 allowed", Parameter: "num_stats"},
+		},
+		{
+			method:   http.MethodPost,
+			url:      "/api/v1.3/containers/",
+			body:     `{"num_stats": 1} {}`,
+			expected: ErrorResponse{Code: ErrorInvalidRequest, Status: http.StatusBadRequest, Reason: "Bad Request", Message: "unable to decode the json value: unexpected data after the request"},
+		},
+		{
+			method:   http.MethodPost,
+			url:      "/api/v1.3/containers/",
+			body:     `{"num_stats": 1` + strings.Repeat(" ", maxInfoRequestBodySize) + `}`,
+			expected: ErrorResponse{Code: ErrorRequestTooLarge, Status: http.StatusRequestEntityTooLarge, Reason: "Request Entity Too Large", Message: "unable to decode the json value: http: request body too large"},
+		},
+		{
+			method:   http.MethodPost,
+			url:      "/api/v1.3/containers/docker/missing",
+			expected: ErrorResponse{Code: ErrorContainerNotFound, Status: http.StatusNotFound, Reason: "Not Found", Message: `failed to get container "/docker/missing" with error: unknown container "/docker/missing"`, Container: "/docker/missing"},
+		},
+		{
+			method:   http.MethodGet,
+			url:      "/api/v2.0/stats/missing?type=docker",
+			expected: ErrorResponse{Code: ErrorContainerNotFound, Status: http.StatusNotFound, Reason: "Not Found", Message: `unable to find container "missing" in "docker" namespace`, Container: "missing"},
+		},
+		{
+			method:   http.MethodGet,
+			url:      "/api/v9.0/machine",
+			expected: ErrorResponse{Code: ErrorNotFound, Status: http.StatusNotFound, Reason: "Not Found", Message: `unsupported API version "v9.0"`},
+		},
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(tc.method, tc.url, strings.NewReader(tc.body)))
+		assert.Equal(t, tc.expected.Status, w.Code, tc.url)
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"), tc.url)
+		var actual ErrorResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &actual), tc.url)
+		assert.Equal(t, tc.expected, actual, tc.url)
+	}
+
+	// Valid requests are served.
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1.3/containers/", strings.NewReader(`{"num_stats": 1}`)))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestPlainTextErrors(t *testing.T) {
+	*plainTextErrors = true
+	defer func() { *plainTextErrors = false }()
+	mux := http.NewServeMux()
+	require.NoError(t, RegisterHandlers(mux, fake.NewManager()))
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v2.0/stats/?count=-5", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "text/plain; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, "invalid 'count' option: only -1 to 1048576 allowed, not -5\n", w.Body.String())
+}
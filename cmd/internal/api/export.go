@@ -0,0 +1,148 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/csv"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+)
+
+const (
+	// Format of the time series exported as CSV, one row per sample.
+	csvArgument    = "csv"
+	csvContentType = "text/csv; charset=utf-8"
+)
+
+// Columns of the CSV export. The cells of the resources which the container
+// does not have are left empty.
+var csvHeader = []string{
+	"container",
+	"timestamp",
+	"cpu_usage_total",
+	"cpu_usage_user",
+	"cpu_usage_system",
+	"memory_usage",
+	"memory_working_set",
+	"memory_rss",
+	"memory_cache",
+	"network_rx_bytes",
+	"network_tx_bytes",
+	"network_rx_packets",
+	"network_tx_packets",
+	"network_rx_errors",
+	"network_tx_errors",
+	"diskio_read_bytes",
+	"diskio_write_bytes",
+	"fs_usage",
+	"fs_limit",
+	"processes",
+	"threads",
+}
+
+// writeStatsCSV writes the stats of containers as CSV, sorted by container
+// name and then by sample, flushing the rows of every container.
+func writeStatsCSV(conts map[string]*info.ContainerInfo, w http.ResponseWriter) error {
+	names := make([]string, 0, len(conts))
+	for name, cont := range conts {
+		if cont != nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	w.Header().Set("Content-Type", csvContentType)
+	out := csv.NewWriter(w)
+	if err := out.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, name := range names {
+		cont := conts[name]
+		for _, stats := range cont.Stats {
+			if err := out.Write(csvRow(name, &cont.Spec, stats)); err != nil {
+				return err
+			}
+		}
+		out.Flush()
+		if err := out.Error(); err != nil {
+			return err
+		}
+		if flusher, ok := w.(http.Flusher); ok {
+			flusher.Flush()
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// csvRow returns the cells of a sample, in the order of csvHeader.
+func csvRow(name string, spec *info.ContainerSpec, stats *info.ContainerStats) []string {
+	row := make([]string, 0, len(csvHeader))
+	row = append(row, name, stats.Timestamp.UTC().Format(time.RFC3339Nano))
+	row = appendCells(row, spec.HasCpu,
+		stats.Cpu.Usage.Total, stats.Cpu.Usage.User, stats.Cpu.Usage.System)
+	row = appendCells(row, spec.HasMemory,
+		stats.Memory.Usage, stats.Memory.WorkingSet, stats.Memory.RSS, stats.Memory.Cache)
+
+	// The counters of the interfaces are summed, those of the default one
+	// are used for the containers not reporting them per interface.
+	interfaces := stats.Network.Interfaces
+	if len(interfaces) == 0 {
+		interfaces = []info.InterfaceStats{stats.Network.InterfaceStats}
+	}
+	var network info.InterfaceStats
+	for _, i := range interfaces {
+		network.RxBytes += i.RxBytes
+		network.TxBytes += i.TxBytes
+		network.RxPackets += i.RxPackets
+		network.TxPackets += i.TxPackets
+		network.RxErrors += i.RxErrors
+		network.TxErrors += i.TxErrors
+	}
+	row = appendCells(row, spec.HasNetwork,
+		network.RxBytes, network.TxBytes, network.RxPackets, network.TxPackets, network.RxErrors, network.TxErrors)
+
+	var readBytes, writeBytes uint64
+	for _, disk := range stats.DiskIo.IoServiceBytes {
+		readBytes += disk.Stats["Read"]
+		writeBytes += disk.Stats["Write"]
+	}
+	row = appendCells(row, spec.HasDiskIo, readBytes, writeBytes)
+
+	var fsUsage, fsLimit uint64
+	for _, fs := range stats.Filesystem {
+		fsUsage += fs.Usage
+		fsLimit += fs.Limit
+	}
+	row = appendCells(row, spec.HasFilesystem, fsUsage, fsLimit)
+	return appendCells(row, spec.HasProcesses, stats.Processes.ProcessCount, stats.Processes.ThreadsCurrent)
+}
+
+// appendCells appends the values to the row, or as many empty cells if the
+// container does not have their resource.
+func appendCells(row []string, has bool, values ...uint64) []string {
+	for _, value := range values {
+		if has {
+			row = append(row, strconv.FormatUint(value, 10))
+		} else {
+			row = append(row, "")
+		}
+	}
+	return row
+}
@@ -0,0 +1,68 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+	"github.com/yidoyoon/cadvisor-lite/manager/fake"
+)
+
+func TestExportCSV(t *testing.T) {
+	m := fake.NewManager()
+	m.AddContainer(info.ContainerReference{Name: "/docker/a"}, info.ContainerSpec{HasCpu: true, HasNetwork: true})
+	start := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 2; i++ {
+		stats := &info.ContainerStats{Timestamp: start.Add(time.Duration(i) * time.Second), Sequence: uint64(i + 1)}
+		stats.Cpu.Usage.Total = uint64(1000 * (i + 1))
+		stats.Network.Interfaces = []info.InterfaceStats{{Name: "eth0", RxBytes: 10}, {Name: "eth1", RxBytes: 5}}
+		require.NoError(t, m.AddStats("/docker/a", stats))
+	}
+	api := newVersion2_2(newVersion2_1(newVersion2_0()))
+
+	r := makeHTTPRequest("http://localhost:8080/api/v2.2/export/csv/docker/a", t)
+	w := httptest.NewRecorder()
+	require.NoError(t, api.HandleRequest(exportAPI, []string{"csv", "docker", "a"}, m, w, r))
+	assert.Equal(t, csvContentType, w.Header().Get("Content-Type"))
+	rows, err := csv.NewReader(w.Body).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+	assert.Equal(t, csvHeader, rows[0])
+	row := make(map[string]string, len(csvHeader))
+	for i, column := range csvHeader {
+		row[column] = rows[2][i]
+	}
+	assert.Equal(t, "/docker/a", row["container"])
+	assert.Equal(t, "2023-05-01T10:00:01Z", row["timestamp"])
+	assert.Equal(t, "2000", row["cpu_usage_total"])
+	// The interfaces are summed.
+	assert.Equal(t, "15", row["network_rx_bytes"])
+	// The resources which the container does not have are left empty.
+	assert.Equal(t, "", row["memory_usage"])
+
+	r = makeHTTPRequest("http://localhost:8080/api/v2.2/export/xml/docker/a", t)
+	w = httptest.NewRecorder()
+	err = api.HandleRequest(exportAPI, []string{"xml", "docker", "a"}, m, w, r)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusNotFound, newErrorResponse(r, err).Status)
+}
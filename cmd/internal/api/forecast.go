@@ -0,0 +1,152 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+	v2 "github.com/yidoyoon/cadvisor-lite/info/v2"
+	"github.com/yidoyoon/cadvisor-lite/utils/forecast"
+)
+
+const (
+	forecastLinear = "linear"
+	forecastHolt   = "holt"
+
+	// Smoothing factors of the level and trend for forecastHolt.
+	holtAlpha = 0.5
+	holtBeta  = 0.3
+
+	defaultForecastHorizon = time.Hour
+
+	// Memory limits at or above this value mean the container is unlimited.
+	unlimitedMemory = 1 << 62
+)
+
+type forecastOptions struct {
+	method  string
+	horizon time.Duration
+}
+
+func getForecastOptions(r *http.Request) (forecastOptions, error) {
+	opt := forecastOptions{
+		method:  forecastLinear,
+		horizon: defaultForecastHorizon,
+	}
+	if method := r.URL.Query().Get("method"); method != "" {
+		if method != forecastLinear && method != forecastHolt {
+			return opt, badRequest("method", "unknown 'method' %q", method)
+		}
+		opt.method = method
+	}
+	if horizon := r.URL.Query().Get("horizon"); horizon != "" {
+		d, err := time.ParseDuration(horizon)
+		if err != nil || d <= 0 {
+			return opt, badRequest("horizon", "invalid 'horizon' option %q", horizon)
+		}
+		opt.horizon = d
+	}
+	return opt, nil
+}
+
+func fitTrend(samples []forecast.Sample, method string) (forecast.Trend, error) {
+	if method == forecastHolt {
+		return forecast.Holt(samples, holtAlpha, holtBeta)
+	}
+	return forecast.Linear(samples)
+}
+
+func resourceForecast(samples []forecast.Sample, limit uint64, opt forecastOptions) (*v2.ResourceForecast, error) {
+	trend, err := fitTrend(samples, opt.method)
+	if err != nil {
+		return nil, err
+	}
+	result := &v2.ResourceForecast{
+		Usage:          uint64(samples[len(samples)-1].Value),
+		Limit:          limit,
+		GrowthRate:     trend.Rate,
+		ProjectedUsage: trend.At(trend.Timestamp.Add(opt.horizon)),
+	}
+	if limit > 0 {
+		if toLimit, ok := trend.TimeTo(float64(limit)); ok {
+			seconds := toLimit.Seconds()
+			result.SecondsToLimit = &seconds
+		}
+	}
+	return result, nil
+}
+
+// Fits trends on the memory and filesystem usage of a container.
+func containerForecast(cont *info.ContainerInfo, opt forecastOptions) (*v2.ContainerForecast, error) {
+	// Only keep samples in increasing time order.
+	var stats []*info.ContainerStats
+	for _, s := range cont.Stats {
+		if len(stats) == 0 || s.Timestamp.After(stats[len(stats)-1].Timestamp) {
+			stats = append(stats, s)
+		}
+	}
+	if len(stats) < 2 {
+		return nil, fmt.Errorf("not enough stats to forecast container %q, got %d", cont.Name, len(stats))
+	}
+	result := &v2.ContainerForecast{
+		Timestamp: stats[len(stats)-1].Timestamp,
+		Samples:   len(stats),
+		Method:    opt.method,
+	}
+
+	if cont.Spec.HasMemory {
+		samples := make([]forecast.Sample, len(stats))
+		for i, s := range stats {
+			samples[i] = forecast.Sample{Timestamp: s.Timestamp, Value: float64(s.Memory.WorkingSet)}
+		}
+		limit := cont.Spec.Memory.Limit
+		if limit >= unlimitedMemory {
+			limit = 0
+		}
+		memory, err := resourceForecast(samples, limit, opt)
+		if err != nil {
+			return nil, err
+		}
+		result.Memory = memory
+	}
+
+	if cont.Spec.HasFilesystem {
+		fsSamples := make(map[string][]forecast.Sample)
+		fsLimits := make(map[string]uint64)
+		for _, s := range stats {
+			for _, fs := range s.Filesystem {
+				fsSamples[fs.Device] = append(fsSamples[fs.Device], forecast.Sample{Timestamp: s.Timestamp, Value: float64(fs.Usage)})
+				fsLimits[fs.Device] = fs.Limit
+			}
+		}
+		for device, samples := range fsSamples {
+			if len(samples) < 2 {
+				continue
+			}
+			fs, err := resourceForecast(samples, fsLimits[device], opt)
+			if err != nil {
+				return nil, err
+			}
+			if result.Filesystem == nil {
+				result.Filesystem = make(map[string]v2.ResourceForecast)
+			}
+			result.Filesystem[device] = *fs
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,74 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"testing"
+	"time"
+
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContainerForecast(t *testing.T) {
+	start := time.Now()
+	cont := &info.ContainerInfo{
+		ContainerReference: info.ContainerReference{Name: "/a"},
+		Spec: info.ContainerSpec{
+			HasMemory:     true,
+			Memory:        info.MemorySpec{Limit: 2000},
+			HasFilesystem: true,
+		},
+	}
+	// Memory grows by 1 byte per second, the filesystem is constant.
+	for i := 0; i < 10; i++ {
+		cont.Stats = append(cont.Stats, &info.ContainerStats{
+			Timestamp:  start.Add(time.Duration(i) * time.Second),
+			Memory:     info.MemoryStats{WorkingSet: uint64(1000 + i)},
+			Filesystem: []info.FsStats{{Device: "/dev/sda1", Limit: 100, Usage: 50}},
+		})
+	}
+
+	forecast, err := containerForecast(cont, forecastOptions{method: forecastLinear, horizon: time.Minute})
+	require.NoError(t, err)
+	assert.Equal(t, 10, forecast.Samples)
+	require.NotNil(t, forecast.Memory)
+	assert.Equal(t, uint64(1009), forecast.Memory.Usage)
+	assert.InDelta(t, 1, forecast.Memory.GrowthRate, 1e-9)
+	assert.InDelta(t, 1069, forecast.Memory.ProjectedUsage, 1e-6)
+	require.NotNil(t, forecast.Memory.SecondsToLimit)
+	assert.InDelta(t, 991, *forecast.Memory.SecondsToLimit, 1e-3)
+
+	fs := forecast.Filesystem["/dev/sda1"]
+	assert.InDelta(t, 0, fs.GrowthRate, 1e-9)
+	assert.Nil(t, fs.SecondsToLimit)
+
+	cont.Stats = cont.Stats[:1]
+	_, err = containerForecast(cont, forecastOptions{method: forecastLinear, horizon: time.Minute})
+	assert.Error(t, err)
+}
+
+func TestGetForecastOptions(t *testing.T) {
+	opt, err := getForecastOptions(makeHTTPRequest("http://localhost:8080/api/v2.2/forecast/a?method=holt&horizon=2h", t))
+	require.NoError(t, err)
+	assert.Equal(t, forecastOptions{method: forecastHolt, horizon: 2 * time.Hour}, opt)
+
+	_, err = getForecastOptions(makeHTTPRequest("http://localhost:8080/api/v2.2/forecast/a?method=arima", t))
+	assert.Error(t, err)
+	_, err = getForecastOptions(makeHTTPRequest("http://localhost:8080/api/v2.2/forecast/a?horizon=-1h", t))
+	assert.Error(t, err)
+}
@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"path"
 	"regexp"
 	"sort"
@@ -32,12 +33,18 @@ import (
 	"github.com/yidoyoon/cadvisor-lite/events"
 	info "github.com/yidoyoon/cadvisor-lite/info/v1"
 	"github.com/yidoyoon/cadvisor-lite/manager"
+	"github.com/yidoyoon/cadvisor-lite/utils/msgpack"
 
+	"google.golang.org/protobuf/proto"
 	"k8s.io/klog/v2"
 )
 
 const (
+	apiPrefix   = "/api"
 	apiResource = "/api/"
+
+	// Media type of the results requested with format=msgpack.
+	msgpackContentType = "application/msgpack"
 )
 
 func RegisterHandlers(mux httpmux.Mux, m manager.Manager) error {
@@ -47,10 +54,18 @@ func RegisterHandlers(mux httpmux.Mux, m manager.Manager) error {
 		supportedAPIVersions[v.Version()] = v
 	}
 
-	mux.HandleFunc(apiResource, func(w http.ResponseWriter, r *http.Request) {
+	handle := func(w http.ResponseWriter, r *http.Request) {
 		err := handleRequest(supportedAPIVersions, m, w, r)
 		if err != nil {
-			http.Error(w, err.Error(), 500)
+			writeError(w, r, err)
+		}
+	}
+	mux.HandleFunc(apiPrefix, handle)
+	mux.HandleFunc(apiResource, handle)
+	spec := getSpec(apiVersions)
+	mux.HandleFunc(specResource, func(w http.ResponseWriter, r *http.Request) {
+		if err := writeCacheableResult(spec, time.Time{}, w, r); err != nil {
+			writeError(w, r, err)
 		}
 	})
 	return nil
@@ -73,27 +88,24 @@ func handleRequest(supportedAPIVersions map[string]ApiVersion, m manager.Manager
 
 	request := r.URL.Path
 
-	const apiPrefix = "/api"
 	if !strings.HasPrefix(request, apiPrefix) {
-		return fmt.Errorf("incomplete API request %q", request)
+		return notFound("incomplete API request %q", request)
 	}
 
 	// If the request doesn't have an API version, list those.
 	if request == apiPrefix || request == apiResource {
-		versions := make([]string, 0, len(supportedAPIVersions))
-		for v := range supportedAPIVersions {
+		versions := make([]ApiVersion, 0, len(supportedAPIVersions))
+		for _, v := range supportedAPIVersions {
 			versions = append(versions, v)
 		}
-		sort.Strings(versions)
-		http.Error(w, fmt.Sprintf("Supported API versions: %s", strings.Join(versions, ",")), http.StatusBadRequest)
-		return nil
+		return writeCacheableResult(listVersions(versions), time.Time{}, w, r)
 	}
 
 	// Verify that we have all the elements we expect:
 	// /<version>/<request type>[/<args...>]
 	requestElements := apiRegexp.FindStringSubmatch(request)
 	if len(requestElements) == 0 {
-		return fmt.Errorf("malformed request %q", request)
+		return notFound("malformed request %q", request)
 	}
 	version := requestElements[apiVersion]
 	requestType := requestElements[apiRequestType]
@@ -102,15 +114,15 @@ func handleRequest(supportedAPIVersions map[string]ApiVersion, m manager.Manager
 	// Check supported versions.
 	versionHandler, ok := supportedAPIVersions[version]
 	if !ok {
-		return fmt.Errorf("unsupported API version %q", version)
+		return notFound("unsupported API version %q", version)
 	}
+	setDeprecationHeaders(version, w)
 
 	// If no request type, list possible request types.
 	if requestType == "" {
 		requestTypes := versionHandler.SupportedRequestTypes()
 		sort.Strings(requestTypes)
-		http.Error(w, fmt.Sprintf("Supported request types: %q", strings.Join(requestTypes, ",")), http.StatusBadRequest)
-		return nil
+		return badRequest("", "Supported request types: %q", strings.Join(requestTypes, ","))
 	}
 
 	// Trim the first empty element from the request.
@@ -118,20 +130,60 @@ func handleRequest(supportedAPIVersions map[string]ApiVersion, m manager.Manager
 		requestArgs = requestArgs[1:]
 	}
 
+	if cachedRequestTypes[requestType] && r.Method == http.MethodGet && r.URL.Query().Get("stream") != "true" {
+		return responses.serve(w, r, func(w http.ResponseWriter) error {
+			return versionHandler.HandleRequest(requestType, requestArgs, m, w, r)
+		})
+	}
 	return versionHandler.HandleRequest(requestType, requestArgs, m, w, r)
 
 }
 
-func writeResult(res interface{}, w http.ResponseWriter) error {
-	out, err := json.Marshal(res)
+// writeResult writes res as JSON, or as protobuf if r accepts it and res has
+// a protobuf message.
+func writeResult(res interface{}, w http.ResponseWriter, r *http.Request) error {
+	out, contentType, err := encodeResult(res, w, r)
 	if err != nil {
-		return fmt.Errorf("failed to marshall response %+v with error: %s", res, err)
+		return err
 	}
-
-	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Type", contentType)
 	_, err = w.Write(out)
 	return err
+}
+
+// encodeResult returns res encoded in the format requested by r, and its
+// media type.
+func encodeResult(res interface{}, w http.ResponseWriter, r *http.Request) ([]byte, string, error) {
+	// An explicit format takes precedence over the Accept header.
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "json":
+	case "msgpack":
+		out, err := msgpack.Marshal(res)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to marshall response %+v with error: %s", res, err)
+		}
+		return out, msgpackContentType, nil
+	default:
+		return nil, "", badRequest("format", "unknown format %q, must be json or msgpack", format)
+	}
 
+	if msg, ok := protobufResult(res); ok {
+		// The encoding depends on the Accept header.
+		w.Header().Add("Vary", "Accept")
+		if acceptsProtobuf(r) {
+			out, err := proto.Marshal(msg)
+			if err != nil {
+				return nil, "", fmt.Errorf("failed to marshall response %+v with error: %s", res, err)
+			}
+			return out, protobufContentType, nil
+		}
+	}
+
+	out, err := json.Marshal(res)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to marshall response %+v with error: %s", res, err)
+	}
+	return out, "application/json", nil
 }
 
 func streamResults(eventChannel *events.EventChannel, w http.ResponseWriter, r *http.Request, m manager.Manager) error {
@@ -160,82 +212,118 @@ func streamResults(eventChannel *events.EventChannel, w http.ResponseWriter, r *
 	}
 }
 
-func getContainerInfoRequest(body io.ReadCloser) (*info.ContainerInfoRequest, error) {
+// getContainerInfoRequest returns the ContainerInfoRequest of the body of r,
+// or the default one if the body is empty. Bodies larger than
+// maxInfoRequestBodySize, with unknown fields or trailing data are rejected.
+func getContainerInfoRequest(w http.ResponseWriter, r *http.Request) (*info.ContainerInfoRequest, error) {
 	query := info.DefaultContainerInfoRequest()
-	decoder := json.NewDecoder(body)
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxInfoRequestBodySize))
+	decoder.DisallowUnknownFields()
 	err := decoder.Decode(&query)
-	if err != nil && err != io.EOF {
-		return nil, fmt.Errorf("unable to decode the json value: %s", err)
+	if err == io.EOF {
+		return &query, nil
+	}
+	if err != nil {
+		return nil, bodyError(err, "unable to decode the json value")
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, badRequest("", "unable to decode the json value: unexpected data after the request")
+	}
+	if query.NumStats < -1 || query.NumStats > maxRequestCount {
+		return nil, badRequest("num_stats", "invalid 'num_stats' %d: only -1 to %d allowed", query.NumStats, maxRequestCount)
+	}
+	if !query.Start.IsZero() && !query.End.IsZero() && query.End.Before(query.Start) {
+		return nil, badRequest("end", "invalid 'end' %s: before 'start' %s", query.End.Format(time.RFC3339), query.Start.Format(time.RFC3339))
 	}
-
 	return &query, nil
 }
 
+// getBoolOption returns the value of the boolean query parameter name of
+// urlMap, the first one if repeated, or def if not set.
+func getBoolOption(urlMap url.Values, name string, def bool) (bool, error) {
+	val, ok := urlMap[name]
+	if !ok {
+		return def, nil
+	}
+	b, err := strconv.ParseBool(val[0])
+	if err != nil {
+		return def, badRequest(name, "invalid '%s' option %q: must be true or false", name, val[0])
+	}
+	return b, nil
+}
+
+// getReclaimBytes returns the reclaim_bytes query parameter of r, the number
+// of bytes a garbage collection reclaims, zero for as many as possible.
+func getReclaimBytes(r *http.Request) (int64, error) {
+	val := r.URL.Query().Get("reclaim_bytes")
+	if val == "" {
+		return 0, nil
+	}
+	reclaim, err := strconv.ParseInt(val, 10, 64)
+	if err != nil || reclaim < 0 {
+		return 0, badRequest("reclaim_bytes", "invalid 'reclaim_bytes' option %q: must be a non-negative integer", val)
+	}
+	return reclaim, nil
+}
+
 // The user can set any or none of the following arguments in any order
 // with any twice defined arguments being assigned the first value.
-// If the value type for the argument is wrong the field will be assumed to be
-// unassigned
+// If the value type for the argument is wrong a 400 Bad Request error is
+// returned.
 // bools: stream, subcontainers, oom_events, creation_events, deletion_events
 // ints: max_events, start_time (unix timestamp), end_time (unix timestamp)
 // example r.URL: http://localhost:8080/api/v1.3/events?oom_events=true&stream=true
 func getEventRequest(r *http.Request) (*events.Request, bool, error) {
 	query := events.NewRequest()
-	stream := false
 
 	urlMap := r.URL.Query()
 
-	if val, ok := urlMap["stream"]; ok {
-		newBool, err := strconv.ParseBool(val[0])
-		if err == nil {
-			stream = newBool
-		}
+	stream, err := getBoolOption(urlMap, "stream", false)
+	if err != nil {
+		return nil, false, err
 	}
-	if val, ok := urlMap["subcontainers"]; ok {
-		newBool, err := strconv.ParseBool(val[0])
-		if err == nil {
-			query.IncludeSubcontainers = newBool
-		}
+	query.IncludeSubcontainers, err = getBoolOption(urlMap, "subcontainers", false)
+	if err != nil {
+		return nil, false, err
 	}
-	eventTypes := map[string]info.EventType{
-		"oom_events":      info.EventOom,
-		"oom_kill_events": info.EventOomKill,
-		"creation_events": info.EventContainerCreation,
-		"deletion_events": info.EventContainerDeletion,
-	}
-	allEventTypes := false
-	if val, ok := urlMap["all_events"]; ok {
-		newBool, err := strconv.ParseBool(val[0])
-		if err == nil {
-			allEventTypes = newBool
-		}
+	allEventTypes, err := getBoolOption(urlMap, "all_events", false)
+	if err != nil {
+		return nil, false, err
 	}
-	for opt, eventType := range eventTypes {
+	for opt, eventType := range events.TypeOptions {
 		if allEventTypes {
 			query.EventType[eventType] = true
-		} else if val, ok := urlMap[opt]; ok {
-			newBool, err := strconv.ParseBool(val[0])
-			if err == nil {
-				query.EventType[eventType] = newBool
+		} else if _, ok := urlMap[opt]; ok {
+			newBool, err := getBoolOption(urlMap, opt, false)
+			if err != nil {
+				return nil, false, err
 			}
+			query.EventType[eventType] = newBool
 		}
 	}
 	if val, ok := urlMap["max_events"]; ok {
 		newInt, err := strconv.Atoi(val[0])
-		if err == nil {
-			query.MaxEventsReturned = int(newInt)
+		if err != nil || newInt < -1 || newInt > maxRequestCount {
+			return nil, false, badRequest("max_events", "invalid 'max_events' option %q: only -1 to %d allowed", val[0], maxRequestCount)
 		}
+		query.MaxEventsReturned = newInt
 	}
 	if val, ok := urlMap["start_time"]; ok {
 		newTime, err := time.Parse(time.RFC3339, val[0])
-		if err == nil {
-			query.StartTime = newTime
+		if err != nil {
+			return nil, false, badRequest("start_time", "failed to parse 'start_time' option: %v", err)
 		}
+		query.StartTime = newTime
 	}
 	if val, ok := urlMap["end_time"]; ok {
 		newTime, err := time.Parse(time.RFC3339, val[0])
-		if err == nil {
-			query.EndTime = newTime
+		if err != nil {
+			return nil, false, badRequest("end_time", "failed to parse 'end_time' option: %v", err)
 		}
+		query.EndTime = newTime
+	}
+	if !query.StartTime.IsZero() && !query.EndTime.IsZero() && query.EndTime.Before(query.StartTime) {
+		return nil, false, badRequest("end_time", "invalid 'end_time' option: before 'start_time'")
 	}
 
 	return query, stream, nil
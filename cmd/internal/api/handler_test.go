@@ -0,0 +1,61 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+	"github.com/yidoyoon/cadvisor-lite/manager/fake"
+	"github.com/yidoyoon/cadvisor-lite/utils/msgpack"
+)
+
+func TestMsgpackFormat(t *testing.T) {
+	m := fake.NewManager()
+	m.AddContainer(info.ContainerReference{Name: "/docker/a"}, info.ContainerSpec{HasCpu: true})
+	stats := &info.ContainerStats{Timestamp: time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC), Sequence: 1}
+	stats.Cpu.Usage.Total = 1000
+	require.NoError(t, m.AddStats("/docker/a", stats))
+	api := newVersion2_2(newVersion2_1(newVersion2_0()))
+
+	r := makeHTTPRequest("http://localhost:8080/api/v2.1/stats/docker/a?format=json", t)
+	w := httptest.NewRecorder()
+	require.NoError(t, api.HandleRequest(statsAPI, []string{"docker", "a"}, m, w, r))
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	expected, err := msgpack.FromJSON(w.Body.Bytes())
+	require.NoError(t, err)
+
+	// The format takes precedence over the Accept header.
+	r = makeHTTPRequest("http://localhost:8080/api/v2.1/stats/docker/a?format=msgpack", t)
+	r.Header.Set("Accept", "application/protobuf")
+	w = httptest.NewRecorder()
+	require.NoError(t, api.HandleRequest(statsAPI, []string{"docker", "a"}, m, w, r))
+	assert.Equal(t, msgpackContentType, w.Header().Get("Content-Type"))
+	assert.Equal(t, expected, w.Body.Bytes())
+
+	r = makeHTTPRequest("http://localhost:8080/api/v2.1/stats/docker/a?format=xml", t)
+	w = httptest.NewRecorder()
+	err = api.HandleRequest(statsAPI, []string{"docker", "a"}, m, w, r)
+	require.Error(t, err)
+	resp := newErrorResponse(r, err)
+	assert.Equal(t, http.StatusBadRequest, resp.Status)
+	assert.Equal(t, "format", resp.Parameter)
+}
@@ -0,0 +1,94 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/base64"
+	"net/http"
+	"sort"
+	"strconv"
+
+	v2 "github.com/yidoyoon/cadvisor-lite/info/v2"
+)
+
+const (
+	// Number of containers per page when the limit is not set.
+	defaultPageLimit = 100
+	maxPageLimit     = 1000
+)
+
+// Page is the envelope of the paginated responses of the v3 API.
+type Page struct {
+	// Containers of the page, sorted by name.
+	Items interface{} `json:"items"`
+	// Cursor of the next page, empty on the last page.
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// ContainerItem is a container of a page of the v3 API.
+type ContainerItem struct {
+	Name string `json:"name"`
+	// Set by the containers and stats endpoints.
+	Spec *v2.ContainerSpec `json:"spec,omitempty"`
+	// Set by the stats endpoint.
+	Stats []*v2.ContainerStats `json:"stats,omitempty"`
+}
+
+type pageOptions struct {
+	// Maximum number of containers of the page.
+	limit int
+	// Name of the last container of the previous page, empty for the first
+	// page.
+	after string
+}
+
+// getPageOptions returns the pagination options of a HTTP request, from the
+// limit and cursor parameters.
+func getPageOptions(r *http.Request) (pageOptions, error) {
+	opt := pageOptions{limit: defaultPageLimit}
+	if limit := r.URL.Query().Get("limit"); len(limit) != 0 {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > maxPageLimit {
+			return opt, badRequest("limit", "invalid 'limit' option %q: must be between 1 and %d", limit, maxPageLimit)
+		}
+		opt.limit = n
+	}
+	if cursor := r.URL.Query().Get("cursor"); len(cursor) != 0 {
+		after, err := base64.RawURLEncoding.DecodeString(cursor)
+		if err != nil || len(after) == 0 {
+			return opt, badRequest("cursor", "invalid 'cursor' option %q", cursor)
+		}
+		opt.after = string(after)
+	}
+	return opt, nil
+}
+
+// paginate returns the names of the page, in order, and the cursor of the
+// next page. The cursor is the last name of the page, so that pages stay
+// consistent while containers are added and removed: the containers after
+// the cursor are returned, whatever happened before it.
+func paginate(names []string, opt pageOptions) ([]string, string) {
+	sort.Strings(names)
+	start := sort.SearchStrings(names, opt.after)
+	if start < len(names) && names[start] == opt.after {
+		start++
+	}
+	names = names[start:]
+	if len(names) <= opt.limit {
+		return names, ""
+	}
+	names = names[:opt.limit]
+	return names, base64.RawURLEncoding.EncodeToString([]byte(names[len(names)-1]))
+}
@@ -0,0 +1,97 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"sort"
+
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+	v2 "github.com/yidoyoon/cadvisor-lite/info/v2"
+	"github.com/yidoyoon/cadvisor-lite/summary"
+
+	"k8s.io/klog/v2"
+)
+
+// podsUsage groups the containers by the UID of their pod and returns the
+// usage of the pods, sorted by namespace and name. Only the pods of the
+// namespace are returned, unless it is empty. Containers without pod labels
+// are ignored.
+func podsUsage(conts map[string]*info.ContainerInfo, namespace string) []v2.PodUsage {
+	pods := make(map[string]*v2.PodUsage)
+	for name, cont := range conts {
+		if cont == nil {
+			continue
+		}
+		labels := cont.Spec.Labels
+		uid := labels[v2.PodUIDLabel]
+		if uid == "" || (namespace != "" && labels[v2.PodNamespaceLabel] != namespace) {
+			continue
+		}
+		pod, ok := pods[uid]
+		if !ok {
+			pod = &v2.PodUsage{UID: uid, Name: labels[v2.PodNameLabel], Namespace: labels[v2.PodNamespaceLabel]}
+			pods[uid] = pod
+		}
+		pod.Containers = append(pod.Containers, name)
+		if len(cont.Stats) == 0 {
+			continue
+		}
+		latest := cont.Stats[len(cont.Stats)-1]
+		if latest.Timestamp.After(pod.Timestamp) {
+			pod.Timestamp = latest.Timestamp
+		}
+		if cont.Spec.HasMemory {
+			pod.MemoryUsage += latest.Memory.Usage
+			pod.MemoryWorkingSet += latest.Memory.WorkingSet
+		}
+		if len(cont.Stats) < 2 {
+			continue
+		}
+		rates, err := summary.GetContainerRates(&cont.Spec, cont.Stats[len(cont.Stats)-2:])
+		if err != nil {
+			klog.V(4).Infof("Failed to compute the rates of container %q: %v", name, err)
+			continue
+		}
+		if rates.Cpu != nil {
+			if pod.Cpu == nil {
+				pod.Cpu = &v2.CpuRates{}
+			}
+			pod.Cpu.Usage += rates.Cpu.Usage
+			pod.Cpu.User += rates.Cpu.User
+			pod.Cpu.System += rates.Cpu.System
+		}
+		if rates.Network != nil {
+			if pod.Network == nil || rates.Network.RxBytes+rates.Network.TxBytes > pod.Network.RxBytes+pod.Network.TxBytes {
+				pod.Network = rates.Network
+			}
+		}
+	}
+
+	result := make([]v2.PodUsage, 0, len(pods))
+	for _, pod := range pods {
+		sort.Strings(pod.Containers)
+		result = append(result, *pod)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Namespace != result[j].Namespace {
+			return result[i].Namespace < result[j].Namespace
+		}
+		if result[i].Name != result[j].Name {
+			return result[i].Name < result[j].Name
+		}
+		return result[i].UID < result[j].UID
+	})
+	return result
+}
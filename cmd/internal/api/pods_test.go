@@ -0,0 +1,90 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+	v2 "github.com/yidoyoon/cadvisor-lite/info/v2"
+	"github.com/yidoyoon/cadvisor-lite/manager/fake"
+)
+
+func TestPodsRequest(t *testing.T) {
+	m := fake.NewManager()
+	m.AddContainer(info.ContainerReference{Name: "/"}, info.ContainerSpec{HasCpu: true, HasMemory: true})
+	start := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	for _, c := range []struct {
+		name, uid, pod, namespace string
+		cpu, memory, rxBytes      uint64
+	}{
+		{"/docker/web-sandbox", "uid-web", "web", "prod", 0, 1 << 20, 2000},
+		{"/docker/web-app", "uid-web", "web", "prod", 2000000000, 100 << 20, 2000},
+		{"/docker/web-sidecar", "uid-web", "web", "prod", 500000000, 10 << 20, 2000},
+		{"/docker/db", "uid-db", "db", "prod", 1000000000, 500 << 20, 0},
+		{"/docker/batch", "uid-batch", "batch", "jobs", 1000000000, 1 << 20, 0},
+		{"/docker/standalone", "", "", "", 1000000000, 1 << 20, 0},
+	} {
+		spec := info.ContainerSpec{HasCpu: true, HasMemory: true, HasNetwork: true}
+		if c.uid != "" {
+			spec.Labels = map[string]string{v2.PodUIDLabel: c.uid, v2.PodNameLabel: c.pod, v2.PodNamespaceLabel: c.namespace}
+		}
+		m.AddContainer(info.ContainerReference{Name: c.name}, spec)
+		for i := uint64(0); i < 2; i++ {
+			stats := &info.ContainerStats{Timestamp: start.Add(time.Duration(i) * time.Second), Sequence: i + 1}
+			stats.Cpu.Usage.Total = i * c.cpu
+			stats.Memory.Usage = c.memory
+			stats.Memory.WorkingSet = c.memory / 2
+			stats.Network.Interfaces = []info.InterfaceStats{{Name: "eth0", RxBytes: i * c.rxBytes}}
+			require.NoError(t, m.AddStats(c.name, stats))
+		}
+	}
+	api := newVersion2_2(newVersion2_1(newVersion2_0()))
+
+	r := makeHTTPRequest("http://localhost:8080/api/v2.2/pods", t)
+	w := httptest.NewRecorder()
+	require.NoError(t, api.HandleRequest(podsAPI, []string{""}, m, w, r))
+	var pods []v2.PodUsage
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &pods))
+	require.Len(t, pods, 3)
+	assert.Equal(t, "batch", pods[0].Name)
+	assert.Equal(t, "db", pods[1].Name)
+	web := pods[2]
+	assert.Equal(t, "uid-web", web.UID)
+	assert.Equal(t, "prod", web.Namespace)
+	assert.Equal(t, start.Add(time.Second), web.Timestamp)
+	assert.Equal(t, []string{"/docker/web-app", "/docker/web-sandbox", "/docker/web-sidecar"}, web.Containers)
+	require.NotNil(t, web.Cpu)
+	assert.InDelta(t, 2.5, web.Cpu.Usage, 1e-9)
+	assert.Equal(t, uint64(111<<20), web.MemoryUsage)
+	assert.Equal(t, uint64(111<<19), web.MemoryWorkingSet)
+	// The network is shared by the containers of the pod, not summed.
+	require.NotNil(t, web.Network)
+	assert.InDelta(t, 2000, web.Network.RxBytes, 1e-9)
+
+	// The pods can be filtered by namespace.
+	r = makeHTTPRequest("http://localhost:8080/api/v2.2/pods/jobs", t)
+	w = httptest.NewRecorder()
+	require.NoError(t, api.HandleRequest(podsAPI, []string{"jobs"}, m, w, r))
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &pods))
+	require.Len(t, pods, 1)
+	assert.Equal(t, "uid-batch", pods[0].UID)
+}
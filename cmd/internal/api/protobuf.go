@@ -0,0 +1,87 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/yidoyoon/cadvisor-lite/grpcapi"
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+	v2 "github.com/yidoyoon/cadvisor-lite/info/v2"
+)
+
+// Media type of the results encoded as the protobuf messages of the gRPC
+// API, defined in grpcapi/cadvisor.proto.
+const protobufContentType = "application/protobuf"
+
+// acceptsProtobuf returns whether the Accept header of r lists the protobuf
+// media type, application/x-protobuf being accepted as an alias.
+func acceptsProtobuf(r *http.Request) bool {
+	for _, accept := range r.Header.Values("Accept") {
+		for _, mediaRange := range strings.Split(accept, ",") {
+			mediaType, params, err := mime.ParseMediaType(mediaRange)
+			if err != nil || params["q"] == "0" {
+				continue
+			}
+			if mediaType == protobufContentType || mediaType == "application/x-protobuf" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// protobufResult returns the protobuf message of a result, if it has one:
+// a ContainerInfo for a container, a ContainerInfoResponse for containers,
+// sorted by name.
+func protobufResult(res interface{}) (proto.Message, bool) {
+	switch res := res.(type) {
+	case info.ContainerInfo:
+		return grpcapi.ContainerInfoFromV1(&res), true
+	case []*info.ContainerInfo:
+		out := &grpcapi.ContainerInfoResponse{Containers: make([]*grpcapi.ContainerInfo, 0, len(res))}
+		for _, cinfo := range res {
+			out.Containers = append(out.Containers, grpcapi.ContainerInfoFromV1(cinfo))
+		}
+		return out, true
+	case map[string]info.ContainerInfo:
+		out := &grpcapi.ContainerInfoResponse{Containers: make([]*grpcapi.ContainerInfo, 0, len(res))}
+		for _, cinfo := range res {
+			cinfo := cinfo
+			out.Containers = append(out.Containers, grpcapi.ContainerInfoFromV1(&cinfo))
+		}
+		sortContainers(out)
+		return out, true
+	case map[string]v2.ContainerInfo:
+		out := &grpcapi.ContainerInfoResponse{Containers: make([]*grpcapi.ContainerInfo, 0, len(res))}
+		for name, cinfo := range res {
+			cinfo := cinfo
+			out.Containers = append(out.Containers, grpcapi.ContainerInfoFromV2(name, &cinfo))
+		}
+		sortContainers(out)
+		return out, true
+	default:
+		return nil, false
+	}
+}
+
+func sortContainers(resp *grpcapi.ContainerInfoResponse) {
+	sort.Slice(resp.Containers, func(i, j int) bool { return resp.Containers[i].Name < resp.Containers[j].Name })
+}
@@ -0,0 +1,104 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/yidoyoon/cadvisor-lite/grpcapi"
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+	"github.com/yidoyoon/cadvisor-lite/manager/fake"
+)
+
+func TestAcceptsProtobuf(t *testing.T) {
+	for accept, expected := range map[string]bool{
+		"":                       false,
+		"application/json":       false,
+		"application/protobuf":   true,
+		"application/x-protobuf": true,
+		"application/json, application/protobuf;q=0.9": true,
+		"application/protobuf;q=0":                     false,
+		"text/event-stream":                            false,
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/api/v2.1/stats", nil)
+		if accept != "" {
+			r.Header.Set("Accept", accept)
+		}
+		assert.Equal(t, expected, acceptsProtobuf(r), accept)
+	}
+}
+
+func TestProtobufStats(t *testing.T) {
+	m := fake.NewManager()
+	for _, name := range []string{"/docker/b", "/docker/a"} {
+		m.AddContainer(info.ContainerReference{Name: name}, info.ContainerSpec{HasCpu: true, HasMemory: true, Image: "nginx"})
+		stats := &info.ContainerStats{Timestamp: time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC), Sequence: 1}
+		stats.Cpu.Usage.Total = 1000
+		stats.Memory.Usage = 2048
+		require.NoError(t, m.AddStats(name, stats))
+	}
+	api := newVersion2_2(newVersion2_1(newVersion2_0()))
+
+	r := makeHTTPRequest("http://localhost:8080/api/v2.1/stats/docker?type=name&recursive=true&fields=cpu", t)
+	r.Header.Set("Accept", "application/protobuf")
+	w := httptest.NewRecorder()
+	require.NoError(t, api.HandleRequest(statsAPI, []string{"docker"}, m, w, r))
+	assert.Equal(t, protobufContentType, w.Header().Get("Content-Type"))
+	assert.Equal(t, "Accept", w.Header().Get("Vary"))
+	var resp grpcapi.ContainerInfoResponse
+	require.NoError(t, proto.Unmarshal(w.Body.Bytes(), &resp))
+	var names []string
+	for _, cinfo := range resp.Containers {
+		names = append(names, cinfo.Name)
+	}
+	// The containers are sorted by name.
+	require.Equal(t, []string{"/docker", "/docker/a", "/docker/b"}, names)
+	assert.Equal(t, "nginx", resp.Containers[1].Spec.Image)
+	require.Len(t, resp.Containers[1].Stats, 1)
+	sample := resp.Containers[1].Stats[0]
+	assert.Equal(t, uint64(1000), sample.Cpu.UsageTotal)
+	assert.Equal(t, time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC), sample.Timestamp.AsTime())
+	// The fields left out are not set.
+	assert.Nil(t, sample.Memory)
+
+	// JSON is still the default.
+	r = makeHTTPRequest("http://localhost:8080/api/v2.1/stats/docker/a", t)
+	w = httptest.NewRecorder()
+	require.NoError(t, api.HandleRequest(statsAPI, []string{"docker", "a"}, m, w, r))
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.True(t, json.Valid(w.Body.Bytes()))
+
+	// Results without a protobuf message are written as JSON.
+	r = makeHTTPRequest("http://localhost:8080/api/v2.2/census", t)
+	r.Header.Set("Accept", "application/protobuf")
+	w = httptest.NewRecorder()
+	require.NoError(t, api.HandleRequest(censusAPI, nil, m, w, r))
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+}
+
+func TestProtobufResponseCacheKey(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/api/v2.1/stats/docker?count=1", nil)
+	jsonKey := requestKey(r)
+	r.Header.Set("Accept", "application/protobuf")
+	assert.NotEqual(t, jsonKey, requestKey(r))
+}
@@ -0,0 +1,103 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+
+	v2 "github.com/yidoyoon/cadvisor-lite/info/v2"
+)
+
+// Orders of the processes of the ps request type, by decreasing usage.
+const (
+	psSortCpu = "cpu"
+	psSortRss = "rss"
+	psSortFds = "fds"
+)
+
+type psOptions struct {
+	// Order of the processes, as listed if empty.
+	sort string
+	// Maximum number of processes, no limit if zero.
+	limit int
+	// Only the processes whose command matches it, all of them if nil.
+	filter *regexp.Regexp
+}
+
+func getPsOptions(r *http.Request) (psOptions, error) {
+	var opt psOptions
+	urlMap := r.URL.Query()
+	if order := urlMap.Get("sort"); order != "" {
+		switch order {
+		case psSortCpu, psSortRss, psSortFds:
+			opt.sort = order
+		default:
+			return opt, badRequest("sort", "unknown 'sort' %q, must be one of cpu, rss or fds", order)
+		}
+	}
+	if limit := urlMap.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			return opt, badRequest("limit", "invalid 'limit' option %q: must be a positive number", limit)
+		}
+		opt.limit = n
+	}
+	if filter := urlMap.Get("filter"); filter != "" {
+		re, err := regexp.Compile(filter)
+		if err != nil {
+			return opt, badRequest("filter", "failed to parse 'filter' option: %v", err)
+		}
+		opt.filter = re
+	}
+	return opt, nil
+}
+
+// selectProcesses returns the processes whose command matches the filter,
+// sorted by decreasing usage, the ties by pid, and limited to the first ones.
+func selectProcesses(ps []v2.ProcessInfo, opt psOptions) []v2.ProcessInfo {
+	// The processes are copied before they are sorted.
+	selected := make([]v2.ProcessInfo, 0, len(ps))
+	for _, p := range ps {
+		if opt.filter == nil || opt.filter.MatchString(p.Cmd) {
+			selected = append(selected, p)
+		}
+	}
+	ps = selected
+	if opt.sort != "" {
+		usage := func(p *v2.ProcessInfo) float64 {
+			switch opt.sort {
+			case psSortCpu:
+				return float64(p.PercentCpu)
+			case psSortRss:
+				return float64(p.RSS)
+			default:
+				return float64(p.FdCount)
+			}
+		}
+		sort.SliceStable(ps, func(i, j int) bool {
+			if ui, uj := usage(&ps[i]), usage(&ps[j]); ui != uj {
+				return ui > uj
+			}
+			return ps[i].Pid < ps[j].Pid
+		})
+	}
+	if opt.limit > 0 && len(ps) > opt.limit {
+		ps = ps[:opt.limit]
+	}
+	return ps
+}
@@ -0,0 +1,75 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+	v2 "github.com/yidoyoon/cadvisor-lite/info/v2"
+	"github.com/yidoyoon/cadvisor-lite/manager/fake"
+)
+
+func TestPsRequest(t *testing.T) {
+	m := fake.NewManager()
+	m.AddContainer(info.ContainerReference{Name: "/docker/a"}, info.ContainerSpec{})
+	processes := []v2.ProcessInfo{
+		{Pid: 1, Cmd: "/sbin/init", PercentCpu: 0.1, RSS: 8 << 20, FdCount: 30},
+		{Pid: 20, Cmd: "nginx: master process", PercentCpu: 2.5, RSS: 16 << 20, FdCount: 12},
+		{Pid: 21, Cmd: "nginx: worker process", PercentCpu: 40, RSS: 64 << 20, FdCount: 1000},
+		{Pid: 22, Cmd: "nginx: worker process", PercentCpu: 40, RSS: 32 << 20, FdCount: 900},
+	}
+	m.SetProcesses("/docker/a", processes)
+	api := newVersion2_2(newVersion2_1(newVersion2_0()))
+
+	for _, test := range []struct {
+		query string
+		pids  []int
+	}{
+		{"", []int{1, 20, 21, 22}},
+		{"sort=cpu", []int{21, 22, 20, 1}},
+		{"sort=rss&limit=2", []int{21, 22}},
+		{"sort=fds&filter=worker", []int{21, 22}},
+		{"filter=%5Enginx&limit=1", []int{20}},
+		{"filter=redis", []int{}},
+	} {
+		w := httptest.NewRecorder()
+		require.NoError(t, api.HandleRequest(psAPI, []string{"docker", "a"}, m, w, makeHTTPRequest("http://localhost:8080/api/v2.0/ps/docker/a?"+test.query, t)), test.query)
+		var actual []v2.ProcessInfo
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &actual))
+		pids := []int{}
+		for _, p := range actual {
+			pids = append(pids, p.Pid)
+		}
+		assert.Equal(t, test.pids, pids, test.query)
+	}
+	// The processes of the manager are not reordered.
+	assert.Equal(t, 1, processes[0].Pid)
+
+	for _, query := range []string{"sort=pid", "limit=0", "limit=x", "filter=%5B"} {
+		r := makeHTTPRequest("http://localhost:8080/api/v2.0/ps/docker/a?"+query, t)
+		err := api.HandleRequest(psAPI, []string{"docker", "a"}, m, httptest.NewRecorder(), r)
+		assert.Equal(t, http.StatusBadRequest, newErrorResponse(r, err).Status, query)
+	}
+	r := makeHTTPRequest("http://localhost:8080/api/v2.0/ps/docker/b", t)
+	err := api.HandleRequest(psAPI, []string{"docker", "b"}, m, httptest.NewRecorder(), r)
+	assert.Equal(t, http.StatusNotFound, newErrorResponse(r, err).Status)
+}
@@ -0,0 +1,67 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"sort"
+	"sync"
+
+	v2 "github.com/yidoyoon/cadvisor-lite/info/v2"
+
+	"k8s.io/klog/v2"
+)
+
+// runtimesStatus returns the status of the container runtimes detected on
+// the machine, sorted by name: those reachable and those of the monitored
+// containers, given their specs. The runtimes are queried concurrently.
+func runtimesStatus(specs map[string]v2.ContainerSpec) []v2.RuntimeStatus {
+	monitored := make(map[string]int)
+	for _, spec := range specs {
+		monitored[spec.Runtime]++
+	}
+	names := make([]string, 0, len(runtimeStatuses))
+	for name := range runtimeStatuses {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	results := make([]*v2.RuntimeStatus, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			status, err := runtimeStatuses[name]()
+			status.MonitoredContainers = monitored[name]
+			if err != nil {
+				if status.MonitoredContainers == 0 {
+					klog.V(4).Infof("Container runtime %q not detected: %v", name, err)
+					return
+				}
+				status.Error = err.Error()
+			}
+			results[i] = status
+		}(i, name)
+	}
+	wg.Wait()
+
+	statuses := make([]v2.RuntimeStatus, 0, len(results))
+	for _, status := range results {
+		if status != nil {
+			statuses = append(statuses, *status)
+		}
+	}
+	return statuses
+}
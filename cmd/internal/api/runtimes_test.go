@@ -0,0 +1,62 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+	v2 "github.com/yidoyoon/cadvisor-lite/info/v2"
+	"github.com/yidoyoon/cadvisor-lite/manager/fake"
+)
+
+func TestRuntimesRequest(t *testing.T) {
+	saved := runtimeStatuses
+	defer func() { runtimeStatuses = saved }()
+	unreachable := func(name string) func() (*v2.RuntimeStatus, error) {
+		return func() (*v2.RuntimeStatus, error) {
+			return &v2.RuntimeStatus{Name: name, Socket: "/run/" + name + ".sock", NumContainers: -1, NumImages: -1}, errors.New("connection refused")
+		}
+	}
+	runtimeStatuses = map[string]func() (*v2.RuntimeStatus, error){
+		"docker": func() (*v2.RuntimeStatus, error) {
+			return &v2.RuntimeStatus{Name: "docker", Socket: "unix:///var/run/docker.sock", Version: "24.0.5", APIVersion: "1.43", RootDir: "/var/lib/docker", StorageDriver: "overlay2", NumContainers: 3, NumImages: 5}, nil
+		},
+		"crio":   unreachable("crio"),
+		"podman": unreachable("podman"),
+	}
+
+	m := fake.NewManager()
+	m.AddContainer(info.ContainerReference{Name: "/docker/a"}, info.ContainerSpec{Runtime: "docker"})
+	m.AddContainer(info.ContainerReference{Name: "/docker/b"}, info.ContainerSpec{Runtime: "docker"})
+	m.AddContainer(info.ContainerReference{Name: "/crio/c"}, info.ContainerSpec{Runtime: "crio"})
+	api := newVersion2_2(newVersion2_1(newVersion2_0()))
+
+	w := httptest.NewRecorder()
+	require.NoError(t, api.HandleRequest(runtimesAPI, nil, m, w, makeHTTPRequest("http://localhost:8080/api/v2.2/runtimes", t)))
+	var statuses []v2.RuntimeStatus
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &statuses))
+	// Podman is neither reachable nor monitored.
+	assert.Equal(t, []v2.RuntimeStatus{
+		{Name: "crio", Socket: "/run/crio.sock", NumContainers: -1, NumImages: -1, MonitoredContainers: 1, Error: "connection refused"},
+		{Name: "docker", Socket: "unix:///var/run/docker.sock", Version: "24.0.5", APIVersion: "1.43", RootDir: "/var/lib/docker", StorageDriver: "overlay2", NumContainers: 3, NumImages: 5, MonitoredContainers: 2},
+	}, statuses)
+}
@@ -0,0 +1,483 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	v2 "github.com/yidoyoon/cadvisor-lite/info/v2"
+	"github.com/yidoyoon/cadvisor-lite/version"
+)
+
+const (
+	specResource = "/api/spec.json"
+
+	// Name of the argument of the request types taking a container name.
+	containerArgument = "container"
+)
+
+// RequestSpec describes a request type in the API specification. The
+// schemas are generated from the types of the values, following their JSON
+// struct tags.
+type RequestSpec struct {
+	// Value of the type of the result. The interface fields of the structs
+	// are documented with the type of the value they hold, if any. Nil if
+	// only the subresources are served.
+	Result interface{}
+	// Media type of the result, application/json if empty.
+	ContentType string
+	// Name of the argument following the request type, empty if none.
+	Argument string
+	// Value of a struct whose JSON fields are the query parameters, nil if
+	// none.
+	Options interface{}
+	// Value of the type of the body of POST requests, nil if they are not
+	// supported.
+	Body interface{}
+	// Whether the body is required, so that only POST requests are
+	// supported.
+	BodyRequired bool
+	// Specs of the fixed paths following the request type, by path, e.g.
+	// batch for /stats/batch.
+	Subresources map[string]*RequestSpec
+}
+
+// Query parameters of the requests for events.
+type eventOptions struct {
+	Stream                bool      `json:"stream"`
+	Subcontainers         bool      `json:"subcontainers"`
+	AllEvents             bool      `json:"all_events"`
+	OomEvents             bool      `json:"oom_events"`
+	OomKillEvents         bool      `json:"oom_kill_events"`
+	CreationEvents        bool      `json:"creation_events"`
+	DeletionEvents        bool      `json:"deletion_events"`
+	AlertEvents           bool      `json:"alert_events"`
+	AnomalyEvents         bool      `json:"anomaly_events"`
+	PidsLimitEvents       bool      `json:"pids_limit_events"`
+	NetworkDropsEvents    bool      `json:"network_drops_events"`
+	CpusetChangeEvents    bool      `json:"cpuset_change_events"`
+	StartLatencyEvents    bool      `json:"start_latency_events"`
+	DiskQuotaEvents       bool      `json:"disk_quota_events"`
+	ImagePullEvents       bool      `json:"image_pull_events"`
+	MetricsDisabledEvents bool      `json:"metrics_disabled_events"`
+	MachineRebootedEvents bool      `json:"machine_rebooted_events"`
+	LimitsChangeEvents    bool      `json:"limits_change_events"`
+	LogSizeEvents         bool      `json:"log_size_events"`
+	MaxEvents             int       `json:"max_events"`
+	StartTime             time.Time `json:"start_time"`
+	EndTime               time.Time `json:"end_time"`
+}
+
+// Query parameters of the requests for stats which can be streamed.
+type streamOptions struct {
+	v2.RequestOptions
+	Stream bool `json:"stream"`
+}
+
+// Query parameters of the requests for forecasts.
+type forecastRequestOptions struct {
+	v2.RequestOptions
+	Method  string        `json:"method"`
+	Horizon time.Duration `json:"horizon"`
+}
+
+// Query parameters of the requests for summaries.
+type summaryRequestOptions struct {
+	v2.RequestOptions
+	Windows     string `json:"windows"`
+	Percentiles string `json:"percentiles"`
+}
+
+// Query parameters of the requests for processes.
+type psRequestOptions struct {
+	Type   string `json:"type"`
+	Sort   string `json:"sort"`
+	Limit  int    `json:"limit"`
+	Filter string `json:"filter"`
+}
+
+// Query parameters of the requests for garbage collection advice.
+type gcRequestOptions struct {
+	ReclaimBytes int64 `json:"reclaim_bytes"`
+}
+
+// Query parameters of the requests for the top containers.
+type topRequestOptions struct {
+	Metric string `json:"metric"`
+	N      int    `json:"n"`
+}
+
+// Query parameters of the paginated requests.
+type pageRequestOptions struct {
+	v2.RequestOptions
+	Limit  int    `json:"limit"`
+	Cursor string `json:"cursor"`
+}
+
+// OpenAPI 3.0 document, limited to what the API needs.
+type openAPIDocument struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       openAPIInfo                      `json:"info"`
+	Paths      map[string]map[string]*operation `json:"paths"`
+	Components openAPIComponents                `json:"components"`
+}
+
+type openAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type openAPIComponents struct {
+	Schemas map[string]*schema `json:"schemas"`
+}
+
+type operation struct {
+	OperationID string              `json:"operationId"`
+	Parameters  []parameter         `json:"parameters,omitempty"`
+	RequestBody *requestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]response `json:"responses"`
+	Deprecated  bool                `json:"deprecated,omitempty"`
+}
+
+type parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *schema `json:"schema"`
+}
+
+type requestBody struct {
+	Content  map[string]mediaType `json:"content"`
+	Required bool                 `json:"required,omitempty"`
+}
+
+type response struct {
+	Description string               `json:"description"`
+	Content     map[string]mediaType `json:"content,omitempty"`
+}
+
+type mediaType struct {
+	Schema *schema `json:"schema"`
+}
+
+type schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Minimum              *int               `json:"minimum,omitempty"`
+	Items                *schema            `json:"items,omitempty"`
+	Properties           map[string]*schema `json:"properties,omitempty"`
+	AdditionalProperties *schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// getSpec returns the OpenAPI specification of the documented request types
+// of the API versions.
+func getSpec(apiVersions []ApiVersion) *openAPIDocument {
+	doc := &openAPIDocument{
+		OpenAPI:    "3.0.3",
+		Info:       openAPIInfo{Title: "cAdvisor API", Version: version.Version},
+		Paths:      make(map[string]map[string]*operation),
+		Components: openAPIComponents{Schemas: make(map[string]*schema)},
+	}
+	if doc.Info.Version == "" {
+		doc.Info.Version = "unknown"
+	}
+	for _, v := range apiVersions {
+		requestTypes := v.SupportedRequestTypes()
+		sort.Strings(requestTypes)
+		for _, requestType := range requestTypes {
+			spec := v.RequestSpec(requestType)
+			if spec == nil {
+				continue
+			}
+			resource := fmt.Sprintf("/api/%s/%s", v.Version(), requestType)
+			id := fmt.Sprintf("%s_%s", strings.ReplaceAll(v.Version(), ".", "_"), requestType)
+			doc.addPaths(resource, id, spec)
+		}
+		if _, ok := deprecations[v.Version()]; ok {
+			prefix := fmt.Sprintf("/api/%s/", v.Version())
+			for resource, operations := range doc.Paths {
+				if strings.HasPrefix(resource, prefix) {
+					for _, op := range operations {
+						op.Deprecated = true
+					}
+				}
+			}
+		}
+	}
+	doc.Paths[apiPrefix] = doc.operations("versions", &RequestSpec{Result: []VersionInfo{}}, nil)
+	return doc
+}
+
+// addPaths adds the operations of a resource, of the resource followed by
+// its argument and of its subresources.
+func (doc *openAPIDocument) addPaths(resource, id string, spec *RequestSpec) {
+	if spec.Result != nil {
+		doc.Paths[resource] = doc.operations(id, spec, nil)
+		if spec.Argument != "" {
+			arg := parameter{
+				Name:     spec.Argument,
+				In:       "path",
+				Required: true,
+				Schema:   &schema{Type: "string"},
+			}
+			if spec.Argument == containerArgument {
+				arg.Description = "Absolute name of the container, without the leading slash. Its slashes are not escaped."
+			}
+			doc.Paths[fmt.Sprintf("%s/{%s}", resource, spec.Argument)] = doc.operations(id+"_by_"+spec.Argument, spec, &arg)
+		}
+	}
+	for name, sub := range spec.Subresources {
+		doc.addPaths(fmt.Sprintf("%s/%s", resource, name), fmt.Sprintf("%s_%s", id, name), sub)
+	}
+}
+
+// operations returns the operations of a resource: GET, unless the body is
+// required, and POST if the request type takes a body.
+func (doc *openAPIDocument) operations(id string, spec *RequestSpec, arg *parameter) map[string]*operation {
+	contentType := spec.ContentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	get := &operation{
+		OperationID: "get_" + id,
+		Responses: map[string]response{
+			"200": {
+				Description: "Success.",
+				Content:     map[string]mediaType{contentType: {Schema: doc.schema(reflect.ValueOf(spec.Result))}},
+			},
+			"default": {Description: "Error, as plain text."},
+		},
+	}
+	if arg != nil {
+		get.Parameters = append(get.Parameters, *arg)
+	}
+	if spec.Options != nil {
+		get.Parameters = append(get.Parameters, queryParameters(reflect.TypeOf(spec.Options))...)
+	}
+	operations := map[string]*operation{"get": get}
+	if spec.Body != nil {
+		post := *get
+		post.OperationID = "post_" + id
+		post.RequestBody = &requestBody{
+			Content:  map[string]mediaType{"application/json": {Schema: doc.schema(reflect.ValueOf(spec.Body))}},
+			Required: spec.BodyRequired,
+		}
+		operations["post"] = &post
+		if spec.BodyRequired {
+			delete(operations, "get")
+		}
+	}
+	return operations
+}
+
+// queryParameters returns the optional query parameters named after the
+// JSON fields of a struct.
+func queryParameters(t reflect.Type) []parameter {
+	var params []parameter
+	for _, f := range jsonFields(t) {
+		s := &schema{}
+		switch ft := indirect(f.field.Type); {
+		case ft == durationType:
+			s.Type, s.Format = "string", "duration"
+		case ft == timeType:
+			s.Type, s.Format = "string", "date-time"
+		default:
+			s.Type, s.Format = scalarType(ft.Kind())
+		}
+		params = append(params, parameter{Name: f.name, In: "query", Schema: s})
+	}
+	return params
+}
+
+// schema returns the schema of the type of v. Named structs are added to the
+// components and referenced, unless they hold values in interface fields,
+// whose types are specific to v.
+func (doc *openAPIDocument) schema(v reflect.Value) *schema {
+	t := v.Type()
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+		if v.IsValid() && !v.IsNil() {
+			v = v.Elem()
+		} else {
+			v = reflect.Value{}
+		}
+	}
+	switch t.Kind() {
+	case reflect.Interface:
+		if v.IsValid() && !v.IsNil() {
+			return doc.schema(v.Elem())
+		}
+		return &schema{}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &schema{Type: "string", Format: "byte"}
+		}
+		return &schema{Type: "array", Items: doc.schema(reflect.Zero(t.Elem()))}
+	case reflect.Map:
+		return &schema{Type: "object", AdditionalProperties: doc.schema(reflect.Zero(t.Elem()))}
+	case reflect.Struct:
+		if t == timeType {
+			return &schema{Type: "string", Format: "date-time"}
+		}
+		if t.Name() == "" || holdsValues(v) {
+			return doc.structSchema(t, v)
+		}
+		name := path.Base(t.PkgPath()) + "." + t.Name()
+		if _, ok := doc.Components.Schemas[name]; !ok {
+			// Registered first, in case the struct refers to itself.
+			doc.Components.Schemas[name] = &schema{}
+			doc.Components.Schemas[name] = doc.structSchema(t, reflect.Value{})
+		}
+		return &schema{Ref: "#/components/schemas/" + name}
+	default:
+		s := &schema{}
+		s.Type, s.Format = scalarType(t.Kind())
+		if strings.HasPrefix(t.Kind().String(), "uint") {
+			s.Minimum = new(int)
+		}
+		return s
+	}
+}
+
+func (doc *openAPIDocument) structSchema(t reflect.Type, v reflect.Value) *schema {
+	s := &schema{Type: "object", Properties: make(map[string]*schema)}
+	for _, f := range jsonFields(t) {
+		fv := reflect.Zero(f.field.Type)
+		if v.IsValid() {
+			// Fails on nil embedded pointers.
+			if value, err := v.FieldByIndexErr(f.field.Index); err == nil {
+				fv = value
+			}
+		}
+		if f.asString {
+			s.Properties[f.name] = &schema{Type: "string"}
+		} else {
+			s.Properties[f.name] = doc.schema(fv)
+		}
+		if !f.omitEmpty {
+			s.Required = append(s.Required, f.name)
+		}
+	}
+	sort.Strings(s.Required)
+	return s
+}
+
+// holdsValues returns whether a struct has interface fields holding values.
+func holdsValues(v reflect.Value) bool {
+	if !v.IsValid() {
+		return false
+	}
+	for i := 0; i < v.NumField(); i++ {
+		if f := v.Field(i); f.Kind() == reflect.Interface && !f.IsNil() {
+			return true
+		}
+	}
+	return false
+}
+
+type jsonField struct {
+	field     reflect.StructField
+	name      string
+	omitEmpty bool
+	asString  bool
+}
+
+// jsonFields returns the fields of a struct encoded by encoding/json, in
+// order, the fields of embedded structs included.
+func jsonFields(t reflect.Type) []jsonField {
+	var fields []jsonField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" && indirect(f.Type).Kind() == reflect.Struct {
+			for _, ef := range jsonFields(indirect(f.Type)) {
+				ef.field.Index = append([]int{i}, ef.field.Index...)
+				fields = append(fields, ef)
+			}
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, jsonField{
+			field: f,
+			name:  name,
+			// Structs are never omitted.
+			omitEmpty: strings.Contains(opts, "omitempty") && f.Type.Kind() != reflect.Struct,
+			asString:  strings.Contains(opts, "string"),
+		})
+	}
+	// The fields of the struct take precedence over the embedded ones.
+	depths := make(map[string]int, len(fields))
+	for _, f := range fields {
+		if depth, ok := depths[f.name]; !ok || len(f.field.Index) < depth {
+			depths[f.name] = len(f.field.Index)
+		}
+	}
+	result := fields[:0]
+	for _, f := range fields {
+		if depths[f.name] == len(f.field.Index) {
+			// Only the first one of the fields of the same depth.
+			depths[f.name] = -1
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// scalarType returns the OpenAPI type and format of a scalar kind.
+func scalarType(kind reflect.Kind) (string, string) {
+	switch kind {
+	case reflect.Bool:
+		return "boolean", ""
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return "integer", "int32"
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return "integer", "int64"
+	case reflect.Float32:
+		return "number", "float"
+	case reflect.Float64:
+		return "number", "double"
+	default:
+		return "string", ""
+	}
+}
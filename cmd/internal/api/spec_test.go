@@ -0,0 +1,145 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yidoyoon/cadvisor-lite/events"
+)
+
+func TestSpec(t *testing.T) {
+	mux := http.NewServeMux()
+	require.NoError(t, RegisterHandlers(mux, nil))
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", specResource, nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	body := w.Body.String()
+
+	var doc openAPIDocument
+	require.NoError(t, json.Unmarshal([]byte(body), &doc))
+	assert.Equal(t, "3.0.3", doc.OpenAPI)
+
+	// Every reference is defined.
+	for _, ref := range regexp.MustCompile(`"\$ref":"#/components/schemas/([^"]+)"`).FindAllStringSubmatch(body, -1) {
+		assert.Contains(t, doc.Components.Schemas, ref[1])
+	}
+
+	get := doc.Paths["/api/v2.1/stats/{container}"]["get"]
+	require.NotNil(t, get)
+	assert.Equal(t, "get_v2_1_stats_by_container", get.OperationID)
+	var params []string
+	for _, p := range get.Parameters {
+		params = append(params, p.In+":"+p.Name)
+	}
+	assert.Equal(t, []string{"path:container", "query:type", "query:count", "query:recursive", "query:max_age", "query:since", "query:start", "query:end", "query:aligned", "query:step", "query:agg", "query:fields", "query:label_selector", "query:name_regex", "query:percpu", "query:stream"}, params)
+	stats := get.Responses["200"].Content["application/json"].Schema
+	assert.Equal(t, "#/components/schemas/v2.ContainerInfo", stats.AdditionalProperties.Ref)
+	assert.Equal(t, &schema{Type: "string", Format: "duration"}, get.Parameters[4].Schema)
+
+	// Fields of embedded structs are flattened, omitempty fields are
+	// optional.
+	cinfo := doc.Components.Schemas["v1.ContainerInfo"]
+	require.NotNil(t, cinfo)
+	assert.Contains(t, cinfo.Properties, "name")
+	assert.Contains(t, cinfo.Properties, "aliases")
+	assert.NotContains(t, cinfo.Properties, "ContainerReference")
+	assert.Contains(t, cinfo.Required, "spec")
+	assert.NotContains(t, cinfo.Required, "aliases")
+	assert.Equal(t, &schema{Type: "string", Format: "date-time"}, doc.Components.Schemas["v1.ContainerStats"].Properties["timestamp"])
+	assert.Equal(t, &schema{Type: "integer", Format: "int64", Minimum: new(int)}, doc.Components.Schemas["v1.CpuUsage"].Properties["total"])
+
+	// The v1 requests take a body.
+	post := doc.Paths["/api/v1.0/containers/{container}"]["post"]
+	require.NotNil(t, post)
+	assert.Equal(t, "#/components/schemas/v1.ContainerInfoRequest", post.RequestBody.Content["application/json"].Schema.Ref)
+	assert.Nil(t, doc.Paths["/api/v2.0/stats"]["post"])
+
+	// Batch stats are only requested with a body.
+	batch := doc.Paths["/api/v2.1/stats/batch"]
+	require.NotNil(t, batch["post"])
+	assert.Nil(t, batch["get"])
+	assert.Equal(t, "post_v2_1_stats_batch", batch["post"].OperationID)
+	assert.True(t, batch["post"].RequestBody.Required)
+	assert.Equal(t, "array", batch["post"].RequestBody.Content["application/json"].Schema.Type)
+
+	// The subresources take arguments and have their own media type.
+	export := doc.Paths["/api/v2.2/export/csv/{container}"]["get"]
+	require.NotNil(t, export)
+	assert.Equal(t, "get_v2_2_export_csv_by_container", export.OperationID)
+	assert.Equal(t, "string", export.Responses["200"].Content[csvContentType].Schema.Type)
+	assert.NotContains(t, doc.Paths, "/api/v2.2/export")
+
+	// The items of the pages are typed.
+	page := doc.Paths["/api/v3.0/subcontainers"]["get"].Responses["200"].Content["application/json"].Schema
+	assert.Equal(t, "#/components/schemas/v1.ContainerInfo", page.Properties["items"].Items.Ref)
+	assert.Equal(t, []string{"items"}, page.Required)
+	page = doc.Paths["/api/v3.0/stats"]["get"].Responses["200"].Content["application/json"].Schema
+	assert.Equal(t, "#/components/schemas/api.ContainerItem", page.Properties["items"].Items.Ref)
+
+	// Every documented request type is supported by its version.
+	for resource := range doc.Paths {
+		elements := strings.Split(resource, "/")
+		if resource == apiPrefix {
+			continue
+		}
+		for _, v := range getAPIVersions() {
+			if v.Version() == elements[2] {
+				assert.Contains(t, v.SupportedRequestTypes(), elements[3])
+			}
+		}
+	}
+	assert.Contains(t, doc.Paths, "/api/v2.2/storagehealth")
+	assert.Equal(t, "#/components/schemas/api.VersionInfo", doc.Paths[apiPrefix]["get"].Responses["200"].Content["application/json"].Schema.Items.Ref)
+
+	// The operations of the deprecated versions are flagged.
+	assert.True(t, doc.Paths["/api/v2.0/stats/{container}"]["get"].Deprecated)
+	assert.True(t, doc.Paths["/api/v1.0/containers/{container}"]["post"].Deprecated)
+	assert.False(t, get.Deprecated)
+	assert.NotContains(t, doc.Paths, "/api/v2.0/appmetrics")
+	params = nil
+	for _, p := range doc.Paths["/api/v2.0/ps/{container}"]["get"].Parameters {
+		params = append(params, p.In+":"+p.Name)
+	}
+	assert.Equal(t, []string{"path:container", "query:type", "query:sort", "query:limit", "query:filter"}, params)
+	params = nil
+	for _, p := range doc.Paths["/api/v2.0/summary/{container}"]["get"].Parameters {
+		params = append(params, p.Name)
+	}
+	assert.Contains(t, params, "windows")
+	assert.Contains(t, params, "percentiles")
+}
+
+func TestEventOptionsSpec(t *testing.T) {
+	// The spec documents every type of events.
+	options := map[string]bool{}
+	typ := reflect.TypeOf(eventOptions{})
+	for i := 0; i < typ.NumField(); i++ {
+		options[typ.Field(i).Tag.Get("json")] = true
+	}
+	for opt := range events.TypeOptions {
+		assert.True(t, options[opt], opt)
+	}
+}
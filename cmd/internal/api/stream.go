@@ -0,0 +1,172 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/net/websocket"
+	"k8s.io/klog/v2"
+
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+	v2 "github.com/yidoyoon/cadvisor-lite/info/v2"
+	"github.com/yidoyoon/cadvisor-lite/manager"
+)
+
+// streamStats sends the stats of the requested containers as they are
+// collected, polling them every housekeeping interval, as Server-Sent Events
+// if the client accepts them, e.g. the EventSource of a browser, and over a
+// WebSocket otherwise. The first message holds the latest count samples of
+// every container, the next ones the samples collected since. A message maps
+// the containers with new samples to them, oldest first.
+func streamStats(name string, opt v2.RequestOptions, m manager.Manager, w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Upgrade") == "" && strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		streamStatsEvents(name, opt, m, w, r)
+		return
+	}
+	// No Handshake, so that clients not sending an Origin header, unlike
+	// browsers, are accepted. The origins of browsers are checked by
+	// the CORS handler of the API.
+	websocket.Server{Handler: func(ws *websocket.Conn) {
+		defer ws.Close()
+		// Clients are not expected to send anything. Reading detects when
+		// they close the connection.
+		closed := make(chan struct{})
+		go func() {
+			_, _ = io.Copy(io.Discard, ws)
+			close(closed)
+		}()
+		sendStats(func(message map[string][]*v2.ContainerStats) error {
+			return websocket.JSON.Send(ws, message)
+		}, name, opt, m, closed)
+	}}.ServeHTTP(w, r)
+}
+
+// streamStatsEvents sends the messages as Server-Sent Events of type stats,
+// whose id is the timestamp of their newest sample. A client reconnecting
+// with this id in the Last-Event-ID header, as EventSource does, resumes
+// after it.
+func streamStatsEvents(name string, opt v2.RequestOptions, m manager.Manager, w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, r, fmt.Errorf("could not access http.Flusher"))
+		return
+	}
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		if since, err := time.Parse(time.RFC3339Nano, id); err == nil && since.After(opt.Since) {
+			opt.Since = since
+		}
+	}
+	header := w.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	// Keeps proxies such as nginx from buffering the events.
+	header.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	// Clients reconnect after this delay when the stream breaks.
+	retry := *manager.HousekeepingInterval
+	if retry < time.Second {
+		retry = time.Second
+	}
+	if _, err := fmt.Fprintf(w, "retry: %d\n\n", retry.Milliseconds()); err != nil {
+		return
+	}
+	flusher.Flush()
+	sendStats(func(message map[string][]*v2.ContainerStats) error {
+		data, err := json.Marshal(message)
+		if err != nil {
+			return err
+		}
+		var newest time.Time
+		for _, samples := range message {
+			if last := samples[len(samples)-1].Timestamp; last.After(newest) {
+				newest = last
+			}
+		}
+		if _, err := fmt.Fprintf(w, "id: %s\nevent: stats\ndata: %s\n\n", newest.Format(time.RFC3339Nano), data); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}, name, opt, m, r.Context().Done())
+}
+
+func sendStats(send func(map[string][]*v2.ContainerStats) error, name string, opt v2.RequestOptions, m manager.Manager, closed <-chan struct{}) {
+	// Timestamp of the last sample sent, per container.
+	last := make(map[string]time.Time)
+	ticker := time.NewTicker(*manager.HousekeepingInterval)
+	defer ticker.Stop()
+	sockets := cpuSockets(opt, m)
+	for {
+		conts, err := m.GetRequestedContainersInfo(name, opt)
+		if err != nil {
+			if len(conts) == 0 {
+				klog.Errorf("Failed to get the stats streamed for container %q: %v", name, err)
+				return
+			}
+			klog.Errorf("Error calling GetRequestedContainersInfo: %v", err)
+		}
+		// Root cgroup stats should be exposed as machine stats
+		delete(conts, "/")
+		message := make(map[string][]*v2.ContainerStats)
+		for name, cont := range conts {
+			if cont == nil {
+				continue
+			}
+			var samples []*info.ContainerStats
+			for _, stats := range cont.Stats {
+				if stats.Timestamp.After(last[name]) {
+					samples = append(samples, stats)
+				}
+			}
+			if len(samples) > 0 {
+				message[name] = v2.ContainerStatsFromV1(name, &cont.Spec, samples)
+				v2.SelectStatsFields(message[name], opt.Fields)
+				v2.SelectPerCpuUsage(message[name], opt.PerCpu, sockets)
+				last[name] = samples[len(samples)-1].Timestamp
+			}
+		}
+		if len(message) > 0 {
+			if err := send(message); err != nil {
+				klog.V(4).Infof("Stopping the stats stream of container %q: %v", name, err)
+				return
+			}
+		}
+		// Forget the containers which are gone, and poll the samples
+		// collected since the oldest last sample of the others.
+		var oldest time.Time
+		for name, timestamp := range last {
+			if _, ok := conts[name]; !ok {
+				delete(last, name)
+			} else if oldest.IsZero() || timestamp.Before(oldest) {
+				oldest = timestamp
+			}
+		}
+		if oldest.After(opt.Since) {
+			opt.Since, opt.Count = oldest, -1
+		}
+
+		select {
+		case <-closed:
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+	v2 "github.com/yidoyoon/cadvisor-lite/info/v2"
+	"github.com/yidoyoon/cadvisor-lite/manager"
+	"github.com/yidoyoon/cadvisor-lite/manager/fake"
+)
+
+func TestStreamStats(t *testing.T) {
+	interval := *manager.HousekeepingInterval
+	*manager.HousekeepingInterval = 10 * time.Millisecond
+	defer func() { *manager.HousekeepingInterval = interval }()
+
+	m := fake.NewManager()
+	m.AddContainer(info.ContainerReference{Name: "/docker/a"}, info.ContainerSpec{HasCpu: true})
+	start := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	addStats := func(i int) {
+		stats := &info.ContainerStats{Timestamp: start.Add(time.Duration(i) * time.Second)}
+		stats.Cpu.Usage.Total = uint64(i)
+		require.NoError(t, m.AddStats("/docker/a", stats))
+	}
+	for i := 0; i < 3; i++ {
+		addStats(i)
+	}
+
+	api := newVersion2_2(newVersion2_1(newVersion2_0()))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, api.HandleRequest(statsAPI, []string{"docker", "a"}, m, w, r))
+	}))
+	defer server.Close()
+	ws, err := websocket.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/api/v2.2/stats/docker/a?stream=true&count=2", "", server.URL)
+	require.NoError(t, err)
+	defer ws.Close()
+
+	// The latest count samples, then the new ones.
+	var message map[string][]*v2.ContainerStats
+	require.NoError(t, websocket.JSON.Receive(ws, &message))
+	require.Len(t, message["/docker/a"], 2)
+	assert.Equal(t, uint64(1), message["/docker/a"][0].Cpu.Usage.Total)
+	assert.Equal(t, uint64(2), message["/docker/a"][1].Cpu.Usage.Total)
+
+	addStats(3)
+	addStats(4)
+	require.NoError(t, websocket.JSON.Receive(ws, &message))
+	require.Len(t, message["/docker/a"], 2)
+	assert.True(t, start.Add(3*time.Second).Equal(message["/docker/a"][0].Timestamp))
+	assert.True(t, start.Add(4*time.Second).Equal(message["/docker/a"][1].Timestamp))
+}
+
+func TestStreamStatsEvents(t *testing.T) {
+	interval := *manager.HousekeepingInterval
+	*manager.HousekeepingInterval = 10 * time.Millisecond
+	defer func() { *manager.HousekeepingInterval = interval }()
+
+	m := fake.NewManager()
+	m.AddContainer(info.ContainerReference{Name: "/docker/a"}, info.ContainerSpec{HasCpu: true})
+	start := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	addStats := func(i int) {
+		stats := &info.ContainerStats{Timestamp: start.Add(time.Duration(i) * time.Second)}
+		stats.Cpu.Usage.Total = uint64(i)
+		require.NoError(t, m.AddStats("/docker/a", stats))
+	}
+	for i := 0; i < 3; i++ {
+		addStats(i)
+	}
+
+	api := newVersion2_2(newVersion2_1(newVersion2_0()))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, api.HandleRequest(statsAPI, []string{"docker", "a"}, m, w, r))
+	}))
+	defer server.Close()
+	get := func(lastEventID string) (*http.Response, *bufio.Reader) {
+		r, err := http.NewRequest(http.MethodGet, server.URL+"/api/v2.2/stats/docker/a?stream=true&count=2", nil)
+		require.NoError(t, err)
+		r.Header.Set("Accept", "text/event-stream")
+		if lastEventID != "" {
+			r.Header.Set("Last-Event-ID", lastEventID)
+		}
+		resp, err := http.DefaultClient.Do(r)
+		require.NoError(t, err)
+		assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+		return resp, bufio.NewReader(resp.Body)
+	}
+	// Returns the fields of the next event.
+	next := func(reader *bufio.Reader) map[string]string {
+		fields := make(map[string]string)
+		for {
+			line, err := reader.ReadString('\n')
+			require.NoError(t, err)
+			line = strings.TrimSuffix(line, "\n")
+			if line == "" {
+				return fields
+			}
+			field, value, _ := strings.Cut(line, ": ")
+			fields[field] = value
+		}
+	}
+	samples := func(event map[string]string) []*v2.ContainerStats {
+		var message map[string][]*v2.ContainerStats
+		require.NoError(t, json.Unmarshal([]byte(event["data"]), &message))
+		return message["/docker/a"]
+	}
+
+	resp, reader := get("")
+	assert.Equal(t, map[string]string{"retry": "1000"}, next(reader))
+	// The latest count samples, then the new ones.
+	event := next(reader)
+	assert.Equal(t, "stats", event["event"])
+	assert.Equal(t, "2023-05-01T10:00:02Z", event["id"])
+	require.Len(t, samples(event), 2)
+	assert.Equal(t, uint64(1), samples(event)[0].Cpu.Usage.Total)
+	addStats(3)
+	event = next(reader)
+	assert.Equal(t, "2023-05-01T10:00:03Z", event["id"])
+	require.Len(t, samples(event), 1)
+	assert.Equal(t, uint64(3), samples(event)[0].Cpu.Usage.Total)
+	resp.Body.Close()
+
+	// A reconnecting client resumes after the last event it received.
+	addStats(4)
+	resp, reader = get("2023-05-01T10:00:03Z")
+	defer resp.Body.Close()
+	next(reader)
+	event = next(reader)
+	assert.Equal(t, "2023-05-01T10:00:04Z", event["id"])
+	require.Len(t, samples(event), 1)
+	assert.Equal(t, uint64(4), samples(event)[0].Cpu.Usage.Total)
+}
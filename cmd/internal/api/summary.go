@@ -0,0 +1,91 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	v2 "github.com/yidoyoon/cadvisor-lite/info/v2"
+	"github.com/yidoyoon/cadvisor-lite/manager"
+)
+
+var (
+	// Window and percentiles of the usage of the summaries when only one of
+	// the windows and percentiles options is set.
+	defaultSummaryWindows     = []time.Duration{time.Hour}
+	defaultSummaryPercentiles = []float64{50, 90, 95}
+)
+
+type summaryOptions struct {
+	windows     []time.Duration
+	percentiles []float64
+}
+
+// getSummaryOptions returns the windows and percentiles of the usage
+// requested with the summaries, none if neither option is set.
+func getSummaryOptions(r *http.Request) (summaryOptions, error) {
+	var opt summaryOptions
+	windows := r.URL.Query().Get("windows")
+	percentiles := r.URL.Query().Get("percentiles")
+	if windows == "" && percentiles == "" {
+		return opt, nil
+	}
+	opt.windows = defaultSummaryWindows
+	opt.percentiles = defaultSummaryPercentiles
+	if windows != "" {
+		opt.windows = nil
+		for _, window := range strings.Split(windows, ",") {
+			d, err := time.ParseDuration(window)
+			if err != nil || d < time.Minute {
+				return opt, badRequest("windows", "invalid window %q in 'windows' option: must be a duration of at least a minute", window)
+			}
+			opt.windows = append(opt.windows, d)
+		}
+	}
+	if percentiles != "" {
+		opt.percentiles = nil
+		for _, percentile := range strings.Split(percentiles, ",") {
+			p, err := strconv.ParseFloat(percentile, 64)
+			if err != nil || p <= 0 || p > 100 {
+				return opt, badRequest("percentiles", "invalid percentile %q in 'percentiles' option: must be in (0, 100]", percentile)
+			}
+			opt.percentiles = append(opt.percentiles, p)
+		}
+	}
+	return opt, nil
+}
+
+// getSummaries returns the derived stats of the requested containers along
+// with their usage over the requested windows.
+func getSummaries(name string, opt v2.RequestOptions, summaryOpt summaryOptions, m manager.Manager) (map[string]v2.DerivedStats, error) {
+	stats, err := m.GetDerivedStats(name, opt)
+	if len(stats) == 0 || len(summaryOpt.windows) == 0 {
+		return stats, err
+	}
+	usages, usageErr := m.GetWindowUsage(name, opt, summaryOpt.windows, summaryOpt.percentiles)
+	for name, usage := range usages {
+		if s, ok := stats[name]; ok {
+			s.WindowUsage = usage
+			stats[name] = s
+		}
+	}
+	if err == nil {
+		err = usageErr
+	}
+	return stats, err
+}
@@ -0,0 +1,71 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+	v2 "github.com/yidoyoon/cadvisor-lite/info/v2"
+	"github.com/yidoyoon/cadvisor-lite/manager/fake"
+)
+
+func TestSummaryRequest(t *testing.T) {
+	m := fake.NewManager()
+	m.AddContainer(info.ContainerReference{Name: "/docker/a"}, info.ContainerSpec{HasCpu: true})
+	hour := v2.Usage{PercentComplete: 100, Cpu: v2.Percentiles{Present: true, Mean: 20, Max: 40, Ninety: 30}}
+	m.SetDerivedStats("/docker/a", v2.DerivedStats{HourUsage: hour})
+	window := func(w string, p50 uint64) v2.WindowUsage {
+		return v2.WindowUsage{
+			Window:          w,
+			PercentComplete: 100,
+			Cpu:             v2.CustomPercentiles{Present: true, Mean: 20, Max: 40, Percentiles: map[string]uint64{"50": p50}},
+		}
+	}
+	m.SetWindowUsage("/docker/a", []v2.WindowUsage{window("5m0s", 10), window("30m0s", 15), window("1h0m0s", 20)})
+	api := newVersion2_2(newVersion2_1(newVersion2_0()))
+
+	for _, test := range []struct {
+		query    string
+		expected []v2.WindowUsage
+	}{
+		{"", nil},
+		{"windows=30m,5m&percentiles=50,99.9", []v2.WindowUsage{window("30m0s", 15), window("5m0s", 10)}},
+		// The usage of the last hour by default.
+		{"percentiles=50", []v2.WindowUsage{window("1h0m0s", 20)}},
+	} {
+		r := makeHTTPRequest("http://localhost:8080/api/v2.0/summary/docker/a?"+test.query, t)
+		w := httptest.NewRecorder()
+		require.NoError(t, api.HandleRequest(summaryAPI, []string{"docker", "a"}, m, w, r), test.query)
+		var stats map[string]v2.DerivedStats
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+		require.Contains(t, stats, "/docker/a", test.query)
+		assert.Equal(t, hour, stats["/docker/a"].HourUsage, test.query)
+		assert.Equal(t, test.expected, stats["/docker/a"].WindowUsage, test.query)
+	}
+
+	for _, query := range []string{"windows=30s", "windows=1h,x", "percentiles=0", "percentiles=100.5", "percentiles=p99"} {
+		r := makeHTTPRequest("http://localhost:8080/api/v2.0/summary/docker/a?"+query, t)
+		err := api.HandleRequest(summaryAPI, []string{"docker", "a"}, m, httptest.NewRecorder(), r)
+		require.Error(t, err, query)
+		assert.Equal(t, http.StatusBadRequest, newErrorResponse(r, err).Status, query)
+	}
+}
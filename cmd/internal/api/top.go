@@ -0,0 +1,126 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+	v2 "github.com/yidoyoon/cadvisor-lite/info/v2"
+	"github.com/yidoyoon/cadvisor-lite/summary"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	defaultTopCount = 10
+	maxTopCount     = 1000
+)
+
+type topOptions struct {
+	metric string
+	n      int
+}
+
+func getTopOptions(r *http.Request) (topOptions, error) {
+	opt := topOptions{
+		metric: v2.TopCpu,
+		n:      defaultTopCount,
+	}
+	if metric := r.URL.Query().Get("metric"); metric != "" {
+		switch metric {
+		case v2.TopCpu, v2.TopMemory, v2.TopNetwork, v2.TopDiskIo:
+			opt.metric = metric
+		default:
+			return opt, badRequest("metric", "unknown 'metric' %q, must be one of cpu, memory, network or diskio", metric)
+		}
+	}
+	if n := r.URL.Query().Get("n"); n != "" {
+		count, err := strconv.Atoi(n)
+		if err != nil || count <= 0 || count > maxTopCount {
+			return opt, badRequest("n", "invalid 'n' option %q: must be between 1 and %d", n, maxTopCount)
+		}
+		opt.n = count
+	}
+	return opt, nil
+}
+
+// topContainers returns the containers with the highest usage of the metric,
+// in decreasing order, the root container and the containers without usage
+// of the resource excluded.
+func topContainers(conts map[string]*info.ContainerInfo, opt topOptions) []v2.TopContainer {
+	top := make([]v2.TopContainer, 0, len(conts))
+	for name, cont := range conts {
+		if cont == nil || name == "/" || len(cont.Stats) == 0 {
+			continue
+		}
+		value, ok := topValue(name, cont, opt.metric)
+		if !ok {
+			continue
+		}
+		top = append(top, v2.TopContainer{
+			Name:      name,
+			Aliases:   cont.Aliases,
+			Timestamp: cont.Stats[len(cont.Stats)-1].Timestamp,
+			Value:     value,
+		})
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Value != top[j].Value {
+			return top[i].Value > top[j].Value
+		}
+		return top[i].Name < top[j].Name
+	})
+	if len(top) > opt.n {
+		top = top[:opt.n]
+	}
+	return top
+}
+
+// topValue returns the usage of the resource of a metric by a container,
+// false if it is not known.
+func topValue(name string, cont *info.ContainerInfo, metric string) (float64, bool) {
+	if metric == v2.TopMemory {
+		if !cont.Spec.HasMemory {
+			return 0, false
+		}
+		return float64(cont.Stats[len(cont.Stats)-1].Memory.WorkingSet), true
+	}
+	if len(cont.Stats) < 2 {
+		return 0, false
+	}
+	rates, err := summary.GetContainerRates(&cont.Spec, cont.Stats[len(cont.Stats)-2:])
+	if err != nil {
+		klog.V(4).Infof("Failed to compute the rates of container %q: %v", name, err)
+		return 0, false
+	}
+	switch metric {
+	case v2.TopCpu:
+		if rates.Cpu != nil {
+			return rates.Cpu.Usage, true
+		}
+	case v2.TopNetwork:
+		if rates.Network != nil {
+			return rates.Network.RxBytes + rates.Network.TxBytes, true
+		}
+	case v2.TopDiskIo:
+		if rates.DiskIo != nil {
+			return rates.DiskIo.ReadBytes + rates.DiskIo.WriteBytes, true
+		}
+	}
+	return 0, false
+}
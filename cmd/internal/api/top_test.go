@@ -0,0 +1,87 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+	v2 "github.com/yidoyoon/cadvisor-lite/info/v2"
+	"github.com/yidoyoon/cadvisor-lite/manager/fake"
+)
+
+func TestTopRequest(t *testing.T) {
+	m := fake.NewManager()
+	start := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	for _, c := range []struct {
+		name        string
+		cpu, memory uint64
+	}{
+		{"/", 8000000000, 8 << 30},
+		{"/docker/a", 500000000, 300 << 20},
+		{"/docker/b", 2000000000, 100 << 20},
+		{"/docker/c", 1000000000, 200 << 20},
+	} {
+		m.AddContainer(info.ContainerReference{Name: c.name}, info.ContainerSpec{HasCpu: true, HasMemory: true})
+		for i := uint64(0); i < 2; i++ {
+			stats := &info.ContainerStats{Timestamp: start.Add(time.Duration(i) * time.Second), Sequence: i + 1}
+			stats.Cpu.Usage.Total = i * c.cpu
+			stats.Memory.WorkingSet = c.memory
+			require.NoError(t, m.AddStats(c.name, stats))
+		}
+	}
+	api := newVersion2_2(newVersion2_1(newVersion2_0()))
+
+	for _, test := range []struct {
+		query    string
+		expected []v2.TopContainer
+	}{
+		{"n=2", []v2.TopContainer{
+			{Name: "/docker/b", Timestamp: start.Add(time.Second), Value: 2},
+			{Name: "/docker/c", Timestamp: start.Add(time.Second), Value: 1},
+		}},
+		{"metric=memory", []v2.TopContainer{
+			{Name: "/docker/a", Timestamp: start.Add(time.Second), Value: 300 << 20},
+			{Name: "/docker/c", Timestamp: start.Add(time.Second), Value: 200 << 20},
+			{Name: "/docker/b", Timestamp: start.Add(time.Second), Value: 100 << 20},
+		}},
+		// The containers without the resource are omitted.
+		{"metric=network", []v2.TopContainer{}},
+	} {
+		r := makeHTTPRequest("http://localhost:8080/api/v2.2/top?"+test.query, t)
+		w := httptest.NewRecorder()
+		require.NoError(t, api.HandleRequest(topAPI, nil, m, w, r), test.query)
+		var top []v2.TopContainer
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &top))
+		for i := range top {
+			top[i].Timestamp = top[i].Timestamp.UTC()
+		}
+		assert.Equal(t, test.expected, top, test.query)
+	}
+
+	for _, query := range []string{"metric=gpu", "n=0", "n=x"} {
+		r := makeHTTPRequest("http://localhost:8080/api/v2.2/top?"+query, t)
+		err := api.HandleRequest(topAPI, nil, m, httptest.NewRecorder(), r)
+		require.Error(t, err, query)
+		assert.Equal(t, http.StatusBadRequest, newErrorResponse(r, err).Status, query)
+	}
+}
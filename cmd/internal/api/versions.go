@@ -15,16 +15,25 @@
 package api
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"path"
 	"strconv"
+	"strings"
 	"time"
 
 	_ "github.com/hodgesds/perf-utils"
+	"github.com/yidoyoon/cadvisor-lite/container"
+	"github.com/yidoyoon/cadvisor-lite/container/containerd"
+	"github.com/yidoyoon/cadvisor-lite/container/crio"
+	"github.com/yidoyoon/cadvisor-lite/container/docker"
+	"github.com/yidoyoon/cadvisor-lite/container/podman"
 	info "github.com/yidoyoon/cadvisor-lite/info/v1"
 	v2 "github.com/yidoyoon/cadvisor-lite/info/v2"
 	"github.com/yidoyoon/cadvisor-lite/manager"
+	"github.com/yidoyoon/cadvisor-lite/storage"
+	"github.com/yidoyoon/cadvisor-lite/summary"
 
 	"k8s.io/klog/v2"
 )
@@ -44,6 +53,34 @@ const (
 	versionAPI       = "version"
 	psAPI            = "ps"
 	customMetricsAPI = "appmetrics"
+	forecastAPI      = "forecast"
+	imagesAPI        = "images"
+	netnsAPI         = "netns"
+	censusAPI        = "census"
+	storageHealthAPI = "storagehealth"
+	decompositionAPI = "decomposition"
+	derivedAPI       = "derived"
+	pullsAPI         = "pulls"
+	factoriesAPI     = "factories"
+	gcAPI            = "gc"
+	exportAPI        = "export"
+	podsAPI          = "pods"
+	topAPI           = "top"
+	runtimesAPI      = "runtimes"
+	portsAPI         = "ports"
+)
+
+const (
+	// Argument of the stats request type listing the containers in the
+	// body of a POST request.
+	batchArgument = "batch"
+	// Maximum size of the body of a batch request.
+	maxBatchBodySize = 1 << 20
+	// Maximum size of the body of a ContainerInfoRequest.
+	maxInfoRequestBodySize = 1 << 16
+	// Maximum number of stats or events a request may ask for, far beyond
+	// what is stored.
+	maxRequestCount = 1 << 20
 )
 
 // Interface for a cAdvisor API version
@@ -56,6 +93,10 @@ type ApiVersion interface {
 
 	// Handles a request. The second argument is the parameters after /api/<version>/<endpoint>
 	HandleRequest(requestType string, request []string, m manager.Manager, w http.ResponseWriter, r *http.Request) error
+
+	// Describes a request type in the API specification, nil if it is not
+	// documented.
+	RequestSpec(requestType string) *RequestSpec
 }
 
 // Gets all supported API versions.
@@ -66,9 +107,37 @@ func getAPIVersions() []ApiVersion {
 	v1_3 := newVersion1_3(v1_2)
 	v2_0 := newVersion2_0()
 	v2_1 := newVersion2_1(v2_0)
+	v2_2 := newVersion2_2(v2_1)
+	v3_0 := newVersion3_0(v2_2)
+
+	return []ApiVersion{v1_0, v1_1, v1_2, v1_3, v2_0, v2_1, v2_2, v3_0}
 
-	return []ApiVersion{v1_0, v1_1, v1_2, v1_3, v2_0, v2_1}
+}
 
+// Functions returning the usage of the images of each container runtime.
+var imagesUsage = map[string]func() ([]info.DockerImageUsage, error){
+	"docker": docker.ImagesUsage,
+	"podman": podman.ImagesUsage,
+}
+
+// Functions returning the image pulls in progress of each container runtime.
+// Docker does not report the downloads of its pulls.
+var imagePulls = map[string]func() (*v2.ImagePulls, error){
+	"containerd": containerd.ImagePulls,
+}
+
+// Functions returning the containers and images of each container runtime
+// which could be removed to reclaim a number of bytes.
+var gcAdvice = map[string]func(int64) (*v2.GCAdvice, error){
+	"docker": docker.GCAdvice,
+}
+
+// Functions returning the status of each container runtime.
+var runtimeStatuses = map[string]func() (*v2.RuntimeStatus, error){
+	"containerd": containerd.RuntimeStatus,
+	"crio":       crio.RuntimeStatus,
+	"docker":     docker.RuntimeStatus,
+	"podman":     podman.RuntimeStatus,
 }
 
 // API v1.0
@@ -95,7 +164,7 @@ func (api *version1_0) HandleRequest(requestType string, request []string, m man
 			return err
 		}
 
-		err = writeResult(machineInfo, w)
+		err = writeCacheableResult(machineInfo, machineInfo.Timestamp, w, r)
 		if err != nil {
 			return err
 		}
@@ -104,7 +173,7 @@ func (api *version1_0) HandleRequest(requestType string, request []string, m man
 		klog.V(4).Infof("Api - Container(%s)", containerName)
 
 		// Get the query request.
-		query, err := getContainerInfoRequest(r.Body)
+		query, err := getContainerInfoRequest(w, r)
 		if err != nil {
 			return err
 		}
@@ -112,20 +181,31 @@ func (api *version1_0) HandleRequest(requestType string, request []string, m man
 		// Get the container.
 		cont, err := m.GetContainerInfo(containerName, query)
 		if err != nil {
-			return fmt.Errorf("failed to get container %q with error: %s", containerName, err)
+			return fmt.Errorf("failed to get container %q with error: %w", containerName, err)
 		}
 
 		// Only output the container as JSON.
-		err = writeResult(cont, w)
+		err = writeResult(cont, w, r)
 		if err != nil {
 			return err
 		}
 	default:
-		return fmt.Errorf("unknown request type %q", requestType)
+		return notFound("unknown request type %q", requestType)
 	}
 	return nil
 }
 
+func (api *version1_0) RequestSpec(requestType string) *RequestSpec {
+	switch requestType {
+	case machineAPI:
+		return &RequestSpec{Result: info.MachineInfo{}}
+	case containersAPI:
+		return &RequestSpec{Result: info.ContainerInfo{}, Argument: containerArgument, Body: info.ContainerInfoRequest{}}
+	default:
+		return nil
+	}
+}
+
 // API v1.1
 
 type version1_1 struct {
@@ -154,7 +234,7 @@ func (api *version1_1) HandleRequest(requestType string, request []string, m man
 		klog.V(4).Infof("Api - Subcontainers(%s)", containerName)
 
 		// Get the query request.
-		query, err := getContainerInfoRequest(r.Body)
+		query, err := getContainerInfoRequest(w, r)
 		if err != nil {
 			return err
 		}
@@ -162,11 +242,11 @@ func (api *version1_1) HandleRequest(requestType string, request []string, m man
 		// Get the subcontainers.
 		containers, err := m.SubcontainersInfo(containerName, query)
 		if err != nil {
-			return fmt.Errorf("failed to get subcontainers for container %q with error: %s", containerName, err)
+			return fmt.Errorf("failed to get subcontainers for container %q with error: %w", containerName, err)
 		}
 
 		// Only output the containers as JSON.
-		err = writeResult(containers, w)
+		err = writeResult(containers, w, r)
 		if err != nil {
 			return err
 		}
@@ -176,6 +256,15 @@ func (api *version1_1) HandleRequest(requestType string, request []string, m man
 	}
 }
 
+func (api *version1_1) RequestSpec(requestType string) *RequestSpec {
+	switch requestType {
+	case subcontainersAPI:
+		return &RequestSpec{Result: []*info.ContainerInfo{}, Argument: containerArgument, Body: info.ContainerInfoRequest{}}
+	default:
+		return api.baseVersion.RequestSpec(requestType)
+	}
+}
+
 // API v1.2
 
 type version1_2 struct {
@@ -203,7 +292,7 @@ func (api *version1_2) HandleRequest(requestType string, request []string, m man
 		klog.V(4).Infof("Api - Docker(%v)", request)
 
 		// Get the query request.
-		query, err := getContainerInfoRequest(r.Body)
+		query, err := getContainerInfoRequest(w, r)
 		if err != nil {
 			return err
 		}
@@ -225,17 +314,17 @@ func (api *version1_2) HandleRequest(requestType string, request []string, m man
 			var cont info.ContainerInfo
 			cont, err = m.DockerContainer(request[0], query)
 			if err != nil {
-				return fmt.Errorf("failed to get Docker container %q with error: %v", request[0], err)
+				return fmt.Errorf("failed to get Docker container %q with error: %w", request[0], err)
 			}
 			containers = map[string]info.ContainerInfo{
 				cont.Name: cont,
 			}
 		default:
-			return fmt.Errorf("unknown request for Docker container %v", request)
+			return notFound("unknown request for Docker container %v", request)
 		}
 
 		// Only output the containers as JSON.
-		err = writeResult(containers, w)
+		err = writeResult(containers, w, r)
 		if err != nil {
 			return err
 		}
@@ -245,6 +334,15 @@ func (api *version1_2) HandleRequest(requestType string, request []string, m man
 	}
 }
 
+func (api *version1_2) RequestSpec(requestType string) *RequestSpec {
+	switch requestType {
+	case dockerAPI:
+		return &RequestSpec{Result: map[string]info.ContainerInfo{}, Argument: "id", Body: info.ContainerInfoRequest{}}
+	default:
+		return api.baseVersion.RequestSpec(requestType)
+	}
+}
+
 // API v1.3
 
 type version1_3 struct {
@@ -275,6 +373,15 @@ func (api *version1_3) HandleRequest(requestType string, request []string, m man
 	}
 }
 
+func (api *version1_3) RequestSpec(requestType string) *RequestSpec {
+	switch requestType {
+	case eventsAPI:
+		return &RequestSpec{Result: []*info.Event{}, Argument: containerArgument, Options: eventOptions{}}
+	default:
+		return api.baseVersion.RequestSpec(requestType)
+	}
+}
+
 func handleEventRequest(request []string, m manager.Manager, w http.ResponseWriter, r *http.Request) error {
 	query, stream, err := getEventRequest(r)
 	if err != nil {
@@ -287,7 +394,7 @@ func handleEventRequest(request []string, m manager.Manager, w http.ResponseWrit
 		if err != nil {
 			return err
 		}
-		return writeResult(pastEvents, w)
+		return writeResult(pastEvents, w, r)
 	}
 	eventChannel, err := m.WatchForEvents(query)
 	if err != nil {
@@ -314,7 +421,7 @@ func (api *version2_0) SupportedRequestTypes() []string {
 	return []string{versionAPI, attributesAPI, eventsAPI, machineAPI, summaryAPI, statsAPI, specAPI, storageAPI, psAPI, customMetricsAPI}
 }
 
-func (api *version2_0) handleStatsAPI(request []string, opt v2.RequestOptions, m manager.Manager, w http.ResponseWriter) error {
+func (api *version2_0) handleStatsAPI(request []string, opt v2.RequestOptions, m manager.Manager, w http.ResponseWriter, r *http.Request) error {
 	name := getContainerName(request)
 
 	klog.V(4).Infof("Api - Stats: Looking for stats for container %q, options %+v", name, opt)
@@ -325,12 +432,16 @@ func (api *version2_0) handleStatsAPI(request []string, opt v2.RequestOptions, m
 		}
 		klog.Errorf("Error calling GetRequestedContainersInfo: %v", err)
 	}
+	if noStatsSince(opt, infos) {
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
 	contStats := make(map[string][]v2.DeprecatedContainerStats)
 	for name, cinfo := range infos {
 		contStats[name] = v2.DeprecatedStatsFromV1(cinfo)
 	}
 
-	return writeResult(contStats, w)
+	return writeResult(contStats, w, r)
 }
 
 func (api *version2_0) HandleRequest(requestType string, request []string, m manager.Manager, w http.ResponseWriter, r *http.Request) error {
@@ -358,9 +469,64 @@ func (api *version2_0) HandleRequest(requestType string, request []string, m man
 		//
 		//fmt.Println(cpuInstructions.Value, cpuCycles.Value, cacheRef.Value, cacheMiss.Value, cpuRefCycles.Value, cpuClock.Value, cpuTaskClock.Value, pageFaults.Value, contextSwitches.Value, minorPageFaults.Value, majorPageFaults.Value)
 
-		return api.handleStatsAPI(request, opt, m, w)
+		return api.handleStatsAPI(request, opt, m, w, r)
+	case attributesAPI:
+		klog.V(4).Info("Api - Attributes")
+		machineInfo, err := m.GetMachineInfo()
+		if err != nil {
+			return err
+		}
+		versionInfo, err := m.GetVersionInfo()
+		if err != nil {
+			return err
+		}
+		return writeResult(v2.GetAttributes(machineInfo, versionInfo), w, r)
+	case summaryAPI:
+		summaryOpt, err := getSummaryOptions(r)
+		if err != nil {
+			return err
+		}
+		name := getContainerName(request)
+		klog.V(4).Infof("Api - Summary for container %q, options %+v, %+v", name, opt, summaryOpt)
+		stats, err := getSummaries(name, opt, summaryOpt, m)
+		if err != nil {
+			if len(stats) == 0 {
+				return err
+			}
+			klog.Errorf("Error getting the summaries: %v", err)
+		}
+		return writeResult(stats, w, r)
+	case psAPI:
+		psOpt, err := getPsOptions(r)
+		if err != nil {
+			return err
+		}
+		// reuse container type from request.
+		// ignore recursive.
+		name := getContainerName(request)
+		klog.V(4).Infof("Api - Processes of container %q, options %+v, %+v", name, opt, psOpt)
+		ps, err := m.GetProcessList(name, opt)
+		if err != nil {
+			return fmt.Errorf("process listing failed: %w", err)
+		}
+		return writeResult(selectProcesses(ps, psOpt), w, r)
 	default:
-		return fmt.Errorf("unknown request type %q", requestType)
+		return notFound("unknown request type %q", requestType)
+	}
+}
+
+func (api *version2_0) RequestSpec(requestType string) *RequestSpec {
+	switch requestType {
+	case statsAPI:
+		return &RequestSpec{Result: map[string][]v2.DeprecatedContainerStats{}, Argument: containerArgument, Options: v2.RequestOptions{}}
+	case attributesAPI:
+		return &RequestSpec{Result: v2.Attributes{}}
+	case summaryAPI:
+		return &RequestSpec{Result: map[string]v2.DerivedStats{}, Argument: containerArgument, Options: summaryRequestOptions{}}
+	case psAPI:
+		return &RequestSpec{Result: []v2.ProcessInfo{}, Argument: containerArgument, Options: psRequestOptions{}}
+	default:
+		return nil
 	}
 }
 
@@ -407,7 +573,7 @@ func (api *version2_0) HandleRequest(requestType string, request []string, m man
 //
 //		return api.handleStatsAPI(request, opt, m, w)
 //	default:
-//		return fmt.Errorf("unknown request type %q", requestType)
+//		return notFound("unknown request type %q", requestType)
 //	}
 //}
 
@@ -438,7 +604,7 @@ func (api *version2_1) HandleRequest(requestType string, request []string, m man
 
 	switch requestType {
 	case machineStatsAPI:
-		klog.V(4).Infof("Api - MachineStats(%v)", request)
+		klog.V(4).Infof("Api - MachineStats(%v, %+v)", request, opt)
 		cont, err := m.GetRequestedContainersInfo("/", opt)
 		if err != nil {
 			if len(cont) == 0 {
@@ -446,9 +612,39 @@ func (api *version2_1) HandleRequest(requestType string, request []string, m man
 			}
 			klog.Errorf("Error calling GetRequestedContainersInfo: %v", err)
 		}
-		return writeResult(v2.MachineStatsFromV1(cont["/"]), w)
+		if noStatsSince(opt, cont) {
+			w.WriteHeader(http.StatusNoContent)
+			return nil
+		}
+		// The stats are aggregated along the topology and related to the
+		// allocatable resources when they are known.
+		machineInfo, err := m.GetMachineInfo()
+		if err != nil {
+			klog.Errorf("Error calling GetMachineInfo: %v", err)
+		}
+		root := cont["/"]
+		if root != nil && opt.Step > 0 {
+			downsampled := *root
+			downsampled.Stats = v2.DownsampleStats(root.Stats, opt.Step, opt.Agg)
+			root = &downsampled
+		}
+		return writeResult(v2.MachineStatsWithMachineInfoFromV1(root, machineInfo), w, r)
 	case statsAPI:
+		if len(request) == 1 && request[0] == batchArgument && r.Method == http.MethodPost {
+			return handleBatchStats(opt, m, w, r)
+		}
 		name := getContainerName(request)
+		if r.URL.Query().Get("stream") == "true" {
+			if !opt.End.IsZero() {
+				return badRequest("end", "invalid 'end' option: ignored with 'stream'")
+			}
+			if opt.Step > 0 {
+				return badRequest("step", "invalid 'step' option: ignored with 'stream'")
+			}
+			klog.V(4).Infof("Api - Stats: Streaming stats for container %q, options %+v", name, opt)
+			streamStats(name, opt, m, w, r)
+			return nil
+		}
 		klog.V(4).Infof("Api - Stats: Looking for stats for container %q, options %+v", name, opt)
 		conts, err := m.GetRequestedContainersInfo(name, opt)
 		if err != nil {
@@ -457,24 +653,509 @@ func (api *version2_1) HandleRequest(requestType string, request []string, m man
 			}
 			klog.Errorf("Error calling GetRequestedContainersInfo: %v", err)
 		}
-		contStats := make(map[string]v2.ContainerInfo, len(conts))
+		return writeContainerStats(conts, opt, cpuSockets(opt, m), w, r)
+	default:
+		return api.baseVersion.HandleRequest(requestType, request, m, w, r)
+	}
+}
+
+func (api *version2_1) RequestSpec(requestType string) *RequestSpec {
+	switch requestType {
+	case machineStatsAPI:
+		return &RequestSpec{Result: []v2.MachineStats{}, Options: v2.RequestOptions{}}
+	case statsAPI:
+		return &RequestSpec{
+			Result:   map[string]v2.ContainerInfo{},
+			Argument: containerArgument,
+			Options:  streamOptions{},
+			Subresources: map[string]*RequestSpec{
+				batchArgument: {Result: map[string]v2.ContainerInfo{}, Options: v2.RequestOptions{}, Body: []string{}, BodyRequired: true},
+			},
+		}
+	default:
+		return api.baseVersion.RequestSpec(requestType)
+	}
+}
+
+// cpuSockets returns the socket of every CPU of the machine if the per-CPU
+// usage is requested per socket, nil otherwise.
+func cpuSockets(opt v2.RequestOptions, m manager.Manager) []int {
+	if opt.PerCpu != v2.PerCpuSocket {
+		return nil
+	}
+	machineInfo, err := m.GetMachineInfo()
+	if err != nil {
+		klog.Errorf("Error calling GetMachineInfo: %v", err)
+		return nil
+	}
+	return v2.CpuSockets(machineInfo.Topology)
+}
+
+// writeContainerStats writes the specs and stats of containers as returned by
+// the stats endpoint, the per-CPU usage being summed over sockets, the socket
+// of every CPU, if requested.
+func writeContainerStats(conts map[string]*info.ContainerInfo, opt v2.RequestOptions, sockets []int, w http.ResponseWriter, r *http.Request) error {
+	// Root cgroup stats should be exposed as machine stats
+	delete(conts, "/")
+	if noStatsSince(opt, conts) {
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	contStats := make(map[string]v2.ContainerInfo, len(conts))
+	for name, cont := range conts {
+		stats := v2.ContainerStatsFromV1(name, &cont.Spec, v2.DownsampleStats(cont.Stats, opt.Step, opt.Agg))
+		v2.SelectStatsFields(stats, opt.Fields)
+		v2.SelectPerCpuUsage(stats, opt.PerCpu, sockets)
+		contStats[name] = v2.ContainerInfo{
+			Spec:  v2.ContainerSpecFromV1(&cont.Spec, cont.Aliases, cont.Namespace),
+			Stats: stats,
+		}
+	}
+	return writeResult(contStats, w, r)
+}
+
+// handleBatchStats writes the stats of the containers listed in the body of
+// the request, a JSON list of names, or of ids if the type option is set.
+// The containers which are not found are omitted.
+func handleBatchStats(opt v2.RequestOptions, m manager.Manager, w http.ResponseWriter, r *http.Request) error {
+	var names []string
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBatchBodySize)).Decode(&names); err != nil {
+		return bodyError(err, "failed to parse the list of containers")
+	}
+	klog.V(4).Infof("Api - Stats: Looking for stats for containers %v, options %+v", names, opt)
+	opt.Recursive = false
+	conts := make(map[string]*info.ContainerInfo, len(names))
+	for _, name := range names {
+		if opt.IdType == v2.TypeName {
+			name = path.Join("/", name)
+		}
+		infos, err := m.GetRequestedContainersInfo(name, opt)
+		if err != nil {
+			klog.V(4).Infof("Failed to get the stats of container %q: %v", name, err)
+			continue
+		}
+		for name, cont := range infos {
+			if cont != nil {
+				conts[name] = cont
+			}
+		}
+	}
+	return writeContainerStats(conts, opt, cpuSockets(opt, m), w, r)
+}
+
+type version2_2 struct {
+	baseVersion *version2_1
+}
+
+func newVersion2_2(v *version2_1) *version2_2 {
+	return &version2_2{
+		baseVersion: v,
+	}
+}
+
+func (api *version2_2) Version() string {
+	return "v2.2"
+}
+
+func (api *version2_2) SupportedRequestTypes() []string {
+	return append([]string{forecastAPI, imagesAPI, netnsAPI, censusAPI, storageHealthAPI, decompositionAPI, derivedAPI, pullsAPI, factoriesAPI, gcAPI, exportAPI, podsAPI, topAPI, runtimesAPI, portsAPI}, api.baseVersion.SupportedRequestTypes()...)
+}
+
+func (api *version2_2) HandleRequest(requestType string, request []string, m manager.Manager, w http.ResponseWriter, r *http.Request) error {
+	switch requestType {
+	case forecastAPI:
+		opt, err := GetRequestOptions(r)
+		if err != nil {
+			return err
+		}
+		forecastOpt, err := getForecastOptions(r)
+		if err != nil {
+			return err
+		}
+		name := getContainerName(request)
+		klog.V(4).Infof("Api - Forecast(%v, %+v, %+v)", name, opt, forecastOpt)
+		conts, err := m.GetRequestedContainersInfo(name, opt)
+		if err != nil {
+			if len(conts) == 0 {
+				return err
+			}
+			klog.Errorf("Error calling GetRequestedContainersInfo: %v", err)
+		}
+		forecasts := make(map[string]v2.ContainerForecast, len(conts))
 		for name, cont := range conts {
-			if name == "/" {
-				// Root cgroup stats should be exposed as machine stats
+			f, err := containerForecast(cont, forecastOpt)
+			if err != nil {
+				klog.V(4).Infof("Failed to forecast container %q: %v", name, err)
 				continue
 			}
-			contStats[name] = v2.ContainerInfo{
-				Spec:  v2.ContainerSpecFromV1(&cont.Spec, cont.Aliases, cont.Namespace),
-				Stats: v2.ContainerStatsFromV1(name, &cont.Spec, cont.Stats),
+			forecasts[name] = *f
+		}
+		return writeResult(forecasts, w, r)
+	case imagesAPI:
+		runtime := "docker"
+		if len(request) > 0 && request[0] != "" {
+			runtime = request[0]
+		}
+		klog.V(4).Infof("Api - Images(%v)", runtime)
+		getUsage, ok := imagesUsage[runtime]
+		if !ok {
+			return notFound("unknown container runtime %q", runtime)
+		}
+		images, err := getUsage()
+		if err != nil {
+			return err
+		}
+		return writeResult(images, w, r)
+	case netnsAPI:
+		opt, err := GetRequestOptions(r)
+		if err != nil {
+			return err
+		}
+		name := getContainerName(request)
+		klog.V(4).Infof("Api - NetworkNamespaces(%v, %+v)", name, opt)
+		namespaces, err := m.GetNetworkNamespaces(name, opt)
+		if err != nil {
+			if len(namespaces) == 0 {
+				return err
+			}
+			klog.Errorf("Error calling GetNetworkNamespaces: %v", err)
+		}
+		return writeResult(namespaces, w, r)
+	case portsAPI:
+		opt, err := GetRequestOptions(r)
+		if err != nil {
+			return err
+		}
+		name := getContainerName(request)
+		klog.V(4).Infof("Api - Ports(%v, %+v)", name, opt)
+		ports, err := m.GetPorts(name, opt)
+		if err != nil {
+			if len(ports) == 0 {
+				return err
 			}
+			klog.Errorf("Error calling GetPorts: %v", err)
 		}
-		return writeResult(contStats, w)
+		return writeResult(ports, w, r)
+	case censusAPI:
+		klog.V(4).Infof("Api - ProcessCensus()")
+		census, err := m.GetProcessCensus()
+		if err != nil {
+			return err
+		}
+		return writeResult(census, w, r)
+	case storageHealthAPI:
+		klog.V(4).Infof("Api - StorageHealth()")
+		return writeResult(storage.DriversHealth(), w, r)
+	case decompositionAPI:
+		klog.V(4).Infof("Api - MachineDecomposition()")
+		decomposition, err := m.GetMachineDecomposition()
+		if err != nil {
+			return err
+		}
+		return writeResult(decomposition, w, r)
+	case derivedAPI:
+		opt, err := GetRequestOptions(r)
+		if err != nil {
+			return err
+		}
+		name := getContainerName(request)
+		klog.V(4).Infof("Api - Derived(%v, %+v)", name, opt)
+		conts, err := m.GetRequestedContainersInfo(name, opt)
+		if err != nil {
+			if len(conts) == 0 {
+				return err
+			}
+			klog.Errorf("Error calling GetRequestedContainersInfo: %v", err)
+		}
+		rates := make(map[string]v2.ContainerRates, len(conts))
+		for name, cont := range conts {
+			// Only keep samples in increasing time order.
+			var stats []*info.ContainerStats
+			for _, s := range cont.Stats {
+				if len(stats) == 0 || s.Timestamp.After(stats[len(stats)-1].Timestamp) {
+					stats = append(stats, s)
+				}
+			}
+			r, err := summary.GetContainerRates(&cont.Spec, stats)
+			if err != nil {
+				klog.V(4).Infof("Failed to compute the rates of container %q: %v", name, err)
+				continue
+			}
+			rates[name] = *r
+		}
+		return writeResult(rates, w, r)
+	case pullsAPI:
+		runtime := "containerd"
+		if len(request) > 0 && request[0] != "" {
+			runtime = request[0]
+		}
+		klog.V(4).Infof("Api - ImagePulls(%v)", runtime)
+		getPulls, ok := imagePulls[runtime]
+		if !ok {
+			return notFound("image pulls of container runtime %q are not available", runtime)
+		}
+		pulls, err := getPulls()
+		if err != nil {
+			return err
+		}
+		return writeResult(pulls, w, r)
+	case factoriesAPI:
+		klog.V(4).Infof("Api - Factories()")
+		return writeResult(container.Factories(), w, r)
+	case gcAPI:
+		runtime := "docker"
+		if len(request) > 0 && request[0] != "" {
+			runtime = request[0]
+		}
+		reclaim, err := getReclaimBytes(r)
+		if err != nil {
+			return err
+		}
+		klog.V(4).Infof("Api - GCAdvice(%v, %v)", runtime, reclaim)
+		getAdvice, ok := gcAdvice[runtime]
+		if !ok {
+			return notFound("garbage collection advice of container runtime %q is not available", runtime)
+		}
+		advice, err := getAdvice(reclaim)
+		if err != nil {
+			return err
+		}
+		return writeResult(advice, w, r)
+	case exportAPI:
+		if len(request) == 0 || request[0] != csvArgument {
+			return notFound("unknown export format %q", strings.Join(request, "/"))
+		}
+		opt, err := GetRequestOptions(r)
+		if err != nil {
+			return err
+		}
+		name := getContainerName(request[1:])
+		klog.V(4).Infof("Api - ExportCSV(%v, %+v)", name, opt)
+		conts, err := m.GetRequestedContainersInfo(name, opt)
+		if err != nil {
+			if len(conts) == 0 {
+				return err
+			}
+			klog.Errorf("Error calling GetRequestedContainersInfo: %v", err)
+		}
+		return writeStatsCSV(conts, w)
+	case podsAPI:
+		namespace := strings.Join(request, "/")
+		klog.V(4).Infof("Api - Pods(%v)", namespace)
+		// The rates are computed between the two latest samples.
+		opt := v2.RequestOptions{IdType: v2.TypeName, Count: 2, Recursive: true}
+		conts, err := m.GetRequestedContainersInfo("/", opt)
+		if err != nil {
+			if len(conts) == 0 {
+				return err
+			}
+			klog.Errorf("Error calling GetRequestedContainersInfo: %v", err)
+		}
+		return writeResult(podsUsage(conts, namespace), w, r)
+	case topAPI:
+		topOpt, err := getTopOptions(r)
+		if err != nil {
+			return err
+		}
+		klog.V(4).Infof("Api - Top(%+v)", topOpt)
+		// The rates are computed between the two latest samples.
+		opt := v2.RequestOptions{IdType: v2.TypeName, Count: 2, Recursive: true}
+		conts, err := m.GetRequestedContainersInfo("/", opt)
+		if err != nil {
+			if len(conts) == 0 {
+				return err
+			}
+			klog.Errorf("Error calling GetRequestedContainersInfo: %v", err)
+		}
+		return writeResult(topContainers(conts, topOpt), w, r)
+	case runtimesAPI:
+		klog.V(4).Infof("Api - Runtimes()")
+		specs, err := m.GetContainerSpec("/", v2.RequestOptions{IdType: v2.TypeName, Recursive: true})
+		if err != nil {
+			if len(specs) == 0 {
+				return err
+			}
+			klog.Errorf("Error calling GetContainerSpec: %v", err)
+		}
+		return writeResult(runtimesStatus(specs), w, r)
 	default:
 		return api.baseVersion.HandleRequest(requestType, request, m, w, r)
 	}
 }
 
+func (api *version2_2) RequestSpec(requestType string) *RequestSpec {
+	switch requestType {
+	case forecastAPI:
+		return &RequestSpec{Result: map[string]v2.ContainerForecast{}, Argument: containerArgument, Options: forecastRequestOptions{}}
+	case imagesAPI:
+		return &RequestSpec{Result: []info.DockerImageUsage{}, Argument: "runtime"}
+	case netnsAPI:
+		return &RequestSpec{Result: map[string]v2.NetworkNamespace{}, Argument: containerArgument, Options: v2.RequestOptions{}}
+	case portsAPI:
+		return &RequestSpec{Result: map[string]v2.ContainerPorts{}, Argument: containerArgument, Options: v2.RequestOptions{}}
+	case censusAPI:
+		return &RequestSpec{Result: v2.ProcessCensus{}}
+	case storageHealthAPI:
+		return &RequestSpec{Result: []storage.DriverHealth{}}
+	case decompositionAPI:
+		return &RequestSpec{Result: v2.MachineDecomposition{}}
+	case derivedAPI:
+		return &RequestSpec{Result: map[string]v2.ContainerRates{}, Argument: containerArgument, Options: v2.RequestOptions{}}
+	case pullsAPI:
+		return &RequestSpec{Result: v2.ImagePulls{}, Argument: "runtime"}
+	case factoriesAPI:
+		return &RequestSpec{Result: container.FactoriesInfo{}}
+	case gcAPI:
+		return &RequestSpec{Result: v2.GCAdvice{}, Argument: "runtime", Options: gcRequestOptions{}}
+	case podsAPI:
+		return &RequestSpec{Result: []v2.PodUsage{}, Argument: "namespace"}
+	case topAPI:
+		return &RequestSpec{Result: []v2.TopContainer{}, Options: topRequestOptions{}}
+	case runtimesAPI:
+		return &RequestSpec{Result: []v2.RuntimeStatus{}}
+	case exportAPI:
+		return &RequestSpec{
+			Subresources: map[string]*RequestSpec{
+				csvArgument: {Result: "", ContentType: csvContentType, Argument: containerArgument, Options: v2.RequestOptions{}},
+			},
+		}
+	default:
+		return api.baseVersion.RequestSpec(requestType)
+	}
+}
+
+// API v3.0
+
+// v3.0 builds on v2.2, paginating the containers, subcontainers and stats
+// endpoints with the limit and cursor parameters.
+type version3_0 struct {
+	baseVersion *version2_2
+}
+
+func newVersion3_0(v *version2_2) *version3_0 {
+	return &version3_0{
+		baseVersion: v,
+	}
+}
+
+func (api *version3_0) Version() string {
+	return "v3.0"
+}
+
+func (api *version3_0) SupportedRequestTypes() []string {
+	return append([]string{containersAPI, subcontainersAPI}, api.baseVersion.SupportedRequestTypes()...)
+}
+
+func (api *version3_0) HandleRequest(requestType string, request []string, m manager.Manager, w http.ResponseWriter, r *http.Request) error {
+	switch requestType {
+	case containersAPI, subcontainersAPI, statsAPI:
+	default:
+		return api.baseVersion.HandleRequest(requestType, request, m, w, r)
+	}
+	opt, err := GetRequestOptions(r)
+	if err != nil {
+		return err
+	}
+	pageOpt, err := getPageOptions(r)
+	if err != nil {
+		return err
+	}
+	name := getContainerName(request)
+	klog.V(4).Infof("Api - %s(%s, %+v, %+v)", requestType, name, opt, pageOpt)
+	if requestType != statsAPI {
+		// The container and all its subcontainers are listed.
+		opt.Recursive = true
+	}
+
+	// Only the specs of all the containers are fetched, to find those of the
+	// page.
+	specs, err := m.GetContainerSpec(name, opt)
+	if err != nil {
+		if len(specs) == 0 {
+			return err
+		}
+		klog.Errorf("Error calling GetContainerSpec: %v", err)
+	}
+	if requestType == statsAPI {
+		// Root cgroup stats should be exposed as machine stats
+		delete(specs, "/")
+	}
+	names := make([]string, 0, len(specs))
+	for name := range specs {
+		names = append(names, name)
+	}
+	names, nextCursor := paginate(names, pageOpt)
+
+	switch requestType {
+	case containersAPI:
+		items := make([]ContainerItem, 0, len(names))
+		for _, name := range names {
+			spec := specs[name]
+			items = append(items, ContainerItem{Name: name, Spec: &spec})
+		}
+		return writeCacheableResult(Page{Items: items, NextCursor: nextCursor}, time.Time{}, w, r)
+	case subcontainersAPI:
+		query := &info.ContainerInfoRequest{NumStats: opt.Count}
+		items := make([]*info.ContainerInfo, 0, len(names))
+		for _, name := range names {
+			cont, err := m.GetContainerInfo(name, query)
+			if err != nil {
+				// The container was removed since it was listed.
+				klog.V(4).Infof("Failed to get container %q: %v", name, err)
+				continue
+			}
+			items = append(items, cont)
+		}
+		return writeResult(Page{Items: items, NextCursor: nextCursor}, w, r)
+	default:
+		// The containers of the page are fetched by their absolute names.
+		opt.IdType, opt.Recursive = v2.TypeName, false
+		sockets := cpuSockets(opt, m)
+		items := make([]ContainerItem, 0, len(names))
+		for _, name := range names {
+			conts, err := m.GetRequestedContainersInfo(name, opt)
+			cont, ok := conts[name]
+			if err != nil || !ok || cont == nil {
+				klog.V(4).Infof("Failed to get the stats of container %q: %v", name, err)
+				continue
+			}
+			spec := v2.ContainerSpecFromV1(&cont.Spec, cont.Aliases, cont.Namespace)
+			stats := v2.ContainerStatsFromV1(name, &cont.Spec, v2.DownsampleStats(cont.Stats, opt.Step, opt.Agg))
+			v2.SelectStatsFields(stats, opt.Fields)
+			v2.SelectPerCpuUsage(stats, opt.PerCpu, sockets)
+			items = append(items, ContainerItem{Name: name, Spec: &spec, Stats: stats})
+		}
+		return writeResult(Page{Items: items, NextCursor: nextCursor}, w, r)
+	}
+}
+
+func (api *version3_0) RequestSpec(requestType string) *RequestSpec {
+	switch requestType {
+	case containersAPI, statsAPI:
+		return &RequestSpec{Result: Page{Items: []ContainerItem{}}, Argument: containerArgument, Options: pageRequestOptions{}}
+	case subcontainersAPI:
+		return &RequestSpec{Result: Page{Items: []*info.ContainerInfo{}}, Argument: containerArgument, Options: pageRequestOptions{}}
+	default:
+		return api.baseVersion.RequestSpec(requestType)
+	}
+}
+
+// noStatsSince returns whether stats newer than the since option of the
+// request were requested and none of the containers has any.
+func noStatsSince(opt v2.RequestOptions, infos map[string]*info.ContainerInfo) bool {
+	if opt.Since.IsZero() {
+		return false
+	}
+	for _, cinfo := range infos {
+		if cinfo != nil && len(cinfo.Stats) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
 // GetRequestOptions returns the metrics request options from a HTTP request.
+// The errors of invalid options are served with 400 Bad Request by the API.
 func GetRequestOptions(r *http.Request) (v2.RequestOptions, error) {
 	supportedTypes := map[string]bool{
 		v2.TypeName:   true,
@@ -487,34 +1168,128 @@ func GetRequestOptions(r *http.Request) (v2.RequestOptions, error) {
 		Count:     64,
 		Recursive: false,
 	}
-	idType := r.URL.Query().Get("type")
+	urlMap := r.URL.Query()
+	idType := urlMap.Get("type")
 	if len(idType) != 0 {
 		if !supportedTypes[idType] {
-			return opt, fmt.Errorf("unknown 'type' %q", idType)
+			return opt, badRequest("type", "unknown 'type' %q", idType)
 		}
 		opt.IdType = idType
 	}
-	count := r.URL.Query().Get("count")
+	count := urlMap.Get("count")
 	if len(count) != 0 {
 		n, err := strconv.Atoi(count)
 		if err != nil {
-			return opt, fmt.Errorf("failed to parse 'count' option: %v", count)
+			return opt, badRequest("count", "failed to parse 'count' option: %v", count)
 		}
-		if n < -1 {
-			return opt, fmt.Errorf("invalid 'count' option: only -1 and larger values allowed, not %d", n)
+		if n < -1 || n > maxRequestCount {
+			return opt, badRequest("count", "invalid 'count' option: only -1 to %d allowed, not %d", maxRequestCount, n)
 		}
 		opt.Count = n
 	}
-	recursive := r.URL.Query().Get("recursive")
-	if recursive == "true" {
-		opt.Recursive = true
+	var err error
+	if opt.Recursive, err = getBoolOption(urlMap, "recursive", false); err != nil {
+		return opt, err
+	}
+	if opt.Aligned, err = getBoolOption(urlMap, "aligned", false); err != nil {
+		return opt, err
 	}
-	if maxAgeString := r.URL.Query().Get("max_age"); len(maxAgeString) > 0 {
+	if maxAgeString := urlMap.Get("max_age"); len(maxAgeString) > 0 {
 		maxAge, err := time.ParseDuration(maxAgeString)
 		if err != nil {
-			return opt, fmt.Errorf("failed to parse 'max_age' option: %v", err)
+			return opt, badRequest("max_age", "failed to parse 'max_age' option: %v", err)
+		}
+		if maxAge < 0 {
+			return opt, badRequest("max_age", "invalid 'max_age' option: negative duration %s", maxAge)
+		}
+		if opt.Count == 0 {
+			return opt, badRequest("max_age", "invalid 'max_age' option: no stats are returned with 'count' 0")
 		}
 		opt.MaxAge = &maxAge
 	}
+	if since := urlMap.Get("since"); len(since) > 0 {
+		t, err := time.Parse(time.RFC3339Nano, since)
+		if err != nil {
+			return opt, badRequest("since", "failed to parse 'since' option: %v", err)
+		}
+		opt.Since = t
+	}
+	for _, param := range []string{"start", "end"} {
+		value := urlMap.Get(param)
+		if value == "" {
+			continue
+		}
+		if urlMap.Has("since") {
+			return opt, badRequest("since", "invalid 'since' option: ignored with '%s'", param)
+		}
+		t, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return opt, badRequest(param, "failed to parse '%s' option: %v", param, err)
+		}
+		if param == "start" {
+			opt.Start = t
+		} else {
+			opt.End = t
+		}
+	}
+	if !opt.Start.IsZero() && !opt.End.IsZero() && opt.End.Before(opt.Start) {
+		return opt, badRequest("end", "invalid 'end' option: %s is before 'start' %s", opt.End.Format(time.RFC3339Nano), opt.Start.Format(time.RFC3339Nano))
+	}
+	if step := urlMap.Get("step"); len(step) > 0 {
+		d, err := time.ParseDuration(step)
+		if err != nil {
+			return opt, badRequest("step", "failed to parse 'step' option: %v", err)
+		}
+		if d <= 0 {
+			return opt, badRequest("step", "invalid 'step' option: %s is not positive", d)
+		}
+		opt.Step = d
+	}
+	if agg := urlMap.Get("agg"); len(agg) > 0 {
+		if opt.Step == 0 {
+			return opt, badRequest("agg", "invalid 'agg' option: ignored without 'step'")
+		}
+		if !v2.IsAggregation(agg) {
+			return opt, badRequest("agg", "invalid 'agg' option %q: must be %s, %s, %s, %s, %s or %s", agg, v2.AggFirst, v2.AggLast, v2.AggAvg, v2.AggMax, v2.AggMin, v2.AggSum)
+		}
+		opt.Agg = agg
+	}
+	// All the stats of the range, or to downsample, unless a count is set.
+	if (!opt.Start.IsZero() || !opt.End.IsZero() || opt.Step > 0) && !urlMap.Has("count") {
+		opt.Count = -1
+	}
+	if opt.Aligned {
+		for _, param := range []string{"count", "max_age", "since", "start", "end", "step"} {
+			if urlMap.Has(param) {
+				return opt, badRequest(param, "invalid '%s' option: ignored with 'aligned'", param)
+			}
+		}
+	}
+	if fields := urlMap.Get("fields"); len(fields) > 0 {
+		for _, field := range strings.Split(fields, ",") {
+			if !v2.IsStatsField(field) {
+				return opt, badRequest("fields", "unknown 'fields' option %q", field)
+			}
+			opt.Fields = append(opt.Fields, field)
+		}
+	}
+	if selector := urlMap.Get("label_selector"); len(selector) > 0 {
+		if _, err := v2.ParseLabelSelector(selector); err != nil {
+			return opt, badRequest("label_selector", "failed to parse 'label_selector' option: %v", err)
+		}
+		opt.LabelSelector = selector
+	}
+	if nameRegex := urlMap.Get("name_regex"); len(nameRegex) > 0 {
+		if _, err := v2.ParseNameRegex(nameRegex); err != nil {
+			return opt, badRequest("name_regex", "failed to parse 'name_regex' option: %v", err)
+		}
+		opt.NameRegex = nameRegex
+	}
+	if perCpu := urlMap.Get("percpu"); len(perCpu) > 0 {
+		if !v2.IsPerCpuMode(perCpu) {
+			return opt, badRequest("percpu", "invalid 'percpu' option %q: must be %s, %s or %s", perCpu, v2.PerCpuFull, v2.PerCpuSocket, v2.PerCpuOff)
+		}
+		opt.PerCpu = perCpu
+	}
 	return opt, nil
 }
@@ -15,15 +15,22 @@
 package api
 
 import (
+	"encoding/json"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/yidoyoon/cadvisor-lite/events"
 	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+	v2 "github.com/yidoyoon/cadvisor-lite/info/v2"
+	"github.com/yidoyoon/cadvisor-lite/manager/fake"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // returns an http.Request pointer for an input url test string
@@ -79,3 +86,599 @@ func TestGetEventRequestDoubleArgument(t *testing.T) {
 	assert.True(t, stream)
 	assert.Nil(t, err)
 }
+
+func TestImagesRequest(t *testing.T) {
+	images := []info.DockerImageUsage{{
+		DockerImage:   info.DockerImage{ID: "sha256:a", Size: 100},
+		SharedSize:    60,
+		UniqueSize:    40,
+		NumContainers: 1,
+	}}
+	defer func(old map[string]func() ([]info.DockerImageUsage, error)) { imagesUsage = old }(imagesUsage)
+	imagesUsage = map[string]func() ([]info.DockerImageUsage, error){
+		"podman": func() ([]info.DockerImageUsage, error) { return images, nil },
+	}
+
+	api := newVersion2_2(newVersion2_1(newVersion2_0()))
+	w := httptest.NewRecorder()
+	err := api.HandleRequest(imagesAPI, []string{"podman"}, nil, w, makeHTTPRequest("http://localhost:8080/api/v2.2/images/podman", t))
+	assert.NoError(t, err)
+	var actual []info.DockerImageUsage
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &actual))
+	assert.Equal(t, images, actual)
+
+	err = api.HandleRequest(imagesAPI, nil, nil, httptest.NewRecorder(), makeHTTPRequest("http://localhost:8080/api/v2.2/images", t))
+	assert.Error(t, err)
+}
+
+func TestPullsRequest(t *testing.T) {
+	pulls := &v2.ImagePulls{
+		Timestamp: time.Unix(100, 0).UTC(),
+		Downloads: []v2.BlobDownload{{
+			Ref:             "layer-sha256:a",
+			Digest:          "sha256:a",
+			Layer:           true,
+			BytesDownloaded: 40,
+			BytesTotal:      100,
+		}},
+		LayersRemaining: 1,
+		BytesDownloaded: 40,
+		BytesTotal:      100,
+	}
+	defer func(old map[string]func() (*v2.ImagePulls, error)) { imagePulls = old }(imagePulls)
+	imagePulls = map[string]func() (*v2.ImagePulls, error){
+		"containerd": func() (*v2.ImagePulls, error) { return pulls, nil },
+	}
+
+	api := newVersion2_2(newVersion2_1(newVersion2_0()))
+	w := httptest.NewRecorder()
+	err := api.HandleRequest(pullsAPI, nil, nil, w, makeHTTPRequest("http://localhost:8080/api/v2.2/pulls", t))
+	assert.NoError(t, err)
+	var actual v2.ImagePulls
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &actual))
+	assert.Equal(t, *pulls, actual)
+
+	err = api.HandleRequest(pullsAPI, []string{"docker"}, nil, httptest.NewRecorder(), makeHTTPRequest("http://localhost:8080/api/v2.2/pulls/docker", t))
+	assert.Error(t, err)
+}
+
+func TestGCRequest(t *testing.T) {
+	var requested []int64
+	defer func(old map[string]func(int64) (*v2.GCAdvice, error)) { gcAdvice = old }(gcAdvice)
+	gcAdvice = map[string]func(int64) (*v2.GCAdvice, error){
+		"docker": func(reclaim int64) (*v2.GCAdvice, error) {
+			requested = append(requested, reclaim)
+			return &v2.GCAdvice{
+				Timestamp:        time.Unix(100, 0).UTC(),
+				RequestedBytes:   reclaim,
+				ReclaimableBytes: 30,
+				Candidates:       []v2.GCCandidate{{Kind: v2.GCContainer, ID: "abc", Names: []string{"old"}, Size: 30}},
+			}, nil
+		},
+	}
+
+	api := newVersion2_2(newVersion2_1(newVersion2_0()))
+	w := httptest.NewRecorder()
+	err := api.HandleRequest(gcAPI, nil, nil, w, makeHTTPRequest("http://localhost:8080/api/v2.2/gc?reclaim_bytes=20", t))
+	assert.NoError(t, err)
+	var actual v2.GCAdvice
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &actual))
+	assert.Equal(t, int64(20), actual.RequestedBytes)
+	assert.Equal(t, []v2.GCCandidate{{Kind: v2.GCContainer, ID: "abc", Names: []string{"old"}, Size: 30}}, actual.Candidates)
+	assert.False(t, actual.Executed)
+
+	err = api.HandleRequest(gcAPI, []string{"docker"}, nil, httptest.NewRecorder(), makeHTTPRequest("http://localhost:8080/api/v2.2/gc/docker", t))
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{20, 0}, requested)
+
+	for _, reclaim := range []string{"-1", "1GB"} {
+		r := makeHTTPRequest("http://localhost:8080/api/v2.2/gc?reclaim_bytes="+reclaim, t)
+		err = api.HandleRequest(gcAPI, nil, nil, httptest.NewRecorder(), r)
+		assert.Equal(t, http.StatusBadRequest, newErrorResponse(r, err).Status, reclaim)
+	}
+	r := makeHTTPRequest("http://localhost:8080/api/v2.2/gc/containerd", t)
+	err = api.HandleRequest(gcAPI, []string{"containerd"}, nil, httptest.NewRecorder(), r)
+	assert.Equal(t, http.StatusNotFound, newErrorResponse(r, err).Status)
+}
+
+func TestAttributesRequest(t *testing.T) {
+	m := fake.NewManager()
+	bootTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	m.SetMachineInfo(info.MachineInfo{NumCores: 4, BootID: "boot-1", BootTime: bootTime, CloudProvider: info.GCE, InstanceType: "e2-standard-4", InstanceID: "1234567890", Zone: "us-central1-a"})
+	m.SetVersionInfo(info.VersionInfo{CadvisorVersion: "v0.47.0"})
+
+	api := newVersion2_2(newVersion2_1(newVersion2_0()))
+	w := httptest.NewRecorder()
+	err := api.HandleRequest(attributesAPI, nil, m, w, makeHTTPRequest("http://localhost:8080/api/v2.2/attributes", t))
+	assert.NoError(t, err)
+	var actual v2.Attributes
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &actual))
+	assert.Equal(t, 4, actual.NumCores)
+	assert.Equal(t, "v0.47.0", actual.CadvisorVersion)
+	assert.Equal(t, "boot-1", actual.BootID)
+	assert.True(t, actual.BootTime.Equal(bootTime))
+	assert.InDelta(t, time.Hour.Seconds(), actual.UptimeSeconds, 60)
+	assert.Equal(t, info.GCE, actual.CloudProvider)
+	assert.Equal(t, info.InstanceType("e2-standard-4"), actual.InstanceType)
+	assert.Equal(t, info.InstanceID("1234567890"), actual.InstanceID)
+	assert.Equal(t, info.InstanceZone("us-central1-a"), actual.Zone)
+}
+
+func TestNetnsRequest(t *testing.T) {
+	m := fake.NewManager()
+	m.AddContainer(info.ContainerReference{Name: "/docker/a"}, info.ContainerSpec{})
+	m.AddContainer(info.ContainerReference{Name: "/docker/b"}, info.ContainerSpec{})
+	ns := v2.NetworkNamespace{
+		Inode: 4026532305,
+		Pid:   42,
+		Interfaces: []v2.NetworkInterface{{
+			Name:      "eth0",
+			Index:     2,
+			Type:      "veth",
+			Addresses: []string{"172.17.0.2/16"},
+			Peer:      &v2.NetworkPeer{Name: "veth1a2b3c", Index: 7, Master: "docker0", MasterType: "bridge"},
+		}},
+		Routes: []v2.NetworkRoute{{Destination: "default", Gateway: "172.17.0.1", Interface: "eth0", Table: 254}},
+	}
+	m.SetNetworkNamespace("/docker/a", ns)
+
+	api := newVersion2_2(newVersion2_1(newVersion2_0()))
+	w := httptest.NewRecorder()
+	err := api.HandleRequest(netnsAPI, []string{"docker"}, m, w, makeHTTPRequest("http://localhost:8080/api/v2.2/netns/docker?recursive=true", t))
+	assert.NoError(t, err)
+	var actual map[string]v2.NetworkNamespace
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &actual))
+	assert.Equal(t, map[string]v2.NetworkNamespace{"/docker/a": ns}, actual)
+}
+
+func TestPortsRequest(t *testing.T) {
+	m := fake.NewManager()
+	m.AddContainer(info.ContainerReference{Name: "/docker/a"}, info.ContainerSpec{})
+	m.AddContainer(info.ContainerReference{Name: "/docker/b"}, info.ContainerSpec{})
+	ports := v2.ContainerPorts{
+		NetworkNamespace: 4026532305,
+		Published:        []info.PortMapping{{Protocol: "tcp", ContainerPort: 80, HostIP: "0.0.0.0", HostPort: 8080}},
+		Listening: []v2.ListeningSocket{
+			{Protocol: "tcp", Address: "0.0.0.0", Port: 80, Inode: 123456, Pid: 4242, Command: "nginx"},
+		},
+	}
+	m.SetPorts("/docker/a", ports)
+
+	api := newVersion2_2(newVersion2_1(newVersion2_0()))
+	w := httptest.NewRecorder()
+	err := api.HandleRequest(portsAPI, []string{"docker"}, m, w, makeHTTPRequest("http://localhost:8080/api/v2.2/ports/docker?recursive=true", t))
+	assert.NoError(t, err)
+	var actual map[string]v2.ContainerPorts
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &actual))
+	assert.Equal(t, map[string]v2.ContainerPorts{"/docker/a": ports}, actual)
+
+	err = api.HandleRequest(portsAPI, []string{"docker", "c"}, m, httptest.NewRecorder(), makeHTTPRequest("http://localhost:8080/api/v2.2/ports/docker/c", t))
+	assert.Error(t, err)
+}
+
+func TestCensusRequest(t *testing.T) {
+	m := fake.NewManager()
+	m.SetProcesses("/", []v2.ProcessInfo{{Pid: 1, Cmd: "systemd", RSS: 100, PercentCpu: 0.5}})
+	m.SetProcesses("/docker/a", []v2.ProcessInfo{
+		{Pid: 42, Cmd: "nginx", RSS: 1000, PercentCpu: 1},
+		{Pid: 43, Cmd: "nginx", RSS: 2000, PercentCpu: 2},
+	})
+
+	api := newVersion2_2(newVersion2_1(newVersion2_0()))
+	w := httptest.NewRecorder()
+	err := api.HandleRequest(censusAPI, []string{}, m, w, makeHTTPRequest("http://localhost:8080/api/v2.2/census", t))
+	assert.NoError(t, err)
+	var actual v2.ProcessCensus
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &actual))
+	assert.Len(t, actual.Processes, 3)
+	assert.Equal(t, v2.CensusHost, actual.Processes[0].Owner)
+	assert.Equal(t, map[string]v2.CensusUsage{
+		v2.CensusHost: {ProcessCount: 1, PercentCpu: 0.5, RSS: 100},
+		"/docker/a":   {ProcessCount: 2, PercentCpu: 3, RSS: 3000},
+	}, actual.Owners)
+}
+
+func TestDecompositionRequest(t *testing.T) {
+	m := fake.NewManager()
+	decomposition := v2.MachineDecomposition{
+		Timestamp: time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC),
+		Machine:   v2.DecompositionUsage{CpuNanoCores: 3000, MemoryUsage: 300},
+		Buckets: map[string]v2.DecompositionUsage{
+			v2.DecompositionContainers:  {CpuNanoCores: 2000, MemoryUsage: 200},
+			"system.slice":              {CpuNanoCores: 500, MemoryUsage: 50},
+			v2.DecompositionUnaccounted: {CpuNanoCores: 500, MemoryUsage: 50},
+		},
+	}
+	m.SetMachineDecomposition(decomposition)
+
+	api := newVersion2_2(newVersion2_1(newVersion2_0()))
+	w := httptest.NewRecorder()
+	err := api.HandleRequest(decompositionAPI, []string{}, m, w, makeHTTPRequest("http://localhost:8080/api/v2.2/decomposition", t))
+	assert.NoError(t, err)
+	var actual v2.MachineDecomposition
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &actual))
+	assert.Equal(t, decomposition, actual)
+}
+
+func TestDerivedRequest(t *testing.T) {
+	m := fake.NewManager()
+	m.AddContainer(info.ContainerReference{Name: "/docker/a"}, info.ContainerSpec{HasCpu: true})
+	m.AddContainer(info.ContainerReference{Name: "/docker/b"}, info.ContainerSpec{HasCpu: true})
+	start := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		stats := &info.ContainerStats{Timestamp: start.Add(time.Duration(i) * time.Second)}
+		stats.Cpu.Usage.Total = uint64(i) * 500000000
+		assert.NoError(t, m.AddStats("/docker/a", stats))
+	}
+	assert.NoError(t, m.AddStats("/docker/b", &info.ContainerStats{Timestamp: start}))
+
+	api := newVersion2_2(newVersion2_1(newVersion2_0()))
+	w := httptest.NewRecorder()
+	err := api.HandleRequest(derivedAPI, []string{"docker"}, m, w, makeHTTPRequest("http://localhost:8080/api/v2.2/derived/docker?recursive=true", t))
+	assert.NoError(t, err)
+	var actual map[string]v2.ContainerRates
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &actual))
+	// The container with a single sample is omitted.
+	assert.Len(t, actual, 1)
+	rates := actual["/docker/a"]
+	assert.Equal(t, 3, rates.Samples)
+	assert.Equal(t, 2.0, rates.Interval)
+	if assert.NotNil(t, rates.Cpu) {
+		assert.Equal(t, 0.5, rates.Cpu.Usage)
+	}
+	assert.Nil(t, rates.Network)
+}
+
+func TestStatsSinceRequest(t *testing.T) {
+	m := fake.NewManager()
+	m.AddContainer(info.ContainerReference{Name: "/docker/a"}, info.ContainerSpec{HasCpu: true})
+	start := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, m.AddStats("/docker/a", &info.ContainerStats{Timestamp: start.Add(time.Duration(i) * time.Second)}))
+	}
+
+	api := newVersion2_2(newVersion2_1(newVersion2_0()))
+	w := httptest.NewRecorder()
+	since := start.Add(time.Second).Format(time.RFC3339Nano)
+	err := api.HandleRequest(statsAPI, []string{"docker", "a"}, m, w, makeHTTPRequest("http://localhost:8080/api/v2.1/stats/docker/a?since="+since, t))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var actual map[string]v2.ContainerInfo
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &actual))
+	if assert.Len(t, actual["/docker/a"].Stats, 1) {
+		assert.True(t, start.Add(2*time.Second).Equal(actual["/docker/a"].Stats[0].Timestamp))
+	}
+
+	w = httptest.NewRecorder()
+	since = start.Add(2 * time.Second).Format(time.RFC3339Nano)
+	err = api.HandleRequest(statsAPI, []string{"docker", "a"}, m, w, makeHTTPRequest("http://localhost:8080/api/v2.1/stats/docker/a?since="+since, t))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Empty(t, w.Body.Bytes())
+
+	err = api.HandleRequest(statsAPI, []string{"docker", "a"}, m, httptest.NewRecorder(), makeHTTPRequest("http://localhost:8080/api/v2.1/stats/docker/a?since=yesterday", t))
+	assert.Error(t, err)
+}
+
+func TestStatsRangeRequest(t *testing.T) {
+	m := fake.NewManager()
+	m.AddContainer(info.ContainerReference{Name: "/docker/a"}, info.ContainerSpec{HasCpu: true})
+	m.AddContainer(info.ContainerReference{Name: "/docker/b"}, info.ContainerSpec{HasCpu: true})
+	start := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 100; i++ {
+		for _, name := range []string{"/docker/a", "/docker/b"} {
+			assert.NoError(t, m.AddStats(name, &info.ContainerStats{Timestamp: start.Add(time.Duration(i) * time.Second)}))
+		}
+	}
+
+	api := newVersion2_2(newVersion2_1(newVersion2_0()))
+	for _, test := range []struct {
+		query       string
+		first, last time.Duration
+	}{
+		// All the stats of the range, inclusive, beyond the default count.
+		{"start=2023-05-01T10:00:10Z&end=2023-05-01T10:01:29Z", 10 * time.Second, 89 * time.Second},
+		{"start=2023-05-01T10:01:30.5Z", 91 * time.Second, 99 * time.Second},
+		// The latest of the range with a count.
+		{"end=2023-05-01T10:00:30Z&count=5", 26 * time.Second, 30 * time.Second},
+	} {
+		w := httptest.NewRecorder()
+		require.NoError(t, api.HandleRequest(statsAPI, []string{"docker"}, m, w, makeHTTPRequest("http://localhost:8080/api/v2.1/stats/docker?recursive=true&"+test.query, t)), test.query)
+		var actual map[string]v2.ContainerInfo
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &actual), test.query)
+		for _, name := range []string{"/docker/a", "/docker/b"} {
+			stats := actual[name].Stats
+			require.NotEmpty(t, stats, test.query)
+			assert.Equal(t, int((test.last-test.first)/time.Second)+1, len(stats), test.query)
+			assert.True(t, start.Add(test.first).Equal(stats[0].Timestamp), test.query)
+			assert.True(t, start.Add(test.last).Equal(stats[len(stats)-1].Timestamp), test.query)
+		}
+	}
+
+	for _, query := range []string{"end=yesterday", "start=2023-05-01T11:00:00Z&end=2023-05-01T10:00:00Z", "end=2023-05-01T11:00:00Z&since=2023-05-01T10:00:00Z", "start=2023-05-01T11:00:00Z&aligned=true", "end=2023-05-01T11:00:00Z&stream=true"} {
+		r := makeHTTPRequest("http://localhost:8080/api/v2.1/stats/docker/a?"+query, t)
+		err := api.HandleRequest(statsAPI, []string{"docker", "a"}, m, httptest.NewRecorder(), r)
+		require.Error(t, err, query)
+		assert.Equal(t, http.StatusBadRequest, newErrorResponse(r, err).Status, query)
+	}
+}
+
+func TestStatsStepRequest(t *testing.T) {
+	m := fake.NewManager()
+	m.AddContainer(info.ContainerReference{Name: "/docker/a"}, info.ContainerSpec{HasCpu: true, HasMemory: true})
+	start := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 120; i++ {
+		assert.NoError(t, m.AddStats("/docker/a", &info.ContainerStats{
+			Timestamp: start.Add(time.Duration(i) * time.Second),
+			Cpu:       info.CpuStats{Usage: info.CpuUsage{Total: uint64(i) * 1e9}},
+			Memory:    info.MemoryStats{Usage: uint64(i)},
+		}))
+	}
+
+	api := newVersion2_2(newVersion2_1(newVersion2_0()))
+	for _, test := range []struct {
+		query  string
+		first  time.Duration
+		memory []uint64
+	}{
+		// All the stats are downsampled beyond the default count.
+		{"step=30s", 29 * time.Second, []uint64{29, 59, 89, 119}},
+		{"step=30s&agg=first", 0, []uint64{0, 30, 60, 90}},
+		{"step=1m&agg=avg", 59 * time.Second, []uint64{29, 89}},
+		{"step=1m&agg=max", 59 * time.Second, []uint64{59, 119}},
+		{"step=1m&agg=min", 59 * time.Second, []uint64{0, 60}},
+		{"step=30s&agg=sum", 29 * time.Second, []uint64{435, 1335, 2235, 3135}},
+		// The count applies before the downsampling.
+		{"step=1m&count=30", 119 * time.Second, []uint64{119}},
+	} {
+		w := httptest.NewRecorder()
+		require.NoError(t, api.HandleRequest(statsAPI, []string{"docker", "a"}, m, w, makeHTTPRequest("http://localhost:8080/api/v2.1/stats/docker/a?"+test.query, t)), test.query)
+		var actual map[string]v2.ContainerInfo
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &actual), test.query)
+		stats := actual["/docker/a"].Stats
+		require.Len(t, stats, len(test.memory), test.query)
+		assert.True(t, start.Add(test.first).Equal(stats[0].Timestamp), test.query)
+		for i, s := range stats {
+			assert.Equal(t, test.memory[i], s.Memory.Usage, test.query)
+		}
+		// The instantaneous CPU usage is averaged between the samples.
+		if len(stats) > 1 {
+			assert.Equal(t, uint64(1e9), stats[1].CpuInst.Usage.Total, test.query)
+		}
+	}
+
+	for _, query := range []string{"step=0s", "step=often", "agg=avg", "step=1m&agg=median", "step=1m&aligned=true", "step=1m&stream=true"} {
+		r := makeHTTPRequest("http://localhost:8080/api/v2.1/stats/docker/a?"+query, t)
+		err := api.HandleRequest(statsAPI, []string{"docker", "a"}, m, httptest.NewRecorder(), r)
+		require.Error(t, err, query)
+		assert.Equal(t, http.StatusBadRequest, newErrorResponse(r, err).Status, query)
+	}
+}
+
+func TestMachineStatsRangeRequest(t *testing.T) {
+	m := fake.NewManager()
+	start := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 100; i++ {
+		assert.NoError(t, m.AddStats("/", &info.ContainerStats{Timestamp: start.Add(time.Duration(i) * time.Minute)}))
+	}
+
+	api := newVersion2_2(newVersion2_1(newVersion2_0()))
+	for _, test := range []struct {
+		query    string
+		expected []time.Time
+	}{
+		// All the stats of the range, inclusive.
+		{"start=2023-05-01T10:10:00Z&end=2023-05-01T11:29:00Z", []time.Time{start.Add(10 * time.Minute), start.Add(89 * time.Minute)}},
+		{"start=2023-05-01T11:30:00Z", []time.Time{start.Add(90 * time.Minute), start.Add(99 * time.Minute)}},
+		// The latest of the range with a count.
+		{"end=2023-05-01T10:30:00Z&count=5", []time.Time{start.Add(26 * time.Minute), start.Add(30 * time.Minute)}},
+	} {
+		w := httptest.NewRecorder()
+		require.NoError(t, api.HandleRequest(machineStatsAPI, nil, m, w, makeHTTPRequest("http://localhost:8080/api/v2.1/machinestats?"+test.query, t)), test.query)
+		var actual []v2.MachineStats
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &actual), test.query)
+		require.NotEmpty(t, actual, test.query)
+		assert.Equal(t, int(test.expected[1].Sub(test.expected[0])/time.Minute)+1, len(actual), test.query)
+		assert.True(t, test.expected[0].Equal(actual[0].Timestamp), test.query)
+		assert.True(t, test.expected[1].Equal(actual[len(actual)-1].Timestamp), test.query)
+	}
+
+	// The range downsampled to the last sample of every 10 minutes.
+	w := httptest.NewRecorder()
+	require.NoError(t, api.HandleRequest(machineStatsAPI, nil, m, w, makeHTTPRequest("http://localhost:8080/api/v2.1/machinestats?start=2023-05-01T10:30:00Z&step=10m", t)))
+	var actual []v2.MachineStats
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &actual))
+	require.Len(t, actual, 7)
+	for i, stat := range actual {
+		assert.True(t, start.Add(time.Duration(39+10*i)*time.Minute).Equal(stat.Timestamp), i)
+	}
+
+	for _, query := range []string{"start=yesterday", "start=2023-05-01T11:00:00Z&end=2023-05-01T10:00:00Z", "start=2023-05-01T11:00:00Z&since=2023-05-01T11:00:00Z", "end=2023-05-01T11:00:00Z&aligned=true"} {
+		r := makeHTTPRequest("http://localhost:8080/api/v2.1/machinestats?"+query, t)
+		err := api.HandleRequest(machineStatsAPI, nil, m, httptest.NewRecorder(), r)
+		require.Error(t, err, query)
+		assert.Equal(t, http.StatusBadRequest, newErrorResponse(r, err).Status, query)
+	}
+}
+
+func TestStatsFieldsRequest(t *testing.T) {
+	m := fake.NewManager()
+	m.AddContainer(info.ContainerReference{Name: "/docker/a"}, info.ContainerSpec{HasCpu: true, HasMemory: true, HasNetwork: true})
+	stats := &info.ContainerStats{Timestamp: time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC), Sequence: 1}
+	stats.Cpu.Usage.Total = 1000
+	stats.Memory.Usage = 2048
+	assert.NoError(t, m.AddStats("/docker/a", stats))
+
+	api := newVersion2_2(newVersion2_1(newVersion2_0()))
+	w := httptest.NewRecorder()
+	err := api.HandleRequest(statsAPI, []string{"docker", "a"}, m, w, makeHTTPRequest("http://localhost:8080/api/v2.1/stats/docker/a?fields=cpu,memory", t))
+	assert.NoError(t, err)
+	var actual map[string]v2.ContainerInfo
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &actual))
+	if assert.Len(t, actual["/docker/a"].Stats, 1) {
+		sample := actual["/docker/a"].Stats[0]
+		assert.Equal(t, uint64(1000), sample.Cpu.Usage.Total)
+		assert.Equal(t, uint64(2048), sample.Memory.Usage)
+		assert.Nil(t, sample.Network)
+		assert.Equal(t, uint64(1), sample.Sequence)
+	}
+	assert.NotContains(t, w.Body.String(), `"network"`)
+
+	err = api.HandleRequest(statsAPI, []string{"docker", "a"}, m, httptest.NewRecorder(), makeHTTPRequest("http://localhost:8080/api/v2.1/stats/docker/a?fields=cpu,gpu", t))
+	assert.EqualError(t, err, `unknown 'fields' option "gpu"`)
+}
+
+func TestStatsPerCpuRequest(t *testing.T) {
+	m := fake.NewManager()
+	m.SetMachineInfo(info.MachineInfo{Topology: []info.Node{
+		{Id: 0, Cores: []info.Core{{SocketID: 0, Threads: []int{0, 2}}}},
+		{Id: 1, Cores: []info.Core{{SocketID: 1, Threads: []int{1, 3}}}},
+	}})
+	m.AddContainer(info.ContainerReference{Name: "/docker/a"}, info.ContainerSpec{HasCpu: true})
+	for i := uint64(1); i <= 2; i++ {
+		stats := &info.ContainerStats{Timestamp: time.Date(2023, 5, 1, 10, 0, int(i), 0, time.UTC)}
+		stats.Cpu.Usage.PerCpu = []uint64{i * 1, i * 2, i * 3, i * 4}
+		stats.Cpu.Usage.Total = i * 10
+		assert.NoError(t, m.AddStats("/docker/a", stats))
+	}
+
+	api := newVersion2_2(newVersion2_1(newVersion2_0()))
+	w := httptest.NewRecorder()
+	err := api.HandleRequest(statsAPI, []string{"docker", "a"}, m, w, makeHTTPRequest("http://localhost:8080/api/v2.1/stats/docker/a?percpu=socket", t))
+	assert.NoError(t, err)
+	var actual map[string]v2.ContainerInfo
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &actual))
+	if assert.Len(t, actual["/docker/a"].Stats, 2) {
+		sample := actual["/docker/a"].Stats[1]
+		assert.Nil(t, sample.Cpu.Usage.PerCpu)
+		assert.Equal(t, []uint64{8, 12}, sample.Cpu.Usage.PerSocket)
+		assert.Equal(t, []uint64{4, 6}, sample.CpuInst.Usage.PerSocket)
+	}
+	assert.NotContains(t, w.Body.String(), `"per_cpu_usage"`)
+
+	// The stats of the manager are not modified.
+	w = httptest.NewRecorder()
+	err = api.HandleRequest(statsAPI, []string{"docker", "a"}, m, w, makeHTTPRequest("http://localhost:8080/api/v2.1/stats/docker/a?percpu=full", t))
+	assert.NoError(t, err)
+	assert.Contains(t, w.Body.String(), `"per_cpu_usage":[2,4,6,8]`)
+
+	w = httptest.NewRecorder()
+	err = api.HandleRequest(statsAPI, []string{"docker", "a"}, m, w, makeHTTPRequest("http://localhost:8080/api/v2.1/stats/docker/a?percpu=off", t))
+	assert.NoError(t, err)
+	assert.NotContains(t, w.Body.String(), `"per_cpu_usage"`)
+	assert.NotContains(t, w.Body.String(), `"per_socket_usage"`)
+
+	err = api.HandleRequest(statsAPI, []string{"docker", "a"}, m, httptest.NewRecorder(), makeHTTPRequest("http://localhost:8080/api/v2.1/stats/docker/a?percpu=core", t))
+	assert.EqualError(t, err, `invalid 'percpu' option "core": must be full, socket or off`)
+}
+
+func TestStatsLabelSelectorRequest(t *testing.T) {
+	m := fake.NewManager()
+	m.AddContainer(info.ContainerReference{Name: "/docker/a"}, info.ContainerSpec{Labels: map[string]string{"io.kubernetes.pod.namespace": "prod"}})
+	m.AddContainer(info.ContainerReference{Name: "/docker/b"}, info.ContainerSpec{Labels: map[string]string{"io.kubernetes.pod.namespace": "dev"}})
+	for _, name := range []string{"/docker/a", "/docker/b"} {
+		assert.NoError(t, m.AddStats(name, &info.ContainerStats{Timestamp: time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)}))
+	}
+
+	api := newVersion2_2(newVersion2_1(newVersion2_0()))
+	w := httptest.NewRecorder()
+	err := api.HandleRequest(statsAPI, []string{"docker"}, m, w, makeHTTPRequest("http://localhost:8080/api/v2.1/stats/docker?recursive=true&label_selector=io.kubernetes.pod.namespace%3Dprod", t))
+	assert.NoError(t, err)
+	var actual map[string]v2.ContainerInfo
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &actual))
+	assert.Len(t, actual, 1)
+	assert.Contains(t, actual, "/docker/a")
+
+	err = api.HandleRequest(statsAPI, []string{"docker"}, m, httptest.NewRecorder(), makeHTTPRequest("http://localhost:8080/api/v2.1/stats/docker?label_selector=%3Dprod", t))
+	assert.EqualError(t, err, `failed to parse 'label_selector' option: invalid label selector requirement "=prod"`)
+}
+
+func TestBatchStatsRequest(t *testing.T) {
+	m := fake.NewManager()
+	for _, name := range []string{"/docker/a", "/docker/b", "/docker/c"} {
+		m.AddContainer(info.ContainerReference{Name: name}, info.ContainerSpec{HasCpu: true})
+		assert.NoError(t, m.AddStats(name, &info.ContainerStats{Timestamp: time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)}))
+	}
+
+	api := newVersion2_2(newVersion2_1(newVersion2_0()))
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "http://localhost:8080/api/v2.1/stats/batch?count=1", strings.NewReader(`["/docker/a", "docker/c", "/missing"]`))
+	err := api.HandleRequest(statsAPI, []string{batchArgument}, m, w, r)
+	assert.NoError(t, err)
+	var actual map[string]v2.ContainerInfo
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &actual))
+	assert.Len(t, actual, 2)
+	assert.Len(t, actual["/docker/a"].Stats, 1)
+	assert.Len(t, actual["/docker/c"].Stats, 1)
+
+	r = httptest.NewRequest(http.MethodPost, "http://localhost:8080/api/v2.1/stats/batch", strings.NewReader(`{"name": "/docker/a"}`))
+	err = api.HandleRequest(statsAPI, []string{batchArgument}, m, httptest.NewRecorder(), r)
+	assert.Error(t, err)
+}
+
+func TestV3Pagination(t *testing.T) {
+	m := fake.NewManager()
+	start := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	for _, name := range []string{"/docker/c", "/docker/a", "/docker/b", "/system"} {
+		m.AddContainer(info.ContainerReference{Name: name}, info.ContainerSpec{HasCpu: true})
+		assert.NoError(t, m.AddStats(name, &info.ContainerStats{Timestamp: start}))
+	}
+	api := newVersion3_0(newVersion2_2(newVersion2_1(newVersion2_0())))
+
+	getPage := func(requestType, url string, items interface{}) string {
+		w := httptest.NewRecorder()
+		err := api.HandleRequest(requestType, []string{"docker"}, m, w, makeHTTPRequest(url, t))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, w.Code)
+		page := Page{Items: items}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+		return page.NextCursor
+	}
+
+	// "/docker" and its 3 subcontainers, 2 per page.
+	var names []string
+	cursor := ""
+	for i := 0; i < 3; i++ {
+		var items []ContainerItem
+		cursor = getPage(containersAPI, "http://localhost:8080/api/v3.0/containers/docker?limit=2&cursor="+cursor, &items)
+		for _, item := range items {
+			assert.NotNil(t, item.Spec)
+			names = append(names, item.Name)
+		}
+		if cursor == "" {
+			break
+		}
+	}
+	assert.Equal(t, []string{"/docker", "/docker/a", "/docker/b", "/docker/c"}, names)
+	assert.Empty(t, cursor)
+
+	// A container removed before the cursor does not shift the next page.
+	var items []ContainerItem
+	cursor = getPage(statsAPI, "http://localhost:8080/api/v3.0/stats/docker?recursive=true&limit=2", &items)
+	if assert.Len(t, items, 2) {
+		assert.Equal(t, "/docker/a", items[1].Name)
+		assert.Len(t, items[1].Stats, 1)
+	}
+	m.RemoveContainer("/docker/a")
+	cursor = getPage(statsAPI, "http://localhost:8080/api/v3.0/stats/docker?recursive=true&limit=2&cursor="+cursor, &items)
+	if assert.Len(t, items, 2) {
+		assert.Equal(t, "/docker/b", items[0].Name)
+		assert.Equal(t, "/docker/c", items[1].Name)
+	}
+	assert.Empty(t, cursor)
+
+	var infos []info.ContainerInfo
+	getPage(subcontainersAPI, "http://localhost:8080/api/v3.0/subcontainers/docker?limit=1&count=1", &infos)
+	if assert.Len(t, infos, 1) {
+		assert.Equal(t, "/docker", infos[0].Name)
+	}
+
+	for _, url := range []string{
+		"http://localhost:8080/api/v3.0/containers/docker?limit=0",
+		"http://localhost:8080/api/v3.0/containers/docker?limit=5000",
+		"http://localhost:8080/api/v3.0/containers/docker?cursor=%25",
+	} {
+		err := api.HandleRequest(containersAPI, []string{"docker"}, m, httptest.NewRecorder(), makeHTTPRequest(url, t))
+		assert.Error(t, err, url)
+	}
+}
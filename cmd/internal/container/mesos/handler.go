@@ -192,6 +192,10 @@ func (h *mesosContainerHandler) ListProcesses(listType container.ListType) ([]in
 	return h.libcontainerHandler.GetProcesses()
 }
 
+func (h *mesosContainerHandler) DisabledMetrics() []container.DisabledMetrics {
+	return h.libcontainerHandler.DisabledMetrics()
+}
+
 func (h *mesosContainerHandler) Exists() bool {
 	return common.CgroupExists(h.cgroupPaths)
 }
@@ -0,0 +1,360 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package federation serves the containers of remote cAdvisor instances
+// alongside the local ones.
+//
+// Remote containers are namespaced by host: container "/docker/abc" of the
+// remote "node-1" is served as "/federated/node-1/docker/abc".
+package federation
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yidoyoon/cadvisor-lite/client"
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+	v2 "github.com/yidoyoon/cadvisor-lite/info/v2"
+	"github.com/yidoyoon/cadvisor-lite/manager"
+
+	"k8s.io/klog/v2"
+)
+
+// Root is the parent of the containers of all remote instances.
+const Root = "/federated"
+
+// Number of stats fetched per container on every scrape.
+const scrapeNumStats = 60
+
+type remote struct {
+	name   string
+	url    string
+	client *client.Client
+
+	lock sync.RWMutex
+	// Containers of the remote, keyed by their federated name.
+	containers map[string]*info.ContainerInfo
+	lastScrape time.Time
+	lastErr    error
+}
+
+// Manager is a manager.Manager that also serves the containers scraped from
+// remote cAdvisor instances. Calls it does not override go to the local
+// manager only.
+type Manager struct {
+	manager.Manager
+
+	remotes  []*remote
+	interval time.Duration
+	quit     chan struct{}
+}
+
+// ParseRemotes parses a comma-separated list of remotes. Every remote is
+// either a URL, named after its host, or "name=URL".
+func ParseRemotes(s string) (map[string]string, error) {
+	remotes := make(map[string]string)
+	for _, r := range strings.Split(s, ",") {
+		r = strings.TrimSpace(r)
+		if r == "" {
+			continue
+		}
+		name, rawURL, found := strings.Cut(r, "=")
+		if !found {
+			rawURL = name
+			name = ""
+		}
+		u, err := url.Parse(rawURL)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("invalid remote URL %q", rawURL)
+		}
+		if name == "" {
+			name = u.Hostname()
+		}
+		if name == "" || strings.Contains(name, "/") {
+			return nil, fmt.Errorf("invalid remote name %q", name)
+		}
+		if _, ok := remotes[name]; ok {
+			return nil, fmt.Errorf("duplicate remote name %q, use name=URL to disambiguate", name)
+		}
+		remotes[name] = rawURL
+	}
+	return remotes, nil
+}
+
+// New returns a Manager serving the containers of local and of the remotes,
+// keyed by name, which are scraped every interval.
+func New(local manager.Manager, remotes map[string]string, interval time.Duration) (*Manager, error) {
+	m := &Manager{
+		Manager:  local,
+		interval: interval,
+		quit:     make(chan struct{}),
+	}
+	for name, u := range remotes {
+		c, err := client.NewClient(u)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create client for remote %q: %v", name, err)
+		}
+		m.remotes = append(m.remotes, &remote{
+			name:       name,
+			url:        u,
+			client:     c,
+			containers: map[string]*info.ContainerInfo{},
+		})
+	}
+	sort.Slice(m.remotes, func(i, j int) bool { return m.remotes[i].name < m.remotes[j].name })
+	return m, nil
+}
+
+// Start starts the local manager and the scraping of the remotes.
+func (m *Manager) Start() error {
+	if err := m.Manager.Start(); err != nil {
+		return err
+	}
+	for _, r := range m.remotes {
+		go m.scrapeLoop(r)
+	}
+	return nil
+}
+
+// Stop stops the scraping of the remotes and the local manager.
+func (m *Manager) Stop() error {
+	close(m.quit)
+	return m.Manager.Stop()
+}
+
+func (m *Manager) scrapeLoop(r *remote) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+	for {
+		r.scrape()
+		select {
+		case <-ticker.C:
+		case <-m.quit:
+			return
+		}
+	}
+}
+
+// Fetches all containers of the remote. On failure, the last scraped
+// containers are kept.
+func (r *remote) scrape() {
+	containers, err := r.client.SubcontainersInfo("/", &info.ContainerInfoRequest{NumStats: scrapeNumStats})
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.lastErr = err
+	if err != nil {
+		klog.Warningf("Failed to scrape remote %q at %s: %v", r.name, r.url, err)
+		return
+	}
+	r.lastScrape = time.Now()
+	r.containers = make(map[string]*info.ContainerInfo, len(containers))
+	for i := range containers {
+		cont := &containers[i]
+		cont.Name = r.federatedName(cont.Name)
+		for j := range cont.Subcontainers {
+			cont.Subcontainers[j].Name = r.federatedName(cont.Subcontainers[j].Name)
+		}
+		r.containers[cont.Name] = cont
+	}
+}
+
+func (r *remote) root() string {
+	return path.Join(Root, r.name)
+}
+
+func (r *remote) federatedName(name string) string {
+	return path.Join(r.root(), name)
+}
+
+// Returns a copy of the named container of the remote, and of its
+// subcontainers if recursive, with at most numStats stats each.
+func (r *remote) get(name string, recursive bool, numStats int) []*info.ContainerInfo {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	var result []*info.ContainerInfo
+	for contName, cont := range r.containers {
+		if contName != name && !(recursive && isSubcontainer(contName, name)) {
+			continue
+		}
+		c := *cont
+		if numStats >= 0 && len(c.Stats) > numStats {
+			c.Stats = c.Stats[len(c.Stats)-numStats:]
+		}
+		result = append(result, &c)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+func isSubcontainer(name, parent string) bool {
+	return parent == "/" || strings.HasPrefix(name, parent+"/")
+}
+
+// Returns the remotes whose containers are named by or nested in name.
+func (m *Manager) remotesFor(name string) []*remote {
+	if name == "/" || name == Root {
+		return m.remotes
+	}
+	for _, r := range m.remotes {
+		if name == r.root() || isSubcontainer(name, r.root()) {
+			return []*remote{r}
+		}
+	}
+	return nil
+}
+
+func isFederated(name string) bool {
+	return name == Root || strings.HasPrefix(name, Root+"/")
+}
+
+func queryNumStats(query *info.ContainerInfoRequest) int {
+	if query == nil || query.NumStats == 0 {
+		return 60
+	}
+	return query.NumStats
+}
+
+// The remote containers named by name, and their subcontainers if recursive.
+func (m *Manager) remoteContainers(name string, recursive bool, numStats int) []*info.ContainerInfo {
+	var result []*info.ContainerInfo
+	for _, r := range m.remotesFor(name) {
+		if name == "/" || name == Root {
+			if recursive {
+				result = append(result, r.get(r.root(), true, numStats)...)
+			}
+			continue
+		}
+		result = append(result, r.get(name, recursive, numStats)...)
+	}
+	return result
+}
+
+// Lists the root container of every remote.
+func (m *Manager) rootInfo() *info.ContainerInfo {
+	cont := &info.ContainerInfo{
+		ContainerReference: info.ContainerReference{Name: Root},
+	}
+	for _, r := range m.remotes {
+		cont.Subcontainers = append(cont.Subcontainers, info.ContainerReference{Name: r.root()})
+	}
+	return cont
+}
+
+func (m *Manager) GetContainerInfo(containerName string, query *info.ContainerInfoRequest) (*info.ContainerInfo, error) {
+	if !isFederated(containerName) {
+		cont, err := m.Manager.GetContainerInfo(containerName, query)
+		if err == nil && containerName == "/" && len(m.remotes) > 0 {
+			cont.Subcontainers = append(cont.Subcontainers, info.ContainerReference{Name: Root})
+		}
+		return cont, err
+	}
+	if containerName == Root {
+		return m.rootInfo(), nil
+	}
+	conts := m.remoteContainers(containerName, false, queryNumStats(query))
+	if len(conts) == 0 {
+		return nil, fmt.Errorf("unknown container %q", containerName)
+	}
+	return conts[0], nil
+}
+
+func (m *Manager) SubcontainersInfo(containerName string, query *info.ContainerInfoRequest) ([]*info.ContainerInfo, error) {
+	if !isFederated(containerName) {
+		conts, err := m.Manager.SubcontainersInfo(containerName, query)
+		if containerName == "/" {
+			conts = append(conts, m.remoteContainers("/", true, queryNumStats(query))...)
+		}
+		return conts, err
+	}
+	conts := m.remoteContainers(containerName, true, queryNumStats(query))
+	if containerName == Root {
+		conts = append([]*info.ContainerInfo{m.rootInfo()}, conts...)
+	}
+	return conts, nil
+}
+
+func (m *Manager) GetContainerInfoV2(containerName string, options v2.RequestOptions) (map[string]v2.ContainerInfo, error) {
+	var infos map[string]v2.ContainerInfo
+	var err error
+	if !isFederated(containerName) {
+		infos, err = m.Manager.GetContainerInfoV2(containerName, options)
+		if infos == nil || options.IdType != v2.TypeName {
+			return infos, err
+		}
+	} else {
+		infos = make(map[string]v2.ContainerInfo)
+	}
+	for _, cont := range m.remoteContainers(containerName, options.Recursive, options.Count) {
+		infos[cont.Name] = v2.ContainerInfo{
+			Spec:  v2.ContainerSpecFromV1(&cont.Spec, cont.Aliases, cont.Namespace),
+			Stats: v2.ContainerStatsFromV1(cont.Name, &cont.Spec, cont.Stats),
+		}
+	}
+	if len(infos) == 0 && err == nil {
+		return nil, fmt.Errorf("unknown container %q", containerName)
+	}
+	return infos, err
+}
+
+func (m *Manager) GetRequestedContainersInfo(containerName string, options v2.RequestOptions) (map[string]*info.ContainerInfo, error) {
+	var infos map[string]*info.ContainerInfo
+	var err error
+	if !isFederated(containerName) {
+		infos, err = m.Manager.GetRequestedContainersInfo(containerName, options)
+		if infos == nil || options.IdType != v2.TypeName {
+			return infos, err
+		}
+	} else {
+		infos = make(map[string]*info.ContainerInfo)
+	}
+	for _, cont := range m.remoteContainers(containerName, options.Recursive, options.Count) {
+		infos[cont.Name] = cont
+	}
+	if len(infos) == 0 && err == nil {
+		return nil, fmt.Errorf("unknown container %q", containerName)
+	}
+	return infos, err
+}
+
+func (m *Manager) Exists(containerName string) bool {
+	if !isFederated(containerName) {
+		return m.Manager.Exists(containerName)
+	}
+	if containerName == Root {
+		return true
+	}
+	return len(m.remoteContainers(containerName, false, 0)) > 0
+}
+
+func (m *Manager) DebugInfo() map[string][]string {
+	debugInfo := m.Manager.DebugInfo()
+	lines := make([]string, 0, len(m.remotes))
+	for _, r := range m.remotes {
+		r.lock.RLock()
+		status := "ok"
+		if r.lastErr != nil {
+			status = r.lastErr.Error()
+		}
+		lines = append(lines, fmt.Sprintf("%s: url=%s containers=%d last_scrape=%s status=%s",
+			r.name, r.url, len(r.containers), r.lastScrape.Format(time.RFC3339), status))
+		r.lock.RUnlock()
+	}
+	debugInfo["Federation"] = lines
+	return debugInfo
+}
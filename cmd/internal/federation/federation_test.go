@@ -0,0 +1,123 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package federation
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+	v2 "github.com/yidoyoon/cadvisor-lite/info/v2"
+	"github.com/yidoyoon/cadvisor-lite/manager"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Serves the local root container only.
+type localManager struct {
+	manager.Manager
+}
+
+func (localManager) GetContainerInfo(name string, query *info.ContainerInfoRequest) (*info.ContainerInfo, error) {
+	return &info.ContainerInfo{ContainerReference: info.ContainerReference{Name: name}}, nil
+}
+
+func (localManager) SubcontainersInfo(name string, query *info.ContainerInfoRequest) ([]*info.ContainerInfo, error) {
+	return []*info.ContainerInfo{{ContainerReference: info.ContainerReference{Name: name}}}, nil
+}
+
+func (localManager) GetRequestedContainersInfo(name string, options v2.RequestOptions) (map[string]*info.ContainerInfo, error) {
+	return map[string]*info.ContainerInfo{name: {ContainerReference: info.ContainerReference{Name: name}}}, nil
+}
+
+func (localManager) Exists(name string) bool {
+	return name == "/"
+}
+
+func newRemoteServer(t *testing.T) *httptest.Server {
+	now := time.Now()
+	containers := []info.ContainerInfo{
+		{
+			ContainerReference: info.ContainerReference{Name: "/"},
+			Subcontainers:      []info.ContainerReference{{Name: "/docker"}},
+			Stats:              []*info.ContainerStats{{Timestamp: now.Add(-time.Second)}, {Timestamp: now}},
+		},
+		{
+			ContainerReference: info.ContainerReference{Name: "/docker"},
+			Stats:              []*info.ContainerStats{{Timestamp: now}},
+		},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1.3/subcontainers", r.URL.Path)
+		assert.NoError(t, json.NewEncoder(w).Encode(containers))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestParseRemotes(t *testing.T) {
+	remotes, err := ParseRemotes("http://node-1:8080, b=http://node-1:8081,")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"node-1": "http://node-1:8080", "b": "http://node-1:8081"}, remotes)
+
+	for _, s := range []string{
+		"node-1:8080",
+		"http://node-1:8080,http://node-1:8081",
+		"a/b=http://node-1:8080",
+	} {
+		_, err := ParseRemotes(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestFederatedContainers(t *testing.T) {
+	server := newRemoteServer(t)
+	m, err := New(localManager{}, map[string]string{"node-1": server.URL}, time.Minute)
+	require.NoError(t, err)
+	m.remotes[0].scrape()
+	require.NoError(t, m.remotes[0].lastErr)
+
+	root, err := m.GetContainerInfo("/", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []info.ContainerReference{{Name: Root}}, root.Subcontainers)
+
+	remoteRoot, err := m.GetContainerInfo("/federated/node-1", &info.ContainerInfoRequest{NumStats: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []info.ContainerReference{{Name: "/federated/node-1/docker"}}, remoteRoot.Subcontainers)
+	assert.Len(t, remoteRoot.Stats, 1)
+
+	_, err = m.GetContainerInfo("/federated/node-2", nil)
+	assert.Error(t, err)
+
+	all, err := m.SubcontainersInfo("/", nil)
+	require.NoError(t, err)
+	var names []string
+	for _, cont := range all {
+		names = append(names, cont.Name)
+	}
+	assert.Equal(t, []string{"/", "/federated/node-1", "/federated/node-1/docker"}, names)
+
+	infos, err := m.GetRequestedContainersInfo("/", v2.RequestOptions{IdType: v2.TypeName, Count: 1, Recursive: true})
+	require.NoError(t, err)
+	assert.Len(t, infos, 3)
+	assert.Contains(t, infos, "/federated/node-1/docker")
+
+	assert.True(t, m.Exists("/federated/node-1/docker"))
+	assert.False(t, m.Exists("/federated/node-1/podman"))
+}
@@ -0,0 +1,224 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpc
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/yidoyoon/cadvisor-lite/cmd/internal/api"
+	cadvisorhttp "github.com/yidoyoon/cadvisor-lite/cmd/internal/http"
+	"github.com/yidoyoon/cadvisor-lite/cmd/internal/http/identity"
+
+	"k8s.io/klog/v2"
+)
+
+// Request types of the methods of the API, as named by the HTTP API for the
+// authorization policy and the audit log.
+var methodRequestTypes = map[string]string{
+	"/cadvisor.v1.Cadvisor/GetContainerInfo": "containers",
+	"/cadvisor.v1.Cadvisor/GetMachineInfo":   "machine",
+	"/cadvisor.v1.Cadvisor/StreamStats":      "stats",
+}
+
+// Security configures the transport security, authentication, authorization,
+// audit and limits of the gRPC API, the same way as the ones of the HTTP API.
+// The zero value serves plain text to all clients.
+type Security struct {
+	// TLS config of the server, nil to serve plain text. The clients must
+	// present a certificate verified by it if it has client CAs.
+	TLS *tls.Config
+	// Verifiers of the bearer tokens of the "authorization" metadata of the
+	// calls, which are then required.
+	TokenVerifiers []cadvisorhttp.TokenVerifier
+	// Request types the clients may access, nil to allow them all.
+	Policy *api.AuthorizationPolicy
+	// Log the calls are recorded in, nil to not record them.
+	AuditLog *api.AuditLog
+	// Limits of the calls.
+	Limits cadvisorhttp.Limits
+}
+
+// ServerOptions returns the options of a server enforcing s.
+func (s Security) ServerOptions() []grpc.ServerOption {
+	var opts []grpc.ServerOption
+	if s.TLS != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(s.TLS)))
+	}
+	g := &guard{security: s, limiter: cadvisorhttp.NewLimiter(s.Limits)}
+	return append(opts, grpc.UnaryInterceptor(g.unary), grpc.StreamInterceptor(g.stream))
+}
+
+type guard struct {
+	security Security
+	limiter  *cadvisorhttp.Limiter
+}
+
+func (g *guard) unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	var resp interface{}
+	err := g.serve(ctx, info.FullMethod, false, func(ctx context.Context) error {
+		var err error
+		resp, err = handler(ctx, req)
+		return err
+	})
+	return resp, err
+}
+
+func (g *guard) stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return g.serve(ss.Context(), info.FullMethod, info.IsServerStream, func(ctx context.Context) error {
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	})
+}
+
+// serve calls handler with the identity of the client in its context if the
+// call is allowed, and records the call in the audit log.
+func (g *guard) serve(ctx context.Context, method string, stream bool, handler func(context.Context) error) error {
+	start := time.Now()
+	remoteAddr := ""
+	if p, ok := peer.FromContext(ctx); ok {
+		remoteAddr = p.Addr.String()
+	}
+	id, release, err := g.authorize(ctx, method, remoteAddr, stream)
+	if err == nil {
+		err = handler(identity.NewContext(ctx, id))
+		release()
+	}
+	if g.security.AuditLog != nil {
+		g.security.AuditLog.Record(&api.AuditRecord{
+			Time:           start,
+			Identity:       id,
+			RemoteAddr:     remoteAddr,
+			Method:         "gRPC",
+			Path:           method,
+			RequestType:    methodRequestTypes[method],
+			Status:         httpStatus(status.Code(err)),
+			LatencySeconds: time.Since(start).Seconds(),
+		})
+	}
+	return err
+}
+
+// authorize returns the identity of the client of a call, empty if not
+// authenticated, and the function to call once the call is served, or an
+// error if the call is not allowed.
+func (g *guard) authorize(ctx context.Context, method, remoteAddr string, stream bool) (string, func(), error) {
+	var id string
+	if g.security.TLS != nil && g.security.TLS.ClientCAs != nil {
+		cert, ok := verifiedCert(ctx)
+		if !ok {
+			return "", nil, status.Error(codes.Unauthenticated, "client certificate required")
+		}
+		id = cadvisorhttp.CertIdentity(cert)
+	}
+	if len(g.security.TokenVerifiers) > 0 {
+		token, ok := bearerToken(ctx)
+		if !ok {
+			return id, nil, status.Error(codes.Unauthenticated, "bearer token required")
+		}
+		subject, err := cadvisorhttp.VerifyToken(g.security.TokenVerifiers, token, time.Now())
+		if err != nil {
+			klog.V(2).Infof("Rejected bearer token of %s from %s: %v", method, remoteAddr, err)
+			return id, nil, status.Error(codes.Unauthenticated, "invalid bearer token")
+		}
+		id = subject
+	}
+	if g.security.Policy != nil && !g.security.Policy.Allowed(id, methodRequestTypes[method]) {
+		klog.V(2).Infof("Denied %s from %s by %q", method, remoteAddr, id)
+		return id, nil, status.Errorf(codes.PermissionDenied, "access to %q requests denied", methodRequestTypes[method])
+	}
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	release, err := g.limiter.Admit(host, stream)
+	if err != nil {
+		var limitErr *cadvisorhttp.LimitError
+		if errors.As(err, &limitErr) && limitErr.RateLimited {
+			return id, nil, status.Error(codes.ResourceExhausted, err.Error())
+		}
+		return id, nil, status.Error(codes.Unavailable, err.Error())
+	}
+	klog.V(2).Infof("%s from %s by %q", method, remoteAddr, id)
+	return id, release, nil
+}
+
+// verifiedCert returns the verified certificate of the client of a call.
+func verifiedCert(ctx context.Context) (*x509.Certificate, bool) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil, false
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.VerifiedChains) == 0 {
+		return nil, false
+	}
+	return tlsInfo.State.VerifiedChains[0][0], true
+}
+
+// bearerToken returns the token of the "authorization" metadata of a call.
+func bearerToken(ctx context.Context) (string, bool) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get("authorization") {
+		scheme, token, ok := strings.Cut(value, " ")
+		if ok && strings.EqualFold(scheme, "Bearer") && strings.TrimSpace(token) != "" {
+			return strings.TrimSpace(token), true
+		}
+	}
+	return "", false
+}
+
+// httpStatus returns the HTTP status of a gRPC code, for the audit log.
+func httpStatus(code codes.Code) int {
+	switch code {
+	case codes.OK, codes.Canceled:
+		return http.StatusOK
+	case codes.InvalidArgument:
+		return http.StatusBadRequest
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// contextStream is a ServerStream with the context of the call replaced.
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,202 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/yidoyoon/cadvisor-lite/cmd/internal/api"
+	cadvisorhttp "github.com/yidoyoon/cadvisor-lite/cmd/internal/http"
+	"github.com/yidoyoon/cadvisor-lite/grpcapi"
+)
+
+// Accepts the tokens named after their holder, e.g. "token-alice".
+type fakeVerifier struct{}
+
+func (fakeVerifier) Verify(token string, now time.Time) (string, error) {
+	if strings.HasPrefix(token, "token-") {
+		return strings.TrimPrefix(token, "token-"), nil
+	}
+	return "", fmt.Errorf("unknown token")
+}
+
+func newSecureClient(t *testing.T, security Security, opts ...grpc.DialOption) grpcapi.CadvisorClient {
+	listener := bufconn.Listen(1 << 20)
+	server := NewServer(&fakeManager{samples: 1}, 10*time.Millisecond, security.ServerOptions()...)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	opts = append(opts, grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }))
+	conn, err := grpc.Dial("bufnet", opts...)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return grpcapi.NewCadvisorClient(conn)
+}
+
+func withToken(token string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+func TestSecurityTokensAndPolicy(t *testing.T) {
+	auditPath := filepath.Join(t.TempDir(), "audit.log")
+	auditLog, err := api.NewAuditLog(api.AuditConfig{Path: auditPath, SampleRate: 1})
+	require.NoError(t, err)
+	defer auditLog.Close()
+	client := newSecureClient(t, Security{
+		TokenVerifiers: []cadvisorhttp.TokenVerifier{fakeVerifier{}},
+		Policy: &api.AuthorizationPolicy{Rules: []api.AuthorizationRule{
+			{Identities: []string{"alice"}, RequestTypes: []string{"machine", "stats"}},
+		}},
+		AuditLog: auditLog,
+	}, grpc.WithTransportCredentials(insecure.NewCredentials()))
+
+	_, err = client.GetMachineInfo(context.Background(), &grpcapi.MachineInfoRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = client.GetMachineInfo(withToken("invalid"), &grpcapi.MachineInfoRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = client.GetMachineInfo(withToken("token-alice"), &grpcapi.MachineInfoRequest{})
+	assert.NoError(t, err)
+	_, err = client.GetContainerInfo(withToken("token-alice"), &grpcapi.ContainerInfoRequest{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = client.GetMachineInfo(withToken("token-bob"), &grpcapi.MachineInfoRequest{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// Streams are checked as well.
+	stream, err := client.StreamStats(context.Background(), &grpcapi.StreamStatsRequest{})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	ctx, cancel := context.WithCancel(withToken("token-alice"))
+	stream, err = client.StreamStats(ctx, &grpcapi.StreamStatsRequest{})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.NoError(t, err)
+	cancel()
+
+	require.Eventually(t, func() bool {
+		content, err := os.ReadFile(auditPath)
+		return err == nil && strings.Count(string(content), "\n") == 7
+	}, 5*time.Second, 10*time.Millisecond)
+	content, err := os.ReadFile(auditPath)
+	require.NoError(t, err)
+	var records []api.AuditRecord
+	for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+		var record api.AuditRecord
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		records = append(records, record)
+	}
+	assert.Equal(t, "/cadvisor.v1.Cadvisor/GetMachineInfo", records[0].Path)
+	assert.Equal(t, 401, records[0].Status)
+	assert.Equal(t, "alice", records[2].Identity)
+	assert.Equal(t, 200, records[2].Status)
+	assert.Equal(t, "containers", records[3].RequestType)
+	assert.Equal(t, 403, records[3].Status)
+}
+
+func TestSecurityRateLimit(t *testing.T) {
+	client := newSecureClient(t, Security{
+		Limits: cadvisorhttp.Limits{ClientRate: 0.001, ClientBurst: 1},
+	}, grpc.WithTransportCredentials(insecure.NewCredentials()))
+
+	_, err := client.GetMachineInfo(context.Background(), &grpcapi.MachineInfoRequest{})
+	assert.NoError(t, err)
+	_, err = client.GetMachineInfo(context.Background(), &grpcapi.MachineInfoRequest{})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+// Returns a certificate signed by parent, or self-signed without parent.
+func newCert(t *testing.T, template *x509.Certificate, parent *tls.Certificate) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+	signer, signerKey := template, interface{}(key)
+	if parent != nil {
+		signer, signerKey = parent.Leaf, parent.PrivateKey
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+func TestSecurityClientCerts(t *testing.T) {
+	ca := newCert(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "test CA"},
+		KeyUsage:              x509.KeyUsageCertSign,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}, nil)
+	serverCert := newCert(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "cadvisor"},
+		DNSNames:    []string{"cadvisor"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, &ca)
+	clientCert := newCert(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "alice"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, &ca)
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.Leaf)
+	serverTLS := &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.VerifyClientCertIfGiven,
+	}
+	security := Security{
+		TLS: serverTLS,
+		Policy: &api.AuthorizationPolicy{Rules: []api.AuthorizationRule{
+			{Identities: []string{"alice"}, RequestTypes: []string{"*"}},
+		}},
+	}
+	clientTLS := func(certs ...tls.Certificate) grpc.DialOption {
+		return grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{RootCAs: pool, ServerName: "cadvisor", Certificates: certs}))
+	}
+
+	_, err := newSecureClient(t, security, clientTLS()).GetMachineInfo(context.Background(), &grpcapi.MachineInfoRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = newSecureClient(t, security, clientTLS(clientCert)).GetMachineInfo(context.Background(), &grpcapi.MachineInfoRequest{})
+	assert.NoError(t, err)
+	// Plain text is not served.
+	_, err = newSecureClient(t, security, grpc.WithTransportCredentials(insecure.NewCredentials())).GetMachineInfo(context.Background(), &grpcapi.MachineInfoRequest{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,375 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpc
+
+import (
+	"context"
+	"path"
+	"sort"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+	v2 "github.com/yidoyoon/cadvisor-lite/info/v2"
+	"github.com/yidoyoon/cadvisor-lite/manager"
+	"github.com/yidoyoon/cadvisor-lite/version"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	// Name of the sandbox containers of Docker and CRI-O.
+	podInfraContainerName = "POD"
+	// Label set by containerd on its containers, sandbox for the sandbox
+	// containers.
+	containerdKindLabel = "io.cri-containerd.kind"
+	// Interface reported as the default one of the pods, as by the kubelet.
+	defaultNetworkInterface = "eth0"
+	// Memory limit beyond which a container is considered unlimited.
+	maxMemoryLimit = uint64(1 << 62)
+)
+
+// criServer serves the stats methods of the CRI runtime service, backed by
+// the manager, so that CRI clients such as the kubelet or crictl can read the
+// stats of the containers of the Kubernetes pods from cAdvisor. The other
+// methods are unimplemented.
+type criServer struct {
+	runtimeapi.UnimplementedRuntimeServiceServer
+
+	manager manager.Manager
+}
+
+// NewCRIServer returns a gRPC server serving the stats methods of the CRI
+// runtime service.
+func NewCRIServer(m manager.Manager, opts ...grpc.ServerOption) *grpc.Server {
+	s := grpc.NewServer(opts...)
+	runtimeapi.RegisterRuntimeServiceServer(s, &criServer{manager: m})
+	return s
+}
+
+// criPod is a Kubernetes pod, identified by the labels the kubelet sets on
+// its containers.
+type criPod struct {
+	// Sandbox container of the pod, nil if it is not monitored.
+	sandbox *info.ContainerInfo
+	// Cgroup of the pod, the parent of the sandbox, nil if it is not
+	// monitored.
+	cgroup *info.ContainerInfo
+	// Containers of the pod, sorted by id.
+	containers []*info.ContainerInfo
+}
+
+func (p *criPod) id() string {
+	if p.sandbox == nil {
+		return ""
+	}
+	return p.sandbox.Id
+}
+
+func isSandbox(labels map[string]string) bool {
+	return labels[v2.ContainerNameLabel] == podInfraContainerName || labels[containerdKindLabel] == "sandbox"
+}
+
+// pods returns the pods of the monitored containers, sorted by the id of
+// their sandbox. The CPU usage rates are computed between the two latest
+// samples.
+func (s *criServer) pods() ([]*criPod, error) {
+	options := v2.RequestOptions{IdType: v2.TypeName, Count: 2, Recursive: true}
+	infos, err := s.manager.GetRequestedContainersInfo("/", options)
+	if err != nil {
+		if len(infos) == 0 {
+			return nil, status.Errorf(codes.Internal, "failed to get containers: %v", err)
+		}
+		klog.Warningf("Failed to get some of the containers: %v", err)
+	}
+
+	byUID := make(map[string]*criPod)
+	for _, cont := range infos {
+		if cont == nil || cont.Id == "" {
+			continue
+		}
+		uid := cont.Spec.Labels[v2.PodUIDLabel]
+		if uid == "" {
+			continue
+		}
+		pod, ok := byUID[uid]
+		if !ok {
+			pod = &criPod{}
+			byUID[uid] = pod
+		}
+		if isSandbox(cont.Spec.Labels) {
+			pod.sandbox = cont
+		} else {
+			pod.containers = append(pod.containers, cont)
+		}
+	}
+
+	pods := make([]*criPod, 0, len(byUID))
+	for _, pod := range byUID {
+		if pod.sandbox != nil {
+			if parent := path.Dir(pod.sandbox.Name); parent != "/" {
+				pod.cgroup = infos[parent]
+			}
+		}
+		sort.Slice(pod.containers, func(i, j int) bool {
+			return pod.containers[i].Id < pod.containers[j].Id
+		})
+		pods = append(pods, pod)
+	}
+	sort.Slice(pods, func(i, j int) bool {
+		return pods[i].id() < pods[j].id()
+	})
+	return pods, nil
+}
+
+func matchLabels(labels, selector map[string]string) bool {
+	for k, v := range selector {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *criServer) Version(ctx context.Context, req *runtimeapi.VersionRequest) (*runtimeapi.VersionResponse, error) {
+	return &runtimeapi.VersionResponse{
+		// Version of the CRI API of the kubelet.
+		Version:           "0.1.0",
+		RuntimeName:       "cadvisor",
+		RuntimeVersion:    version.Info["version"],
+		RuntimeApiVersion: "v1",
+	}, nil
+}
+
+func (s *criServer) ContainerStats(ctx context.Context, req *runtimeapi.ContainerStatsRequest) (*runtimeapi.ContainerStatsResponse, error) {
+	pods, err := s.pods()
+	if err != nil {
+		return nil, err
+	}
+	for _, pod := range pods {
+		for _, cont := range pod.containers {
+			if cont.Id == req.ContainerId {
+				return &runtimeapi.ContainerStatsResponse{Stats: containerStats(cont)}, nil
+			}
+		}
+	}
+	return nil, status.Errorf(codes.NotFound, "container %q not found", req.ContainerId)
+}
+
+func (s *criServer) ListContainerStats(ctx context.Context, req *runtimeapi.ListContainerStatsRequest) (*runtimeapi.ListContainerStatsResponse, error) {
+	pods, err := s.pods()
+	if err != nil {
+		return nil, err
+	}
+	filter := req.Filter
+	if filter == nil {
+		filter = &runtimeapi.ContainerStatsFilter{}
+	}
+	resp := &runtimeapi.ListContainerStatsResponse{Stats: []*runtimeapi.ContainerStats{}}
+	for _, pod := range pods {
+		if filter.PodSandboxId != "" && pod.id() != filter.PodSandboxId {
+			continue
+		}
+		for _, cont := range pod.containers {
+			if filter.Id != "" && cont.Id != filter.Id {
+				continue
+			}
+			if !matchLabels(cont.Spec.Labels, filter.LabelSelector) {
+				continue
+			}
+			resp.Stats = append(resp.Stats, containerStats(cont))
+		}
+	}
+	return resp, nil
+}
+
+func (s *criServer) PodSandboxStats(ctx context.Context, req *runtimeapi.PodSandboxStatsRequest) (*runtimeapi.PodSandboxStatsResponse, error) {
+	pods, err := s.pods()
+	if err != nil {
+		return nil, err
+	}
+	for _, pod := range pods {
+		if pod.sandbox != nil && pod.id() == req.PodSandboxId {
+			return &runtimeapi.PodSandboxStatsResponse{Stats: podSandboxStats(pod)}, nil
+		}
+	}
+	return nil, status.Errorf(codes.NotFound, "pod sandbox %q not found", req.PodSandboxId)
+}
+
+// ListPodSandboxStats lists the stats of the pods whose sandbox container is
+// monitored, the pods being identified by their sandbox.
+func (s *criServer) ListPodSandboxStats(ctx context.Context, req *runtimeapi.ListPodSandboxStatsRequest) (*runtimeapi.ListPodSandboxStatsResponse, error) {
+	pods, err := s.pods()
+	if err != nil {
+		return nil, err
+	}
+	filter := req.Filter
+	if filter == nil {
+		filter = &runtimeapi.PodSandboxStatsFilter{}
+	}
+	resp := &runtimeapi.ListPodSandboxStatsResponse{Stats: []*runtimeapi.PodSandboxStats{}}
+	for _, pod := range pods {
+		if pod.sandbox == nil || (filter.Id != "" && pod.id() != filter.Id) {
+			continue
+		}
+		if !matchLabels(pod.sandbox.Spec.Labels, filter.LabelSelector) {
+			continue
+		}
+		resp.Stats = append(resp.Stats, podSandboxStats(pod))
+	}
+	return resp, nil
+}
+
+func containerStats(cont *info.ContainerInfo) *runtimeapi.ContainerStats {
+	stats := &runtimeapi.ContainerStats{
+		Attributes: &runtimeapi.ContainerAttributes{
+			Id:       cont.Id,
+			Metadata: &runtimeapi.ContainerMetadata{Name: cont.Spec.Labels[v2.ContainerNameLabel]},
+			Labels:   cont.Spec.Labels,
+		},
+		Cpu:    cpuUsage(cont),
+		Memory: memoryUsage(cont),
+	}
+	if latest := latestStats(cont); latest != nil && cont.Spec.HasFilesystem && len(latest.Filesystem) > 0 {
+		stats.WritableLayer = &runtimeapi.FilesystemUsage{
+			Timestamp:  latest.Timestamp.UnixNano(),
+			UsedBytes:  &runtimeapi.UInt64Value{Value: latest.Filesystem[0].BaseUsage},
+			InodesUsed: &runtimeapi.UInt64Value{Value: latest.Filesystem[0].Inodes},
+		}
+	}
+	return stats
+}
+
+// podSandboxStats returns the stats of a pod: the CPU, memory and process
+// usage of its cgroup, if it is monitored, and the network usage of its
+// sandbox, which holds the network namespace of the pod.
+func podSandboxStats(pod *criPod) *runtimeapi.PodSandboxStats {
+	labels := pod.sandbox.Spec.Labels
+	stats := &runtimeapi.PodSandboxStats{
+		Attributes: &runtimeapi.PodSandboxAttributes{
+			Id: pod.sandbox.Id,
+			Metadata: &runtimeapi.PodSandboxMetadata{
+				Name:      labels[v2.PodNameLabel],
+				Uid:       labels[v2.PodUIDLabel],
+				Namespace: labels[v2.PodNamespaceLabel],
+			},
+			Labels: labels,
+		},
+		Linux: &runtimeapi.LinuxPodSandboxStats{
+			Network:    networkUsage(pod.sandbox),
+			Containers: make([]*runtimeapi.ContainerStats, 0, len(pod.containers)),
+		},
+	}
+	if pod.cgroup != nil {
+		stats.Linux.Cpu = cpuUsage(pod.cgroup)
+		stats.Linux.Memory = memoryUsage(pod.cgroup)
+		stats.Linux.Process = processUsage(pod.cgroup)
+	}
+	for _, cont := range pod.containers {
+		stats.Linux.Containers = append(stats.Linux.Containers, containerStats(cont))
+	}
+	return stats
+}
+
+func latestStats(cont *info.ContainerInfo) *info.ContainerStats {
+	if len(cont.Stats) == 0 {
+		return nil
+	}
+	return cont.Stats[len(cont.Stats)-1]
+}
+
+// cpuUsage returns the CPU usage of the container, in nanocores between its
+// two latest samples, if it has two.
+func cpuUsage(cont *info.ContainerInfo) *runtimeapi.CpuUsage {
+	latest := latestStats(cont)
+	if latest == nil || !cont.Spec.HasCpu {
+		return nil
+	}
+	usage := &runtimeapi.CpuUsage{
+		Timestamp:            latest.Timestamp.UnixNano(),
+		UsageCoreNanoSeconds: &runtimeapi.UInt64Value{Value: latest.Cpu.Usage.Total},
+	}
+	if len(cont.Stats) > 1 {
+		previous := cont.Stats[len(cont.Stats)-2]
+		elapsed := latest.Timestamp.Sub(previous.Timestamp).Seconds()
+		if elapsed > 0 && latest.Cpu.Usage.Total >= previous.Cpu.Usage.Total {
+			nanoCores := float64(latest.Cpu.Usage.Total-previous.Cpu.Usage.Total) / elapsed
+			usage.UsageNanoCores = &runtimeapi.UInt64Value{Value: uint64(nanoCores)}
+		}
+	}
+	return usage
+}
+
+// memoryUsage returns the memory usage of the container, and the memory
+// still available below its limit, if it has one.
+func memoryUsage(cont *info.ContainerInfo) *runtimeapi.MemoryUsage {
+	latest := latestStats(cont)
+	if latest == nil || !cont.Spec.HasMemory {
+		return nil
+	}
+	memory := latest.Memory
+	usage := &runtimeapi.MemoryUsage{
+		Timestamp:       latest.Timestamp.UnixNano(),
+		WorkingSetBytes: &runtimeapi.UInt64Value{Value: memory.WorkingSet},
+		UsageBytes:      &runtimeapi.UInt64Value{Value: memory.Usage},
+		RssBytes:        &runtimeapi.UInt64Value{Value: memory.RSS},
+		PageFaults:      &runtimeapi.UInt64Value{Value: memory.ContainerData.Pgfault},
+		MajorPageFaults: &runtimeapi.UInt64Value{Value: memory.ContainerData.Pgmajfault},
+	}
+	if limit := cont.Spec.Memory.Limit; limit != 0 && limit <= maxMemoryLimit {
+		var available uint64
+		if limit > memory.WorkingSet {
+			available = limit - memory.WorkingSet
+		}
+		usage.AvailableBytes = &runtimeapi.UInt64Value{Value: available}
+	}
+	return usage
+}
+
+func networkUsage(cont *info.ContainerInfo) *runtimeapi.NetworkUsage {
+	latest := latestStats(cont)
+	if latest == nil || !cont.Spec.HasNetwork {
+		return nil
+	}
+	usage := &runtimeapi.NetworkUsage{Timestamp: latest.Timestamp.UnixNano()}
+	for _, iface := range latest.Network.Interfaces {
+		ifaceUsage := &runtimeapi.NetworkInterfaceUsage{
+			Name:     iface.Name,
+			RxBytes:  &runtimeapi.UInt64Value{Value: iface.RxBytes},
+			RxErrors: &runtimeapi.UInt64Value{Value: iface.RxErrors},
+			TxBytes:  &runtimeapi.UInt64Value{Value: iface.TxBytes},
+			TxErrors: &runtimeapi.UInt64Value{Value: iface.TxErrors},
+		}
+		if iface.Name == defaultNetworkInterface {
+			usage.DefaultInterface = ifaceUsage
+		}
+		usage.Interfaces = append(usage.Interfaces, ifaceUsage)
+	}
+	return usage
+}
+
+func processUsage(cont *info.ContainerInfo) *runtimeapi.ProcessUsage {
+	latest := latestStats(cont)
+	if latest == nil || !cont.Spec.HasProcesses {
+		return nil
+	}
+	return &runtimeapi.ProcessUsage{
+		Timestamp:    latest.Timestamp.UnixNano(),
+		ProcessCount: &runtimeapi.UInt64Value{Value: latest.Processes.ProcessCount},
+	}
+}
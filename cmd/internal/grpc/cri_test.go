@@ -0,0 +1,232 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+	v2 "github.com/yidoyoon/cadvisor-lite/info/v2"
+	"github.com/yidoyoon/cadvisor-lite/manager"
+)
+
+// Serves a pod with a sandbox, a pod whose sandbox is not monitored, and a
+// container which is not in a pod.
+type fakeCRIManager struct {
+	manager.Manager
+}
+
+func podLabels(uid, name, container string) map[string]string {
+	return map[string]string{
+		v2.PodUIDLabel:        uid,
+		v2.PodNameLabel:       name,
+		v2.PodNamespaceLabel:  "default",
+		v2.ContainerNameLabel: container,
+	}
+}
+
+// criStats returns two samples a second apart, using 0.5 core in between.
+func criStats() []*info.ContainerStats {
+	stats := make([]*info.ContainerStats, 2)
+	for i := range stats {
+		stats[i] = &info.ContainerStats{Timestamp: epoch.Add(time.Duration(i) * time.Second)}
+		stats[i].Cpu.Usage.Total = uint64(1+i) * 500000000
+		stats[i].Memory.Usage = 300 << 20
+		stats[i].Memory.WorkingSet = 200 << 20
+		stats[i].Memory.RSS = 100 << 20
+		stats[i].Memory.ContainerData.Pgfault = 1000
+		stats[i].Memory.ContainerData.Pgmajfault = 10
+		stats[i].Network.Interfaces = []info.InterfaceStats{
+			{Name: "eth0", RxBytes: 4096, TxBytes: 2048, RxErrors: 1},
+			{Name: "eth1", RxBytes: 512},
+		}
+		stats[i].Filesystem = []info.FsStats{{Device: "/dev/sda1", Usage: 8192, BaseUsage: 4096, Inodes: 40}}
+		stats[i].Processes.ProcessCount = 3
+	}
+	return stats
+}
+
+func (m *fakeCRIManager) GetRequestedContainersInfo(name string, options v2.RequestOptions) (map[string]*info.ContainerInfo, error) {
+	infos := map[string]*info.ContainerInfo{
+		"/kubepods/pod123": {
+			ContainerReference: info.ContainerReference{Name: "/kubepods/pod123"},
+			Spec: info.ContainerSpec{
+				HasCpu: true, HasMemory: true, HasProcesses: true,
+				Memory: info.MemorySpec{Limit: 1 << 30},
+			},
+		},
+		"/kubepods/pod123/sandbox1": {
+			ContainerReference: info.ContainerReference{Id: "sandbox1", Name: "/kubepods/pod123/sandbox1"},
+			Spec:               info.ContainerSpec{Labels: podLabels("123", "web", "POD"), HasNetwork: true},
+		},
+		"/kubepods/pod123/app1": {
+			ContainerReference: info.ContainerReference{Id: "app1", Name: "/kubepods/pod123/app1"},
+			Spec: info.ContainerSpec{
+				Labels: podLabels("123", "web", "app"),
+				HasCpu: true, HasMemory: true, HasFilesystem: true,
+				Memory: info.MemorySpec{Limit: 1 << 63},
+			},
+		},
+		"/kubepods/pod456/app2": {
+			ContainerReference: info.ContainerReference{Id: "app2", Name: "/kubepods/pod456/app2"},
+			Spec:               info.ContainerSpec{Labels: podLabels("456", "worker", "app"), HasCpu: true},
+		},
+		"/system.slice/docker-3f2a.scope": {
+			ContainerReference: info.ContainerReference{Id: "3f2a", Name: "/system.slice/docker-3f2a.scope"},
+			Spec:               info.ContainerSpec{HasCpu: true},
+		},
+	}
+	for _, cinfo := range infos {
+		cinfo.Stats = criStats()
+	}
+	return infos, nil
+}
+
+func newCRIClient(t *testing.T, m manager.Manager) runtimeapi.RuntimeServiceClient {
+	listener := bufconn.Listen(1 << 20)
+	server := NewCRIServer(m)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return runtimeapi.NewRuntimeServiceClient(conn)
+}
+
+func containerIds(stats []*runtimeapi.ContainerStats) []string {
+	ids := []string{}
+	for _, s := range stats {
+		ids = append(ids, s.Attributes.Id)
+	}
+	return ids
+}
+
+func TestCRIListContainerStats(t *testing.T) {
+	client := newCRIClient(t, &fakeCRIManager{})
+
+	resp, err := client.ListContainerStats(context.Background(), &runtimeapi.ListContainerStatsRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app2", "app1"}, containerIds(resp.Stats))
+
+	stats := resp.Stats[1]
+	assert.Equal(t, "app", stats.Attributes.Metadata.Name)
+	assert.Equal(t, "web", stats.Attributes.Labels[v2.PodNameLabel])
+	assert.Equal(t, epoch.Add(time.Second).UnixNano(), stats.Cpu.Timestamp)
+	assert.Equal(t, uint64(1000000000), stats.Cpu.UsageCoreNanoSeconds.Value)
+	assert.Equal(t, uint64(500000000), stats.Cpu.UsageNanoCores.Value)
+	assert.Equal(t, uint64(200<<20), stats.Memory.WorkingSetBytes.Value)
+	assert.Equal(t, uint64(300<<20), stats.Memory.UsageBytes.Value)
+	assert.Equal(t, uint64(100<<20), stats.Memory.RssBytes.Value)
+	assert.Equal(t, uint64(1000), stats.Memory.PageFaults.Value)
+	assert.Equal(t, uint64(10), stats.Memory.MajorPageFaults.Value)
+	// The limit of the container is beyond the one of the machine.
+	assert.Nil(t, stats.Memory.AvailableBytes)
+	assert.Equal(t, uint64(4096), stats.WritableLayer.UsedBytes.Value)
+	assert.Equal(t, uint64(40), stats.WritableLayer.InodesUsed.Value)
+	// The other container has neither memory nor filesystem.
+	assert.Nil(t, resp.Stats[0].Memory)
+	assert.Nil(t, resp.Stats[0].WritableLayer)
+
+	resp, err = client.ListContainerStats(context.Background(), &runtimeapi.ListContainerStatsRequest{
+		Filter: &runtimeapi.ContainerStatsFilter{PodSandboxId: "sandbox1"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app1"}, containerIds(resp.Stats))
+
+	resp, err = client.ListContainerStats(context.Background(), &runtimeapi.ListContainerStatsRequest{
+		Filter: &runtimeapi.ContainerStatsFilter{LabelSelector: map[string]string{v2.PodNameLabel: "worker"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app2"}, containerIds(resp.Stats))
+
+	resp, err = client.ListContainerStats(context.Background(), &runtimeapi.ListContainerStatsRequest{
+		Filter: &runtimeapi.ContainerStatsFilter{Id: "sandbox1"},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, resp.Stats)
+}
+
+func TestCRIContainerStats(t *testing.T) {
+	client := newCRIClient(t, &fakeCRIManager{})
+
+	resp, err := client.ContainerStats(context.Background(), &runtimeapi.ContainerStatsRequest{ContainerId: "app2"})
+	require.NoError(t, err)
+	assert.Equal(t, "app2", resp.Stats.Attributes.Id)
+
+	_, err = client.ContainerStats(context.Background(), &runtimeapi.ContainerStatsRequest{ContainerId: "3f2a"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestCRIListPodSandboxStats(t *testing.T) {
+	client := newCRIClient(t, &fakeCRIManager{})
+
+	resp, err := client.ListPodSandboxStats(context.Background(), &runtimeapi.ListPodSandboxStatsRequest{})
+	require.NoError(t, err)
+	// The pod whose sandbox is not monitored cannot be identified.
+	require.Len(t, resp.Stats, 1)
+	stats := resp.Stats[0]
+	assert.Equal(t, "sandbox1", stats.Attributes.Id)
+	assert.Equal(t, &runtimeapi.PodSandboxMetadata{Name: "web", Uid: "123", Namespace: "default"}, stats.Attributes.Metadata)
+	// The CPU, memory and processes are the ones of the cgroup of the pod.
+	assert.Equal(t, uint64(500000000), stats.Linux.Cpu.UsageNanoCores.Value)
+	assert.Equal(t, uint64(1<<30-200<<20), stats.Linux.Memory.AvailableBytes.Value)
+	assert.Equal(t, uint64(3), stats.Linux.Process.ProcessCount.Value)
+	// The network is the one of the sandbox.
+	assert.Equal(t, "eth0", stats.Linux.Network.DefaultInterface.Name)
+	assert.Equal(t, uint64(4096), stats.Linux.Network.DefaultInterface.RxBytes.Value)
+	assert.Equal(t, uint64(1), stats.Linux.Network.DefaultInterface.RxErrors.Value)
+	assert.Len(t, stats.Linux.Network.Interfaces, 2)
+	assert.Equal(t, []string{"app1"}, containerIds(stats.Linux.Containers))
+
+	resp, err = client.ListPodSandboxStats(context.Background(), &runtimeapi.ListPodSandboxStatsRequest{
+		Filter: &runtimeapi.PodSandboxStatsFilter{LabelSelector: map[string]string{v2.PodNameLabel: "worker"}},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, resp.Stats)
+
+	podResp, err := client.PodSandboxStats(context.Background(), &runtimeapi.PodSandboxStatsRequest{PodSandboxId: "sandbox1"})
+	require.NoError(t, err)
+	assert.Equal(t, "sandbox1", podResp.Stats.Attributes.Id)
+	_, err = client.PodSandboxStats(context.Background(), &runtimeapi.PodSandboxStatsRequest{PodSandboxId: "app1"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestCRIVersion(t *testing.T) {
+	client := newCRIClient(t, &fakeCRIManager{})
+
+	resp, err := client.Version(context.Background(), &runtimeapi.VersionRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "cadvisor", resp.RuntimeName)
+	assert.Equal(t, "v1", resp.RuntimeApiVersion)
+
+	// The other methods of the runtime service are not implemented.
+	_, err = client.ListContainers(context.Background(), &runtimeapi.ListContainersRequest{})
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+}
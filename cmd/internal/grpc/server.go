@@ -0,0 +1,174 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package grpc serves the gRPC API of cAdvisor, defined in the grpcapi
+// package.
+package grpc
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+
+	"github.com/yidoyoon/cadvisor-lite/grpcapi"
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+	v2 "github.com/yidoyoon/cadvisor-lite/info/v2"
+	"github.com/yidoyoon/cadvisor-lite/manager"
+
+	"k8s.io/klog/v2"
+)
+
+type server struct {
+	grpcapi.UnimplementedCadvisorServer
+
+	manager manager.Manager
+	// Interval at which the streamed containers are polled for new stats.
+	interval time.Duration
+}
+
+// NewServer returns a gRPC server serving the API of cAdvisor and the server
+// reflection service, so that clients such as grpcurl can discover it.
+func NewServer(m manager.Manager, interval time.Duration, opts ...grpc.ServerOption) *grpc.Server {
+	s := grpc.NewServer(opts...)
+	grpcapi.RegisterCadvisorServer(s, &server{manager: m, interval: interval})
+	reflection.Register(s)
+	return s
+}
+
+func requestOptions(idType string, count int, recursive bool) (v2.RequestOptions, error) {
+	switch idType {
+	case "":
+		idType = v2.TypeName
+	case v2.TypeName, v2.TypeDocker, v2.TypePodman:
+	default:
+		return v2.RequestOptions{}, status.Errorf(codes.InvalidArgument, "unknown type %q", idType)
+	}
+	return v2.RequestOptions{IdType: idType, Count: count, Recursive: recursive}, nil
+}
+
+func containerName(name string) string {
+	if name == "" {
+		return "/"
+	}
+	return name
+}
+
+// containersInfo returns the requested containers. A partial failure is only
+// logged, unless no container is left.
+func (s *server) containersInfo(name string, options v2.RequestOptions) (map[string]*info.ContainerInfo, error) {
+	infos, err := s.manager.GetRequestedContainersInfo(containerName(name), options)
+	if err != nil {
+		if len(infos) == 0 {
+			return nil, status.Errorf(codes.NotFound, "failed to get container %q: %v", name, err)
+		}
+		klog.Warningf("Failed to get some of the containers of %q: %v", name, err)
+	}
+	return infos, nil
+}
+
+func sortedNames(infos map[string]*info.ContainerInfo) []string {
+	names := make([]string, 0, len(infos))
+	for name, cinfo := range infos {
+		if cinfo != nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (s *server) GetContainerInfo(ctx context.Context, req *grpcapi.ContainerInfoRequest) (*grpcapi.ContainerInfoResponse, error) {
+	count := int(req.Count)
+	if count <= 0 {
+		count = 1
+	}
+	options, err := requestOptions(req.Type, count, req.Recursive)
+	if err != nil {
+		return nil, err
+	}
+	infos, err := s.containersInfo(req.Name, options)
+	if err != nil {
+		return nil, err
+	}
+	resp := &grpcapi.ContainerInfoResponse{}
+	for _, name := range sortedNames(infos) {
+		resp.Containers = append(resp.Containers, grpcapi.ContainerInfoFromV1(infos[name]))
+	}
+	return resp, nil
+}
+
+func (s *server) GetMachineInfo(ctx context.Context, req *grpcapi.MachineInfoRequest) (*grpcapi.MachineInfo, error) {
+	minfo, err := s.manager.GetMachineInfo()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get machine info: %v", err)
+	}
+	return grpcapi.MachineInfoFromV1(minfo), nil
+}
+
+// StreamStats sends the latest sample of every requested container, then
+// every sample collected afterwards, until the client cancels the stream.
+// The containers are polled, so new subcontainers are picked up.
+func (s *server) StreamStats(req *grpcapi.StreamStatsRequest, stream grpcapi.Cadvisor_StreamStatsServer) error {
+	options, err := requestOptions(req.Type, 1, req.Recursive)
+	if err != nil {
+		return err
+	}
+	// Timestamp of the last sample sent, per container.
+	last := make(map[string]time.Time)
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		infos, err := s.containersInfo(req.Name, options)
+		if err != nil {
+			return err
+		}
+		for _, name := range sortedNames(infos) {
+			for _, stats := range infos[name].Stats {
+				if !stats.Timestamp.After(last[name]) {
+					continue
+				}
+				sample := grpcapi.ContainerStatsFromV1(stats)
+				sample.ContainerName = name
+				if err := stream.Send(sample); err != nil {
+					return err
+				}
+				last[name] = stats.Timestamp
+			}
+		}
+		// Forget the containers which are gone, and poll the samples
+		// collected since the oldest last sample of the others.
+		options.Count, options.Since = -1, time.Time{}
+		for name, timestamp := range last {
+			if _, ok := infos[name]; !ok {
+				delete(last, name)
+			} else if options.Since.IsZero() || timestamp.Before(options.Since) {
+				options.Since = timestamp
+			}
+		}
+		if options.Since.IsZero() {
+			options.Count = 1
+		}
+
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
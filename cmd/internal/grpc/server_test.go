@@ -0,0 +1,172 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/yidoyoon/cadvisor-lite/grpcapi"
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+	v2 "github.com/yidoyoon/cadvisor-lite/info/v2"
+	"github.com/yidoyoon/cadvisor-lite/manager"
+)
+
+var epoch = time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+
+// Serves "/" and its subcontainer "/docker", which gain a sample on every
+// request.
+type fakeManager struct {
+	manager.Manager
+
+	lock     sync.Mutex
+	samples  int
+	requests []v2.RequestOptions
+}
+
+func (m *fakeManager) GetRequestedContainersInfo(name string, options v2.RequestOptions) (map[string]*info.ContainerInfo, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.samples++
+	m.requests = append(m.requests, options)
+	names := []string{name}
+	if name != "/" {
+		return nil, fmt.Errorf("unknown container %q", name)
+	}
+	if options.Recursive {
+		names = append(names, "/docker")
+	}
+	infos := make(map[string]*info.ContainerInfo)
+	for _, n := range names {
+		cinfo := &info.ContainerInfo{
+			ContainerReference: info.ContainerReference{Name: n},
+			Spec:               info.ContainerSpec{HasCpu: true, Cpu: info.CpuSpec{Limit: 1024, Mask: "0-3"}},
+		}
+		for i := 1; i <= m.samples; i++ {
+			stats := &info.ContainerStats{Timestamp: epoch.Add(time.Duration(i) * time.Second), Sequence: uint64(i)}
+			stats.Cpu.Usage.Total = uint64(i) * 1000
+			stats.Network.Interfaces = []info.InterfaceStats{{Name: "eth0", RxBytes: uint64(i)}}
+			if stats.Timestamp.After(options.Since) {
+				cinfo.Stats = append(cinfo.Stats, stats)
+			}
+		}
+		if options.Count > 0 && len(cinfo.Stats) > options.Count {
+			cinfo.Stats = cinfo.Stats[len(cinfo.Stats)-options.Count:]
+		}
+		infos[n] = cinfo
+	}
+	return infos, nil
+}
+
+func (m *fakeManager) GetMachineInfo() (*info.MachineInfo, error) {
+	return &info.MachineInfo{Timestamp: epoch, NumCores: 4, MemoryCapacity: 1 << 30, MachineID: "machine-1", CloudProvider: info.GCE}, nil
+}
+
+func newClient(t *testing.T, m manager.Manager) *grpc.ClientConn {
+	listener := bufconn.Listen(1 << 20)
+	server := NewServer(m, 10*time.Millisecond)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestGetContainerInfo(t *testing.T) {
+	m := &fakeManager{samples: 1}
+	client := grpcapi.NewCadvisorClient(newClient(t, m))
+
+	resp, err := client.GetContainerInfo(context.Background(), &grpcapi.ContainerInfoRequest{Recursive: true, Count: 2})
+	require.NoError(t, err)
+	require.Len(t, resp.Containers, 2)
+	assert.Equal(t, "/", resp.Containers[0].Name)
+	assert.Equal(t, "/docker", resp.Containers[1].Name)
+	assert.Equal(t, uint64(1024), resp.Containers[0].Spec.CpuLimit)
+	assert.Equal(t, "0-3", resp.Containers[0].Spec.CpuMask)
+	require.Len(t, resp.Containers[0].Stats, 2)
+	assert.Equal(t, uint64(2000), resp.Containers[0].Stats[1].Cpu.UsageTotal)
+	assert.Equal(t, epoch.Add(2*time.Second), resp.Containers[0].Stats[1].Timestamp.AsTime())
+	assert.Equal(t, "eth0", resp.Containers[0].Stats[1].Network[0].Name)
+	assert.Equal(t, v2.RequestOptions{IdType: v2.TypeName, Count: 2, Recursive: true}, m.requests[0])
+
+	_, err = client.GetContainerInfo(context.Background(), &grpcapi.ContainerInfoRequest{Name: "/missing"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	_, err = client.GetContainerInfo(context.Background(), &grpcapi.ContainerInfoRequest{Type: "lxc"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestGetMachineInfo(t *testing.T) {
+	client := grpcapi.NewCadvisorClient(newClient(t, &fakeManager{}))
+
+	minfo, err := client.GetMachineInfo(context.Background(), &grpcapi.MachineInfoRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, int32(4), minfo.NumCores)
+	assert.Equal(t, uint64(1<<30), minfo.MemoryCapacity)
+	assert.Equal(t, "machine-1", minfo.MachineId)
+	assert.Equal(t, "GCE", minfo.CloudProvider)
+	assert.Equal(t, epoch, minfo.Timestamp.AsTime())
+}
+
+func TestStreamStats(t *testing.T) {
+	m := &fakeManager{samples: 2}
+	client := grpcapi.NewCadvisorClient(newClient(t, m))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := client.StreamStats(ctx, &grpcapi.StreamStatsRequest{Recursive: true})
+	require.NoError(t, err)
+
+	// The latest sample of each container, then every new one, once.
+	sequences := map[string][]uint64{}
+	for i := 0; i < 6; i++ {
+		stats, err := stream.Recv()
+		require.NoError(t, err)
+		sequences[stats.ContainerName] = append(sequences[stats.ContainerName], stats.Sequence)
+	}
+	assert.Equal(t, []uint64{3, 4, 5}, sequences["/"])
+	assert.Equal(t, []uint64{3, 4, 5}, sequences["/docker"])
+}
+
+func TestReflection(t *testing.T) {
+	client := rpb.NewServerReflectionClient(newClient(t, &fakeManager{}))
+
+	stream, err := client.ServerReflectionInfo(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&rpb.ServerReflectionRequest{MessageRequest: &rpb.ServerReflectionRequest_ListServices{}}))
+	resp, err := stream.Recv()
+	require.NoError(t, err)
+	var services []string
+	for _, service := range resp.GetListServicesResponse().Service {
+		services = append(services, service.Name)
+	}
+	assert.Contains(t, services, "cadvisor.v1.Cadvisor")
+}
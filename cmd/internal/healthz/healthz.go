@@ -15,18 +15,169 @@
 package healthz
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"sort"
+	"time"
 
+	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+
+	"github.com/yidoyoon/cadvisor-lite/cmd/internal/http/identity"
 	httpmux "github.com/yidoyoon/cadvisor-lite/cmd/internal/http/mux"
+	"github.com/yidoyoon/cadvisor-lite/manager"
+	"github.com/yidoyoon/cadvisor-lite/storage"
+)
+
+// Number of global housekeeping intervals without housekeeping after which
+// the housekeeping is considered stuck.
+const staleHousekeepingIntervals = 3
+
+const (
+	statusOK     = "ok"
+	statusFailed = "failed"
 )
 
-func handleHealthz(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(http.StatusOK)
-	_, _ = w.Write([]byte("ok"))
+// check is the result of a health check.
+type check struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	// Why the check failed, or details on its success. Only reported to
+	// authenticated clients, since it may hold the errors of the backends.
+	Message string `json:"message,omitempty"`
+}
+
+// disabledMetrics is a source of metrics no longer read for a container.
+type disabledMetrics struct {
+	Container string `json:"container"`
+	Kind      string `json:"kind"`
+	Source    string `json:"source"`
+	Reason    string `json:"reason"`
+}
+
+// status is the body of the responses of the health endpoints.
+type status struct {
+	// "ok" if all the checks succeeded, "failed" otherwise.
+	Status string  `json:"status"`
+	Checks []check `json:"checks"`
+	// Sources of metrics no longer read after their reads kept failing,
+	// which do not fail the checks since the other metrics are still
+	// collected. Only reported by /healthz, to authenticated clients.
+	DisabledMetrics []disabledMetrics `json:"disabled_metrics,omitempty"`
+}
+
+func newCheck(name string, err error) check {
+	if err != nil {
+		return check{Name: name, Status: statusFailed, Message: err.Error()}
+	}
+	return check{Name: name, Status: statusOK}
+}
+
+// livenessChecks only fails when the housekeeping is stuck, which a restart
+// may fix.
+func livenessChecks(health manager.Health, now time.Time) []check {
+	var err error
+	if health.Housekeeping {
+		err = housekeepingStale(health, now)
+	}
+	return []check{newCheck("housekeeping", err)}
+}
+
+// readinessChecks fails unless the housekeeping runs, the container watchers
+// receive the events of the containers and the storage drivers can write
+// the stats.
+func readinessChecks(health manager.Health, now time.Time) []check {
+	var err error
+	switch {
+	case !health.Housekeeping:
+		err = fmt.Errorf("not running, the manager is not started or no container factory is registered")
+	case health.LastGlobalHousekeepingError != nil:
+		err = fmt.Errorf("last global housekeeping failed: %v", health.LastGlobalHousekeepingError)
+	default:
+		err = housekeepingStale(health, now)
+	}
+	checks := []check{newCheck("housekeeping", err)}
+	for _, w := range health.Watchers {
+		checks = append(checks, newCheck("watcher:"+w.Name, w.Err))
+	}
+	for _, d := range storage.DriversHealth() {
+		checks = append(checks, newCheck("storage:"+d.Name, storageUnwritable(d)))
+	}
+	return checks
+}
+
+func housekeepingStale(health manager.Health, now time.Time) error {
+	if since := now.Sub(health.LastGlobalHousekeeping); since > staleHousekeepingIntervals*health.GlobalHousekeepingInterval {
+		return fmt.Errorf("last global housekeeping %s ago, more than %d intervals of %s", since, staleHousekeepingIntervals, health.GlobalHousekeepingInterval)
+	}
+	return nil
+}
+
+func storageUnwritable(d storage.DriverHealth) error {
+	if d.Connected != nil && !*d.Connected {
+		return fmt.Errorf("not connected to the backend, %d samples buffered", d.QueueDepth)
+	}
+	if d.LastError != "" && d.LastErrorTime.After(d.LastFlush) {
+		return fmt.Errorf("last write failed at %s: %s", d.LastErrorTime.Format(time.RFC3339), d.LastError)
+	}
+	return nil
+}
+
+// handleChecks returns the result of checks as JSON, with 503 Service
+// Unavailable if one of them failed. The messages of the checks, and the
+// disabled metrics if withDisabledMetrics, are only returned to the clients
+// authenticated with a certificate or a bearer token.
+func handleChecks(m manager.Manager, clock clock.PassiveClock, checks func(manager.Health, time.Time) []check, withDisabledMetrics bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, authenticated := identity.FromRequest(r)
+		result := status{Status: statusOK, Checks: checks(m.Health(), clock.Now())}
+		for i, c := range result.Checks {
+			if c.Status != statusOK {
+				result.Status = statusFailed
+			}
+			if !authenticated {
+				result.Checks[i].Message = ""
+			}
+		}
+		if withDisabledMetrics && authenticated {
+			for name, disabled := range m.DisabledMetrics() {
+				for _, d := range disabled {
+					result.DisabledMetrics = append(result.DisabledMetrics, disabledMetrics{Container: name, Kind: string(d.Kind), Source: d.Source, Reason: d.Reason})
+				}
+			}
+			sort.Slice(result.DisabledMetrics, func(i, j int) bool {
+				a, b := result.DisabledMetrics[i], result.DisabledMetrics[j]
+				if a.Container != b.Container {
+					return a.Container < b.Container
+				}
+				return a.Source < b.Source
+			})
+		}
+		out, err := json.Marshal(result)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if result.Status != statusOK {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		if _, err := w.Write(out); err != nil {
+			klog.V(4).Infof("Failed to write health check response: %v", err)
+		}
+	}
 }
 
-// Register simple HTTP /healthz handler to return "ok".
-func RegisterHandler(mux httpmux.Mux) error {
-	mux.HandleFunc("/healthz", handleHealthz)
+// Register the HTTP health handlers, returning their checks as JSON:
+// /livez checks that the housekeeping is not stuck, /readyz that the
+// housekeeping runs, the container watchers are connected and the storage
+// drivers are writable, and /healthz does the same as /livez, so that the
+// existing liveness probes keep working, and adds the disabled metrics.
+func RegisterHandler(mux httpmux.Mux, m manager.Manager) error {
+	realClock := clock.RealClock{}
+	mux.HandleFunc("/livez", handleChecks(m, realClock, livenessChecks, false))
+	mux.HandleFunc("/readyz", handleChecks(m, realClock, readinessChecks, false))
+	mux.HandleFunc("/healthz", handleChecks(m, realClock, livenessChecks, true))
 	return nil
 }
@@ -0,0 +1,155 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthz
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	testingclock "k8s.io/utils/clock/testing"
+
+	"github.com/yidoyoon/cadvisor-lite/cmd/internal/http/identity"
+	"github.com/yidoyoon/cadvisor-lite/container"
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+	"github.com/yidoyoon/cadvisor-lite/manager"
+	"github.com/yidoyoon/cadvisor-lite/manager/fake"
+	"github.com/yidoyoon/cadvisor-lite/storage"
+)
+
+var now = time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+
+// serve calls h for an authenticated client.
+func serve(t *testing.T, h http.HandlerFunc) (int, status) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	return serveRequest(t, h, r.WithContext(identity.NewContext(r.Context(), "prometheus")))
+}
+
+func serveRequest(t *testing.T, h http.HandlerFunc, r *http.Request) (int, status) {
+	w := httptest.NewRecorder()
+	h(w, r)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	var result status
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	return w.Code, result
+}
+
+type failingDriver struct{}
+
+func (failingDriver) AddStats(*info.ContainerInfo, *info.ContainerStats) error {
+	return errors.New("connection refused")
+}
+func (failingDriver) Close() error { return nil }
+
+func TestHealthChecks(t *testing.T) {
+	m := fake.NewManager()
+	clock := testingclock.NewFakePassiveClock(now)
+	health := manager.Health{
+		Housekeeping:               true,
+		LastGlobalHousekeeping:     now.Add(-time.Minute),
+		GlobalHousekeepingInterval: time.Minute,
+		Watchers:                   []manager.WatcherHealth{{Name: "raw.rawContainerWatcher"}},
+	}
+	m.SetHealth(health)
+	livez := handleChecks(m, clock, livenessChecks, false)
+	readyz := handleChecks(m, clock, readinessChecks, false)
+
+	code, result := serve(t, readyz)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, status{Status: statusOK, Checks: []check{
+		{Name: "housekeeping", Status: statusOK},
+		{Name: "watcher:raw.rawContainerWatcher", Status: statusOK},
+	}}, result)
+
+	// A disconnected watcher and a failing storage driver fail the
+	// readiness but not the liveness.
+	health.Watchers[0].Err = errors.New("inotify watch failed: queue overflow")
+	m.SetHealth(health)
+	driver := storage.NewInstrumentedDriver(failingDriver{}, "influxdb")
+	defer driver.Close()
+	assert.Error(t, driver.AddStats(&info.ContainerInfo{}, &info.ContainerStats{}))
+	code, result = serve(t, readyz)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, statusFailed, result.Status)
+	require.Len(t, result.Checks, 3)
+	assert.Equal(t, check{Name: "watcher:raw.rawContainerWatcher", Status: statusFailed, Message: "inotify watch failed: queue overflow"}, result.Checks[1])
+	assert.Equal(t, "storage:influxdb", result.Checks[2].Name)
+	assert.Equal(t, statusFailed, result.Checks[2].Status)
+	assert.Contains(t, result.Checks[2].Message, "connection refused")
+	code, _ = serve(t, livez)
+	assert.Equal(t, http.StatusOK, code)
+
+	// The errors of the backends are not returned to anonymous clients.
+	code, result = serveRequest(t, readyz, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	for _, c := range result.Checks {
+		assert.Empty(t, c.Message, c.Name)
+	}
+	assert.Equal(t, check{Name: "watcher:raw.rawContainerWatcher", Status: statusFailed}, result.Checks[1])
+
+	// A stuck housekeeping fails both.
+	clock.SetTime(now.Add(3 * time.Minute))
+	code, result = serve(t, livez)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, []check{{Name: "housekeeping", Status: statusFailed, Message: "last global housekeeping 4m0s ago, more than 3 intervals of 1m0s"}}, result.Checks)
+
+	// A manager not started is live but not ready.
+	m.SetHealth(manager.Health{})
+	code, _ = serve(t, livez)
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = serve(t, readyz)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+}
+
+type disabledMetricsManager struct {
+	*fake.Manager
+}
+
+func (m *disabledMetricsManager) DisabledMetrics() map[string][]container.DisabledMetrics {
+	return map[string][]container.DisabledMetrics{
+		"/b": {{Kind: container.NetworkUsageMetrics, Source: "net/dev", Reason: "read failed 10 times in a row"}},
+		"/a": {{Kind: container.DiskIOMetrics, Source: "blkio", Reason: "read failed 10 times in a row"}},
+	}
+}
+
+func TestHealthzDisabledMetrics(t *testing.T) {
+	m := &disabledMetricsManager{Manager: fake.NewManager()}
+	m.SetHealth(manager.Health{
+		Housekeeping:                true,
+		LastGlobalHousekeeping:      now,
+		GlobalHousekeepingInterval:  time.Minute,
+		LastGlobalHousekeepingError: errors.New("failed to list containers"),
+	})
+	healthz := handleChecks(m, testingclock.NewFakePassiveClock(now), livenessChecks, true)
+	code, result := serve(t, healthz)
+	// Neither the disabled metrics nor the readiness checks fail the
+	// health check.
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, statusOK, result.Status)
+	assert.Equal(t, []disabledMetrics{
+		{Container: "/a", Kind: "diskIO", Source: "blkio", Reason: "read failed 10 times in a row"},
+		{Container: "/b", Kind: "network", Source: "net/dev", Reason: "read failed 10 times in a row"},
+	}, result.DisabledMetrics)
+
+	// The names of the containers are not returned to anonymous clients.
+	code, result = serveRequest(t, healthz, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Empty(t, result.DisabledMetrics)
+}
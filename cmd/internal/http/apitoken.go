@@ -0,0 +1,152 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// Minimum interval between the checks for a modified token file.
+const tokenFileCheckInterval = 10 * time.Second
+
+// APITokens verifies bearer tokens against the static tokens of a file. The
+// file is loaded again when it changes, so that tokens can be added and
+// revoked without restarting the server.
+//
+// Each line of the file holds a token, optionally followed by whitespace and
+// the name of its holder, used as the identity of the client. Empty lines and
+// lines starting with # are ignored.
+type APITokens struct {
+	file string
+
+	lock      sync.Mutex
+	tokens    []apiToken
+	lastCheck time.Time
+	// Modification time of the file when the tokens were loaded.
+	modTime time.Time
+}
+
+type apiToken struct {
+	// The SHA-256 hash of the token, so that comparisons take the same time
+	// whatever the length of the tokens.
+	hash [sha256.Size]byte
+	name string
+}
+
+// NewAPITokens returns a verifier of the tokens of file, which must hold at
+// least one token.
+func NewAPITokens(file string) (*APITokens, error) {
+	t := &APITokens{file: file}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if err := t.reload(time.Now()); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Verify returns the name of the holder of token if it is one of the tokens
+// of the file.
+func (t *APITokens) Verify(token string, now time.Time) (string, error) {
+	hash := sha256.Sum256([]byte(token))
+	name, found := "", false
+	for _, known := range t.load(now) {
+		// Compare with all the tokens, so that the time taken does not
+		// tell which one matched.
+		if subtle.ConstantTimeCompare(hash[:], known.hash[:]) == 1 && !found {
+			name, found = known.name, true
+		}
+	}
+	if !found {
+		return "", fmt.Errorf("unknown API token")
+	}
+	return name, nil
+}
+
+// load returns the tokens, loading them again if the file changed since the
+// last check, at most every tokenFileCheckInterval. The last tokens are kept
+// if the file cannot be loaded, e.g. while it is written.
+func (t *APITokens) load(now time.Time) []apiToken {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if now.Sub(t.lastCheck) < tokenFileCheckInterval {
+		return t.tokens
+	}
+	t.lastCheck = now
+	if info, err := os.Stat(t.file); err == nil && info.ModTime().Equal(t.modTime) {
+		return t.tokens
+	}
+	if err := t.reload(now); err != nil {
+		klog.Warningf("Failed to load the modified API tokens, still using the previous ones: %v", err)
+	}
+	return t.tokens
+}
+
+// reload reads the tokens of the file. Must be called with t.lock held.
+func (t *APITokens) reload(now time.Time) error {
+	t.lastCheck = now
+	info, err := os.Stat(t.file)
+	if err != nil {
+		return fmt.Errorf("failed to read the API tokens: %v", err)
+	}
+	data, err := os.ReadFile(t.file)
+	if err != nil {
+		return fmt.Errorf("failed to read the API tokens: %v", err)
+	}
+	tokens, err := parseAPITokens(data)
+	if err != nil {
+		return fmt.Errorf("invalid API token file %q: %v", t.file, err)
+	}
+	t.tokens = tokens
+	t.modTime = info.ModTime()
+	klog.V(1).Infof("Loaded %d API tokens from %s", len(tokens), t.file)
+	return nil
+}
+
+func parseAPITokens(data []byte) ([]apiToken, error) {
+	var tokens []apiToken
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		token, name := line, ""
+		if i := strings.IndexAny(line, " \t"); i >= 0 {
+			token, name = line[:i], strings.TrimSpace(line[i+1:])
+		}
+		if name == "" {
+			name = fmt.Sprintf("API token on line %d", lineNumber)
+		}
+		tokens = append(tokens, apiToken{hash: sha256.Sum256([]byte(token)), name: name})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("no token found")
+	}
+	return tokens, nil
+}
@@ -0,0 +1,111 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yidoyoon/cadvisor-lite/cmd/internal/http/identity"
+)
+
+func TestAPITokens(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "tokens")
+	require.NoError(t, os.WriteFile(file, []byte("# Scrapers\ns3cr3t prometheus\n\n0th3r\n"), 0600))
+	tokens, err := NewAPITokens(file)
+	require.NoError(t, err)
+	now := time.Now()
+
+	name, err := tokens.Verify("s3cr3t", now)
+	assert.NoError(t, err)
+	assert.Equal(t, "prometheus", name)
+	name, err = tokens.Verify("0th3r", now)
+	assert.NoError(t, err)
+	assert.Equal(t, "API token on line 4", name)
+	for _, invalid := range []string{"", "s3cr3t prometheus", "# Scrapers", "S3CR3T"} {
+		_, err = tokens.Verify(invalid, now)
+		assert.Error(t, err, invalid)
+	}
+
+	// Tokens are revoked and added once the file is modified.
+	require.NoError(t, os.WriteFile(file, []byte("n3w\tscript\n"), 0600))
+	require.NoError(t, os.Chtimes(file, now.Add(time.Second), now.Add(time.Second)))
+	_, err = tokens.Verify("n3w", now.Add(time.Second))
+	assert.Error(t, err, "file checked before tokenFileCheckInterval")
+	now = now.Add(tokenFileCheckInterval)
+	name, err = tokens.Verify("n3w", now)
+	assert.NoError(t, err)
+	assert.Equal(t, "script", name)
+	_, err = tokens.Verify("s3cr3t", now)
+	assert.Error(t, err)
+
+	// The previous tokens are kept if the file cannot be loaded.
+	require.NoError(t, os.WriteFile(file, nil, 0600))
+	require.NoError(t, os.Chtimes(file, now.Add(time.Second), now.Add(time.Second)))
+	now = now.Add(tokenFileCheckInterval)
+	_, err = tokens.Verify("n3w", now)
+	assert.NoError(t, err)
+}
+
+func TestNewAPITokensInvalidFile(t *testing.T) {
+	_, err := NewAPITokens("/missing/tokens")
+	assert.Error(t, err)
+	file := filepath.Join(t.TempDir(), "tokens")
+	require.NoError(t, os.WriteFile(file, []byte("# No token yet\n"), 0600))
+	_, err = NewAPITokens(file)
+	assert.Error(t, err)
+}
+
+func TestTokenAuthHandlerVerifiers(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "tokens")
+	require.NoError(t, os.WriteFile(file, []byte("s3cr3t prometheus\n"), 0600))
+	tokens, err := NewAPITokens(file)
+	require.NoError(t, err)
+	issuer := newTestIssuer(t)
+	issuer.addKey(t, "ec", true)
+	authenticator, err := NewTokenAuthenticator(TokenAuthConfig{Issuer: issuer.server.URL, Audience: "cadvisor"})
+	require.NoError(t, err)
+	handler := TokenAuthHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, _ := identity.FromRequest(r)
+		_, _ = io.WriteString(w, id)
+	}), CORSConfig{}, authenticator, tokens)
+
+	// Tokens accepted by any of the verifiers are accepted.
+	for token, id := range map[string]string{
+		"s3cr3t": "prometheus",
+		issuer.sign(t, "ec", issuer.claims(time.Now())): "alice@example.com",
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/api/v2.0/machine", nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, id, w.Body.String())
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/api/v2.0/machine", nil)
+	r.Header.Set("Authorization", "Bearer wrong")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
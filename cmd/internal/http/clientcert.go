@@ -0,0 +1,81 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/yidoyoon/cadvisor-lite/cmd/internal/http/identity"
+
+	"k8s.io/klog/v2"
+)
+
+// VerifyClientCerts makes config verify the certificates of the clients
+// against the CA certificates of caFile. The certificates are only verified if
+// given during the handshake: ClientCertHandler rejects the requests without
+// one.
+func VerifyClientCerts(config *tls.Config, caFile string) error {
+	ca, err := os.ReadFile(caFile)
+	if err != nil {
+		return fmt.Errorf("failed to read the client CA certificates: %v", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return fmt.Errorf("no CA certificate found in %q", caFile)
+	}
+	config.ClientCAs = pool
+	config.ClientAuth = tls.VerifyClientCertIfGiven
+	return nil
+}
+
+// ClientCertHandler rejects the requests without a verified client
+// certificate with 401 Unauthorized, except health checks so that probes
+// do not need a certificate. The identity of the client is logged and
+// available to h through identity.FromRequest.
+func ClientCertHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+			if healthCheck(r) {
+				h.ServeHTTP(w, r)
+				return
+			}
+			http.Error(w, "client certificate required", http.StatusUnauthorized)
+			return
+		}
+		id := CertIdentity(r.TLS.VerifiedChains[0][0])
+		klog.V(2).Infof("%s %s from %s by %q", r.Method, r.URL.Path, r.RemoteAddr, id)
+		h.ServeHTTP(w, r.WithContext(identity.NewContext(r.Context(), id)))
+	})
+}
+
+// CertIdentity returns the common name of the subject of cert, or else its
+// first URI, e.g. a SPIFFE ID, DNS name or email address.
+func CertIdentity(cert *x509.Certificate) string {
+	switch {
+	case cert.Subject.CommonName != "":
+		return cert.Subject.CommonName
+	case len(cert.URIs) > 0:
+		return cert.URIs[0].String()
+	case len(cert.DNSNames) > 0:
+		return cert.DNSNames[0]
+	case len(cert.EmailAddresses) > 0:
+		return cert.EmailAddresses[0]
+	}
+	return cert.Subject.String()
+}
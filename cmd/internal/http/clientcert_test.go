@@ -0,0 +1,148 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yidoyoon/cadvisor-lite/cmd/internal/http/identity"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCA{cert: cert, key: key}
+}
+
+// Returns a client certificate issued by the CA for the subject and URIs.
+func (ca *testCA) issue(t *testing.T, subject pkix.Name, uris ...string) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      subject,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	for _, u := range uris {
+		parsed, err := url.Parse(u)
+		require.NoError(t, err)
+		template.URIs = append(template.URIs, parsed)
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestClientCertHandler(t *testing.T) {
+	ca := newTestCA(t)
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw}), 0600))
+
+	config, err := SelfSignedTLSConfig([]string{"127.0.0.1"})
+	require.NoError(t, err)
+	require.NoError(t, VerifyClientCerts(config, caFile))
+	server := httptest.NewUnstartedServer(ClientCertHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, _ := identity.FromRequest(r)
+		_, _ = io.WriteString(w, id)
+	})))
+	server.TLS = config
+	server.StartTLS()
+	defer server.Close()
+
+	get := func(path string, certs ...tls.Certificate) (int, string, error) {
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+			InsecureSkipVerify: true,
+			Certificates:       certs,
+		}}}
+		resp, err := client.Get(server.URL + path)
+		if err != nil {
+			return 0, "", err
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body), err
+	}
+
+	code, body, err := get("/api/v2.0/machine", ca.issue(t, pkix.Name{CommonName: "prometheus"}))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "prometheus", body)
+
+	_, body, err = get("/api/v2.0/machine", ca.issue(t, pkix.Name{}, "spiffe://cluster.local/ns/monitoring/sa/prometheus"))
+	require.NoError(t, err)
+	assert.Equal(t, "spiffe://cluster.local/ns/monitoring/sa/prometheus", body)
+
+	// Health checks do not need a certificate, other requests do.
+	code, _, err = get("/healthz")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, code)
+	code, _, err = get("/api/v2.0/machine")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, code)
+	// Paths merely ending like health endpoints are not health checks.
+	code, _, err = get("/api/v2.0/machine/healthz")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	// Certificates of other CAs are rejected during the handshake.
+	_, _, err = get("/healthz", newTestCA(t).issue(t, pkix.Name{CommonName: "prometheus"}))
+	assert.Error(t, err)
+}
+
+func TestVerifyClientCertsInvalidCA(t *testing.T) {
+	assert.Error(t, VerifyClientCerts(&tls.Config{}, "/missing/ca.pem"))
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, []byte("not a certificate"), 0600))
+	assert.Error(t, VerifyClientCerts(&tls.Config{}, caFile))
+}
@@ -0,0 +1,130 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Encodings supported by CompressHandler, by order of preference.
+var compressEncodings = []string{"gzip", "deflate"}
+
+// CompressHandler compresses the responses of h with gzip or deflate when the
+// client accepts either encoding. Responses already encoded by h, e.g. by the
+// Prometheus handler, partial responses and WebSocket upgrades are not
+// compressed.
+func CompressHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		encoding := acceptedEncoding(r.Header.Get("Accept-Encoding"))
+		if encoding == "" || r.Method == http.MethodHead || r.Header.Get("Upgrade") != "" {
+			h.ServeHTTP(w, r)
+			return
+		}
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding}
+		defer cw.close()
+		h.ServeHTTP(cw, r)
+	})
+}
+
+// acceptedEncoding returns the preferred encoding of compressEncodings
+// accepted by an Accept-Encoding header, or "" if none is.
+func acceptedEncoding(header string) string {
+	accepted := make(map[string]bool)
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		ok := true
+		if name, value, found := strings.Cut(strings.TrimSpace(params), "="); found && strings.TrimSpace(name) == "q" {
+			q, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			ok = err == nil && q > 0
+		}
+		accepted[strings.ToLower(strings.TrimSpace(coding))] = ok
+	}
+	for _, encoding := range compressEncodings {
+		if accepted[encoding] {
+			return encoding
+		}
+	}
+	return ""
+}
+
+// compressWriter compresses the body of a response once its status is known
+// to allow it.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding    string
+	wroteHeader bool
+	// Writer of the compressed body, nil if the body is not compressed.
+	writer io.WriteCloser
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	// Informational responses precede the actual one.
+	if w.wroteHeader || code < http.StatusOK {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.wroteHeader = true
+	header := w.Header()
+	if header.Get("Content-Encoding") == "" && code != http.StatusNoContent && code != http.StatusPartialContent && code != http.StatusNotModified {
+		header.Set("Content-Encoding", w.encoding)
+		header.Del("Content-Length")
+		if w.encoding == "gzip" {
+			w.writer = gzip.NewWriter(w.ResponseWriter)
+		} else {
+			w.writer, _ = flate.NewWriter(w.ResponseWriter, flate.DefaultCompression)
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *compressWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		// The content type can't be sniffed from the compressed body.
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", http.DetectContentType(p))
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.writer == nil {
+		return w.ResponseWriter.Write(p)
+	}
+	return w.writer.Write(p)
+}
+
+// Flush sends the body compressed so far, so that streamed responses, e.g.
+// of events, are not held back by the compression.
+func (w *compressWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if f, ok := w.writer.(interface{ Flush() error }); ok {
+		_ = f.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *compressWriter) close() {
+	if w.writer != nil {
+		_ = w.writer.Close()
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const body = `{"/":{"name":"/"}}`
+
+func serve(t *testing.T, h http.Handler, acceptEncoding string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, "/api/v1.3/subcontainers/", nil)
+	if acceptEncoding != "" {
+		r.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	w := httptest.NewRecorder()
+	CompressHandler(h).ServeHTTP(w, r)
+	return w
+}
+
+func TestCompressHandler(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, body)
+	})
+
+	w := serve(t, h, "deflate, gzip;q=0.5")
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	gz, err := gzip.NewReader(w.Body)
+	require.NoError(t, err)
+	out, err := io.ReadAll(gz)
+	require.NoError(t, err)
+	assert.Equal(t, body, string(out))
+
+	w = serve(t, h, "gzip;q=0, deflate")
+	assert.Equal(t, "deflate", w.Header().Get("Content-Encoding"))
+	out, err = io.ReadAll(flate.NewReader(w.Body))
+	require.NoError(t, err)
+	assert.Equal(t, body, string(out))
+
+	for _, acceptEncoding := range []string{"", "br", "identity"} {
+		w = serve(t, h, acceptEncoding)
+		assert.Empty(t, w.Header().Get("Content-Encoding"), acceptEncoding)
+		assert.Equal(t, body, w.Body.String(), acceptEncoding)
+	}
+}
+
+func TestCompressHandlerPassThrough(t *testing.T) {
+	// Already encoded.
+	w := serve(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		_, _ = io.WriteString(w, "encoded")
+	}), "gzip")
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	assert.Equal(t, "encoded", w.Body.String())
+
+	// No content.
+	w = serve(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}), "gzip")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Empty(t, w.Body.String())
+}
+
+func TestCompressHandlerFlush(t *testing.T) {
+	flushed, done := make(chan struct{}), make(chan struct{})
+	defer close(done)
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, body)
+		w.(http.Flusher).Flush()
+		flushed <- struct{}{}
+		<-done
+	})
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/api/v1.3/events?stream=true", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	go CompressHandler(h).ServeHTTP(w, r)
+
+	// The flushed body decompresses before the response ends.
+	<-flushed
+	assert.True(t, w.Flushed)
+	gz, err := gzip.NewReader(strings.NewReader(w.Body.String()))
+	require.NoError(t, err)
+	out := make([]byte, len(body))
+	_, err = io.ReadFull(gz, out)
+	require.NoError(t, err)
+	assert.Equal(t, body, string(out))
+}
@@ -0,0 +1,151 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// Response headers of the API readable by the scripts of other origins, on
+// top of the CORS-safelisted ones.
+var corsExposedHeaders = strings.Join([]string{"Age", "ETag", "Last-Modified", "Retry-After"}, ", ")
+
+// CORSConfig sets which cross-origin requests are allowed.
+type CORSConfig struct {
+	// Origins allowed, e.g. "https://dashboard.example.com", or "*" for any.
+	// Empty disables CORS.
+	AllowedOrigins []string
+	// Methods and request headers allowed in the requests.
+	AllowedMethods []string
+	AllowedHeaders []string
+	// Duration for which browsers may cache the result of a preflight
+	// request. Zero lets browsers use their default.
+	MaxAge time.Duration
+}
+
+// Enabled returns whether any origin is allowed.
+func (c CORSConfig) Enabled() bool {
+	return len(c.AllowedOrigins) > 0
+}
+
+func (c CORSConfig) allowsOrigin(origin string) bool {
+	for _, allowed := range c.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c CORSConfig) allowsAny() bool {
+	for _, allowed := range c.AllowedOrigins {
+		if allowed == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+func (c CORSConfig) allowsMethod(method string) bool {
+	for _, allowed := range c.AllowedMethods {
+		if strings.EqualFold(allowed, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// CORSHandler lets the scripts of the origins allowed by config call h.
+// Preflight requests are answered without calling h, with 403 Forbidden if
+// the requested method is not allowed. Requests without an Origin header or
+// from other origins are served as usual, and browsers deny the scripts access
+// to the responses. WebSockets are not subject to CORS: browsers let any page
+// open them, so the upgrades from other origins than the one of the request
+// and the allowed ones are rejected with 403 Forbidden, even if CORS is
+// disabled.
+func CORSHandler(h http.Handler, config CORSConfig) http.Handler {
+	cors := corsHandler(h, config)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if origin := r.Header.Get("Origin"); origin != "" && websocketUpgrade(r) && !sameOrigin(r, origin) && !config.allowsOrigin(origin) {
+			klog.V(2).Infof("Rejected WebSocket upgrade of %s from %s by origin %q", r.URL.Path, r.RemoteAddr, origin)
+			http.Error(w, "origin not allowed", http.StatusForbidden)
+			return
+		}
+		cors.ServeHTTP(w, r)
+	})
+}
+
+// sameOrigin returns whether origin is the one of the host r is sent to.
+func sameOrigin(r *http.Request, origin string) bool {
+	u, err := url.Parse(origin)
+	return err == nil && u.Host != "" && strings.EqualFold(u.Host, r.Host)
+}
+
+func corsHandler(h http.Handler, config CORSConfig) http.Handler {
+	if !config.Enabled() {
+		return h
+	}
+	allowOrigin := func(w http.ResponseWriter, origin string) {
+		if config.allowsAny() {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		} else {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+		}
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The response depends on the origin, unless any is allowed.
+		if !config.allowsAny() {
+			w.Header().Add("Vary", "Origin")
+		}
+		origin := r.Header.Get("Origin")
+		if origin == "" || !config.allowsOrigin(origin) {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		if corsPreflight(r) {
+			method := r.Header.Get("Access-Control-Request-Method")
+			if !config.allowsMethod(method) {
+				http.Error(w, "method not allowed by CORS", http.StatusForbidden)
+				return
+			}
+			allowOrigin(w, origin)
+			w.Header().Set("Access-Control-Allow-Methods", strings.Join(config.AllowedMethods, ", "))
+			if len(config.AllowedHeaders) > 0 {
+				w.Header().Set("Access-Control-Allow-Headers", strings.Join(config.AllowedHeaders, ", "))
+			}
+			if config.MaxAge > 0 {
+				w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(config.MaxAge.Seconds())))
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		allowOrigin(w, origin)
+		w.Header().Set("Access-Control-Expose-Headers", corsExposedHeaders)
+		h.ServeHTTP(w, r)
+	})
+}
+
+// corsPreflight returns whether r is a CORS preflight request, sent by
+// browsers before cross-origin requests which are not simple.
+func corsPreflight(r *http.Request) bool {
+	return r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+}
@@ -0,0 +1,118 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func corsRequest(h http.Handler, method, origin, requestMethod string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, "/api/v2.0/machine", nil)
+	if origin != "" {
+		r.Header.Set("Origin", origin)
+	}
+	if requestMethod != "" {
+		r.Header.Set("Access-Control-Request-Method", requestMethod)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestCORSHandler(t *testing.T) {
+	api := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, body)
+	})
+	h := CORSHandler(api, CORSConfig{
+		AllowedOrigins: []string{"https://dashboard.example.com"},
+		AllowedMethods: []string{"GET", "POST"},
+		AllowedHeaders: []string{"Content-Type"},
+		MaxAge:         10 * time.Minute,
+	})
+
+	w := corsRequest(h, http.MethodGet, "https://dashboard.example.com", "")
+	assert.Equal(t, body, w.Body.String())
+	assert.Equal(t, "https://dashboard.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "Origin", w.Header().Get("Vary"))
+	assert.Contains(t, w.Header().Get("Access-Control-Expose-Headers"), "ETag")
+
+	// Preflight requests do not reach the API.
+	w = corsRequest(h, http.MethodOptions, "https://dashboard.example.com", http.MethodPost)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Empty(t, w.Body.String())
+	assert.Equal(t, "GET, POST", w.Header().Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "Content-Type", w.Header().Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "600", w.Header().Get("Access-Control-Max-Age"))
+
+	w = corsRequest(h, http.MethodOptions, "https://dashboard.example.com", http.MethodDelete)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+
+	// Other origins and same-origin requests are served without CORS
+	// headers.
+	for _, origin := range []string{"https://evil.example.com", ""} {
+		w = corsRequest(h, http.MethodGet, origin, "")
+		assert.Equal(t, body, w.Body.String())
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+	}
+}
+
+func TestCORSHandlerAnyOrigin(t *testing.T) {
+	api := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	h := CORSHandler(api, CORSConfig{AllowedOrigins: []string{"*"}, AllowedMethods: []string{"GET"}})
+
+	w := corsRequest(h, http.MethodGet, "https://dashboard.example.com", "")
+	assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, w.Header().Get("Vary"))
+
+	w = corsRequest(h, http.MethodOptions, "https://dashboard.example.com", http.MethodGet)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Empty(t, w.Header().Get("Access-Control-Max-Age"))
+}
+
+func TestCORSHandlerWebSocketOrigin(t *testing.T) {
+	api := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	upgrade := func(h http.Handler, origin string) int {
+		r := httptest.NewRequest(http.MethodGet, "http://cadvisor:8080/api/v2.0/stats?stream=true", nil)
+		r.Header.Set("Connection", "Upgrade")
+		r.Header.Set("Upgrade", "websocket")
+		if origin != "" {
+			r.Header.Set("Origin", origin)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	for _, config := range []CORSConfig{{}, {AllowedOrigins: []string{"https://dashboard.example.com"}}} {
+		h := CORSHandler(api, config)
+		assert.Equal(t, http.StatusForbidden, upgrade(h, "https://evil.example.com"))
+		assert.Equal(t, http.StatusOK, upgrade(h, "http://cadvisor:8080"))
+		// Clients other than browsers do not send an Origin header.
+		assert.Equal(t, http.StatusOK, upgrade(h, ""))
+	}
+	h := CORSHandler(api, CORSConfig{AllowedOrigins: []string{"https://dashboard.example.com"}})
+	assert.Equal(t, http.StatusOK, upgrade(h, "https://dashboard.example.com"))
+	assert.Equal(t, http.StatusForbidden, upgrade(CORSHandler(api, CORSConfig{}), "https://dashboard.example.com"))
+
+	// Requests which are not upgrades are left to CORS.
+	assert.Equal(t, http.StatusOK, corsRequest(CORSHandler(api, CORSConfig{}), http.MethodGet, "https://evil.example.com", "").Code)
+}
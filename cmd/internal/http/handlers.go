@@ -17,29 +17,49 @@ package http
 import (
 	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
 
+	"github.com/yidoyoon/cadvisor-lite/cmd/internal/admin"
 	"github.com/yidoyoon/cadvisor-lite/cmd/internal/api"
 	"github.com/yidoyoon/cadvisor-lite/cmd/internal/healthz"
 	httpmux "github.com/yidoyoon/cadvisor-lite/cmd/internal/http/mux"
 	"github.com/yidoyoon/cadvisor-lite/cmd/internal/pages"
 	"github.com/yidoyoon/cadvisor-lite/cmd/internal/pages/static"
 	"github.com/yidoyoon/cadvisor-lite/container"
+	v2 "github.com/yidoyoon/cadvisor-lite/info/v2"
 	"github.com/yidoyoon/cadvisor-lite/manager"
 	"github.com/yidoyoon/cadvisor-lite/metrics"
+	"github.com/yidoyoon/cadvisor-lite/storage"
+	"github.com/yidoyoon/cadvisor-lite/utils/apicache"
+	"github.com/yidoyoon/cadvisor-lite/utils/relabel"
 	"github.com/yidoyoon/cadvisor-lite/validate"
 
 	auth "github.com/abbot/go-http-auth"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/collectors"
-
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/common/expfmt"
 	"k8s.io/klog/v2"
 	"k8s.io/utils/clock"
 )
 
-func RegisterHandlers(mux httpmux.Mux, containerManager manager.Manager, httpAuthFile, httpAuthRealm, httpDigestFile, httpDigestRealm string, urlBasePrefix string) error {
-	// Basic health handler.
-	if err := healthz.RegisterHandler(mux); err != nil {
+// healthCheck returns whether r is for one of the health endpoints, which
+// are served without credentials. The path of r must already be stripped of
+// the URL base prefix: other paths merely ending like a health endpoint, e.g.
+// /api/v2.0/machine/healthz, are served by other handlers.
+func healthCheck(r *http.Request) bool {
+	switch r.URL.Path {
+	case "/healthz", "/livez", "/readyz":
+		return true
+	}
+	return false
+}
+
+func RegisterHandlers(mux httpmux.Mux, containerManager manager.Manager, httpAuthFile, httpAuthRealm, httpDigestFile, httpDigestRealm string, urlBasePrefix string, enableAdminAPI bool, cors CORSConfig, apiAuthorizationFile string, auditLog *api.AuditLog) error {
+	// Health handlers.
+	if err := healthz.RegisterHandler(mux, containerManager); err != nil {
 		return fmt.Errorf("failed to register healthz handler: %s", err)
 	}
 
@@ -51,77 +71,210 @@ func RegisterHandlers(mux httpmux.Mux, containerManager manager.Manager, httpAut
 		}
 	})
 
-	// Register API handler.
-	if err := api.RegisterHandlers(mux, containerManager); err != nil {
+	// Register API handler, callable from the origins allowed by cors, which
+	// also restrict the origins of the WebSockets, by
+	// the clients allowed by the authorization policy if any. The requests,
+	// including the denied ones, are recorded in the audit log if any.
+	apiMux := mux
+	if auditLog != nil {
+		apiMux = httpmux.Wrap(apiMux, func(h http.Handler) http.Handler { return api.AuditHandler(h, auditLog) })
+	}
+	apiMux = httpmux.Wrap(apiMux, func(h http.Handler) http.Handler { return CORSHandler(h, cors) })
+	if apiAuthorizationFile != "" {
+		klog.V(1).Infof("Using API authorization policy %s", apiAuthorizationFile)
+		policy, err := api.ParseAuthorizationPolicy(apiAuthorizationFile)
+		if err != nil {
+			return err
+		}
+		apiMux = httpmux.Wrap(apiMux, func(h http.Handler) http.Handler { return api.AuthorizationHandler(h, policy) })
+	}
+	if err := api.RegisterHandlers(apiMux, containerManager); err != nil {
 		return fmt.Errorf("failed to register API handlers: %s", err)
 	}
 
 	// Redirect / to containers page.
 	mux.Handle("/", http.RedirectHandler(urlBasePrefix+pages.ContainersPage, http.StatusTemporaryRedirect))
 
-	var authenticated bool
+	// The authenticator used by the web UI, if any. Also guards the admin API.
+	var authenticator auth.AuthenticatorInterface
 
 	// Setup the authenticator object
 	if httpAuthFile != "" {
 		klog.V(1).Infof("Using auth file %s", httpAuthFile)
 		secrets := auth.HtpasswdFileProvider(httpAuthFile)
-		authenticator := auth.NewBasicAuthenticator(httpAuthRealm, secrets)
-		mux.HandleFunc(static.StaticResource, authenticator.Wrap(staticHandler))
-		if err := pages.RegisterHandlersBasic(mux, containerManager, authenticator, urlBasePrefix); err != nil {
+		basicAuthenticator := auth.NewBasicAuthenticator(httpAuthRealm, secrets)
+		mux.HandleFunc(static.StaticResource, basicAuthenticator.Wrap(staticHandler))
+		if err := pages.RegisterHandlersBasic(mux, containerManager, basicAuthenticator, urlBasePrefix); err != nil {
 			return fmt.Errorf("failed to register pages auth handlers: %s", err)
 		}
-		authenticated = true
+		authenticator = basicAuthenticator
 	}
 	if httpAuthFile == "" && httpDigestFile != "" {
 		klog.V(1).Infof("Using digest file %s", httpDigestFile)
 		secrets := auth.HtdigestFileProvider(httpDigestFile)
-		authenticator := auth.NewDigestAuthenticator(httpDigestRealm, secrets)
-		mux.HandleFunc(static.StaticResource, authenticator.Wrap(staticHandler))
-		if err := pages.RegisterHandlersDigest(mux, containerManager, authenticator, urlBasePrefix); err != nil {
+		digestAuthenticator := auth.NewDigestAuthenticator(httpDigestRealm, secrets)
+		mux.HandleFunc(static.StaticResource, digestAuthenticator.Wrap(staticHandler))
+		if err := pages.RegisterHandlersDigest(mux, containerManager, digestAuthenticator, urlBasePrefix); err != nil {
 			return fmt.Errorf("failed to register pages digest handlers: %s", err)
 		}
-		authenticated = true
+		authenticator = digestAuthenticator
 	}
 
 	// Change handler based on authenticator initalization
-	if !authenticated {
+	if authenticator == nil {
 		mux.HandleFunc(static.StaticResource, staticHandlerNoAuth)
 		if err := pages.RegisterHandlersBasic(mux, containerManager, nil, urlBasePrefix); err != nil {
 			return fmt.Errorf("failed to register pages handlers: %s", err)
 		}
 	}
 
+	if enableAdminAPI {
+		if err := admin.RegisterHandlers(mux, containerManager, authenticator); err != nil {
+			return fmt.Errorf("failed to register admin handlers: %s", err)
+		}
+	}
+
 	return nil
 }
 
 // RegisterPrometheusHandler creates a new PrometheusCollector and configures
 // the provided HTTP mux to handle the given Prometheus endpoint.
+// RegisterPrometheusHandler registers the handler of the Prometheus metrics.
+// With openMetrics, the metrics are served in the OpenMetrics format to the
+// scrapers accepting it, with the exemplars of the counters if exemplars is
+// set.
 func RegisterPrometheusHandler(mux httpmux.Mux, resourceManager manager.Manager, prometheusEndpoint string,
-	f metrics.ContainerLabelsFunc, includedMetrics container.MetricSet) {
+	f metrics.ContainerLabelsFunc, includedMetrics container.MetricSet, relabeler *relabel.Relabeler, openMetrics, exemplars bool) {
 	goCollector := collectors.NewGoCollector()
 	processCollector := collectors.NewProcessCollector(collectors.ProcessCollectorOpts{})
 	machineCollector := metrics.NewPrometheusMachineCollector(resourceManager, includedMetrics)
+	apiCacheCollector := apicache.NewPrometheusCollector()
+	storageCollector := storage.NewPrometheusCollector()
+	var nodeCollector prometheus.Collector
+	if includedMetrics.Has(container.NodeMetrics) {
+		nodeCollector = metrics.NewPrometheusNodeCollector("/proc")
+	}
+
+	labelsCaches := &prometheusLabelsCaches{caches: make(map[string]*metrics.LabelsCache)}
 
 	mux.Handle(prometheusEndpoint, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		opts, err := api.GetRequestOptions(req)
+		opts, labelFilter, err := prometheusRequestOptions(req)
 		if err != nil {
-			http.Error(w, "No metrics gathered, last error:\n\n"+err.Error(), http.StatusInternalServerError)
+			http.Error(w, "No metrics gathered, last error:\n\n"+err.Error(), http.StatusBadRequest)
 			return
 		}
 		opts.Count = 1        // we only want the latest datapoint
 		opts.Recursive = true // get all child containers
 
+		collector := metrics.NewPrometheusCollector(resourceManager, f, includedMetrics, clock.RealClock{}, opts, labelFilter)
+		collector.SetLabelsCache(labelsCaches.get(req.URL.Query()))
 		r := prometheus.NewRegistry()
 		r.MustRegister(
-			metrics.NewPrometheusCollector(resourceManager, f, includedMetrics, clock.RealClock{}, opts),
+			collector,
 			machineCollector,
+			apiCacheCollector,
+			storageCollector,
 			goCollector,
 			processCollector,
 		)
-		promhttp.HandlerFor(r, promhttp.HandlerOpts{ErrorHandling: promhttp.ContinueOnError}).ServeHTTP(w, req)
+		if nodeCollector != nil {
+			r.MustRegister(nodeCollector)
+		}
+		var g prometheus.Gatherer = r
+		if relabeler.HasRules() {
+			g = metrics.NewRelabelingGatherer(r, relabeler)
+		}
+		if format := expfmt.NegotiateIncludingOpenMetrics(req.Header); openMetrics && strings.HasPrefix(string(format), expfmt.OpenMetricsType) {
+			serveOpenMetrics(w, g, format, exemplars)
+			return
+		}
+		promhttp.HandlerFor(g, promhttp.HandlerOpts{ErrorHandling: promhttp.ContinueOnError}).ServeHTTP(w, req)
 	}))
 }
 
+// Maximum number of the sets of parameters of the scrapes whose labels are
+// cached, beyond which the caches are dropped.
+const maxPrometheusLabelsCaches = 16
+
+// prometheusLabelsCaches holds the labels of the containers across the
+// scrapes of the Prometheus endpoint, in a cache per set of parameters since
+// they select different containers.
+type prometheusLabelsCaches struct {
+	mu     sync.Mutex
+	caches map[string]*metrics.LabelsCache
+}
+
+func (c *prometheusLabelsCaches) get(query url.Values) *metrics.LabelsCache {
+	key := query.Encode()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cache, ok := c.caches[key]
+	if !ok {
+		if len(c.caches) >= maxPrometheusLabelsCaches {
+			c.caches = make(map[string]*metrics.LabelsCache)
+		}
+		cache = metrics.NewLabelsCache()
+		c.caches[key] = cache
+	}
+	return cache
+}
+
+// serveOpenMetrics writes the metrics of g in the OpenMetrics format, which
+// promhttp writes without the _created series. As promhttp does with
+// ContinueOnError, the metrics gathered despite errors are served.
+func serveOpenMetrics(w http.ResponseWriter, g prometheus.Gatherer, format expfmt.Format, exemplars bool) {
+	families, err := g.Gather()
+	if err != nil {
+		if len(families) == 0 {
+			http.Error(w, "An error has occurred while serving metrics:\n\n"+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		klog.Errorf("Error gathering metrics: %v", err)
+	}
+	w.Header().Set("Content-Type", string(format))
+	if err := metrics.WriteOpenMetrics(w, families, exemplars); err != nil {
+		klog.Errorf("Error writing the metrics: %v", err)
+	}
+}
+
+// prometheusRequestOptions returns the request options of a scrape of the
+// Prometheus endpoint, and the filter of the containers by the labels of
+// their metrics. Besides the options of the API, the containers can be
+// selected by their Kubernetes namespace with the namespace parameter, and by
+// the container_label_ labels of their metrics with parameters named after
+// them, e.g. container_label_io_kubernetes_pod_name=web. Each parameter may
+// be repeated to select several values.
+func prometheusRequestOptions(req *http.Request) (v2.RequestOptions, metrics.ContainerLabelFilter, error) {
+	opts, err := api.GetRequestOptions(req)
+	if err != nil {
+		return opts, nil, err
+	}
+	query := req.URL.Query()
+	if namespaces := query["namespace"]; len(namespaces) > 0 {
+		for _, namespace := range namespaces {
+			if namespace == "" || strings.ContainsAny(namespace, ",() ") {
+				return opts, nil, fmt.Errorf("invalid 'namespace' option %q", namespace)
+			}
+		}
+		requirement := fmt.Sprintf("%s in (%s)", v2.PodNamespaceLabel, strings.Join(namespaces, ","))
+		if opts.LabelSelector != "" {
+			requirement = opts.LabelSelector + "," + requirement
+		}
+		opts.LabelSelector = requirement
+	}
+	var labelFilter metrics.ContainerLabelFilter
+	for name, values := range query {
+		if !strings.HasPrefix(name, metrics.ContainerLabelPrefix) {
+			continue
+		}
+		if labelFilter == nil {
+			labelFilter = make(metrics.ContainerLabelFilter)
+		}
+		labelFilter[name] = values
+	}
+	return opts, labelFilter, nil
+}
+
 func staticHandlerNoAuth(w http.ResponseWriter, r *http.Request) {
 	static.HandleRequest(w, r.URL)
 }
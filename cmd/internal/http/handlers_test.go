@@ -0,0 +1,182 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yidoyoon/cadvisor-lite/container"
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+	"github.com/yidoyoon/cadvisor-lite/manager/fake"
+	"github.com/yidoyoon/cadvisor-lite/metrics"
+)
+
+var startTimeID = regexp.MustCompile(`(?m)^container_start_time_seconds\{.*\bid="([^"]*)"`)
+
+func TestHealthCheck(t *testing.T) {
+	for path, expected := range map[string]bool{
+		"/healthz":                    true,
+		"/livez":                      true,
+		"/readyz":                     true,
+		"/api/v2.0/machine/healthz":   false,
+		"/api/v2.1/attributes/readyz": false,
+		"/containers/livez":           false,
+		"/healthz/":                   false,
+		"/cadvisor/healthz":           false,
+	} {
+		assert.Equal(t, expected, healthCheck(httptest.NewRequest(http.MethodGet, path, nil)), path)
+	}
+
+	// The URL base prefix is stripped before the health endpoints are matched.
+	handler := http.StripPrefix("/cadvisor", TokenAuthHandler(http.NotFoundHandler(), CORSConfig{}))
+	for path, code := range map[string]int{
+		"/cadvisor/healthz":                  http.StatusNotFound,
+		"/cadvisor/api/v2.0/machine/healthz": http.StatusUnauthorized,
+	} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, code, w.Code, path)
+	}
+}
+
+func TestPrometheusHandlerFilters(t *testing.T) {
+	m := fake.NewManager()
+	for name, labels := range map[string]map[string]string{
+		"/kubepods/web-1": {"io.kubernetes.pod.namespace": "prod", "io.kubernetes.pod.name": "web-1"},
+		"/kubepods/web-2": {"io.kubernetes.pod.namespace": "prod", "io.kubernetes.pod.name": "web-2"},
+		"/kubepods/db-1":  {"io.kubernetes.pod.namespace": "dev", "io.kubernetes.pod.name": "db-1"},
+		"/system.slice":   nil,
+	} {
+		m.AddContainer(info.ContainerReference{Name: name}, info.ContainerSpec{Labels: labels})
+		require.NoError(t, m.AddStats(name, &info.ContainerStats{Timestamp: time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)}))
+	}
+	mux := http.NewServeMux()
+	RegisterPrometheusHandler(mux, m, "/metrics", nil, container.MetricSet{}, nil, false, false)
+
+	scrape := func(query string) (int, []string) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics?"+query, nil))
+		ids := []string{}
+		for _, match := range startTimeID.FindAllStringSubmatch(w.Body.String(), -1) {
+			ids = append(ids, match[1])
+		}
+		sort.Strings(ids)
+		return w.Code, ids
+	}
+
+	code, ids := scrape("")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []string{"/", "/kubepods", "/kubepods/db-1", "/kubepods/web-1", "/kubepods/web-2", "/system.slice"}, ids)
+
+	_, ids = scrape("namespace=prod")
+	assert.Equal(t, []string{"/kubepods/web-1", "/kubepods/web-2"}, ids)
+	_, ids = scrape("namespace=prod&namespace=dev&name_regex=.*-1")
+	assert.Equal(t, []string{"/kubepods/db-1", "/kubepods/web-1"}, ids)
+	_, ids = scrape("container_label_io_kubernetes_pod_name=web-2&container_label_io_kubernetes_pod_name=db-1")
+	assert.Equal(t, []string{"/kubepods/db-1", "/kubepods/web-2"}, ids)
+	_, ids = scrape("namespace=prod&container_label_io_kubernetes_pod_name=db-1")
+	assert.Equal(t, []string{}, ids)
+	_, ids = scrape("name_regex=/system.*")
+	assert.Equal(t, []string{"/system.slice"}, ids)
+
+	for _, query := range []string{"namespace=", "namespace=a,b", "name_regex=(", "label_selector=%3Dprod"} {
+		code, _ = scrape(query)
+		assert.Equal(t, http.StatusBadRequest, code, query)
+	}
+}
+
+func TestPrometheusHandlerLabelsCache(t *testing.T) {
+	m := fake.NewManager()
+	for name, namespace := range map[string]string{"/kubepods/web-1": "prod", "/kubepods/db-1": "dev"} {
+		m.AddContainer(info.ContainerReference{Name: name}, info.ContainerSpec{Labels: map[string]string{"io.kubernetes.pod.namespace": namespace}})
+		require.NoError(t, m.AddStats(name, &info.ContainerStats{Timestamp: time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)}))
+	}
+	var lock sync.Mutex
+	calls := map[string]int{}
+	mux := http.NewServeMux()
+	RegisterPrometheusHandler(mux, m, "/metrics", func(cont *info.ContainerInfo) map[string]string {
+		lock.Lock()
+		defer lock.Unlock()
+		calls[cont.Name]++
+		return metrics.DefaultContainerLabels(cont)
+	}, container.MetricSet{}, nil, false, false)
+	scrape := func(query string) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics?"+query, nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Contains(t, w.Body.String(), "container_start_time_seconds")
+	}
+
+	// The labels of the containers are computed by the first scrape only.
+	scrape("")
+	scrape("")
+	assert.Equal(t, map[string]int{"/": 1, "/kubepods": 1, "/kubepods/web-1": 1, "/kubepods/db-1": 1}, calls)
+
+	// Scrapes selecting other containers do not evict them.
+	scrape("namespace=prod")
+	scrape("namespace=prod")
+	scrape("")
+	assert.Equal(t, map[string]int{"/": 1, "/kubepods": 1, "/kubepods/web-1": 2, "/kubepods/db-1": 1}, calls)
+}
+
+func TestPrometheusHandlerOpenMetrics(t *testing.T) {
+	m := fake.NewManager()
+	m.AddContainer(info.ContainerReference{Name: "/web"}, info.ContainerSpec{CreationTime: time.Unix(1257894000, 0)})
+	require.NoError(t, m.AddStats("/web", &info.ContainerStats{
+		Timestamp:    time.Unix(1395066363, 0),
+		OOMEvents:    2,
+		LastOOMEvent: &info.EventReference{Id: 12, Timestamp: time.Unix(1395066300, 0)},
+	}))
+	includedMetrics := container.MetricSet{container.OOMMetrics: struct{}{}}
+
+	scrape := func(openMetrics, exemplars bool, accept string) *httptest.ResponseRecorder {
+		mux := http.NewServeMux()
+		RegisterPrometheusHandler(mux, m, "/metrics", nil, includedMetrics, nil, openMetrics, exemplars)
+		r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		r.Header.Set("Accept", accept)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		require.Equal(t, http.StatusOK, w.Code)
+		return w
+	}
+	const openMetricsAccept = "application/openmetrics-text; version=1.0.0,text/plain;version=0.0.4;q=0.5"
+	const sample = `container_oom_events_total{id="/web"} 2.0 1.395066363e+09`
+	const created = "\ncontainer_oom_events_created{id=\"/web\"} 1.257894e+09 1.395066363e+09\n"
+
+	// The text format is served unless OpenMetrics is enabled and accepted.
+	w := scrape(false, false, openMetricsAccept)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/plain")
+	assert.NotContains(t, w.Body.String(), "_created")
+	w = scrape(true, true, "text/plain")
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/plain")
+
+	w = scrape(true, false, openMetricsAccept)
+	assert.Contains(t, w.Header().Get("Content-Type"), "application/openmetrics-text; version=1.0.0")
+	assert.Contains(t, w.Body.String(), sample+created)
+	assert.True(t, strings.HasSuffix(w.Body.String(), "# EOF\n"))
+
+	w = scrape(true, true, openMetricsAccept)
+	assert.Contains(t, w.Body.String(), sample+` # {event_id="12"} 1.0 1.3950663e+09`+created)
+}
@@ -0,0 +1,36 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package identity carries the identity of the authenticated client of a
+// request, so that the handlers can base authorization decisions on it.
+package identity
+
+import (
+	"context"
+	"net/http"
+)
+
+type key struct{}
+
+// NewContext returns a copy of ctx carrying the identity of the client.
+func NewContext(ctx context.Context, identity string) context.Context {
+	return context.WithValue(ctx, key{}, identity)
+}
+
+// FromRequest returns the identity of the client of r, if it was
+// authenticated with a certificate or a bearer token.
+func FromRequest(r *http.Request) (string, bool) {
+	identity, ok := r.Context().Value(key{}).(string)
+	return identity, ok
+}
@@ -0,0 +1,256 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	"k8s.io/klog/v2"
+)
+
+// Clients idle for longer than this are forgotten, so that the limiters of
+// past clients do not pile up.
+const clientIdleTimeout = 5 * time.Minute
+
+// Limits of the requests served by LimitHandler. Zero values disable them.
+type Limits struct {
+	// Requests per second allowed from a client IP, and how many of them
+	// may be made at once.
+	ClientRate  float64
+	ClientBurst int
+	// Maximum number of requests served at once.
+	MaxInFlight int
+}
+
+// LimitHandler rejects the requests of h exceeding limits: with 429 Too Many
+// Requests for a client over its rate, and with 503 Service Unavailable while
+// the maximum number of requests are in flight. Both tell when to retry in a
+// Retry-After header. Health checks and the stats and events streams, which
+// last as long as the client wants, do not count against the in-flight
+// requests, nor do WebSocket upgrades once accepted.
+func LimitHandler(h http.Handler, limits Limits) http.Handler {
+	if limits.ClientRate <= 0 && limits.MaxInFlight <= 0 {
+		return h
+	}
+	return newLimiter(h, limits)
+}
+
+func newLimiter(h http.Handler, limits Limits) *limiter {
+	l := &limiter{
+		handler: h,
+		limits:  limits,
+		clients: make(map[string]*clientLimiter),
+	}
+	if limits.MaxInFlight > 0 {
+		l.inFlight = make(chan struct{}, limits.MaxInFlight)
+	}
+	return l
+}
+
+type limiter struct {
+	handler http.Handler
+	limits  Limits
+	// Semaphore of the requests in flight, nil without limit.
+	inFlight chan struct{}
+
+	lock      sync.Mutex
+	clients   map[string]*clientLimiter
+	lastPrune time.Time
+}
+
+type clientLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+func (l *limiter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if l.limits.ClientRate > 0 {
+		client := clientIP(r)
+		if delay := l.reserve(client, time.Now()); delay > 0 {
+			klog.V(4).Infof("Rejecting request %q of client %s over its rate limit", r.URL.Path, client)
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+			http.Error(w, "too many requests", http.StatusTooManyRequests)
+			return
+		}
+	}
+	if l.inFlight != nil && !inFlightExempt(r) {
+		select {
+		case l.inFlight <- struct{}{}:
+			var release sync.Once
+			defer release.Do(func() { <-l.inFlight })
+			if websocketUpgrade(r) {
+				// An accepted upgrade hijacks the connection, which then
+				// lasts as long as the client wants.
+				if hijacker, ok := w.(http.Hijacker); ok {
+					w = &releasingHijacker{ResponseWriter: w, hijacker: hijacker, release: func() { release.Do(func() { <-l.inFlight }) }}
+				}
+			}
+		default:
+			klog.V(4).Infof("Rejecting request %q, %d requests in flight", r.URL.Path, l.limits.MaxInFlight)
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "too many requests in flight", http.StatusServiceUnavailable)
+			return
+		}
+	}
+	l.handler.ServeHTTP(w, r)
+}
+
+// Limiter enforces Limits on the calls of protocols other than HTTP, e.g.
+// gRPC. It shares neither the rates of the clients nor the calls in flight
+// with LimitHandler.
+type Limiter struct {
+	l *limiter
+}
+
+// NewLimiter returns a Limiter enforcing limits.
+func NewLimiter(limits Limits) *Limiter {
+	return &Limiter{l: newLimiter(nil, limits)}
+}
+
+// LimitError is returned for a call over the limits.
+type LimitError struct {
+	// Whether the client is over its rate, rather than too many calls are in
+	// flight.
+	RateLimited bool
+	// How long to wait before retrying.
+	RetryAfter time.Duration
+}
+
+func (e *LimitError) Error() string {
+	if e.RateLimited {
+		return fmt.Sprintf("too many requests, retry after %v", e.RetryAfter)
+	}
+	return fmt.Sprintf("too many requests in flight, retry after %v", e.RetryAfter)
+}
+
+// Admit returns a function to call once the call of client is served, or a
+// LimitError if the call is over the limits. Streams, which last as long as
+// the client wants, do not count against the calls in flight.
+func (l *Limiter) Admit(client string, stream bool) (func(), error) {
+	if l.l.limits.ClientRate > 0 {
+		if delay := l.l.reserve(client, time.Now()); delay > 0 {
+			return nil, &LimitError{RateLimited: true, RetryAfter: delay}
+		}
+	}
+	if l.l.inFlight == nil || stream {
+		return func() {}, nil
+	}
+	select {
+	case l.l.inFlight <- struct{}{}:
+		var release sync.Once
+		return func() { release.Do(func() { <-l.l.inFlight }) }, nil
+	default:
+		return nil, &LimitError{RetryAfter: time.Second}
+	}
+}
+
+// reserve takes a token of the limiter of client, and returns how long to
+// wait for one if there is none.
+func (l *limiter) reserve(client string, now time.Time) time.Duration {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if now.Sub(l.lastPrune) > clientIdleTimeout {
+		for ip, c := range l.clients {
+			if now.Sub(c.lastSeen) > clientIdleTimeout {
+				delete(l.clients, ip)
+			}
+		}
+		l.lastPrune = now
+	}
+	c, ok := l.clients[client]
+	if !ok {
+		burst := l.limits.ClientBurst
+		if burst < 1 {
+			burst = 1
+		}
+		c = &clientLimiter{limiter: rate.NewLimiter(rate.Limit(l.limits.ClientRate), burst)}
+		l.clients[client] = c
+	}
+	c.lastSeen = now
+	reservation := c.limiter.ReserveN(now, 1)
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return delay
+	}
+	return 0
+}
+
+// clientIP returns the IP of the client of r. Proxy headers such as
+// X-Forwarded-For are not trusted, since clients can set them.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// streamPath matches the paths of the API requests which may stream their
+// response: the stats and the events.
+var streamPath = regexp.MustCompile(`^/api/[^/]+/(stats|events)(/|$)`)
+
+// inFlightExempt returns whether r is a health check or streams stats or
+// events for as long as the client wants.
+func inFlightExempt(r *http.Request) bool {
+	if healthCheck(r) {
+		return true
+	}
+	return r.Method == http.MethodGet && r.URL.Query().Get("stream") == "true" && streamPath.MatchString(r.URL.Path)
+}
+
+// websocketUpgrade returns whether r asks to upgrade its connection to a
+// WebSocket.
+func websocketUpgrade(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket") && headerContains(r.Header, "Connection", "upgrade")
+}
+
+// headerContains returns whether the comma-separated values of the header
+// key of h contain token, ignoring case.
+func headerContains(h http.Header, key, token string) bool {
+	for _, value := range h.Values(key) {
+		for _, v := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(v), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// releasingHijacker calls release when the connection is hijacked, i.e. when
+// the upgrade is accepted.
+type releasingHijacker struct {
+	http.ResponseWriter
+	hijacker http.Hijacker
+	release  func()
+}
+
+func (h *releasingHijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := h.hijacker.Hijack()
+	if err == nil {
+		h.release()
+	}
+	return conn, rw, err
+}
@@ -0,0 +1,185 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func request(h http.Handler, remoteAddr, url string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, url, nil)
+	r.RemoteAddr = remoteAddr
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestLimitHandlerClientRate(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	h := LimitHandler(ok, Limits{ClientRate: 0.1, ClientBurst: 2})
+
+	// The burst is allowed, then the client has to wait for 10s.
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, http.StatusOK, request(h, "10.0.0.1:1234", "/api/v2.0/stats").Code)
+	}
+	w := request(h, "10.0.0.1:5678", "/api/v2.0/stats")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "10", w.Header().Get("Retry-After"))
+
+	// Other clients are not limited.
+	assert.Equal(t, http.StatusOK, request(h, "10.0.0.2:1234", "/api/v2.0/stats").Code)
+}
+
+func TestLimitHandlerInFlight(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	blocking := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v2.0/stats" {
+			started <- struct{}{}
+			<-release
+		}
+	})
+	h := LimitHandler(blocking, Limits{MaxInFlight: 1})
+
+	done := make(chan int)
+	go func() { done <- request(h, "10.0.0.1:1234", "/api/v2.0/stats").Code }()
+	<-started
+	w := request(h, "10.0.0.2:1234", "/api/v2.0/machine")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+	// Health checks and streams are served anyway.
+	assert.Equal(t, http.StatusOK, request(h, "10.0.0.2:1234", "/healthz").Code)
+	assert.Equal(t, http.StatusOK, request(h, "10.0.0.2:1234", "/api/v1.3/events?stream=true").Code)
+
+	close(release)
+	assert.Equal(t, http.StatusOK, <-done)
+	assert.Equal(t, http.StatusOK, request(h, "10.0.0.2:1234", "/api/v2.0/machine").Code)
+}
+
+func TestInFlightExempt(t *testing.T) {
+	tests := []struct {
+		method string
+		url    string
+		exempt bool
+	}{
+		{http.MethodGet, "/healthz", true},
+		{http.MethodGet, "/api/v1.3/events?stream=true", true},
+		{http.MethodGet, "/api/v2.0/stats/docker?stream=true", true},
+		{http.MethodGet, "/api/v2.0/stats?stream=true", true},
+		{http.MethodGet, "/api/v2.0/stats", false},
+		{http.MethodPost, "/api/v2.0/stats?stream=true", false},
+		{http.MethodGet, "/api/v2.0/machine?stream=true", false},
+		{http.MethodGet, "/api/v2.0/statsx?stream=true", false},
+		{http.MethodGet, "/metrics?stream=true", false},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest(test.method, test.url, nil)
+		assert.Equal(t, test.exempt, inFlightExempt(r), "%s %s", test.method, test.url)
+	}
+	// An Upgrade header alone does not exempt a request.
+	r := httptest.NewRequest(http.MethodGet, "/api/v2.0/machine", nil)
+	r.Header.Set("Upgrade", "websocket")
+	r.Header.Set("Connection", "Upgrade")
+	assert.False(t, inFlightExempt(r))
+}
+
+// hijackRecorder is a ResponseRecorder whose connection can be hijacked.
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+}
+
+func (r hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, _ := net.Pipe()
+	return conn, bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn)), nil
+}
+
+func TestLimitHandlerWebSocketUpgrade(t *testing.T) {
+	hijacked := make(chan struct{})
+	release := make(chan struct{})
+	upgrading := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ws" {
+			return
+		}
+		hijacked <- struct{}{}
+		<-hijacked
+		conn, _, err := w.(http.Hijacker).Hijack()
+		assert.NoError(t, err)
+		defer conn.Close()
+		hijacked <- struct{}{}
+		<-release
+	})
+	h := LimitHandler(upgrading, Limits{MaxInFlight: 1})
+
+	done := make(chan struct{})
+	go func() {
+		r := httptest.NewRequest(http.MethodGet, "/ws", nil)
+		r.Header.Set("Upgrade", "websocket")
+		r.Header.Set("Connection", "keep-alive, Upgrade")
+		h.ServeHTTP(hijackRecorder{httptest.NewRecorder()}, r)
+		close(done)
+	}()
+	// The upgrade counts until it is accepted.
+	<-hijacked
+	assert.Equal(t, http.StatusServiceUnavailable, request(h, "10.0.0.2:1234", "/api/v2.0/machine").Code)
+	hijacked <- struct{}{}
+	<-hijacked
+	assert.Equal(t, http.StatusOK, request(h, "10.0.0.2:1234", "/api/v2.0/machine").Code)
+
+	close(release)
+	<-done
+	assert.Equal(t, http.StatusOK, request(h, "10.0.0.2:1234", "/api/v2.0/machine").Code)
+}
+
+func TestLimiter(t *testing.T) {
+	l := NewLimiter(Limits{ClientRate: 0.1, ClientBurst: 2, MaxInFlight: 1})
+
+	release, err := l.Admit("10.0.0.1", false)
+	require.NoError(t, err)
+	// Streams are not counted in flight.
+	_, err = l.Admit("10.0.0.2", true)
+	assert.NoError(t, err)
+	_, err = l.Admit("10.0.0.2", false)
+	var limitErr *LimitError
+	require.ErrorAs(t, err, &limitErr)
+	assert.False(t, limitErr.RateLimited)
+	release()
+	release, err = l.Admit("10.0.0.1", false)
+	require.NoError(t, err)
+	release()
+
+	_, err = l.Admit("10.0.0.1", false)
+	require.ErrorAs(t, err, &limitErr)
+	assert.True(t, limitErr.RateLimited)
+	assert.InDelta(t, 10*time.Second, limitErr.RetryAfter, float64(time.Second))
+}
+
+func TestLimiterPrunesIdleClients(t *testing.T) {
+	l := LimitHandler(nil, Limits{ClientRate: 1}).(*limiter)
+	now := time.Now()
+	assert.Zero(t, l.reserve("10.0.0.1", now))
+	assert.NotZero(t, l.reserve("10.0.0.1", now))
+	later := now.Add(2 * clientIdleTimeout)
+	assert.Zero(t, l.reserve("10.0.0.2", later))
+	assert.Len(t, l.clients, 1)
+	assert.Contains(t, l.clients, "10.0.0.2")
+}
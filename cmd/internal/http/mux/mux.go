@@ -24,3 +24,21 @@ type Mux interface {
 	Handler(r *http.Request) (http.Handler, string)
 	Handle(pattern string, handler http.Handler)
 }
+
+// Wrap returns a Mux registering the handlers in m, wrapped by middleware.
+func Wrap(m Mux, middleware func(http.Handler) http.Handler) Mux {
+	return &wrappedMux{Mux: m, middleware: middleware}
+}
+
+type wrappedMux struct {
+	Mux
+	middleware func(http.Handler) http.Handler
+}
+
+func (m *wrappedMux) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	m.Mux.Handle(pattern, m.middleware(http.HandlerFunc(handler)))
+}
+
+func (m *wrappedMux) Handle(pattern string, handler http.Handler) {
+	m.Mux.Handle(pattern, m.middleware(handler))
+}
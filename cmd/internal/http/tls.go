@@ -0,0 +1,147 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// Minimum interval between the checks for a renewed certificate.
+const certCheckInterval = 10 * time.Second
+
+// Validity of the self-signed certificates.
+const selfSignedValidity = 365 * 24 * time.Hour
+
+// TLSConfig returns the TLS config of a server presenting the certificate and
+// key of the given files. The files are loaded again when they change, so
+// that the certificate can be renewed without restarting the server.
+func TLSConfig(certFile, keyFile string) (*tls.Config, error) {
+	loader := &certLoader{certFile: certFile, keyFile: keyFile}
+	if _, err := loader.load(time.Now()); err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return loader.load(time.Now())
+		},
+	}, nil
+}
+
+// SelfSignedTLSConfig returns the TLS config of a server presenting a new
+// self-signed certificate for the given host names and IPs.
+func SelfSignedTLSConfig(hosts []string) (*tls.Config, error) {
+	cert, err := selfSignedCert(hosts, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	klog.Infof("Serving HTTPS with a self-signed certificate for %v, SHA-256 fingerprint %x", hosts, sha256.Sum256(cert.Certificate[0]))
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{*cert},
+	}, nil
+}
+
+func selfSignedCert(hosts []string, now time.Time) (*tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate the key: %v", err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate the serial number: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "cAdvisor"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(selfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else if host != "" {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the self-signed certificate: %v", err)
+	}
+	return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
+
+// certLoader loads a certificate and its key, again when either file is
+// modified.
+type certLoader struct {
+	certFile string
+	keyFile  string
+
+	lock      sync.Mutex
+	cert      *tls.Certificate
+	lastCheck time.Time
+	// Modification times of the files when the certificate was loaded.
+	certModTime time.Time
+	keyModTime  time.Time
+}
+
+// load returns the certificate, loading it again if the files changed since
+// the last check, at most every certCheckInterval. The last certificate is
+// kept if the new one cannot be loaded, e.g. while the files are written.
+func (l *certLoader) load(now time.Time) (*tls.Certificate, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.cert != nil && now.Sub(l.lastCheck) < certCheckInterval {
+		return l.cert, nil
+	}
+	l.lastCheck = now
+
+	certInfo, certErr := os.Stat(l.certFile)
+	keyInfo, keyErr := os.Stat(l.keyFile)
+	if l.cert != nil && certErr == nil && keyErr == nil &&
+		certInfo.ModTime().Equal(l.certModTime) && keyInfo.ModTime().Equal(l.keyModTime) {
+		return l.cert, nil
+	}
+	cert, err := tls.LoadX509KeyPair(l.certFile, l.keyFile)
+	if err != nil {
+		if l.cert != nil {
+			klog.Warningf("Failed to load the renewed TLS certificate, still using the previous one: %v", err)
+			return l.cert, nil
+		}
+		return nil, fmt.Errorf("failed to load the TLS certificate: %v", err)
+	}
+	l.cert = &cert
+	if certErr == nil && keyErr == nil {
+		l.certModTime, l.keyModTime = certInfo.ModTime(), keyInfo.ModTime()
+	}
+	return l.cert, nil
+}
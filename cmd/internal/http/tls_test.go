@@ -0,0 +1,102 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Writes a new self-signed certificate for host and its key to certFile and
+// keyFile, and returns the certificate.
+func writeCert(t *testing.T, host, certFile, keyFile string) *x509.Certificate {
+	cert, err := selfSignedCert([]string{host}, time.Now())
+	require.NoError(t, err)
+	key, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}), 0600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: key}), 0600))
+	parsed, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	return parsed
+}
+
+func TestSelfSignedTLSConfig(t *testing.T) {
+	config, err := SelfSignedTLSConfig([]string{"localhost", "127.0.0.1"})
+	require.NoError(t, err)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, body)
+	}))
+	server.TLS = config
+	server.StartTLS()
+	defer server.Close()
+
+	cert, err := x509.ParseCertificate(config.Certificates[0].Certificate[0])
+	require.NoError(t, err)
+	assert.Equal(t, []string{"localhost"}, cert.DNSNames)
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	content, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, body, string(content))
+}
+
+func TestCertLoaderReload(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	first := writeCert(t, "first.example.com", certFile, keyFile)
+	loader := &certLoader{certFile: certFile, keyFile: keyFile}
+	now := time.Now()
+	cert, err := loader.load(now)
+	require.NoError(t, err)
+	assert.Equal(t, first.Raw, cert.Certificate[0])
+
+	second := writeCert(t, "second.example.com", certFile, keyFile)
+	// Make sure the modification times differ on file systems with a coarse
+	// resolution.
+	modTime := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, modTime, modTime))
+	require.NoError(t, os.Chtimes(keyFile, modTime, modTime))
+	cert, _ = loader.load(now.Add(time.Second))
+	assert.Equal(t, first.Raw, cert.Certificate[0])
+	cert, _ = loader.load(now.Add(certCheckInterval))
+	assert.Equal(t, second.Raw, cert.Certificate[0])
+
+	// The last certificate is kept while the files are invalid.
+	require.NoError(t, os.WriteFile(keyFile, []byte("invalid"), 0600))
+	cert, err = loader.load(now.Add(2 * certCheckInterval))
+	require.NoError(t, err)
+	assert.Equal(t, second.Raw, cert.Certificate[0])
+}
+
+func TestTLSConfigInvalidFiles(t *testing.T) {
+	_, err := TLSConfig("/missing/tls.crt", "/missing/tls.key")
+	assert.Error(t, err)
+}
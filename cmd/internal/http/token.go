@@ -0,0 +1,535 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	_ "crypto/sha256" // Hashes of the supported signing algorithms.
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yidoyoon/cadvisor-lite/cmd/internal/http/identity"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	// Timeout of the requests to the issuer.
+	keysRequestTimeout = 10 * time.Second
+	// Duration after which the keys are fetched again, to pick up rotations.
+	keysMaxAge = time.Hour
+	// Minimum delay between two fetches of the keys, when tokens are signed
+	// by unknown keys.
+	keysMinRefreshInterval = time.Minute
+	// Clock skew tolerated when checking the validity period of the tokens.
+	tokenLeeway = time.Minute
+	// Maximum size of the responses of the issuer.
+	maxKeysResponseSize = 1 << 20
+)
+
+// TokenAuthConfig configures the authentication of the clients with bearer
+// tokens, i.e. JSON Web Tokens signed by an identity provider.
+type TokenAuthConfig struct {
+	// Issuer of the tokens, which must match their iss claim. Unless
+	// JWKSURL is set, the keys are found through the OpenID Connect
+	// discovery document of the issuer.
+	Issuer string
+	// Audience which must be among the aud claim of the tokens.
+	Audience string
+	// URL of the JSON Web Key Set verifying the signatures of the tokens.
+	JWKSURL string
+}
+
+// TokenAuthenticator verifies bearer tokens against the keys of their
+// issuer, which are cached and fetched again periodically.
+type TokenAuthenticator struct {
+	config TokenAuthConfig
+	client *http.Client
+
+	mu        sync.Mutex
+	jwksURL   string
+	keys      []verificationKey
+	fetchedAt time.Time
+}
+
+type verificationKey struct {
+	id  string
+	alg string
+	key crypto.PublicKey
+}
+
+// NewTokenAuthenticator returns an authenticator of the tokens of the given
+// audience. The keys are fetched at once, a failure to do so is only logged
+// since they are fetched again when verifying tokens.
+func NewTokenAuthenticator(config TokenAuthConfig) (*TokenAuthenticator, error) {
+	if config.Issuer == "" && config.JWKSURL == "" {
+		return nil, fmt.Errorf("either the issuer or the key set URL of the tokens must be set")
+	}
+	if config.Audience == "" {
+		return nil, fmt.Errorf("the audience of the tokens must be set")
+	}
+	a := &TokenAuthenticator{
+		config:  config,
+		client:  &http.Client{Timeout: keysRequestTimeout},
+		jwksURL: config.JWKSURL,
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := a.refresh(time.Now()); err != nil {
+		klog.Warningf("Failed to fetch the keys verifying the bearer tokens: %v", err)
+	}
+	return a, nil
+}
+
+// TokenVerifier verifies bearer tokens.
+type TokenVerifier interface {
+	// Returns the identity of the holder of token if it is valid at now.
+	Verify(token string, now time.Time) (string, error)
+}
+
+// TokenAuthHandler rejects the requests without a bearer token accepted by
+// one of the verifiers with 401 Unauthorized, except health checks, which
+// are only authenticated when they carry an accepted token. If cors
+// is enabled, CORS preflight requests, which browsers send without
+// credentials, are answered without calling h. The identity of the holder of
+// the token is logged and available to h through identity.FromRequest.
+func TokenAuthHandler(h http.Handler, cors CORSConfig, verifiers ...TokenVerifier) http.Handler {
+	// Answers the preflight requests of the origins not allowed by cors,
+	// without the headers letting browsers make the actual requests.
+	preflight := CORSHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}), cors)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if healthCheck(r) {
+			if token, ok := bearerToken(r); ok {
+				if subject, err := VerifyToken(verifiers, token, time.Now()); err == nil {
+					r = r.WithContext(identity.NewContext(r.Context(), subject))
+				}
+			}
+			h.ServeHTTP(w, r)
+			return
+		}
+		if cors.Enabled() && corsPreflight(r) {
+			preflight.ServeHTTP(w, r)
+			return
+		}
+		token, ok := bearerToken(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "bearer token required", http.StatusUnauthorized)
+			return
+		}
+		subject, err := VerifyToken(verifiers, token, time.Now())
+		if err != nil {
+			klog.V(2).Infof("Rejected bearer token of %s %s from %s: %v", r.Method, r.URL.Path, r.RemoteAddr, err)
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			http.Error(w, "invalid bearer token", http.StatusUnauthorized)
+			return
+		}
+		klog.V(2).Infof("%s %s from %s by %q", r.Method, r.URL.Path, r.RemoteAddr, subject)
+		h.ServeHTTP(w, r.WithContext(identity.NewContext(r.Context(), subject)))
+	})
+}
+
+// VerifyToken returns the identity of the holder of token from the first
+// verifier accepting it.
+func VerifyToken(verifiers []TokenVerifier, token string, now time.Time) (string, error) {
+	var errs []string
+	for _, verifier := range verifiers {
+		id, err := verifier.Verify(token, now)
+		if err == nil {
+			return id, nil
+		}
+		errs = append(errs, err.Error())
+	}
+	return "", fmt.Errorf("%s", strings.Join(errs, "; "))
+}
+
+// bearerToken returns the token of the Authorization header of r.
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// tokenClaims are the claims of a token checked by the authenticator.
+type tokenClaims struct {
+	Issuer    string    `json:"iss"`
+	Subject   string    `json:"sub"`
+	Audience  audience  `json:"aud"`
+	ExpiresAt *jsonTime `json:"exp"`
+	NotBefore *jsonTime `json:"nbf"`
+}
+
+// audience is the aud claim, either a string or a list of strings.
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("invalid audience: %v", err)
+	}
+	*a = list
+	return nil
+}
+
+// jsonTime is a time claim, in seconds since the epoch.
+type jsonTime struct {
+	time.Time
+}
+
+func (t *jsonTime) UnmarshalJSON(data []byte) error {
+	var seconds float64
+	if err := json.Unmarshal(data, &seconds); err != nil {
+		return fmt.Errorf("invalid time: %v", err)
+	}
+	t.Time = time.Unix(0, int64(seconds*float64(time.Second)))
+	return nil
+}
+
+type tokenHeader struct {
+	Alg  string   `json:"alg"`
+	Kid  string   `json:"kid"`
+	Crit []string `json:"crit"`
+}
+
+// Verify returns the subject of token, a JSON Web Token in compact form, if it
+// is signed by a key of the issuer, is valid at now and is meant for the
+// audience.
+func (a *TokenAuthenticator) Verify(token string, now time.Time) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("malformed token")
+	}
+	var header tokenHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return "", fmt.Errorf("invalid header: %v", err)
+	}
+	if len(header.Crit) > 0 {
+		return "", fmt.Errorf("unsupported critical header parameters %q", header.Crit)
+	}
+	alg, ok := signingAlgs[header.Alg]
+	if !ok {
+		return "", fmt.Errorf("unsupported signing algorithm %q", header.Alg)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", fmt.Errorf("invalid signature encoding: %v", err)
+	}
+	hash := alg.hash.New()
+	hash.Write([]byte(parts[0] + "." + parts[1]))
+	digest := hash.Sum(nil)
+
+	verified := false
+	for _, key := range a.candidateKeys(header, now) {
+		if alg.verify(key.key, alg.hash, digest, signature) == nil {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return "", fmt.Errorf("signature not verified by any key of the issuer")
+	}
+
+	var claims tokenClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return "", fmt.Errorf("invalid claims: %v", err)
+	}
+	if a.config.Issuer != "" && claims.Issuer != a.config.Issuer {
+		return "", fmt.Errorf("unexpected issuer %q", claims.Issuer)
+	}
+	if !claims.Audience.contains(a.config.Audience) {
+		return "", fmt.Errorf("token not meant for audience %q", a.config.Audience)
+	}
+	if claims.ExpiresAt == nil {
+		return "", fmt.Errorf("token without expiration time")
+	}
+	if now.After(claims.ExpiresAt.Add(tokenLeeway)) {
+		return "", fmt.Errorf("token expired at %v", claims.ExpiresAt.Time)
+	}
+	if claims.NotBefore != nil && now.Before(claims.NotBefore.Add(-tokenLeeway)) {
+		return "", fmt.Errorf("token not valid before %v", claims.NotBefore.Time)
+	}
+	return claims.Subject, nil
+}
+
+func (a audience) contains(value string) bool {
+	for _, v := range a {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// candidateKeys returns the keys which may have signed a token with the given
+// header. The keys are fetched again if they are too old, or if none matches
+// and they were not fetched recently, e.g. after a rotation.
+func (a *TokenAuthenticator) candidateKeys(header tokenHeader, now time.Time) []verificationKey {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if now.Sub(a.fetchedAt) > keysMaxAge {
+		if err := a.refresh(now); err != nil {
+			klog.Warningf("Failed to fetch the keys verifying the bearer tokens: %v", err)
+		}
+	}
+	candidates := matchingKeys(a.keys, header)
+	if len(candidates) == 0 && now.Sub(a.fetchedAt) > keysMinRefreshInterval {
+		if err := a.refresh(now); err != nil {
+			klog.Warningf("Failed to fetch the keys verifying the bearer tokens: %v", err)
+		}
+		candidates = matchingKeys(a.keys, header)
+	}
+	return candidates
+}
+
+func matchingKeys(keys []verificationKey, header tokenHeader) []verificationKey {
+	var matching []verificationKey
+	for _, key := range keys {
+		if header.Kid != "" && key.id != header.Kid {
+			continue
+		}
+		if key.alg != "" && key.alg != header.Alg {
+			continue
+		}
+		matching = append(matching, key)
+	}
+	return matching
+}
+
+// refresh fetches the keys of the issuer. The previous keys are kept on
+// failures. Must be called with a.mu held.
+func (a *TokenAuthenticator) refresh(now time.Time) error {
+	// Failures count as fetches, so that an unavailable issuer is not
+	// queried for every request.
+	a.fetchedAt = now
+	if a.jwksURL == "" {
+		jwksURL, err := a.discoverJWKSURL()
+		if err != nil {
+			return err
+		}
+		a.jwksURL = jwksURL
+	}
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := a.getJSON(a.jwksURL, &set); err != nil {
+		return err
+	}
+	keys := make([]verificationKey, 0, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			klog.V(2).Infof("Skipping key %q of %s: %v", jwk.Kid, a.jwksURL, err)
+			continue
+		}
+		keys = append(keys, verificationKey{id: jwk.Kid, alg: jwk.Alg, key: key})
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("no supported signing key found in %s", a.jwksURL)
+	}
+	a.keys = keys
+	klog.V(2).Infof("Fetched %d keys verifying the bearer tokens from %s", len(keys), a.jwksURL)
+	return nil
+}
+
+// discoverJWKSURL returns the URL of the keys of the issuer from its OpenID
+// Connect discovery document.
+func (a *TokenAuthenticator) discoverJWKSURL() (string, error) {
+	var discovery struct {
+		Issuer  string `json:"issuer"`
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := a.getJSON(strings.TrimSuffix(a.config.Issuer, "/")+"/.well-known/openid-configuration", &discovery); err != nil {
+		return "", err
+	}
+	if discovery.Issuer != a.config.Issuer {
+		return "", fmt.Errorf("discovery document of issuer %q is for issuer %q", a.config.Issuer, discovery.Issuer)
+	}
+	if discovery.JWKSURI == "" {
+		return "", fmt.Errorf("discovery document of issuer %q without jwks_uri", a.config.Issuer)
+	}
+	return discovery.JWKSURI, nil
+}
+
+func (a *TokenAuthenticator) getJSON(url string, v interface{}) error {
+	resp, err := a.client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxKeysResponseSize))
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %v", url, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("request to %s failed with %s: %s", url, resp.Status, bytes.TrimSpace(body))
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("invalid response from %s: %v", url, err)
+	}
+	return nil
+}
+
+// jsonWebKey is a public key of a JSON Web Key Set, see RFC 7517.
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA keys.
+	N string `json:"n"`
+	E string `json:"e"`
+	// Elliptic curve keys.
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k *jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus: %v", err)
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil || !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("invalid exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		curve, ok := curves[k.Crv]
+		if !ok {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("invalid x coordinate: %v", err)
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("invalid y coordinate: %v", err)
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("point not on curve %s", k.Crv)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("empty value")
+	}
+	return new(big.Int).SetBytes(data), nil
+}
+
+var curves = map[string]elliptic.Curve{
+	"P-256": elliptic.P256(),
+	"P-384": elliptic.P384(),
+	"P-521": elliptic.P521(),
+}
+
+// signingAlg is a signing algorithm of JSON Web Signature, see RFC 7518.
+type signingAlg struct {
+	hash   crypto.Hash
+	verify func(key crypto.PublicKey, hash crypto.Hash, digest, signature []byte) error
+}
+
+var signingAlgs = map[string]signingAlg{
+	"RS256": {crypto.SHA256, verifyPKCS1v15},
+	"RS384": {crypto.SHA384, verifyPKCS1v15},
+	"RS512": {crypto.SHA512, verifyPKCS1v15},
+	"PS256": {crypto.SHA256, verifyPSS},
+	"PS384": {crypto.SHA384, verifyPSS},
+	"PS512": {crypto.SHA512, verifyPSS},
+	"ES256": {crypto.SHA256, verifyECDSA(elliptic.P256())},
+	"ES384": {crypto.SHA384, verifyECDSA(elliptic.P384())},
+	"ES512": {crypto.SHA512, verifyECDSA(elliptic.P521())},
+}
+
+func verifyPKCS1v15(key crypto.PublicKey, hash crypto.Hash, digest, signature []byte) error {
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("not an RSA key")
+	}
+	return rsa.VerifyPKCS1v15(rsaKey, hash, digest, signature)
+}
+
+func verifyPSS(key crypto.PublicKey, hash crypto.Hash, digest, signature []byte) error {
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("not an RSA key")
+	}
+	return rsa.VerifyPSS(rsaKey, hash, digest, signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+}
+
+// verifyECDSA returns the verification of the signatures made with keys on
+// curve, which are the concatenation of the r and s values.
+func verifyECDSA(curve elliptic.Curve) func(crypto.PublicKey, crypto.Hash, []byte, []byte) error {
+	return func(key crypto.PublicKey, _ crypto.Hash, digest, signature []byte) error {
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok || ecKey.Curve != curve {
+			return fmt.Errorf("not a key on curve %s", curve.Params().Name)
+		}
+		size := (curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return fmt.Errorf("invalid signature size")
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(ecKey, digest, r, s) {
+			return fmt.Errorf("invalid signature")
+		}
+		return nil
+	}
+}
@@ -0,0 +1,286 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yidoyoon/cadvisor-lite/cmd/internal/http/identity"
+)
+
+// testIssuer serves the discovery document and the keys of an identity
+// provider, and signs tokens with them.
+type testIssuer struct {
+	server *httptest.Server
+
+	mu   sync.Mutex
+	keys map[string]crypto.Signer
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	issuer := &testIssuer{keys: map[string]crypto.Signer{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":   issuer.server.URL,
+			"jwks_uri": issuer.server.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		issuer.mu.Lock()
+		defer issuer.mu.Unlock()
+		var keys []map[string]string
+		for kid, signer := range issuer.keys {
+			encode := func(i *big.Int) string { return base64.RawURLEncoding.EncodeToString(i.Bytes()) }
+			switch key := signer.Public().(type) {
+			case *rsa.PublicKey:
+				keys = append(keys, map[string]string{"kty": "RSA", "kid": kid, "use": "sig", "n": encode(key.N), "e": encode(big.NewInt(int64(key.E)))})
+			case *ecdsa.PublicKey:
+				keys = append(keys, map[string]string{"kty": "EC", "kid": kid, "crv": "P-256", "x": encode(key.X), "y": encode(key.Y)})
+			}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+	})
+	issuer.server = httptest.NewServer(mux)
+	t.Cleanup(issuer.server.Close)
+	return issuer
+}
+
+func (i *testIssuer) addKey(t *testing.T, kid string, ec bool) {
+	var signer crypto.Signer
+	var err error
+	if ec {
+		signer, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	} else {
+		signer, err = rsa.GenerateKey(rand.Reader, 2048)
+	}
+	require.NoError(t, err)
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.keys[kid] = signer
+}
+
+// Returns a token with the claims signed by the key kid.
+func (i *testIssuer) sign(t *testing.T, kid string, claims map[string]interface{}) string {
+	i.mu.Lock()
+	signer := i.keys[kid]
+	i.mu.Unlock()
+	alg := "RS256"
+	if _, ok := signer.(*ecdsa.PrivateKey); ok {
+		alg = "ES256"
+	}
+	segment := func(v interface{}) string {
+		data, err := json.Marshal(v)
+		require.NoError(t, err)
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	payload := segment(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"}) + "." + segment(claims)
+	digest := sha256.Sum256([]byte(payload))
+	var signature []byte
+	switch key := signer.(type) {
+	case *rsa.PrivateKey:
+		var err error
+		signature, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		require.NoError(t, err)
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+		require.NoError(t, err)
+		signature = make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+	}
+	return payload + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func (i *testIssuer) claims(now time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"iss": i.server.URL,
+		"sub": "alice@example.com",
+		"aud": []string{"other", "cadvisor"},
+		"exp": now.Add(time.Hour).Unix(),
+		"nbf": now.Add(-time.Minute).Unix(),
+	}
+}
+
+func TestTokenAuthenticatorVerify(t *testing.T) {
+	issuer := newTestIssuer(t)
+	issuer.addKey(t, "rsa", false)
+	issuer.addKey(t, "ec", true)
+	a, err := NewTokenAuthenticator(TokenAuthConfig{Issuer: issuer.server.URL, Audience: "cadvisor"})
+	require.NoError(t, err)
+	now := time.Now()
+
+	for _, kid := range []string{"rsa", "ec"} {
+		subject, err := a.Verify(issuer.sign(t, kid, issuer.claims(now)), now)
+		assert.NoError(t, err, kid)
+		assert.Equal(t, "alice@example.com", subject, kid)
+	}
+
+	invalid := map[string]func(map[string]interface{}){
+		"wrong issuer":   func(c map[string]interface{}) { c["iss"] = "https://other.example.com" },
+		"wrong audience": func(c map[string]interface{}) { c["aud"] = "other" },
+		"expired":        func(c map[string]interface{}) { c["exp"] = now.Add(-2 * time.Minute).Unix() },
+		"not yet valid":  func(c map[string]interface{}) { c["nbf"] = now.Add(2 * time.Minute).Unix() },
+		"no expiration":  func(c map[string]interface{}) { delete(c, "exp") },
+	}
+	for name, modify := range invalid {
+		claims := issuer.claims(now)
+		modify(claims)
+		_, err := a.Verify(issuer.sign(t, "rsa", claims), now)
+		assert.Error(t, err, name)
+	}
+
+	// Tokens signed by other keys, tampered or unsigned are rejected.
+	token := issuer.sign(t, "rsa", issuer.claims(now))
+	other := newTestIssuer(t)
+	other.addKey(t, "rsa", false)
+	_, err = a.Verify(other.sign(t, "rsa", issuer.claims(now)), now)
+	assert.Error(t, err)
+	claims := issuer.claims(now)
+	claims["sub"] = "mallory@example.com"
+	tampered := issuer.sign(t, "rsa", claims)
+	_, err = a.Verify(tampered[:strings.LastIndex(tampered, ".")]+token[strings.LastIndex(token, "."):], now)
+	assert.Error(t, err)
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`))
+	payload, _ := json.Marshal(issuer.claims(now))
+	_, err = a.Verify(header+"."+base64.RawURLEncoding.EncodeToString(payload)+".", now)
+	assert.Error(t, err)
+	_, err = a.Verify("not a token", now)
+	assert.Error(t, err)
+}
+
+func TestTokenAuthenticatorKeyRotation(t *testing.T) {
+	issuer := newTestIssuer(t)
+	issuer.addKey(t, "old", true)
+	a, err := NewTokenAuthenticator(TokenAuthConfig{JWKSURL: issuer.server.URL + "/keys", Audience: "cadvisor"})
+	require.NoError(t, err)
+
+	// The keys are only fetched again for unknown keys once they were not
+	// fetched for a while.
+	issuer.addKey(t, "new", true)
+	now := time.Now()
+	_, err = a.Verify(issuer.sign(t, "new", issuer.claims(now)), now)
+	assert.Error(t, err)
+	now = now.Add(2 * keysMinRefreshInterval)
+	subject, err := a.Verify(issuer.sign(t, "new", issuer.claims(now)), now)
+	assert.NoError(t, err)
+	assert.Equal(t, "alice@example.com", subject)
+
+	// The issuer is not checked without the issuer set.
+	claims := issuer.claims(now)
+	claims["iss"] = "https://other.example.com"
+	_, err = a.Verify(issuer.sign(t, "old", claims), now)
+	assert.NoError(t, err)
+}
+
+func TestNewTokenAuthenticatorInvalidConfig(t *testing.T) {
+	_, err := NewTokenAuthenticator(TokenAuthConfig{Audience: "cadvisor"})
+	assert.Error(t, err)
+	_, err = NewTokenAuthenticator(TokenAuthConfig{Issuer: "https://accounts.example.com"})
+	assert.Error(t, err)
+}
+
+func TestTokenAuthHandler(t *testing.T) {
+	issuer := newTestIssuer(t)
+	issuer.addKey(t, "rsa", false)
+	a, err := NewTokenAuthenticator(TokenAuthConfig{Issuer: issuer.server.URL, Audience: "cadvisor"})
+	require.NoError(t, err)
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, _ := identity.FromRequest(r)
+		_, _ = io.WriteString(w, id)
+	})
+	handler := TokenAuthHandler(h, CORSConfig{}, a)
+
+	serve := func(method, path, authorization string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, nil)
+		if authorization != "" {
+			r.Header.Set("Authorization", authorization)
+		}
+		if method == http.MethodOptions {
+			r.Header.Set("Origin", "https://dashboard.example.com")
+			r.Header.Set("Access-Control-Request-Method", http.MethodGet)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	w := serve(http.MethodGet, "/api/v2.0/machine", "Bearer "+issuer.sign(t, "rsa", issuer.claims(time.Now())))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "alice@example.com", w.Body.String())
+
+	w = serve(http.MethodGet, "/api/v2.0/machine", "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "Bearer", w.Header().Get("WWW-Authenticate"))
+	w = serve(http.MethodGet, "/api/v2.0/machine", "Bearer invalid")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, `Bearer error="invalid_token"`, w.Header().Get("WWW-Authenticate"))
+	w = serve(http.MethodGet, "/api/v2.0/machine", "Basic YWxpY2U6c2VjcmV0")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// Health checks do not need a token, paths merely ending like them do.
+	for _, path := range []string{"/healthz", "/livez", "/readyz"} {
+		assert.Equal(t, http.StatusOK, serve(http.MethodGet, path, "").Code, path)
+	}
+	// Their client is only authenticated by an accepted token.
+	w = serve(http.MethodGet, "/healthz", "Bearer "+issuer.sign(t, "rsa", issuer.claims(time.Now())))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "alice@example.com", w.Body.String())
+	w = serve(http.MethodGet, "/healthz", "Bearer invalid")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Body.String())
+	assert.Equal(t, http.StatusUnauthorized, serve(http.MethodGet, "/api/v2.0/machine/healthz", "").Code)
+
+	// CORS preflight requests need a token unless CORS is enabled.
+	assert.Equal(t, http.StatusUnauthorized, serve(http.MethodOptions, "/api/v2.0/machine", "").Code)
+	assert.Equal(t, http.StatusUnauthorized, serve(http.MethodOptions, "/metrics", "").Code)
+	handler = TokenAuthHandler(h, CORSConfig{
+		AllowedOrigins: []string{"https://dashboard.example.com"},
+		AllowedMethods: []string{http.MethodGet},
+	}, a)
+	for _, path := range []string{"/api/v2.0/machine", "/metrics"} {
+		w = serve(http.MethodOptions, path, "")
+		assert.Equal(t, http.StatusNoContent, w.Code, path)
+		assert.Empty(t, w.Body.String(), path)
+		assert.Equal(t, "https://dashboard.example.com", w.Header().Get("Access-Control-Allow-Origin"), path)
+	}
+	// Preflight requests of other origins are answered without calling h.
+	r := httptest.NewRequest(http.MethodOptions, "/metrics", nil)
+	r.Header.Set("Origin", "https://evil.example.com")
+	r.Header.Set("Access-Control-Request-Method", http.MethodGet)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Empty(t, w.Body.String())
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+}
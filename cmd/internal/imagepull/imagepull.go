@@ -0,0 +1,124 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package imagepull follows the event streams of the container runtimes and
+// adds an image pull event to the manager for each image pulled.
+package imagepull
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/yidoyoon/cadvisor-lite/container/containerd"
+	"github.com/yidoyoon/cadvisor-lite/container/docker"
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+	"github.com/yidoyoon/cadvisor-lite/manager"
+
+	"k8s.io/klog/v2"
+)
+
+// Delays between the attempts to follow the events of a runtime again after
+// a failure.
+const (
+	minRetryDelay = time.Second
+	maxRetryDelay = time.Minute
+)
+
+// watchFunc calls f for each image pulled by a runtime, until ctx is done or
+// the connection to the runtime fails.
+type watchFunc func(ctx context.Context, f func(image string, timestamp time.Time)) error
+
+var watchFuncs = map[string]watchFunc{
+	"docker":     docker.WatchImagePulls,
+	"containerd": containerd.WatchImagePulls,
+}
+
+// Watcher adds the image pull events of a set of runtimes.
+type Watcher struct {
+	manager manager.Manager
+	watches map[string]watchFunc
+	cancel  context.CancelFunc
+}
+
+// NewWatcher returns a watcher of the pulls of the given runtimes, among
+// docker and containerd.
+func NewWatcher(m manager.Manager, runtimes []string) (*Watcher, error) {
+	w := &Watcher{manager: m, watches: make(map[string]watchFunc, len(runtimes))}
+	for _, runtime := range runtimes {
+		watch, ok := watchFuncs[runtime]
+		if !ok {
+			return nil, fmt.Errorf("image pulls of runtime %q cannot be watched", runtime)
+		}
+		w.watches[runtime] = watch
+	}
+	return w, nil
+}
+
+// Start follows the events of the runtimes in the background, again after
+// failures, e.g. while a runtime restarts.
+func (w *Watcher) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+	for runtime, watch := range w.watches {
+		go w.follow(ctx, runtime, watch)
+	}
+}
+
+// Stop stops following the events.
+func (w *Watcher) Stop() {
+	if w.cancel != nil {
+		w.cancel()
+	}
+}
+
+func (w *Watcher) follow(ctx context.Context, runtime string, watch watchFunc) {
+	delay := minRetryDelay
+	for {
+		err := watch(ctx, func(image string, timestamp time.Time) {
+			delay = minRetryDelay
+			w.addEvent(runtime, image, timestamp)
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		klog.V(2).Infof("Failed to follow the image pulls of %s, retrying in %v: %v", runtime, delay, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > maxRetryDelay {
+			delay = maxRetryDelay
+		}
+	}
+}
+
+func (w *Watcher) addEvent(runtime, image string, timestamp time.Time) {
+	klog.V(1).Infof("Image %q pulled by %s", image, runtime)
+	err := w.manager.AddEvent(&info.Event{
+		ContainerName: "/",
+		Timestamp:     timestamp,
+		EventType:     info.EventImagePull,
+		EventData: info.EventData{
+			ImagePull: &info.ImagePullEventData{
+				Runtime: runtime,
+				Image:   image,
+			},
+		},
+	})
+	if err != nil {
+		klog.Errorf("Failed to add image pull event: %v", err)
+	}
+}
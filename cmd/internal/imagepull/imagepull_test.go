@@ -0,0 +1,74 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package imagepull
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+	"github.com/yidoyoon/cadvisor-lite/manager"
+)
+
+type fakeManager struct {
+	manager.Manager
+	events chan *info.Event
+}
+
+func (m *fakeManager) AddEvent(event *info.Event) error {
+	m.events <- event
+	return nil
+}
+
+func TestWatcher(t *testing.T) {
+	pulled := time.Unix(100, 0)
+	m := &fakeManager{events: make(chan *info.Event)}
+	w := &Watcher{manager: m, watches: map[string]watchFunc{
+		"containerd": func(ctx context.Context, f func(string, time.Time)) error {
+			f("docker.io/library/nginx:latest", pulled)
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}}
+	w.Start()
+	defer w.Stop()
+
+	select {
+	case event := <-m.events:
+		assert.Equal(t, &info.Event{
+			ContainerName: "/",
+			Timestamp:     pulled,
+			EventType:     info.EventImagePull,
+			EventData: info.EventData{
+				ImagePull: &info.ImagePullEventData{Runtime: "containerd", Image: "docker.io/library/nginx:latest"},
+			},
+		}, event)
+	case <-time.After(10 * time.Second):
+		t.Fatal("no image pull event added")
+	}
+}
+
+func TestNewWatcher(t *testing.T) {
+	w, err := NewWatcher(&fakeManager{}, []string{"docker", "containerd"})
+	require.NoError(t, err)
+	assert.Len(t, w.watches, 2)
+
+	_, err = NewWatcher(&fakeManager{}, []string{"podman"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,160 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package standby coordinates cAdvisor instances on the same host through an
+// exclusive file lock, so that only the instance holding the lock exports and
+// serves stats while the others collect them as hot standbys.
+package standby
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+	"github.com/yidoyoon/cadvisor-lite/storage"
+
+	"k8s.io/klog/v2"
+)
+
+// Lock is an exclusive lock on a file shared by the instances on a host. The
+// lock is released by Release or when the process exits.
+type Lock struct {
+	file *os.File
+}
+
+func openLockFile(path string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file %q: %v", path, err)
+	}
+	return f, nil
+}
+
+// TryAcquire takes the lock at path without blocking. It returns nil if the
+// lock is held by another instance.
+func TryAcquire(path string) (*Lock, error) {
+	f, err := openLockFile(path)
+	if err != nil {
+		return nil, err
+	}
+	err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		f.Close()
+		return nil, nil
+	}
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to lock %q: %v", path, err)
+	}
+	return newLock(f), nil
+}
+
+// Acquire takes the lock at path, blocking while another instance holds it.
+func Acquire(path string) (*Lock, error) {
+	lock, err := TryAcquire(path)
+	if err != nil || lock != nil {
+		return lock, err
+	}
+
+	klog.Infof("Lock %q is held by %s, standing by", path, lockHolder(path))
+	f, err := openLockFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to lock %q: %v", path, err)
+	}
+	klog.Infof("Acquired lock %q, leaving standby", path)
+	return newLock(f), nil
+}
+
+// Records the pid of the holder in the lock file, for diagnostics only.
+func newLock(f *os.File) *Lock {
+	if err := f.Truncate(0); err == nil {
+		_, _ = f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	}
+	return &Lock{file: f}
+}
+
+func lockHolder(path string) string {
+	out, err := os.ReadFile(path)
+	pid := strings.TrimSpace(string(out))
+	if err != nil || pid == "" {
+		return "another instance"
+	}
+	return "pid " + pid
+}
+
+// Release releases the lock, letting a standby instance take over.
+func (l *Lock) Release() error {
+	if err := syscall.Flock(int(l.file.Fd()), syscall.LOCK_UN); err != nil {
+		l.file.Close()
+		return err
+	}
+	return l.file.Close()
+}
+
+// Locks taken by AcquireInBackground, held until the process exits. They are
+// kept reachable since the finalizer of their file would release them.
+var (
+	heldLock sync.Mutex
+	held     []*Lock
+)
+
+// AcquireInBackground takes the lock at path in the background, so that the
+// instance collects stats while standing by. The returned channel is closed
+// once the lock is held, which is then held until the process exits, and
+// errors are sent to errs.
+func AcquireInBackground(path string, errs chan<- error) <-chan struct{} {
+	acquired := make(chan struct{})
+	go func() {
+		lock, err := Acquire(path)
+		if err != nil {
+			errs <- err
+			return
+		}
+		heldLock.Lock()
+		held = append(held, lock)
+		heldLock.Unlock()
+		close(acquired)
+	}()
+	return acquired
+}
+
+type storageDriver struct {
+	storage.StorageDriver
+	active <-chan struct{}
+}
+
+// NewStorageDriver returns a StorageDriver dropping the stats while the
+// instance stands by, i.e. until active is closed, and passing them to driver
+// afterwards.
+func NewStorageDriver(driver storage.StorageDriver, active <-chan struct{}) storage.StorageDriver {
+	return &storageDriver{StorageDriver: driver, active: active}
+}
+
+func (d *storageDriver) AddStats(cInfo *info.ContainerInfo, stats *info.ContainerStats) error {
+	select {
+	case <-d.active:
+		return d.StorageDriver.AddStats(cInfo, stats)
+	default:
+		return nil
+	}
+}
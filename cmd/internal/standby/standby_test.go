@@ -0,0 +1,134 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package standby
+
+import (
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+)
+
+func TestTryAcquire(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cadvisor.lock")
+
+	active, err := TryAcquire(path)
+	require.NoError(t, err)
+	require.NotNil(t, active)
+
+	standby, err := TryAcquire(path)
+	require.NoError(t, err)
+	assert.Nil(t, standby)
+
+	require.NoError(t, active.Release())
+	standby, err = TryAcquire(path)
+	require.NoError(t, err)
+	require.NotNil(t, standby)
+	assert.NoError(t, standby.Release())
+}
+
+func TestAcquireWaitsForRelease(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cadvisor.lock")
+
+	active, err := Acquire(path)
+	require.NoError(t, err)
+
+	acquired := make(chan *Lock)
+	go func() {
+		lock, err := Acquire(path)
+		assert.NoError(t, err)
+		acquired <- lock
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("standby acquired a held lock")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	require.NoError(t, active.Release())
+	select {
+	case lock := <-acquired:
+		require.NotNil(t, lock)
+		assert.NoError(t, lock.Release())
+	case <-time.After(5 * time.Second):
+		t.Fatal("standby did not take over a released lock")
+	}
+}
+
+type countingDriver struct {
+	added int
+}
+
+func (d *countingDriver) AddStats(*info.ContainerInfo, *info.ContainerStats) error {
+	d.added++
+	return nil
+}
+
+func (d *countingDriver) Close() error {
+	return nil
+}
+
+func TestStorageDriverWaitsForLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cadvisor.lock")
+	active, err := Acquire(path)
+	require.NoError(t, err)
+
+	errs := make(chan error, 1)
+	acquired := AcquireInBackground(path, errs)
+	backend := &countingDriver{}
+	driver := NewStorageDriver(backend, acquired)
+
+	// The stats are dropped while standing by.
+	require.NoError(t, driver.AddStats(&info.ContainerInfo{}, &info.ContainerStats{}))
+	assert.Equal(t, 0, backend.added)
+
+	require.NoError(t, active.Release())
+	select {
+	case <-acquired:
+	case err := <-errs:
+		t.Fatal(err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("standby did not take over a released lock")
+	}
+	require.NoError(t, driver.AddStats(&info.ContainerInfo{}, &info.ContainerStats{}))
+	assert.Equal(t, 1, backend.added)
+}
+
+func TestAcquireInBackgroundKeepsLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cadvisor.lock")
+	errs := make(chan error, 1)
+	select {
+	case <-AcquireInBackground(path, errs):
+	case err := <-errs:
+		t.Fatal(err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("lock was not acquired")
+	}
+
+	// The lock is not released by the finalizer of its file.
+	for i := 0; i < 3; i++ {
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+	}
+	lock, err := TryAcquire(path)
+	require.NoError(t, err)
+	assert.Nil(t, lock)
+}
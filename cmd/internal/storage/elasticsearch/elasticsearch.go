@@ -21,6 +21,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/yidoyoon/cadvisor-lite/cmd/internal/storage/httpclient"
 	info "github.com/yidoyoon/cadvisor-lite/info/v1"
 	storage "github.com/yidoyoon/cadvisor-lite/storage"
 
@@ -125,10 +126,15 @@ func newStorage(
 	elasticHost string,
 	enableSniffer bool,
 ) (storage.StorageDriver, error) {
+	httpClient, err := httpclient.New(httpclient.ConfigFromFlags())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the elasticsearch HTTP client - %s", err)
+	}
 	// Obtain a client and connect to the default Elasticsearch installation
 	// on 127.0.0.1:9200. Of course you can configure your client to connect
 	// to other hosts and configure it in various other ways.
 	client, err := elastic.NewClient(
+		elastic.SetHttpClient(httpClient),
 		elastic.SetHealthcheck(true),
 		elastic.SetSniff(enableSniffer),
 		elastic.SetHealthcheckInterval(30*time.Second),
@@ -0,0 +1,193 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package httpclient builds the HTTP client of the elasticsearch storage
+// driver, with the TLS, authentication and proxy settings of the flags. The
+// influxdb client does not accept an HTTP client, so it ignores them.
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	caFile             = flag.String("storage_driver_http_tls_ca", "", "path to the CA certificates verifying the endpoint of the elasticsearch storage driver, instead of the system ones")
+	certFile           = flag.String("storage_driver_http_tls_cert", "", "path to the client certificate presented to the endpoint of the elasticsearch storage driver. Requires --storage_driver_http_tls_key.")
+	keyFile            = flag.String("storage_driver_http_tls_key", "", "path to the key of --storage_driver_http_tls_cert")
+	insecureSkipVerify = flag.Bool("storage_driver_http_tls_insecure_skip_verify", false, "do not verify the certificate of the endpoint of the elasticsearch storage driver")
+	bearerTokenFile    = flag.String("storage_driver_http_bearer_token_file", "", "path to a bearer token sent to the endpoint of the elasticsearch storage driver. The file is read again every minute, so that the token can be rotated.")
+	basicAuthUser      = flag.String("storage_driver_http_basic_auth_user", "", "user authenticated with basic auth by the endpoint of the elasticsearch storage driver. The influxdb driver authenticates with --storage_driver_user instead.")
+	basicAuthPassword  = flag.String("storage_driver_http_basic_auth_password_file", "", "path to the password of --storage_driver_http_basic_auth_user")
+	proxyURL           = flag.String("storage_driver_http_proxy", "", "URL of the proxy to the endpoint of the elasticsearch storage driver. Empty value uses the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables, which the other drivers always use.")
+)
+
+// Interval between the reads of the bearer token file.
+const tokenRefreshInterval = time.Minute
+
+// Config of the HTTP clients. Zero values are the defaults of net/http.
+type Config struct {
+	CAFile             string
+	CertFile           string
+	KeyFile            string
+	InsecureSkipVerify bool
+	BearerTokenFile    string
+	Username           string
+	PasswordFile       string
+	ProxyURL           string
+}
+
+// ConfigFromFlags returns the config set by the storage_driver_http_* flags.
+func ConfigFromFlags() Config {
+	return Config{
+		CAFile:             *caFile,
+		CertFile:           *certFile,
+		KeyFile:            *keyFile,
+		InsecureSkipVerify: *insecureSkipVerify,
+		BearerTokenFile:    *bearerTokenFile,
+		Username:           *basicAuthUser,
+		PasswordFile:       *basicAuthPassword,
+		ProxyURL:           *proxyURL,
+	}
+}
+
+// New returns a HTTP client using config.
+func New(config Config) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	tlsConfig, err := tlsConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	transport.TLSClientConfig = tlsConfig
+	if config.ProxyURL != "" {
+		proxy, err := url.Parse(config.ProxyURL)
+		if err != nil || proxy.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL %q", config.ProxyURL)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	var roundTripper http.RoundTripper = transport
+	switch {
+	case config.BearerTokenFile != "" && config.Username != "":
+		return nil, fmt.Errorf("bearer token and basic auth are mutually exclusive")
+	case config.BearerTokenFile != "":
+		auth := &authTransport{base: transport, tokenFile: config.BearerTokenFile}
+		if _, err := auth.token(time.Now()); err != nil {
+			return nil, err
+		}
+		roundTripper = auth
+	case config.Username != "":
+		var password string
+		if config.PasswordFile != "" {
+			p, err := readSecret(config.PasswordFile)
+			if err != nil {
+				return nil, err
+			}
+			password = p
+		}
+		roundTripper = &authTransport{base: transport, username: config.Username, password: password}
+	}
+	return &http.Client{Transport: roundTripper}, nil
+}
+
+func tlsConfig(config Config) (*tls.Config, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: config.InsecureSkipVerify}
+	if config.CAFile != "" {
+		ca, err := os.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the CA certificates: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no CA certificate found in %q", config.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if (config.CertFile == "") != (config.KeyFile == "") {
+		return nil, fmt.Errorf("the client certificate and its key must be set together")
+	}
+	if config.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+func readSecret(path string) (string, error) {
+	secret, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(secret)), nil
+}
+
+// authTransport authenticates the requests with a bearer token read from a
+// file, or with basic auth.
+type authTransport struct {
+	base     http.RoundTripper
+	username string
+	password string
+
+	tokenFile string
+	lock      sync.Mutex
+	// Last token read, and when.
+	lastToken string
+	lastRead  time.Time
+}
+
+// token returns the bearer token, reading the file again every
+// tokenRefreshInterval. The last token is kept if the file cannot be read.
+func (t *authTransport) token(now time.Time) (string, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if now.Sub(t.lastRead) < tokenRefreshInterval {
+		return t.lastToken, nil
+	}
+	token, err := readSecret(t.tokenFile)
+	if err != nil {
+		if t.lastToken != "" {
+			return t.lastToken, nil
+		}
+		return "", fmt.Errorf("failed to read the bearer token: %v", err)
+	}
+	t.lastToken, t.lastRead = token, now
+	return token, nil
+}
+
+func (t *authTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	// Requests must not be modified by round trippers.
+	r = r.Clone(r.Context())
+	if t.tokenFile != "" {
+		token, err := t.token(time.Now())
+		if err != nil {
+			return nil, err
+		}
+		r.Header.Set("Authorization", "Bearer "+token)
+	} else {
+		r.SetBasicAuth(t.username, t.password)
+	}
+	return t.base.RoundTrip(r)
+}
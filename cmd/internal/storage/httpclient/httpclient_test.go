@@ -0,0 +1,110 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpclient
+
+import (
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+// Returns a TLS server replying with the Authorization header of the
+// requests, and the path of its CA certificate.
+func newServer(t *testing.T) (*httptest.Server, string) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Header.Get("Authorization")))
+	}))
+	t.Cleanup(server.Close)
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	return server, writeFile(t, "ca.pem", string(ca))
+}
+
+func get(t *testing.T, client *http.Client, url string) string {
+	resp, err := client.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestNewBearerToken(t *testing.T) {
+	server, ca := newServer(t)
+	client, err := New(Config{CAFile: ca, BearerTokenFile: writeFile(t, "token", "secret\n")})
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer secret", get(t, client, server.URL))
+
+	// The server is not trusted without its CA.
+	client, err = New(Config{})
+	require.NoError(t, err)
+	_, err = client.Get(server.URL)
+	assert.Error(t, err)
+}
+
+func TestNewBasicAuth(t *testing.T) {
+	server, _ := newServer(t)
+	client, err := New(Config{InsecureSkipVerify: true, Username: "cadvisor", PasswordFile: writeFile(t, "password", "pass")})
+	require.NoError(t, err)
+	// base64("cadvisor:pass")
+	assert.Equal(t, "Basic Y2Fkdmlzb3I6cGFzcw==", get(t, client, server.URL))
+}
+
+func TestNewInvalidConfig(t *testing.T) {
+	for _, config := range []Config{
+		{CAFile: "/missing/ca.pem"},
+		{CertFile: "cert.pem"},
+		{BearerTokenFile: "/missing/token"},
+		{BearerTokenFile: "token", Username: "cadvisor"},
+		{ProxyURL: "proxy:3128"},
+	} {
+		_, err := New(config)
+		assert.Error(t, err, "%+v", config)
+	}
+}
+
+func TestTokenRefresh(t *testing.T) {
+	path := writeFile(t, "token", "first")
+	transport := &authTransport{tokenFile: path}
+	now := time.Now()
+	token, err := transport.token(now)
+	require.NoError(t, err)
+	assert.Equal(t, "first", token)
+
+	require.NoError(t, os.WriteFile(path, []byte("second"), 0600))
+	token, _ = transport.token(now.Add(time.Second))
+	assert.Equal(t, "first", token)
+	token, _ = transport.token(now.Add(tokenRefreshInterval))
+	assert.Equal(t, "second", token)
+
+	// The last token is kept while the file is missing.
+	require.NoError(t, os.Remove(path))
+	token, err = transport.token(now.Add(2 * tokenRefreshInterval))
+	require.NoError(t, err)
+	assert.Equal(t, "second", token)
+}
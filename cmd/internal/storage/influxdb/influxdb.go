@@ -27,6 +27,7 @@ import (
 	"github.com/yidoyoon/cadvisor-lite/version"
 
 	influxdb "github.com/influxdb/influxdb/client"
+	"k8s.io/utils/clock"
 )
 
 func init() {
@@ -41,10 +42,16 @@ type influxdbStorage struct {
 	database        string
 	retentionPolicy string
 	bufferDuration  time.Duration
-	lastWrite       time.Time
-	points          []*influxdb.Point
-	lock            sync.Mutex
-	readyToFlush    func() bool
+	// Clock of the buffering of the points, fake in tests.
+	clock        clock.PassiveClock
+	lastWrite    time.Time
+	points       []*influxdb.Point
+	lock         sync.Mutex
+	readyToFlush func() bool
+	// Whether the last write succeeded, and the time of the last successful
+	// one.
+	writeFailed bool
+	lastFlush   time.Time
 }
 
 // Series names
@@ -96,6 +103,10 @@ const (
 	serPerfStat = "perf_stat"
 	// Referenced memory
 	serReferencedMemory = "referenced_memory"
+	// Sequence number of the sample
+	serSequence = "sequence"
+	// Time between the timestamp of the sample and its storage, in nanoseconds
+	serTimestampSkew = "timestamp_skew"
 	// Resctrl - Total memory bandwidth
 	serResctrlMemoryBandwidthTotal = "resctrl_memory_bandwidth_total"
 	// Resctrl - Local memory bandwidth
@@ -233,6 +244,10 @@ func (s *influxdbStorage) containerStatsToPoints(
 	// Referenced Memory
 	points = append(points, makePoint(serReferencedMemory, stats.ReferencedMemory))
 
+	// Sequence number and timestamp skew
+	points = append(points, makePoint(serSequence, stats.Sequence))
+	points = append(points, makePoint(serTimestampSkew, stats.TimestampSkew.Nanoseconds()))
+
 	s.tagPoints(cInfo, stats, points)
 
 	return points
@@ -380,7 +395,7 @@ func (s *influxdbStorage) OverrideReadyToFlush(readyToFlush func() bool) {
 }
 
 func (s *influxdbStorage) defaultReadyToFlush() bool {
-	return time.Since(s.lastWrite) >= s.bufferDuration
+	return s.clock.Since(s.lastWrite) >= s.bufferDuration
 }
 
 func (s *influxdbStorage) AddStats(cInfo *info.ContainerInfo, stats *info.ContainerStats) error {
@@ -402,7 +417,7 @@ func (s *influxdbStorage) AddStats(cInfo *info.ContainerInfo, stats *info.Contai
 		if s.readyToFlush() {
 			pointsToFlush = s.points
 			s.points = make([]*influxdb.Point, 0)
-			s.lastWrite = time.Now()
+			s.lastWrite = s.clock.Now()
 		}
 	}()
 	if len(pointsToFlush) > 0 {
@@ -420,13 +435,42 @@ func (s *influxdbStorage) AddStats(cInfo *info.ContainerInfo, stats *info.Contai
 			Time:            stats.Timestamp,
 		}
 		response, err := s.client.Write(bp)
-		if err != nil || checkResponseForErrors(response) != nil {
+		if err == nil {
+			err = checkResponseForErrors(response)
+		}
+		s.lock.Lock()
+		s.writeFailed = err != nil
+		if err == nil {
+			s.lastFlush = s.clock.Now()
+		}
+		s.lock.Unlock()
+		if err != nil {
 			return fmt.Errorf("failed to write stats to influxDb - %s", err)
 		}
 	}
 	return nil
 }
 
+// Connected returns whether the last write to InfluxDB succeeded.
+func (s *influxdbStorage) Connected() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return !s.writeFailed
+}
+
+// QueueDepth returns the number of points waiting for the next write.
+func (s *influxdbStorage) QueueDepth() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return len(s.points)
+}
+
+func (s *influxdbStorage) LastFlush() time.Time {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.lastFlush
+}
+
 func (s *influxdbStorage) Close() error {
 	s.client = nil
 	return nil
@@ -471,9 +515,10 @@ func newStorage(
 		database:        database,
 		retentionPolicy: retentionPolicy,
 		bufferDuration:  bufferDuration,
-		lastWrite:       time.Now(),
+		clock:           clock.RealClock{},
 		points:          make([]*influxdb.Point, 0),
 	}
+	ret.lastWrite = ret.clock.Now()
 	ret.readyToFlush = ret.defaultReadyToFlush
 	return ret, nil
 }
@@ -34,6 +34,7 @@ import (
 	influxdb "github.com/influxdb/influxdb/client"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	testingclock "k8s.io/utils/clock/testing"
 )
 
 // The duration in seconds for which stats will be buffered in the influxdb driver.
@@ -280,6 +281,10 @@ func TestContainerStatsToPoints(t *testing.T) {
 	// Reference memory
 	assertContainsPointWithValue(t, points, serReferencedMemory, stats.ReferencedMemory)
 
+	// Sequence number and timestamp skew
+	assertContainsPointWithValue(t, points, serSequence, stats.Sequence)
+	assertContainsPointWithValue(t, points, serTimestampSkew, stats.TimestampSkew.Nanoseconds())
+
 	// Resource Control stats - memory bandwidth
 	for _, rdtMemoryBandwidth := range stats.Resctrl.MemoryBandwidth {
 		assertContainsPointWithValue(t, points, serResctrlMemoryBandwidthTotal, rdtMemoryBandwidth.TotalBytes)
@@ -303,6 +308,30 @@ func assertContainsPointWithValue(t *testing.T, points []*influxdb.Point, name s
 	return assert.True(t, found, "no point found with name='%v' and value=%v", name, value)
 }
 
+func TestBufferDuration(t *testing.T) {
+	storage, err := createTestStorage()
+	require.NoError(t, err)
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	storage.clock = fakeClock
+	storage.lastWrite = fakeClock.Now()
+	cInfo, stats := createTestStats()
+
+	// The points are buffered until the buffer duration is over.
+	require.NoError(t, storage.AddStats(cInfo, stats))
+	depth := storage.QueueDepth()
+	assert.NotZero(t, depth)
+	fakeClock.Step(time.Minute)
+	require.NoError(t, storage.AddStats(cInfo, stats))
+	assert.Equal(t, 2*depth, storage.QueueDepth())
+
+	// All of them are written at once afterwards, here to an unreachable
+	// server.
+	fakeClock.Step(time.Minute)
+	assert.Error(t, storage.AddStats(cInfo, stats))
+	assert.Zero(t, storage.QueueDepth())
+	assert.Equal(t, fakeClock.Now(), storage.lastWrite)
+}
+
 func createTestStorage() (*influxdbStorage, error) {
 	machineName := "testMachine"
 	table := "cadvisor_table"
@@ -362,6 +391,8 @@ func createTestStats() (*info.ContainerInfo, *info.ContainerStats) {
 			"2GB": {Usage: 9876, MaxUsage: 5432, Failcnt: 1},
 		},
 		ReferencedMemory: 12345,
+		Sequence:         42,
+		TimestampSkew:    3 * time.Millisecond,
 		PerfStats:        []info.PerfStat{{Cpu: 1, PerfValue: info.PerfValue{Name: "cycles", ScalingRatio: 1.5, Value: 4589}}},
 		Resctrl: info.ResctrlStats{
 			MemoryBandwidth: []info.MemoryBandwidthStats{
@@ -57,6 +57,7 @@ type detailSpec struct {
 	MachineName     string               `json:"machine_name,omitempty"`
 	ContainerName   string               `json:"container_Name,omitempty"`
 	ContainerID     string               `json:"container_Id,omitempty"`
+	ContainerUID    string               `json:"container_uid,omitempty"`
 	ContainerLabels map[string]string    `json:"container_labels,omitempty"`
 	ContainerStats  *info.ContainerStats `json:"container_stats,omitempty"`
 }
@@ -72,6 +73,7 @@ func (s *kafkaStorage) infoToDetailSpec(cInfo *info.ContainerInfo, stats *info.C
 		MachineName:     s.machineName,
 		ContainerName:   containerName,
 		ContainerID:     containerID,
+		ContainerUID:    cInfo.Spec.Uid,
 		ContainerLabels: containerLabels,
 		ContainerStats:  stats,
 	}
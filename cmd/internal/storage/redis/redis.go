@@ -24,6 +24,7 @@ import (
 	storage "github.com/yidoyoon/cadvisor-lite/storage"
 
 	redis "github.com/gomodule/redigo/redis"
+	"k8s.io/utils/clock"
 )
 
 func init() {
@@ -35,6 +36,7 @@ type redisStorage struct {
 	machineName    string
 	redisKey       string
 	bufferDuration time.Duration
+	clock          clock.PassiveClock
 	lastWrite      time.Time
 	lock           sync.Mutex
 	readyToFlush   func() bool
@@ -61,7 +63,7 @@ func new() (storage.StorageDriver, error) {
 }
 
 func (s *redisStorage) defaultReadyToFlush() bool {
-	return time.Since(s.lastWrite) >= s.bufferDuration
+	return s.clock.Since(s.lastWrite) >= s.bufferDuration
 }
 
 // We must add some default params (for example: MachineName,ContainerName...)because containerStats do not include them
@@ -98,7 +100,7 @@ func (s *redisStorage) AddStats(cInfo *info.ContainerInfo, stats *info.Container
 		b, _ := json.Marshal(detail)
 		if s.readyToFlush() {
 			seriesToFlush = b
-			s.lastWrite = time.Now()
+			s.lastWrite = s.clock.Now()
 		}
 	}()
 	if len(seriesToFlush) == 0 {
@@ -132,8 +134,9 @@ func newStorage(
 		machineName:    machineName,
 		redisKey:       redisKey,
 		bufferDuration: bufferDuration,
-		lastWrite:      time.Now(),
+		clock:          clock.RealClock{},
 	}
+	ret.lastWrite = ret.clock.Now()
 	ret.readyToFlush = ret.defaultReadyToFlush
 	return ret, nil
 }
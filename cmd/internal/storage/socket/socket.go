@@ -0,0 +1,172 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package socket is a storage driver writing the stats as JSON Lines to a TCP
+// or unix socket, for consumers such as Vector or Fluent Bit.
+package socket
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+	"github.com/yidoyoon/cadvisor-lite/storage"
+	"github.com/yidoyoon/cadvisor-lite/utils/container"
+
+	"k8s.io/klog/v2"
+)
+
+func init() {
+	storage.RegisterStorageDriver("socket", new)
+}
+
+var (
+	network      = flag.String("storage_driver_socket_network", "tcp", "network of the socket the socket storage driver writes to: tcp, udp, unix or unixgram. Its address is set by --storage_driver_host.")
+	writeTimeout = flag.Duration("storage_driver_socket_write_timeout", time.Second, "timeout of the writes of the socket storage driver, after which the sample is dropped and the connection reopened")
+)
+
+// Minimum interval between connection attempts, so that the housekeepings do
+// not all wait for a consumer which is down.
+const redialInterval = 5 * time.Second
+
+// sample is a line written to the socket.
+type sample struct {
+	Timestamp       time.Time            `json:"timestamp"`
+	MachineName     string               `json:"machine_name,omitempty"`
+	ContainerName   string               `json:"container_name"`
+	ContainerID     string               `json:"container_id,omitempty"`
+	ContainerUID    string               `json:"container_uid,omitempty"`
+	ContainerLabels map[string]string    `json:"container_labels,omitempty"`
+	ContainerStats  *info.ContainerStats `json:"container_stats"`
+}
+
+type socketStorage struct {
+	network     string
+	address     string
+	timeout     time.Duration
+	machineName string
+
+	lock sync.Mutex
+	// Nil until the first sample and after a failed write.
+	conn net.Conn
+	// Time of the last failed connection attempt.
+	lastDialFailure time.Time
+	// Time of the last successful write.
+	lastWrite time.Time
+}
+
+func new() (storage.StorageDriver, error) {
+	machineName, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+	return newStorage(*network, *storage.ArgDbHost, *writeTimeout, machineName)
+}
+
+func newStorage(network, address string, timeout time.Duration, machineName string) (*socketStorage, error) {
+	switch network {
+	case "tcp", "udp", "unix", "unixgram":
+	default:
+		return nil, fmt.Errorf("unsupported socket network %q", network)
+	}
+	return &socketStorage{
+		network:     network,
+		address:     address,
+		timeout:     timeout,
+		machineName: machineName,
+	}, nil
+}
+
+// AddStats writes the sample as a line. The socket is (re)connected on demand,
+// so that the consumer may start after cAdvisor or restart.
+func (s *socketStorage) AddStats(cInfo *info.ContainerInfo, stats *info.ContainerStats) error {
+	if stats == nil {
+		return nil
+	}
+	line, err := json.Marshal(&sample{
+		Timestamp:       stats.Timestamp,
+		MachineName:     s.machineName,
+		ContainerName:   container.GetPreferredName(cInfo.ContainerReference),
+		ContainerID:     cInfo.ContainerReference.Id,
+		ContainerUID:    cInfo.Spec.Uid,
+		ContainerLabels: cInfo.Spec.Labels,
+		ContainerStats:  stats,
+	})
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.conn == nil {
+		if time.Since(s.lastDialFailure) < redialInterval {
+			return fmt.Errorf("not connected to %s socket %q", s.network, s.address)
+		}
+		conn, err := net.DialTimeout(s.network, s.address, s.timeout)
+		if err != nil {
+			s.lastDialFailure = time.Now()
+			return fmt.Errorf("failed to connect to %s socket %q: %v", s.network, s.address, err)
+		}
+		klog.V(2).Infof("Connected to %s socket %q", s.network, s.address)
+		s.conn = conn
+	}
+	if err := s.conn.SetWriteDeadline(time.Now().Add(s.timeout)); err != nil {
+		return err
+	}
+	if _, err := s.conn.Write(line); err != nil {
+		// A partial line may have been written, the connection is
+		// reopened so that the next one starts on a new connection.
+		s.conn.Close()
+		s.conn = nil
+		return fmt.Errorf("failed to write to %s socket %q: %v", s.network, s.address, err)
+	}
+	s.lastWrite = time.Now()
+	return nil
+}
+
+// Connected returns whether the socket is open. It is closed until the first
+// sample and after a failed write.
+func (s *socketStorage) Connected() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.conn != nil
+}
+
+// QueueDepth returns 0, the samples are written as they are added.
+func (s *socketStorage) QueueDepth() int {
+	return 0
+}
+
+func (s *socketStorage) LastFlush() time.Time {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.lastWrite
+}
+
+func (s *socketStorage) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
@@ -0,0 +1,91 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package socket
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddStats(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cadvisor.sock")
+	listener, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	defer listener.Close()
+	lines := make(chan []byte)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			lines <- append([]byte(nil), scanner.Bytes()...)
+		}
+	}()
+
+	driver, err := newStorage("unix", path, time.Second, "node-1")
+	require.NoError(t, err)
+	defer driver.Close()
+	cInfo := &info.ContainerInfo{
+		ContainerReference: info.ContainerReference{Name: "/docker/abc", Id: "abc", Aliases: []string{"web"}},
+		Spec:               info.ContainerSpec{Labels: map[string]string{"app": "web"}},
+	}
+	timestamp := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	for i := uint64(1); i <= 2; i++ {
+		stats := &info.ContainerStats{Timestamp: timestamp, Sequence: i}
+		stats.Cpu.Usage.Total = 1000 * i
+		require.NoError(t, driver.AddStats(cInfo, stats))
+	}
+	assert.True(t, driver.Connected())
+	assert.False(t, driver.LastFlush().IsZero())
+
+	for i := uint64(1); i <= 2; i++ {
+		var s sample
+		require.NoError(t, json.Unmarshal(<-lines, &s))
+		assert.Equal(t, "node-1", s.MachineName)
+		assert.Equal(t, "web", s.ContainerName)
+		assert.Equal(t, "abc", s.ContainerID)
+		assert.Equal(t, map[string]string{"app": "web"}, s.ContainerLabels)
+		assert.True(t, timestamp.Equal(s.Timestamp))
+		assert.Equal(t, i, s.ContainerStats.Sequence)
+		assert.Equal(t, 1000*i, s.ContainerStats.Cpu.Usage.Total)
+	}
+}
+
+func TestAddStatsNotListening(t *testing.T) {
+	driver, err := newStorage("unix", filepath.Join(t.TempDir(), "missing.sock"), time.Second, "node-1")
+	require.NoError(t, err)
+	cInfo := &info.ContainerInfo{ContainerReference: info.ContainerReference{Name: "/"}}
+	assert.Error(t, driver.AddStats(cInfo, &info.ContainerStats{}))
+	// Not retried right away.
+	assert.Error(t, driver.AddStats(cInfo, &info.ContainerStats{}))
+	assert.False(t, driver.Connected())
+	assert.True(t, driver.LastFlush().IsZero())
+	assert.NoError(t, driver.Close())
+
+	_, err = newStorage("sctp", "localhost:9000", time.Second, "node-1")
+	assert.Error(t, err)
+}
@@ -17,14 +17,29 @@ package client
 import (
 	"fmt"
 	"net"
+	"strconv"
+	"sync"
+	"time"
 
 	"k8s.io/klog/v2"
 )
 
+// Client sends gauges to a statsd daemon, batching them in datagrams of at
+// most MTU bytes, separated by newlines.
 type Client struct {
-	HostPort  string
-	Namespace string
-	conn      net.Conn
+	HostPort string
+	// Maximum size of the datagrams. A metric larger than it is sent alone.
+	MTU int
+
+	lock sync.Mutex
+	conn net.Conn
+	// Metrics not sent yet, and their number.
+	buf     []byte
+	metrics int
+	// Whether the last write failed, and the time of the last successful
+	// one.
+	writeFailed bool
+	lastFlush   time.Time
 }
 
 func (c *Client) Open() error {
@@ -37,27 +52,87 @@ func (c *Client) Open() error {
 	return nil
 }
 
+// Close sends the buffered metrics and closes the connection.
 func (c *Client) Close() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	err := c.flush()
 	c.conn.Close()
 	c.conn = nil
-	return nil
+	return err
+}
+
+// Send buffers a gauge, without sampling. The buffered metrics are sent first
+// if the datagram would exceed the MTU with it.
+func (c *Client) Send(name string, value uint64) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	var err error
+	size := len(name) + len(":|g") + len(strconv.FormatUint(value, 10))
+	if len(c.buf) > 0 && len(c.buf)+len("\n")+size > c.MTU {
+		err = c.flush()
+	}
+	if len(c.buf) > 0 {
+		c.buf = append(c.buf, '\n')
+	}
+	c.buf = append(c.buf, name...)
+	c.buf = append(c.buf, ':')
+	c.buf = strconv.AppendUint(c.buf, value, 10)
+	c.buf = append(c.buf, "|g"...)
+	c.metrics++
+	return err
+}
+
+// Flush sends the buffered metrics.
+func (c *Client) Flush() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.flush()
 }
 
-// Simple send to statsd daemon without sampling.
-func (c *Client) Send(namespace, containerName, key string, value uint64) error {
-	// only send counter value
-	formatted := fmt.Sprintf("%s.%s.%s:%d|g", namespace, containerName, key, value)
-	_, err := fmt.Fprint(c.conn, formatted)
+func (c *Client) flush() error {
+	if len(c.buf) == 0 {
+		return nil
+	}
+	// The metrics are dropped on failure, statsd being lossy anyway.
+	_, err := c.conn.Write(c.buf)
+	c.buf = c.buf[:0]
+	c.metrics = 0
+	c.writeFailed = err != nil
 	if err != nil {
-		return fmt.Errorf("failed to send data %q: %v", formatted, err)
+		return fmt.Errorf("failed to send data to %q: %v", c.HostPort, err)
 	}
+	c.lastFlush = time.Now()
 	return nil
 }
 
-func New(hostPort string) (*Client, error) {
-	Client := Client{HostPort: hostPort}
-	if err := Client.Open(); err != nil {
+// Connected returns whether the last write succeeded.
+func (c *Client) Connected() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return !c.writeFailed
+}
+
+// QueueDepth returns the number of metrics buffered.
+func (c *Client) QueueDepth() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.metrics
+}
+
+func (c *Client) LastFlush() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.lastFlush
+}
+
+func New(hostPort string, mtu int) (*Client, error) {
+	if mtu <= 0 {
+		return nil, fmt.Errorf("invalid statsd MTU %d", mtu)
+	}
+	client := &Client{HostPort: hostPort, MTU: mtu}
+	if err := client.Open(); err != nil {
 		return nil, err
 	}
-	return &Client, nil
+	return client, nil
 }
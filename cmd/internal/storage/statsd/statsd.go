@@ -15,7 +15,13 @@
 package statsd
 
 import (
+	"flag"
+	"fmt"
 	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/klog/v2"
 
 	client "github.com/yidoyoon/cadvisor-lite/cmd/internal/storage/statsd/client"
 	info "github.com/yidoyoon/cadvisor-lite/info/v1"
@@ -26,9 +32,24 @@ func init() {
 	storage.RegisterStorageDriver("statsd", new)
 }
 
+var (
+	mtu           = flag.Int("storage_driver_statsd_mtu", 1432, "maximum size in bytes of the UDP datagrams sent by the statsd storage driver, each holding as many metrics as fit")
+	flushInterval = flag.Duration("storage_driver_statsd_flush_interval", time.Second, "interval between the sends of the metrics buffered by the statsd storage driver, in addition to the sends of the full datagrams. 0 sends them after every sample")
+	nameTemplate  = flag.String("storage_driver_statsd_name_template", "{namespace}.{container}.{metric}", "template of the names of the metrics of the statsd storage driver. Placeholders are {namespace}, {container}, {id}, {uid}, {metric} and {label:<name>}")
+)
+
 type statsdStorage struct {
 	client    *client.Client
 	Namespace string
+	template  []templatePart
+	// Closed on Close to stop the periodic flushes, nil without them.
+	stop chan struct{}
+}
+
+// templatePart is a literal string of a name template, or a placeholder.
+type templatePart struct {
+	literal     string
+	placeholder string
 }
 
 const (
@@ -85,6 +106,10 @@ const (
 	serPerfStat string = "perf_stat"
 	// Referenced memory
 	serReferencedMemory string = "referenced_memory"
+	// Sequence number of the sample
+	serSequence string = "sequence"
+	// Time between the timestamp of the sample and its storage, in nanoseconds
+	serTimestampSkew string = "timestamp_skew"
 	// Resctrl - Total memory bandwidth
 	serResctrlMemoryBandwidthTotal string = "resctrl_memory_bandwidth_total"
 	// Resctrl - Local memory bandwidth
@@ -94,7 +119,71 @@ const (
 )
 
 func new() (storage.StorageDriver, error) {
-	return newStorage(*storage.ArgDbName, *storage.ArgDbHost)
+	return newStorage(*storage.ArgDbName, *storage.ArgDbHost, *mtu, *flushInterval, *nameTemplate)
+}
+
+// parseTemplate splits a name template into literals and placeholders.
+func parseTemplate(template string) ([]templatePart, error) {
+	var parts []templatePart
+	hasMetric := false
+	for rest := template; len(rest) > 0; {
+		start := strings.IndexByte(rest, '{')
+		if start < 0 {
+			parts = append(parts, templatePart{literal: rest})
+			break
+		}
+		if start > 0 {
+			parts = append(parts, templatePart{literal: rest[:start]})
+		}
+		end := strings.IndexByte(rest[start:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated placeholder in statsd name template %q", template)
+		}
+		placeholder := rest[start+1 : start+end]
+		switch {
+		case placeholder == "metric":
+			hasMetric = true
+		case placeholder == "namespace", placeholder == "container", placeholder == "id", placeholder == "uid":
+		case strings.HasPrefix(placeholder, "label:") && len(placeholder) > len("label:"):
+		default:
+			return nil, fmt.Errorf("unknown placeholder {%s} in statsd name template", placeholder)
+		}
+		parts = append(parts, templatePart{placeholder: placeholder})
+		rest = rest[start+end+1:]
+	}
+	if !hasMetric {
+		return nil, fmt.Errorf("statsd name template %q has no {metric} placeholder", template)
+	}
+	return parts, nil
+}
+
+// The characters of the statsd line protocol, replaced in the values of the
+// placeholders.
+var nameReplacer = strings.NewReplacer(":", "_", "|", "_", "@", "_", "\n", "_")
+
+// name returns the name of the metric of a container from the template.
+func (s *statsdStorage) name(cInfo *info.ContainerInfo, containerName, metric string) string {
+	var b strings.Builder
+	for _, part := range s.template {
+		switch part.placeholder {
+		case "":
+			b.WriteString(part.literal)
+		case "metric":
+			b.WriteString(metric)
+		case "namespace":
+			b.WriteString(s.Namespace)
+		case "container":
+			b.WriteString(nameReplacer.Replace(containerName))
+		case "id":
+			b.WriteString(nameReplacer.Replace(cInfo.ContainerReference.Id))
+		case "uid":
+			b.WriteString(cInfo.Spec.Uid)
+		default:
+			label := strings.TrimPrefix(part.placeholder, "label:")
+			b.WriteString(nameReplacer.Replace(cInfo.Spec.Labels[label]))
+		}
+	}
+	return b.String()
 }
 
 func (s *statsdStorage) containerStatsToValues(stats *info.ContainerStats) (series map[string]uint64) {
@@ -129,6 +218,10 @@ func (s *statsdStorage) containerStatsToValues(stats *info.ContainerStats) (seri
 	// Referenced Memory
 	series[serReferencedMemory] = stats.ReferencedMemory
 
+	// Sequence number and timestamp skew
+	series[serSequence] = stats.Sequence
+	series[serTimestampSkew] = uint64(stats.TimestampSkew.Nanoseconds())
+
 	return series
 }
 
@@ -214,29 +307,77 @@ func (s *statsdStorage) AddStats(cInfo *info.ContainerInfo, stats *info.Containe
 		containerName = cInfo.ContainerReference.Name
 	}
 
+	// The metrics are buffered, and sent whenever a datagram is full.
+	var sendErr error
 	for key, value := range series {
-		err := s.client.Send(s.Namespace, containerName, key, value)
-		if err != nil {
-			return err
+		if err := s.client.Send(s.name(cInfo, containerName, key), value); err != nil && sendErr == nil {
+			sendErr = err
+		}
+	}
+	if s.stop == nil {
+		if err := s.client.Flush(); err != nil && sendErr == nil {
+			sendErr = err
 		}
 	}
-	return nil
+	return sendErr
+}
+
+// flushPeriodically sends the buffered metrics every interval until stop is
+// closed, so that the last datagram of a housekeeping is not held back.
+func (s *statsdStorage) flushPeriodically(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			if err := s.client.Flush(); err != nil {
+				klog.Warningf("Failed to flush statsd metrics: %v", err)
+			}
+		}
+	}
+}
+
+func (s *statsdStorage) Connected() bool {
+	return s.client.Connected()
+}
+
+// QueueDepth returns the number of metrics waiting for the next datagram.
+func (s *statsdStorage) QueueDepth() int {
+	return s.client.QueueDepth()
+}
+
+func (s *statsdStorage) LastFlush() time.Time {
+	return s.client.LastFlush()
 }
 
 func (s *statsdStorage) Close() error {
-	s.client.Close()
+	if s.stop != nil {
+		close(s.stop)
+	}
+	err := s.client.Close()
 	s.client = nil
-	return nil
+	return err
 }
 
-func newStorage(namespace, hostPort string) (*statsdStorage, error) {
-	statsdClient, err := client.New(hostPort)
+func newStorage(namespace, hostPort string, mtu int, flushInterval time.Duration, nameTemplate string) (*statsdStorage, error) {
+	template, err := parseTemplate(nameTemplate)
+	if err != nil {
+		return nil, err
+	}
+	statsdClient, err := client.New(hostPort, mtu)
 	if err != nil {
 		return nil, err
 	}
 	statsdStorage := &statsdStorage{
 		client:    statsdClient,
 		Namespace: namespace,
+		template:  template,
+	}
+	if flushInterval > 0 {
+		statsdStorage.stop = make(chan struct{})
+		go statsdStorage.flushPeriodically(flushInterval)
 	}
 	return statsdStorage, nil
 }
@@ -0,0 +1,79 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package statsd
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddStatsBatches(t *testing.T) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	const mtu = 200
+	driver, err := newStorage("cadvisor", listener.LocalAddr().String(), mtu, 0, "{namespace}.{label:app}.{metric}")
+	require.NoError(t, err)
+	defer driver.Close()
+	cInfo := &info.ContainerInfo{
+		ContainerReference: info.ContainerReference{Name: "/docker/abc", Aliases: []string{"web"}},
+		Spec:               info.ContainerSpec{Labels: map[string]string{"app": "front:end"}},
+	}
+	stats := &info.ContainerStats{Timestamp: time.Now()}
+	stats.Cpu.Usage.Total = 1234
+	require.NoError(t, driver.AddStats(cInfo, stats))
+	assert.Zero(t, driver.QueueDepth())
+	assert.True(t, driver.Connected())
+	assert.False(t, driver.LastFlush().IsZero())
+
+	// Every metric is sent once, in datagrams holding several of them.
+	series := driver.containerStatsToValues(stats)
+	driver.memoryStatsToValues(&series, stats)
+	metrics := map[string]bool{}
+	datagrams := 0
+	buf := make([]byte, 2*mtu)
+	require.NoError(t, listener.SetReadDeadline(time.Now().Add(5*time.Second)))
+	for len(metrics) < len(series) {
+		n, _, err := listener.ReadFrom(buf)
+		require.NoError(t, err)
+		assert.LessOrEqual(t, n, mtu)
+		datagrams++
+		for _, line := range strings.Split(string(buf[:n]), "\n") {
+			assert.True(t, strings.HasPrefix(line, "cadvisor.front_end."), line)
+			metrics[line] = true
+		}
+	}
+	assert.Less(t, datagrams, len(series))
+	assert.True(t, metrics["cadvisor.front_end.cpu_usage_total:1234|g"])
+}
+
+func TestParseTemplate(t *testing.T) {
+	parts, err := parseTemplate("cadvisor.{container}.{metric}")
+	require.NoError(t, err)
+	assert.Equal(t, []templatePart{{literal: "cadvisor."}, {placeholder: "container"}, {literal: "."}, {placeholder: "metric"}}, parts)
+
+	for _, template := range []string{"{namespace}.{container}", "{metric}.{unknown}", "{metric}.{label:}", "{metric}.{id"} {
+		_, err := parseTemplate(template)
+		assert.Error(t, err, template)
+	}
+}
@@ -87,6 +87,10 @@ const (
 	serPerfStat string = "perf_stat"
 	// Referenced memory
 	serReferencedMemory string = "referenced_memory"
+	// Sequence number of the sample
+	serSequence string = "sequence"
+	// Time between the timestamp of the sample and its storage, in nanoseconds
+	serTimestampSkew string = "timestamp_skew"
 	// Resctrl - Total memory bandwidth
 	serResctrlMemoryBandwidthTotal string = "resctrl_memory_bandwidth_total"
 	// Resctrl - Local memory bandwidth
@@ -134,6 +138,10 @@ func (driver *stdoutStorage) containerStatsToValues(stats *info.ContainerStats)
 	// Referenced Memory
 	series[serReferencedMemory] = stats.ReferencedMemory
 
+	// Sequence number and timestamp skew
+	series[serSequence] = stats.Sequence
+	series[serTimestampSkew] = uint64(stats.TimestampSkew.Nanoseconds())
+
 	return series
 }
 
@@ -0,0 +1,222 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package wasm runs user-supplied WebAssembly modules as stats transformers.
+//
+// A module must export its memory and two functions:
+//
+//	cadvisor_alloc(size i32) i32
+//	cadvisor_transform(ptr i32, len i32) i64
+//
+// cadvisor_alloc returns a buffer of size bytes in the module memory, into
+// which the JSON encoding of a TransformRequest is written.
+// cadvisor_transform is then called with that buffer and returns the location
+// of the JSON encoding of the transformed ContainerStats, packed as
+// ptr<<32 | len. A zero length leaves the stats unchanged.
+//
+// A module may also export
+//
+//	cadvisor_dealloc(ptr i32, len i32)
+//
+// which is called with the input buffer once transformed and with the output
+// buffer once read, so that the instances can be reused indefinitely. The
+// instances of the modules which do not export it are discarded once their
+// memory grew beyond half of its limit.
+//
+// Modules are sandboxed: they may use WASI but have no access to the
+// filesystem, the network or the environment, their memory is bounded and
+// every invocation is interrupted after a timeout.
+package wasm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+)
+
+const (
+	allocFunction     = "cadvisor_alloc"
+	transformFunction = "cadvisor_transform"
+	deallocFunction   = "cadvisor_dealloc"
+
+	// Memory limit of a module instance, in 64KiB pages.
+	memoryLimitPages = 256
+	// Memory size beyond which the instances of the modules without
+	// cadvisor_dealloc are discarded rather than reused.
+	resetMemorySize = memoryLimitPages / 2 * 65536
+)
+
+// TransformRequest is the input of cadvisor_transform.
+type TransformRequest struct {
+	Container info.ContainerReference `json:"container"`
+	Stats     *info.ContainerStats    `json:"stats"`
+}
+
+// Transformer is a stats.Transformer running a WebAssembly module.
+type Transformer struct {
+	runtime  wazero.Runtime
+	compiled wazero.CompiledModule
+	timeout  time.Duration
+
+	// Instances are not safe for concurrent use, idle ones are kept here.
+	lock sync.Mutex
+	idle []api.Module
+}
+
+// NewTransformerFromFile compiles the WebAssembly module at path.
+func NewTransformerFromFile(path string, timeout time.Duration) (*Transformer, error) {
+	code, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read WASM module: %v", err)
+	}
+	return NewTransformer(code, timeout)
+}
+
+// NewTransformer compiles a WebAssembly module. Every invocation of the
+// module is interrupted after timeout.
+func NewTransformer(code []byte, timeout time.Duration) (*Transformer, error) {
+	ctx := context.Background()
+	config := wazero.NewRuntimeConfig().
+		WithCloseOnContextDone(true).
+		WithMemoryLimitPages(memoryLimitPages)
+	runtime := wazero.NewRuntimeWithConfig(ctx, config)
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, runtime); err != nil {
+		runtime.Close(ctx)
+		return nil, fmt.Errorf("failed to instantiate WASI: %v", err)
+	}
+	compiled, err := runtime.CompileModule(ctx, code)
+	if err != nil {
+		runtime.Close(ctx)
+		return nil, fmt.Errorf("failed to compile WASM module: %v", err)
+	}
+	for _, name := range []string{allocFunction, transformFunction} {
+		if _, ok := compiled.ExportedFunctions()[name]; !ok {
+			runtime.Close(ctx)
+			return nil, fmt.Errorf("WASM module does not export %q", name)
+		}
+	}
+	return &Transformer{
+		runtime:  runtime,
+		compiled: compiled,
+		timeout:  timeout,
+	}, nil
+}
+
+// Close releases the module and all its instances.
+func (t *Transformer) Close() error {
+	return t.runtime.Close(context.Background())
+}
+
+func (t *Transformer) getInstance(ctx context.Context) (api.Module, error) {
+	t.lock.Lock()
+	if n := len(t.idle); n > 0 {
+		mod := t.idle[n-1]
+		t.idle = t.idle[:n-1]
+		t.lock.Unlock()
+		return mod, nil
+	}
+	t.lock.Unlock()
+
+	// Anonymous, so that the module can be instantiated more than once.
+	config := wazero.NewModuleConfig().WithName("").WithStartFunctions("_initialize")
+	return t.runtime.InstantiateModule(ctx, t.compiled, config)
+}
+
+func (t *Transformer) putInstance(mod api.Module) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.idle = append(t.idle, mod)
+}
+
+// Transform implements stats.Transformer.
+func (t *Transformer) Transform(ref info.ContainerReference, stats *info.ContainerStats) error {
+	in, err := json.Marshal(TransformRequest{Container: ref, Stats: stats})
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), t.timeout)
+	defer cancel()
+	mod, err := t.getInstance(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to instantiate WASM module: %v", err)
+	}
+	out, err := call(ctx, mod, in)
+	if err != nil {
+		// The instance may be in any state, or closed on timeout.
+		mod.Close(context.Background())
+		return err
+	}
+	if mod.ExportedFunction(deallocFunction) == nil && mod.Memory().Size() > resetMemorySize {
+		// The buffers are never freed, start again from a fresh instance.
+		mod.Close(context.Background())
+	} else {
+		t.putInstance(mod)
+	}
+
+	if len(out) == 0 {
+		return nil
+	}
+	var transformed info.ContainerStats
+	if err := json.Unmarshal(out, &transformed); err != nil {
+		return fmt.Errorf("invalid output of WASM module: %v", err)
+	}
+	*stats = transformed
+	return nil
+}
+
+// Passes in to cadvisor_transform and returns a copy of its output.
+func call(ctx context.Context, mod api.Module, in []byte) ([]byte, error) {
+	res, err := mod.ExportedFunction(allocFunction).Call(ctx, uint64(len(in)))
+	if err != nil {
+		return nil, fmt.Errorf("%s failed: %v", allocFunction, err)
+	}
+	ptr := uint32(res[0])
+	if !mod.Memory().Write(ptr, in) {
+		return nil, fmt.Errorf("%s returned out of range buffer %d", allocFunction, ptr)
+	}
+
+	res, err = mod.ExportedFunction(transformFunction).Call(ctx, uint64(ptr), uint64(len(in)))
+	if err != nil {
+		return nil, fmt.Errorf("%s failed: %v", transformFunction, err)
+	}
+	outPtr, outLen := uint32(res[0]>>32), uint32(res[0])
+	out, ok := mod.Memory().Read(outPtr, outLen)
+	if !ok {
+		return nil, fmt.Errorf("%s returned out of range buffer %d+%d", transformFunction, outPtr, outLen)
+	}
+	// The view is only valid until the next call into the module.
+	out = append([]byte(nil), out...)
+
+	if dealloc := mod.ExportedFunction(deallocFunction); dealloc != nil {
+		if _, err := dealloc.Call(ctx, uint64(ptr), uint64(len(in))); err != nil {
+			return nil, fmt.Errorf("%s failed: %v", deallocFunction, err)
+		}
+		if outLen > 0 {
+			if _, err := dealloc.Call(ctx, uint64(outPtr), uint64(outLen)); err != nil {
+				return nil, fmt.Errorf("%s failed: %v", deallocFunction, err)
+			}
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,166 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wasm
+
+import (
+	"testing"
+	"time"
+
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func section(id byte, content ...byte) []byte {
+	return append([]byte{id, byte(len(content))}, content...)
+}
+
+// Assembles a module exporting its memory, a cadvisor_alloc returning offset
+// 1024 and a cadvisor_transform with the given body. data is stored at offset 0.
+func testModule(transformBody []byte, data string) []byte {
+	return buildTestModule(transformBody, data, []byte{1}, false)
+}
+
+// Assembles a module as testModule does, with a memory of the given number
+// of pages, LEB128-encoded. With dealloc, the module exports a
+// cadvisor_dealloc counting its calls at offset 65532.
+func buildTestModule(transformBody []byte, data string, pages []byte, dealloc bool) []byte {
+	module := []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+	// Types: (i32) -> i32, (i32, i32) -> i64 and (i32, i32) -> ().
+	module = append(module, section(1, 3, 0x60, 1, 0x7f, 1, 0x7f, 0x60, 2, 0x7f, 0x7f, 1, 0x7e, 0x60, 2, 0x7f, 0x7f, 0)...)
+	names := []string{"memory", allocFunction, transformFunction}
+	functions := []byte{2, 0, 1}
+	if dealloc {
+		names = append(names, deallocFunction)
+		functions = []byte{3, 0, 1, 2}
+	}
+	// Functions.
+	module = append(module, section(3, functions...)...)
+	// Memory.
+	module = append(module, section(5, append([]byte{1, 0}, pages...)...)...)
+	// Exports.
+	exports := []byte{byte(len(names))}
+	for i, name := range names {
+		kind, index := byte(0), byte(i-1)
+		if i == 0 {
+			kind, index = 2, 0
+		}
+		exports = append(exports, byte(len(name)))
+		exports = append(exports, name...)
+		exports = append(exports, kind, index)
+	}
+	module = append(module, section(7, exports...)...)
+	// Code: alloc is "i32.const 1024", dealloc increments the i32 at 65532.
+	bodies := [][]byte{
+		{0, 0x41, 0x80, 0x08, 0x0b},
+		append([]byte{0}, transformBody...),
+	}
+	if dealloc {
+		bodies = append(bodies, []byte{0, 0x41, 0xfc, 0xff, 0x03, 0x41, 0xfc, 0xff, 0x03, 0x28, 0x02, 0x00, 0x41, 0x01, 0x6a, 0x36, 0x02, 0x00, 0x0b})
+	}
+	code := []byte{byte(len(bodies))}
+	for _, body := range bodies {
+		code = append(code, byte(len(body)))
+		code = append(code, body...)
+	}
+	module = append(module, section(10, code...)...)
+	// Data at offset 0.
+	segment := []byte{1, 0, 0x41, 0, 0x0b, byte(len(data))}
+	module = append(module, section(11, append(segment, data...)...)...)
+	return module
+}
+
+func TestTransform(t *testing.T) {
+	out := `{"cpu":{"usage":{"total":42}}}`
+	// Returns (0 << 32 | len(out)), the location of out.
+	module := testModule([]byte{0x42, byte(len(out)), 0x0b}, out)
+	transformer, err := NewTransformer(module, time.Second)
+	require.NoError(t, err)
+	defer transformer.Close()
+
+	for i := 0; i < 2; i++ {
+		stats := &info.ContainerStats{}
+		require.NoError(t, transformer.Transform(info.ContainerReference{Name: "/"}, stats))
+		assert.Equal(t, uint64(42), stats.Cpu.Usage.Total)
+	}
+	assert.Len(t, transformer.idle, 1)
+}
+
+func TestTransformDealloc(t *testing.T) {
+	out := `{"cpu":{"usage":{"total":42}}}`
+	module := buildTestModule([]byte{0x42, byte(len(out)), 0x0b}, out, []byte{1}, true)
+	transformer, err := NewTransformer(module, time.Second)
+	require.NoError(t, err)
+	defer transformer.Close()
+
+	stats := &info.ContainerStats{}
+	require.NoError(t, transformer.Transform(info.ContainerReference{Name: "/"}, stats))
+	assert.Equal(t, uint64(42), stats.Cpu.Usage.Total)
+	// The input and output buffers are freed.
+	require.Len(t, transformer.idle, 1)
+	calls, ok := transformer.idle[0].Memory().ReadUint32Le(65532)
+	require.True(t, ok)
+	assert.Equal(t, uint32(2), calls)
+}
+
+func TestTransformResetsGrownInstances(t *testing.T) {
+	// 129 pages, beyond half of the limit, without cadvisor_dealloc.
+	module := buildTestModule([]byte{0x42, 0, 0x0b}, "", []byte{0x81, 0x01}, false)
+	transformer, err := NewTransformer(module, time.Second)
+	require.NoError(t, err)
+	defer transformer.Close()
+
+	require.NoError(t, transformer.Transform(info.ContainerReference{Name: "/"}, &info.ContainerStats{}))
+	assert.Empty(t, transformer.idle)
+
+	// Instances freeing their buffers are kept.
+	module = buildTestModule([]byte{0x42, 0, 0x0b}, "", []byte{0x81, 0x01}, true)
+	transformer, err = NewTransformer(module, time.Second)
+	require.NoError(t, err)
+	defer transformer.Close()
+	require.NoError(t, transformer.Transform(info.ContainerReference{Name: "/"}, &info.ContainerStats{}))
+	assert.Len(t, transformer.idle, 1)
+}
+
+func TestTransformUnchanged(t *testing.T) {
+	// Returns 0, an empty output.
+	module := testModule([]byte{0x42, 0, 0x0b}, "")
+	transformer, err := NewTransformer(module, time.Second)
+	require.NoError(t, err)
+	defer transformer.Close()
+
+	stats := &info.ContainerStats{OOMEvents: 3}
+	require.NoError(t, transformer.Transform(info.ContainerReference{Name: "/"}, stats))
+	assert.Equal(t, uint64(3), stats.OOMEvents)
+}
+
+func TestTransformTimeout(t *testing.T) {
+	// Loops forever.
+	module := testModule([]byte{0x03, 0x40, 0x0c, 0x00, 0x0b, 0x00, 0x0b}, "")
+	transformer, err := NewTransformer(module, 10*time.Millisecond)
+	require.NoError(t, err)
+	defer transformer.Close()
+
+	stats := &info.ContainerStats{OOMEvents: 3}
+	assert.Error(t, transformer.Transform(info.ContainerReference{Name: "/"}, stats))
+	assert.Equal(t, uint64(3), stats.OOMEvents)
+	assert.Empty(t, transformer.idle)
+}
+
+func TestNewTransformerMissingExports(t *testing.T) {
+	_, err := NewTransformer([]byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}, time.Second)
+	assert.Error(t, err)
+}
@@ -21,14 +21,17 @@ import (
 	"time"
 
 	"github.com/yidoyoon/cadvisor-lite/cache/memory"
+	"github.com/yidoyoon/cadvisor-lite/cmd/internal/standby"
 	_ "github.com/yidoyoon/cadvisor-lite/cmd/internal/storage/bigquery"
 	_ "github.com/yidoyoon/cadvisor-lite/cmd/internal/storage/elasticsearch"
 	_ "github.com/yidoyoon/cadvisor-lite/cmd/internal/storage/influxdb"
 	_ "github.com/yidoyoon/cadvisor-lite/cmd/internal/storage/kafka"
 	_ "github.com/yidoyoon/cadvisor-lite/cmd/internal/storage/redis"
+	_ "github.com/yidoyoon/cadvisor-lite/cmd/internal/storage/socket"
 	_ "github.com/yidoyoon/cadvisor-lite/cmd/internal/storage/statsd"
 	_ "github.com/yidoyoon/cadvisor-lite/cmd/internal/storage/stdout"
 	"github.com/yidoyoon/cadvisor-lite/storage"
+	"github.com/yidoyoon/cadvisor-lite/utils/relabel"
 
 	"k8s.io/klog/v2"
 )
@@ -36,22 +39,41 @@ import (
 var (
 	storageDriver   = flag.String("storage_driver", "", fmt.Sprintf("Storage `driver` to use. Data is always cached shortly in memory, this controls where data is pushed besides the local cache. Empty means none, multiple separated by commas. Options are: <empty>, %s", strings.Join(storage.ListDrivers(), ", ")))
 	storageDuration = flag.Duration("storage_duration", 2*time.Minute, "How long to keep data stored (Default: 2min).")
+
+	machineStatsDuration   = flag.Duration("machine_stats_duration", 0, "How long to keep the stats of the machine served by the machinestats API, at --machine_stats_resolution beyond --storage_duration. Values not above --storage_duration keep them for --storage_duration like the stats of the containers.")
+	machineStatsResolution = flag.Duration("machine_stats_resolution", time.Minute, "Minimum interval between the stats of the machine kept beyond --storage_duration with --machine_stats_duration.")
 )
 
 // NewMemoryStorage creates a memory storage with an optional backend storage option.
-func NewMemoryStorage() (*memory.InMemoryCache, error) {
+// The health of the backend storages is reported by storage.DriversHealth.
+// The labels of the containers are rewritten by the relabeler before being
+// written by the backend storages. If active is not nil, the stats are only
+// written once it is closed, see standby.NewStorageDriver.
+func NewMemoryStorage(relabeler *relabel.Relabeler, active <-chan struct{}) (*memory.InMemoryCache, error) {
 	backendStorages := []storage.StorageDriver{}
 	for _, driver := range strings.Split(*storageDriver, ",") {
 		if driver == "" {
 			continue
 		}
-		storage, err := storage.New(driver)
+		backendStorage, err := storage.New(driver)
 		if err != nil {
 			return nil, err
 		}
-		backendStorages = append(backendStorages, storage)
+		backendStorage = storage.NewInstrumentedDriver(backendStorage, driver)
+		if relabeler.HasRules() {
+			backendStorage = storage.NewRelabelingDriver(backendStorage, driver, relabeler)
+		}
+		if active != nil {
+			backendStorage = standby.NewStorageDriver(backendStorage, active)
+		}
+		backendStorages = append(backendStorages, backendStorage)
 		klog.V(1).Infof("Using backend storage type %q", driver)
 	}
 	klog.V(1).Infof("Caching stats in memory for %v", *storageDuration)
-	return memory.New(*storageDuration, backendStorages), nil
+	memoryStorage := memory.New(*storageDuration, backendStorages)
+	if *machineStatsDuration > *storageDuration {
+		klog.V(1).Infof("Keeping machine stats in memory for %v, one per %v", *machineStatsDuration, *machineStatsResolution)
+		memoryStorage.RetainHistory("/", *machineStatsDuration, *machineStatsResolution)
+	}
+	return memoryStorage, nil
 }
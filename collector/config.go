@@ -63,6 +63,17 @@ type Prometheus struct {
 	MetricsConfig []string `json:"metrics_config"`
 }
 
+type Probe struct {
+	// the endpoint to probe
+	Endpoint EndpointConfig `json:"endpoint"`
+
+	// the frequency at which the endpoint should be probed
+	PollingFrequency time.Duration `json:"polling_frequency"`
+
+	// the time after which a probe fails, at most the polling frequency
+	Timeout time.Duration `json:"timeout"`
+}
+
 type EndpointConfig struct {
 	// The full URL of the endpoint to reach
 	URL string
@@ -0,0 +1,157 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"github.com/yidoyoon/cadvisor-lite/container"
+	v1 "github.com/yidoyoon/cadvisor-lite/info/v1"
+)
+
+// Names of the metrics of the probe collector, the same as the ones of the
+// http prober of the Prometheus blackbox exporter.
+const (
+	probeSuccess       = "probe_success"
+	probeDuration      = "probe_duration_seconds"
+	probeStatusCode    = "probe_http_status_code"
+	probeTLSCertExpiry = "probe_ssl_earliest_cert_expiry"
+)
+
+// ProbeCollector probes an HTTP(S) endpoint of a container and reports
+// whether it is healthy, its latency, status code and the expiry of its TLS
+// certificate as metrics.
+type ProbeCollector struct {
+	// name of the collector
+	name string
+
+	// rate at which the endpoint is probed
+	pollingFrequency time.Duration
+
+	// holds information extracted from the config file for a collector
+	configFile Probe
+
+	// The Http client to use when probing the endpoint
+	httpClient *http.Client
+}
+
+// Returns a new probe collector using the information extracted from the
+// configfile
+func NewProbeCollector(collectorName string, configFile []byte, containerHandler container.ContainerHandler, httpClient *http.Client) (*ProbeCollector, error) {
+	var configInJSON Probe
+	err := json.Unmarshal(configFile, &configInJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	configInJSON.Endpoint.configure(containerHandler)
+
+	// Minimum supported frequency is 1s
+	pollingFrequency := configInJSON.PollingFrequency
+	if pollingFrequency < time.Second {
+		pollingFrequency = time.Second
+	}
+	// A probe must end before the next one.
+	if configInJSON.Timeout <= 0 || configInJSON.Timeout > pollingFrequency {
+		configInJSON.Timeout = pollingFrequency
+	}
+
+	return &ProbeCollector{
+		name:             collectorName,
+		pollingFrequency: pollingFrequency,
+		configFile:       configInJSON,
+		httpClient:       httpClient,
+	}, nil
+}
+
+// Returns name of the collector
+func (collector *ProbeCollector) Name() string {
+	return collector.name
+}
+
+func (collector *ProbeCollector) GetSpec() []v1.MetricSpec {
+	specs := []v1.MetricSpec{
+		{Name: probeSuccess, Type: v1.MetricGauge, Format: v1.FloatType},
+		{Name: probeDuration, Type: v1.MetricGauge, Format: v1.FloatType, Units: "seconds"},
+		{Name: probeStatusCode, Type: v1.MetricGauge, Format: v1.FloatType},
+	}
+	if collector.isTLS() {
+		specs = append(specs, v1.MetricSpec{Name: probeTLSCertExpiry, Type: v1.MetricGauge, Format: v1.FloatType, Units: "seconds"})
+	}
+	return specs
+}
+
+func (collector *ProbeCollector) isTLS() bool {
+	return strings.HasPrefix(strings.ToLower(collector.configFile.Endpoint.URL), "https://")
+}
+
+// Returns collected metrics and the next collection time of the collector. A
+// failed probe is reported by the metrics rather than as an error.
+func (collector *ProbeCollector) Collect(metrics map[string][]v1.MetricVal) (time.Time, map[string][]v1.MetricVal, error) {
+	currentTime := time.Now()
+	nextCollectionTime := currentTime.Add(collector.pollingFrequency)
+
+	add := func(name string, value float64) {
+		metrics[name] = append(metrics[name], v1.MetricVal{
+			Label:      "probe=" + collector.name,
+			Labels:     map[string]string{"probe": collector.name},
+			Timestamp:  currentTime,
+			FloatValue: value,
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), collector.configFile.Timeout)
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, collector.configFile.Endpoint.URL, nil)
+	if err != nil {
+		return nextCollectionTime, metrics, err
+	}
+	response, err := collector.httpClient.Do(request)
+	if err != nil {
+		klog.V(4).Infof("Probe %q of %q failed: %v", collector.name, collector.configFile.Endpoint.URL, err)
+		add(probeSuccess, 0)
+		add(probeDuration, time.Since(currentTime).Seconds())
+		return nextCollectionTime, metrics, nil
+	}
+	defer response.Body.Close()
+	// The latency includes reading the body, as for a client of the endpoint.
+	_, _ = io.Copy(io.Discard, response.Body)
+	duration := time.Since(currentTime)
+
+	success := 0.0
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		success = 1
+	}
+	add(probeSuccess, success)
+	add(probeDuration, duration.Seconds())
+	add(probeStatusCode, float64(response.StatusCode))
+	if response.TLS != nil && len(response.TLS.PeerCertificates) > 0 {
+		earliest := response.TLS.PeerCertificates[0].NotAfter
+		for _, cert := range response.TLS.PeerCertificates[1:] {
+			if cert.NotAfter.Before(earliest) {
+				earliest = cert.NotAfter
+			}
+		}
+		add(probeTLSCertExpiry, float64(earliest.Unix()))
+	}
+	return nextCollectionTime, metrics, nil
+}
@@ -0,0 +1,78 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	containertest "github.com/yidoyoon/cadvisor-lite/container/testing"
+	v1 "github.com/yidoyoon/cadvisor-lite/info/v1"
+)
+
+func TestProbeConfig(t *testing.T) {
+	configFile, err := os.ReadFile("config/sample_config_probe.json")
+	require.NoError(t, err)
+	containerHandler := containertest.NewMockContainerHandler("mockContainer")
+	containerHandler.On("GetContainerIPAddress").Return("222.222.222.222")
+
+	collector, err := NewProbeCollector("probe", configFile, containerHandler, http.DefaultClient)
+	require.NoError(t, err)
+	assert.Equal(t, "probe", collector.Name())
+	assert.Equal(t, "https://222.222.222.222:8443/healthz", collector.configFile.Endpoint.URL)
+	assert.Equal(t, 10*time.Second, collector.pollingFrequency)
+	assert.Equal(t, 2*time.Second, collector.configFile.Timeout)
+	assert.Len(t, collector.GetSpec(), 4)
+}
+
+func TestProbe(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/healthz" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	configFile := []byte(`{"endpoint": "` + server.URL + `/healthz"}`)
+	collector, err := NewProbeCollector("probe-healthz", configFile, containertest.NewMockContainerHandler("mockContainer"), server.Client())
+	require.NoError(t, err)
+	assert.Equal(t, time.Second, collector.configFile.Timeout)
+
+	_, metrics, err := collector.Collect(map[string][]v1.MetricVal{})
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, metrics[probeSuccess][0].FloatValue)
+	assert.Equal(t, map[string]string{"probe": "probe-healthz"}, metrics[probeSuccess][0].Labels)
+	assert.Equal(t, 200.0, metrics[probeStatusCode][0].FloatValue)
+	assert.Greater(t, metrics[probeDuration][0].FloatValue, 0.0)
+	assert.Equal(t, float64(server.Certificate().NotAfter.Unix()), metrics[probeTLSCertExpiry][0].FloatValue)
+
+	collector.configFile.Endpoint.URL = server.URL + "/unhealthy"
+	_, metrics, err = collector.Collect(map[string][]v1.MetricVal{})
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, metrics[probeSuccess][0].FloatValue)
+	assert.Equal(t, 503.0, metrics[probeStatusCode][0].FloatValue)
+
+	server.Close()
+	_, metrics, err = collector.Collect(map[string][]v1.MetricVal{})
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, metrics[probeSuccess][0].FloatValue)
+	assert.NotContains(t, metrics, probeStatusCode)
+}
@@ -0,0 +1,107 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/yidoyoon/cadvisor-lite/container"
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+	"github.com/yidoyoon/cadvisor-lite/utils"
+)
+
+// metricControllers are the cgroup controllers the kinds of metrics are read
+// from, on cgroup v1 and v2, and the file checked for permission on v2.
+var metricControllers = []struct {
+	kind   container.MetricKind
+	v1, v2 string
+	file   string
+}{
+	{container.CpuUsageMetrics, "cpuacct", "cpu", "cpu.stat"},
+	{container.MemoryUsageMetrics, "memory", "memory", "memory.stat"},
+	{container.DiskIOMetrics, "blkio", "io", "io.stat"},
+	{container.HugetlbUsageMetrics, "hugetlb", "hugetlb", ""},
+	{container.ProcessMetrics, "pids", "pids", "pids.current"},
+}
+
+// MetricsAvailability returns whether the kinds of metrics read from the
+// cgroup of a container can be collected: their controller has to be
+// available and cAdvisor has to be allowed to read it.
+func MetricsAvailability(cgroupPaths map[string]string, cgroup2UnifiedMode bool) map[string]info.MetricAvailability {
+	availability := make(map[string]info.MetricAvailability, len(metricControllers))
+	if cgroup2UnifiedMode {
+		dir := cgroupPaths[""]
+		controllers, err := os.ReadFile(path.Join(dir, "cgroup.controllers"))
+		enabled := map[string]bool{}
+		for _, c := range strings.Fields(string(controllers)) {
+			enabled[c] = true
+		}
+		for _, mc := range metricControllers {
+			a := info.MetricAvailability{Available: true}
+			switch {
+			case err != nil:
+				a = unavailable("failed to read the controllers of cgroup %s: %v", dir, err)
+			// cpu.stat exists even if the controller is not enabled.
+			case !enabled[mc.v2] && mc.v2 != "cpu":
+				a = unavailable("cgroup controller %s is not enabled", mc.v2)
+			case mc.file != "" && IsPermissionError(unix.Access(path.Join(dir, mc.file), unix.R_OK)):
+				a = unavailable("no permission to read %s", path.Join(dir, mc.file))
+			}
+			availability[mc.kind.String()] = a
+		}
+		return availability
+	}
+
+	for _, mc := range metricControllers {
+		a := info.MetricAvailability{Available: true}
+		dir, ok := cgroupPaths[mc.v1]
+		switch {
+		case !ok || dir == "":
+			a = unavailable("cgroup controller %s is not mounted", mc.v1)
+		case IsPermissionError(unix.Access(dir, unix.R_OK|unix.X_OK)):
+			a = unavailable("no permission to read cgroup %s", dir)
+		case !utils.FileExists(dir):
+			a = unavailable("cgroup %s does not exist", dir)
+		}
+		availability[mc.kind.String()] = a
+	}
+	return availability
+}
+
+// CgroupFilesReadable returns whether cAdvisor is allowed to read the files of
+// the cgroup v2 directory dir the metrics are read from.
+func CgroupFilesReadable(dir string) bool {
+	for _, mc := range metricControllers {
+		if mc.file != "" && IsPermissionError(unix.Access(path.Join(dir, mc.file), unix.R_OK)) {
+			return false
+		}
+	}
+	return true
+}
+
+func unavailable(format string, args ...interface{}) info.MetricAvailability {
+	return info.MetricAvailability{Reason: fmt.Sprintf(format, args...)}
+}
+
+// IsPermissionError returns whether err is caused by a lack of permission.
+func IsPermissionError(err error) bool {
+	return errors.Is(err, unix.EACCES) || errors.Is(err, unix.EPERM)
+}
@@ -0,0 +1,67 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsAvailabilityCgroupV2(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "cgroup.controllers"), []byte("memory pids\n"), 0o644))
+
+	availability := MetricsAvailability(map[string]string{"": dir}, true)
+
+	assert.True(t, availability["cpu"].Available)
+	assert.True(t, availability["memory"].Available)
+	assert.True(t, availability["process"].Available)
+	assert.False(t, availability["diskIO"].Available)
+	assert.Equal(t, "cgroup controller io is not enabled", availability["diskIO"].Reason)
+	assert.False(t, availability["hugetlb"].Available)
+}
+
+func TestMetricsAvailabilityCgroupV2NoControllers(t *testing.T) {
+	availability := MetricsAvailability(map[string]string{"": filepath.Join(t.TempDir(), "missing")}, true)
+
+	assert.Len(t, availability, len(metricControllers))
+	for kind, a := range availability {
+		assert.False(t, a.Available, kind)
+		assert.Contains(t, a.Reason, "failed to read the controllers of cgroup", kind)
+	}
+}
+
+func TestMetricsAvailabilityCgroupV1(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(root, "memory"), 0o755))
+	require.NoError(t, os.Mkdir(filepath.Join(root, "cpuacct"), 0o755))
+
+	availability := MetricsAvailability(map[string]string{
+		"memory":  filepath.Join(root, "memory"),
+		"cpuacct": filepath.Join(root, "cpuacct"),
+		"pids":    filepath.Join(root, "pids"),
+	}, false)
+
+	assert.True(t, availability["cpu"].Available)
+	assert.True(t, availability["memory"].Available)
+	assert.False(t, availability["process"].Available)
+	assert.Equal(t, "cgroup "+filepath.Join(root, "pids")+" does not exist", availability["process"].Reason)
+	assert.False(t, availability["diskIO"].Available)
+	assert.Equal(t, "cgroup controller blkio is not mounted", availability["diskIO"].Reason)
+}
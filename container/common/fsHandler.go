@@ -35,6 +35,18 @@ type FsUsage struct {
 	BaseUsageBytes  uint64
 	TotalUsageBytes uint64
 	InodeUsage      uint64
+	// Capacity of the filesystem as seen from the rootfs directory, lower
+	// than the capacity of the filesystem under a project quota.
+	RootfsCapacityBytes uint64
+}
+
+// Quota returns the project quota of the rootfs directory, given the capacity
+// of its filesystem, or zero if there is none.
+func (u FsUsage) Quota(fsCapacity uint64) uint64 {
+	if u.RootfsCapacityBytes == 0 || u.RootfsCapacityBytes >= fsCapacity {
+		return 0
+	}
+	return u.RootfsCapacityBytes
 }
 
 type realFsHandler struct {
@@ -75,10 +87,18 @@ func (fh *realFsHandler) update() error {
 	var (
 		rootUsage, extraUsage fs.UsageInfo
 		rootErr, extraErr     error
+		rootCapacity          uint64
 	)
 	// TODO(vishh): Add support for external mounts.
 	if fh.rootfs != "" {
 		rootUsage, rootErr = fh.fsInfo.GetDirUsage(fh.rootfs)
+		// The capacity only tells the quota, if any: failures are not
+		// worth reporting.
+		capacity, err := fs.GetDirCapacity(fh.rootfs)
+		if err != nil {
+			klog.V(5).Infof("fs: unable to get the capacity of %q: %v", fh.rootfs, err)
+		}
+		rootCapacity = capacity
 	}
 
 	if fh.extraDir != "" {
@@ -94,6 +114,7 @@ func (fh *realFsHandler) update() error {
 		fh.usage.InodeUsage = rootUsage.Inodes
 		fh.usage.BaseUsageBytes = rootUsage.Bytes
 		fh.usage.TotalUsageBytes = rootUsage.Bytes
+		fh.usage.RootfsCapacityBytes = rootCapacity
 	}
 	if fh.extraDir != "" && extraErr == nil {
 		if fh.rootfs != "" {
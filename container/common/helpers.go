@@ -97,6 +97,8 @@ func getSpecInternal(cgroupPaths map[string]string, machineInfoFactory info.Mach
 		spec.CreationTime = lowestTime
 	}
 
+	spec.CgroupPath = cgroupPath(cgroupPaths, cgroup2UnifiedMode)
+
 	// Get machine info.
 	mi, err := machineInfoFactory.GetMachineInfo()
 	if err != nil {
@@ -149,7 +151,7 @@ func getSpecInternal(cgroupPaths map[string]string, machineInfoFactory info.Mach
 		}
 	}
 
-	// Cpu Mask.
+	// Cpu Mask and memory nodes, the effective ones on cgroup v2.
 	// This will fail for non-unified hierarchies. We'll return the whole machine mask in that case.
 	cpusetRoot, ok := GetControllerPath(cgroupPaths, "cpuset", cgroup2UnifiedMode)
 	if ok {
@@ -158,8 +160,10 @@ func getSpecInternal(cgroupPaths map[string]string, machineInfoFactory info.Mach
 			mask := ""
 			if cgroup2UnifiedMode {
 				mask = readString(cpusetRoot, "cpuset.cpus.effective")
+				spec.Cpu.Mems = readString(cpusetRoot, "cpuset.mems.effective")
 			} else {
 				mask = readString(cpusetRoot, "cpuset.cpus")
+				spec.Cpu.Mems = readString(cpusetRoot, "cpuset.mems")
 			}
 			spec.Cpu.Mask = utils.FixCpuMask(mask, mi.NumCores)
 		}
@@ -214,6 +218,8 @@ func getSpecInternal(cgroupPaths map[string]string, machineInfoFactory info.Mach
 		spec.HasDiskIo = true
 	}
 
+	spec.MetricsAvailability = MetricsAvailability(cgroupPaths, cgroup2UnifiedMode)
+
 	return spec, nil
 }
 
@@ -230,14 +236,31 @@ func GetControllerPath(cgroupPaths map[string]string, controllerName string, cgr
 	return path, ok
 }
 
+// cgroupPath returns the path identifying the cgroup of a container: the
+// unified path on cgroup v2, the path of the cpu or memory hierarchy on v1.
+func cgroupPath(cgroupPaths map[string]string, cgroup2UnifiedMode bool) string {
+	if cgroup2UnifiedMode {
+		return cgroupPaths[""]
+	}
+	for _, controller := range []string{"cpu", "memory"} {
+		if p, ok := cgroupPaths[controller]; ok {
+			return p
+		}
+	}
+	return ""
+}
+
 func readString(dirpath string, file string) string {
 	cgroupFile := path.Join(dirpath, file)
 
 	// Read
 	out, err := os.ReadFile(cgroupFile)
 	if err != nil {
-		// Ignore non-existent files
-		if !os.IsNotExist(err) {
+		// Ignore non-existent files. Files cAdvisor has no permission to read
+		// are flagged in the metrics availability of the spec.
+		if IsPermissionError(err) {
+			klog.V(4).Infof("readString: Failed to read %q: %s", cgroupFile, err)
+		} else if !os.IsNotExist(err) {
 			klog.Warningf("readString: Failed to read %q: %s", cgroupFile, err)
 		}
 		return ""
@@ -416,6 +439,10 @@ func (n *MachineInfoNamer) DeviceName(major, minor uint64) (string, bool) {
 			return info.Device, true
 		}
 	}
+	// The block devices also list the loop devices.
+	if info, ok := n.BlockDevices[fmt.Sprintf("%d:%d", major, minor)]; ok {
+		return info.Device, true
+	}
 	return "", false
 }
 
@@ -131,12 +131,15 @@ func TestGetSpecCgroupV1(t *testing.T) {
 	assert.EqualValues(t, spec.Cpu.Quota, 20000)
 
 	assert.EqualValues(t, spec.Cpu.Mask, "0-5")
+	assert.EqualValues(t, spec.Cpu.Mems, "0")
 
 	assert.True(t, spec.HasProcesses)
 	assert.EqualValues(t, spec.Processes.Limit, 1027)
 
 	assert.False(t, spec.HasHugetlb)
 	assert.False(t, spec.HasDiskIo)
+
+	assert.Equal(t, cgroupPaths["cpu"], spec.CgroupPath)
 }
 
 func TestGetSpecCgroupV2(t *testing.T) {
@@ -163,12 +166,15 @@ func TestGetSpecCgroupV2(t *testing.T) {
 	assert.EqualValues(t, spec.Cpu.Quota, 20000)
 
 	assert.EqualValues(t, spec.Cpu.Mask, "0-5")
+	assert.EqualValues(t, spec.Cpu.Mems, "0-1")
 
 	assert.True(t, spec.HasProcesses)
 	assert.EqualValues(t, spec.Processes.Limit, 1027)
 
 	assert.False(t, spec.HasHugetlb)
 	assert.True(t, spec.HasDiskIo)
+
+	assert.Equal(t, cgroupPaths[""], spec.CgroupPath)
 }
 
 func TestGetSpecCgroupV2Max(t *testing.T) {
@@ -247,3 +253,21 @@ func TestRemoveNetMetrics(t *testing.T) {
 		}
 	}
 }
+
+func TestAssignDeviceNamesToDiskStats(t *testing.T) {
+	machineInfo := &info.MachineInfo{
+		DiskMap: map[string]info.DiskInfo{"8:0": {Name: "sda", Major: 8, Minor: 0}},
+		BlockDevices: map[string]info.BlockDevice{
+			"8:0": {Major: 8, Minor: 0, Device: "/dev/sda"},
+			"7:0": {Major: 7, Minor: 0, Device: "/dev/loop0", BackingFile: "/var/lib/images/disk.img"},
+		},
+	}
+	stats := info.DiskIoStats{
+		IoServiceBytes: []info.PerDiskStats{{Major: 8, Minor: 0}, {Major: 7, Minor: 0}, {Major: 9, Minor: 0}},
+	}
+	AssignDeviceNamesToDiskStats((*MachineInfoNamer)(machineInfo), &stats)
+	assert.Equal(t, "/dev/sda", stats.IoServiceBytes[0].Device)
+	// The loop devices are not in the disk map.
+	assert.Equal(t, "/dev/loop0", stats.IoServiceBytes[1].Device)
+	assert.Equal(t, "", stats.IoServiceBytes[2].Device)
+}
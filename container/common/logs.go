@@ -0,0 +1,92 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+
+	"k8s.io/klog/v2"
+)
+
+// LogFiles tracks the size of the log file of a container along with the
+// files it was rotated to, which share its name as a prefix, e.g.
+// <id>-json.log.1 for the json-file logging driver of Docker or
+// 0.log.20230501-100000.gz for the kubelet.
+type LogFiles struct {
+	dir  string
+	name string
+	// Usage at the previous update, to compute the growth rate.
+	lastUsage uint64
+	lastTime  time.Time
+}
+
+// NewLogFiles returns the log files of a container whose log file is at path,
+// or nil if path is empty, e.g. when the container logs to journald.
+func NewLogFiles(path string) *LogFiles {
+	if path == "" {
+		return nil
+	}
+	return &LogFiles{dir: filepath.Dir(path), name: filepath.Base(path)}
+}
+
+// Usage returns the number of bytes used by the log files.
+func (l *LogFiles) Usage() (uint64, error) {
+	entries, err := os.ReadDir(l.dir)
+	if err != nil {
+		return 0, err
+	}
+	var usage uint64
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || !strings.HasPrefix(entry.Name(), l.name) {
+			continue
+		}
+		fi, err := entry.Info()
+		if err != nil {
+			// The file was rotated away since the directory was read.
+			continue
+		}
+		usage += uint64(fi.Size())
+	}
+	return usage, nil
+}
+
+// UpdateStats sets the log usage and growth rate of the writable layer of the
+// container, the first filesystem of stats.
+func (l *LogFiles) UpdateStats(stats *info.ContainerStats) {
+	if len(stats.Filesystem) == 0 {
+		return
+	}
+	usage, err := l.Usage()
+	if err != nil {
+		klog.V(4).Infof("Unable to get the usage of the log files in %q: %v", l.dir, err)
+		return
+	}
+	now := time.Now()
+	fsStats := &stats.Filesystem[0]
+	fsStats.LogUsage = usage
+	if !l.lastTime.IsZero() {
+		if elapsed := now.Sub(l.lastTime).Seconds(); elapsed > 0 {
+			// The usage goes down when the oldest rotated file is removed.
+			fsStats.LogGrowthRate = (float64(usage) - float64(l.lastUsage)) / elapsed
+		}
+	}
+	l.lastUsage = usage
+	l.lastTime = now
+}
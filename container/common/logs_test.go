@@ -0,0 +1,64 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+)
+
+func TestLogFiles(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "abc-json.log")
+	write := func(name string, size int) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), make([]byte, size), 0o644))
+	}
+	write("abc-json.log", 100)
+	write("abc-json.log.1", 1000)
+	write("abc-json.log.2.gz", 10)
+	// Neither the files of other containers nor the directories are counted.
+	write("def-json.log", 5000)
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "abc-json.log.d"), 0o755))
+
+	assert.Nil(t, NewLogFiles(""))
+	l := NewLogFiles(logPath)
+	usage, err := l.Usage()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1110), usage)
+
+	stats := &info.ContainerStats{Filesystem: []info.FsStats{{Device: "/dev/sda1"}}}
+	l.UpdateStats(stats)
+	assert.Equal(t, uint64(1110), stats.Filesystem[0].LogUsage)
+	assert.Zero(t, stats.Filesystem[0].LogGrowthRate)
+
+	write("abc-json.log", 300)
+	l.lastTime = l.lastTime.Add(-2 * time.Second)
+	stats = &info.ContainerStats{Filesystem: []info.FsStats{{Device: "/dev/sda1"}}}
+	l.UpdateStats(stats)
+	assert.Equal(t, uint64(1310), stats.Filesystem[0].LogUsage)
+	assert.InDelta(t, 100, stats.Filesystem[0].LogGrowthRate, 1)
+
+	// Nothing is set without the stats of the writable layer.
+	stats = &info.ContainerStats{}
+	l.UpdateStats(stats)
+	assert.Empty(t, stats.Filesystem)
+}
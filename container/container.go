@@ -38,6 +38,25 @@ const (
 	ContainerTypePodman
 )
 
+func (t ContainerType) String() string {
+	switch t {
+	case ContainerTypeRaw:
+		return "raw"
+	case ContainerTypeDocker:
+		return "docker"
+	case ContainerTypeCrio:
+		return "crio"
+	case ContainerTypeContainerd:
+		return "containerd"
+	case ContainerTypeMesos:
+		return "mesos"
+	case ContainerTypePodman:
+		return "podman"
+	default:
+		return "unknown"
+	}
+}
+
 // Interface for container operation handlers.
 type ContainerHandler interface {
 	// Returns the ContainerReference
@@ -77,3 +96,21 @@ type ContainerHandler interface {
 	// Type of handler
 	Type() ContainerType
 }
+
+// DisabledMetrics is a source of metrics of a container no longer read after
+// its reads kept failing or taking too long.
+type DisabledMetrics struct {
+	// Kind of the metrics read from the source.
+	Kind MetricKind
+	// Source of the metrics, e.g. a cgroup controller or a file of /proc.
+	Source string
+	// Why the source is no longer read.
+	Reason string
+}
+
+// MetricsDisabler is implemented by the container handlers which stop
+// reading the sources of metrics whose reads keep failing or taking too long.
+type MetricsDisabler interface {
+	// Returns the sources of metrics no longer read for the container.
+	DisabledMetrics() []DisabledMetrics
+}
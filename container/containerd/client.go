@@ -31,27 +31,41 @@ import (
 	"github.com/yidoyoon/cadvisor-lite/container/containerd/errdefs"
 	"github.com/yidoyoon/cadvisor-lite/container/containerd/pkg/dialer"
 	containersapi "github.com/yidoyoon/cadvisor-lite/third_party/containerd/api/services/containers/v1"
+	contentapi "github.com/yidoyoon/cadvisor-lite/third_party/containerd/api/services/content/v1"
+	eventsapi "github.com/yidoyoon/cadvisor-lite/third_party/containerd/api/services/events/v1"
 	tasksapi "github.com/yidoyoon/cadvisor-lite/third_party/containerd/api/services/tasks/v1"
 	versionapi "github.com/yidoyoon/cadvisor-lite/third_party/containerd/api/services/version/v1"
 	tasktypes "github.com/yidoyoon/cadvisor-lite/third_party/containerd/api/types/task"
+	"github.com/yidoyoon/cadvisor-lite/utils/apicache"
 )
 
 type client struct {
 	containerService containersapi.ContainersClient
 	taskService      tasksapi.TasksClient
 	versionService   versionapi.VersionClient
+	contentService   contentapi.ContentClient
+	eventsService    eventsapi.EventsClient
 }
 
 type ContainerdClient interface {
 	LoadContainer(ctx context.Context, id string) (*containers.Container, error)
 	TaskPid(ctx context.Context, id string) (uint32, error)
 	Version(ctx context.Context) (string, error)
+	// ContentStatuses returns the statuses of the writes to the content
+	// store in progress, e.g. of the blobs of the images being pulled.
+	ContentStatuses(ctx context.Context) ([]contentapi.Status, error)
+	// Subscribe returns the stream of the events matching one of the
+	// filters, until ctx is done.
+	Subscribe(ctx context.Context, filters ...string) (eventsapi.Events_SubscribeClient, error)
 }
 
 var (
 	ErrTaskIsInUnknownState = errors.New("containerd task is in unknown state") // used when process reported in containerd task is in Unknown State
 )
 
+// Cache of the container lookups.
+var loadContainerCache = apicache.New("containerd_load_container")
+
 var once sync.Once
 var ctrdClient ContainerdClient = nil
 
@@ -102,19 +116,29 @@ func Client(address, namespace string) (ContainerdClient, error) {
 			containerService: containersapi.NewContainersClient(conn),
 			taskService:      tasksapi.NewTasksClient(conn),
 			versionService:   versionapi.NewVersionClient(conn),
+			contentService:   contentapi.NewContentClient(conn),
+			eventsService:    eventsapi.NewEventsClient(conn),
 		}
 	})
 	return ctrdClient, retErr
 }
 
+// LoadContainer returns a container. The result is shared between callers and
+// must not be modified.
 func (c *client) LoadContainer(ctx context.Context, id string) (*containers.Container, error) {
-	r, err := c.containerService.Get(ctx, &containersapi.GetContainerRequest{
-		ID: id,
+	cntr, err := loadContainerCache.Get(ctx, id, func(ctx context.Context) (interface{}, error) {
+		r, err := c.containerService.Get(ctx, &containersapi.GetContainerRequest{
+			ID: id,
+		})
+		if err != nil {
+			return nil, errdefs.FromGRPC(err)
+		}
+		return containerFromProto(r.Container), nil
 	})
 	if err != nil {
-		return nil, errdefs.FromGRPC(err)
+		return nil, err
 	}
-	return containerFromProto(r.Container), nil
+	return cntr.(*containers.Container), nil
 }
 
 func (c *client) TaskPid(ctx context.Context, id string) (uint32, error) {
@@ -138,6 +162,22 @@ func (c *client) Version(ctx context.Context) (string, error) {
 	return response.Version, nil
 }
 
+func (c *client) ContentStatuses(ctx context.Context) ([]contentapi.Status, error) {
+	response, err := c.contentService.ListStatuses(ctx, &contentapi.ListStatusesRequest{})
+	if err != nil {
+		return nil, errdefs.FromGRPC(err)
+	}
+	return response.Statuses, nil
+}
+
+func (c *client) Subscribe(ctx context.Context, filters ...string) (eventsapi.Events_SubscribeClient, error) {
+	stream, err := c.eventsService.Subscribe(ctx, &eventsapi.SubscribeRequest{Filters: filters})
+	if err != nil {
+		return nil, errdefs.FromGRPC(err)
+	}
+	return stream, nil
+}
+
 func containerFromProto(containerpb containersapi.Container) *containers.Container {
 	var runtime containers.RuntimeInfo
 	if containerpb.Runtime != nil {
@@ -19,6 +19,8 @@ import (
 	"fmt"
 
 	"github.com/yidoyoon/cadvisor-lite/container/containerd/containers"
+	contentapi "github.com/yidoyoon/cadvisor-lite/third_party/containerd/api/services/content/v1"
+	eventsapi "github.com/yidoyoon/cadvisor-lite/third_party/containerd/api/services/events/v1"
 )
 
 type containerdClientMock struct {
@@ -45,6 +47,14 @@ func (c *containerdClientMock) TaskPid(ctx context.Context, id string) (uint32,
 	return 2389, nil
 }
 
+func (c *containerdClientMock) ContentStatuses(ctx context.Context) ([]contentapi.Status, error) {
+	return nil, c.returnErr
+}
+
+func (c *containerdClientMock) Subscribe(ctx context.Context, filters ...string) (eventsapi.Events_SubscribeClient, error) {
+	return nil, fmt.Errorf("events are not supported by the mock")
+}
+
 func mockcontainerdClient(cntrs map[string]*containers.Container, returnErr error) ContainerdClient {
 	return &containerdClientMock{
 		cntrs:     cntrs,
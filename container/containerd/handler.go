@@ -180,6 +180,9 @@ func (h *containerdContainerHandler) GetSpec() (info.ContainerSpec, error) {
 	spec.Labels = h.labels
 	spec.Envs = h.envs
 	spec.Image = h.image
+	spec.Security = h.libcontainerHandler.GetSecuritySpec()
+	spec.Runtime = container.ContainerTypeContainerd.String()
+	spec.RuntimeId = h.reference.Id
 
 	return spec, err
 }
@@ -231,6 +234,10 @@ func (h *containerdContainerHandler) ListProcesses(listType container.ListType)
 	return h.libcontainerHandler.GetProcesses()
 }
 
+func (h *containerdContainerHandler) DisabledMetrics() []container.DisabledMetrics {
+	return h.libcontainerHandler.DisabledMetrics()
+}
+
 func (h *containerdContainerHandler) Exists() bool {
 	return common.CgroupExists(h.cgroupPaths)
 }
@@ -0,0 +1,119 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package containerd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	v2 "github.com/yidoyoon/cadvisor-lite/info/v2"
+	eventtypes "github.com/yidoyoon/cadvisor-lite/third_party/containerd/api/events"
+	contentapi "github.com/yidoyoon/cadvisor-lite/third_party/containerd/api/services/content/v1"
+)
+
+// Topic of the events of the images created, e.g. when pulled.
+const imageCreateTopic = "/images/create"
+
+// ImagePulls returns the downloads of the images being pulled by containerd,
+// in the namespace of --containerd-namespace.
+func ImagePulls() (*v2.ImagePulls, error) {
+	client, err := Client(*ArgContainerdEndpoint, *ArgContainerdNamespace)
+	if err != nil {
+		return nil, fmt.Errorf("unable to communicate with containerd: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), connectionTimeout)
+	defer cancel()
+	statuses, err := client.ContentStatuses(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return imagePullsFromStatuses(statuses, time.Now()), nil
+}
+
+// imagePullsFromStatuses returns the downloads of the writes to the content
+// store, sorted by start time.
+func imagePullsFromStatuses(statuses []contentapi.Status, now time.Time) *v2.ImagePulls {
+	pulls := &v2.ImagePulls{Timestamp: now, Downloads: make([]v2.BlobDownload, 0, len(statuses))}
+	for _, status := range statuses {
+		// The refs of the blobs fetched from registries are made of their
+		// kind and digest, e.g. layer-sha256:<digest>.
+		download := v2.BlobDownload{
+			Ref:       status.Ref,
+			Digest:    string(status.Expected),
+			Layer:     strings.HasPrefix(status.Ref, "layer-"),
+			StartedAt: status.StartedAt,
+			UpdatedAt: status.UpdatedAt,
+		}
+		if status.Offset > 0 {
+			download.BytesDownloaded = uint64(status.Offset)
+		}
+		if status.Total > 0 {
+			download.BytesTotal = uint64(status.Total)
+		}
+		pulls.BytesDownloaded += download.BytesDownloaded
+		pulls.BytesTotal += download.BytesTotal
+		if download.Layer {
+			pulls.LayersRemaining++
+		}
+		pulls.Downloads = append(pulls.Downloads, download)
+	}
+	sort.SliceStable(pulls.Downloads, func(i, j int) bool {
+		return pulls.Downloads[i].StartedAt.Before(pulls.Downloads[j].StartedAt)
+	})
+	return pulls
+}
+
+// WatchImagePulls calls f with the reference of each image created in the
+// namespace of --containerd-namespace, e.g. pulled by the CRI plugin, until
+// ctx is done or the connection to containerd fails.
+func WatchImagePulls(ctx context.Context, f func(image string, timestamp time.Time)) error {
+	client, err := Client(*ArgContainerdEndpoint, *ArgContainerdNamespace)
+	if err != nil {
+		return fmt.Errorf("unable to communicate with containerd: %v", err)
+	}
+	stream, err := client.Subscribe(ctx, fmt.Sprintf("topic==%q", imageCreateTopic))
+	if err != nil {
+		return err
+	}
+	for {
+		envelope, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		if image, ok := pulledImage(envelope.Topic, envelope.Event.GetValue()); ok {
+			f(image, envelope.Timestamp)
+		}
+	}
+}
+
+// pulledImage returns the reference of the image created by an event. The CRI
+// plugin also creates an image named after the digest of the config of the
+// image, which is not reported.
+func pulledImage(topic string, event []byte) (string, bool) {
+	if topic != imageCreateTopic {
+		return "", false
+	}
+	var create eventtypes.ImageCreate
+	if err := create.Unmarshal(event); err != nil || create.Name == "" {
+		return "", false
+	}
+	if strings.HasPrefix(create.Name, "sha256:") {
+		return "", false
+	}
+	return create.Name, true
+}
@@ -0,0 +1,90 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package containerd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	v2 "github.com/yidoyoon/cadvisor-lite/info/v2"
+	eventtypes "github.com/yidoyoon/cadvisor-lite/third_party/containerd/api/events"
+	contentapi "github.com/yidoyoon/cadvisor-lite/third_party/containerd/api/services/content/v1"
+)
+
+func TestImagePullsFromStatuses(t *testing.T) {
+	now := time.Unix(1000, 0)
+	statuses := []contentapi.Status{
+		{
+			Ref:       "layer-sha256:b",
+			Expected:  "sha256:b",
+			Offset:    10,
+			Total:     200,
+			StartedAt: time.Unix(900, 0),
+			UpdatedAt: time.Unix(990, 0),
+		},
+		{
+			Ref:       "manifest-sha256:m",
+			Expected:  "sha256:m",
+			Offset:    1,
+			StartedAt: time.Unix(800, 0),
+			UpdatedAt: time.Unix(800, 0),
+		},
+		{
+			Ref:       "layer-sha256:a",
+			Expected:  "sha256:a",
+			Offset:    50,
+			Total:     100,
+			StartedAt: time.Unix(850, 0),
+			UpdatedAt: time.Unix(995, 0),
+		},
+	}
+
+	expected := &v2.ImagePulls{
+		Timestamp: now,
+		Downloads: []v2.BlobDownload{
+			{Ref: "manifest-sha256:m", Digest: "sha256:m", BytesDownloaded: 1, StartedAt: time.Unix(800, 0), UpdatedAt: time.Unix(800, 0)},
+			{Ref: "layer-sha256:a", Digest: "sha256:a", Layer: true, BytesDownloaded: 50, BytesTotal: 100, StartedAt: time.Unix(850, 0), UpdatedAt: time.Unix(995, 0)},
+			{Ref: "layer-sha256:b", Digest: "sha256:b", Layer: true, BytesDownloaded: 10, BytesTotal: 200, StartedAt: time.Unix(900, 0), UpdatedAt: time.Unix(990, 0)},
+		},
+		LayersRemaining: 2,
+		BytesDownloaded: 61,
+		BytesTotal:      300,
+	}
+	assert.Equal(t, expected, imagePullsFromStatuses(statuses, now))
+	assert.Equal(t, &v2.ImagePulls{Timestamp: now, Downloads: []v2.BlobDownload{}}, imagePullsFromStatuses(nil, now))
+}
+
+func TestPulledImage(t *testing.T) {
+	event := func(name string) []byte {
+		data, err := (&eventtypes.ImageCreate{Name: name}).Marshal()
+		require.NoError(t, err)
+		return data
+	}
+
+	image, ok := pulledImage(imageCreateTopic, event("docker.io/library/nginx:latest"))
+	assert.True(t, ok)
+	assert.Equal(t, "docker.io/library/nginx:latest", image)
+
+	// The images named after their config digest are not reported.
+	_, ok = pulledImage(imageCreateTopic, event("sha256:0123"))
+	assert.False(t, ok)
+	_, ok = pulledImage("/images/update", event("docker.io/library/nginx:latest"))
+	assert.False(t, ok)
+	_, ok = pulledImage(imageCreateTopic, []byte("invalid"))
+	assert.False(t, ok)
+}
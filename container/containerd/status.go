@@ -0,0 +1,43 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package containerd
+
+import (
+	"context"
+	"fmt"
+
+	v2 "github.com/yidoyoon/cadvisor-lite/info/v2"
+)
+
+// RuntimeStatus returns the status of containerd common to all the runtimes.
+// containerd does not report its root directory, storage driver nor numbers
+// of containers and images.
+func RuntimeStatus() (*v2.RuntimeStatus, error) {
+	status := &v2.RuntimeStatus{Name: "containerd", Socket: *ArgContainerdEndpoint, NumContainers: -1, NumImages: -1}
+	client, err := Client(*ArgContainerdEndpoint, *ArgContainerdNamespace)
+	if err != nil {
+		return status, fmt.Errorf("unable to communicate with containerd: %v", err)
+	}
+	if client == nil {
+		return status, fmt.Errorf("unable to communicate with containerd")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), connectionTimeout)
+	defer cancel()
+	status.Version, err = client.Version(ctx)
+	if err != nil {
+		return status, err
+	}
+	return status, nil
+}
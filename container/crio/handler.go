@@ -61,6 +61,9 @@ type crioContainerHandler struct {
 	// Filesystem handler.
 	fsHandler common.FsHandler
 
+	// Log file of the container and the files the kubelet rotated it to.
+	logFiles *common.LogFiles
+
 	// The IP address of the container
 	ipAddress string
 
@@ -193,6 +196,7 @@ func newCrioContainerHandler(
 	// we optionally collect disk usage metrics
 	if includedMetrics.Has(container.DiskUsageMetrics) {
 		handler.fsHandler = common.NewFsHandler(common.DefaultPeriod, rootfsStorageDir, storageLogDir, fsInfo)
+		handler.logFiles = common.NewLogFiles(storageLogDir)
 	}
 	// TODO for env vars we wanted to show from container.Config.Env from whitelist
 	//for _, exposedEnv := range metadataEnvAllowList {
@@ -226,6 +230,9 @@ func (h *crioContainerHandler) GetSpec() (info.ContainerSpec, error) {
 	spec.Labels = h.labels
 	spec.Envs = h.envs
 	spec.Image = h.image
+	spec.Security = h.libcontainerHandler.GetSecuritySpec()
+	spec.Runtime = container.ContainerTypeCrio.String()
+	spec.RuntimeId = h.reference.Id
 
 	return spec, err
 }
@@ -277,8 +284,12 @@ func (h *crioContainerHandler) getFsStats(stats *info.ContainerStats) error {
 	fsStat.BaseUsage = usage.BaseUsageBytes
 	fsStat.Usage = usage.TotalUsageBytes
 	fsStat.Inodes = usage.InodeUsage
+	fsStat.Quota = usage.Quota(limit)
 
 	stats.Filesystem = append(stats.Filesystem, fsStat)
+	if h.logFiles != nil {
+		h.logFiles.UpdateStats(stats)
+	}
 
 	return nil
 }
@@ -353,6 +364,10 @@ func (h *crioContainerHandler) ListProcesses(listType container.ListType) ([]int
 	return h.libcontainerHandler.GetProcesses()
 }
 
+func (h *crioContainerHandler) DisabledMetrics() []container.DisabledMetrics {
+	return h.libcontainerHandler.DisabledMetrics()
+}
+
 func (h *crioContainerHandler) Exists() bool {
 	return common.CgroupExists(h.cgroupPaths)
 }
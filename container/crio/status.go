@@ -0,0 +1,38 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crio
+
+import (
+	"fmt"
+
+	v2 "github.com/yidoyoon/cadvisor-lite/info/v2"
+)
+
+// RuntimeStatus returns the status of CRI-O common to all the runtimes. CRI-O
+// does not report its version nor numbers of containers and images.
+func RuntimeStatus() (*v2.RuntimeStatus, error) {
+	status := &v2.RuntimeStatus{Name: "crio", Socket: CrioSocket, NumContainers: -1, NumImages: -1}
+	client, err := Client()
+	if err != nil {
+		return status, fmt.Errorf("unable to communicate with crio: %v", err)
+	}
+	info, err := client.Info()
+	if err != nil {
+		return status, err
+	}
+	status.RootDir = info.StorageRoot
+	status.StorageDriver = info.StorageDriver
+	return status, nil
+}
@@ -29,8 +29,18 @@ var (
 	dockerClient     *dclient.Client
 	dockerClientErr  error
 	dockerClientOnce sync.Once
+
+	// Wraps the transport of the client, if set.
+	transportWrapper func(http.RoundTripper) http.RoundTripper
 )
 
+// SetTransportWrapper sets a function wrapping the transport of the client
+// returned by Client, e.g. to observe the responses of the Docker API. It
+// must be called before the client is created.
+func SetTransportWrapper(wrapper func(http.RoundTripper) http.RoundTripper) {
+	transportWrapper = wrapper
+}
+
 // Client creates a Docker API client based on the given Docker flags
 func Client() (*dclient.Client, error) {
 	dockerClientOnce.Do(func() {
@@ -56,6 +66,22 @@ func Client() (*dclient.Client, error) {
 			dclient.WithHost(*ArgDockerEndpoint),
 			dclient.WithHTTPClient(client),
 			dclient.WithAPIVersionNegotiation())
+		if dockerClientErr != nil || transportWrapper == nil {
+			return
+		}
+		// The transport is configured for the host by the client. The
+		// scheme is no longer inferred from the wrapped transport.
+		scheme := "http"
+		if *ArgDockerTLS {
+			scheme = "https"
+		}
+		client = dockerClient.HTTPClient()
+		client.Transport = transportWrapper(client.Transport)
+		dockerClient, dockerClientErr = dclient.NewClientWithOpts(
+			dclient.WithHost(*ArgDockerEndpoint),
+			dclient.WithHTTPClient(client),
+			dclient.WithScheme(scheme),
+			dclient.WithAPIVersionNegotiation())
 	})
 	return dockerClient, dockerClientErr
 }
@@ -22,15 +22,61 @@ import (
 	"time"
 
 	dockertypes "github.com/docker/docker/api/types"
+	dockerimage "github.com/docker/docker/api/types/image"
+	dclient "github.com/docker/docker/client"
 	"golang.org/x/net/context"
 
 	"github.com/yidoyoon/cadvisor-lite/container/docker/utils"
 	v1 "github.com/yidoyoon/cadvisor-lite/info/v1"
+	v2 "github.com/yidoyoon/cadvisor-lite/info/v2"
 	"github.com/yidoyoon/cadvisor-lite/machine"
+	"github.com/yidoyoon/cadvisor-lite/utils/apicache"
 )
 
 var dockerTimeout = 10 * time.Second
 
+// Caches of the Docker API calls.
+var (
+	infoCache    = apicache.New("docker_info")
+	versionCache = apicache.New("docker_version")
+	imagesCache  = apicache.New("docker_image_list")
+	inspectCache = apicache.New("docker_inspect")
+	dfCache      = apicache.New("docker_disk_usage")
+	historyCache = apicache.New("docker_image_history")
+)
+
+func cachedInfo(ctx context.Context, client *dclient.Client) (dockertypes.Info, error) {
+	info, err := infoCache.Get(ctx, "", func(ctx context.Context) (interface{}, error) {
+		return client.Info(ctx)
+	})
+	if err != nil {
+		return dockertypes.Info{}, err
+	}
+	return info.(dockertypes.Info), nil
+}
+
+func cachedVersion(ctx context.Context, client *dclient.Client) (dockertypes.Version, error) {
+	version, err := versionCache.Get(ctx, "", func(ctx context.Context) (interface{}, error) {
+		return client.ServerVersion(ctx)
+	})
+	if err != nil {
+		return dockertypes.Version{}, err
+	}
+	return version.(dockertypes.Version), nil
+}
+
+// Returns the inspection of a container. The result is shared between callers
+// and must not be modified.
+func inspectContainer(client *dclient.Client, id string) (dockertypes.ContainerJSON, error) {
+	ctnr, err := inspectCache.Get(context.Background(), id, func(ctx context.Context) (interface{}, error) {
+		return client.ContainerInspect(ctx, id)
+	})
+	if err != nil {
+		return dockertypes.ContainerJSON{}, err
+	}
+	return ctnr.(dockertypes.ContainerJSON), nil
+}
+
 func defaultContext() context.Context {
 	ctx, _ := context.WithTimeout(context.Background(), dockerTimeout)
 	return ctx
@@ -49,7 +95,7 @@ func StatusWithContext(ctx context.Context) (v1.DockerStatus, error) {
 	if err != nil {
 		return v1.DockerStatus{}, fmt.Errorf("unable to communicate with docker daemon: %v", err)
 	}
-	dockerInfo, err := client.Info(ctx)
+	dockerInfo, err := cachedInfo(ctx, client)
 	if err != nil {
 		return v1.DockerStatus{}, err
 	}
@@ -57,6 +103,24 @@ func StatusWithContext(ctx context.Context) (v1.DockerStatus, error) {
 }
 
 func StatusFromDockerInfo(dockerInfo dockertypes.Info) (v1.DockerStatus, error) {
+	out := StatusFromInfo(dockerInfo)
+	var err error
+	ver, err := VersionString()
+	if err != nil {
+		return out, err
+	}
+	out.Version = ver
+	ver, err = APIVersionString()
+	if err != nil {
+		return out, err
+	}
+	out.APIVersion = ver
+	return out, nil
+}
+
+// StatusFromInfo returns the status described by the info of a Docker API
+// compatible runtime, without the versions.
+func StatusFromInfo(dockerInfo dockertypes.Info) v1.DockerStatus {
 	out := v1.DockerStatus{}
 	out.KernelVersion = machine.KernelVersion()
 	out.OS = dockerInfo.OperatingSystem
@@ -69,30 +133,45 @@ func StatusFromDockerInfo(dockerInfo dockertypes.Info) (v1.DockerStatus, error)
 	for _, v := range dockerInfo.DriverStatus {
 		out.DriverStatus[v[0]] = v[1]
 	}
-	var err error
-	ver, err := VersionString()
+	return out
+}
+
+func Images() ([]v1.DockerImage, error) {
+	client, err := Client()
 	if err != nil {
-		return out, err
+		return nil, fmt.Errorf("unable to communicate with docker daemon: %v", err)
 	}
-	out.Version = ver
-	ver, err = APIVersionString()
+	summaries, err := imagesCache.Get(context.Background(), "", func(context.Context) (interface{}, error) {
+		return client.ImageList(defaultContext(), dockertypes.ImageListOptions{All: false})
+	})
 	if err != nil {
-		return out, err
+		return nil, err
 	}
-	out.APIVersion = ver
-	return out, nil
+	return utils.SummariesToImages(summaries.([]dockertypes.ImageSummary))
 }
 
-func Images() ([]v1.DockerImage, error) {
+// ImagesUsage returns the disk usage and the layers of all images.
+func ImagesUsage() ([]v1.DockerImageUsage, error) {
 	client, err := Client()
 	if err != nil {
 		return nil, fmt.Errorf("unable to communicate with docker daemon: %v", err)
 	}
-	summaries, err := client.ImageList(defaultContext(), dockertypes.ImageListOptions{All: false})
+	du, err := dfCache.Get(context.Background(), "", func(context.Context) (interface{}, error) {
+		return client.DiskUsage(defaultContext())
+	})
 	if err != nil {
 		return nil, err
 	}
-	return utils.SummariesToImages(summaries)
+	history := func(id string) ([]dockerimage.HistoryResponseItem, error) {
+		items, err := historyCache.Get(context.Background(), id, func(context.Context) (interface{}, error) {
+			return client.ImageHistory(defaultContext(), id)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return items.([]dockerimage.HistoryResponseItem), nil
+	}
+	return utils.DiskUsageToImageUsage(du.(dockertypes.DiskUsage), history), nil
 }
 
 // Checks whether the dockerInfo reflects a valid docker setup, and returns it if it does, or an
@@ -134,7 +213,7 @@ func Info() (*dockertypes.Info, error) {
 		return nil, fmt.Errorf("unable to communicate with docker daemon: %v", err)
 	}
 
-	dockerInfo, err := client.Info(defaultContext())
+	dockerInfo, err := cachedInfo(defaultContext(), client)
 	if err != nil {
 		return nil, fmt.Errorf("failed to detect Docker info: %v", err)
 	}
@@ -154,7 +233,7 @@ func VersionString() (string, error) {
 	dockerVersion := "Unknown"
 	client, err := Client()
 	if err == nil {
-		version, err := client.ServerVersion(defaultContext())
+		version, err := cachedVersion(defaultContext(), client)
 		if err == nil {
 			dockerVersion = version.Version
 		}
@@ -166,7 +245,7 @@ func APIVersionString() (string, error) {
 	apiVersion := "Unknown"
 	client, err := Client()
 	if err == nil {
-		version, err := client.ServerVersion(defaultContext())
+		version, err := cachedVersion(defaultContext(), client)
 		if err == nil {
 			apiVersion = version.APIVersion
 		}
@@ -190,3 +269,25 @@ func ParseVersion(versionString string, regex *regexp.Regexp, length int) ([]int
 	}
 	return versionArray, nil
 }
+
+// RuntimeStatus returns the status of Docker common to all the runtimes.
+func RuntimeStatus() (*v2.RuntimeStatus, error) {
+	status := &v2.RuntimeStatus{Name: "docker", Socket: *ArgDockerEndpoint, NumContainers: -1, NumImages: -1}
+	dockerStatus, err := Status()
+	if err != nil {
+		return status, err
+	}
+	return RuntimeStatusFromDockerStatus(status, dockerStatus), nil
+}
+
+// RuntimeStatusFromDockerStatus fills status with the status of a Docker API
+// compatible runtime.
+func RuntimeStatusFromDockerStatus(status *v2.RuntimeStatus, dockerStatus v1.DockerStatus) *v2.RuntimeStatus {
+	status.Version = dockerStatus.Version
+	status.APIVersion = dockerStatus.APIVersion
+	status.RootDir = dockerStatus.RootDir
+	status.StorageDriver = dockerStatus.Driver
+	status.NumContainers = dockerStatus.NumContainers
+	status.NumImages = dockerStatus.NumImages
+	return status
+}
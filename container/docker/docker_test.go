@@ -18,6 +18,9 @@ import (
 	"reflect"
 	"regexp"
 	"testing"
+
+	v1 "github.com/yidoyoon/cadvisor-lite/info/v1"
+	v2 "github.com/yidoyoon/cadvisor-lite/info/v2"
 )
 
 func TestParseDockerAPIVersion(t *testing.T) {
@@ -49,3 +52,12 @@ func TestParseDockerAPIVersion(t *testing.T) {
 		}
 	}
 }
+
+func TestRuntimeStatusFromDockerStatus(t *testing.T) {
+	status := &v2.RuntimeStatus{Name: "docker", Socket: "unix:///var/run/docker.sock", NumContainers: -1, NumImages: -1}
+	dockerStatus := v1.DockerStatus{Version: "24.0.5", APIVersion: "1.43", RootDir: "/var/lib/docker", Driver: "overlay2", NumContainers: 3, NumImages: 5, OS: "Ubuntu"}
+	expected := &v2.RuntimeStatus{Name: "docker", Socket: "unix:///var/run/docker.sock", Version: "24.0.5", APIVersion: "1.43", RootDir: "/var/lib/docker", StorageDriver: "overlay2", NumContainers: 3, NumImages: 5}
+	if actual := RuntimeStatusFromDockerStatus(status, dockerStatus); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("RuntimeStatusFromDockerStatus() = %+v, expected %+v", actual, expected)
+	}
+}
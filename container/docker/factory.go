@@ -37,7 +37,6 @@ import (
 	"github.com/yidoyoon/cadvisor-lite/zfs"
 
 	docker "github.com/docker/docker/client"
-	"golang.org/x/net/context"
 	"k8s.io/klog/v2"
 )
 
@@ -175,7 +174,7 @@ func (f *dockerFactory) CanHandleAndAccept(name string) (bool, bool, error) {
 	id := dockerutil.ContainerNameToId(name)
 
 	// We assume that if Inspect fails then the container is not known to docker.
-	ctnr, err := f.client.ContainerInspect(context.Background(), id)
+	ctnr, err := inspectContainer(f.client, id)
 	if err != nil || !ctnr.State.Running {
 		return false, true, fmt.Errorf("error inspecting container: %v", err)
 	}
@@ -74,6 +74,7 @@ func FsStats(
 					BaseUsage: usage.BaseUsageBytes,
 					Usage:     usage.TotalUsageBytes,
 					Inodes:    usage.InodeUsage,
+					Quota:     usage.Quota(fs.Capacity),
 				}
 				fileSystems, err := globalFsInfo.GetGlobalFsInfo()
 				if err != nil {
@@ -97,22 +98,26 @@ func addDiskStats(fileSystems []fs.Fs, fsInfo *info.FsInfo, fsStats *info.FsStat
 	for _, fileSys := range fileSystems {
 		if fsInfo.DeviceMajor == fileSys.DiskStats.Major &&
 			fsInfo.DeviceMinor == fileSys.DiskStats.Minor {
-			fsStats.ReadsCompleted = fileSys.DiskStats.ReadsCompleted
-			fsStats.ReadsMerged = fileSys.DiskStats.ReadsMerged
-			fsStats.SectorsRead = fileSys.DiskStats.SectorsRead
-			fsStats.ReadTime = fileSys.DiskStats.ReadTime
-			fsStats.WritesCompleted = fileSys.DiskStats.WritesCompleted
-			fsStats.WritesMerged = fileSys.DiskStats.WritesMerged
-			fsStats.SectorsWritten = fileSys.DiskStats.SectorsWritten
-			fsStats.WriteTime = fileSys.DiskStats.WriteTime
-			fsStats.IoInProgress = fileSys.DiskStats.IoInProgress
-			fsStats.IoTime = fileSys.DiskStats.IoTime
-			fsStats.WeightedIoTime = fileSys.DiskStats.WeightedIoTime
+			setDiskStats(fileSys.DiskStats, fsStats)
 			break
 		}
 	}
 }
 
+func setDiskStats(diskStats fs.DiskStats, fsStats *info.FsStats) {
+	fsStats.ReadsCompleted = diskStats.ReadsCompleted
+	fsStats.ReadsMerged = diskStats.ReadsMerged
+	fsStats.SectorsRead = diskStats.SectorsRead
+	fsStats.ReadTime = diskStats.ReadTime
+	fsStats.WritesCompleted = diskStats.WritesCompleted
+	fsStats.WritesMerged = diskStats.WritesMerged
+	fsStats.SectorsWritten = diskStats.SectorsWritten
+	fsStats.WriteTime = diskStats.WriteTime
+	fsStats.IoInProgress = diskStats.IoInProgress
+	fsStats.IoTime = diskStats.IoTime
+	fsStats.WeightedIoTime = diskStats.WeightedIoTime
+}
+
 // FsHandler is a composite FsHandler implementation the incorporates
 // the common fs handler, a devicemapper ThinPoolWatcher, and a zfsWatcher
 type FsHandler struct {
@@ -0,0 +1,106 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	dockertypes "github.com/docker/docker/api/types"
+	"k8s.io/klog/v2"
+
+	"github.com/yidoyoon/cadvisor-lite/container/docker/utils"
+	v2 "github.com/yidoyoon/cadvisor-lite/info/v2"
+)
+
+// GCAdvice returns the stopped containers and unused images whose removal
+// reclaims reclaim bytes, or as many as possible if reclaim is zero.
+func GCAdvice(reclaim int64) (*v2.GCAdvice, error) {
+	client, err := Client()
+	if err != nil {
+		return nil, fmt.Errorf("unable to communicate with docker daemon: %v", err)
+	}
+	du, err := dfCache.Get(context.Background(), "", func(context.Context) (interface{}, error) {
+		return client.DiskUsage(defaultContext())
+	})
+	if err != nil {
+		return nil, err
+	}
+	advice := utils.DiskUsageToGCAdvice(du.(dockertypes.DiskUsage), reclaim)
+	advice.Timestamp = time.Now()
+	return &advice, nil
+}
+
+// CollectGarbage removes the candidates of the advice for reclaim, read from
+// the current disk usage, and returns the advice with the outcome of each
+// removal. Removals fail without forcing anything, e.g. if a container was
+// started since the disk usage was read.
+func CollectGarbage(reclaim int64) (*v2.GCAdvice, error) {
+	client, err := Client()
+	if err != nil {
+		return nil, fmt.Errorf("unable to communicate with docker daemon: %v", err)
+	}
+	du, err := client.DiskUsage(defaultContext())
+	if err != nil {
+		return nil, err
+	}
+	advice := utils.DiskUsageToGCAdvice(du, reclaim)
+	advice.Timestamp = time.Now()
+	removeContainer := func(id string) error {
+		return client.ContainerRemove(defaultContext(), id, dockertypes.ContainerRemoveOptions{})
+	}
+	removeImage := func(ref string) error {
+		_, err := client.ImageRemove(defaultContext(), ref, dockertypes.ImageRemoveOptions{PruneChildren: true})
+		return err
+	}
+	removeCandidates(&advice, removeContainer, removeImage)
+	return &advice, nil
+}
+
+// removeCandidates removes the candidates of advice in order. Images are
+// removed by tag, so that removing an image with several tags does not need
+// to be forced, and the image is deleted along with its last tag. Images
+// without tags are removed by ID.
+func removeCandidates(advice *v2.GCAdvice, removeContainer, removeImage func(string) error) {
+	advice.Executed = true
+	for i := range advice.Candidates {
+		candidate := &advice.Candidates[i]
+		var err error
+		switch candidate.Kind {
+		case v2.GCContainer:
+			err = removeContainer(candidate.ID)
+		case v2.GCImage:
+			refs := candidate.Names
+			if len(refs) == 0 {
+				refs = []string{candidate.ID}
+			}
+			for _, ref := range refs {
+				if err = removeImage(ref); err != nil {
+					break
+				}
+			}
+		default:
+			err = fmt.Errorf("unknown kind %q", candidate.Kind)
+		}
+		if err != nil {
+			klog.Warningf("Failed to remove %s %s: %v", candidate.Kind, candidate.ID, err)
+			candidate.Error = err.Error()
+			continue
+		}
+		candidate.Removed = true
+		advice.ReclaimedBytes += candidate.Size
+	}
+}
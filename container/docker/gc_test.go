@@ -0,0 +1,61 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	v2 "github.com/yidoyoon/cadvisor-lite/info/v2"
+)
+
+func TestRemoveCandidates(t *testing.T) {
+	advice := &v2.GCAdvice{
+		ReclaimableBytes: 111,
+		Candidates: []v2.GCCandidate{
+			{Kind: v2.GCContainer, ID: "1", Size: 1},
+			{Kind: v2.GCContainer, ID: "2", Size: 10},
+			{Kind: v2.GCImage, ID: "sha256:a", Names: []string{"a:1", "a:latest"}, Size: 100},
+			{Kind: v2.GCImage, ID: "sha256:b", Size: 1000},
+		},
+	}
+	var removed []string
+	removeContainer := func(id string) error {
+		if id == "2" {
+			return fmt.Errorf("container is running")
+		}
+		removed = append(removed, id)
+		return nil
+	}
+	removeImage := func(ref string) error {
+		if ref == "sha256:b" {
+			return fmt.Errorf("image is being used")
+		}
+		removed = append(removed, ref)
+		return nil
+	}
+	removeCandidates(advice, removeContainer, removeImage)
+
+	assert.True(t, advice.Executed)
+	assert.Equal(t, []string{"1", "a:1", "a:latest"}, removed)
+	assert.Equal(t, int64(101), advice.ReclaimedBytes)
+	assert.True(t, advice.Candidates[0].Removed)
+	assert.Equal(t, "container is running", advice.Candidates[1].Error)
+	assert.False(t, advice.Candidates[1].Removed)
+	assert.True(t, advice.Candidates[2].Removed)
+	assert.Equal(t, "image is being used", advice.Candidates[3].Error)
+}
@@ -19,9 +19,6 @@ import (
 	"fmt"
 	"os"
 	"path"
-	"strconv"
-	"strings"
-	"time"
 
 	"github.com/opencontainers/runc/libcontainer/cgroups"
 	"github.com/yidoyoon/cadvisor-lite/container"
@@ -34,7 +31,6 @@ import (
 	"github.com/yidoyoon/cadvisor-lite/zfs"
 
 	docker "github.com/docker/docker/client"
-	"golang.org/x/net/context"
 )
 
 const (
@@ -60,21 +56,18 @@ type dockerContainerHandler struct {
 	fsInfo           fs.FsInfo
 	rootfsStorageDir string
 
-	// Time at which this container was created.
-	creationTime time.Time
-
-	// Metadata associated with the container.
-	envs   map[string]string
-	labels map[string]string
-
-	// Image name used for this container.
-	image string
+	// Metadata of the container from its inspection.
+	metadata Metadata
 
 	// Filesystem handler.
 	fsHandler common.FsHandler
 
-	// The IP address of the container
-	ipAddress string
+	// Named volumes and bind mounts of the container.
+	volumes *Volumes
+
+	// Log files of the container, nil if its logging driver does not write
+	// to a file.
+	logFiles *common.LogFiles
 
 	includedMetrics container.MetricSet
 
@@ -84,9 +77,6 @@ type dockerContainerHandler struct {
 	// zfsParent is the parent for docker zfs
 	zfsParent string
 
-	// Reference to the container
-	reference info.ContainerReference
-
 	libcontainerHandler *containerlibcontainer.Handler
 }
 
@@ -160,7 +150,7 @@ func newDockerContainerHandler(
 	}
 
 	// We assume that if Inspect fails then the container is not known to docker.
-	ctnr, err := client.ContainerInspect(context.Background(), id)
+	ctnr, err := inspectContainer(client, id)
 	if err != nil {
 		return nil, fmt.Errorf("failed to inspect container %q: %v", id, err)
 	}
@@ -176,48 +166,15 @@ func newDockerContainerHandler(
 		storageDriver:      storageDriver,
 		poolName:           thinPoolName,
 		rootfsStorageDir:   rootfsStorageDir,
-		envs:               make(map[string]string),
-		labels:             ctnr.Config.Labels,
 		includedMetrics:    metrics,
 		zfsParent:          zfsParent,
 	}
-	// Timestamp returned by Docker is in time.RFC3339Nano format.
-	handler.creationTime, err = time.Parse(time.RFC3339Nano, ctnr.Created)
+	handler.metadata, err = newMetadata(client, name, ctnr, metadataEnvAllowList)
 	if err != nil {
-		// This should not happen, report the error just in case
-		return nil, fmt.Errorf("failed to parse the create timestamp %q for container %q: %v", ctnr.Created, id, err)
+		return nil, err
 	}
 	handler.libcontainerHandler = containerlibcontainer.NewHandler(cgroupManager, rootFs, ctnr.State.Pid, metrics)
 
-	// Add the name and bare ID as aliases of the container.
-	handler.reference = info.ContainerReference{
-		Id:        id,
-		Name:      name,
-		Aliases:   []string{strings.TrimPrefix(ctnr.Name, "/"), id},
-		Namespace: DockerNamespace,
-	}
-	handler.image = ctnr.Config.Image
-	// Only adds restartcount label if it's greater than 0
-	if ctnr.RestartCount > 0 {
-		handler.labels["restartcount"] = strconv.Itoa(ctnr.RestartCount)
-	}
-
-	// Obtain the IP address for the container.
-	// If the NetworkMode starts with 'container:' then we need to use the IP address of the container specified.
-	// This happens in cases such as kubernetes where the containers doesn't have an IP address itself and we need to use the pod's address
-	ipAddress := ctnr.NetworkSettings.IPAddress
-	networkMode := string(ctnr.HostConfig.NetworkMode)
-	if ipAddress == "" && strings.HasPrefix(networkMode, "container:") {
-		containerID := strings.TrimPrefix(networkMode, "container:")
-		c, err := client.ContainerInspect(context.Background(), containerID)
-		if err != nil {
-			return nil, fmt.Errorf("failed to inspect container %q: %v", id, err)
-		}
-		ipAddress = c.NetworkSettings.IPAddress
-	}
-
-	handler.ipAddress = ipAddress
-
 	if includedMetrics.Has(container.DiskUsageMetrics) {
 		handler.fsHandler = &FsHandler{
 			FsHandler:       common.NewFsHandler(common.DefaultPeriod, rootfsStorageDir, otherStorageDir, fsInfo),
@@ -226,22 +183,11 @@ func newDockerContainerHandler(
 			DeviceID:        ctnr.GraphDriver.Data["DeviceId"],
 			ZfsFilesystem:   zfsFilesystem,
 		}
-	}
-
-	// split env vars to get metadata map.
-	for _, exposedEnv := range metadataEnvAllowList {
-		if exposedEnv == "" {
-			// if no dockerEnvWhitelist provided, len(metadataEnvAllowList) == 1, metadataEnvAllowList[0] == ""
-			continue
-		}
-
-		for _, envVar := range ctnr.Config.Env {
-			if envVar != "" {
-				splits := strings.SplitN(envVar, "=", 2)
-				if len(splits) == 2 && strings.HasPrefix(splits[0], exposedEnv) {
-					handler.envs[strings.ToLower(splits[0])] = splits[1]
-				}
-			}
+		handler.volumes = NewVolumes(ctnr.Mounts, rootFs, fsInfo)
+		// Docker only reports the path of the log file for the json-file
+		// logging driver.
+		if ctnr.LogPath != "" {
+			handler.logFiles = common.NewLogFiles(path.Join(rootFs, ctnr.LogPath))
 		}
 	}
 
@@ -275,16 +221,22 @@ func (h *dockerContainerHandler) Start() {
 	if h.fsHandler != nil {
 		h.fsHandler.Start()
 	}
+	if h.volumes != nil {
+		h.volumes.Start()
+	}
 }
 
 func (h *dockerContainerHandler) Cleanup() {
 	if h.fsHandler != nil {
 		h.fsHandler.Stop()
 	}
+	if h.volumes != nil {
+		h.volumes.Stop()
+	}
 }
 
 func (h *dockerContainerHandler) ContainerReference() (info.ContainerReference, error) {
-	return h.reference, nil
+	return h.metadata.Reference, nil
 }
 
 func (h *dockerContainerHandler) GetSpec() (info.ContainerSpec, error) {
@@ -292,10 +244,8 @@ func (h *dockerContainerHandler) GetSpec() (info.ContainerSpec, error) {
 	hasNetwork := h.includedMetrics.Has(container.NetworkUsageMetrics)
 	spec, err := common.GetSpec(h.cgroupPaths, h.machineInfoFactory, hasNetwork, hasFilesystem)
 
-	spec.Labels = h.labels
-	spec.Envs = h.envs
-	spec.Image = h.image
-	spec.CreationTime = h.creationTime
+	h.metadata.UpdateSpec(&spec)
+	spec.Security = h.libcontainerHandler.GetSecuritySpec()
 
 	return spec, err
 }
@@ -313,6 +263,16 @@ func (h *dockerContainerHandler) GetStats() (*info.ContainerStats, error) {
 	if err != nil {
 		return stats, err
 	}
+	if h.logFiles != nil {
+		h.logFiles.UpdateStats(stats)
+	}
+
+	if h.volumes != nil {
+		stats.Volumes, err = h.volumes.Stats()
+		if err != nil {
+			return stats, err
+		}
+	}
 
 	return stats, nil
 }
@@ -329,23 +289,27 @@ func (h *dockerContainerHandler) GetCgroupPath(resource string) (string, error)
 	}
 	path, ok := h.cgroupPaths[res]
 	if !ok {
-		return "", fmt.Errorf("could not find path for resource %q for container %q", resource, h.reference.Name)
+		return "", fmt.Errorf("could not find path for resource %q for container %q", resource, h.metadata.Reference.Name)
 	}
 	return path, nil
 }
 
 func (h *dockerContainerHandler) GetContainerLabels() map[string]string {
-	return h.labels
+	return h.metadata.Labels
 }
 
 func (h *dockerContainerHandler) GetContainerIPAddress() string {
-	return h.ipAddress
+	return h.metadata.IPAddress
 }
 
 func (h *dockerContainerHandler) ListProcesses(listType container.ListType) ([]int, error) {
 	return h.libcontainerHandler.GetProcesses()
 }
 
+func (h *dockerContainerHandler) DisabledMetrics() []container.DisabledMetrics {
+	return h.libcontainerHandler.DisabledMetrics()
+}
+
 func (h *dockerContainerHandler) Exists() bool {
 	return common.CgroupExists(h.cgroupPaths)
 }
@@ -0,0 +1,166 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yidoyoon/cadvisor-lite/container"
+	dockerutil "github.com/yidoyoon/cadvisor-lite/container/docker/utils"
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+
+	dockertypes "github.com/docker/docker/api/types"
+	docker "github.com/docker/docker/client"
+	units "github.com/docker/go-units"
+	"k8s.io/klog/v2"
+)
+
+// Metadata is the metadata of a docker container reported by its handler,
+// from the inspection of the container.
+type Metadata struct {
+	// Reference to the container.
+	Reference info.ContainerReference
+
+	// Time at which this container was created.
+	CreationTime time.Time
+	// Time at which this container was last started, zero if unknown.
+	StartedAt time.Time
+
+	Envs   map[string]string
+	Labels map[string]string
+
+	// Image name used for this container.
+	Image string
+
+	// Size of the writable layer set by the size storage option, zero if
+	// unset.
+	DiskQuota uint64
+
+	// The IP address of the container
+	IPAddress string
+
+	// The ports of the container published on the host
+	Ports []info.PortMapping
+}
+
+// InspectMetadata inspects the container name with client and returns its
+// metadata, with the environment variables whose name starts with one of
+// metadataEnvAllowList.
+func InspectMetadata(client *docker.Client, name string, metadataEnvAllowList []string) (Metadata, error) {
+	id := dockerutil.ContainerNameToId(name)
+	ctnr, err := inspectContainer(client, id)
+	if err != nil {
+		return Metadata{}, fmt.Errorf("failed to inspect container %q: %v", id, err)
+	}
+	return newMetadata(client, name, ctnr, metadataEnvAllowList)
+}
+
+func newMetadata(client *docker.Client, name string, ctnr dockertypes.ContainerJSON, metadataEnvAllowList []string) (Metadata, error) {
+	id := dockerutil.ContainerNameToId(name)
+	m := Metadata{
+		Envs:   make(map[string]string),
+		Labels: make(map[string]string, len(ctnr.Config.Labels)),
+	}
+	// Timestamp returned by Docker is in time.RFC3339Nano format.
+	var err error
+	m.CreationTime, err = time.Parse(time.RFC3339Nano, ctnr.Created)
+	if err != nil {
+		// This should not happen, report the error just in case
+		return m, fmt.Errorf("failed to parse the create timestamp %q for container %q: %v", ctnr.Created, id, err)
+	}
+	// Docker reports the zero time for containers which never started.
+	if ctnr.State != nil {
+		m.StartedAt, _ = time.Parse(time.RFC3339Nano, ctnr.State.StartedAt)
+	}
+
+	// Add the name and bare ID as aliases of the container.
+	m.Reference = info.ContainerReference{
+		Id:        id,
+		Name:      name,
+		Aliases:   []string{strings.TrimPrefix(ctnr.Name, "/"), id},
+		Namespace: DockerNamespace,
+	}
+	m.Image = ctnr.Config.Image
+	if size, ok := ctnr.HostConfig.StorageOpt["size"]; ok {
+		quota, err := units.RAMInBytes(size)
+		if err != nil {
+			klog.Warningf("Invalid size storage option %q of container %q: %v", size, id, err)
+		} else {
+			m.DiskQuota = uint64(quota)
+		}
+	}
+	// Copy the labels, the inspection is shared.
+	for k, v := range ctnr.Config.Labels {
+		m.Labels[k] = v
+	}
+	// Only adds restartcount label if it's greater than 0
+	if ctnr.RestartCount > 0 {
+		m.Labels["restartcount"] = strconv.Itoa(ctnr.RestartCount)
+	}
+
+	// Obtain the IP address for the container.
+	// If the NetworkMode starts with 'container:' then we need to use the IP address of the container specified.
+	// This happens in cases such as kubernetes where the containers doesn't have an IP address itself and we need to use the pod's address
+	// The ports are published by the container owning the namespace as well.
+	ipAddress := ctnr.NetworkSettings.IPAddress
+	ports := ctnr.NetworkSettings.Ports
+	networkMode := string(ctnr.HostConfig.NetworkMode)
+	if ipAddress == "" && strings.HasPrefix(networkMode, "container:") {
+		containerID := strings.TrimPrefix(networkMode, "container:")
+		c, err := inspectContainer(client, containerID)
+		if err != nil {
+			return m, fmt.Errorf("failed to inspect container %q: %v", id, err)
+		}
+		ipAddress = c.NetworkSettings.IPAddress
+		ports = c.NetworkSettings.Ports
+	}
+
+	m.IPAddress = ipAddress
+	m.Ports = dockerutil.PortMappings(ports)
+
+	// split env vars to get metadata map.
+	for _, exposedEnv := range metadataEnvAllowList {
+		if exposedEnv == "" {
+			// if no dockerEnvWhitelist provided, len(metadataEnvAllowList) == 1, metadataEnvAllowList[0] == ""
+			continue
+		}
+
+		for _, envVar := range ctnr.Config.Env {
+			if envVar != "" {
+				splits := strings.SplitN(envVar, "=", 2)
+				if len(splits) == 2 && strings.HasPrefix(splits[0], exposedEnv) {
+					m.Envs[strings.ToLower(splits[0])] = splits[1]
+				}
+			}
+		}
+	}
+	return m, nil
+}
+
+// UpdateSpec sets the fields of spec reported from the metadata.
+func (m *Metadata) UpdateSpec(spec *info.ContainerSpec) {
+	spec.Labels = m.Labels
+	spec.Envs = m.Envs
+	spec.Image = m.Image
+	spec.CreationTime = m.CreationTime
+	spec.StartedAt = m.StartedAt
+	spec.DiskQuota = m.DiskQuota
+	spec.Ports = m.Ports
+	spec.Runtime = container.ContainerTypeDocker.String()
+	spec.RuntimeId = m.Reference.Id
+}
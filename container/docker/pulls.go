@@ -0,0 +1,45 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	dockertypes "github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+)
+
+// WatchImagePulls calls f with the reference of each image pulled by docker,
+// until ctx is done or the connection to docker fails. Docker only reports
+// the pulls once completed.
+func WatchImagePulls(ctx context.Context, f func(image string, timestamp time.Time)) error {
+	client, err := Client()
+	if err != nil {
+		return fmt.Errorf("unable to communicate with docker daemon: %v", err)
+	}
+	messages, errs := client.Events(ctx, dockertypes.EventsOptions{
+		Filters: filters.NewArgs(filters.Arg("type", "image"), filters.Arg("event", "pull")),
+	})
+	for {
+		select {
+		case m := <-messages:
+			f(m.Actor.ID, time.Unix(0, m.TimeNano))
+		case err := <-errs:
+			return err
+		}
+	}
+}
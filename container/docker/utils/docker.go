@@ -19,10 +19,15 @@ import (
 	"os"
 	"path"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 
 	dockertypes "github.com/docker/docker/api/types"
+	dockerimage "github.com/docker/docker/api/types/image"
+	"github.com/docker/go-connections/nat"
 	v1 "github.com/yidoyoon/cadvisor-lite/info/v1"
+	v2 "github.com/yidoyoon/cadvisor-lite/info/v2"
 )
 
 const (
@@ -103,6 +108,194 @@ func SummariesToImages(summaries []dockertypes.ImageSummary) ([]v1.DockerImage,
 	return out, nil
 }
 
+// DiskUsageToImageUsage returns the disk usage of the images of du. The layers
+// of each image are obtained with history, images whose history fails are
+// reported without layers.
+func DiskUsageToImageUsage(du dockertypes.DiskUsage, history func(id string) ([]dockerimage.HistoryResponseItem, error)) []v1.DockerImageUsage {
+	running := make(map[string][]string)
+	for _, c := range du.Containers {
+		if c == nil || c.State != "running" {
+			continue
+		}
+		name := c.ID
+		if len(c.Names) > 0 {
+			name = strings.TrimPrefix(c.Names[0], "/")
+		}
+		running[c.ImageID] = append(running[c.ImageID], name)
+	}
+
+	out := make([]v1.DockerImageUsage, 0, len(du.Images))
+	for _, summary := range du.Images {
+		if summary == nil {
+			continue
+		}
+		usage := v1.DockerImageUsage{
+			DockerImage: v1.DockerImage{
+				ID:          summary.ID,
+				RepoTags:    summary.RepoTags,
+				Created:     summary.Created,
+				VirtualSize: summary.VirtualSize,
+				Size:        summary.Size,
+			},
+			SharedSize:        summary.SharedSize,
+			UniqueSize:        -1,
+			NumContainers:     summary.Containers,
+			RunningContainers: running[summary.ID],
+		}
+		if summary.SharedSize >= 0 {
+			usage.UniqueSize = summary.Size - summary.SharedSize
+		}
+		if items, err := history(summary.ID); err == nil {
+			for _, item := range items {
+				usage.Layers = append(usage.Layers, v1.DockerImageLayer{
+					ID:        item.ID,
+					Created:   item.Created,
+					CreatedBy: item.CreatedBy,
+					Size:      item.Size,
+				})
+			}
+		}
+		out = append(out, usage)
+	}
+	return out
+}
+
+// States of the containers which are not running and may be removed.
+var stoppedStates = map[string]bool{"created": true, "exited": true, "dead": true}
+
+// DiskUsageToGCAdvice returns the stopped containers of du, oldest first, then
+// the images no other container uses, oldest first, whose removal reclaims
+// reclaim bytes, or as many as possible if reclaim is zero. Containers are
+// listed before images because an image cannot be removed while a container
+// uses it.
+func DiskUsageToGCAdvice(du dockertypes.DiskUsage, reclaim int64) v2.GCAdvice {
+	advice := v2.GCAdvice{RequestedBytes: reclaim, Candidates: []v2.GCCandidate{}}
+	done := func() bool {
+		return reclaim > 0 && advice.ReclaimableBytes >= reclaim
+	}
+
+	var stopped []*dockertypes.Container
+	for _, c := range du.Containers {
+		if c != nil && stoppedStates[c.State] {
+			stopped = append(stopped, c)
+		}
+	}
+	sort.Slice(stopped, func(i, j int) bool {
+		if stopped[i].Created != stopped[j].Created {
+			return stopped[i].Created < stopped[j].Created
+		}
+		return stopped[i].ID < stopped[j].ID
+	})
+	removed := make(map[string]bool)
+	for _, c := range stopped {
+		if done() {
+			break
+		}
+		names := make([]string, 0, len(c.Names))
+		for _, name := range c.Names {
+			names = append(names, strings.TrimPrefix(name, "/"))
+		}
+		advice.Candidates = append(advice.Candidates, v2.GCCandidate{
+			Kind:    v2.GCContainer,
+			ID:      c.ID,
+			Names:   names,
+			Created: c.Created,
+			Size:    c.SizeRw,
+		})
+		advice.ReclaimableBytes += c.SizeRw
+		removed[c.ID] = true
+	}
+
+	// Images used by the containers which are kept, and their parents,
+	// which cannot be removed while a child image exists.
+	parents := make(map[string]string)
+	for _, image := range du.Images {
+		if image != nil && image.ParentID != "" {
+			parents[image.ID] = image.ParentID
+		}
+	}
+	used := make(map[string]bool)
+	for _, c := range du.Containers {
+		if c == nil || removed[c.ID] {
+			continue
+		}
+		for id := c.ImageID; id != "" && !used[id]; id = parents[id] {
+			used[id] = true
+		}
+	}
+	var unused []*dockertypes.ImageSummary
+	for _, image := range du.Images {
+		if image != nil && !used[image.ID] {
+			unused = append(unused, image)
+		}
+	}
+	sort.Slice(unused, func(i, j int) bool {
+		if unused[i].Created != unused[j].Created {
+			return unused[i].Created < unused[j].Created
+		}
+		return unused[i].ID < unused[j].ID
+	})
+	for _, image := range unused {
+		if done() {
+			break
+		}
+		var tags []string
+		for _, tag := range image.RepoTags {
+			if tag != "<none>:<none>" {
+				tags = append(tags, tag)
+			}
+		}
+		size := image.Size
+		if image.SharedSize >= 0 {
+			size -= image.SharedSize
+		}
+		advice.Candidates = append(advice.Candidates, v2.GCCandidate{
+			Kind:    v2.GCImage,
+			ID:      image.ID,
+			Names:   tags,
+			Created: image.Created,
+			Size:    size,
+		})
+		advice.ReclaimableBytes += size
+	}
+	return advice
+}
+
+// PortMappings returns the ports published on the host of the port map of an
+// inspected container, sorted by container port, protocol, host IP and host
+// port. The exposed ports which are not published are left out.
+func PortMappings(ports nat.PortMap) []v1.PortMapping {
+	var mappings []v1.PortMapping
+	for port, bindings := range ports {
+		for _, binding := range bindings {
+			hostPort, err := strconv.ParseUint(binding.HostPort, 10, 16)
+			if err != nil {
+				continue
+			}
+			mappings = append(mappings, v1.PortMapping{
+				Protocol:      port.Proto(),
+				ContainerPort: uint16(port.Int()),
+				HostIP:        binding.HostIP,
+				HostPort:      uint16(hostPort),
+			})
+		}
+	}
+	sort.Slice(mappings, func(i, j int) bool {
+		a, b := mappings[i], mappings[j]
+		if a.ContainerPort != b.ContainerPort {
+			return a.ContainerPort < b.ContainerPort
+		}
+		if a.Protocol != b.Protocol {
+			return a.Protocol < b.Protocol
+		}
+		if a.HostIP != b.HostIP {
+			return a.HostIP < b.HostIP
+		}
+		return a.HostPort < b.HostPort
+	})
+	return mappings
+}
+
 // Returns the ID from the full container name.
 func ContainerNameToId(name string) string {
 	id := path.Base(name)
@@ -14,7 +14,18 @@
 
 package utils
 
-import "testing"
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	dockertypes "github.com/docker/docker/api/types"
+	dockerimage "github.com/docker/docker/api/types/image"
+	"github.com/docker/go-connections/nat"
+
+	v1 "github.com/yidoyoon/cadvisor-lite/info/v1"
+	v2 "github.com/yidoyoon/cadvisor-lite/info/v2"
+)
 
 func TestIsContainerName(t *testing.T) {
 	tests := []struct {
@@ -36,3 +47,143 @@ func TestIsContainerName(t *testing.T) {
 		}
 	}
 }
+
+func TestPortMappings(t *testing.T) {
+	ports := nat.PortMap{
+		"443/tcp":  {{HostIP: "0.0.0.0", HostPort: "8443"}, {HostIP: "::", HostPort: "8443"}},
+		"80/tcp":   {{HostIP: "127.0.0.1", HostPort: "8080"}},
+		"53/udp":   {{HostPort: "5353"}},
+		"53/tcp":   {{HostPort: "5353"}},
+		"9000/tcp": nil,
+	}
+	expected := []v1.PortMapping{
+		{Protocol: "tcp", ContainerPort: 53, HostPort: 5353},
+		{Protocol: "udp", ContainerPort: 53, HostPort: 5353},
+		{Protocol: "tcp", ContainerPort: 80, HostIP: "127.0.0.1", HostPort: 8080},
+		{Protocol: "tcp", ContainerPort: 443, HostIP: "0.0.0.0", HostPort: 8443},
+		{Protocol: "tcp", ContainerPort: 443, HostIP: "::", HostPort: 8443},
+	}
+	if actual := PortMappings(ports); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %+v, got %+v", expected, actual)
+	}
+	if actual := PortMappings(nil); actual != nil {
+		t.Errorf("expected no mappings, got %+v", actual)
+	}
+}
+
+func TestDiskUsageToImageUsage(t *testing.T) {
+	du := dockertypes.DiskUsage{
+		Images: []*dockertypes.ImageSummary{
+			{ID: "sha256:a", RepoTags: []string{"a:latest"}, Size: 100, SharedSize: 60, Containers: 2},
+			{ID: "sha256:b", RepoTags: []string{"<none>:<none>"}, Size: 50, SharedSize: -1},
+		},
+		Containers: []*dockertypes.Container{
+			{ID: "1", Names: []string{"/web"}, ImageID: "sha256:a", State: "running"},
+			{ID: "2", Names: []string{"/old"}, ImageID: "sha256:a", State: "exited"},
+		},
+	}
+	history := func(id string) ([]dockerimage.HistoryResponseItem, error) {
+		if id != "sha256:a" {
+			return nil, fmt.Errorf("no history for %q", id)
+		}
+		return []dockerimage.HistoryResponseItem{
+			{ID: "sha256:a", CreatedBy: "COPY app /app", Size: 40},
+			{ID: "<missing>", CreatedBy: "ADD rootfs /", Size: 60},
+		}, nil
+	}
+
+	expected := []v1.DockerImageUsage{
+		{
+			DockerImage:       v1.DockerImage{ID: "sha256:a", RepoTags: []string{"a:latest"}, Size: 100},
+			SharedSize:        60,
+			UniqueSize:        40,
+			NumContainers:     2,
+			RunningContainers: []string{"web"},
+			Layers: []v1.DockerImageLayer{
+				{ID: "sha256:a", CreatedBy: "COPY app /app", Size: 40},
+				{ID: "<missing>", CreatedBy: "ADD rootfs /", Size: 60},
+			},
+		},
+		{
+			DockerImage: v1.DockerImage{ID: "sha256:b", RepoTags: []string{"<none>:<none>"}, Size: 50},
+			SharedSize:  -1,
+			UniqueSize:  -1,
+		},
+	}
+	if actual := DiskUsageToImageUsage(du, history); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected: %+v, actual: %+v", expected, actual)
+	}
+}
+
+func TestDiskUsageToGCAdvice(t *testing.T) {
+	du := dockertypes.DiskUsage{
+		Images: []*dockertypes.ImageSummary{
+			{ID: "sha256:a", RepoTags: []string{"a:latest"}, Created: 10, Size: 100, SharedSize: 60},
+			{ID: "sha256:b", RepoTags: []string{"<none>:<none>"}, Created: 20, Size: 50, SharedSize: -1},
+			{ID: "sha256:c", RepoTags: []string{"c:1", "c:latest"}, Created: 5, Size: 80, SharedSize: 0},
+		},
+		Containers: []*dockertypes.Container{
+			{ID: "1", Names: []string{"/web"}, ImageID: "sha256:a", State: "running", Created: 100, SizeRw: 1000},
+			{ID: "2", Names: []string{"/old"}, ImageID: "sha256:b", State: "exited", Created: 300, SizeRw: 30},
+			{ID: "3", Names: []string{"/older"}, ImageID: "sha256:c", State: "dead", Created: 200, SizeRw: 20},
+			{ID: "4", Names: []string{"/paused"}, ImageID: "sha256:c", State: "paused", Created: 50, SizeRw: 5},
+		},
+	}
+	older := v2.GCCandidate{Kind: v2.GCContainer, ID: "3", Names: []string{"older"}, Created: 200, Size: 20}
+	old := v2.GCCandidate{Kind: v2.GCContainer, ID: "2", Names: []string{"old"}, Created: 300, Size: 30}
+	// Image c is still used by the paused container, image a by the
+	// running one.
+	b := v2.GCCandidate{Kind: v2.GCImage, ID: "sha256:b", Created: 20, Size: 50}
+
+	tests := []struct {
+		reclaim  int64
+		expected v2.GCAdvice
+	}{
+		{
+			reclaim:  0,
+			expected: v2.GCAdvice{ReclaimableBytes: 100, Candidates: []v2.GCCandidate{older, old, b}},
+		},
+		{
+			reclaim:  15,
+			expected: v2.GCAdvice{RequestedBytes: 15, ReclaimableBytes: 20, Candidates: []v2.GCCandidate{older}},
+		},
+		{
+			// Image b is still used by container 2, which is kept.
+			reclaim:  20,
+			expected: v2.GCAdvice{RequestedBytes: 20, ReclaimableBytes: 20, Candidates: []v2.GCCandidate{older}},
+		},
+		{
+			reclaim:  1 << 30,
+			expected: v2.GCAdvice{RequestedBytes: 1 << 30, ReclaimableBytes: 100, Candidates: []v2.GCCandidate{older, old, b}},
+		},
+	}
+	for _, test := range tests {
+		if actual := DiskUsageToGCAdvice(du, test.reclaim); !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("reclaim %d: expected: %+v, actual: %+v", test.reclaim, test.expected, actual)
+		}
+	}
+}
+
+func TestDiskUsageToGCAdviceParentImages(t *testing.T) {
+	du := dockertypes.DiskUsage{
+		Images: []*dockertypes.ImageSummary{
+			{ID: "sha256:base", RepoTags: []string{"<none>:<none>"}, Created: 1, Size: 10, SharedSize: -1},
+			{ID: "sha256:mid", ParentID: "sha256:base", RepoTags: []string{"<none>:<none>"}, Created: 2, Size: 20, SharedSize: -1},
+			{ID: "sha256:app", ParentID: "sha256:mid", RepoTags: []string{"app:latest"}, Created: 3, Size: 30, SharedSize: -1},
+			{ID: "sha256:other", ParentID: "sha256:base", RepoTags: []string{"other:latest"}, Created: 4, Size: 40, SharedSize: -1},
+		},
+		Containers: []*dockertypes.Container{
+			{ID: "1", Names: []string{"/app"}, ImageID: "sha256:app", State: "running", Created: 100, SizeRw: 1},
+		},
+	}
+	// The parents of the used image cannot be removed.
+	expected := v2.GCAdvice{
+		ReclaimableBytes: 40,
+		Candidates: []v2.GCCandidate{
+			{Kind: v2.GCImage, ID: "sha256:other", Names: []string{"other:latest"}, Created: 4, Size: 40},
+		},
+	}
+	if actual := DiskUsageToGCAdvice(du, 0); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected: %+v, actual: %+v", expected, actual)
+	}
+}
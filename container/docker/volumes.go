@@ -0,0 +1,145 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	dockertypes "github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/mount"
+	"k8s.io/klog/v2"
+
+	"github.com/yidoyoon/cadvisor-lite/container/common"
+	"github.com/yidoyoon/cadvisor-lite/fs"
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+)
+
+var volumeStatsTypes = flag.String("volume_stats_types", "volume", "Comma-separated list of the types of mounts of Docker and Podman containers whose disk usage is reported, among volume and bind. Bind mounts are not tracked by default since walking large host directories is expensive. Empty value disables volume stats.")
+
+// Volumes tracks the disk usage of the named volumes and bind mounts of a
+// Docker or Podman container.
+type Volumes struct {
+	volumes []volume
+	fsInfo  fs.FsInfo
+}
+
+type volume struct {
+	info.FsVolume
+	// Path of the volume as seen by cAdvisor.
+	dir string
+	// Device of the filesystem of the volume, empty if unknown.
+	device    string
+	fsHandler common.FsHandler
+}
+
+// NewVolumes returns the volumes of the mounts of a container whose type is
+// listed in --volume_stats_types. rootFs is the path of the root filesystem of
+// the host. Only mounts of directories are tracked.
+func NewVolumes(mounts []dockertypes.MountPoint, rootFs string, fsInfo fs.FsInfo) *Volumes {
+	types := map[mount.Type]bool{}
+	for _, t := range strings.Split(*volumeStatsTypes, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			types[mount.Type(t)] = true
+		}
+	}
+
+	v := &Volumes{fsInfo: fsInfo}
+	for _, m := range mounts {
+		if !types[m.Type] || m.Source == "" {
+			continue
+		}
+		dir := path.Join(rootFs, m.Source)
+		if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+			klog.V(4).Infof("Not tracking the usage of mount %q: not a directory", m.Source)
+			continue
+		}
+		vol := volume{
+			FsVolume: info.FsVolume{
+				Type:        string(m.Type),
+				Source:      m.Source,
+				Destination: m.Destination,
+			},
+			dir:       dir,
+			fsHandler: common.NewFsHandler(common.DefaultPeriod, dir, "", fsInfo),
+		}
+		if m.Type == mount.TypeVolume {
+			vol.Name = m.Name
+		}
+		if device, err := fsInfo.GetDirFsDevice(dir); err != nil {
+			klog.V(4).Infof("Unable to determine device info for volume %q: %v", m.Source, err)
+		} else {
+			vol.device = device.Device
+		}
+		v.volumes = append(v.volumes, vol)
+	}
+	return v
+}
+
+func (v *Volumes) Start() {
+	for _, vol := range v.volumes {
+		vol.fsHandler.Start()
+	}
+}
+
+func (v *Volumes) Stop() {
+	for _, vol := range v.volumes {
+		vol.fsHandler.Stop()
+	}
+}
+
+// Stats returns the stats of the volumes: their usage, the capacity of their
+// filesystem and the IO stats of its device.
+func (v *Volumes) Stats() ([]info.FsStats, error) {
+	if len(v.volumes) == 0 {
+		return nil, nil
+	}
+	fileSystems, err := v.fsInfo.GetGlobalFsInfo()
+	if err != nil {
+		return nil, fmt.Errorf("unable to obtain filesystems of volumes: %v", err)
+	}
+
+	stats := make([]info.FsStats, 0, len(v.volumes))
+	for i := range v.volumes {
+		vol := &v.volumes[i]
+		usage := vol.fsHandler.Usage()
+		stat := info.FsStats{
+			Device: vol.device,
+			Usage:  usage.TotalUsageBytes,
+			Inodes: usage.InodeUsage,
+			Volume: &vol.FsVolume,
+		}
+		for _, fileSys := range fileSystems {
+			if vol.device == "" || fileSys.Device != vol.device {
+				continue
+			}
+			stat.Type = fileSys.Type.String()
+			stat.Limit = fileSys.Capacity
+			stat.Available = fileSys.Available
+			stat.Quota = usage.Quota(fileSys.Capacity)
+			if fileSys.InodesFree != nil {
+				stat.HasInodes = true
+				stat.InodesFree = *fileSys.InodesFree
+			}
+			setDiskStats(fileSys.DiskStats, &stat)
+			break
+		}
+		stats = append(stats, stat)
+	}
+	return stats, nil
+}
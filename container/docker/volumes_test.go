@@ -0,0 +1,141 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	dockertypes "github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yidoyoon/cadvisor-lite/container/common"
+	"github.com/yidoyoon/cadvisor-lite/fs"
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+)
+
+type volumesFsInfo struct {
+	fs.FsInfo
+	fileSystems []fs.Fs
+}
+
+func (f volumesFsInfo) GetGlobalFsInfo() ([]fs.Fs, error) {
+	return f.fileSystems, nil
+}
+
+func (f volumesFsInfo) GetDirFsDevice(_ string) (*fs.DeviceInfo, error) {
+	return &fs.DeviceInfo{Device: "/dev/sda1", Major: 8, Minor: 1}, nil
+}
+
+type volumeFsHandler struct {
+	usage common.FsUsage
+}
+
+func (h volumeFsHandler) Start() {}
+
+func (h volumeFsHandler) Stop() {}
+
+func (h volumeFsHandler) Usage() common.FsUsage {
+	return h.usage
+}
+
+func TestNewVolumes(t *testing.T) {
+	rootFs := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(rootFs, "var/lib/docker/volumes/db/_data"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(rootFs, "srv/www"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(rootFs, "docker.sock"), nil, 0644))
+
+	mounts := []dockertypes.MountPoint{
+		{Type: mount.TypeVolume, Name: "db", Source: "/var/lib/docker/volumes/db/_data", Destination: "/var/lib/postgresql/data"},
+		{Type: mount.TypeBind, Source: "/srv/www", Destination: "/usr/share/nginx/html"},
+		{Type: mount.TypeBind, Source: "/docker.sock", Destination: "/var/run/docker.sock"},
+		{Type: mount.TypeTmpfs, Destination: "/tmp"},
+		{Type: mount.TypeBind, Source: "/missing", Destination: "/missing"},
+	}
+	// Only the named volumes are tracked by default.
+	volumes := NewVolumes(mounts, rootFs, volumesFsInfo{})
+	require.Len(t, volumes.volumes, 1)
+	assert.Equal(t, "db", volumes.volumes[0].Name)
+
+	defer func(types string) { *volumeStatsTypes = types }(*volumeStatsTypes)
+	*volumeStatsTypes = "volume,bind"
+	volumes = NewVolumes(mounts, rootFs, volumesFsInfo{})
+	var actual []info.FsVolume
+	for _, vol := range volumes.volumes {
+		actual = append(actual, vol.FsVolume)
+		assert.Equal(t, "/dev/sda1", vol.device)
+		assert.Equal(t, filepath.Join(rootFs, vol.Source), vol.dir)
+	}
+	assert.Equal(t, []info.FsVolume{
+		{Name: "db", Type: "volume", Source: "/var/lib/docker/volumes/db/_data", Destination: "/var/lib/postgresql/data"},
+		{Type: "bind", Source: "/srv/www", Destination: "/usr/share/nginx/html"},
+	}, actual)
+}
+
+func TestVolumesStats(t *testing.T) {
+	inodesFree := uint64(1000)
+	fsInfo := volumesFsInfo{
+		fileSystems: []fs.Fs{{
+			DeviceInfo: fs.DeviceInfo{Device: "/dev/sda1", Major: 8, Minor: 1},
+			Type:       fs.VFS,
+			Capacity:   100 << 30,
+			Available:  40 << 30,
+			InodesFree: &inodesFree,
+			DiskStats:  fs.DiskStats{ReadsCompleted: 10, WritesCompleted: 20},
+		}},
+	}
+	db := info.FsVolume{Name: "db", Type: "volume", Source: "/var/lib/docker/volumes/db/_data", Destination: "/data"}
+	volumes := &Volumes{
+		fsInfo: fsInfo,
+		volumes: []volume{
+			{
+				FsVolume:  db,
+				device:    "/dev/sda1",
+				fsHandler: volumeFsHandler{usage: common.FsUsage{TotalUsageBytes: 5 << 30, InodeUsage: 300, RootfsCapacityBytes: 10 << 30}},
+			},
+			{
+				FsVolume:  info.FsVolume{Type: "bind", Source: "/srv", Destination: "/srv"},
+				fsHandler: volumeFsHandler{usage: common.FsUsage{TotalUsageBytes: 1024, InodeUsage: 2}},
+			},
+		},
+	}
+
+	stats, err := volumes.Stats()
+	require.NoError(t, err)
+	assert.Equal(t, []info.FsStats{
+		{
+			Device:          "/dev/sda1",
+			Type:            "vfs",
+			Limit:           100 << 30,
+			Usage:           5 << 30,
+			Available:       40 << 30,
+			Quota:           10 << 30,
+			HasInodes:       true,
+			Inodes:          300,
+			InodesFree:      1000,
+			ReadsCompleted:  10,
+			WritesCompleted: 20,
+			Volume:          &db,
+		},
+		{
+			Usage:  1024,
+			Inodes: 2,
+			Volume: &info.FsVolume{Type: "bind", Source: "/srv", Destination: "/srv"},
+		},
+	}, stats)
+}
@@ -19,6 +19,7 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/yidoyoon/cadvisor-lite/fs"
 	info "github.com/yidoyoon/cadvisor-lite/info/v1"
@@ -57,6 +58,10 @@ const (
 	NetworkTcpUsageMetrics         MetricKind = "tcp"
 	NetworkAdvancedTcpUsageMetrics MetricKind = "advtcp"
 	NetworkUdpUsageMetrics         MetricKind = "udp"
+	NetworkConntrackMetrics        MetricKind = "conntrack"
+	NetfilterMetrics               MetricKind = "netfilter"
+	NetworkTcMetrics               MetricKind = "tc"
+	NodeMetrics                    MetricKind = "node"
 	AppMetrics                     MetricKind = "app"
 	ProcessMetrics                 MetricKind = "process"
 	HugetlbUsageMetrics            MetricKind = "hugetlb"
@@ -82,6 +87,10 @@ var AllMetrics = MetricSet{
 	NetworkTcpUsageMetrics:         struct{}{},
 	NetworkAdvancedTcpUsageMetrics: struct{}{},
 	NetworkUdpUsageMetrics:         struct{}{},
+	NetworkConntrackMetrics:        struct{}{},
+	NetfilterMetrics:               struct{}{},
+	NetworkTcMetrics:               struct{}{},
+	NodeMetrics:                    struct{}{},
 	ProcessMetrics:                 struct{}{},
 	AppMetrics:                     struct{}{},
 	HugetlbUsageMetrics:            struct{}{},
@@ -99,6 +108,8 @@ var AllNetworkMetrics = MetricSet{
 	NetworkTcpUsageMetrics:         struct{}{},
 	NetworkAdvancedTcpUsageMetrics: struct{}{},
 	NetworkUdpUsageMetrics:         struct{}{},
+	NetworkConntrackMetrics:        struct{}{},
+	NetworkTcMetrics:               struct{}{},
 }
 
 func (mk MetricKind) String() string {
@@ -230,8 +241,71 @@ func InitializePlugins(factory info.MachineInfoFactory, fsInfo fs.FsInfo, includ
 var (
 	factories     = map[watcher.ContainerWatchSource][]ContainerHandlerFactory{}
 	factoriesLock sync.RWMutex
+
+	// Wraps every handler created by NewContainerHandler, if set.
+	handlerWrapper func(ContainerHandler) ContainerHandler
+
+	// Stats of the factories since they were registered, by name.
+	factoryStats     = map[string]*FactoryStats{}
+	factoryStatsLock sync.Mutex
 )
 
+// FactoryStats counts the containers a factory was asked about.
+type FactoryStats struct {
+	// Number of containers the factory created a handler for.
+	Handled uint64 `json:"handled"`
+	// Number of containers the factory can handle but ignored, e.g. because
+	// of their labels or of --raw_cgroup_prefix_whitelist.
+	Ignored uint64 `json:"ignored"`
+	// Number of errors while checking whether the factory can handle a
+	// container, and while creating a handler.
+	CheckErrors   uint64 `json:"check_errors"`
+	HandlerErrors uint64 `json:"handler_errors"`
+	// Last error and its time, if any.
+	LastError     string    `json:"last_error,omitempty"`
+	LastErrorTime time.Time `json:"last_error_time,omitempty"`
+}
+
+// FactoryInfo describes a registered ContainerHandlerFactory.
+type FactoryInfo struct {
+	Name string `json:"name"`
+	// Watch sources the factory is registered for.
+	WatchSources []string `json:"watch_sources"`
+	// Debugging information of the factory, lines per category.
+	DebugInfo map[string][]string `json:"debug_info,omitempty"`
+	Stats     FactoryStats        `json:"stats"`
+}
+
+// FactoriesInfo describes the registered ContainerHandlerFactories.
+type FactoriesInfo struct {
+	// Names of the factories of each watch source, in the order in which
+	// they are asked whether they can handle a container.
+	Order map[string][]string `json:"order"`
+	// The factories, by name.
+	Factories []FactoryInfo `json:"factories"`
+}
+
+// updateFactoryStats applies update to the stats of the factory called name.
+func updateFactoryStats(name string, update func(*FactoryStats)) {
+	factoryStatsLock.Lock()
+	defer factoryStatsLock.Unlock()
+
+	stats, ok := factoryStats[name]
+	if !ok {
+		stats = &FactoryStats{}
+		factoryStats[name] = stats
+	}
+	update(stats)
+}
+
+func recordFactoryError(name string, err error, counter func(*FactoryStats) *uint64) {
+	updateFactoryStats(name, func(stats *FactoryStats) {
+		*counter(stats)++
+		stats.LastError = err.Error()
+		stats.LastErrorTime = time.Now()
+	})
+}
+
 // Register a ContainerHandlerFactory. These should be registered from least general to most general
 // as they will be asked in order whether they can handle a particular container.
 func RegisterContainerHandlerFactory(factory ContainerHandlerFactory, watchTypes []watcher.ContainerWatchSource) {
@@ -243,6 +317,15 @@ func RegisterContainerHandlerFactory(factory ContainerHandlerFactory, watchTypes
 	}
 }
 
+// SetHandlerWrapper sets a function wrapping every ContainerHandler created
+// from now on, e.g. to observe the calls made to the handlers.
+func SetHandlerWrapper(wrapper func(ContainerHandler) ContainerHandler) {
+	factoriesLock.Lock()
+	defer factoriesLock.Unlock()
+
+	handlerWrapper = wrapper
+}
+
 // Returns whether there are any container handler factories registered.
 func HasFactories() bool {
 	factoriesLock.Lock()
@@ -264,14 +347,24 @@ func NewContainerHandler(name string, watchType watcher.ContainerWatchSource, me
 		canHandle, canAccept, err := factory.CanHandleAndAccept(name)
 		if err != nil {
 			klog.V(4).Infof("Error trying to work out if we can handle %s: %v", name, err)
+			recordFactoryError(factory.String(), err, func(s *FactoryStats) *uint64 { return &s.CheckErrors })
 		}
 		if canHandle {
 			if !canAccept {
 				klog.V(3).Infof("Factory %q can handle container %q, but ignoring.", factory, name)
+				updateFactoryStats(factory.String(), func(s *FactoryStats) { s.Ignored++ })
 				return nil, false, nil
 			}
 			klog.V(3).Infof("Using factory %q for container %q", factory, name)
 			handle, err := factory.NewContainerHandler(name, metadataEnvAllowList, inHostNamespace)
+			if err != nil {
+				recordFactoryError(factory.String(), err, func(s *FactoryStats) *uint64 { return &s.HandlerErrors })
+				return handle, canAccept, err
+			}
+			updateFactoryStats(factory.String(), func(s *FactoryStats) { s.Handled++ })
+			if handlerWrapper != nil {
+				handle = handlerWrapper(handle)
+			}
 			return handle, canAccept, err
 		}
 		klog.V(4).Infof("Factory %q was unable to handle container %q", factory, name)
@@ -286,6 +379,10 @@ func ClearContainerHandlerFactories() {
 	defer factoriesLock.Unlock()
 
 	factories = map[watcher.ContainerWatchSource][]ContainerHandlerFactory{}
+
+	factoryStatsLock.Lock()
+	defer factoryStatsLock.Unlock()
+	factoryStats = map[string]*FactoryStats{}
 }
 
 func DebugInfo() map[string][]string {
@@ -304,6 +401,45 @@ func DebugInfo() map[string][]string {
 	return out
 }
 
+// Factories returns the registered factories, in the order in which they are
+// asked whether they can handle a container, and their stats.
+func Factories() FactoriesInfo {
+	factoriesLock.RLock()
+	defer factoriesLock.RUnlock()
+
+	result := FactoriesInfo{Order: make(map[string][]string, len(factories))}
+	byName := map[string]*FactoryInfo{}
+	var names []string
+	for watchType := range factories {
+		source := watchType.String()
+		for _, factory := range GetReorderedFactoryList(watchType) {
+			name := factory.String()
+			result.Order[source] = append(result.Order[source], name)
+			info, ok := byName[name]
+			if !ok {
+				info = &FactoryInfo{Name: name, DebugInfo: factory.DebugInfo()}
+				byName[name] = info
+				names = append(names, name)
+			}
+			info.WatchSources = append(info.WatchSources, source)
+		}
+	}
+
+	factoryStatsLock.Lock()
+	defer factoryStatsLock.Unlock()
+	sort.Strings(names)
+	result.Factories = make([]FactoryInfo, 0, len(names))
+	for _, name := range names {
+		info := byName[name]
+		sort.Strings(info.WatchSources)
+		if stats, ok := factoryStats[name]; ok {
+			info.Stats = *stats
+		}
+		result.Factories = append(result.Factories, *info)
+	}
+	return result
+}
+
 // GetReorderedFactoryList returns the list of ContainerHandlerFactory where the
 // RawContainerHandler is always the last element.
 func GetReorderedFactoryList(watchType watcher.ContainerWatchSource) []ContainerHandlerFactory {
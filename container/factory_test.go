@@ -15,6 +15,8 @@
 package container_test
 
 import (
+	"errors"
+	"reflect"
 	"testing"
 
 	"github.com/yidoyoon/cadvisor-lite/container"
@@ -29,6 +31,7 @@ type mockContainerHandlerFactory struct {
 	Name           string
 	CanHandleValue bool
 	CanAcceptValue bool
+	CanHandleErr   error
 }
 
 func (f *mockContainerHandlerFactory) String() string {
@@ -40,7 +43,7 @@ func (f *mockContainerHandlerFactory) DebugInfo() map[string][]string {
 }
 
 func (f *mockContainerHandlerFactory) CanHandleAndAccept(name string) (bool, bool, error) {
-	return f.CanHandleValue, f.CanAcceptValue, nil
+	return f.CanHandleValue, f.CanAcceptValue, f.CanHandleErr
 }
 
 func (f *mockContainerHandlerFactory) NewContainerHandler(name string, metadataEnvAllowList []string, isHostNamespace bool) (container.ContainerHandler, error) {
@@ -184,3 +187,64 @@ func TestRawContainerHandler_Last(t *testing.T) {
 		t.Error("Expected raw container handler to be last in the list.")
 	}
 }
+
+func TestFactories(t *testing.T) {
+	container.ClearContainerHandlerFactories()
+
+	raw := &mockContainerHandlerFactory{
+		Name:           "raw",
+		CanHandleValue: true,
+		CanAcceptValue: false,
+	}
+	container.RegisterContainerHandlerFactory(raw, []watcher.ContainerWatchSource{watcher.Raw})
+	failing := &mockContainerHandlerFactory{
+		Name:         "docker",
+		CanHandleErr: errors.New("cannot connect to docker"),
+	}
+	container.RegisterContainerHandlerFactory(failing, []watcher.ContainerWatchSource{watcher.Raw})
+	crio := &mockContainerHandlerFactory{
+		Name:           "crio",
+		CanHandleValue: true,
+		CanAcceptValue: true,
+	}
+	container.RegisterContainerHandlerFactory(crio, []watcher.ContainerWatchSource{watcher.Raw})
+
+	mockContainer, err := mockFactory.NewContainerHandler(testContainerName, testMetadataEnvAllowList, true)
+	if err != nil {
+		t.Error(err)
+	}
+	crio.On("NewContainerHandler", "/good").Return(mockContainer, nil)
+	crio.On("NewContainerHandler", "/bad").Return(mockContainer, errors.New("container not found"))
+	for _, name := range []string{"/good", "/good", "/bad"} {
+		_, _, _ = container.NewContainerHandler(name, watcher.Raw, testMetadataEnvAllowList, true)
+	}
+
+	factories := container.Factories()
+	if expected := map[string][]string{"raw": {"docker", "crio", "raw"}}; !reflect.DeepEqual(factories.Order, expected) {
+		t.Errorf("Expected order %v, got %v", expected, factories.Order)
+	}
+	stats := map[string]container.FactoryStats{}
+	for _, factory := range factories.Factories {
+		if !reflect.DeepEqual(factory.WatchSources, []string{"raw"}) {
+			t.Errorf("Expected factory %q to be registered for the raw watch source, got %v", factory.Name, factory.WatchSources)
+		}
+		stats[factory.Name] = factory.Stats
+	}
+	if len(stats) != 3 || factories.Factories[0].Name != "crio" {
+		t.Errorf("Expected the 3 factories sorted by name, got %+v", factories.Factories)
+	}
+	if s := stats["docker"]; s.CheckErrors != 3 || s.LastError != "cannot connect to docker" {
+		t.Errorf("Expected 3 check errors of docker, got %+v", s)
+	}
+	if s := stats["crio"]; s.Handled != 2 || s.HandlerErrors != 1 || s.LastError != "container not found" {
+		t.Errorf("Expected 2 containers handled and 1 handler error by crio, got %+v", s)
+	}
+	if s := stats["raw"]; s != (container.FactoryStats{}) {
+		t.Errorf("Expected no stats for raw, got %+v", s)
+	}
+
+	container.ClearContainerHandlerFactories()
+	if factories := container.Factories(); len(factories.Factories) != 0 {
+		t.Errorf("Expected no factories after clearing them, got %+v", factories)
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package libcontainer
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+
+	"github.com/yidoyoon/cadvisor-lite/container"
+)
+
+var (
+	metricErrorBudget       = flag.Int("metric_error_budget", 0, "Number of consecutive failed or slow reads of a source of metrics of a container, e.g. a cgroup v1 controller, after which the source is no longer read for the container until it is removed, its metrics are no longer exported and a metricsDisabled event is added. Zero value never stops reading metrics.")
+	metricSlowReadThreshold = flag.Duration("metric_slow_read_threshold", 0, "Duration beyond which a read of a source of metrics counts against --metric_error_budget as if it failed. Zero value does not count slow reads.")
+)
+
+// errorBudget stops reading the sources of metrics of a container whose
+// reads keep failing or taking too long, e.g. the blkio controller on a buggy
+// kernel, instead of failing and logging the same error every housekeeping.
+type errorBudget struct {
+	// Consecutive failed or slow reads allowed before a source is disabled,
+	// zero for never disabling sources.
+	budget int
+	// Duration beyond which a read counts as failed, zero for none.
+	slowRead time.Duration
+	clock    clock.PassiveClock
+
+	lock sync.Mutex
+	// Consecutive failed or slow reads, by source.
+	failures map[string]int
+	disabled map[string]container.DisabledMetrics
+}
+
+func newErrorBudget(budget int, slowRead time.Duration, clock clock.PassiveClock) *errorBudget {
+	return &errorBudget{
+		budget:   budget,
+		slowRead: slowRead,
+		clock:    clock,
+		failures: make(map[string]int),
+		disabled: make(map[string]container.DisabledMetrics),
+	}
+}
+
+// read calls f to read the metrics of kind from source, unless the source was
+// disabled, and returns its error.
+func (b *errorBudget) read(source string, kind container.MetricKind, f func() error) error {
+	b.lock.Lock()
+	_, disabled := b.disabled[source]
+	b.lock.Unlock()
+	if disabled {
+		return nil
+	}
+
+	start := b.clock.Now()
+	err := f()
+	elapsed := b.clock.Since(start)
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	var reason string
+	switch {
+	case err != nil:
+		reason = fmt.Sprintf("read failed %d times in a row, last with: %v", b.failures[source]+1, err)
+	case b.slowRead > 0 && elapsed > b.slowRead:
+		reason = fmt.Sprintf("read took longer than %s %d times in a row, last %s", b.slowRead, b.failures[source]+1, elapsed)
+	default:
+		delete(b.failures, source)
+		return nil
+	}
+	b.failures[source]++
+	if b.budget > 0 && b.failures[source] >= b.budget {
+		delete(b.failures, source)
+		b.disabled[source] = container.DisabledMetrics{Kind: kind, Source: source, Reason: reason}
+		klog.Warningf("No longer reading %s metrics from %s: %s", kind, source, reason)
+	}
+	return err
+}
+
+// list returns the disabled sources, sorted by source.
+func (b *errorBudget) list() []container.DisabledMetrics {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	disabled := make([]container.DisabledMetrics, 0, len(b.disabled))
+	for _, d := range b.disabled {
+		disabled = append(disabled, d)
+	}
+	sort.Slice(disabled, func(i, j int) bool { return disabled[i].Source < disabled[j].Source })
+	return disabled
+}
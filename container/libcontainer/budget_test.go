@@ -0,0 +1,95 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package libcontainer
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	testingclock "k8s.io/utils/clock/testing"
+
+	"github.com/yidoyoon/cadvisor-lite/container"
+)
+
+func TestErrorBudget(t *testing.T) {
+	b := newErrorBudget(3, 0, testingclock.NewFakeClock(time.Now()))
+	reads := 0
+	fail := func() error {
+		reads++
+		return errors.New("invalid blkio.throttle.io_service_bytes")
+	}
+	succeed := func() error {
+		reads++
+		return nil
+	}
+
+	// The failures must be consecutive.
+	assert.Error(t, b.read("blkio", container.DiskIOMetrics, fail))
+	assert.Error(t, b.read("blkio", container.DiskIOMetrics, fail))
+	assert.NoError(t, b.read("blkio", container.DiskIOMetrics, succeed))
+	assert.Error(t, b.read("blkio", container.DiskIOMetrics, fail))
+	assert.Error(t, b.read("blkio", container.DiskIOMetrics, fail))
+	assert.Empty(t, b.list())
+	// Other sources are counted on their own.
+	assert.Error(t, b.read("memory", container.MemoryUsageMetrics, fail))
+
+	assert.Error(t, b.read("blkio", container.DiskIOMetrics, fail))
+	disabled := b.list()
+	if assert.Len(t, disabled, 1) {
+		assert.Equal(t, container.DiskIOMetrics, disabled[0].Kind)
+		assert.Equal(t, "blkio", disabled[0].Source)
+		assert.Contains(t, disabled[0].Reason, "3 times in a row")
+		assert.Contains(t, disabled[0].Reason, "invalid blkio.throttle.io_service_bytes")
+	}
+
+	// The disabled sources are no longer read.
+	reads = 0
+	assert.NoError(t, b.read("blkio", container.DiskIOMetrics, fail))
+	assert.Equal(t, 0, reads)
+	assert.NoError(t, b.read("memory", container.MemoryUsageMetrics, succeed))
+	assert.Equal(t, 1, reads)
+}
+
+func TestErrorBudgetSlowReads(t *testing.T) {
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	b := newErrorBudget(2, time.Second, fakeClock)
+	read := func(d time.Duration) func() error {
+		return func() error {
+			fakeClock.Step(d)
+			return nil
+		}
+	}
+
+	assert.NoError(t, b.read("net/tcp", container.NetworkTcpUsageMetrics, read(2*time.Second)))
+	assert.NoError(t, b.read("net/tcp", container.NetworkTcpUsageMetrics, read(time.Second)))
+	assert.NoError(t, b.read("net/tcp", container.NetworkTcpUsageMetrics, read(2*time.Second)))
+	assert.Empty(t, b.list())
+	assert.NoError(t, b.read("net/tcp", container.NetworkTcpUsageMetrics, read(3*time.Second)))
+	disabled := b.list()
+	if assert.Len(t, disabled, 1) {
+		assert.Equal(t, "net/tcp", disabled[0].Source)
+		assert.Contains(t, disabled[0].Reason, "longer than 1s")
+	}
+}
+
+func TestErrorBudgetDisabled(t *testing.T) {
+	b := newErrorBudget(0, 0, testingclock.NewFakeClock(time.Now()))
+	for i := 0; i < 100; i++ {
+		assert.Error(t, b.read("blkio", container.DiskIOMetrics, func() error { return errors.New("failed") }))
+	}
+	assert.Empty(t, b.list())
+}
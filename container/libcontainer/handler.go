@@ -21,21 +21,28 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/opencontainers/runc/libcontainer"
 	"github.com/opencontainers/runc/libcontainer/cgroups"
+	cgroupfs "github.com/opencontainers/runc/libcontainer/cgroups/fs"
 	"github.com/opencontainers/runc/libcontainer/cgroups/fs2"
 	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
 
 	"github.com/yidoyoon/cadvisor-lite/container"
 	"github.com/yidoyoon/cadvisor-lite/container/common"
 	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+	"github.com/yidoyoon/cadvisor-lite/utils/forks"
+	"github.com/yidoyoon/cadvisor-lite/utils/tc"
 )
 
 var (
@@ -49,6 +56,50 @@ var (
 	referencedRegexp = regexp.MustCompile(`Referenced:\s*([0-9]+)\s*kB`)
 )
 
+// forkCounter counts the forks of the cgroups and their descendants.
+type forkCounter interface {
+	Forks(cgroupPath string) uint64
+}
+
+var (
+	sharedForkCounterOnce sync.Once
+	sharedForkCounter     forkCounter
+)
+
+// getForkCounter returns the counter of the fork events of the kernel shared
+// by the handlers, nil if they cannot be listened to, e.g. without the
+// CAP_NET_ADMIN capability.
+func getForkCounter() forkCounter {
+	sharedForkCounterOnce.Do(func() {
+		c, err := forks.New()
+		if err != nil {
+			klog.Infof("Estimating the forks of the containers from their tasks, the fork events cannot be listened to: %v", err)
+			return
+		}
+		sharedForkCounter = c
+	})
+	return sharedForkCounter
+}
+
+// cgroupV1Controllers are the cgroup v1 controllers the stats are read from,
+// one at a time so that the failing ones can be disabled on their own. The
+// stats of the other controllers, e.g. rdma, are not reported.
+var cgroupV1Controllers = []struct {
+	name  string
+	kind  container.MetricKind
+	group interface {
+		GetStats(path string, stats *cgroups.Stats) error
+	}
+}{
+	{"cpuset", container.CPUSetMetrics, &cgroupfs.CpusetGroup{}},
+	{"memory", container.MemoryUsageMetrics, &cgroupfs.MemoryGroup{}},
+	{"cpu", container.CpuUsageMetrics, &cgroupfs.CpuGroup{}},
+	{"cpuacct", container.CpuUsageMetrics, &cgroupfs.CpuacctGroup{}},
+	{"pids", container.ProcessMetrics, &cgroupfs.PidsGroup{}},
+	{"blkio", container.DiskIOMetrics, &cgroupfs.BlkioGroup{}},
+	{"hugetlb", container.HugetlbUsageMetrics, &cgroupfs.HugetlbGroup{}},
+}
+
 type Handler struct {
 	cgroupManager   cgroups.Manager
 	rootFs          string
@@ -59,16 +110,36 @@ type Handler struct {
 	// pidMetricsSaved holds accumulated CPU scheduler stats for processes that no longer exist.
 	pidMetricsSaved info.CpuSchedstat
 	cycles          uint64
+	// forkCounter counts the forks of the container, nil to estimate them
+	// from the tasks of the container seen by the previous call to
+	// countForks.
+	forkCounter forkCounter
+	tasks       map[int]struct{}
+	forks       uint64
+	tasksTime   time.Time
+	// partialStats is set when cAdvisor is not allowed to read some of the
+	// files of the cgroup, the stats that can be read are reported then.
+	partialStats bool
+	// budget stops reading the sources of metrics that keep failing.
+	budget *errorBudget
 }
 
 func NewHandler(cgroupManager cgroups.Manager, rootFs string, pid int, includedMetrics container.MetricSet) *Handler {
-	return &Handler{
+	h := &Handler{
 		cgroupManager:   cgroupManager,
 		rootFs:          rootFs,
 		pid:             pid,
 		includedMetrics: includedMetrics,
 		pidMetricsCache: make(map[int]*info.CpuSchedstat),
+		budget:          newErrorBudget(*metricErrorBudget, *metricSlowReadThreshold, clock.RealClock{}),
+	}
+	if cgroups.IsCgroup2UnifiedMode() {
+		h.partialStats = !common.CgroupFilesReadable(cgroupManager.Path(""))
 	}
+	if includedMetrics.Has(container.ProcessMetrics) {
+		h.forkCounter = getForkCounter()
+	}
+	return h
 }
 
 // Get cgroup and networking stats of the specified container
@@ -83,12 +154,17 @@ func (h *Handler) GetStats() (*info.ContainerStats, error) {
 		}
 	}
 
-	cgroupStats, err := h.cgroupManager.GetStats()
+	cgroupStats, err := h.cgroupStats()
 	if err != nil {
-		if !ignoreStatsError {
+		switch {
+		case ignoreStatsError:
+			klog.V(4).Infof("Ignoring errors when gathering stats for root cgroup since some controllers don't have stats on the root cgroup: %v", err)
+		case h.partialStats && cgroupStats != nil:
+			// The unavailable metrics are flagged in the spec of the container.
+			klog.V(4).Infof("Ignoring errors when gathering stats for cgroup with unreadable files: %v", err)
+		default:
 			return nil, err
 		}
-		klog.V(4).Infof("Ignoring errors when gathering stats for root cgroup since some controllers don't have stats on the root cgroup: %v", err)
 	}
 	libcontainerStats := &libcontainer.Stats{
 		CgroupStats: cgroupStats,
@@ -96,7 +172,10 @@ func (h *Handler) GetStats() (*info.ContainerStats, error) {
 	stats := newContainerStats(libcontainerStats, h.includedMetrics)
 
 	if h.includedMetrics.Has(container.ProcessSchedulerMetrics) {
-		stats.Cpu.Schedstat, err = h.schedulerStatsFromProcs()
+		err = h.budget.read("schedstat", container.ProcessSchedulerMetrics, func() (err error) {
+			stats.Cpu.Schedstat, err = h.schedulerStatsFromProcs()
+			return err
+		})
 		if err != nil {
 			klog.V(4).Infof("Unable to get Process Scheduler Stats: %v", err)
 		}
@@ -108,7 +187,10 @@ func (h *Handler) GetStats() (*info.ContainerStats, error) {
 		if err != nil {
 			klog.V(4).Infof("Could not get PIDs for container %d: %v", h.pid, err)
 		} else {
-			stats.ReferencedMemory, err = referencedBytesStat(pids, h.cycles, *referencedResetInterval)
+			err = h.budget.read("smaps", container.ReferencedMemoryMetrics, func() (err error) {
+				stats.ReferencedMemory, err = referencedBytesStat(pids, h.cycles, *referencedResetInterval)
+				return err
+			})
 			if err != nil {
 				klog.V(4).Infof("Unable to get referenced bytes: %v", err)
 			}
@@ -118,50 +200,98 @@ func (h *Handler) GetStats() (*info.ContainerStats, error) {
 	// If we know the pid then get network stats from /proc/<pid>/net/dev
 	if h.pid > 0 {
 		if h.includedMetrics.Has(container.NetworkUsageMetrics) {
-			netStats, err := networkStatsFromProc(h.rootFs, h.pid)
+			err := h.budget.read("net/dev", container.NetworkUsageMetrics, func() error {
+				netStats, err := networkStatsFromProc(h.rootFs, h.pid)
+				if err == nil {
+					stats.Network.Interfaces = append(stats.Network.Interfaces, netStats...)
+				}
+				return err
+			})
 			if err != nil {
 				klog.V(4).Infof("Unable to get network stats from pid %d: %v", h.pid, err)
-			} else {
-				stats.Network.Interfaces = append(stats.Network.Interfaces, netStats...)
 			}
 		}
 		if h.includedMetrics.Has(container.NetworkTcpUsageMetrics) {
-			t, err := tcpStatsFromProc(h.rootFs, h.pid, "net/tcp")
+			err := h.budget.read("net/tcp", container.NetworkTcpUsageMetrics, func() error {
+				t, err := tcpStatsFromProc(h.rootFs, h.pid, "net/tcp")
+				if err == nil {
+					stats.Network.Tcp = t
+				}
+				return err
+			})
 			if err != nil {
 				klog.V(4).Infof("Unable to get tcp stats from pid %d: %v", h.pid, err)
-			} else {
-				stats.Network.Tcp = t
 			}
 
-			t6, err := tcpStatsFromProc(h.rootFs, h.pid, "net/tcp6")
+			err = h.budget.read("net/tcp6", container.NetworkTcpUsageMetrics, func() error {
+				t6, err := tcpStatsFromProc(h.rootFs, h.pid, "net/tcp6")
+				if err == nil {
+					stats.Network.Tcp6 = t6
+				}
+				return err
+			})
 			if err != nil {
 				klog.V(4).Infof("Unable to get tcp6 stats from pid %d: %v", h.pid, err)
-			} else {
-				stats.Network.Tcp6 = t6
 			}
 
 		}
 		if h.includedMetrics.Has(container.NetworkAdvancedTcpUsageMetrics) {
-			ta, err := advancedTCPStatsFromProc(h.rootFs, h.pid, "net/netstat", "net/snmp")
+			err := h.budget.read("net/netstat", container.NetworkAdvancedTcpUsageMetrics, func() error {
+				ta, err := advancedTCPStatsFromProc(h.rootFs, h.pid, "net/netstat", "net/snmp")
+				if err == nil {
+					stats.Network.TcpAdvanced = ta
+				}
+				return err
+			})
 			if err != nil {
 				klog.V(4).Infof("Unable to get advanced tcp stats from pid %d: %v", h.pid, err)
-			} else {
-				stats.Network.TcpAdvanced = ta
 			}
 		}
 		if h.includedMetrics.Has(container.NetworkUdpUsageMetrics) {
-			u, err := udpStatsFromProc(h.rootFs, h.pid, "net/udp")
+			err := h.budget.read("net/udp", container.NetworkUdpUsageMetrics, func() error {
+				u, err := udpStatsFromProc(h.rootFs, h.pid, "net/udp")
+				if err == nil {
+					stats.Network.Udp = u
+				}
+				return err
+			})
 			if err != nil {
 				klog.V(4).Infof("Unable to get udp stats from pid %d: %v", h.pid, err)
-			} else {
-				stats.Network.Udp = u
 			}
 
-			u6, err := udpStatsFromProc(h.rootFs, h.pid, "net/udp6")
+			err = h.budget.read("net/udp6", container.NetworkUdpUsageMetrics, func() error {
+				u6, err := udpStatsFromProc(h.rootFs, h.pid, "net/udp6")
+				if err == nil {
+					stats.Network.Udp6 = u6
+				}
+				return err
+			})
 			if err != nil {
 				klog.V(4).Infof("Unable to get udp6 stats from pid %d: %v", h.pid, err)
-			} else {
-				stats.Network.Udp6 = u6
+			}
+		}
+		if h.includedMetrics.Has(container.NetworkConntrackMetrics) {
+			err := h.budget.read("net/nf_conntrack", container.NetworkConntrackMetrics, func() error {
+				c, err := conntrackStatsFromProc(h.rootFs, h.pid)
+				if err == nil {
+					stats.Network.Conntrack = c
+				}
+				return err
+			})
+			if err != nil {
+				klog.V(4).Infof("Unable to get conntrack stats from pid %d: %v", h.pid, err)
+			}
+		}
+		if h.includedMetrics.Has(container.NetworkTcMetrics) {
+			err := h.budget.read("tc", container.NetworkTcMetrics, func() error {
+				t, err := tc.Stats(h.rootFs, h.pid)
+				if err == nil {
+					stats.Network.Tc = t
+				}
+				return err
+			})
+			if err != nil {
+				klog.V(4).Infof("Unable to get tc stats from pid %d: %v", h.pid, err)
 			}
 		}
 	}
@@ -173,7 +303,10 @@ func (h *Handler) GetStats() (*info.ContainerStats, error) {
 		if !ok {
 			klog.V(4).Infof("Could not find cgroups CPU for container %d", h.pid)
 		} else {
-			stats.Processes, err = processStatsFromProcs(h.rootFs, path, h.pid)
+			err = h.budget.read("processes", container.ProcessMetrics, func() (err error) {
+				stats.Processes, err = processStatsFromProcs(h.rootFs, path, h.pid)
+				return err
+			})
 			if err != nil {
 				klog.V(4).Infof("Unable to get Process Stats: %v", err)
 			}
@@ -181,6 +314,25 @@ func (h *Handler) GetStats() (*info.ContainerStats, error) {
 
 		// if include processes metrics, just set threads metrics if exist, and has no relationship with cpu path
 		setThreadsStats(cgroupStats, stats)
+
+		pidsPath, ok := common.GetControllerPath(h.cgroupManager.GetPaths(), "pids", cgroups.IsCgroup2UnifiedMode())
+		if !ok {
+			klog.V(4).Infof("Could not find cgroups pids for container %d", h.pid)
+		} else {
+			err = h.budget.read("pids.events", container.ProcessMetrics, func() (err error) {
+				stats.Processes.ThreadsMaxReached, err = pidsMaxEvents(pidsPath)
+				return err
+			})
+			if err != nil {
+				klog.V(4).Infof("Unable to get pids events: %v", err)
+			}
+			err = h.budget.read("forks", container.ProcessMetrics, func() error {
+				return h.countForks(pidsPath, stats)
+			})
+			if err != nil {
+				klog.V(4).Infof("Unable to count forks: %v", err)
+			}
+		}
 	}
 
 	// For backwards compatibility.
@@ -191,6 +343,52 @@ func (h *Handler) GetStats() (*info.ContainerStats, error) {
 	return stats, nil
 }
 
+// cgroupStats reads the stats of the cgroup. On cgroup v1 the controllers are
+// read one at a time, so that those whose reads keep failing are no longer
+// read while the others still are. On cgroup v2 the controllers are read
+// together, the stats of the others being returned along with the error of a
+// failing one.
+func (h *Handler) cgroupStats() (*cgroups.Stats, error) {
+	if cgroups.IsCgroup2UnifiedMode() {
+		return h.cgroupManager.GetStats()
+	}
+	stats := cgroups.NewStats()
+	paths := h.cgroupManager.GetPaths()
+	for _, controller := range cgroupV1Controllers {
+		path := paths[controller.name]
+		if path == "" {
+			continue
+		}
+		err := h.budget.read(controller.name, controller.kind, func() error {
+			return controller.group.GetStats(path, stats)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return stats, nil
+}
+
+// DisabledMetrics returns the sources of metrics no longer read for the
+// container after their reads kept failing or taking too long.
+func (h *Handler) DisabledMetrics() []container.DisabledMetrics {
+	return h.budget.list()
+}
+
+// GetSecuritySpec returns the security profile of the init process of the
+// container, or nil if it cannot be read.
+func (h *Handler) GetSecuritySpec() *info.SecuritySpec {
+	if h.pid <= 0 {
+		return nil
+	}
+	spec, err := securitySpecFromProc(h.rootFs, h.pid)
+	if err != nil {
+		klog.V(4).Infof("Unable to get security spec from pid %d: %v", h.pid, err)
+		return nil
+	}
+	return spec
+}
+
 func parseUlimit(value string) (int64, error) {
 	num, err := strconv.ParseInt(value, 10, 64)
 	if err != nil {
@@ -802,12 +1000,29 @@ func setMemoryStats(s *cgroups.Stats, ret *info.ContainerStats) {
 	ret.Memory.MaxUsage = s.MemoryStats.Usage.MaxUsage
 	ret.Memory.Failcnt = s.MemoryStats.Usage.Failcnt
 	ret.Memory.KernelUsage = s.MemoryStats.KernelUsage.Usage
+	ret.Memory.Kernel.Sock = s.MemoryStats.KernelTCPUsage.Usage
 
 	if cgroups.IsCgroup2UnifiedMode() {
 		ret.Memory.Cache = s.MemoryStats.Stats["file"]
 		ret.Memory.RSS = s.MemoryStats.Stats["anon"]
 		ret.Memory.Swap = s.MemoryStats.SwapUsage.Usage - s.MemoryStats.Usage.Usage
 		ret.Memory.MappedFile = s.MemoryStats.Stats["file_mapped"]
+		ret.Memory.Kernel = info.MemoryKernelStats{
+			KernelStack:       s.MemoryStats.Stats["kernel_stack"],
+			PageTables:        s.MemoryStats.Stats["pagetables"],
+			Percpu:            s.MemoryStats.Stats["percpu"],
+			Sock:              s.MemoryStats.Stats["sock"],
+			SlabReclaimable:   s.MemoryStats.Stats["slab_reclaimable"],
+			SlabUnreclaimable: s.MemoryStats.Stats["slab_unreclaimable"],
+		}
+		// The kernel counter only exists since Linux 5.18, sum up its parts
+		// on older kernels.
+		if v, ok := s.MemoryStats.Stats["kernel"]; ok {
+			ret.Memory.KernelUsage = v
+		} else {
+			k := ret.Memory.Kernel
+			ret.Memory.KernelUsage = k.KernelStack + k.PageTables + k.Percpu + k.SlabReclaimable + k.SlabUnreclaimable
+		}
 	} else if s.MemoryStats.UseHierarchy {
 		ret.Memory.Cache = s.MemoryStats.Stats["total_cache"]
 		ret.Memory.RSS = s.MemoryStats.Stats["total_rss"]
@@ -899,6 +1114,107 @@ func setNetworkStats(libcontainerStats *libcontainer.Stats, ret *info.ContainerS
 	}
 }
 
+// pidsMaxEvents returns the number of times the pids limit of the cgroup or
+// of its ancestors was reached, from the pids.events file.
+func pidsMaxEvents(pidsPath string) (uint64, error) {
+	data, err := os.ReadFile(path.Join(pidsPath, "pids.events"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	// Format: max <count>
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "max" {
+			return strconv.ParseUint(fields[1], 10, 64)
+		}
+	}
+	return 0, nil
+}
+
+// countForks updates the fork count and rate of the container, threads
+// included. They are counted from the fork events of the kernel when they can
+// be listened to, since pids.events only counts the failed forks. Otherwise
+// they are estimated from the tasks of the cgroup and its descendants which
+// were not present at the previous call: the tasks living shorter than the
+// interval between the calls are missed, and the reuse of a task id for a new
+// task is not noticed.
+func (h *Handler) countForks(pidsPath string, stats *info.ContainerStats) error {
+	now := time.Now()
+	if h.forkCounter != nil {
+		forks := h.forkCounter.Forks(pidsPath)
+		if !h.tasksTime.IsZero() && forks >= h.forks {
+			if elapsed := now.Sub(h.tasksTime).Seconds(); elapsed > 0 {
+				stats.Processes.ForkRate = float64(forks-h.forks) / elapsed
+			}
+		}
+		h.forks = forks
+		h.tasksTime = now
+		stats.Processes.Forks = forks
+		return nil
+	}
+	tasksFile := "tasks"
+	if cgroups.IsCgroup2UnifiedMode() {
+		tasksFile = "cgroup.threads"
+	}
+	tasks, err := listTasks(pidsPath, tasksFile)
+	if err != nil {
+		return err
+	}
+	if h.tasks != nil {
+		started := 0
+		for tid := range tasks {
+			if _, ok := h.tasks[tid]; !ok {
+				started++
+			}
+		}
+		h.forks += uint64(started)
+		if elapsed := now.Sub(h.tasksTime).Seconds(); elapsed > 0 {
+			stats.Processes.ForkRate = float64(started) / elapsed
+		}
+	}
+	h.tasks = tasks
+	h.tasksTime = now
+	stats.Processes.Forks = h.forks
+	return nil
+}
+
+// listTasks returns the ids listed in tasksFile by the cgroup at cgroupPath
+// and its descendants.
+func listTasks(cgroupPath, tasksFile string) (map[int]struct{}, error) {
+	tasks := make(map[int]struct{})
+	err := filepath.WalkDir(cgroupPath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Cgroups may be removed concurrently.
+			if os.IsNotExist(err) && p != cgroupPath {
+				return nil
+			}
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		data, err := os.ReadFile(path.Join(p, tasksFile))
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		for _, field := range strings.Fields(string(data)) {
+			tid, err := strconv.Atoi(field)
+			if err != nil {
+				return fmt.Errorf("invalid task id %q in %s: %v", field, path.Join(p, tasksFile), err)
+			}
+			tasks[tid] = struct{}{}
+		}
+		return nil
+	})
+	return tasks, err
+}
+
 // read from pids path not cpu
 func setThreadsStats(s *cgroups.Stats, ret *info.ContainerStats) {
 	if s != nil {
@@ -933,3 +1249,72 @@ func newContainerStats(libcontainerStats *libcontainer.Stats, includedMetrics co
 	}
 	return ret
 }
+
+// conntrackStatsFromProc returns the connection tracking stats of the network
+// namespace of pid. The per-CPU counters of the namespace are read from
+// /proc/<pid>/net/stat/nf_conntrack, while the table size is host-wide: the
+// kernel has a single nf_conntrack_max, shared by all the network namespaces,
+// which is read from the namespace of cAdvisor.
+func conntrackStatsFromProc(rootFs string, pid int) (info.ConntrackStat, error) {
+	statFile := path.Join(rootFs, "proc", strconv.Itoa(pid), "net/stat/nf_conntrack")
+	r, err := os.Open(statFile)
+	if err != nil {
+		return info.ConntrackStat{}, fmt.Errorf("failure opening %s: %v", statFile, err)
+	}
+	defer r.Close()
+
+	stats, err := scanConntrackStats(r)
+	if err != nil {
+		return stats, fmt.Errorf("couldn't read conntrack stats: %v", err)
+	}
+
+	maxFile := path.Join(rootFs, "proc/sys/net/netfilter/nf_conntrack_max")
+	data, err := os.ReadFile(maxFile)
+	if err != nil {
+		return stats, fmt.Errorf("failure opening %s: %v", maxFile, err)
+	}
+	stats.Max, err = strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return stats, fmt.Errorf("couldn't parse %s: %v", maxFile, err)
+	}
+	return stats, nil
+}
+
+func scanConntrackStats(r io.Reader) (info.ConntrackStat, error) {
+	var stats info.ConntrackStat
+
+	scanner := bufio.NewScanner(r)
+	scanner.Split(bufio.ScanLines)
+
+	// The columns depend on the kernel version.
+	if b := scanner.Scan(); !b {
+		return stats, scanner.Err()
+	}
+	header := strings.Fields(scanner.Text())
+
+	for scanner.Scan() {
+		// Format: one line per CPU of hexadecimal counters, the entries being
+		// the same on all lines.
+		fs := strings.Fields(scanner.Text())
+		if len(fs) != len(header) {
+			return stats, fmt.Errorf("expected %d fields, found %d: %q", len(header), len(fs), scanner.Text())
+		}
+		for i, name := range header {
+			v, err := strconv.ParseUint(fs[i], 16, 64)
+			if err != nil {
+				return stats, err
+			}
+			switch name {
+			case "entries":
+				stats.Entries = v
+			case "insert_failed":
+				stats.InsertFailed += v
+			case "drop":
+				stats.Drop += v
+			case "early_drop":
+				stats.EarlyDrop += v
+			}
+		}
+	}
+	return stats, scanner.Err()
+}
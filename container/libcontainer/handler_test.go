@@ -16,6 +16,7 @@ package libcontainer
 
 import (
 	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 
@@ -91,6 +92,85 @@ func TestScanUDPStats(t *testing.T) {
 	}
 }
 
+func TestScanConntrackStats(t *testing.T) {
+	conntrackStatsFile := "testdata/procnetstatconntrack"
+	r, err := os.Open(conntrackStatsFile)
+	if err != nil {
+		t.Errorf("failure opening %s: %v", conntrackStatsFile, err)
+	}
+
+	stats, err := scanConntrackStats(r)
+	if err != nil {
+		t.Error(err)
+	}
+
+	conntrackStats := info.ConntrackStat{
+		Entries:      300,
+		InsertFailed: 3,
+		Drop:         3,
+		EarlyDrop:    1,
+	}
+
+	if stats != conntrackStats {
+		t.Errorf("Expected %#v, got %#v", conntrackStats, stats)
+	}
+}
+
+func writeTasks(t *testing.T, dir string, tasks string) {
+	assert.NoError(t, os.MkdirAll(dir, 0755))
+	// Depending on the cgroup version of the host, either file is read.
+	for _, file := range []string{"tasks", "cgroup.threads"} {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, file), []byte(tasks), 0644))
+	}
+}
+
+type fakeForkCounter map[string]uint64
+
+func (c fakeForkCounter) Forks(cgroupPath string) uint64 {
+	return c[cgroupPath]
+}
+
+func TestCountForks(t *testing.T) {
+	root := t.TempDir()
+	writeTasks(t, root, "10\n11\n")
+	writeTasks(t, filepath.Join(root, "child"), "20\n")
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "pids.events"), []byte("max 4\n"), 0644))
+
+	h := &Handler{}
+	stats := &info.ContainerStats{}
+	assert.NoError(t, h.countForks(root, stats))
+	assert.Equal(t, uint64(0), stats.Processes.Forks)
+	assert.Equal(t, 0.0, stats.Processes.ForkRate)
+
+	// 11 exited, 12 and 21 started.
+	writeTasks(t, root, "10\n12\n")
+	writeTasks(t, filepath.Join(root, "child"), "20\n21\n")
+	stats = &info.ContainerStats{}
+	assert.NoError(t, h.countForks(root, stats))
+	assert.Equal(t, uint64(2), stats.Processes.Forks)
+	assert.Greater(t, stats.Processes.ForkRate, 0.0)
+
+	// The counter of the fork events is used when there is one.
+	counter := fakeForkCounter{root: 5}
+	h = &Handler{forkCounter: counter}
+	stats = &info.ContainerStats{}
+	assert.NoError(t, h.countForks(root, stats))
+	assert.Equal(t, uint64(5), stats.Processes.Forks)
+	assert.Equal(t, 0.0, stats.Processes.ForkRate)
+	counter[root] = 8
+	stats = &info.ContainerStats{}
+	assert.NoError(t, h.countForks(root, stats))
+	assert.Equal(t, uint64(8), stats.Processes.Forks)
+	assert.Greater(t, stats.Processes.ForkRate, 0.0)
+
+	maxReached, err := pidsMaxEvents(root)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(4), maxReached)
+	maxReached, err = pidsMaxEvents(filepath.Join(root, "child"))
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), maxReached)
+}
+
 // https://github.com/docker/libcontainer/blob/v2.2.1/cgroups/fs/cpuacct.go#L19
 const nanosecondsInSeconds = 1000000000
 
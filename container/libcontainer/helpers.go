@@ -16,16 +16,19 @@ package libcontainer
 
 import (
 	"fmt"
+	"sort"
 
 	info "github.com/yidoyoon/cadvisor-lite/info/v1"
 
 	"github.com/opencontainers/runc/libcontainer/cgroups"
 
 	"github.com/yidoyoon/cadvisor-lite/container"
+	"github.com/yidoyoon/cadvisor-lite/container/common"
 
 	fs "github.com/opencontainers/runc/libcontainer/cgroups/fs"
 	fs2 "github.com/opencontainers/runc/libcontainer/cgroups/fs2"
 	configs "github.com/opencontainers/runc/libcontainer/configs"
+	"golang.org/x/sys/unix"
 	"k8s.io/klog/v2"
 )
 
@@ -91,6 +94,17 @@ var supportedSubsystems = map[string]container.MetricKind{
 	"perf_event": container.PerfMetrics,
 }
 
+// SupportedSubsystems returns the names of the cgroup v1 subsystems cAdvisor
+// reads, sorted.
+func SupportedSubsystems() []string {
+	names := make([]string, 0, len(supportedSubsystems))
+	for name := range supportedSubsystems {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // Check if this cgroup subsystem/controller is of use.
 func needSubsys(name string, metrics container.MetricSet) bool {
 	// Check if supported.
@@ -166,5 +180,16 @@ func NewCgroupManager(name string, paths map[string]string) (cgroups.Manager, er
 		return fs2.NewManager(config, path)
 	}
 
-	return fs.NewManager(config, paths)
+	// Skip the hierarchies cAdvisor is not allowed to read, so that the stats
+	// of the other ones are still collected. They are flagged as unavailable
+	// in the spec of the container.
+	readable := make(map[string]string, len(paths))
+	for controller, p := range paths {
+		if common.IsPermissionError(unix.Access(p, unix.R_OK|unix.X_OK)) {
+			klog.V(4).Infof("Not collecting the stats of cgroup %q of %q: permission denied", p, name)
+			continue
+		}
+		readable[controller] = p
+	}
+	return fs.NewManager(config, readable)
 }
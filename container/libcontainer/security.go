@@ -0,0 +1,154 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package libcontainer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/syndtr/gocapability/capability"
+
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+)
+
+// Files telling whether AppArmor and SELinux are enabled. The LSMs are the
+// ones of the kernel, so they are read from the sysfs of cAdvisor.
+var (
+	appArmorEnabledFile = "/sys/module/apparmor/parameters/enabled"
+	selinuxEnforceFile  = "/sys/fs/selinux/enforce"
+)
+
+// Seccomp modes, as numbered in /proc/<pid>/status.
+var seccompModes = []string{"disabled", "strict", "filter"}
+
+// Names of the capabilities known to cAdvisor, by bit.
+var capabilityNames = func() map[uint]string {
+	names := make(map[uint]string)
+	for _, c := range capability.List() {
+		names[uint(c)] = "CAP_" + strings.ToUpper(c.String())
+	}
+	return names
+}()
+
+// securitySpecFromProc reads the security profile of the given process from
+// /proc/<pid>/status and its LSM attributes.
+func securitySpecFromProc(rootFs string, pid int) (*info.SecuritySpec, error) {
+	procPath := path.Join(rootFs, "/proc", strconv.Itoa(pid))
+	status, err := os.ReadFile(path.Join(procPath, "status"))
+	if err != nil {
+		return nil, err
+	}
+
+	spec := &info.SecuritySpec{}
+	scanner := bufio.NewScanner(bytes.NewReader(status))
+	for scanner.Scan() {
+		key, value, found := strings.Cut(scanner.Text(), ":")
+		if !found {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch key {
+		case "Seccomp":
+			mode, err := strconv.Atoi(value)
+			if err != nil || mode < 0 || mode >= len(seccompModes) {
+				return nil, fmt.Errorf("unexpected seccomp mode %q", value)
+			}
+			spec.SeccompMode = seccompModes[mode]
+		case "NoNewPrivs":
+			spec.NoNewPrivileges = value == "1"
+		case "CapInh", "CapPrm", "CapEff", "CapBnd", "CapAmb":
+			caps, err := parseCapabilities(value)
+			if err != nil {
+				return nil, fmt.Errorf("unexpected %s capabilities %q: %v", key, value, err)
+			}
+			switch key {
+			case "CapInh":
+				spec.Capabilities.Inheritable = caps
+			case "CapPrm":
+				spec.Capabilities.Permitted = caps
+			case "CapEff":
+				spec.Capabilities.Effective = caps
+			case "CapBnd":
+				spec.Capabilities.Bounding = caps
+			case "CapAmb":
+				spec.Capabilities.Ambient = caps
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// The LSM attributes of the process are those of the major LSM in
+	// attr/current, and those of AppArmor also in attr/apparmor/current
+	// since Linux 5.8.
+	current := readAttr(path.Join(procPath, "attr/current"))
+	if appArmor := readAttr(path.Join(procPath, "attr/apparmor/current")); appArmor != "" {
+		spec.AppArmorProfile, spec.AppArmorMode = parseAppArmorLabel(appArmor)
+	} else if current != "" && readAttr(appArmorEnabledFile) == "Y" {
+		spec.AppArmorProfile, spec.AppArmorMode = parseAppArmorLabel(current)
+		current = ""
+	}
+	if _, err := os.Stat(selinuxEnforceFile); err == nil && current != "" {
+		spec.SELinuxLabel = current
+	}
+	return spec, nil
+}
+
+// parseCapabilities returns the names of the capabilities of the given
+// hexadecimal mask, as in /proc/<pid>/status.
+func parseCapabilities(mask string) ([]string, error) {
+	bits, err := strconv.ParseUint(mask, 16, 64)
+	if err != nil {
+		return nil, err
+	}
+	var caps []string
+	for bit := uint(0); bit < 64; bit++ {
+		if bits&(1<<bit) == 0 {
+			continue
+		}
+		name, ok := capabilityNames[bit]
+		if !ok {
+			// Capability added to the kernel after cAdvisor was built.
+			name = fmt.Sprintf("CAP_%d", bit)
+		}
+		caps = append(caps, name)
+	}
+	return caps, nil
+}
+
+// parseAppArmorLabel splits an AppArmor label such as "docker-default
+// (enforce)" into its profile and mode. Unconfined processes have no mode.
+func parseAppArmorLabel(label string) (string, string) {
+	if strings.HasSuffix(label, ")") {
+		if i := strings.LastIndex(label, " ("); i >= 0 {
+			return label[:i], label[i+2 : len(label)-1]
+		}
+	}
+	return label, ""
+}
+
+func readAttr(file string) string {
+	value, err := os.ReadFile(file)
+	if err != nil {
+		return ""
+	}
+	return string(bytes.TrimRight(value, "\x00\n"))
+}
@@ -0,0 +1,129 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package libcontainer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+)
+
+const securityStatus = `Name:	nginx
+Umask:	0022
+State:	S (sleeping)
+Pid:	42
+NoNewPrivs:	1
+Seccomp:	2
+Seccomp_filters:	1
+CapInh:	0000000000000000
+CapPrm:	0000000000000401
+CapEff:	0000000000000401
+CapBnd:	80000000a80425fb
+CapAmb:	0000000000000000
+`
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	for name, content := range files {
+		file := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(file), 0755))
+		require.NoError(t, os.WriteFile(file, []byte(content), 0644))
+	}
+}
+
+func TestSecuritySpecFromProc(t *testing.T) {
+	dockerCaps := []string{
+		"CAP_CHOWN", "CAP_DAC_OVERRIDE", "CAP_FOWNER", "CAP_FSETID", "CAP_KILL",
+		"CAP_SETGID", "CAP_SETUID", "CAP_SETPCAP", "CAP_NET_BIND_SERVICE", "CAP_NET_RAW",
+		"CAP_SYS_CHROOT", "CAP_MKNOD", "CAP_AUDIT_WRITE", "CAP_SETFCAP", "CAP_63",
+	}
+	for _, tc := range []struct {
+		name     string
+		files    map[string]string
+		expected info.SecuritySpec
+	}{
+		{
+			name: "no LSM",
+			files: map[string]string{
+				"proc/42/attr/current": "kernel",
+			},
+			expected: info.SecuritySpec{},
+		},
+		{
+			name: "AppArmor",
+			files: map[string]string{
+				"proc/42/attr/current":          "docker-default (enforce)\n",
+				"proc/42/attr/apparmor/current": "docker-default (enforce)\n",
+			},
+			expected: info.SecuritySpec{AppArmorProfile: "docker-default", AppArmorMode: "enforce"},
+		},
+		{
+			name: "AppArmor before Linux 5.8",
+			files: map[string]string{
+				"proc/42/attr/current":                   "unconfined\n",
+				"sys/module/apparmor/parameters/enabled": "Y\n",
+			},
+			expected: info.SecuritySpec{AppArmorProfile: "unconfined"},
+		},
+		{
+			name: "SELinux",
+			files: map[string]string{
+				"proc/42/attr/current":   "system_u:system_r:container_t:s0:c170,c633\x00",
+				"sys/fs/selinux/enforce": "1",
+			},
+			expected: info.SecuritySpec{SELinuxLabel: "system_u:system_r:container_t:s0:c170,c633"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			root := t.TempDir()
+			tc.files["proc/42/status"] = securityStatus
+			writeFiles(t, root, tc.files)
+			defer func(appArmor, selinux string) {
+				appArmorEnabledFile, selinuxEnforceFile = appArmor, selinux
+			}(appArmorEnabledFile, selinuxEnforceFile)
+			appArmorEnabledFile = filepath.Join(root, "sys/module/apparmor/parameters/enabled")
+			selinuxEnforceFile = filepath.Join(root, "sys/fs/selinux/enforce")
+
+			spec, err := securitySpecFromProc(root, 42)
+			require.NoError(t, err)
+			tc.expected.SeccompMode = "filter"
+			tc.expected.NoNewPrivileges = true
+			tc.expected.Capabilities = info.CapabilitiesSpec{
+				Effective: []string{"CAP_CHOWN", "CAP_NET_BIND_SERVICE"},
+				Permitted: []string{"CAP_CHOWN", "CAP_NET_BIND_SERVICE"},
+				Bounding:  dockerCaps,
+			}
+			assert.Equal(t, &tc.expected, spec)
+		})
+	}
+}
+
+func TestSecuritySpecFromProcErrors(t *testing.T) {
+	root := t.TempDir()
+	_, err := securitySpecFromProc(root, 42)
+	assert.Error(t, err)
+
+	writeFiles(t, root, map[string]string{"proc/42/status": "Seccomp:\t3\n"})
+	_, err = securitySpecFromProc(root, 42)
+	assert.EqualError(t, err, `unexpected seccomp mode "3"`)
+
+	writeFiles(t, root, map[string]string{"proc/42/status": "CapEff:\tffffx\n"})
+	_, err = securitySpecFromProc(root, 42)
+	assert.ErrorContains(t, err, `unexpected CapEff capabilities "ffffx"`)
+}
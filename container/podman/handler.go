@@ -48,6 +48,7 @@ type podmanContainerHandler struct {
 	rootfsStorageDir string
 
 	creationTime time.Time
+	startedAt    time.Time
 
 	// Metadata associated with the container.
 	envs   map[string]string
@@ -59,7 +60,15 @@ type podmanContainerHandler struct {
 
 	fsHandler common.FsHandler
 
+	// Named volumes and bind mounts of the container.
+	volumes *docker.Volumes
+
+	// Log files of the container, nil if its log driver does not write to
+	// a file.
+	logFiles *common.LogFiles
+
 	ipAddress string
+	ports     []info.PortMapping
 
 	metrics container.MetricSet
 
@@ -132,8 +141,9 @@ func newPodmanContainerHandler(
 		fsInfo:             fsInfo,
 		rootfsStorageDir:   rootfsStorageDir,
 		ipAddress:          ctnr.NetworkSettings.IPAddress,
+		ports:              dockerutil.PortMappings(ctnr.NetworkSettings.Ports),
 		envs:               make(map[string]string),
-		labels:             ctnr.Config.Labels,
+		labels:             make(map[string]string, len(ctnr.Config.Labels)),
 		image:              ctnr.Config.Image,
 		networkMode:        ctnr.HostConfig.NetworkMode,
 		fsHandler:          common.NewFsHandler(common.DefaultPeriod, rootfsStorageDir, otherStorageDir, fsInfo),
@@ -153,7 +163,15 @@ func newPodmanContainerHandler(
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse the create timestamp %q for container %q: %v", ctnr.Created, id, err)
 	}
+	// Zero for containers which never started.
+	if ctnr.State != nil {
+		handler.startedAt, _ = time.Parse(time.RFC3339Nano, ctnr.State.StartedAt)
+	}
 
+	// Copy the labels, the inspection is shared.
+	for k, v := range ctnr.Config.Labels {
+		handler.labels[k] = v
+	}
 	if ctnr.RestartCount > 0 {
 		handler.labels["restartcount"] = fmt.Sprint(ctnr.RestartCount)
 	}
@@ -179,6 +197,10 @@ func newPodmanContainerHandler(
 			DeviceID:        ctnr.GraphDriver.Data["DeviceId"],
 			ZfsFilesystem:   zfsFilesystem,
 		}
+		handler.volumes = docker.NewVolumes(ctnr.Mounts, rootFs, fsInfo)
+		if ctnr.LogPath != "" {
+			handler.logFiles = common.NewLogFiles(filepath.Join(rootFs, ctnr.LogPath))
+		}
 	}
 
 	// Split env vars to get metadata map.
@@ -236,6 +258,11 @@ func (p podmanContainerHandler) GetSpec() (info.ContainerSpec, error) {
 	spec.Envs = p.envs
 	spec.Image = p.image
 	spec.CreationTime = p.creationTime
+	spec.StartedAt = p.startedAt
+	spec.Security = p.libcontainerHandler.GetSecuritySpec()
+	spec.Ports = p.ports
+	spec.Runtime = container.ContainerTypePodman.String()
+	spec.RuntimeId = p.reference.Id
 
 	return spec, nil
 }
@@ -255,6 +282,16 @@ func (p podmanContainerHandler) GetStats() (*info.ContainerStats, error) {
 	if err != nil {
 		return stats, err
 	}
+	if p.logFiles != nil {
+		p.logFiles.UpdateStats(stats)
+	}
+
+	if p.volumes != nil {
+		stats.Volumes, err = p.volumes.Stats()
+		if err != nil {
+			return stats, err
+		}
+	}
 
 	return stats, nil
 }
@@ -267,6 +304,10 @@ func (p podmanContainerHandler) ListProcesses(listType container.ListType) ([]in
 	return p.libcontainerHandler.GetProcesses()
 }
 
+func (p podmanContainerHandler) DisabledMetrics() []container.DisabledMetrics {
+	return p.libcontainerHandler.DisabledMetrics()
+}
+
 func (p podmanContainerHandler) GetCgroupPath(resource string) (string, error) {
 	var res string
 	if !cgroups.IsCgroup2UnifiedMode() {
@@ -296,12 +337,18 @@ func (p podmanContainerHandler) Cleanup() {
 	if p.fsHandler != nil {
 		p.fsHandler.Stop()
 	}
+	if p.volumes != nil {
+		p.volumes.Stop()
+	}
 }
 
 func (p podmanContainerHandler) Start() {
 	if p.fsHandler != nil {
 		p.fsHandler.Start()
 	}
+	if p.volumes != nil {
+		p.volumes.Start()
+	}
 }
 
 func (p podmanContainerHandler) Type() container.ContainerType {
@@ -44,6 +44,17 @@ func (p *plugin) InitializeFSContext(context *fs.Context) error {
 		DriverStatus: map[string]string{},
 	}
 
+	storage, err := StorageInfo()
+	if err != nil {
+		klog.Warningf("Unable to get Podman storage info, podman-images label will not be reported: %v", err)
+		return nil
+	}
+	context.Podman.Root = storage.GraphRoot
+	context.Podman.Driver = storage.GraphDriverName
+	for k, v := range storage.GraphStatus {
+		context.Podman.DriverStatus[k] = v
+	}
+
 	return nil
 }
 
@@ -75,7 +86,7 @@ func Register(factory info.MachineInfoFactory, fsInfo fs.FsInfo, metrics contain
 				klog.Errorf("devicemapper filesystem stats will not be reported: %v", err)
 			}
 
-			status, _ := docker.StatusFromDockerInfo(*validatedInfo)
+			status := docker.StatusFromInfo(*validatedInfo)
 			thinPoolName = status.DriverStatus[dockerutil.DriverStatusPoolName]
 		case docker.ZfsStorageDriver:
 			zfsWatcher, err = docker.StartZfsWatcher(validatedInfo)
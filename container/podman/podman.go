@@ -23,11 +23,15 @@ import (
 	"time"
 
 	dockertypes "github.com/docker/docker/api/types"
+	dockerimage "github.com/docker/docker/api/types/image"
+	units "github.com/docker/go-units"
 	"github.com/pkg/errors"
 
 	"github.com/yidoyoon/cadvisor-lite/container/docker"
 	"github.com/yidoyoon/cadvisor-lite/container/docker/utils"
 	v1 "github.com/yidoyoon/cadvisor-lite/info/v1"
+	v2 "github.com/yidoyoon/cadvisor-lite/info/v2"
+	"github.com/yidoyoon/cadvisor-lite/utils/apicache"
 )
 
 const (
@@ -36,6 +40,17 @@ const (
 
 var timeout = 10 * time.Second
 
+// Caches of the Podman API calls.
+var (
+	infoCache    = apicache.New("podman_info")
+	imagesCache  = apicache.New("podman_image_list")
+	inspectCache = apicache.New("podman_inspect")
+	versionCache = apicache.New("podman_version")
+	storageCache = apicache.New("podman_storage_info")
+	dfCache      = apicache.New("podman_disk_usage")
+	historyCache = apicache.New("podman_image_history")
+)
+
 func validateResponse(gotError error, response *http.Response) error {
 	var err error
 	switch {
@@ -92,32 +107,159 @@ func apiGetRequest(url string, item interface{}) error {
 }
 
 func Images() ([]v1.DockerImage, error) {
-	var summaries []dockertypes.ImageSummary
-	err := apiGetRequest("http://d/v1.0.0/images/json", &summaries)
+	summaries, err := imagesCache.Get(context.Background(), "", func(context.Context) (interface{}, error) {
+		var summaries []dockertypes.ImageSummary
+		err := apiGetRequest("http://d/v1.0.0/images/json", &summaries)
+		return summaries, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return utils.SummariesToImages(summaries.([]dockertypes.ImageSummary))
+}
+
+// ImagesUsage returns the disk usage and the layers of all images.
+func ImagesUsage() ([]v1.DockerImageUsage, error) {
+	du, err := dfCache.Get(context.Background(), "", func(context.Context) (interface{}, error) {
+		var du dockertypes.DiskUsage
+		err := apiGetRequest("http://d/v1.0.0/system/df", &du)
+		return du, err
+	})
 	if err != nil {
 		return nil, err
 	}
-	return utils.SummariesToImages(summaries)
+	history := func(id string) ([]dockerimage.HistoryResponseItem, error) {
+		items, err := historyCache.Get(context.Background(), id, func(context.Context) (interface{}, error) {
+			var items []dockerimage.HistoryResponseItem
+			err := apiGetRequest(fmt.Sprintf("http://d/v1.0.0/images/%s/history", id), &items)
+			return items, err
+		})
+		if err != nil {
+			return nil, err
+		}
+		return items.([]dockerimage.HistoryResponseItem), nil
+	}
+	return utils.DiskUsageToImageUsage(du.(dockertypes.DiskUsage), history), nil
 }
 
+// Keys of the storage information added to the driver status.
+const (
+	DriverStatusGraphRoot          = "Graph Root"
+	DriverStatusGraphRootUsed      = "Graph Root Used"
+	DriverStatusGraphRootAllocated = "Graph Root Allocated"
+)
+
 func Status() (v1.DockerStatus, error) {
 	podmanInfo, err := GetInfo()
 	if err != nil {
 		return v1.DockerStatus{}, err
 	}
+	out := docker.StatusFromInfo(*podmanInfo)
+
+	version, err := version()
+	if err != nil {
+		return out, err
+	}
+	out.Version = version.Version
+	out.APIVersion = version.APIVersion
+
+	// The Docker compatible info lacks the usage of the graph root.
+	storage, err := StorageInfo()
+	if err != nil {
+		return out, err
+	}
+	addStorageStatus(&out, storage)
+	return out, nil
+}
+
+// RuntimeStatus returns the status of Podman common to all the runtimes.
+func RuntimeStatus() (*v2.RuntimeStatus, error) {
+	status := &v2.RuntimeStatus{Name: Namespace, Socket: *endpointFlag, NumContainers: -1, NumImages: -1}
+	podmanStatus, err := Status()
+	if err != nil {
+		return status, err
+	}
+	return docker.RuntimeStatusFromDockerStatus(status, podmanStatus), nil
+}
+
+func addStorageStatus(status *v1.DockerStatus, storage Storage) {
+	if storage.GraphDriverName != "" {
+		status.Driver = storage.GraphDriverName
+	}
+	if storage.GraphRoot != "" {
+		status.RootDir = storage.GraphRoot
+	}
+	if status.DriverStatus == nil {
+		status.DriverStatus = make(map[string]string, len(storage.GraphStatus)+3)
+	}
+	for k, v := range storage.GraphStatus {
+		status.DriverStatus[k] = v
+	}
+	status.DriverStatus[DriverStatusGraphRoot] = storage.GraphRoot
+	status.DriverStatus[DriverStatusGraphRootUsed] = units.BytesSize(float64(storage.GraphRootUsed))
+	status.DriverStatus[DriverStatusGraphRootAllocated] = units.BytesSize(float64(storage.GraphRootAllocated))
+}
+
+// Storage is the storage information of Podman.
+type Storage struct {
+	GraphDriverName    string            `json:"graphDriverName"`
+	GraphRoot          string            `json:"graphRoot"`
+	GraphStatus        map[string]string `json:"graphStatus"`
+	GraphRootAllocated uint64            `json:"graphRootAllocated"`
+	GraphRootUsed      uint64            `json:"graphRootUsed"`
+	RunRoot            string            `json:"runRoot"`
+	ImageStore         struct {
+		Number int `json:"number"`
+	} `json:"imageStore"`
+	ContainerStore struct {
+		Number int `json:"number"`
+	} `json:"containerStore"`
+}
 
-	return docker.StatusFromDockerInfo(*podmanInfo)
+// StorageInfo returns the storage information of the libpod info API, which
+// the Docker compatible info API does not report.
+func StorageInfo() (Storage, error) {
+	storage, err := storageCache.Get(context.Background(), "", func(context.Context) (interface{}, error) {
+		var info struct {
+			Store Storage `json:"store"`
+		}
+		err := apiGetRequest("http://d/v1.0.0/libpod/info", &info)
+		return info.Store, err
+	})
+	if err != nil {
+		return Storage{}, err
+	}
+	return storage.(Storage), nil
 }
 
 func GetInfo() (*dockertypes.Info, error) {
-	var info dockertypes.Info
-	err := apiGetRequest("http://d/v1.0.0/info", &info)
-	return &info, err
+	info, err := infoCache.Get(context.Background(), "", func(context.Context) (interface{}, error) {
+		var info dockertypes.Info
+		err := apiGetRequest("http://d/v1.0.0/info", &info)
+		return info, err
+	})
+	if err != nil {
+		return &dockertypes.Info{}, err
+	}
+	// Return a copy, callers may modify it.
+	result := info.(dockertypes.Info)
+	return &result, nil
+}
+
+func version() (dockertypes.Version, error) {
+	version, err := versionCache.Get(context.Background(), "", func(context.Context) (interface{}, error) {
+		var version dockertypes.Version
+		err := apiGetRequest("http://d/v1.0.0/version", &version)
+		return version, err
+	})
+	if err != nil {
+		return dockertypes.Version{}, err
+	}
+	return version.(dockertypes.Version), nil
 }
 
 func VersionString() (string, error) {
-	var version dockertypes.Version
-	err := apiGetRequest("http://d/v1.0.0/version", &version)
+	version, err := version()
 	if err != nil {
 		return "Unknown", err
 	}
@@ -125,8 +267,16 @@ func VersionString() (string, error) {
 	return version.Version, nil
 }
 
+// InspectContainer returns the inspection of a container. The result is shared
+// between callers and must not be modified.
 func InspectContainer(id string) (dockertypes.ContainerJSON, error) {
-	var data dockertypes.ContainerJSON
-	err := apiGetRequest(fmt.Sprintf("http://d/v1.0.0/containers/%s/json", id), &data)
-	return data, err
+	data, err := inspectCache.Get(context.Background(), id, func(context.Context) (interface{}, error) {
+		var data dockertypes.ContainerJSON
+		err := apiGetRequest(fmt.Sprintf("http://d/v1.0.0/containers/%s/json", id), &data)
+		return data, err
+	})
+	if err != nil {
+		return dockertypes.ContainerJSON{}, err
+	}
+	return data.(dockertypes.ContainerJSON), nil
 }
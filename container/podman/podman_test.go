@@ -15,11 +15,15 @@
 package podman
 
 import (
+	"encoding/json"
 	"errors"
 	"net/http"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	v1 "github.com/yidoyoon/cadvisor-lite/info/v1"
 )
 
 func TestValidateResponse(t *testing.T) {
@@ -70,3 +74,46 @@ func TestValidateResponse(t *testing.T) {
 		}
 	}
 }
+
+func TestAddStorageStatus(t *testing.T) {
+	// Store section of /v1.0.0/libpod/info, trimmed.
+	const libpodInfo = `{
+		"store": {
+			"containerStore": {"number": 3, "paused": 0, "running": 1, "stopped": 2},
+			"graphDriverName": "overlay",
+			"graphRoot": "/var/lib/containers/storage",
+			"graphRootAllocated": 107374182400,
+			"graphRootUsed": 10737418240,
+			"graphStatus": {
+				"Backing Filesystem": "xfs",
+				"Native Overlay Diff": "true"
+			},
+			"imageStore": {"number": 5},
+			"runRoot": "/run/containers/storage"
+		}
+	}`
+	var info struct {
+		Store Storage `json:"store"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(libpodInfo), &info))
+	assert.Equal(t, 5, info.Store.ImageStore.Number)
+	assert.Equal(t, 3, info.Store.ContainerStore.Number)
+
+	status := v1.DockerStatus{
+		Driver:       "vfs",
+		RootDir:      "/home/user/.local/share/containers/storage",
+		DriverStatus: map[string]string{"Backing Filesystem": "extfs"},
+	}
+	addStorageStatus(&status, info.Store)
+	assert.Equal(t, v1.DockerStatus{
+		Driver:  "overlay",
+		RootDir: "/var/lib/containers/storage",
+		DriverStatus: map[string]string{
+			"Backing Filesystem":           "xfs",
+			"Native Overlay Diff":          "true",
+			DriverStatusGraphRoot:          "/var/lib/containers/storage",
+			DriverStatusGraphRootUsed:      "10GiB",
+			DriverStatusGraphRootAllocated: "100GiB",
+		},
+	}, status)
+}
@@ -32,6 +32,7 @@ import (
 var (
 	DockerOnly             = flag.Bool("docker_only", false, "Only report docker containers in addition to root stats")
 	disableRootCgroupStats = flag.Bool("disable_root_cgroup_stats", false, "Disable collecting root Cgroup stats")
+	slabTopCaches          = flag.Int("slab_top_caches", 10, "Number of slab caches using the most memory reported in the root cgroup stats. 0 disables reading /proc/slabinfo")
 )
 
 type rawFactory struct {
@@ -17,6 +17,7 @@ package raw
 
 import (
 	"fmt"
+	"path"
 
 	"github.com/opencontainers/runc/libcontainer/cgroups"
 	"github.com/yidoyoon/cadvisor-lite/container"
@@ -25,6 +26,7 @@ import (
 	"github.com/yidoyoon/cadvisor-lite/fs"
 	info "github.com/yidoyoon/cadvisor-lite/info/v1"
 	"github.com/yidoyoon/cadvisor-lite/machine"
+	"github.com/yidoyoon/cadvisor-lite/utils/netfilter"
 
 	"k8s.io/klog/v2"
 )
@@ -41,6 +43,7 @@ type rawContainerHandler struct {
 	fsInfo          fs.FsInfo
 	externalMounts  []common.Mount
 	includedMetrics container.MetricSet
+	rootFs          string
 
 	libcontainerHandler *libcontainer.Handler
 }
@@ -87,6 +90,7 @@ func newRawContainerHandler(name string, cgroupSubsystems map[string]string, mac
 		fsInfo:              fsInfo,
 		externalMounts:      externalMounts,
 		includedMetrics:     includedMetrics,
+		rootFs:              rootFs,
 		libcontainerHandler: handler,
 	}, nil
 }
@@ -123,6 +127,7 @@ func (h *rawContainerHandler) GetSpec() (info.ContainerSpec, error) {
 	if err != nil {
 		return spec, err
 	}
+	spec.Runtime = container.ContainerTypeRaw.String()
 
 	if isRootCgroup(h.name) {
 		// Check physical network devices for root container.
@@ -235,6 +240,24 @@ func (h *rawContainerHandler) GetStats() (*info.ContainerStats, error) {
 		return stats, err
 	}
 
+	if isRootCgroup(h.name) && h.includedMetrics.Has(container.MemoryUsageMetrics) && *slabTopCaches > 0 {
+		caches, err := topSlabCaches(path.Join(h.rootFs, "/proc/slabinfo"), *slabTopCaches)
+		if err != nil {
+			klog.V(4).Infof("Unable to get slab caches: %v", err)
+		} else {
+			stats.Memory.Kernel.TopSlabCaches = caches
+		}
+	}
+
+	if isRootCgroup(h.name) && h.includedMetrics.Has(container.NetfilterMetrics) {
+		chains, err := netfilter.Chains()
+		if err != nil {
+			klog.V(4).Infof("Unable to get netfilter counters: %v", err)
+		} else {
+			stats.Network.Netfilter = chains
+		}
+	}
+
 	// Get filesystem stats.
 	err = h.getFsStats(stats)
 	if err != nil {
@@ -273,6 +296,10 @@ func (h *rawContainerHandler) ListProcesses(listType container.ListType) ([]int,
 	return h.libcontainerHandler.GetProcesses()
 }
 
+func (h *rawContainerHandler) DisabledMetrics() []container.DisabledMetrics {
+	return h.libcontainerHandler.DisabledMetrics()
+}
+
 func (h *rawContainerHandler) Exists() bool {
 	return common.CgroupExists(h.cgroupPaths)
 }
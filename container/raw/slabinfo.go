@@ -0,0 +1,95 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package raw
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+)
+
+var pageSize = uint64(os.Getpagesize())
+
+// topSlabCaches returns the n slab caches using the most memory listed in
+// the slabinfo file at slabinfoPath.
+func topSlabCaches(slabinfoPath string, n int) ([]info.SlabCache, error) {
+	f, err := os.Open(slabinfoPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	caches, err := scanSlabInfo(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", slabinfoPath, err)
+	}
+	sort.SliceStable(caches, func(i, j int) bool {
+		return caches[i].Size > caches[j].Size
+	})
+	if len(caches) > n {
+		caches = caches[:n]
+	}
+	return caches, nil
+}
+
+// scanSlabInfo parses the slab caches of a version 2.x slabinfo file, e.g.:
+//
+//	slabinfo - version: 2.1
+//	# name <active_objs> <num_objs> <objsize> <objperslab> <pagesperslab> : tunables <limit> <batchcount> <sharedfactor> : slabdata <active_slabs> <num_slabs> <sharedavail>
+//	dentry 215530 218883 192 21 1 : tunables 0 0 0 : slabdata 10423 10423 0
+func scanSlabInfo(r io.Reader) ([]info.SlabCache, error) {
+	scanner := bufio.NewScanner(r)
+	if !scanner.Scan() {
+		return nil, fmt.Errorf("empty slabinfo")
+	}
+	if header := scanner.Text(); !strings.HasPrefix(header, "slabinfo - version: 2.") {
+		return nil, fmt.Errorf("unsupported slabinfo %q", header)
+	}
+
+	caches := []info.SlabCache{}
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		// name, 5 object fields, tunables (4), slabdata (4) and the separators.
+		if len(fields) < 16 || fields[6] != ":" || fields[11] != ":" {
+			return nil, fmt.Errorf("malformed line %q", line)
+		}
+		values := make([]uint64, 0, 5)
+		for _, i := range []int{1, 2, 3, 5, 14} {
+			v, err := strconv.ParseUint(fields[i], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("malformed line %q: %v", line, err)
+			}
+			values = append(values, v)
+		}
+		caches = append(caches, info.SlabCache{
+			Name:          fields[0],
+			ActiveObjects: values[0],
+			Objects:       values[1],
+			ObjectSize:    values[2],
+			// num_slabs * pagesperslab
+			Size: values[4] * values[3] * pageSize,
+		})
+	}
+	return caches, scanner.Err()
+}
@@ -0,0 +1,45 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package raw
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+)
+
+func TestTopSlabCaches(t *testing.T) {
+	caches, err := topSlabCaches("testdata/slabinfo", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []info.SlabCache{
+		{Name: "ext4_inode_cache", ActiveObjects: 40210, Objects: 40290, ObjectSize: 1080, Size: 1343 * 8 * pageSize},
+		{Name: "dentry", ActiveObjects: 215530, Objects: 218883, ObjectSize: 192, Size: 10423 * pageSize},
+	}, caches)
+}
+
+func TestScanSlabInfoErrors(t *testing.T) {
+	for name, slabinfo := range map[string]string{
+		"empty":     "",
+		"version 1": "slabinfo - version: 1.1\n",
+		"malformed": "slabinfo - version: 2.1\ndentry 215530 218883 192\n",
+		"not uint":  "slabinfo - version: 2.1\ndentry x 218883 192 21 1 : tunables 0 0 0 : slabdata 10423 10423 0\n",
+	} {
+		_, err := scanSlabInfo(strings.NewReader(slabinfo))
+		assert.Error(t, err, name)
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"os"
 	"path"
 	"strings"
+	"sync"
 
 	inotify "k8s.io/utils/inotify"
 
@@ -41,6 +42,12 @@ type rawContainerWatcher struct {
 
 	// Signal for watcher thread to stop.
 	stopWatcher chan error
+
+	lock sync.Mutex
+	// Whether the watcher thread runs.
+	running bool
+	// Last error of the inotify watch, reset by the next event processed.
+	lastErr error
 }
 
 func NewRawContainerWatcher(includedMetrics container.MetricSet) (watcher.ContainerWatcher, error) {
@@ -82,6 +89,7 @@ func (w *rawContainerWatcher) Start(events chan watcher.ContainerEvent) error {
 		}
 		watched = append(watched, cgroupPath)
 	}
+	w.setState(true, nil)
 
 	// Process the events received from the kernel.
 	go func() {
@@ -92,11 +100,14 @@ func (w *rawContainerWatcher) Start(events chan watcher.ContainerEvent) error {
 				if err != nil {
 					klog.Warningf("Error while processing event (%+v): %v", event, err)
 				}
+				w.setState(true, nil)
 			case err := <-w.watcher.Error():
 				klog.Warningf("Error while watching %q: %v", "/", err)
+				w.setState(true, err)
 			case <-w.stopWatcher:
 				err := w.watcher.Close()
 				if err == nil {
+					w.setState(false, nil)
 					w.stopWatcher <- err
 					return
 				}
@@ -113,6 +124,27 @@ func (w *rawContainerWatcher) Stop() error {
 	return <-w.stopWatcher
 }
 
+func (w *rawContainerWatcher) setState(running bool, err error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.running = running
+	w.lastErr = err
+}
+
+// Healthy returns an error if the watcher is not started, or if the inotify
+// watch failed since the last event, e.g. when its queue overflowed.
+func (w *rawContainerWatcher) Healthy() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if !w.running {
+		return fmt.Errorf("not watching the cgroups")
+	}
+	if w.lastErr != nil {
+		return fmt.Errorf("inotify watch failed: %v", w.lastErr)
+	}
+	return nil
+}
+
 // Watches the specified directory and all subdirectories. Returns whether the path was
 // already being watched and an error (if any).
 func (w *rawContainerWatcher) watchDirectory(events chan watcher.ContainerEvent, dir string, containerName string) (bool, error) {
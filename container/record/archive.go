@@ -0,0 +1,135 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package record records the raw data read by container handlers to an
+// archive: the files of the cgroups and of procfs, and the responses of the
+// container runtimes. It replays an archive through the handlers, which then
+// read the recorded data instead of the ones of the host.
+//
+// This allows reproducing metric bugs from a node offline: record on the node
+// with --record_file, then run cAdvisor anywhere with --replay_file.
+package record
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Kinds of the recorded entries.
+const (
+	// State of a container when its reads were recorded, followed by the
+	// files read.
+	kindContainer = "container"
+	// Content of a file of a cgroup or of procfs, by its path on the host.
+	kindFile = "file"
+	// Response of the Docker API, by the path of the request.
+	kindDocker = "docker"
+)
+
+// Entry is a single recorded read.
+type Entry struct {
+	Timestamp time.Time `json:"timestamp"`
+	Kind      string    `json:"kind"`
+	// Name of the container the read was made for, empty for the responses
+	// of the runtimes.
+	Container string `json:"container,omitempty"`
+	Path      string `json:"path,omitempty"`
+	// HTTP status of a response.
+	Status int    `json:"status,omitempty"`
+	Data   []byte `json:"data,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Writer appends entries to a gzip-compressed archive of JSON lines. It is
+// safe for concurrent use.
+type Writer struct {
+	lock    sync.Mutex
+	file    *os.File
+	gzip    *gzip.Writer
+	encoder *json.Encoder
+}
+
+// NewWriter creates the archive at path.
+func NewWriter(path string) (*Writer, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create archive: %v", err)
+	}
+	gz := gzip.NewWriter(f)
+	return &Writer{
+		file:    f,
+		gzip:    gz,
+		encoder: json.NewEncoder(gz),
+	}, nil
+}
+
+// write appends entries to the archive, together so that the entries of a
+// container are not interleaved with the ones of another.
+func (w *Writer) write(entries ...Entry) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	for _, entry := range entries {
+		if err := w.encoder.Encode(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close flushes and closes the archive.
+func (w *Writer) Close() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if err := w.gzip.Close(); err != nil {
+		w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
+
+// ReadArchive reads all entries of the archive at path.
+func ReadArchive(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive: %v", err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(bufio.NewReader(f))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive: %v", err)
+	}
+	defer gz.Close()
+
+	var entries []Entry
+	decoder := json.NewDecoder(gz)
+	for {
+		var entry Entry
+		err := decoder.Decode(&entry)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			// An archive that was not closed properly is truncated.
+			break
+		}
+		if err != nil {
+			return entries, fmt.Errorf("failed to decode archive entry %d: %v", len(entries), err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
@@ -0,0 +1,195 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package record
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/opencontainers/runc/libcontainer/cgroups"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yidoyoon/cadvisor-lite/container"
+	containertest "github.com/yidoyoon/cadvisor-lite/container/testing"
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+)
+
+type machineInfo struct{}
+
+func (machineInfo) GetMachineInfo() (*info.MachineInfo, error) {
+	return &info.MachineInfo{NumCores: 2, MemoryCapacity: 1 << 30}, nil
+}
+
+func (machineInfo) GetVersionInfo() (*info.VersionInfo, error) {
+	return &info.VersionInfo{}, nil
+}
+
+// Writes the files of a cgroup using usage bytes of memory, for both cgroup
+// versions.
+func writeCgroup(t *testing.T, dir string, usage string) {
+	files := map[string]string{
+		"cgroup.procs": "42\n43\n",
+		// cgroup v1.
+		"memory.usage_in_bytes":     usage,
+		"memory.max_usage_in_bytes": usage,
+		"memory.failcnt":            "0",
+		"memory.limit_in_bytes":     "1048576",
+		"memory.stat":               "cache 0\nrss " + usage + "\n",
+		"memory.use_hierarchy":      "1",
+		// cgroup v2.
+		"memory.current": usage,
+		"memory.max":     "1048576",
+	}
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+}
+
+func writeNetDev(t *testing.T, rootFs string, rxBytes string) {
+	netDev := "Inter-|   Receive                                                |  Transmit\n" +
+		" face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed\n" +
+		"  eth0: " + rxBytes + " 2 0 0 0 0 0 0 300 4 0 0 0 0 0 0\n"
+	dir := filepath.Join(rootFs, "proc/42/net")
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "dev"), []byte(netDev), 0o644))
+}
+
+type fakeDocker struct{}
+
+func (fakeDocker) RoundTrip(req *http.Request) (*http.Response, error) {
+	body := `{"Id": "abc", "Name": "/web", "Created": "2023-01-02T03:04:05Z",
+		"State": {"Pid": 42, "StartedAt": "2023-01-02T03:04:06Z"},
+		"Config": {"Image": "nginx", "Labels": {"app": "web"}},
+		"HostConfig": {}, "NetworkSettings": {"IPAddress": "10.0.0.1"}}`
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body)), Header: http.Header{}}, nil
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "archive.gz")
+	writer, err := NewWriter(archive)
+	require.NoError(t, err)
+	rootFs := t.TempDir()
+	cgroupDir := filepath.Join(t.TempDir(), "docker/abc")
+	recorder := NewRecorder(writer, rootFs)
+
+	// The runtime is inspected through the recording transport.
+	client := &http.Client{Transport: recorder.WrapTransport(fakeDocker{})}
+	resp, err := client.Get("http://docker/v1.41/containers/abc/json")
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	handler := containertest.NewMockContainerHandler("/docker/abc")
+	handler.On("Type").Return(container.ContainerTypeDocker)
+	if cgroups.IsCgroup2UnifiedMode() {
+		handler.On("GetCgroupPath", mock.Anything).Return(cgroupDir, nil)
+	} else {
+		handler.On("GetCgroupPath", "memory").Return(cgroupDir, nil)
+		handler.On("GetCgroupPath", "devices").Return(cgroupDir, nil)
+		handler.On("GetCgroupPath", mock.Anything).Return("", errors.New("not mounted"))
+	}
+	handler.On("GetStats").Return(&info.ContainerStats{}, nil)
+
+	writeCgroup(t, cgroupDir, "100")
+	writeNetDev(t, rootFs, "1000")
+	h := recorder.Wrap(handler)
+	for _, usage := range []string{"200", "300"} {
+		writeCgroup(t, cgroupDir, usage)
+		writeNetDev(t, rootFs, usage+"0")
+		_, err := h.GetStats()
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.Close())
+	// The replay does not read the host.
+	require.NoError(t, os.RemoveAll(cgroupDir))
+	require.NoError(t, os.RemoveAll(rootFs))
+
+	factory, err := NewReplayFactory(archive)
+	require.NoError(t, err)
+	defer factory.Close()
+	_, err = factory.Register(machineInfo{}, nil, container.MetricSet{
+		container.MemoryUsageMetrics:  struct{}{},
+		container.NetworkUsageMetrics: struct{}{},
+	})
+	require.NoError(t, err)
+
+	canHandle, canAccept, err := factory.CanHandleAndAccept("/c")
+	require.NoError(t, err)
+	assert.True(t, canHandle)
+	assert.False(t, canAccept)
+
+	replayed, err := factory.NewContainerHandler("/docker/abc", nil, true)
+	require.NoError(t, err)
+	ref, err := replayed.ContainerReference()
+	require.NoError(t, err)
+	assert.Equal(t, "abc", ref.Id)
+	assert.Equal(t, []string{"web", "abc"}, ref.Aliases)
+	assert.Equal(t, map[string]string{"app": "web"}, replayed.GetContainerLabels())
+	assert.Equal(t, "10.0.0.1", replayed.GetContainerIPAddress())
+	assert.Equal(t, container.ContainerTypeDocker, replayed.Type())
+	spec, err := replayed.GetSpec()
+	require.NoError(t, err)
+	assert.Equal(t, "nginx", spec.Image)
+	assert.Equal(t, uint64(1048576), spec.Memory.Limit)
+	pids, err := replayed.ListProcesses(container.ListSelf)
+	require.NoError(t, err)
+	assert.Equal(t, []int{42, 43}, pids)
+
+	// The stats are read from the files recorded at each read.
+	for _, usage := range []uint64{200, 300} {
+		stats, err := replayed.GetStats()
+		require.NoError(t, err)
+		require.NotNil(t, stats)
+		assert.Equal(t, usage, stats.Memory.Usage)
+		require.Len(t, stats.Network.Interfaces, 1)
+		assert.Equal(t, usage*10, stats.Network.Interfaces[0].RxBytes)
+	}
+	stats, err := replayed.GetStats()
+	require.NoError(t, err)
+	assert.Nil(t, stats)
+}
+
+func TestReplaySubcontainers(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "archive.gz")
+	writer, err := NewWriter(archive)
+	require.NoError(t, err)
+	recorder := NewRecorder(writer, t.TempDir())
+	for _, name := range []string{"/", "/a", "/a/b"} {
+		handler := containertest.NewMockContainerHandler(name)
+		handler.On("Type").Return(container.ContainerTypeRaw)
+		handler.On("GetCgroupPath", mock.Anything).Return("", errors.New("not mounted"))
+		recorder.Wrap(handler)
+	}
+	require.NoError(t, writer.Close())
+
+	factory, err := NewReplayFactory(archive)
+	require.NoError(t, err)
+	defer factory.Close()
+	root, err := factory.NewContainerHandler("/", nil, true)
+	require.NoError(t, err)
+	subcontainers, err := root.ListContainers(container.ListSelf)
+	require.NoError(t, err)
+	assert.Equal(t, []info.ContainerReference{{Name: "/a"}}, subcontainers)
+	subcontainers, err = root.ListContainers(container.ListRecursive)
+	require.NoError(t, err)
+	assert.Equal(t, []info.ContainerReference{{Name: "/a"}, {Name: "/a/b"}}, subcontainers)
+}
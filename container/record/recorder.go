@@ -0,0 +1,252 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package record
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opencontainers/runc/libcontainer/cgroups"
+
+	"github.com/yidoyoon/cadvisor-lite/container"
+	"github.com/yidoyoon/cadvisor-lite/container/libcontainer"
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+
+	"k8s.io/klog/v2"
+)
+
+// Files of procfs read by the handlers for the process of a container,
+// relative to /proc/<pid>.
+var procFiles = []string{
+	"status",
+	"attr/current",
+	"attr/apparmor/current",
+	"limits",
+	"net/dev",
+	"net/tcp",
+	"net/tcp6",
+	"net/udp",
+	"net/udp6",
+	"net/netstat",
+	"net/snmp",
+	"net/stat/nf_conntrack",
+}
+
+// Files of procfs read by the handlers for all the containers.
+var hostProcFiles = []string{
+	"/proc/sys/net/netfilter/nf_conntrack_max",
+}
+
+// Requests of the Docker API that are recorded: the inspections of the
+// containers, without the version of the API.
+var dockerRequest = regexp.MustCompile(`^/containers/[^/]+/json$`)
+
+// The version prefixing the paths of the requests of the Docker API.
+var dockerVersion = regexp.MustCompile(`^/v[0-9.]+`)
+
+// containerState is the state of a container when its reads were recorded.
+type containerState struct {
+	Type container.ContainerType `json:"type"`
+	// Directories of the cgroups of the container, by resource as
+	// accepted by ContainerHandler.GetCgroupPath, the empty one for the
+	// unified hierarchy.
+	Cgroups map[string]string `json:"cgroups"`
+	// Process whose procfs files are recorded, zero for none.
+	Pid int `json:"pid,omitempty"`
+}
+
+// Recorder records the raw data read by container handlers.
+type Recorder struct {
+	writer *Writer
+	// Root of the file system of the host.
+	rootFs string
+}
+
+// NewRecorder returns a Recorder writing to writer, which reads the files of
+// the host under rootFs.
+func NewRecorder(writer *Writer, rootFs string) *Recorder {
+	return &Recorder{writer: writer, rootFs: rootFs}
+}
+
+// Wrap returns a handler recording the files of the cgroups and of procfs
+// read by h when it is created and every time its stats are read. It can be
+// passed to container.SetHandlerWrapper.
+func (r *Recorder) Wrap(h container.ContainerHandler) container.ContainerHandler {
+	handler := &recordingHandler{ContainerHandler: h, recorder: r}
+	ref, _ := h.ContainerReference()
+	handler.name = ref.Name
+	handler.record()
+	return handler
+}
+
+// WrapTransport returns a transport recording the responses of the Docker API
+// to the inspections of the containers made through t. It can be passed to
+// docker.SetTransportWrapper.
+func (r *Recorder) WrapTransport(t http.RoundTripper) http.RoundTripper {
+	return &recordingTransport{RoundTripper: t, writer: r.writer}
+}
+
+type recordingTransport struct {
+	http.RoundTripper
+	writer *Writer
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.RoundTripper.RoundTrip(req)
+	p := dockerVersion.ReplaceAllString(req.URL.Path, "")
+	if err != nil || req.Method != http.MethodGet || !dockerRequest.MatchString(p) {
+		return resp, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	entry := Entry{Timestamp: time.Now(), Kind: kindDocker, Path: p, Status: resp.StatusCode, Data: body}
+	if writeErr := t.writer.write(entry); writeErr != nil {
+		klog.Errorf("Failed to record the response to %s: %v", p, writeErr)
+	}
+	return resp, nil
+}
+
+type recordingHandler struct {
+	container.ContainerHandler
+	name     string
+	recorder *Recorder
+	// Serializes the records of the container.
+	lock sync.Mutex
+}
+
+// record records the state of the container and the files read by its
+// handler.
+func (h *recordingHandler) record() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	now := time.Now()
+	state := containerState{Type: h.Type(), Cgroups: make(map[string]string)}
+	resources := libcontainer.SupportedSubsystems()
+	if cgroups.IsCgroup2UnifiedMode() {
+		resources = []string{""}
+	}
+	var files []string
+	dirs := make(map[string]bool)
+	for _, resource := range resources {
+		dir, err := h.GetCgroupPath(resource)
+		if err != nil {
+			continue
+		}
+		state.Cgroups[resource] = dir
+		if !dirs[dir] {
+			dirs[dir] = true
+			files = append(files, cgroupFiles(dir)...)
+		}
+	}
+	state.Pid = h.pid(state.Cgroups)
+	if state.Pid != 0 {
+		for _, file := range procFiles {
+			files = append(files, path.Join("/proc", strconv.Itoa(state.Pid), file))
+		}
+	}
+	files = append(files, hostProcFiles...)
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		klog.Errorf("Failed to record the state of %q: %v", h.name, err)
+		return
+	}
+	entries := []Entry{{Timestamp: now, Kind: kindContainer, Container: h.name, Data: data}}
+	for _, file := range files {
+		// Procfs is read under the root of the host, the cgroups are
+		// read as the handler does.
+		p := file
+		if strings.HasPrefix(file, "/proc/") {
+			p = path.Join(h.recorder.rootFs, file)
+		}
+		content, err := os.ReadFile(p)
+		if err != nil {
+			// E.g. write-only files, or the files of a kernel
+			// without the feature.
+			klog.V(5).Infof("Not recording %q of %q: %v", p, h.name, err)
+			continue
+		}
+		entries = append(entries, Entry{Timestamp: now, Kind: kindFile, Container: h.name, Path: file, Data: content})
+	}
+	if err := h.recorder.writer.write(entries...); err != nil {
+		klog.Errorf("Failed to record the reads of %q: %v", h.name, err)
+	}
+}
+
+// pid returns the process of the container whose procfs files are read by
+// its handler: init for the root container, none for the other raw cgroups,
+// and the first process of the cgroup of the other containers.
+func (h *recordingHandler) pid(cgroupDirs map[string]string) int {
+	if h.Type() == container.ContainerTypeRaw {
+		if h.name == "/" {
+			return 1
+		}
+		return 0
+	}
+	for _, dir := range cgroupDirs {
+		content, err := os.ReadFile(path.Join(dir, "cgroup.procs"))
+		if err != nil {
+			continue
+		}
+		if fields := strings.Fields(string(content)); len(fields) > 0 {
+			pid, _ := strconv.Atoi(fields[0])
+			return pid
+		}
+	}
+	return 0
+}
+
+// cgroupFiles returns the paths of the files of the cgroup at dir, without
+// the ones of its children.
+func cgroupFiles(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var files []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() {
+			files = append(files, path.Join(dir, entry.Name()))
+		}
+	}
+	return files
+}
+
+func (h *recordingHandler) GetStats() (*info.ContainerStats, error) {
+	stats, err := h.ContainerHandler.GetStats()
+	h.record()
+	return stats, err
+}
+
+func (h *recordingHandler) DisabledMetrics() []container.DisabledMetrics {
+	if disabler, ok := h.ContainerHandler.(container.MetricsDisabler); ok {
+		return disabler.DisabledMetrics()
+	}
+	return nil
+}
@@ -0,0 +1,367 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package record
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/opencontainers/runc/libcontainer/cgroups"
+
+	"github.com/yidoyoon/cadvisor-lite/container"
+	"github.com/yidoyoon/cadvisor-lite/container/common"
+	"github.com/yidoyoon/cadvisor-lite/container/docker"
+	"github.com/yidoyoon/cadvisor-lite/container/libcontainer"
+	"github.com/yidoyoon/cadvisor-lite/fs"
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+	"github.com/yidoyoon/cadvisor-lite/watcher"
+
+	dclient "github.com/docker/docker/client"
+)
+
+// The state of a container and the files read by its handler at a time.
+type snapshot struct {
+	state containerState
+	files []Entry
+}
+
+// The snapshots of a container, in order.
+type recording struct {
+	name      string
+	snapshots []*snapshot
+}
+
+// ReplayFactory is a container.ContainerHandlerFactory serving the containers
+// of an archive. It claims every container, so that only the recorded ones
+// are monitored.
+//
+// The recorded files are restored in a directory standing for the root of
+// the host, from which the stats and specs of the containers are read by the
+// libcontainer handler and the cgroup helpers. The recorded responses of the
+// Docker API are served to a Docker client, from which the metadata of the
+// docker containers are read. It must also be registered as a
+// container.Plugin, to get the machine info and the metrics to collect.
+type ReplayFactory struct {
+	recordings map[string]*recording
+	// Last recorded response of the Docker API to each request.
+	dockerResponses map[string]Entry
+	dockerClient    *dclient.Client
+	root            string
+
+	machineInfoFactory info.MachineInfoFactory
+	includedMetrics    container.MetricSet
+}
+
+// NewReplayFactory loads the archive at path. It must be closed to remove
+// the restored files.
+func NewReplayFactory(path string) (*ReplayFactory, error) {
+	entries, err := ReadArchive(path)
+	if err != nil {
+		return nil, err
+	}
+	f := &ReplayFactory{
+		recordings:      make(map[string]*recording),
+		dockerResponses: make(map[string]Entry),
+	}
+	for _, entry := range entries {
+		switch entry.Kind {
+		case kindDocker:
+			f.dockerResponses[entry.Path] = entry
+		case kindContainer:
+			s := &snapshot{}
+			if err := json.Unmarshal(entry.Data, &s.state); err != nil {
+				return nil, fmt.Errorf("invalid state of %q: %v", entry.Container, err)
+			}
+			if _, unified := s.state.Cgroups[""]; len(s.state.Cgroups) > 0 && unified != cgroups.IsCgroup2UnifiedMode() {
+				return nil, fmt.Errorf("archive %q was recorded with another cgroup version than the one of this host", path)
+			}
+			r, ok := f.recordings[entry.Container]
+			if !ok {
+				r = &recording{name: entry.Container}
+				f.recordings[entry.Container] = r
+			}
+			r.snapshots = append(r.snapshots, s)
+		case kindFile:
+			// The files follow the state of their container.
+			r, ok := f.recordings[entry.Container]
+			if !ok {
+				continue
+			}
+			s := r.snapshots[len(r.snapshots)-1]
+			s.files = append(s.files, entry)
+		}
+	}
+	if len(f.recordings) == 0 {
+		return nil, fmt.Errorf("archive %q is empty", path)
+	}
+	f.dockerClient, err = dclient.NewClientWithOpts(dclient.WithHTTPClient(&http.Client{Transport: &replayTransport{responses: f.dockerResponses}}))
+	if err != nil {
+		return nil, err
+	}
+	f.root, err = os.MkdirTemp("", "cadvisor-replay")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the directory of the restored files: %v", err)
+	}
+	// Let the cgroup files be read from the restored ones, which are not
+	// on a cgroup file system. Nothing is written to the cgroups.
+	cgroups.TestMode = true
+	return f, nil
+}
+
+// Close removes the restored files.
+func (f *ReplayFactory) Close() error {
+	return os.RemoveAll(f.root)
+}
+
+// restore writes the files of s under the root of the factory.
+func (f *ReplayFactory) restore(s *snapshot) error {
+	for _, dir := range s.state.Cgroups {
+		if err := os.MkdirAll(filepath.Join(f.root, dir), 0o755); err != nil {
+			return err
+		}
+	}
+	for _, entry := range s.files {
+		p := filepath.Join(f.root, entry.Path)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			return err
+		}
+		// Written aside and renamed, so that the handlers of other
+		// containers sharing the file do not read it partially.
+		tmp, err := os.CreateTemp(filepath.Dir(p), ".restore")
+		if err != nil {
+			return err
+		}
+		_, err = tmp.Write(entry.Data)
+		if closeErr := tmp.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			err = os.Rename(tmp.Name(), p)
+		}
+		if err != nil {
+			os.Remove(tmp.Name())
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *ReplayFactory) String() string {
+	return "replay"
+}
+
+func (f *ReplayFactory) InitializeFSContext(context *fs.Context) error {
+	return nil
+}
+
+func (f *ReplayFactory) Register(factory info.MachineInfoFactory, fsInfo fs.FsInfo, includedMetrics container.MetricSet) (watcher.ContainerWatcher, error) {
+	f.machineInfoFactory = factory
+	f.includedMetrics = includedMetrics
+	return nil, nil
+}
+
+func (f *ReplayFactory) CanHandleAndAccept(name string) (bool, bool, error) {
+	_, ok := f.recordings[name]
+	return true, ok, nil
+}
+
+func (f *ReplayFactory) NewContainerHandler(name string, metadataEnvAllowList []string, inHostNamespace bool) (container.ContainerHandler, error) {
+	r, ok := f.recordings[name]
+	if !ok {
+		return nil, fmt.Errorf("container %q was not recorded", name)
+	}
+	first := r.snapshots[0]
+	if err := f.restore(first); err != nil {
+		return nil, fmt.Errorf("failed to restore the files of %q: %v", name, err)
+	}
+	h := &replayHandler{
+		recording:   r,
+		factory:     f,
+		state:       first.state,
+		cgroupPaths: make(map[string]string, len(first.state.Cgroups)),
+		next:        1,
+	}
+	for resource, dir := range first.state.Cgroups {
+		h.cgroupPaths[resource] = filepath.Join(f.root, dir)
+	}
+	cgroupManager, err := libcontainer.NewCgroupManager(name, h.cgroupPaths)
+	if err != nil {
+		return nil, err
+	}
+	h.handler = libcontainer.NewHandler(cgroupManager, f.root, first.state.Pid, f.includedMetrics)
+	if first.state.Type == container.ContainerTypeDocker {
+		metadata, err := docker.InspectMetadata(f.dockerClient, name, metadataEnvAllowList)
+		if err != nil {
+			return nil, err
+		}
+		h.metadata = &metadata
+	}
+	return h, nil
+}
+
+func (f *ReplayFactory) DebugInfo() map[string][]string {
+	names := make([]string, 0, len(f.recordings))
+	for name, r := range f.recordings {
+		names = append(names, fmt.Sprintf("%s: %d snapshots", name, len(r.snapshots)))
+	}
+	sort.Strings(names)
+	return map[string][]string{"Replayed containers": names}
+}
+
+// Returns the recorded containers under name, direct children only unless
+// recursive.
+func (f *ReplayFactory) subcontainers(name string, recursive bool) []info.ContainerReference {
+	prefix := strings.TrimSuffix(name, "/") + "/"
+	var refs []info.ContainerReference
+	for sub := range f.recordings {
+		if sub == name || !strings.HasPrefix(sub, prefix) {
+			continue
+		}
+		if !recursive && strings.Contains(sub[len(prefix):], "/") {
+			continue
+		}
+		refs = append(refs, info.ContainerReference{Name: sub})
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].Name < refs[j].Name })
+	return refs
+}
+
+// replayTransport serves the recorded responses of the Docker API.
+type replayTransport struct {
+	responses map[string]Entry
+}
+
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	status, body := http.StatusNotFound, []byte(`{"message":"not recorded"}`)
+	if entry, ok := t.responses[dockerVersion.ReplaceAllString(req.URL.Path, "")]; ok {
+		status, body = entry.Status, entry.Data
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// replayHandler reads the stats of a container from its recorded files, with
+// the libcontainer handler. Every call to GetStats restores the next
+// recorded files, then returns no stats once the recording is exhausted.
+type replayHandler struct {
+	recording *recording
+	factory   *ReplayFactory
+	// State of the container when its handler was created.
+	state       containerState
+	cgroupPaths map[string]string
+	handler     *libcontainer.Handler
+	// Metadata of a docker container, from its recorded inspection.
+	metadata *docker.Metadata
+
+	lock sync.Mutex
+	next int
+}
+
+func (h *replayHandler) ContainerReference() (info.ContainerReference, error) {
+	if h.metadata != nil {
+		return h.metadata.Reference, nil
+	}
+	return info.ContainerReference{Name: h.recording.name}, nil
+}
+
+func (h *replayHandler) GetSpec() (info.ContainerSpec, error) {
+	hasNetwork := h.state.Pid != 0 && h.factory.includedMetrics.Has(container.NetworkUsageMetrics)
+	spec, err := common.GetSpec(h.cgroupPaths, h.factory.machineInfoFactory, hasNetwork, false)
+	if h.metadata != nil {
+		h.metadata.UpdateSpec(&spec)
+		spec.Security = h.handler.GetSecuritySpec()
+	}
+	return spec, err
+}
+
+func (h *replayHandler) GetStats() (*info.ContainerStats, error) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if h.next >= len(h.recording.snapshots) {
+		return nil, nil
+	}
+	s := h.recording.snapshots[h.next]
+	h.next++
+	if err := h.factory.restore(s); err != nil {
+		return nil, fmt.Errorf("failed to restore the files of %q: %v", h.recording.name, err)
+	}
+	return h.handler.GetStats()
+}
+
+func (h *replayHandler) ListContainers(listType container.ListType) ([]info.ContainerReference, error) {
+	return h.factory.subcontainers(h.recording.name, listType == container.ListRecursive), nil
+}
+
+func (h *replayHandler) ListProcesses(listType container.ListType) ([]int, error) {
+	return h.handler.GetProcesses()
+}
+
+func (h *replayHandler) GetCgroupPath(resource string) (string, error) {
+	if cgroups.IsCgroup2UnifiedMode() {
+		resource = ""
+	}
+	path, ok := h.cgroupPaths[resource]
+	if !ok {
+		return "", fmt.Errorf("could not find path for resource %q for container %q", resource, h.recording.name)
+	}
+	return path, nil
+}
+
+func (h *replayHandler) GetContainerLabels() map[string]string {
+	if h.metadata != nil {
+		return h.metadata.Labels
+	}
+	return nil
+}
+
+func (h *replayHandler) GetContainerIPAddress() string {
+	if h.metadata != nil {
+		return h.metadata.IPAddress
+	}
+	return ""
+}
+
+func (h *replayHandler) Type() container.ContainerType {
+	return h.state.Type
+}
+
+func (h *replayHandler) DisabledMetrics() []container.DisabledMetrics {
+	return h.handler.DisabledMetrics()
+}
+
+func (h *replayHandler) Exists() bool {
+	return true
+}
+
+func (h *replayHandler) Cleanup() {}
+
+func (h *replayHandler) Start() {}
@@ -19,6 +19,7 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	info "github.com/yidoyoon/cadvisor-lite/info/v1"
@@ -83,7 +84,7 @@ type EventManager interface {
 	// GetEvents() returns all detected events based on the filters specified in request.
 	GetEvents(request *Request) ([]*info.Event, error)
 	// AddEvent allows the caller to add an event to an EventManager
-	// object
+	// object, and sets the id of the event.
 	AddEvent(event *info.Event) error
 	// Cancels a previously requested watch event.
 	StopWatch(watchID int)
@@ -101,6 +102,8 @@ type events struct {
 	watcherLock sync.RWMutex
 	// last allocated watch id.
 	lastID int
+	// last allocated event id, accessed atomically.
+	lastEventID uint64
 	// Event storage policy.
 	storagePolicy StoragePolicy
 }
@@ -156,6 +159,27 @@ func NewEventManager(storagePolicy StoragePolicy) EventManager {
 	}
 }
 
+// TypeOptions are the query parameters of the events API selecting each type
+// of events, e.g. oom_events=true for the OOM events.
+var TypeOptions = map[string]info.EventType{
+	"oom_events":              info.EventOom,
+	"oom_kill_events":         info.EventOomKill,
+	"creation_events":         info.EventContainerCreation,
+	"deletion_events":         info.EventContainerDeletion,
+	"alert_events":            info.EventAlert,
+	"anomaly_events":          info.EventAnomaly,
+	"pids_limit_events":       info.EventPidsLimit,
+	"network_drops_events":    info.EventNetworkDrops,
+	"cpuset_change_events":    info.EventCpusetChange,
+	"start_latency_events":    info.EventStartLatency,
+	"disk_quota_events":       info.EventDiskQuota,
+	"image_pull_events":       info.EventImagePull,
+	"metrics_disabled_events": info.EventMetricsDisabled,
+	"machine_rebooted_events": info.EventMachineRebooted,
+	"limits_change_events":    info.EventLimitsChange,
+	"log_size_events":         info.EventLogSize,
+}
+
 // returns a pointer to an initialized Request object
 func NewRequest() *Request {
 	return &Request{
@@ -312,9 +336,11 @@ func (e *events) findValidWatchers(event *info.Event) []*watch {
 }
 
 // method of Events object that adds the argument Event object to the
-// eventStore. It also feeds the event to a set of watch channels
-// held by the manager if it satisfies the request keys of the channels
+// eventStore, after giving it the next event id. It also feeds the event to a
+// set of watch channels held by the manager if it satisfies the request keys
+// of the channels
 func (e *events) AddEvent(event *info.Event) error {
+	event.Id = atomic.AddUint64(&e.lastEventID, 1)
 	e.updateEventStore(event)
 	e.watcherLock.RLock()
 	defer e.watcherLock.RUnlock()
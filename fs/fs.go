@@ -43,6 +43,7 @@ const (
 	LabelSystemRoot          = "root"
 	LabelDockerImages        = "docker-images"
 	LabelCrioImages          = "crio-images"
+	LabelPodmanImages        = "podman-images"
 	DriverStatusPoolName     = "Pool Name"
 	DriverStatusDataLoopFile = "Data loop file"
 )
@@ -128,6 +129,7 @@ func NewFsInfo(context Context) (FsInfo, error) {
 	// add a "partition" for devicemapper to fsInfo.partitions
 	fsInfo.addDockerImagesLabel(context, mounts)
 	fsInfo.addCrioImagesLabel(context, mounts)
+	fsInfo.addPodmanImagesLabel(context, mounts)
 
 	klog.V(1).Infof("Filesystem UUIDs: %+v", fsInfo.fsUUIDToDeviceName)
 	klog.V(1).Infof("Filesystem partitions: %+v", fsInfo.partitions)
@@ -311,6 +313,32 @@ func (i *RealFsInfo) addCrioImagesLabel(context Context, mounts []*mount.Info) {
 	}
 }
 
+// addPodmanImagesLabel attempts to determine which device contains the graph root of podman.
+func (i *RealFsInfo) addPodmanImagesLabel(context Context, mounts []*mount.Info) {
+	if context.Podman.Root != "" {
+		i.updateContainerImagesPath(LabelPodmanImages, mounts, getPodmanImagePaths(context))
+	}
+}
+
+// Generate a list of possible mount points for podman image management from the graph root and
+// the graph driver reported by the libpod info.
+func getPodmanImagePaths(context Context) map[string]struct{} {
+	podmanImagePaths := map[string]struct{}{
+		"/": {},
+	}
+
+	podmanRoot := context.Podman.Root
+	if context.Podman.Driver != "" {
+		podmanImagePaths[path.Join(podmanRoot, context.Podman.Driver)] = struct{}{}
+		podmanImagePaths[path.Join(podmanRoot, context.Podman.Driver+"-images")] = struct{}{}
+	}
+	for podmanRoot != "/" && podmanRoot != "." {
+		podmanImagePaths[podmanRoot] = struct{}{}
+		podmanRoot = filepath.Dir(podmanRoot)
+	}
+	return podmanImagePaths
+}
+
 // Generate a list of possible mount points for docker image management from the docker root directory.
 // Right now, we look for each type of supported graph driver directories, but we can do better by parsing
 // some of the context from `docker info`.
@@ -691,6 +719,15 @@ func (i *RealFsInfo) GetDirUsage(dir string) (UsageInfo, error) {
 	return GetDirUsage(dir)
 }
 
+// GetDirCapacity returns the capacity, in bytes, of the filesystem of dir as
+// seen from dir. XFS and ext4 report the limit of the project quota of dir
+// instead of the capacity of the filesystem, e.g. for the writable layers of
+// the overlay2 driver of Docker with the size storage option.
+func GetDirCapacity(dir string) (uint64, error) {
+	total, _, _, _, _, err := getVfsStats(dir)
+	return total, err
+}
+
 func getVfsStats(path string) (total uint64, free uint64, avail uint64, inodes uint64, inodesFree uint64, err error) {
 	var s syscall.Statfs_t
 	if err = syscall.Statfs(path, &s); err != nil {
@@ -590,3 +590,78 @@ func TestProcessMounts(t *testing.T) {
 		}
 	}
 }
+
+func TestAddPodmanImagesLabel(t *testing.T) {
+	tests := []struct {
+		name                 string
+		root                 string
+		mounts               []*mount.Info
+		expectedPodmanDevice string
+	}{
+		{
+			name: "no podman root",
+			mounts: []*mount.Info{
+				{
+					Source:     "/dev/sda1",
+					Mountpoint: "/",
+					FSType:     "ext4",
+				},
+			},
+			expectedPodmanDevice: "",
+		},
+		{
+			name: "graph root on root fs",
+			root: "/var/lib/containers/storage",
+			mounts: []*mount.Info{
+				{
+					Source:     "/dev/sda1",
+					Mountpoint: "/",
+					FSType:     "ext4",
+				},
+			},
+			expectedPodmanDevice: "/dev/sda1",
+		},
+		{
+			name: "graph driver mount - innermost check",
+			root: "/var/lib/containers/storage",
+			mounts: []*mount.Info{
+				{
+					Source:     "/dev/sda1",
+					Mountpoint: "/",
+					FSType:     "ext4",
+				},
+				{
+					Source:     "/dev/sdb1",
+					Mountpoint: "/var/lib/containers",
+					FSType:     "ext4",
+				},
+				{
+					Source:     "/dev/sdb2",
+					Mountpoint: "/var/lib/containers/storage/overlay",
+					FSType:     "xfs",
+				},
+			},
+			expectedPodmanDevice: "/dev/sdb2",
+		},
+	}
+
+	for _, tt := range tests {
+		fsInfo := &RealFsInfo{
+			labels:     map[string]string{},
+			partitions: map[string]partition{},
+		}
+
+		context := Context{
+			Podman: PodmanContext{
+				Root:   tt.root,
+				Driver: "overlay",
+			},
+		}
+
+		fsInfo.addPodmanImagesLabel(context, tt.mounts)
+
+		if e, a := tt.expectedPodmanDevice, fsInfo.labels[LabelPodmanImages]; e != a {
+			t.Errorf("%s: podman device: expected %q, got %q", tt.name, e, a)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package grpcapi contains the messages and the service of the gRPC API of
+// cAdvisor, generated from cadvisor.proto, and their conversions from the v1
+// and v2 API types.
+package grpcapi
+
+//go:generate protoc --go_out=plugins=grpc:. --go_opt=paths=source_relative cadvisor.proto
+
+import (
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	v1 "github.com/yidoyoon/cadvisor-lite/info/v1"
+	v2 "github.com/yidoyoon/cadvisor-lite/info/v2"
+)
+
+// ContainerInfoFromV1 converts the information about a container.
+func ContainerInfoFromV1(cinfo *v1.ContainerInfo) *ContainerInfo {
+	out := &ContainerInfo{
+		Name:      cinfo.Name,
+		Aliases:   cinfo.Aliases,
+		Namespace: cinfo.Namespace,
+		Spec:      ContainerSpecFromV1(&cinfo.Spec),
+		Stats:     make([]*ContainerStats, 0, len(cinfo.Stats)),
+	}
+	for _, stats := range cinfo.Stats {
+		out.Stats = append(out.Stats, ContainerStatsFromV1(stats))
+	}
+	return out
+}
+
+// ContainerSpecFromV1 converts the spec of a container.
+func ContainerSpecFromV1(spec *v1.ContainerSpec) *ContainerSpec {
+	return &ContainerSpec{
+		CreationTime:      timestamppb.New(spec.CreationTime),
+		Labels:            spec.Labels,
+		Image:             spec.Image,
+		HasCpu:            spec.HasCpu,
+		CpuLimit:          spec.Cpu.Limit,
+		CpuMaxLimit:       spec.Cpu.MaxLimit,
+		CpuMask:           spec.Cpu.Mask,
+		HasMemory:         spec.HasMemory,
+		MemoryLimit:       spec.Memory.Limit,
+		MemoryReservation: spec.Memory.Reservation,
+		MemorySwapLimit:   spec.Memory.SwapLimit,
+		HasNetwork:        spec.HasNetwork,
+		HasFilesystem:     spec.HasFilesystem,
+		HasProcesses:      spec.HasProcesses,
+	}
+}
+
+// ContainerStatsFromV1 converts a stats sample of a container. The name of
+// the container is left empty.
+func ContainerStatsFromV1(stats *v1.ContainerStats) *ContainerStats {
+	out := &ContainerStats{
+		Timestamp: timestamppb.New(stats.Timestamp),
+		Sequence:  stats.Sequence,
+		Cpu: &CpuStats{
+			UsageTotal:          stats.Cpu.Usage.Total,
+			UsageUser:           stats.Cpu.Usage.User,
+			UsageSystem:         stats.Cpu.Usage.System,
+			UsagePerCpu:         stats.Cpu.Usage.PerCpu,
+			CfsPeriods:          stats.Cpu.CFS.Periods,
+			CfsThrottledPeriods: stats.Cpu.CFS.ThrottledPeriods,
+			CfsThrottledTime:    stats.Cpu.CFS.ThrottledTime,
+		},
+		Memory: &MemoryStats{
+			Usage:      stats.Memory.Usage,
+			MaxUsage:   stats.Memory.MaxUsage,
+			Cache:      stats.Memory.Cache,
+			Rss:        stats.Memory.RSS,
+			Swap:       stats.Memory.Swap,
+			WorkingSet: stats.Memory.WorkingSet,
+			Failcnt:    stats.Memory.Failcnt,
+		},
+		Processes: &ProcessStats{
+			ProcessCount:   stats.Processes.ProcessCount,
+			FdCount:        stats.Processes.FdCount,
+			ThreadsCurrent: stats.Processes.ThreadsCurrent,
+			ThreadsMax:     stats.Processes.ThreadsMax,
+		},
+	}
+	out.Network = interfaceStatsFromV1(stats.Network.Interfaces)
+	for _, fs := range stats.Filesystem {
+		out.Filesystem = append(out.Filesystem, &FsStats{
+			Device:     fs.Device,
+			Type:       fs.Type,
+			Limit:      fs.Limit,
+			Usage:      fs.Usage,
+			Available:  fs.Available,
+			Inodes:     fs.Inodes,
+			InodesFree: fs.InodesFree,
+		})
+	}
+	return out
+}
+
+// interfaceStatsFromV1 converts the stats of the network interfaces.
+func interfaceStatsFromV1(ifaces []v1.InterfaceStats) []*InterfaceStats {
+	var out []*InterfaceStats
+	for _, iface := range ifaces {
+		out = append(out, &InterfaceStats{
+			Name:      iface.Name,
+			RxBytes:   iface.RxBytes,
+			RxPackets: iface.RxPackets,
+			RxErrors:  iface.RxErrors,
+			RxDropped: iface.RxDropped,
+			TxBytes:   iface.TxBytes,
+			TxPackets: iface.TxPackets,
+			TxErrors:  iface.TxErrors,
+			TxDropped: iface.TxDropped,
+		})
+	}
+	return out
+}
+
+// ContainerInfoFromV2 converts the spec and stats of the container name.
+func ContainerInfoFromV2(name string, cinfo *v2.ContainerInfo) *ContainerInfo {
+	out := &ContainerInfo{
+		Name:      name,
+		Aliases:   cinfo.Spec.Aliases,
+		Namespace: cinfo.Spec.Namespace,
+		Spec:      ContainerSpecFromV2(&cinfo.Spec),
+		Stats:     make([]*ContainerStats, 0, len(cinfo.Stats)),
+	}
+	for _, stats := range cinfo.Stats {
+		out.Stats = append(out.Stats, ContainerStatsFromV2(stats))
+	}
+	return out
+}
+
+// ContainerSpecFromV2 converts the spec of a container.
+func ContainerSpecFromV2(spec *v2.ContainerSpec) *ContainerSpec {
+	return &ContainerSpec{
+		CreationTime:      timestamppb.New(spec.CreationTime),
+		Labels:            spec.Labels,
+		Image:             spec.Image,
+		HasCpu:            spec.HasCpu,
+		CpuLimit:          spec.Cpu.Limit,
+		CpuMaxLimit:       spec.Cpu.MaxLimit,
+		CpuMask:           spec.Cpu.Mask,
+		HasMemory:         spec.HasMemory,
+		MemoryLimit:       spec.Memory.Limit,
+		MemoryReservation: spec.Memory.Reservation,
+		MemorySwapLimit:   spec.Memory.SwapLimit,
+		HasNetwork:        spec.HasNetwork,
+		HasFilesystem:     spec.HasFilesystem,
+		HasProcesses:      spec.HasProcesses,
+	}
+}
+
+// ContainerStatsFromV2 converts a stats sample of a container. The name of
+// the container is left empty, and the resources left out of the sample, e.g.
+// by the fields option, are left unset. The filesystem usage and quota of the
+// container are reported as a filesystem without device.
+func ContainerStatsFromV2(stats *v2.ContainerStats) *ContainerStats {
+	out := &ContainerStats{
+		Timestamp: timestamppb.New(stats.Timestamp),
+		Sequence:  stats.Sequence,
+	}
+	if stats.Cpu != nil {
+		out.Cpu = &CpuStats{
+			UsageTotal:          stats.Cpu.Usage.Total,
+			UsageUser:           stats.Cpu.Usage.User,
+			UsageSystem:         stats.Cpu.Usage.System,
+			UsagePerCpu:         stats.Cpu.Usage.PerCpu,
+			CfsPeriods:          stats.Cpu.CFS.Periods,
+			CfsThrottledPeriods: stats.Cpu.CFS.ThrottledPeriods,
+			CfsThrottledTime:    stats.Cpu.CFS.ThrottledTime,
+		}
+	}
+	if stats.Memory != nil {
+		out.Memory = &MemoryStats{
+			Usage:      stats.Memory.Usage,
+			MaxUsage:   stats.Memory.MaxUsage,
+			Cache:      stats.Memory.Cache,
+			Rss:        stats.Memory.RSS,
+			Swap:       stats.Memory.Swap,
+			WorkingSet: stats.Memory.WorkingSet,
+			Failcnt:    stats.Memory.Failcnt,
+		}
+	}
+	if stats.Processes != nil {
+		out.Processes = &ProcessStats{
+			ProcessCount:   stats.Processes.ProcessCount,
+			FdCount:        stats.Processes.FdCount,
+			ThreadsCurrent: stats.Processes.ThreadsCurrent,
+			ThreadsMax:     stats.Processes.ThreadsMax,
+		}
+	}
+	if stats.Network != nil {
+		out.Network = interfaceStatsFromV1(stats.Network.Interfaces)
+	}
+	if stats.Filesystem != nil && stats.Filesystem.TotalUsageBytes != nil {
+		fs := &FsStats{Usage: *stats.Filesystem.TotalUsageBytes}
+		if stats.Filesystem.QuotaBytes != nil {
+			fs.Limit = *stats.Filesystem.QuotaBytes
+		}
+		out.Filesystem = append(out.Filesystem, fs)
+	}
+	return out
+}
+
+// MachineInfoFromV1 converts the information about the machine.
+func MachineInfoFromV1(minfo *v1.MachineInfo) *MachineInfo {
+	return &MachineInfo{
+		Timestamp:        timestamppb.New(minfo.Timestamp),
+		NumCores:         int32(minfo.NumCores),
+		NumPhysicalCores: int32(minfo.NumPhysicalCores),
+		NumSockets:       int32(minfo.NumSockets),
+		CpuFrequencyKhz:  minfo.CpuFrequency,
+		MemoryCapacity:   minfo.MemoryCapacity,
+		SwapCapacity:     minfo.SwapCapacity,
+		MachineId:        minfo.MachineID,
+		SystemUuid:       minfo.SystemUUID,
+		BootId:           minfo.BootID,
+		CloudProvider:    string(minfo.CloudProvider),
+		InstanceType:     string(minfo.InstanceType),
+		InstanceId:       string(minfo.InstanceID),
+	}
+}
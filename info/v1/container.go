@@ -25,6 +25,8 @@ type CpuSpec struct {
 	Mask     string `json:"mask,omitempty"`
 	Quota    uint64 `json:"quota,omitempty"`
 	Period   uint64 `json:"period,omitempty"`
+	// Memory nodes the container may allocate on, e.g. "0-1".
+	Mems string `json:"mems,omitempty"`
 }
 
 type MemorySpec struct {
@@ -45,10 +47,64 @@ type ProcessSpec struct {
 	Limit uint64 `json:"limit,omitempty"`
 }
 
+// Port of a container published on the host by its runtime.
+type PortMapping struct {
+	// Protocol of the port: tcp, udp or sctp.
+	Protocol      string `json:"protocol"`
+	ContainerPort uint16 `json:"container_port"`
+	// Address of the host the port is published on, empty for all the
+	// addresses.
+	HostIP   string `json:"host_ip,omitempty"`
+	HostPort uint16 `json:"host_port"`
+}
+
+// Security profile of the init process of a container, as applied by its
+// runtime.
+type SecuritySpec struct {
+	// SELinux label of the process, e.g. system_u:system_r:container_t:s0:c1,c2.
+	// Empty if SELinux is not enabled.
+	SELinuxLabel string `json:"selinux_label,omitempty"`
+
+	// AppArmor profile confining the process, e.g. docker-default, or
+	// unconfined. Empty if AppArmor is not enabled.
+	AppArmorProfile string `json:"apparmor_profile,omitempty"`
+	// Mode of the AppArmor profile: enforce, complain or kill. Empty for
+	// unconfined processes.
+	AppArmorMode string `json:"apparmor_mode,omitempty"`
+
+	// Seccomp mode of the process: disabled, strict or filter.
+	SeccompMode string `json:"seccomp_mode,omitempty"`
+
+	// Whether the process and its children cannot gain privileges, e.g.
+	// through setuid binaries.
+	NoNewPrivileges bool `json:"no_new_privileges"`
+
+	// Capabilities of the process, by set.
+	Capabilities CapabilitiesSpec `json:"capabilities"`
+}
+
+// Capabilities of a process, named as in capabilities(7), e.g. CAP_NET_ADMIN.
+type CapabilitiesSpec struct {
+	Effective   []string `json:"effective,omitempty"`
+	Permitted   []string `json:"permitted,omitempty"`
+	Inheritable []string `json:"inheritable,omitempty"`
+	Bounding    []string `json:"bounding,omitempty"`
+	Ambient     []string `json:"ambient,omitempty"`
+}
+
 type ContainerSpec struct {
 	// Time at which the container was created.
 	CreationTime time.Time `json:"creation_time,omitempty"`
 
+	// Time at which the runtime last started the container. Only reported
+	// by docker and podman.
+	StartedAt time.Time `json:"started_at,omitempty"`
+
+	// Time between the discovery of the container, when its cgroup
+	// appeared, and its first stats. Only known for the containers created
+	// since cAdvisor started, once they have stats.
+	FirstStatsLatency time.Duration `json:"first_stats_latency,omitempty"`
+
 	// Metadata labels associated with this container.
 	Labels map[string]string `json:"labels,omitempty"`
 	// Metadata envs associated with this container. Only whitelisted envs are added.
@@ -67,16 +123,71 @@ type ContainerSpec struct {
 	HasProcesses bool        `json:"has_processes"`
 	Processes    ProcessSpec `json:"processes,omitempty"`
 
+	// Security profile of the container, as read from its init process.
+	// Only reported by docker, containerd, crio and podman.
+	Security *SecuritySpec `json:"security,omitempty"`
+
+	// Ports of the container published on the host, sorted by container
+	// port and protocol. Only reported by docker and podman.
+	Ports []PortMapping `json:"ports,omitempty"`
+
 	HasFilesystem bool `json:"has_filesystem"`
 
+	// Number of bytes the writable layer of the container may use, as set
+	// by its runtime, e.g. with the size storage option of Docker. Zero if
+	// there is no quota.
+	DiskQuota uint64 `json:"disk_quota,omitempty"`
+
 	// HasDiskIo when true, indicates that DiskIo stats will be available.
 	HasDiskIo bool `json:"has_diskio"`
+	// Devices which the container did I/O on, sorted by major:minor number,
+	// with their names as known to the machine.
+	DiskIoDevices []BlockDevice `json:"diskio_devices,omitempty"`
+
+	// Kinds of the metrics, e.g. diskIO, partly or no longer read for the
+	// container after the reads of one of their sources kept failing, sorted.
+	// Their values in the stats are not meaningful.
+	DisabledMetrics []string `json:"disabled_metrics,omitempty"`
 
 	HasCustomMetrics bool         `json:"has_custom_metrics"`
 	CustomMetrics    []MetricSpec `json:"custom_metrics,omitempty"`
 
 	// Image name used for this container.
 	Image string `json:"image,omitempty"`
+
+	// Container runtime managing the container, e.g. docker, containerd,
+	// crio, podman or raw for cgroups not managed by a runtime.
+	Runtime string `json:"runtime,omitempty"`
+
+	// Id of the container in its runtime, e.g. the full container id for
+	// docker. Empty for raw containers.
+	RuntimeId string `json:"runtime_id,omitempty"`
+
+	// Identifier of the container set by cAdvisor, derived from its runtime
+	// id, or its name for raw containers, and its creation time. It is the
+	// same across cAdvisor restarts, and differs between the containers
+	// which successively take the same name.
+	Uid string `json:"uid,omitempty"`
+
+	// Absolute path of the cgroup of the container. On cgroup v1, the path
+	// in the cpu hierarchy, or in the memory hierarchy without cpu.
+	CgroupPath string `json:"cgroup_path,omitempty"`
+
+	// Availability of the metrics read from the cgroup of the container, by
+	// kind of metrics (cpu, memory, diskIO, hugetlb and process). The stats
+	// of an unavailable kind are zero because they cannot be read, not
+	// because nothing is used.
+	MetricsAvailability map[string]MetricAvailability `json:"metrics_availability,omitempty"`
+}
+
+// MetricAvailability tells whether a kind of metrics can be collected for a
+// container.
+type MetricAvailability struct {
+	Available bool `json:"available"`
+
+	// Why the metrics cannot be collected, e.g. the cgroup controller is not
+	// enabled for the container or cAdvisor has no permission to read it.
+	Reason string `json:"reason,omitempty"`
 }
 
 // Container reference contains enough information to uniquely identify a container
@@ -183,6 +294,18 @@ func (ci *ContainerInfo) Eq(b *ContainerInfo) bool {
 	return true
 }
 
+// StartLatency returns the time between the creation of the container by its
+// runtime and its start, or zero if the runtime does not report its start or
+// if the container restarted since, its last start then being unrelated to
+// its creation. The restarts are known from the restartcount label set by
+// the runtimes reporting them.
+func (s *ContainerSpec) StartLatency() time.Duration {
+	if _, restarted := s.Labels["restartcount"]; restarted || !s.StartedAt.After(s.CreationTime) {
+		return 0
+	}
+	return s.StartedAt.Sub(s.CreationTime)
+}
+
 func (s *ContainerSpec) Eq(b *ContainerSpec) bool {
 	// Creation within 1s of each other.
 	diff := s.CreationTime.Sub(b.CreationTime)
@@ -214,6 +337,9 @@ func (s *ContainerSpec) Eq(b *ContainerSpec) bool {
 	if s.HasFilesystem != b.HasFilesystem {
 		return false
 	}
+	if s.DiskQuota != b.DiskQuota {
+		return false
+	}
 	if s.HasDiskIo != b.HasDiskIo {
 		return false
 	}
@@ -223,6 +349,15 @@ func (s *ContainerSpec) Eq(b *ContainerSpec) bool {
 	if s.Image != b.Image {
 		return false
 	}
+	if !reflect.DeepEqual(s.Security, b.Security) {
+		return false
+	}
+	if !reflect.DeepEqual(s.Ports, b.Ports) {
+		return false
+	}
+	if !reflect.DeepEqual(s.MetricsAvailability, b.MetricsAvailability) {
+		return false
+	}
 	return true
 }
 
@@ -289,6 +424,11 @@ type CpuUsage struct {
 	// Unit: nanoseconds.
 	PerCpu []uint64 `json:"per_cpu_usage,omitempty"`
 
+	// Usage of the container summed per socket, indexed by socket id, set
+	// instead of PerCpu when the per-CPU usage is collected per socket.
+	// Unit: nanoseconds.
+	PerSocket []uint64 `json:"per_socket_usage,omitempty"`
+
 	// Time spent in user space.
 	// Unit: nanoseconds.
 	User uint64 `json:"user"`
@@ -399,10 +539,67 @@ type MemoryStats struct {
 	// Units: Bytes.
 	KernelUsage uint64 `json:"kernel"`
 
+	// Breakdown of the kernel memory charged to the container.
+	Kernel MemoryKernelStats `json:"kernel_stats,omitempty"`
+
 	ContainerData    MemoryStatsMemoryData `json:"container_data,omitempty"`
 	HierarchicalData MemoryStatsMemoryData `json:"hierarchical_data,omitempty"`
 }
 
+// MemoryKernelStats holds the kernel memory charged to a container, by kind of
+// allocation. Fields are zero where the kernel does not expose them, the slab,
+// stack, page table and per-cpu breakdown is only available on cgroup v2.
+type MemoryKernelStats struct {
+	// Memory used by the kernel stacks of the tasks.
+	// Units: Bytes.
+	KernelStack uint64 `json:"kernel_stack"`
+
+	// Memory used by page tables.
+	// Units: Bytes.
+	PageTables uint64 `json:"page_tables"`
+
+	// Memory used by per-cpu kernel data structures.
+	// Units: Bytes.
+	Percpu uint64 `json:"percpu"`
+
+	// Memory used by network transmission buffers.
+	// Units: Bytes.
+	Sock uint64 `json:"sock"`
+
+	// Slab memory that might be reclaimed, such as dentries and inodes.
+	// Units: Bytes.
+	SlabReclaimable uint64 `json:"slab_reclaimable"`
+
+	// Slab memory that cannot be reclaimed on memory pressure.
+	// Units: Bytes.
+	SlabUnreclaimable uint64 `json:"slab_unreclaimable"`
+
+	// Slab caches of the machine using the most memory, largest first. Only
+	// reported for the root container.
+	TopSlabCaches []SlabCache `json:"top_slab_caches,omitempty"`
+}
+
+// SlabCache holds the usage of a kernel slab cache, as reported by
+// /proc/slabinfo.
+type SlabCache struct {
+	// Name of the cache, e.g. dentry.
+	Name string `json:"name"`
+
+	// Number of objects in use.
+	ActiveObjects uint64 `json:"active_objects"`
+
+	// Number of allocated objects.
+	Objects uint64 `json:"objects"`
+
+	// Size of an object.
+	// Units: Bytes.
+	ObjectSize uint64 `json:"object_size"`
+
+	// Memory used by the slabs of the cache.
+	// Units: Bytes.
+	Size uint64 `json:"size"`
+}
+
 type CPUSetStats struct {
 	MemoryMigrate uint64 `json:"memory_migrate"`
 }
@@ -453,6 +650,77 @@ type NetworkStats struct {
 	Udp6 UdpStat `json:"udp6"`
 	// TCP advanced stats
 	TcpAdvanced TcpAdvancedStat `json:"tcp_advanced"`
+	// Connection tracking stats
+	Conntrack ConntrackStat `json:"conntrack"`
+	// Counters of the netfilter chains of the machine. Only reported for the
+	// root container.
+	Netfilter []NetfilterChain `json:"netfilter,omitempty"`
+	// Statistics of the traffic control qdiscs and classes of the interfaces
+	// of the container.
+	Tc []TcStats `json:"tc,omitempty"`
+}
+
+// TcStats holds the statistics of a queueing discipline (qdisc), or of one
+// of its classes, of the traffic control (tc) of an interface.
+type TcStats struct {
+	// Name of the interface.
+	Interface string `json:"interface"`
+	// Whether the interface is the host side of a veth pair of the container
+	// rather than an interface of its network namespace.
+	Host bool `json:"host,omitempty"`
+	// Whether these are the statistics of a class rather than of a qdisc.
+	Class bool `json:"class,omitempty"`
+	// Kind of the qdisc, or of the qdisc of the class, e.g. tbf or htb.
+	Kind string `json:"kind"`
+	// Handle of the qdisc or class, e.g. 1: or 1:10.
+	Handle string `json:"handle"`
+	// Handle of the parent, root for a root qdisc.
+	Parent string `json:"parent"`
+	// Cumulative count of bytes sent.
+	Bytes uint64 `json:"bytes"`
+	// Cumulative count of packets sent.
+	Packets uint64 `json:"packets"`
+	// Number of bytes queued.
+	Backlog uint64 `json:"backlog"`
+	// Number of packets queued.
+	Qlen uint64 `json:"qlen"`
+	// Cumulative count of packets dropped.
+	Drops uint64 `json:"drops"`
+	// Cumulative count of packets which exceeded the rate limit, and were
+	// delayed or dropped.
+	Overlimits uint64 `json:"overlimits"`
+	// Cumulative count of packets requeued.
+	Requeues uint64 `json:"requeues"`
+}
+
+// NetfilterChain holds the packet and byte counters of a chain of the
+// netfilter (iptables) tables of the machine.
+type NetfilterChain struct {
+	// Address family of the table, ipv4 or ipv6.
+	Family string `json:"family"`
+	// Name of the table, e.g. filter.
+	Table string `json:"table"`
+	// Name of the chain, e.g. FORWARD.
+	Name string `json:"name"`
+	// Policy of a built-in chain, e.g. ACCEPT. Empty for user defined chains.
+	Policy string `json:"policy,omitempty"`
+	// Count of packets the policy of the chain was applied to.
+	PolicyPackets uint64 `json:"policy_packets"`
+	// Count of bytes the policy of the chain was applied to.
+	PolicyBytes uint64 `json:"policy_bytes"`
+	// Rules of the chain, in order.
+	Rules []NetfilterRule `json:"rules,omitempty"`
+}
+
+// NetfilterRule holds the packet and byte counters of a netfilter rule.
+type NetfilterRule struct {
+	// Specification of the rule as printed by iptables-save, without the
+	// chain, e.g. "-o eth0 -j ACCEPT".
+	Spec string `json:"spec"`
+	// Count of packets matched by the rule.
+	Packets uint64 `json:"packets"`
+	// Count of bytes matched by the rule.
+	Bytes uint64 `json:"bytes"`
 }
 
 type TcpStat struct {
@@ -733,6 +1001,22 @@ type UdpStat struct {
 	TxQueued uint64
 }
 
+type ConntrackStat struct {
+	// Number of connection tracking entries of the network namespace.
+	Entries uint64 `json:"entries"`
+	// Maximum number of entries, beyond which new connections are dropped
+	// (nf_conntrack_max). The limit is host-wide, the same for all the
+	// network namespaces.
+	Max uint64 `json:"max"`
+	// Number of entries which could not be inserted in the table.
+	InsertFailed uint64 `json:"insert_failed"`
+	// Number of packets dropped because of a failed entry insertion.
+	Drop uint64 `json:"drop"`
+	// Number of entries evicted to make room for new ones when the table
+	// was full.
+	EarlyDrop uint64 `json:"early_drop"`
+}
+
 type FsStats struct {
 	// The block device name associated with the filesystem.
 	Device string `json:"device,omitempty"`
@@ -753,6 +1037,18 @@ type FsStats struct {
 	// Number of bytes available for non-root user.
 	Available uint64 `json:"available"`
 
+	// Number of bytes the container may use on this filesystem under a
+	// quota, e.g. an XFS project quota. Zero if there is no quota.
+	Quota uint64 `json:"quota,omitempty"`
+
+	// Number of bytes used by the log file of the container and the files
+	// it was rotated to, and the growth of that usage in bytes per second
+	// since the previous stats. Only set on the writable layer of the
+	// containers whose runtime logs to a file, e.g. with the json-file
+	// logging driver of Docker.
+	LogUsage      uint64  `json:"log_usage,omitempty"`
+	LogGrowthRate float64 `json:"log_growth_rate,omitempty"`
+
 	// HasInodes when true, indicates that Inodes info will be available.
 	HasInodes bool `json:"has_inodes"`
 
@@ -815,6 +1111,25 @@ type FsStats struct {
 	// last update of this field.  This can provide an easy measure of both
 	// I/O completion time and the backlog that may be accumulating.
 	WeightedIoTime uint64 `json:"weighted_io_time"`
+
+	// The volume or bind mount the stats are for. Only set for the stats of
+	// the volumes of a container.
+	Volume *FsVolume `json:"volume,omitempty"`
+}
+
+// FsVolume describes a named volume or a bind mount of a container.
+type FsVolume struct {
+	// Name of a named volume, empty for bind mounts.
+	Name string `json:"name,omitempty"`
+
+	// Type of the mount, volume or bind.
+	Type string `json:"type"`
+
+	// Path of the volume on the host.
+	Source string `json:"source"`
+
+	// Path of the volume in the container.
+	Destination string `json:"destination"`
 }
 
 type AcceleratorStats struct {
@@ -926,6 +1241,20 @@ type ProcessStats struct {
 	// Maxium number of threads allowed in container
 	ThreadsMax uint64 `json:"threads_max,omitempty"`
 
+	// Number of times a fork or clone failed because the container reached
+	// its maximum number of threads
+	ThreadsMaxReached uint64 `json:"threads_max_reached,omitempty"`
+
+	// Cumulative number of threads and processes started in the container,
+	// counted from the fork events of the kernel. Without them, it is an
+	// estimate from the tasks seen starting, which misses the tasks living
+	// shorter than the housekeeping interval.
+	Forks uint64 `json:"forks,omitempty"`
+
+	// Number of threads and processes started per second since the
+	// previous stats
+	ForkRate float64 `json:"fork_rate,omitempty"`
+
 	// Ulimits for the top-level container process
 	Ulimits []UlimitSpec `json:"ulimits,omitempty"`
 }
@@ -941,6 +1270,11 @@ type ContainerStats struct {
 	// Filesystem statistics
 	Filesystem []FsStats `json:"filesystem,omitempty"`
 
+	// Statistics of the named volumes and bind mounts of the container. Usage
+	// and inodes are the ones of the volume, the other fields the ones of the
+	// filesystem the volume is on.
+	Volumes []FsStats `json:"volumes,omitempty"`
+
 	// Task load stats
 	TaskStats LoadStats `json:"task_stats,omitempty"`
 
@@ -969,6 +1303,27 @@ type ContainerStats struct {
 	CpuSet CPUSetStats `json:"cpuset,omitempty"`
 
 	OOMEvents uint64 `json:"oom_events,omitempty"`
+
+	// Latest OOM event of the container, unset if there was none since
+	// cAdvisor started monitoring it.
+	LastOOMEvent *EventReference `json:"last_oom_event,omitempty"`
+
+	// Sequence number of the sample among the samples collected for the
+	// container since cAdvisor started monitoring it, starting at 1. Gaps and
+	// repeated numbers downstream tell that samples were dropped or
+	// duplicated.
+	Sequence uint64 `json:"sequence,omitempty"`
+
+	// Time between the timestamp of the sample and the moment cAdvisor
+	// stored it, i.e. how long the stats took to read. The values of a sample
+	// with a large skew were not all read at its timestamp.
+	TimestampSkew time.Duration `json:"timestamp_skew,omitempty"`
+
+	// Time at which the sample was collected on the monotonic clock, since
+	// cAdvisor started. Unlike the timestamp, it does not jump when the wall
+	// clock is set, e.g. by NTP, but it is only comparable between the
+	// samples of the same run of cAdvisor.
+	MonotonicTime time.Duration `json:"monotonic_time,omitempty"`
 }
 
 func timeEq(t1, t2 time.Time, tolerance time.Duration) bool {
@@ -1018,6 +1373,9 @@ func (a *ContainerStats) StatsEq(b *ContainerStats) bool {
 	if !reflect.DeepEqual(a.Filesystem, b.Filesystem) {
 		return false
 	}
+	if !reflect.DeepEqual(a.Volumes, b.Volumes) {
+		return false
+	}
 	if !reflect.DeepEqual(a.TaskStats, b.TaskStats) {
 		return false
 	}
@@ -1046,6 +1404,20 @@ type Event struct {
 	// the original event object and all of its extraneous data, ex. an
 	// OomInstance
 	EventData EventData `json:"event_data,omitempty"`
+
+	// Id of the event, given by the event manager when the event is added,
+	// in the order of addition. The ids are unique while cAdvisor runs.
+	Id uint64 `json:"id,omitempty"`
+}
+
+// EventReference identifies an event from elsewhere, e.g. from the stats of
+// its container.
+type EventReference struct {
+	// Id of the event.
+	Id uint64 `json:"id"`
+
+	// Time at which the event occurred.
+	Timestamp time.Time `json:"timestamp"`
 }
 
 // EventType is an enumerated type which lists the categories under which
@@ -1057,12 +1429,60 @@ const (
 	EventOomKill           EventType = "oomKill"
 	EventContainerCreation EventType = "containerCreation"
 	EventContainerDeletion EventType = "containerDeletion"
+	EventAlert             EventType = "alert"
+	EventAnomaly           EventType = "anomaly"
+	EventPidsLimit         EventType = "pidsLimit"
+	EventNetworkDrops      EventType = "networkDrops"
+	EventCpusetChange      EventType = "cpusetChange"
+	EventStartLatency      EventType = "startLatency"
+	EventDiskQuota         EventType = "diskQuota"
+	EventImagePull         EventType = "imagePull"
+	EventMetricsDisabled   EventType = "metricsDisabled"
+	EventMachineRebooted   EventType = "machineRebooted"
+	EventLimitsChange      EventType = "limitsChange"
+	EventLogSize           EventType = "logSize"
 )
 
 // Extra information about an event. Only one type will be set.
 type EventData struct {
 	// Information about an OOM kill event.
 	OomKill *OomKillEventData `json:"oom,omitempty"`
+
+	// Information about an alert firing or resolving.
+	Alert *AlertEventData `json:"alert,omitempty"`
+
+	// Information about a metric deviating from its baseline.
+	Anomaly *AnomalyEventData `json:"anomaly,omitempty"`
+
+	// Information about a container approaching its pids limit.
+	PidsLimit *PidsLimitEventData `json:"pids_limit,omitempty"`
+
+	// Information about an interface of a container dropping packets.
+	NetworkDrops *NetworkDropsEventData `json:"network_drops,omitempty"`
+
+	// Information about a change of the effective cpuset of a container.
+	CpusetChange *CpusetChangeEventData `json:"cpuset_change,omitempty"`
+
+	// Information about the start of a container.
+	StartLatency *StartLatencyEventData `json:"start_latency,omitempty"`
+
+	// Information about a container approaching a disk quota.
+	DiskQuota *DiskQuotaEventData `json:"disk_quota,omitempty"`
+
+	// Information about an image pulled by a container runtime.
+	ImagePull *ImagePullEventData `json:"image_pull,omitempty"`
+
+	// Information about a source of metrics no longer read for a container.
+	MetricsDisabled *MetricsDisabledEventData `json:"metrics_disabled,omitempty"`
+
+	// Information about a reboot of the machine.
+	MachineRebooted *MachineRebootedEventData `json:"machine_rebooted,omitempty"`
+
+	// Information about a change of the CPU or memory limits of a container.
+	LimitsChange *LimitsChangeEventData `json:"limits_change,omitempty"`
+
+	// Information about the log files of a container growing too large.
+	LogSize *LogSizeEventData `json:"log_size,omitempty"`
 }
 
 // Information related to an OOM kill instance
@@ -1073,3 +1493,171 @@ type OomKillEventData struct {
 	// The name of the killed process
 	ProcessName string `json:"process_name"`
 }
+
+// Information related to a container approaching its pids limit
+type PidsLimitEventData struct {
+	// Number of threads in the container.
+	ThreadsCurrent uint64 `json:"threads_current"`
+
+	// Maximum number of threads allowed in the container.
+	ThreadsMax uint64 `json:"threads_max"`
+}
+
+// Information related to an interface of a container dropping packets. Rates
+// are in packets per second since the previous stats.
+type NetworkDropsEventData struct {
+	// Name of the interface, e.g. eth0.
+	Interface string `json:"interface"`
+
+	RxDropRate  float64 `json:"rx_drop_rate"`
+	TxDropRate  float64 `json:"tx_drop_rate"`
+	RxErrorRate float64 `json:"rx_error_rate"`
+	TxErrorRate float64 `json:"tx_error_rate"`
+}
+
+// Information related to a change of the effective cpuset of a container, e.g.
+// when the CPU manager of the kubelet reassigns its exclusive CPUs.
+type CpusetChangeEventData struct {
+	// Effective CPUs before and after the change, e.g. "0-3,8".
+	PreviousCpus string `json:"previous_cpus"`
+	Cpus         string `json:"cpus"`
+
+	// Effective memory nodes before and after the change.
+	PreviousMems string `json:"previous_mems"`
+	Mems         string `json:"mems"`
+}
+
+// Information related to a change of the CPU or memory limits of a running
+// container, e.g. by `docker update` or an in-place resize of its pod. Only
+// the limits of the resources tracked both before and after the change are
+// reported, the others are zero.
+type LimitsChangeEventData struct {
+	// Limits before and after the change.
+	Previous ContainerLimits `json:"previous"`
+	Current  ContainerLimits `json:"current"`
+}
+
+// CPU and memory limits of a container, as in its spec.
+type ContainerLimits struct {
+	// CPU shares, and CFS quota and period in microseconds.
+	CpuShares uint64 `json:"cpu_shares,omitempty"`
+	CpuQuota  uint64 `json:"cpu_quota,omitempty"`
+	CpuPeriod uint64 `json:"cpu_period,omitempty"`
+
+	// Memory limit, reservation and swap limit in bytes.
+	MemoryLimit       uint64 `json:"memory_limit,omitempty"`
+	MemoryReservation uint64 `json:"memory_reservation,omitempty"`
+	MemorySwapLimit   uint64 `json:"memory_swap_limit,omitempty"`
+}
+
+// Information related to the start of a container created since cAdvisor
+// started, reported with its first stats.
+type StartLatencyEventData struct {
+	// Time between the creation of the container by its runtime and its
+	// start. Zero if the runtime does not report it, or if the container
+	// restarted since its creation.
+	CreateToRunning time.Duration `json:"create_to_running,omitempty"`
+
+	// Time between the discovery of the container, when its cgroup
+	// appeared, and its first stats.
+	DiscoveryToFirstStats time.Duration `json:"discovery_to_first_stats"`
+}
+
+// Information related to a container approaching the quota of its writable
+// layer or of a volume
+type DiskQuotaEventData struct {
+	// Device of the filesystem.
+	Device string `json:"device"`
+
+	// Path of the volume in the container, empty for the writable layer.
+	Destination string `json:"destination,omitempty"`
+
+	// Number of bytes used, and allowed by the quota.
+	Usage uint64 `json:"usage"`
+	Quota uint64 `json:"quota"`
+}
+
+// Information related to the log files of a container growing beyond the
+// threshold
+type LogSizeEventData struct {
+	// Device of the filesystem of the log files.
+	Device string `json:"device"`
+
+	// Number of bytes used by the log files, and their growth in bytes per
+	// second since the previous stats.
+	Usage      uint64  `json:"usage"`
+	GrowthRate float64 `json:"growth_rate"`
+
+	// Number of bytes beyond which the event is recorded.
+	Threshold uint64 `json:"threshold"`
+}
+
+// Information related to an image pulled by a container runtime
+type ImagePullEventData struct {
+	// Container runtime which pulled the image, e.g. docker or containerd.
+	Runtime string `json:"runtime"`
+
+	// Reference of the image, e.g. docker.io/library/nginx:1.25.
+	Image string `json:"image"`
+}
+
+// Information related to a source of metrics no longer read for a container
+// after its reads kept failing or taking too long
+type MetricsDisabledEventData struct {
+	// Kind of the metrics read from the source, e.g. diskIO.
+	Metric string `json:"metric"`
+
+	// Source of the metrics, e.g. the blkio cgroup controller.
+	Source string `json:"source"`
+
+	// Why the source is no longer read.
+	Reason string `json:"reason"`
+}
+
+// Information related to a reboot of the machine, detected from a change of
+// its boot id
+type MachineRebootedEventData struct {
+	// Boot ids before and after the reboot.
+	PreviousBootID string `json:"previous_boot_id"`
+	BootID         string `json:"boot_id"`
+
+	// Time at which the machine booted, zero if unknown.
+	BootTime time.Time `json:"boot_time"`
+}
+
+// Information related to an alert firing or resolving
+type AlertEventData struct {
+	// Name of the alerting rule.
+	Rule string `json:"rule"`
+
+	// Whether the alert is "firing" or "resolved".
+	State string `json:"state"`
+
+	// Metric evaluated by the rule.
+	Metric string `json:"metric"`
+
+	// Last evaluated value of the metric.
+	Value float64 `json:"value"`
+
+	// Threshold of the rule.
+	Threshold float64 `json:"threshold"`
+
+	// Human readable description of the alert.
+	Description string `json:"description"`
+}
+
+// Information related to a metric deviating from its baseline
+type AnomalyEventData struct {
+	// Name of the metric.
+	Metric string `json:"metric"`
+
+	// Value of the anomalous sample.
+	Value float64 `json:"value"`
+
+	// Mean and standard deviation of the baseline before the sample.
+	Mean   float64 `json:"mean"`
+	StdDev float64 `json:"std_dev"`
+
+	// Number of standard deviations between the sample and the mean.
+	ZScore float64 `json:"z_score"`
+}
@@ -66,3 +66,22 @@ func TestStatsEndTime(t *testing.T) {
 		t.Errorf("start time is %v; should be %v", start, ref)
 	}
 }
+
+func TestSpecStartLatency(t *testing.T) {
+	created := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	for _, tc := range []struct {
+		spec     ContainerSpec
+		expected time.Duration
+	}{
+		{ContainerSpec{CreationTime: created, StartedAt: created.Add(time.Second)}, time.Second},
+		// The runtime does not report the start.
+		{ContainerSpec{CreationTime: created}, 0},
+		// The last start of a restarted container is unrelated to its
+		// creation.
+		{ContainerSpec{CreationTime: created, StartedAt: created.Add(time.Hour), Labels: map[string]string{"restartcount": "1"}}, 0},
+	} {
+		if latency := tc.spec.StartLatency(); latency != tc.expected {
+			t.Errorf("start latency of %+v is %v; should be %v", tc.spec, latency, tc.expected)
+		}
+	}
+}
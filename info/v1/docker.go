@@ -36,3 +36,29 @@ type DockerImage struct {
 	VirtualSize int64    `json:"virtual_size"`
 	Size        int64    `json:"size"`
 }
+
+// Disk usage of an image, as reported by the container runtime.
+type DockerImageUsage struct {
+	DockerImage
+	// Bytes shared with other images, -1 if unknown.
+	SharedSize int64 `json:"shared_size"`
+	// Bytes only used by this image, reclaimed when it is removed, -1 if
+	// unknown.
+	UniqueSize int64 `json:"unique_size"`
+	// Number of containers using the image, including stopped ones. The
+	// image can only be removed when there are none.
+	NumContainers int64 `json:"num_containers"`
+	// Names of the running containers using the image.
+	RunningContainers []string `json:"running_containers,omitempty"`
+	// Layers of the image, from the most recent.
+	Layers []DockerImageLayer `json:"layers,omitempty"`
+}
+
+type DockerImageLayer struct {
+	// ID of the image created by the layer, "<missing>" if it was built on
+	// another host.
+	ID        string `json:"id"`
+	Created   int64  `json:"created"` // unix time since creation.
+	CreatedBy string `json:"created_by"`
+	Size      int64  `json:"size"`
+}
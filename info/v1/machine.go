@@ -140,6 +140,33 @@ type DiskInfo struct {
 	Scheduler string `json:"scheduler"`
 }
 
+// BlockDevice maps the major:minor number of a block device, as reported in
+// the disk I/O stats, to the device it names.
+type BlockDevice struct {
+	// Major number
+	Major uint64 `json:"major"`
+
+	// Minor number
+	Minor uint64 `json:"minor"`
+
+	// Path of the device node, e.g. /dev/sda, or /dev/mapper/<name> for the
+	// device mapper devices.
+	Device string `json:"device"`
+
+	// Model of the disk, empty for virtual devices.
+	Model string `json:"model,omitempty"`
+
+	// File backing a loop device.
+	BackingFile string `json:"backing_file,omitempty"`
+
+	// Devices underlying a device mapper device, e.g. the partitions of an
+	// LVM volume.
+	Slaves []string `json:"slaves,omitempty"`
+
+	// Mount points of the filesystems on the device or its partitions.
+	Mountpoints []string `json:"mountpoints,omitempty"`
+}
+
 type NetInfo struct {
 	// Device name
 	Name string `json:"name"`
@@ -175,6 +202,12 @@ const (
 	UnNamedInstance InstanceID = "None"
 )
 
+type InstanceZone string
+
+const (
+	UnknownZone InstanceZone = "Unknown"
+)
+
 type MachineInfo struct {
 	// The time of this information point.
 	Timestamp time.Time `json:"timestamp"`
@@ -217,12 +250,19 @@ type MachineInfo struct {
 	// The boot id
 	BootID string `json:"boot_id"`
 
+	// Time at which the machine booted, zero if unknown.
+	BootTime time.Time `json:"boot_time"`
+
 	// Filesystems on this machine.
 	Filesystems []FsInfo `json:"filesystems"`
 
 	// Disk map
 	DiskMap map[string]DiskInfo `json:"disk_map"`
 
+	// Block devices, loop and device mapper devices included, keyed by
+	// "major:minor" number.
+	BlockDevices map[string]BlockDevice `json:"block_devices,omitempty"`
+
 	// Network devices
 	NetworkDevices []NetInfo `json:"network_devices"`
 
@@ -238,6 +278,37 @@ type MachineInfo struct {
 
 	// ID of cloud instance (e.g. instance-1) given to it by the cloud provider.
 	InstanceID InstanceID `json:"instance_id"`
+
+	// Zone of the cloud instance (e.g. us-central1-a).
+	Zone InstanceZone `json:"zone"`
+
+	// Resources reserved on the machine and left allocatable to pods, unset
+	// if no reservation is configured.
+	Allocatable *NodeAllocatable `json:"allocatable,omitempty"`
+}
+
+// ResourceAmounts is an amount of CPU and of memory.
+type ResourceAmounts struct {
+	// CPU, in millicores.
+	MilliCpu uint64 `json:"milli_cpu"`
+	// Memory, in bytes.
+	Memory uint64 `json:"memory"`
+}
+
+// NodeAllocatable describes the resources of the machine reserved for the
+// system daemons and for the Kubernetes daemons, and the resources left
+// allocatable to pods, as a Kubernetes scheduler sees them.
+type NodeAllocatable struct {
+	// Resources reserved for the system daemons (systemReserved).
+	SystemReserved ResourceAmounts `json:"system_reserved"`
+	// Resources reserved for the Kubernetes daemons (kubeReserved).
+	KubeReserved ResourceAmounts `json:"kube_reserved"`
+	// Memory kept available by the hard eviction threshold
+	// (evictionHard memory.available). The CPU is always zero.
+	EvictionThreshold ResourceAmounts `json:"eviction_threshold"`
+	// Capacity of the machine minus the reservations and the eviction
+	// threshold.
+	Allocatable ResourceAmounts `json:"allocatable"`
 }
 
 func (m *MachineInfo) Clone() *MachineInfo {
@@ -255,6 +326,18 @@ func (m *MachineInfo) Clone() *MachineInfo {
 			diskMap[k] = info
 		}
 	}
+	var allocatable *NodeAllocatable
+	if m.Allocatable != nil {
+		a := *m.Allocatable
+		allocatable = &a
+	}
+	blockDevices := m.BlockDevices
+	if len(m.BlockDevices) > 0 {
+		blockDevices = make(map[string]BlockDevice)
+		for k, device := range m.BlockDevices {
+			blockDevices[k] = device
+		}
+	}
 	copy := MachineInfo{
 		CPUVendorID:      m.CPUVendorID,
 		Timestamp:        m.Timestamp,
@@ -270,13 +353,17 @@ func (m *MachineInfo) Clone() *MachineInfo {
 		MachineID:        m.MachineID,
 		SystemUUID:       m.SystemUUID,
 		BootID:           m.BootID,
+		BootTime:         m.BootTime,
 		Filesystems:      m.Filesystems,
 		DiskMap:          diskMap,
+		BlockDevices:     blockDevices,
 		NetworkDevices:   m.NetworkDevices,
 		Topology:         m.Topology,
 		CloudProvider:    m.CloudProvider,
 		InstanceType:     m.InstanceType,
 		InstanceID:       m.InstanceID,
+		Zone:             m.Zone,
+		Allocatable:      allocatable,
 	}
 	return &copy
 }
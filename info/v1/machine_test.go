@@ -60,6 +60,7 @@ func getFakeMachineInfo() MachineInfo {
 		MachineID:  "fake-machine-id",
 		SystemUUID: "fake-uuid",
 		BootID:     "fake-boot-id",
+		BootTime:   time.Now(),
 		Filesystems: []FsInfo{{
 			Device:      "dev",
 			DeviceMajor: 1,
@@ -76,6 +77,13 @@ func getFakeMachineInfo() MachineInfo {
 			Size:      3,
 			Scheduler: "sched",
 		}},
+		BlockDevices: map[string]BlockDevice{"253:0": {
+			Major:       253,
+			Minor:       0,
+			Device:      "/dev/mapper/fake",
+			Slaves:      []string{"/dev/fake1"},
+			Mountpoints: []string{"/"},
+		}},
 		NetworkDevices: []NetInfo{{
 			Name:       "fake-net-info",
 			MacAddress: "123",
@@ -89,5 +97,10 @@ func getFakeMachineInfo() MachineInfo {
 		CloudProvider: "fake-provider",
 		InstanceType:  "fake-instance-type",
 		InstanceID:    "fake-instance-id",
+		Zone:          "fake-zone",
+		Allocatable: &NodeAllocatable{
+			SystemReserved: ResourceAmounts{MilliCpu: 1, Memory: 2},
+			Allocatable:    ResourceAmounts{MilliCpu: 999, Memory: 3},
+		},
 	}
 }
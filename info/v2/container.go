@@ -37,6 +37,8 @@ type CpuSpec struct {
 	// Cpu affinity mask.
 	// TODO(rjnagal): Add a library to convert mask string to set of cpu bitmask.
 	Mask string `json:"mask,omitempty"`
+	// Memory nodes the container may allocate on.
+	Mems string `json:"mems,omitempty"`
 	// CPUQuota Default is disabled
 	Quota uint64 `json:"quota,omitempty"`
 	// Period is the CPU reference time in ns e.g the quota is compared against this.
@@ -69,6 +71,14 @@ type ContainerSpec struct {
 	// Time at which the container was created.
 	CreationTime time.Time `json:"creation_time,omitempty"`
 
+	// Time at which the runtime last started the container. Only reported
+	// by docker and podman.
+	StartedAt time.Time `json:"started_at,omitempty"`
+
+	// Time between the discovery of the container by cAdvisor and its first
+	// stats, for the containers created since cAdvisor started.
+	FirstStatsLatency time.Duration `json:"first_stats_latency,omitempty"`
+
 	// Other names by which the container is known within a certain namespace.
 	// This is unique within that namespace.
 	Aliases []string `json:"aliases,omitempty"`
@@ -96,13 +106,48 @@ type ContainerSpec struct {
 	HasProcesses bool           `json:"has_processes"`
 	Processes    v1.ProcessSpec `json:"processes,omitempty"`
 
+	// Security profile of the container: SELinux label, AppArmor profile,
+	// seccomp mode, no-new-privileges and capabilities. Only reported by
+	// docker, containerd, crio and podman.
+	Security *v1.SecuritySpec `json:"security,omitempty"`
+
+	// Ports of the container published on the host. Only reported by docker
+	// and podman.
+	Ports []v1.PortMapping `json:"ports,omitempty"`
+
 	// Following resources have no associated spec, but are being isolated.
 	HasNetwork    bool `json:"has_network"`
 	HasFilesystem bool `json:"has_filesystem"`
 	HasDiskIo     bool `json:"has_diskio"`
+	// Devices which the container did I/O on, with their names.
+	DiskIoDevices []v1.BlockDevice `json:"diskio_devices,omitempty"`
+
+	// Number of bytes the writable layer of the container may use, as set
+	// by its runtime. Zero if there is no quota.
+	DiskQuota uint64 `json:"disk_quota,omitempty"`
 
 	// Image name used for this container.
 	Image string `json:"image,omitempty"`
+
+	// Container runtime managing the container, e.g. docker, containerd,
+	// crio, podman or raw for cgroups not managed by a runtime.
+	Runtime string `json:"runtime,omitempty"`
+
+	// Id of the container in its runtime, as used by the runtime CLI.
+	// Empty for raw containers.
+	RuntimeId string `json:"runtime_id,omitempty"`
+
+	// Identifier of the container set by cAdvisor, stable across cAdvisor
+	// restarts and unique among the containers taking the same name.
+	Uid string `json:"uid,omitempty"`
+
+	// Absolute path of the cgroup of the container. On cgroup v1, the path
+	// in the cpu hierarchy, or in the memory hierarchy without cpu.
+	CgroupPath string `json:"cgroup_path,omitempty"`
+
+	// Availability of the metrics read from the cgroup of the container, by
+	// kind of metrics.
+	MetricsAvailability map[string]v1.MetricAvailability `json:"metrics_availability,omitempty"`
 }
 
 type DeprecatedContainerStats struct {
@@ -176,6 +221,8 @@ type ContainerStats struct {
 	Processes *v1.ProcessStats `json:"processes,omitempty"`
 	// Filesystem statistics
 	Filesystem *FilesystemStats `json:"filesystem,omitempty"`
+	// Statistics of the named volumes and bind mounts
+	Volumes []v1.FsStats `json:"volumes,omitempty"`
 	// Task load statistics
 	Load *v1.LoadStats `json:"load_stats,omitempty"`
 	// Metrics for Accelerators. Each Accelerator corresponds to one element in the array.
@@ -191,6 +238,61 @@ type ContainerStats struct {
 	ReferencedMemory uint64 `json:"referenced_memory,omitempty"`
 	// Resource Control (resctrl) statistics
 	Resctrl v1.ResctrlStats `json:"resctrl,omitempty"`
+	// Sequence number of the sample, see v1.ContainerStats
+	Sequence uint64 `json:"sequence,omitempty"`
+	// Time between the timestamp of the sample and the moment it was stored
+	TimestampSkew time.Duration `json:"timestamp_skew,omitempty"`
+	// Time of the sample on the monotonic clock, see v1.ContainerStats
+	MonotonicTime time.Duration `json:"monotonic_time,omitempty"`
+}
+
+// Functions clearing the fields of ContainerStats which can be selected by
+// the Fields request option, by JSON name. The timestamp, sequence, timestamp
+// skew and monotonic time are always kept.
+var clearStatsField = map[string]func(*ContainerStats){
+	"cpu":               func(s *ContainerStats) { s.Cpu = nil },
+	"cpu_inst":          func(s *ContainerStats) { s.CpuInst = nil },
+	"diskio":            func(s *ContainerStats) { s.DiskIo = nil },
+	"memory":            func(s *ContainerStats) { s.Memory = nil },
+	"hugetlb":           func(s *ContainerStats) { s.Hugetlb = nil },
+	"network":           func(s *ContainerStats) { s.Network = nil },
+	"processes":         func(s *ContainerStats) { s.Processes = nil },
+	"filesystem":        func(s *ContainerStats) { s.Filesystem = nil },
+	"volumes":           func(s *ContainerStats) { s.Volumes = nil },
+	"load_stats":        func(s *ContainerStats) { s.Load = nil },
+	"accelerators":      func(s *ContainerStats) { s.Accelerators = nil },
+	"custom_metrics":    func(s *ContainerStats) { s.CustomMetrics = nil },
+	"perf_stats":        func(s *ContainerStats) { s.PerfStats = nil },
+	"perf_uncore_stats": func(s *ContainerStats) { s.PerfUncoreStats = nil },
+	"referenced_memory": func(s *ContainerStats) { s.ReferencedMemory = 0 },
+	"resctrl":           func(s *ContainerStats) { s.Resctrl = v1.ResctrlStats{} },
+}
+
+// IsStatsField returns whether the field of ContainerStats with the given
+// JSON name can be selected by the Fields request option.
+func IsStatsField(name string) bool {
+	_, ok := clearStatsField[name]
+	return ok
+}
+
+// SelectStatsFields clears the fields of the stats not selected by fields,
+// JSON names of ContainerStats fields. Nothing is cleared if fields is empty.
+func SelectStatsFields(stats []*ContainerStats, fields []string) {
+	if len(fields) == 0 {
+		return
+	}
+	selected := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		selected[field] = true
+	}
+	for name, clearField := range clearStatsField {
+		if selected[name] {
+			continue
+		}
+		for _, s := range stats {
+			clearField(s)
+		}
+	}
 }
 
 type Percentiles struct {
@@ -239,6 +341,127 @@ type DerivedStats struct {
 	HourUsage Usage `json:"hour_usage"`
 	// Percentile in last day.
 	DayUsage Usage `json:"day_usage"`
+	// Percentiles over the windows requested with the windows and
+	// percentiles options of the summary API, in the requested order.
+	WindowUsage []WindowUsage `json:"window_usage,omitempty"`
+	// Utilization of the allocatable resources of the machine, only set
+	// for the root container if resources are reserved on the machine.
+	Allocatable *DerivedUtilization `json:"allocatable,omitempty"`
+}
+
+// DerivedUtilization is the utilization of the allocatable resources of the
+// machine by the derived usage of the root container.
+type DerivedUtilization struct {
+	// Allocatable resources of the machine.
+	Allocatable v1.ResourceAmounts `json:"allocatable"`
+	// Utilization by the latest instantaneous sample.
+	LatestUsage AllocatableUtilization `json:"latest_usage"`
+	// Utilization by the percentiles in last observed minute.
+	MinuteUsage UtilizationPercentiles `json:"minute_usage"`
+	// Utilization by the percentiles in last hour.
+	HourUsage UtilizationPercentiles `json:"hour_usage"`
+	// Utilization by the percentiles in last day.
+	DayUsage UtilizationPercentiles `json:"day_usage"`
+}
+
+// UtilizationPercentiles is the utilization of the allocatable resources by
+// the percentiles of a usage, omitted for the resources without percentiles.
+type UtilizationPercentiles struct {
+	Mean   AllocatableUtilization `json:"mean"`
+	Max    AllocatableUtilization `json:"max"`
+	Ninety AllocatableUtilization `json:"ninety"`
+}
+
+// Percentiles of the usage of a resource over a window, as requested by
+// the caller.
+type CustomPercentiles struct {
+	// Indicates whether the stats are present or not.
+	Present bool `json:"present"`
+	// Average over the collected sample.
+	Mean uint64 `json:"mean"`
+	// Max seen over the collected sample.
+	Max uint64 `json:"max"`
+	// Requested percentiles over the collected sample, keyed by percentile,
+	// e.g. "99.9".
+	Percentiles map[string]uint64 `json:"percentiles"`
+}
+
+// Usage over a window of the latest minute samples.
+type WindowUsage struct {
+	// Length of the window, e.g. "30m0s".
+	Window string `json:"window"`
+	// Indicates amount of data available [0-100].
+	PercentComplete int32 `json:"percent_complete"`
+	// Cpu rate in milliCpus/seconds.
+	Cpu CustomPercentiles `json:"cpu"`
+	// Memory size in bytes.
+	Memory CustomPercentiles `json:"memory"`
+}
+
+type ContainerForecast struct {
+	// Time of the latest sample the forecast is based on.
+	Timestamp time.Time `json:"timestamp"`
+	// Number of samples the trends are fitted on.
+	Samples int `json:"samples"`
+	// Trend fitting method, "linear" or "holt".
+	Method string `json:"method"`
+	// Forecast of the memory working set.
+	Memory *ResourceForecast `json:"memory,omitempty"`
+	// Forecast of the usage of every filesystem, keyed by device.
+	Filesystem map[string]ResourceForecast `json:"filesystem,omitempty"`
+}
+
+type ResourceForecast struct {
+	// Latest usage in bytes.
+	Usage uint64 `json:"usage"`
+	// Limit in bytes. Zero if unlimited.
+	Limit uint64 `json:"limit,omitempty"`
+	// Fitted growth of the usage in bytes per second.
+	GrowthRate float64 `json:"growth_rate"`
+	// Projected usage in bytes at the end of the horizon.
+	ProjectedUsage float64 `json:"projected_usage"`
+	// Projected seconds until the usage reaches the limit. Omitted if there is
+	// no limit or the usage is not growing towards it.
+	SecondsToLimit *float64 `json:"seconds_to_limit,omitempty"`
+}
+
+// ContainerRates are the rates of the cumulative counters of a container,
+// between the oldest and the latest of its recent samples.
+type ContainerRates struct {
+	// Time of the latest sample the rates are computed over.
+	Timestamp time.Time `json:"timestamp"`
+	// Number of samples, and seconds between the oldest and the latest.
+	Samples  int     `json:"samples"`
+	Interval float64 `json:"interval_seconds"`
+	// Omitted if not collected for the container, or if a counter was reset
+	// during the interval.
+	Cpu     *CpuRates     `json:"cpu,omitempty"`
+	Network *NetworkRates `json:"network,omitempty"`
+	DiskIo  *DiskIoRates  `json:"diskio,omitempty"`
+}
+
+type CpuRates struct {
+	// Average number of cores used, in total, in user and in kernel mode.
+	Usage  float64 `json:"usage_cores"`
+	User   float64 `json:"user_cores"`
+	System float64 `json:"system_cores"`
+}
+
+// NetworkRates are the rates of all the interfaces of a container, per second.
+type NetworkRates struct {
+	RxBytes   float64 `json:"rx_bytes_per_second"`
+	TxBytes   float64 `json:"tx_bytes_per_second"`
+	RxPackets float64 `json:"rx_packets_per_second"`
+	TxPackets float64 `json:"tx_packets_per_second"`
+}
+
+// DiskIoRates are the rates of all the block devices of a container, per
+// second.
+type DiskIoRates struct {
+	ReadBytes  float64 `json:"read_bytes_per_second"`
+	WriteBytes float64 `json:"write_bytes_per_second"`
+	ReadIops   float64 `json:"read_iops"`
+	WriteIops  float64 `json:"write_iops"`
 }
 
 type FsInfo struct {
@@ -280,6 +503,34 @@ type RequestOptions struct {
 	// Update stats if they are older than MaxAge
 	// nil indicates no update, and 0 will always trigger an update.
 	MaxAge *time.Duration `json:"max_age"`
+	// Only return stats sampled after Since, zero means no limit.
+	Since time.Time `json:"since"`
+	// Only return stats sampled from Start to End, inclusive, zero means no
+	// limit.
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	// Return a single sample per container, all collected by the same
+	// on-demand housekeeping and timestamped with its start. Count, MaxAge,
+	// Since, Start, End and Step are ignored.
+	Aligned bool `json:"aligned"`
+	// Return a single sample per interval of Step, aggregated according to
+	// Agg, e.g. AggAvg, AggLast if empty. Count applies to the samples
+	// before. All the samples if zero, see DownsampleStats.
+	Step time.Duration `json:"step,omitempty"`
+	Agg  string        `json:"agg,omitempty"`
+	// Fields of the ContainerStats to return, by JSON name, e.g. cpu and
+	// memory. All of them if empty.
+	Fields []string `json:"fields,omitempty"`
+	// Only return the containers whose labels match this selector, parsed
+	// with ParseLabelSelector, e.g. io.kubernetes.pod.namespace=prod. All
+	// of them if empty.
+	LabelSelector string `json:"label_selector,omitempty"`
+	// Only return the containers whose name or one of whose aliases fully
+	// matches this regular expression. All of them if empty.
+	NameRegex string `json:"name_regex,omitempty"`
+	// Mode of the per-CPU usage of the stats, PerCpuFull, PerCpuSocket or
+	// PerCpuOff. As collected if empty.
+	PerCpu string `json:"percpu,omitempty"`
 }
 
 type ProcessInfo struct {
@@ -299,6 +550,119 @@ type ProcessInfo struct {
 	Psr           int     `json:"psr"`
 }
 
+// Owner of the processes which are not in any container.
+const CensusHost = "host"
+
+// Processes of the whole machine, with the container owning each of them.
+type ProcessCensus struct {
+	Processes []CensusProcess `json:"processes"`
+	// Usage of the processes summed by owner.
+	Owners map[string]CensusUsage `json:"owners"`
+}
+
+type CensusProcess struct {
+	ProcessInfo `json:",inline"`
+	// Name of the container running the process, or "host" for processes
+	// which are not in the cgroup of a container runtime.
+	Owner string `json:"owner"`
+}
+
+type CensusUsage struct {
+	ProcessCount  int     `json:"process_count"`
+	PercentCpu    float32 `json:"percent_cpu"`
+	PercentMemory float32 `json:"percent_mem"`
+	RSS           uint64  `json:"rss"`
+}
+
+// Buckets of the decomposition of the usage of the machine besides the
+// configured cgroups.
+const (
+	// Containers managed by a container runtime.
+	DecompositionContainers = "containers"
+	// Usage of the machine not in any other bucket, e.g. the kernel and the
+	// processes in cgroups which are neither configured nor containers.
+	DecompositionUnaccounted = "unaccounted"
+)
+
+// Usage of the machine, i.e. of the root container, split between the
+// containers, the configured cgroups (e.g. system.slice and user.slice) and
+// the rest.
+type MachineDecomposition struct {
+	// Time of the latest sample of the root container.
+	Timestamp time.Time `json:"timestamp"`
+	// Usage of the whole machine.
+	Machine DecompositionUsage `json:"machine"`
+	// Usage by bucket: "containers", the configured cgroups, by name
+	// relative to the root, without the containers they hold, and
+	// "unaccounted".
+	Buckets map[string]DecompositionUsage `json:"buckets"`
+}
+
+type DecompositionUsage struct {
+	// CPU usage in nanocores, i.e. nanoseconds of CPU time per second,
+	// between the two latest samples.
+	CpuNanoCores uint64 `json:"cpu_nanocores"`
+	MemoryUsage  uint64 `json:"memory_usage"`
+	// Memory working set, the usage minus the inactive file cache.
+	MemoryWorkingSet uint64 `json:"memory_working_set"`
+}
+
+// Labels set by the kubelet on the containers of a pod, through Docker or
+// CRI.
+const (
+	PodUIDLabel       = "io.kubernetes.pod.uid"
+	PodNameLabel      = "io.kubernetes.pod.name"
+	PodNamespaceLabel = "io.kubernetes.pod.namespace"
+	// Name of the container in the pod, POD for the sandbox container of
+	// Docker and CRI-O.
+	ContainerNameLabel = "io.kubernetes.container.name"
+)
+
+// PodUsage is the usage of the containers of a Kubernetes pod, grouped by
+// the pod labels set by the kubelet.
+type PodUsage struct {
+	UID       string `json:"uid"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	// Time of the latest sample of the containers of the pod.
+	Timestamp time.Time `json:"timestamp"`
+	// Names of the containers of the pod, sorted.
+	Containers []string `json:"containers"`
+	// Sum of the CPU rates of the containers between their two latest
+	// samples, omitted if none has two samples.
+	Cpu *CpuRates `json:"cpu,omitempty"`
+	// Sum of the latest memory usage and working set of the containers.
+	MemoryUsage      uint64 `json:"memory_usage"`
+	MemoryWorkingSet uint64 `json:"memory_working_set"`
+	// Network rates of the pod between the two latest samples. The
+	// containers share the network namespace of the pod, so these are the
+	// rates of the container with the most traffic rather than their sum.
+	Network *NetworkRates `json:"network,omitempty"`
+}
+
+// Metrics by which the containers can be ranked.
+const (
+	// Cores used between the two latest samples.
+	TopCpu = "cpu"
+	// Latest memory working set, in bytes.
+	TopMemory = "memory"
+	// Bytes received and transmitted per second between the two latest
+	// samples.
+	TopNetwork = "network"
+	// Bytes read and written per second between the two latest samples.
+	TopDiskIo = "diskio"
+)
+
+// TopContainer is a container ranked by its usage of a resource.
+type TopContainer struct {
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases,omitempty"`
+	// Time of the latest sample of the container.
+	Timestamp time.Time `json:"timestamp"`
+	// Usage of the resource, in the unit of the metric.
+	Value float64 `json:"value"`
+}
+
 type TcpStat struct {
 	Established uint64
 	SynSent     uint64
@@ -326,6 +690,12 @@ type NetworkStats struct {
 	Udp6 v1.UdpStat `json:"udp6"`
 	// TCP advanced stats
 	TcpAdvanced v1.TcpAdvancedStat `json:"tcp_advanced"`
+	// Connection tracking stats
+	Conntrack v1.ConntrackStat `json:"conntrack"`
+	// Counters of the netfilter chains of the machine.
+	Netfilter []v1.NetfilterChain `json:"netfilter,omitempty"`
+	// Statistics of the traffic control qdiscs and classes of the interfaces.
+	Tc []v1.TcStats `json:"tc,omitempty"`
 }
 
 // Instantaneous CPU stats
@@ -343,6 +713,10 @@ type CpuInstUsage struct {
 	// Unit: nanocores per second
 	PerCpu []uint64 `json:"per_cpu_usage,omitempty"`
 
+	// Usage of the container summed per socket, indexed by socket id.
+	// Unit: nanocores per second
+	PerSocket []uint64 `json:"per_socket_usage,omitempty"`
+
 	// Time spent in user space.
 	// Unit: nanocores per second
 	User uint64 `json:"user"`
@@ -362,4 +736,91 @@ type FilesystemStats struct {
 	// This only accounts for inodes that are shared across containers,
 	// and does not include inodes used in mounted directories.
 	InodeUsage *uint64 `json:"containter_inode_usage,omitempty"`
+	// Number of bytes the container may use through its root filesystem
+	// under a quota.
+	QuotaBytes *uint64 `json:"quotaBytes,omitempty"`
+	// Number of bytes used by the log files of the container, and their
+	// growth in bytes per second.
+	LogUsageBytes *uint64  `json:"logUsageBytes,omitempty"`
+	LogGrowthRate *float64 `json:"logGrowthRate,omitempty"`
+}
+
+// Network namespace of a container, as seen through netlink.
+type NetworkNamespace struct {
+	// Inode of the namespace. Containers sharing a namespace (e.g. the
+	// containers of a pod) report the same inode.
+	Inode uint64 `json:"inode"`
+	// Whether the namespace is the one of the host.
+	HostNetwork bool `json:"host_network"`
+	// Pid of the process whose namespace was inspected.
+	Pid        int                `json:"pid"`
+	Interfaces []NetworkInterface `json:"interfaces"`
+	Routes     []NetworkRoute     `json:"routes"`
+}
+
+type NetworkInterface struct {
+	Name  string `json:"name"`
+	Index int    `json:"index"`
+	// Link type, e.g. veth, bridge, device or openvswitch.
+	Type string `json:"type"`
+	MAC  string `json:"mac,omitempty"`
+	MTU  int    `json:"mtu"`
+	// Operational state, e.g. up or down.
+	State string `json:"state"`
+	// Addresses in CIDR notation.
+	Addresses []string `json:"addresses,omitempty"`
+	// Host side of a veth interface, if it could be found in the host
+	// network namespace.
+	Peer *NetworkPeer `json:"peer,omitempty"`
+}
+
+// Host side of a veth pair.
+type NetworkPeer struct {
+	Name  string `json:"name"`
+	Index int    `json:"index"`
+	// Bridge or Open vSwitch datapath the interface is attached to, if any.
+	Master string `json:"master,omitempty"`
+	// Link type of the master, e.g. bridge or openvswitch.
+	MasterType string `json:"master_type,omitempty"`
+}
+
+type NetworkRoute struct {
+	// Destination in CIDR notation, "default" for the default routes.
+	Destination string `json:"destination"`
+	Gateway     string `json:"gateway,omitempty"`
+	Source      string `json:"source,omitempty"`
+	Interface   string `json:"interface,omitempty"`
+	// Routing table, 254 being the main table.
+	Table    int `json:"table"`
+	Priority int `json:"priority,omitempty"`
+}
+
+// The ports of a container: those published on the host by its runtime, and
+// the sockets its processes listen on.
+type ContainerPorts struct {
+	// Inode of the network namespace of the container, as reported by the
+	// netns resource.
+	NetworkNamespace uint64 `json:"network_namespace"`
+	// Whether the namespace is the one of the host, where the listening
+	// sockets are reachable without a published port.
+	HostNetwork bool `json:"host_network"`
+	// Ports published on the host. Only reported by docker and podman.
+	Published []v1.PortMapping `json:"published"`
+	// Sockets the processes of the container listen on, sorted by port,
+	// protocol and address.
+	Listening []ListeningSocket `json:"listening"`
+}
+
+// A TCP socket in the LISTEN state, or a bound UDP socket.
+type ListeningSocket struct {
+	// Protocol of the socket: tcp, tcp6, udp or udp6.
+	Protocol string `json:"protocol"`
+	// Local address of the socket, 0.0.0.0 or :: for all the addresses.
+	Address string `json:"address"`
+	Port    uint16 `json:"port"`
+	// Inode of the socket.
+	Inode uint64 `json:"inode"`
+	// Pid and command of a process of the container holding the socket.
+	Pid     int    `json:"pid"`
+	Command string `json:"command"`
 }
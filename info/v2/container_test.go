@@ -0,0 +1,66 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v2
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	v1 "github.com/yidoyoon/cadvisor-lite/info/v1"
+)
+
+func TestSelectStatsFields(t *testing.T) {
+	newStats := func() *ContainerStats {
+		return &ContainerStats{
+			Timestamp:        timestamp,
+			Cpu:              &v1.CpuStats{},
+			CpuInst:          &CpuInstStats{},
+			Memory:           &v1.MemoryStats{},
+			Network:          &NetworkStats{},
+			Processes:        &v1.ProcessStats{},
+			ReferencedMemory: 42,
+			Sequence:         7,
+		}
+	}
+
+	stats := []*ContainerStats{newStats(), newStats()}
+	SelectStatsFields(stats, []string{"cpu", "memory"})
+	for _, s := range stats {
+		assert.Equal(t, &ContainerStats{Timestamp: timestamp, Cpu: &v1.CpuStats{}, Memory: &v1.MemoryStats{}, Sequence: 7}, s)
+	}
+
+	stats = []*ContainerStats{newStats()}
+	SelectStatsFields(stats, nil)
+	assert.Equal(t, newStats(), stats[0])
+}
+
+// Every field of ContainerStats but the timestamp, sequence and timestamp
+// skew can be selected.
+func TestIsStatsField(t *testing.T) {
+	statsType := reflect.TypeOf(ContainerStats{})
+	for i := 0; i < statsType.NumField(); i++ {
+		name := strings.Split(statsType.Field(i).Tag.Get("json"), ",")[0]
+		switch name {
+		case "timestamp", "sequence", "timestamp_skew", "monotonic_time":
+			assert.False(t, IsStatsField(name), name)
+		default:
+			assert.True(t, IsStatsField(name), name)
+		}
+	}
+	assert.False(t, IsStatsField("gpu"))
+}
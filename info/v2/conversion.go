@@ -16,6 +16,7 @@ package v2
 
 import (
 	"fmt"
+	"sort"
 	"time"
 
 	"k8s.io/klog/v2"
@@ -60,6 +61,13 @@ func machineFsStatsFromV1(fsStats []v1.FsStats) []MachineFsStats {
 }
 
 func MachineStatsFromV1(cont *v1.ContainerInfo) []MachineStats {
+	return MachineStatsWithTopologyFromV1(cont, nil)
+}
+
+// MachineStatsWithTopologyFromV1 converts the stats of the root container to
+// machine stats, and aggregates them per NUMA node and per socket of topology
+// if it is set.
+func MachineStatsWithTopologyFromV1(cont *v1.ContainerInfo, topology []v1.Node) []MachineStats {
 	var stats []MachineStats
 	var last *v1.ContainerStats
 	for i := range cont.Stats {
@@ -86,17 +94,190 @@ func MachineStatsFromV1(cont *v1.ContainerInfo) []MachineStats {
 				Tcp:        TcpStat(val.Network.Tcp),
 				Tcp6:       TcpStat(val.Network.Tcp6),
 				Interfaces: val.Network.Interfaces,
+				Conntrack:  val.Network.Conntrack,
+				Netfilter:  val.Network.Netfilter,
+				Tc:         val.Network.Tc,
 			}
 		}
 		if cont.Spec.HasFilesystem {
 			stat.Filesystem = machineFsStatsFromV1(val.Filesystem)
 		}
+		if len(topology) > 0 && (cont.Spec.HasCpu || cont.Spec.HasMemory) {
+			stat.Topology = topologyStats(topology, val, stat.CpuInst, cont.Spec.HasCpu, cont.Spec.HasMemory)
+		}
 		// TODO(rjnagal): Handle load stats.
 		stats = append(stats, stat)
 	}
 	return stats
 }
 
+// MachineStatsWithMachineInfoFromV1 converts the stats of the root container
+// to machine stats aggregated along the topology of the machine, with the
+// utilization of its allocatable resources if resources are reserved on it.
+func MachineStatsWithMachineInfoFromV1(cont *v1.ContainerInfo, machineInfo *v1.MachineInfo) []MachineStats {
+	if machineInfo == nil {
+		return MachineStatsFromV1(cont)
+	}
+	stats := MachineStatsWithTopologyFromV1(cont, machineInfo.Topology)
+	if machineInfo.Allocatable != nil {
+		allocatable := machineInfo.Allocatable.Allocatable
+		for i := range stats {
+			var cpu, memory *uint64
+			if stats[i].CpuInst != nil {
+				// The instantaneous usage is in nanocores.
+				cpu = &stats[i].CpuInst.Usage.Total
+			}
+			if stats[i].Memory != nil {
+				memory = &stats[i].Memory.WorkingSet
+			}
+			utilization := allocatableUtilization(cpu, memory, 1e6, allocatable)
+			stats[i].Allocatable = &utilization
+		}
+	}
+	return stats
+}
+
+// DerivedUtilizationFromStats returns the utilization of the allocatable
+// resources of the machine by the derived stats of the root container.
+func DerivedUtilizationFromStats(stats DerivedStats, allocatable v1.ResourceAmounts) *DerivedUtilization {
+	// The derived CPU usage is in millicores.
+	latest := stats.LatestUsage
+	return &DerivedUtilization{
+		Allocatable: allocatable,
+		LatestUsage: allocatableUtilization(&latest.Cpu, &latest.Memory, 1, allocatable),
+		MinuteUsage: utilizationPercentiles(stats.MinuteUsage, allocatable),
+		HourUsage:   utilizationPercentiles(stats.HourUsage, allocatable),
+		DayUsage:    utilizationPercentiles(stats.DayUsage, allocatable),
+	}
+}
+
+func utilizationPercentiles(usage Usage, allocatable v1.ResourceAmounts) UtilizationPercentiles {
+	var cpuMean, cpuMax, cpuNinety, memoryMean, memoryMax, memoryNinety *uint64
+	if usage.Cpu.Present {
+		cpuMean, cpuMax, cpuNinety = &usage.Cpu.Mean, &usage.Cpu.Max, &usage.Cpu.Ninety
+	}
+	if usage.Memory.Present {
+		memoryMean, memoryMax, memoryNinety = &usage.Memory.Mean, &usage.Memory.Max, &usage.Memory.Ninety
+	}
+	return UtilizationPercentiles{
+		Mean:   allocatableUtilization(cpuMean, memoryMean, 1, allocatable),
+		Max:    allocatableUtilization(cpuMax, memoryMax, 1, allocatable),
+		Ninety: allocatableUtilization(cpuNinety, memoryNinety, 1, allocatable),
+	}
+}
+
+// allocatableUtilization returns the fractions of the allocatable resources
+// used by cpu, in millicores divided by cpuScale, and by memory, in bytes.
+// The fractions are omitted for unknown usages and for resources of which
+// nothing is allocatable.
+func allocatableUtilization(cpu, memory *uint64, cpuScale float64, allocatable v1.ResourceAmounts) AllocatableUtilization {
+	var utilization AllocatableUtilization
+	if cpu != nil && allocatable.MilliCpu > 0 {
+		fraction := float64(*cpu) / (float64(allocatable.MilliCpu) * cpuScale)
+		utilization.Cpu = &fraction
+	}
+	if memory != nil && allocatable.Memory > 0 {
+		fraction := float64(*memory) / float64(allocatable.Memory)
+		utilization.Memory = &fraction
+	}
+	return utilization
+}
+
+// topologyStats sums the per-CPU usage of the threads of every NUMA node and
+// socket, and the NUMA memory statistics of every node. The sockets take the
+// per-socket usage instead when the usage is collected per socket.
+func topologyStats(topology []v1.Node, val *v1.ContainerStats, cpuInst *CpuInstStats, hasCpu, hasMemory bool) *TopologyStats {
+	add := func(sum **uint64, value uint64) {
+		if *sum == nil {
+			*sum = new(uint64)
+		}
+		**sum += value
+	}
+	// The per-CPU usage is only used if it covers every thread, it is not
+	// collected at all with cgroup v2.
+	maxThread := -1
+	for _, node := range topology {
+		for _, core := range node.Cores {
+			for _, thread := range core.Threads {
+				if thread > maxThread {
+					maxThread = thread
+				}
+			}
+		}
+	}
+	var perCpu, perCpuInst []uint64
+	if hasCpu && len(val.Cpu.Usage.PerCpu) > maxThread {
+		perCpu = val.Cpu.Usage.PerCpu
+		if cpuInst != nil {
+			perCpuInst = cpuInst.Usage.PerCpu
+		}
+	}
+	var perSocket, perSocketInst []uint64
+	if hasCpu && perCpu == nil {
+		perSocket = val.Cpu.Usage.PerSocket
+		if cpuInst != nil {
+			perSocketInst = cpuInst.Usage.PerSocket
+		}
+	}
+	numa := val.Memory.HierarchicalData.NumaStats
+	hasNuma := hasMemory && (len(numa.File) > 0 || len(numa.Anon) > 0 || len(numa.Unevictable) > 0)
+
+	result := &TopologyStats{Nodes: make([]NodeStats, 0, len(topology))}
+	sockets := make(map[int]*SocketStats)
+	for _, node := range topology {
+		nodeStats := NodeStats{Id: node.Id, MemoryCapacity: node.Memory}
+		// The socket of the node is the one of its first core. Nodes
+		// without CPUs, e.g. of memory expanders, are on none.
+		var nodeSocket *SocketStats
+		for _, core := range node.Cores {
+			socket, ok := sockets[core.SocketID]
+			if !ok {
+				socket = &SocketStats{Id: core.SocketID}
+				sockets[core.SocketID] = socket
+				if core.SocketID >= 0 && core.SocketID < len(perSocket) {
+					add(&socket.CpuUsage, perSocket[core.SocketID])
+				}
+				if core.SocketID >= 0 && core.SocketID < len(perSocketInst) {
+					add(&socket.CpuInstUsage, perSocketInst[core.SocketID])
+				}
+			}
+			if nodeSocket == nil {
+				nodeSocket = socket
+			}
+			for _, thread := range core.Threads {
+				nodeStats.NumThreads++
+				socket.NumThreads++
+				if perCpu != nil {
+					add(&nodeStats.CpuUsage, perCpu[thread])
+					add(&socket.CpuUsage, perCpu[thread])
+				}
+				if thread < len(perCpuInst) {
+					add(&nodeStats.CpuInstUsage, perCpuInst[thread])
+					add(&socket.CpuInstUsage, perCpuInst[thread])
+				}
+			}
+		}
+		if hasNuma {
+			id := uint8(node.Id)
+			usage := numa.File[id] + numa.Anon[id] + numa.Unevictable[id]
+			nodeStats.MemoryPages = &usage
+		}
+		if nodeSocket != nil {
+			nodeSocket.MemoryCapacity += node.Memory
+			if nodeStats.MemoryPages != nil {
+				add(&nodeSocket.MemoryPages, *nodeStats.MemoryPages)
+			}
+		}
+		result.Nodes = append(result.Nodes, nodeStats)
+	}
+	result.Sockets = make([]SocketStats, 0, len(sockets))
+	for _, socket := range sockets {
+		result.Sockets = append(result.Sockets, *socket)
+	}
+	sort.Slice(result.Sockets, func(i, j int) bool { return result.Sockets[i].Id < result.Sockets[j].Id })
+	return result
+}
+
 func ContainerStatsFromV1(containerName string, spec *v1.ContainerSpec, stats []*v1.ContainerStats) []*ContainerStats {
 	newStats := make([]*ContainerStats, 0, len(stats))
 	var last *v1.ContainerStats
@@ -104,6 +285,9 @@ func ContainerStatsFromV1(containerName string, spec *v1.ContainerSpec, stats []
 		stat := &ContainerStats{
 			Timestamp:        val.Timestamp,
 			ReferencedMemory: val.ReferencedMemory,
+			Sequence:         val.Sequence,
+			TimestampSkew:    val.TimestampSkew,
+			MonotonicTime:    val.MonotonicTime,
 		}
 		if spec.HasCpu {
 			stat.Cpu = &val.Cpu
@@ -127,6 +311,9 @@ func ContainerStatsFromV1(containerName string, spec *v1.ContainerSpec, stats []
 				Tcp:        TcpStat(val.Network.Tcp),
 				Tcp6:       TcpStat(val.Network.Tcp6),
 				Interfaces: val.Network.Interfaces,
+				Conntrack:  val.Network.Conntrack,
+				Netfilter:  val.Network.Netfilter,
+				Tc:         val.Network.Tc,
 			}
 		}
 		if spec.HasProcesses {
@@ -139,10 +326,18 @@ func ContainerStatsFromV1(containerName string, spec *v1.ContainerSpec, stats []
 					BaseUsageBytes:  &val.Filesystem[0].BaseUsage,
 					InodeUsage:      &val.Filesystem[0].Inodes,
 				}
+				if val.Filesystem[0].Quota != 0 {
+					stat.Filesystem.QuotaBytes = &val.Filesystem[0].Quota
+				}
+				if val.Filesystem[0].LogUsage != 0 {
+					stat.Filesystem.LogUsageBytes = &val.Filesystem[0].LogUsage
+					stat.Filesystem.LogGrowthRate = &val.Filesystem[0].LogGrowthRate
+				}
 			} else if len(val.Filesystem) > 1 && containerName != "/" {
 				// Cannot handle multiple devices per container.
 				klog.V(4).Infof("failed to handle multiple devices for container %s. Skipping Filesystem stats", containerName)
 			}
+			stat.Volumes = val.Volumes
 		}
 		if spec.HasDiskIo {
 			stat.DiskIo = &val.DiskIo
@@ -193,6 +388,9 @@ func InstCpuStats(last, cur *v1.ContainerStats) (*CpuInstStats, error) {
 	if len(last.Cpu.Usage.PerCpu) != len(cur.Cpu.Usage.PerCpu) {
 		return nil, fmt.Errorf("different number of cpus")
 	}
+	if len(last.Cpu.Usage.PerSocket) != len(cur.Cpu.Usage.PerSocket) {
+		return nil, fmt.Errorf("different number of sockets")
+	}
 	timeDelta := cur.Timestamp.Sub(last.Timestamp)
 	// Nanoseconds to gain precision and avoid having zero seconds if the
 	// difference between the timestamps is just under a second
@@ -217,6 +415,17 @@ func InstCpuStats(last, cur *v1.ContainerStats) (*CpuInstStats, error) {
 			return nil, err
 		}
 	}
+	var persocket []uint64
+	if len(last.Cpu.Usage.PerSocket) > 0 {
+		persocket = make([]uint64, len(last.Cpu.Usage.PerSocket))
+	}
+	for i := range persocket {
+		var err error
+		persocket[i], err = convertToRate(last.Cpu.Usage.PerSocket[i], cur.Cpu.Usage.PerSocket[i])
+		if err != nil {
+			return nil, err
+		}
+	}
 	user, err := convertToRate(last.Cpu.Usage.User, cur.Cpu.Usage.User)
 	if err != nil {
 		return nil, err
@@ -227,10 +436,11 @@ func InstCpuStats(last, cur *v1.ContainerStats) (*CpuInstStats, error) {
 	}
 	return &CpuInstStats{
 		Usage: CpuInstUsage{
-			Total:  total,
-			PerCpu: percpu,
-			User:   user,
-			System: system,
+			Total:     total,
+			PerCpu:    percpu,
+			PerSocket: persocket,
+			User:      user,
+			System:    system,
 		},
 	}, nil
 }
@@ -238,23 +448,34 @@ func InstCpuStats(last, cur *v1.ContainerStats) (*CpuInstStats, error) {
 // Get V2 container spec from v1 container info.
 func ContainerSpecFromV1(specV1 *v1.ContainerSpec, aliases []string, namespace string) ContainerSpec {
 	specV2 := ContainerSpec{
-		CreationTime:     specV1.CreationTime,
-		HasCpu:           specV1.HasCpu,
-		HasMemory:        specV1.HasMemory,
-		HasHugetlb:       specV1.HasHugetlb,
-		HasFilesystem:    specV1.HasFilesystem,
-		HasNetwork:       specV1.HasNetwork,
-		HasProcesses:     specV1.HasProcesses,
-		HasDiskIo:        specV1.HasDiskIo,
-		HasCustomMetrics: specV1.HasCustomMetrics,
-		Image:            specV1.Image,
-		Labels:           specV1.Labels,
-		Envs:             specV1.Envs,
+		CreationTime:        specV1.CreationTime,
+		StartedAt:           specV1.StartedAt,
+		FirstStatsLatency:   specV1.FirstStatsLatency,
+		HasCpu:              specV1.HasCpu,
+		HasMemory:           specV1.HasMemory,
+		HasHugetlb:          specV1.HasHugetlb,
+		HasFilesystem:       specV1.HasFilesystem,
+		HasNetwork:          specV1.HasNetwork,
+		HasProcesses:        specV1.HasProcesses,
+		Security:            specV1.Security,
+		Ports:               specV1.Ports,
+		HasDiskIo:           specV1.HasDiskIo,
+		DiskQuota:           specV1.DiskQuota,
+		HasCustomMetrics:    specV1.HasCustomMetrics,
+		Image:               specV1.Image,
+		Labels:              specV1.Labels,
+		Envs:                specV1.Envs,
+		Runtime:             specV1.Runtime,
+		RuntimeId:           specV1.RuntimeId,
+		Uid:                 specV1.Uid,
+		CgroupPath:          specV1.CgroupPath,
+		MetricsAvailability: specV1.MetricsAvailability,
 	}
 	if specV1.HasCpu {
 		specV2.Cpu.Limit = specV1.Cpu.Limit
 		specV2.Cpu.MaxLimit = specV1.Cpu.MaxLimit
 		specV2.Cpu.Mask = specV1.Cpu.Mask
+		specV2.Cpu.Mems = specV1.Cpu.Mems
 	}
 	if specV1.HasMemory {
 		specV2.Memory.Limit = specV1.Memory.Limit
@@ -264,6 +485,9 @@ func ContainerSpecFromV1(specV1 *v1.ContainerSpec, aliases []string, namespace s
 	if specV1.HasCustomMetrics {
 		specV2.CustomMetrics = specV1.CustomMetrics
 	}
+	if specV1.HasDiskIo {
+		specV2.DiskIoDevices = specV1.DiskIoDevices
+	}
 	specV2.Aliases = aliases
 	specV2.Namespace = namespace
 	return specV2
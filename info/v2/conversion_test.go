@@ -31,6 +31,15 @@ var (
 )
 
 func TestContainerSpecFromV1(t *testing.T) {
+	security := &v1.SecuritySpec{
+		AppArmorProfile: "docker-default",
+		AppArmorMode:    "enforce",
+		SeccompMode:     "filter",
+		Capabilities: v1.CapabilitiesSpec{
+			Effective: []string{"CAP_CHOWN", "CAP_NET_BIND_SERVICE"},
+			Bounding:  []string{"CAP_CHOWN", "CAP_NET_BIND_SERVICE"},
+		},
+	}
 	v1Spec := v1.ContainerSpec{
 		CreationTime: timestamp,
 		Labels:       labels,
@@ -50,6 +59,7 @@ func TestContainerSpecFromV1(t *testing.T) {
 		HasHugetlb:       true,
 		HasNetwork:       true,
 		HasProcesses:     true,
+		Security:         security,
 		HasFilesystem:    true,
 		HasDiskIo:        true,
 		HasCustomMetrics: true,
@@ -59,7 +69,10 @@ func TestContainerSpecFromV1(t *testing.T) {
 			Format: v1.IntType,
 			Units:  "bars",
 		}},
-		Image: "gcr.io/kubernetes/kubernetes:v1",
+		Image:      "gcr.io/kubernetes/kubernetes:v1",
+		Runtime:    "docker",
+		RuntimeId:  "8d6f6a7b4e2c",
+		CgroupPath: "/sys/fs/cgroup/system.slice/docker-8d6f6a7b4e2c.scope",
 	}
 
 	aliases := []string{"baz", "oof"}
@@ -84,6 +97,7 @@ func TestContainerSpecFromV1(t *testing.T) {
 		HasHugetlb:       true,
 		HasNetwork:       true,
 		HasProcesses:     true,
+		Security:         security,
 		HasFilesystem:    true,
 		HasDiskIo:        true,
 		HasCustomMetrics: true,
@@ -93,9 +107,12 @@ func TestContainerSpecFromV1(t *testing.T) {
 			Format: v1.IntType,
 			Units:  "bars",
 		}},
-		Image:     "gcr.io/kubernetes/kubernetes:v1",
-		Aliases:   aliases,
-		Namespace: namespace,
+		Image:      "gcr.io/kubernetes/kubernetes:v1",
+		Runtime:    "docker",
+		RuntimeId:  "8d6f6a7b4e2c",
+		CgroupPath: "/sys/fs/cgroup/system.slice/docker-8d6f6a7b4e2c.scope",
+		Aliases:    aliases,
+		Namespace:  namespace,
 	}
 
 	v2Spec := ContainerSpecFromV1(&v1Spec, aliases, namespace)
@@ -429,6 +446,34 @@ func TestInstCpuStats(t *testing.T) {
 				},
 			},
 		},
+		// Usage collected per socket
+		{
+			&v1.ContainerStats{
+				Timestamp: time.Unix(100, 0),
+				Cpu: v1.CpuStats{
+					Usage: v1.CpuUsage{
+						Total:     300,
+						PerSocket: []uint64{100, 200},
+					},
+				},
+			},
+			&v1.ContainerStats{
+				Timestamp: time.Unix(100, 0).Add(2 * time.Second),
+				Cpu: v1.CpuStats{
+					Usage: v1.CpuUsage{
+						Total:     500,
+						PerSocket: []uint64{140, 360},
+					},
+				},
+			},
+			&CpuInstStats{
+				Usage: CpuInstUsage{
+					Total:     100,
+					PerCpu:    []uint64{},
+					PerSocket: []uint64{20, 80},
+				},
+			},
+		},
 	}
 	for _, c := range tests {
 		got, err := InstCpuStats(c.last, c.cur)
@@ -441,3 +486,133 @@ func TestInstCpuStats(t *testing.T) {
 		assert.Equal(t, c.want, got)
 	}
 }
+
+func TestMachineStatsWithTopologyFromV1(t *testing.T) {
+	// Two sockets, the first one holding nodes 0 and 1, and node 2 without
+	// CPUs.
+	topology := []v1.Node{
+		{Id: 0, Memory: 1000, Cores: []v1.Core{{Id: 0, Threads: []int{0, 4}, SocketID: 0}, {Id: 1, Threads: []int{1, 5}, SocketID: 0}}},
+		{Id: 1, Memory: 2000, Cores: []v1.Core{{Id: 2, Threads: []int{2, 6}, SocketID: 0}}},
+		{Id: 3, Memory: 4000, Cores: []v1.Core{{Id: 3, Threads: []int{3, 7}, SocketID: 1}}},
+		{Id: 2, Memory: 8000},
+	}
+	cont := &v1.ContainerInfo{
+		Spec: v1.ContainerSpec{HasCpu: true, HasMemory: true},
+	}
+	for i := uint64(1); i <= 2; i++ {
+		stats := &v1.ContainerStats{Timestamp: timestamp.Add(time.Duration(i) * time.Second)}
+		for cpu := uint64(0); cpu < 8; cpu++ {
+			stats.Cpu.Usage.PerCpu = append(stats.Cpu.Usage.PerCpu, i*(cpu+1)*100)
+		}
+		stats.Memory.HierarchicalData.NumaStats = v1.MemoryNumaStats{
+			File: map[uint8]uint64{0: 10, 1: 20, 2: 30, 3: 40},
+			Anon: map[uint8]uint64{0: 1, 1: 2, 2: 3, 3: 4},
+		}
+		cont.Stats = append(cont.Stats, stats)
+	}
+
+	stats := MachineStatsWithTopologyFromV1(cont, topology)
+	assert.Len(t, stats, 2)
+	uint64p := func(v uint64) *uint64 { return &v }
+	assert.Equal(t, &TopologyStats{
+		Nodes: []NodeStats{
+			{Id: 0, NumThreads: 4, CpuUsage: uint64p(2 * 1400), CpuInstUsage: uint64p(1400), MemoryCapacity: 1000, MemoryPages: uint64p(11)},
+			{Id: 1, NumThreads: 2, CpuUsage: uint64p(2 * 1000), CpuInstUsage: uint64p(1000), MemoryCapacity: 2000, MemoryPages: uint64p(22)},
+			{Id: 3, NumThreads: 2, CpuUsage: uint64p(2 * 1200), CpuInstUsage: uint64p(1200), MemoryCapacity: 4000, MemoryPages: uint64p(44)},
+			{Id: 2, MemoryCapacity: 8000, MemoryPages: uint64p(33)},
+		},
+		Sockets: []SocketStats{
+			{Id: 0, NumThreads: 6, CpuUsage: uint64p(2 * 2400), CpuInstUsage: uint64p(2400), MemoryCapacity: 3000, MemoryPages: uint64p(33)},
+			{Id: 1, NumThreads: 2, CpuUsage: uint64p(2 * 1200), CpuInstUsage: uint64p(1200), MemoryCapacity: 4000, MemoryPages: uint64p(44)},
+		},
+	}, stats[1].Topology)
+
+	// With the usage collected per socket, only the sockets have their CPU
+	// usage.
+	sockets := CpuSockets(topology)
+	for _, s := range cont.Stats {
+		s.Cpu.Usage.PerSocket = SumPerSocket(s.Cpu.Usage.PerCpu, sockets)
+		s.Cpu.Usage.PerCpu = nil
+	}
+	stats = MachineStatsWithTopologyFromV1(cont, topology)
+	assert.Nil(t, stats[1].Topology.Nodes[0].CpuUsage)
+	assert.Equal(t, []SocketStats{
+		{Id: 0, NumThreads: 6, CpuUsage: uint64p(2 * 2400), CpuInstUsage: uint64p(2400), MemoryCapacity: 3000, MemoryPages: uint64p(33)},
+		{Id: 1, NumThreads: 2, CpuUsage: uint64p(2 * 1200), CpuInstUsage: uint64p(1200), MemoryCapacity: 4000, MemoryPages: uint64p(44)},
+	}, stats[1].Topology.Sockets)
+
+	// Without per-CPU usage, as with cgroup v2, and NUMA stats, only the
+	// topology is reported.
+	for _, s := range cont.Stats {
+		s.Cpu.Usage.PerSocket = nil
+		s.Memory.HierarchicalData.NumaStats = v1.MemoryNumaStats{}
+	}
+	stats = MachineStatsWithTopologyFromV1(cont, topology)
+	assert.Equal(t, NodeStats{Id: 0, NumThreads: 4, MemoryCapacity: 1000}, stats[1].Topology.Nodes[0])
+	assert.Nil(t, MachineStatsFromV1(cont)[1].Topology)
+}
+
+func TestMachineStatsWithMachineInfoFromV1(t *testing.T) {
+	cont := &v1.ContainerInfo{
+		Spec: v1.ContainerSpec{HasCpu: true, HasMemory: true},
+	}
+	for i := uint64(1); i <= 2; i++ {
+		stats := &v1.ContainerStats{Timestamp: timestamp.Add(time.Duration(i) * time.Second)}
+		stats.Cpu.Usage.Total = i * 1500000000
+		stats.Memory.WorkingSet = i * 1000
+		cont.Stats = append(cont.Stats, stats)
+	}
+	machineInfo := &v1.MachineInfo{
+		Allocatable: &v1.NodeAllocatable{
+			Allocatable: v1.ResourceAmounts{MilliCpu: 3000, Memory: 4000},
+		},
+	}
+
+	stats := MachineStatsWithMachineInfoFromV1(cont, machineInfo)
+	assert.Len(t, stats, 2)
+	// There is no instantaneous CPU usage for the first sample.
+	assert.NotNil(t, stats[0].Allocatable)
+	assert.Nil(t, stats[0].Allocatable.Cpu)
+	assert.Equal(t, 0.25, *stats[0].Allocatable.Memory)
+	assert.Equal(t, 0.5, *stats[1].Allocatable.Cpu)
+	assert.Equal(t, 0.5, *stats[1].Allocatable.Memory)
+
+	// Nothing is related to the allocatable resources without reservations.
+	machineInfo.Allocatable = nil
+	assert.Nil(t, MachineStatsWithMachineInfoFromV1(cont, machineInfo)[1].Allocatable)
+	assert.Nil(t, MachineStatsWithMachineInfoFromV1(cont, nil)[1].Allocatable)
+}
+
+func TestDerivedUtilizationFromStats(t *testing.T) {
+	stats := DerivedStats{
+		LatestUsage: InstantUsage{Cpu: 500, Memory: 1000},
+		MinuteUsage: Usage{
+			Cpu:    Percentiles{Present: true, Mean: 250, Max: 1000, Ninety: 750},
+			Memory: Percentiles{Present: true, Mean: 500, Max: 2000, Ninety: 1500},
+		},
+		HourUsage: Usage{
+			Cpu: Percentiles{Present: true, Mean: 100, Max: 2000, Ninety: 500},
+		},
+	}
+	allocatable := v1.ResourceAmounts{MilliCpu: 1000, Memory: 2000}
+	float64p := func(v float64) *float64 { return &v }
+	assert.Equal(t, &DerivedUtilization{
+		Allocatable: allocatable,
+		LatestUsage: AllocatableUtilization{Cpu: float64p(0.5), Memory: float64p(0.5)},
+		MinuteUsage: UtilizationPercentiles{
+			Mean:   AllocatableUtilization{Cpu: float64p(0.25), Memory: float64p(0.25)},
+			Max:    AllocatableUtilization{Cpu: float64p(1), Memory: float64p(1)},
+			Ninety: AllocatableUtilization{Cpu: float64p(0.75), Memory: float64p(0.75)},
+		},
+		HourUsage: UtilizationPercentiles{
+			Mean:   AllocatableUtilization{Cpu: float64p(0.1)},
+			Max:    AllocatableUtilization{Cpu: float64p(2)},
+			Ninety: AllocatableUtilization{Cpu: float64p(0.5)},
+		},
+	}, DerivedUtilizationFromStats(stats, allocatable))
+
+	// Nothing allocatable leaves the utilization unknown.
+	utilization := DerivedUtilizationFromStats(stats, v1.ResourceAmounts{Memory: 2000})
+	assert.Nil(t, utilization.LatestUsage.Cpu)
+	assert.Equal(t, 0.5, *utilization.LatestUsage.Memory)
+}
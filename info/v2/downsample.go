@@ -0,0 +1,119 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v2
+
+import (
+	"time"
+
+	v1 "github.com/yidoyoon/cadvisor-lite/info/v1"
+)
+
+// Aggregations of the samples of each interval of the step request option,
+// set by the agg request option.
+const (
+	// The first sample of the interval.
+	AggFirst = "first"
+	// The last sample of the interval.
+	AggLast = "last"
+	// The last sample of the interval, with its gauges averaged, or their
+	// maximum, minimum or sum, over the interval.
+	AggAvg = "avg"
+	AggMax = "max"
+	AggMin = "min"
+	AggSum = "sum"
+)
+
+// IsAggregation returns whether agg is an aggregation of the samples of an
+// interval.
+func IsAggregation(agg string) bool {
+	switch agg {
+	case AggFirst, AggLast, AggAvg, AggMax, AggMin, AggSum:
+		return true
+	}
+	return false
+}
+
+// DownsampleStats returns one sample per interval of step of the wall clock,
+// e.g. every whole minute for 1m, out of the stats sorted by timestamp,
+// aggregated according to agg, AggLast if empty. The stats are returned as is
+// if step is zero. The cumulative counters, e.g. of the CPU usage, are the
+// ones of the last sample of each interval whatever agg, so that the
+// instantaneous usage computed between the samples returned is the average
+// over the intervals.
+func DownsampleStats(stats []*v1.ContainerStats, step time.Duration, agg string) []*v1.ContainerStats {
+	if step <= 0 || len(stats) == 0 {
+		return stats
+	}
+	var downsampled []*v1.ContainerStats
+	for start := 0; start < len(stats); {
+		interval := stats[start].Timestamp.Truncate(step)
+		end := start + 1
+		for end < len(stats) && stats[end].Timestamp.Truncate(step).Equal(interval) {
+			end++
+		}
+		switch agg {
+		case AggFirst:
+			downsampled = append(downsampled, stats[start])
+		case AggAvg, AggMax, AggMin, AggSum:
+			downsampled = append(downsampled, aggregateGauges(stats[start:end], agg))
+		default:
+			downsampled = append(downsampled, stats[end-1])
+		}
+		start = end
+	}
+	return downsampled
+}
+
+// Gauges of the stats aggregated over an interval.
+var statsGauges = []func(*v1.ContainerStats) *uint64{
+	func(s *v1.ContainerStats) *uint64 { return &s.Memory.Usage },
+	func(s *v1.ContainerStats) *uint64 { return &s.Memory.WorkingSet },
+	func(s *v1.ContainerStats) *uint64 { return &s.Memory.RSS },
+	func(s *v1.ContainerStats) *uint64 { return &s.Memory.Cache },
+	func(s *v1.ContainerStats) *uint64 { return &s.Memory.Swap },
+	func(s *v1.ContainerStats) *uint64 { return &s.Memory.MappedFile },
+	func(s *v1.ContainerStats) *uint64 { return &s.Processes.ProcessCount },
+	func(s *v1.ContainerStats) *uint64 { return &s.Processes.ThreadsCurrent },
+	func(s *v1.ContainerStats) *uint64 { return &s.Processes.FdCount },
+}
+
+// aggregateGauges returns a copy of the last of the stats with its gauges
+// aggregated over the stats according to agg. The stats are not modified.
+func aggregateGauges(stats []*v1.ContainerStats, agg string) *v1.ContainerStats {
+	aggregated := *stats[len(stats)-1]
+	for _, gauge := range statsGauges {
+		value := *gauge(stats[0])
+		for _, s := range stats[1:] {
+			v := *gauge(s)
+			switch agg {
+			case AggMax:
+				if v > value {
+					value = v
+				}
+			case AggMin:
+				if v < value {
+					value = v
+				}
+			default:
+				value += v
+			}
+		}
+		if agg == AggAvg {
+			value /= uint64(len(stats))
+		}
+		*gauge(&aggregated) = value
+	}
+	return &aggregated
+}
@@ -0,0 +1,80 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v2
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	v1 "github.com/yidoyoon/cadvisor-lite/info/v1"
+)
+
+func TestDownsampleStats(t *testing.T) {
+	start := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	var stats []*v1.ContainerStats
+	// Samples every 10s from 10:00:20, skipping 10:01:00 to 10:01:50.
+	for _, offset := range []time.Duration{20, 30, 40, 50, 120, 130} {
+		stats = append(stats, &v1.ContainerStats{
+			Timestamp: start.Add(offset * time.Second),
+			Memory:    v1.MemoryStats{Usage: uint64(offset), WorkingSet: uint64(offset) / 10, MaxUsage: 1000 + uint64(offset)},
+		})
+	}
+
+	assert.Equal(t, stats, DownsampleStats(stats, 0, AggAvg))
+	assert.Empty(t, DownsampleStats(nil, time.Minute, AggLast))
+
+	last := DownsampleStats(stats, time.Minute, "")
+	assert.Equal(t, []*v1.ContainerStats{stats[3], stats[5]}, last)
+	assert.Equal(t, last, DownsampleStats(stats, time.Minute, AggLast))
+	assert.Equal(t, []*v1.ContainerStats{stats[0], stats[4]}, DownsampleStats(stats, time.Minute, AggFirst))
+	assert.Equal(t, stats, DownsampleStats(stats, 10*time.Second, AggFirst))
+
+	avg := DownsampleStats(stats, time.Minute, AggAvg)
+	if assert.Len(t, avg, 2) {
+		assert.Equal(t, stats[3].Timestamp, avg[0].Timestamp)
+		assert.Equal(t, uint64(35), avg[0].Memory.Usage)
+		assert.Equal(t, uint64(3), avg[0].Memory.WorkingSet)
+		// The other fields are the ones of the last sample.
+		assert.Equal(t, uint64(1050), avg[0].Memory.MaxUsage)
+		assert.Equal(t, uint64(125), avg[1].Memory.Usage)
+	}
+	for _, test := range []struct {
+		agg   string
+		usage []uint64
+	}{
+		{AggMax, []uint64{50, 130}},
+		{AggMin, []uint64{20, 120}},
+		{AggSum, []uint64{140, 250}},
+	} {
+		aggregated := DownsampleStats(stats, time.Minute, test.agg)
+		if assert.Len(t, aggregated, 2, test.agg) {
+			assert.Equal(t, test.usage[0], aggregated[0].Memory.Usage, test.agg)
+			assert.Equal(t, test.usage[1], aggregated[1].Memory.Usage, test.agg)
+			assert.Equal(t, uint64(1050), aggregated[0].Memory.MaxUsage, test.agg)
+		}
+	}
+	// The stats are not modified.
+	assert.Equal(t, uint64(50), stats[3].Memory.Usage)
+}
+
+func TestIsAggregation(t *testing.T) {
+	for _, agg := range []string{AggFirst, AggLast, AggAvg, AggMax, AggMin, AggSum} {
+		assert.True(t, IsAggregation(agg), agg)
+	}
+	assert.False(t, IsAggregation(""))
+	assert.False(t, IsAggregation("median"))
+}
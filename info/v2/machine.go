@@ -73,10 +73,24 @@ type Attributes struct {
 
 	// Type of cloud instance (e.g. GCE standard) the machine is.
 	InstanceType v1.InstanceType `json:"instance_type"`
+
+	// ID of the cloud instance given to it by the cloud provider.
+	InstanceID v1.InstanceID `json:"instance_id"`
+
+	// Zone of the cloud instance.
+	Zone v1.InstanceZone `json:"zone"`
+
+	// The boot id, which changes when the machine reboots.
+	BootID string `json:"boot_id"`
+
+	// Time at which the machine booted, and time since then in seconds,
+	// zero if unknown.
+	BootTime      time.Time `json:"boot_time"`
+	UptimeSeconds float64   `json:"uptime_seconds"`
 }
 
 func GetAttributes(mi *v1.MachineInfo, vi *v1.VersionInfo) Attributes {
-	return Attributes{
+	attributes := Attributes{
 		KernelVersion:      vi.KernelVersion,
 		ContainerOsVersion: vi.ContainerOsVersion,
 		DockerVersion:      vi.DockerVersion,
@@ -94,7 +108,15 @@ func GetAttributes(mi *v1.MachineInfo, vi *v1.VersionInfo) Attributes {
 		Topology:           mi.Topology,
 		CloudProvider:      mi.CloudProvider,
 		InstanceType:       mi.InstanceType,
+		InstanceID:         mi.InstanceID,
+		Zone:               mi.Zone,
+		BootID:             mi.BootID,
+		BootTime:           mi.BootTime,
 	}
+	if !mi.BootTime.IsZero() {
+		attributes.UptimeSeconds = time.Since(mi.BootTime).Seconds()
+	}
+	return attributes
 }
 
 // MachineStats contains usage statistics for the entire machine.
@@ -113,6 +135,57 @@ type MachineStats struct {
 	Filesystem []MachineFsStats `json:"filesystem,omitempty"`
 	// Task load statistics
 	Load *v1.LoadStats `json:"load_stats,omitempty"`
+	// CPU and memory statistics aggregated per NUMA node and per socket
+	Topology *TopologyStats `json:"topology,omitempty"`
+	// Utilization of the allocatable resources of the machine, set if
+	// resources are reserved on the machine
+	Allocatable *AllocatableUtilization `json:"allocatable,omitempty"`
+}
+
+// AllocatableUtilization is a usage of the machine as fractions of its
+// allocatable resources, i.e. its capacity minus the resources reserved for
+// the system and Kubernetes daemons and the eviction threshold. The fractions
+// exceed 1 when the reservations are used.
+type AllocatableUtilization struct {
+	// CPU usage as a fraction of the allocatable CPU.
+	Cpu *float64 `json:"cpu,omitempty"`
+	// Memory working set as a fraction of the allocatable memory.
+	Memory *float64 `json:"memory,omitempty"`
+}
+
+// TopologyStats aggregates the per-CPU usage and the NUMA memory statistics of
+// the machine along its topology.
+type TopologyStats struct {
+	Nodes   []NodeStats   `json:"nodes"`
+	Sockets []SocketStats `json:"sockets"`
+}
+
+// NodeStats contains the usage of the CPUs and of the memory of a NUMA node.
+type NodeStats struct {
+	Id int `json:"node_id"`
+	// Number of CPU threads of the node.
+	NumThreads int `json:"num_threads"`
+	// Cumulative CPU usage of the threads, in nanoseconds. Omitted if the
+	// per-CPU usage is not collected, as with cgroup v2.
+	CpuUsage *uint64 `json:"cpu_usage,omitempty"`
+	// CPU usage of the threads since the previous sample, in nanocores.
+	CpuInstUsage *uint64 `json:"cpu_inst_usage,omitempty"`
+	// Memory of the node, in bytes.
+	MemoryCapacity uint64 `json:"memory_capacity"`
+	// File, anonymous and unevictable memory allocated on the node, in
+	// pages. Omitted if the memory_numa metrics are not collected.
+	MemoryPages *uint64 `json:"memory_pages,omitempty"`
+}
+
+// SocketStats contains the usage of the CPUs of a socket, and of the memory
+// of the NUMA nodes whose CPUs are on the socket.
+type SocketStats struct {
+	Id             int     `json:"socket_id"`
+	NumThreads     int     `json:"num_threads"`
+	CpuUsage       *uint64 `json:"cpu_usage,omitempty"`
+	CpuInstUsage   *uint64 `json:"cpu_inst_usage,omitempty"`
+	MemoryCapacity uint64  `json:"memory_capacity"`
+	MemoryPages    *uint64 `json:"memory_pages,omitempty"`
 }
 
 // MachineFsStats contains per filesystem capacity and usage information.
@@ -196,3 +269,100 @@ type DiskStats struct {
 	// I/O completion time and the backlog that may be accumulating.
 	WeightedIoDuration *time.Duration `json:"weighted_io_duration,omitempty"`
 }
+
+// ImagePulls describes the downloads of the images being pulled by a container
+// runtime.
+type ImagePulls struct {
+	// Time at which the downloads were listed.
+	Timestamp time.Time `json:"timestamp"`
+	// Blobs being downloaded: layers, manifests and image configs.
+	Downloads []BlobDownload `json:"downloads"`
+	// Number of layers not downloaded yet.
+	LayersRemaining int `json:"layers_remaining"`
+	// Bytes downloaded so far, and total size of the blobs whose size is
+	// known.
+	BytesDownloaded uint64 `json:"bytes_downloaded"`
+	BytesTotal      uint64 `json:"bytes_total"`
+}
+
+// BlobDownload describes the download of a blob of an image.
+type BlobDownload struct {
+	// Reference of the download in the runtime, e.g. layer-sha256:<digest>.
+	Ref string `json:"ref"`
+	// Digest of the blob, if known.
+	Digest string `json:"digest,omitempty"`
+	// Whether the blob is a layer.
+	Layer bool `json:"layer"`
+	// Bytes downloaded so far, and size of the blob, zero if unknown.
+	BytesDownloaded uint64    `json:"bytes_downloaded"`
+	BytesTotal      uint64    `json:"bytes_total"`
+	StartedAt       time.Time `json:"started_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// GCAdvice lists the stopped containers and the images they leave unused
+// whose removal reclaims disk space in a container runtime.
+type GCAdvice struct {
+	// Time at which the disk usage was read.
+	Timestamp time.Time `json:"timestamp"`
+	// Bytes requested to be reclaimed, zero for as many as possible.
+	RequestedBytes int64 `json:"requested_bytes"`
+	// Bytes reclaimed by removing all the candidates, less than requested
+	// if there are not enough candidates.
+	ReclaimableBytes int64 `json:"reclaimable_bytes"`
+	// Candidates for removal, in the order they are removed: containers
+	// first, oldest first, then images.
+	Candidates []GCCandidate `json:"candidates"`
+	// Whether the removal of the candidates was attempted, and the bytes
+	// reclaimed by the successful removals.
+	Executed       bool  `json:"executed"`
+	ReclaimedBytes int64 `json:"reclaimed_bytes,omitempty"`
+}
+
+// Kinds of GCCandidate.
+const (
+	GCContainer = "container"
+	GCImage     = "image"
+)
+
+// GCCandidate is a container or image whose removal reclaims disk space.
+type GCCandidate struct {
+	// GCContainer or GCImage.
+	Kind string `json:"kind"`
+	ID   string `json:"id"`
+	// Names of the container or tags of the image.
+	Names []string `json:"names,omitempty"`
+	// Creation time, in seconds since the epoch.
+	Created int64 `json:"created"`
+	// Bytes reclaimed by its removal: the writable layer of a container, the
+	// layers of an image not shared with other images.
+	Size int64 `json:"size"`
+	// Whether it was removed, and why not if its removal failed.
+	Removed bool   `json:"removed,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// RuntimeStatus describes a container runtime detected on the machine, with
+// the same fields for all the runtimes.
+type RuntimeStatus struct {
+	// Name of the runtime: docker, containerd, podman or crio.
+	Name string `json:"name"`
+	// Endpoint cAdvisor reaches the runtime at, e.g. its unix socket.
+	Socket string `json:"socket"`
+	// Versions of the runtime and of its API, if reported.
+	Version    string `json:"version,omitempty"`
+	APIVersion string `json:"api_version,omitempty"`
+	// Root directory of the runtime and storage driver of its images and
+	// containers, e.g. overlay2, if reported.
+	RootDir       string `json:"root_dir,omitempty"`
+	StorageDriver string `json:"storage_driver,omitempty"`
+	// Number of containers and images known to the runtime, including the
+	// stopped containers, -1 if not reported.
+	NumContainers int `json:"num_containers"`
+	NumImages     int `json:"num_images"`
+	// Number of the containers of the runtime monitored by cAdvisor.
+	MonitoredContainers int `json:"monitored_containers"`
+	// Why the runtime could not be reached, in which case only the socket
+	// and the monitored containers are reported.
+	Error string `json:"error,omitempty"`
+}
@@ -0,0 +1,104 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v2
+
+import (
+	v1 "github.com/yidoyoon/cadvisor-lite/info/v1"
+)
+
+// Modes of the per-CPU usage, collected by the percpu_usage flag and
+// returned by the percpu request option.
+const (
+	// The usage of every CPU.
+	PerCpuFull = "full"
+	// The usage of every CPU summed per socket.
+	PerCpuSocket = "socket"
+	// No per-CPU usage.
+	PerCpuOff = "off"
+)
+
+// IsPerCpuMode returns whether mode is a mode of the per-CPU usage.
+func IsPerCpuMode(mode string) bool {
+	return mode == PerCpuFull || mode == PerCpuSocket || mode == PerCpuOff
+}
+
+// CpuSockets returns the socket of every CPU of topology, indexed by the id
+// of the CPU, -1 for the ids which are not in topology.
+func CpuSockets(topology []v1.Node) []int {
+	var sockets []int
+	for _, node := range topology {
+		for _, core := range node.Cores {
+			for _, thread := range core.Threads {
+				for len(sockets) <= thread {
+					sockets = append(sockets, -1)
+				}
+				sockets[thread] = core.SocketID
+			}
+		}
+	}
+	return sockets
+}
+
+// SumPerSocket sums the per-CPU usage perCpu per socket, indexed by the id of
+// the socket. The CPUs whose socket is not known are left out. Nil if no CPU
+// is on a known socket.
+func SumPerSocket(perCpu []uint64, sockets []int) []uint64 {
+	var perSocket []uint64
+	for cpu, usage := range perCpu {
+		if cpu >= len(sockets) || sockets[cpu] < 0 {
+			continue
+		}
+		for len(perSocket) <= sockets[cpu] {
+			perSocket = append(perSocket, 0)
+		}
+		perSocket[sockets[cpu]] += usage
+	}
+	return perSocket
+}
+
+// SelectPerCpuUsage sums the per-CPU usage of the stats per socket of sockets,
+// as returned by CpuSockets, or clears it, depending on mode. Nothing is
+// changed if mode is empty or PerCpuFull. The CPU stats are copied before
+// they are changed since they are shared with the v1 stats.
+func SelectPerCpuUsage(stats []*ContainerStats, mode string, sockets []int) {
+	if mode == "" || mode == PerCpuFull {
+		return
+	}
+	for _, s := range stats {
+		if s.Cpu != nil && (len(s.Cpu.Usage.PerCpu) > 0 || len(s.Cpu.Usage.PerSocket) > 0) {
+			cpu := *s.Cpu
+			cpu.Usage.PerSocket = perSocketUsage(cpu.Usage.PerCpu, cpu.Usage.PerSocket, mode, sockets)
+			cpu.Usage.PerCpu = nil
+			s.Cpu = &cpu
+		}
+		if s.CpuInst != nil {
+			s.CpuInst.Usage.PerSocket = perSocketUsage(s.CpuInst.Usage.PerCpu, s.CpuInst.Usage.PerSocket, mode, sockets)
+			s.CpuInst.Usage.PerCpu = nil
+		}
+	}
+}
+
+// perSocketUsage returns the per-socket usage in mode, given the per-CPU and
+// per-socket usage of the stats, the latter being set instead of the former
+// when the usage was collected per socket.
+func perSocketUsage(perCpu, perSocket []uint64, mode string, sockets []int) []uint64 {
+	if mode != PerCpuSocket {
+		return nil
+	}
+	if len(perCpu) > 0 {
+		return SumPerSocket(perCpu, sockets)
+	}
+	return perSocket
+}
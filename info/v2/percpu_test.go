@@ -0,0 +1,94 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v2
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	v1 "github.com/yidoyoon/cadvisor-lite/info/v1"
+)
+
+// Two sockets of two cores of two threads, the threads of a core not being
+// numbered next to each other, and CPU 8 missing.
+var percpuTopology = []v1.Node{
+	{Id: 0, Cores: []v1.Core{{SocketID: 0, Threads: []int{0, 4}}, {SocketID: 0, Threads: []int{1, 5}}}},
+	{Id: 1, Cores: []v1.Core{{SocketID: 1, Threads: []int{2, 6}}, {SocketID: 1, Threads: []int{3, 7}}, {SocketID: 1, Threads: []int{9}}}},
+}
+
+func TestCpuSockets(t *testing.T) {
+	assert.Equal(t, []int{0, 0, 1, 1, 0, 0, 1, 1, -1, 1}, CpuSockets(percpuTopology))
+	assert.Nil(t, CpuSockets(nil))
+}
+
+func TestSumPerSocket(t *testing.T) {
+	sockets := CpuSockets(percpuTopology)
+	assert.Equal(t, []uint64{1 + 2 + 5 + 6, 3 + 4 + 7 + 8 + 10}, SumPerSocket([]uint64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, sockets))
+	// The CPUs beyond the topology are left out.
+	assert.Equal(t, []uint64{1, 2}, SumPerSocket([]uint64{1, 0, 2, 0, 0, 0, 0, 0, 0, 0, 100}, sockets))
+	assert.Nil(t, SumPerSocket([]uint64{1, 2}, nil))
+}
+
+func TestSelectPerCpuUsage(t *testing.T) {
+	sockets := CpuSockets(percpuTopology)
+	newStats := func() *ContainerStats {
+		return &ContainerStats{
+			Timestamp: timestamp,
+			Cpu:       &v1.CpuStats{Usage: v1.CpuUsage{Total: 36, PerCpu: []uint64{1, 2, 3, 4, 5, 6, 7, 8}}},
+			CpuInst:   &CpuInstStats{Usage: CpuInstUsage{Total: 10, PerCpu: []uint64{1, 1, 1, 1, 1, 1, 2, 2}}},
+		}
+	}
+
+	stats := []*ContainerStats{newStats()}
+	SelectPerCpuUsage(stats, PerCpuFull, sockets)
+	assert.Equal(t, newStats(), stats[0])
+	SelectPerCpuUsage(stats, "", sockets)
+	assert.Equal(t, newStats(), stats[0])
+
+	// The CPU stats are copied, not modified.
+	cpu := newStats().Cpu
+	stats = []*ContainerStats{{Timestamp: timestamp, Cpu: cpu, CpuInst: newStats().CpuInst}}
+	SelectPerCpuUsage(stats, PerCpuSocket, sockets)
+	assert.Equal(t, &ContainerStats{
+		Timestamp: timestamp,
+		Cpu:       &v1.CpuStats{Usage: v1.CpuUsage{Total: 36, PerSocket: []uint64{14, 22}}},
+		CpuInst:   &CpuInstStats{Usage: CpuInstUsage{Total: 10, PerSocket: []uint64{4, 6}}},
+	}, stats[0])
+	assert.Equal(t, newStats().Cpu, cpu)
+
+	stats = []*ContainerStats{newStats()}
+	SelectPerCpuUsage(stats, PerCpuOff, sockets)
+	assert.Equal(t, &ContainerStats{
+		Timestamp: timestamp,
+		Cpu:       &v1.CpuStats{Usage: v1.CpuUsage{Total: 36}},
+		CpuInst:   &CpuInstStats{Usage: CpuInstUsage{Total: 10}},
+	}, stats[0])
+
+	// The usage collected per socket is kept as is, or dropped.
+	collected := func() *ContainerStats {
+		return &ContainerStats{
+			Timestamp: timestamp,
+			Cpu:       &v1.CpuStats{Usage: v1.CpuUsage{Total: 36, PerSocket: []uint64{14, 22}}},
+			CpuInst:   &CpuInstStats{Usage: CpuInstUsage{Total: 10, PerSocket: []uint64{4, 6}}},
+		}
+	}
+	stats = []*ContainerStats{collected()}
+	SelectPerCpuUsage(stats, PerCpuSocket, sockets)
+	assert.Equal(t, collected(), stats[0])
+	SelectPerCpuUsage(stats, PerCpuOff, sockets)
+	assert.Nil(t, stats[0].Cpu.Usage.PerSocket)
+	assert.Nil(t, stats[0].CpuInst.Usage.PerSocket)
+}
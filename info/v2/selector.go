@@ -0,0 +1,176 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v2
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Operators of the requirements of a label selector.
+const (
+	selectorEquals    = "="
+	selectorNotEquals = "!="
+	selectorExists    = "exists"
+	selectorNotExists = "!"
+	selectorIn        = "in"
+	selectorNotIn     = "notin"
+)
+
+type labelRequirement struct {
+	key      string
+	operator string
+	values   []string
+}
+
+// LabelSelector selects containers by their labels, with the syntax of the
+// Kubernetes label selectors: a comma separated list of requirements, which
+// must all be met, among key=value, key==value, key!=value, key (the label
+// is set), !key (the label is not set), key in (value1,value2) and
+// key notin (value1,value2).
+type LabelSelector struct {
+	requirements []labelRequirement
+}
+
+var setRequirementRegexp = regexp.MustCompile(`^(\S+)\s+(in|notin)\s*\(([^()]*)\)$`)
+
+// ParseLabelSelector parses a label selector. The empty selector selects all
+// containers.
+func ParseLabelSelector(selector string) (LabelSelector, error) {
+	var s LabelSelector
+	for _, part := range splitSelector(selector) {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		var r labelRequirement
+		if m := setRequirementRegexp.FindStringSubmatch(part); m != nil {
+			r = labelRequirement{key: m[1], operator: m[2]}
+			for _, value := range strings.Split(m[3], ",") {
+				r.values = append(r.values, strings.TrimSpace(value))
+			}
+		} else if key, value, ok := strings.Cut(part, "!="); ok {
+			r = labelRequirement{key: key, operator: selectorNotEquals, values: []string{value}}
+		} else if key, value, ok := strings.Cut(part, "=="); ok {
+			r = labelRequirement{key: key, operator: selectorEquals, values: []string{value}}
+		} else if key, value, ok := strings.Cut(part, "="); ok {
+			r = labelRequirement{key: key, operator: selectorEquals, values: []string{value}}
+		} else if strings.HasPrefix(part, "!") {
+			r = labelRequirement{key: part[1:], operator: selectorNotExists}
+		} else {
+			r = labelRequirement{key: part, operator: selectorExists}
+		}
+		r.key = strings.TrimSpace(r.key)
+		for i := range r.values {
+			r.values[i] = strings.TrimSpace(r.values[i])
+		}
+		if r.key == "" || strings.ContainsAny(r.key, " \t()=!") {
+			return LabelSelector{}, fmt.Errorf("invalid label selector requirement %q", part)
+		}
+		s.requirements = append(s.requirements, r)
+	}
+	return s, nil
+}
+
+// splitSelector splits a selector at the commas which are not in the values
+// of a set requirement.
+func splitSelector(selector string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i, c := range selector {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, selector[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, selector[start:])
+}
+
+// Empty returns whether the selector selects all containers.
+func (s LabelSelector) Empty() bool {
+	return len(s.requirements) == 0
+}
+
+// Matches returns whether labels meet all the requirements of the selector.
+func (s LabelSelector) Matches(labels map[string]string) bool {
+	for _, r := range s.requirements {
+		value, ok := labels[r.key]
+		switch r.operator {
+		case selectorExists:
+			if !ok {
+				return false
+			}
+		case selectorNotExists:
+			if ok {
+				return false
+			}
+		case selectorEquals, selectorIn:
+			if !ok || !containsString(r.values, value) {
+				return false
+			}
+		case selectorNotEquals, selectorNotIn:
+			if ok && containsString(r.values, value) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// NameMatcher selects containers by their names, with a regular expression
+// which must fully match the name or one of the aliases of a container, as
+// the label matchers of Prometheus do.
+type NameMatcher struct {
+	re *regexp.Regexp
+}
+
+// ParseNameRegex parses the regular expression of a NameMatcher.
+func ParseNameRegex(nameRegex string) (NameMatcher, error) {
+	re, err := regexp.Compile("^(?:" + nameRegex + ")$")
+	if err != nil {
+		return NameMatcher{}, err
+	}
+	return NameMatcher{re: re}, nil
+}
+
+// Matches returns whether the name or one of the aliases matches.
+func (m NameMatcher) Matches(name string, aliases []string) bool {
+	if m.re.MatchString(name) {
+		return true
+	}
+	for _, alias := range aliases {
+		if m.re.MatchString(alias) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
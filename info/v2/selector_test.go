@@ -0,0 +1,83 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v2
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLabelSelector(t *testing.T) {
+	labels := map[string]string{
+		"io.kubernetes.pod.namespace": "prod",
+		"app":                         "web",
+	}
+	for selector, expected := range map[string]bool{
+		"":                                         true,
+		"io.kubernetes.pod.namespace=prod":         true,
+		"io.kubernetes.pod.namespace==prod":        true,
+		"io.kubernetes.pod.namespace=dev":          false,
+		"io.kubernetes.pod.namespace!=dev":         true,
+		"tier!=db":                                 true,
+		"app=web,io.kubernetes.pod.namespace=prod": true,
+		"app=web, io.kubernetes.pod.namespace=dev": false,
+		"app":                        true,
+		"tier":                       false,
+		"!tier":                      true,
+		"!app":                       false,
+		"app in (web, api)":          true,
+		"app in (api,db),tier":       false,
+		"app notin (api,db)":         true,
+		"tier notin (db)":            true,
+		"app notin (web),!tier":      false,
+		"app=web,app in (web),!tier": true,
+		"app = web":                  true,
+	} {
+		s, err := ParseLabelSelector(selector)
+		require.NoError(t, err, selector)
+		assert.Equal(t, expected, s.Matches(labels), selector)
+		assert.Equal(t, selector == "", s.Empty(), selector)
+	}
+}
+
+func TestNameMatcher(t *testing.T) {
+	aliases := []string{"nginx", "3f2a9c"}
+	for nameRegex, expected := range map[string]bool{
+		"/docker/3f2a9c":   true,
+		"/docker/.*":       true,
+		"/docker":          false,
+		"ngin.":            true,
+		"ngin":             false,
+		"redis|nginx":      true,
+		"3f2a.*|/system.*": true,
+		"/system.slice/.*": false,
+	} {
+		m, err := ParseNameRegex(nameRegex)
+		require.NoError(t, err, nameRegex)
+		assert.Equal(t, expected, m.Matches("/docker/3f2a9c", aliases), nameRegex)
+	}
+
+	_, err := ParseNameRegex("/docker/(")
+	assert.Error(t, err)
+}
+
+func TestLabelSelectorInvalid(t *testing.T) {
+	for _, selector := range []string{"=prod", "!", "app in web", "a b=c", "app in (web"} {
+		_, err := ParseLabelSelector(selector)
+		assert.Error(t, err, selector)
+	}
+}
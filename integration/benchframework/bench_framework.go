@@ -18,6 +18,8 @@ import (
 	"bytes"
 	"flag"
 	"fmt"
+	"io"
+	"net/http"
 	"os/exec"
 	"strings"
 	"testing"
@@ -32,6 +34,7 @@ import (
 var host = flag.String("host", "localhost", "Address of the host being tested")
 var port = flag.Int("port", 8080, "Port of the application on the host being tested")
 var sshOptions = flag.String("ssh-options", "", "Command line options for ssh")
+var containers = flag.Int("containers", 10, "Number of pause containers to run on the host being tested before benchmarking, 0 to benchmark the containers already running")
 
 // Integration bench framework.
 type Framework interface {
@@ -97,10 +100,35 @@ const (
 	Unknown      string = ""
 )
 
+// Encodings of the results of the API.
+const (
+	JSON     string = "json"
+	Protobuf string = "protobuf"
+	Msgpack  string = "msgpack"
+)
+
+// Compressions of the responses of the API.
+const (
+	Identity string = "identity"
+	Gzip     string = "gzip"
+	Deflate  string = "deflate"
+)
+
+// Media types of the results in each encoding.
+var mediaTypes = map[string]string{
+	JSON:     "application/json",
+	Protobuf: "application/protobuf",
+	Msgpack:  "application/msgpack",
+}
+
 type DockerActions interface {
 	// Run the no-op pause Docker container and return its ID.
 	RunPause() string
 
+	// Run as many pause containers as set by the containers flag and return
+	// their IDs.
+	RunPauses() []string
+
 	// Run the specified command in a Docker busybox container and return its ID.
 	RunBusybox(cmd ...string) string
 
@@ -127,6 +155,11 @@ type CadvisorActions interface {
 	// Returns a cAdvisor client to the machine being tested.
 	Client() *client.Client
 	ClientV2() *v2.Client
+
+	// Gets the resource at path, relative to the root of cAdvisor, e.g.
+	// api/v1.3/docker, in the specified encoding and compression. Returns
+	// the number of bytes of the body received on the wire.
+	Fetch(path, encoding, compression string) (int64, error)
 }
 
 type realFramework struct {
@@ -134,6 +167,7 @@ type realFramework struct {
 	b                *testing.B
 	cadvisorClient   *client.Client
 	cadvisorClientV2 *v2.Client
+	httpClient       *http.Client
 
 	shellActions  shellActions
 	dockerActions dockerActions
@@ -211,12 +245,70 @@ func (f *realFramework) ClientV2() *v2.Client {
 	return f.cadvisorClientV2
 }
 
+func (f *realFramework) Fetch(path, encoding, compression string) (int64, error) {
+	mediaType, ok := mediaTypes[encoding]
+	if !ok {
+		return 0, fmt.Errorf("unknown encoding %q", encoding)
+	}
+	if f.httpClient == nil {
+		// The bodies are read as received on the wire, without being
+		// decompressed.
+		f.httpClient = &http.Client{
+			Transport: &http.Transport{DisableCompression: true},
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodGet, f.Hostname().FullHostname()+path, nil)
+	if err != nil {
+		return 0, err
+	}
+	if encoding == Msgpack {
+		// Msgpack is only selected by the format parameter.
+		query := req.URL.Query()
+		query.Set("format", Msgpack)
+		req.URL.RawQuery = query.Encode()
+	}
+	req.Header.Set("Accept", mediaType)
+	req.Header.Set("Accept-Encoding", compression)
+
+	resp, err := f.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return 0, fmt.Errorf("failed to get %q: %s: %s", path, resp.Status, body)
+	}
+	// Make sure the numbers are not those of another encoding or
+	// compression the server fell back to.
+	if contentType := resp.Header.Get("Content-Type"); contentType != mediaType {
+		return 0, fmt.Errorf("expected %q to be encoded as %q, got %q", path, mediaType, contentType)
+	}
+	contentEncoding := resp.Header.Get("Content-Encoding")
+	if contentEncoding == "" {
+		contentEncoding = Identity
+	}
+	if contentEncoding != compression {
+		return 0, fmt.Errorf("expected %q to be compressed with %q, got %q", path, compression, contentEncoding)
+	}
+	return io.Copy(io.Discard, resp.Body)
+}
+
 func (a dockerActions) RunPause() string {
 	return a.Run(DockerRunArgs{
 		Image: "registry.k8s.io/pause",
 	})
 }
 
+func (a dockerActions) RunPauses() []string {
+	ids := make([]string, 0, *containers)
+	for i := 0; i < *containers; i++ {
+		ids = append(ids, a.RunPause())
+	}
+	return ids
+}
+
 // Run the specified command in a Docker busybox container.
 func (a dockerActions) RunBusybox(cmd ...string) string {
 	return a.Run(DockerRunArgs{
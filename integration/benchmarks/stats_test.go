@@ -0,0 +1,83 @@
+// Copyright 2026 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package benchmarks
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+	"github.com/yidoyoon/cadvisor-lite/integration/benchframework"
+)
+
+// Stats endpoints of the API, by name of the benchmark.
+var statsEndpoints = []struct {
+	name string
+	path string
+}{
+	{"v1.3-docker", "api/v1.3/docker"},
+	{"v1.3-subcontainers", "api/v1.3/subcontainers/"},
+	{"v2.1-stats", "api/v2.1/stats/?type=docker&recursive=true"},
+}
+
+// Waits up to 30s for the containers to have stats in cAdvisor.
+func waitForContainers(ids []string, fm benchframework.Framework) {
+	err := benchframework.RetryForDuration(func() error {
+		for _, id := range ids {
+			cont, err := fm.Cadvisor().Client().DockerContainer(id, &info.ContainerInfoRequest{
+				NumStats: 1,
+			})
+			if err != nil {
+				return err
+			}
+			if len(cont.Stats) != 1 {
+				return fmt.Errorf("no stats returned for container %q", id)
+			}
+		}
+		return nil
+	}, 30*time.Second)
+	if err != nil {
+		fm.B().Fatalf("Timed out waiting for the containers to be available in cAdvisor: %v", err)
+	}
+}
+
+// Measures the latency of the stats endpoints, from sending the request to
+// receiving the whole body, and the bytes of the body on the wire, in each
+// encoding and compression of the API.
+func BenchmarkStats(b *testing.B) {
+	fm := benchframework.New(b)
+	defer fm.Cleanup()
+
+	waitForContainers(fm.Docker().RunPauses(), fm)
+
+	for _, endpoint := range statsEndpoints {
+		for _, encoding := range []string{benchframework.JSON, benchframework.Protobuf, benchframework.Msgpack} {
+			for _, compression := range []string{benchframework.Identity, benchframework.Gzip, benchframework.Deflate} {
+				b.Run(fmt.Sprintf("%s/%s/%s", endpoint.name, encoding, compression), func(b *testing.B) {
+					var bytes int64
+					for i := 0; i < b.N; i++ {
+						n, err := fm.Cadvisor().Fetch(endpoint.path, encoding, compression)
+						if err != nil {
+							b.Fatal(err)
+						}
+						bytes += n
+					}
+					b.ReportMetric(float64(bytes)/float64(b.N), "wire-B/op")
+				})
+			}
+		}
+	}
+}
@@ -27,11 +27,13 @@ import (
 
 	"github.com/yidoyoon/cadvisor-lite/client"
 	v2 "github.com/yidoyoon/cadvisor-lite/client/v2"
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
 )
 
 var host = flag.String("host", "localhost", "Address of the host being tested")
 var port = flag.Int("port", 8080, "Port of the application on the host being tested")
 var sshOptions = flag.String("ssh-options", "", "Command line options for ssh")
+var apiOnly = flag.Bool("api-only", false, "Validate the host only through the cAdvisor API, without running commands or Docker containers on it, e.g. for a locked-down production node. The tests which need them are skipped.")
 
 // Integration test framework.
 type Framework interface {
@@ -44,14 +46,35 @@ type Framework interface {
 	// Returns the hostname being tested.
 	Hostname() HostnameInfo
 
-	// Returns the Docker actions for the test framework.
+	// Returns the Docker actions for the test framework. Skips the test in
+	// API-only mode.
 	Docker() DockerActions
 
-	// Returns the shell actions for the test framework.
+	// Returns the shell actions for the test framework. Skips the test in
+	// API-only mode.
 	Shell() ShellActions
 
 	// Returns the cAdvisor actions for the test framework.
 	Cadvisor() CadvisorActions
+
+	// Returns the capabilities of the host being tested, probed through the
+	// cAdvisor API at the first call.
+	Capabilities() Capabilities
+}
+
+// Capabilities of the host and of the cAdvisor being tested, which the tests
+// check to skip what cannot be validated on the host.
+type Capabilities struct {
+	// Whether the tests can run commands and Docker containers on the host,
+	// false in API-only mode.
+	Shell bool
+
+	// Whether cAdvisor reports the per-CPU usage, which it does not on
+	// cgroup v2 nor with --percpu_usage other than full.
+	PerCpuUsage bool
+
+	// Whether cAdvisor monitors running Docker containers.
+	DockerContainers bool
 }
 
 // Instantiates a Framework. Cleanup *must* be called. Class is thread-compatible.
@@ -138,6 +161,9 @@ type realFramework struct {
 	shellActions  shellActions
 	dockerActions dockerActions
 
+	// Capabilities of the host, nil until probed.
+	capabilities *Capabilities
+
 	// Cleanup functions to call on Cleanup()
 	cleanups []func()
 }
@@ -169,13 +195,43 @@ func (f *realFramework) Hostname() HostnameInfo {
 }
 
 func (f *realFramework) Shell() ShellActions {
+	if *apiOnly {
+		f.t.Skip("Skipping test running commands on the host in API-only mode")
+	}
 	return f.shellActions
 }
 
 func (f *realFramework) Docker() DockerActions {
+	if *apiOnly {
+		f.t.Skip("Skipping test running Docker containers on the host in API-only mode")
+	}
 	return f.dockerActions
 }
 
+func (f *realFramework) Capabilities() Capabilities {
+	if f.capabilities != nil {
+		return *f.capabilities
+	}
+	c := &Capabilities{Shell: !*apiOnly}
+	machineStats, err := f.ClientV2().MachineStats()
+	if err != nil {
+		f.t.Fatalf("Failed to probe the machine stats of cAdvisor: %v", err)
+	}
+	for _, stat := range machineStats {
+		if stat.Cpu != nil && len(stat.Cpu.Usage.PerCpu) > 0 {
+			c.PerCpuUsage = true
+		}
+	}
+	containers, err := f.Client().AllDockerContainers(&info.ContainerInfoRequest{NumStats: 1})
+	if err != nil {
+		f.t.Fatalf("Failed to probe the Docker containers of cAdvisor: %v", err)
+	}
+	c.DockerContainers = len(containers) > 0
+	klog.Infof("Capabilities of %q: %+v", f.hostname.Host, *c)
+	f.capabilities = c
+	return *c
+}
+
 func (f *realFramework) Cadvisor() CadvisorActions {
 	return f
 }
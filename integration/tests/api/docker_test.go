@@ -143,6 +143,33 @@ func TestGetAllDockerContainers(t *testing.T) {
 	sanityCheck(containerID2, findContainer(containerID2, containersInfo, t), t)
 }
 
+// Check the Docker containers already running on the host, through the API
+// only.
+func TestRunningDockerContainers(t *testing.T) {
+	fm := framework.New(t)
+	defer fm.Cleanup()
+
+	capabilities := fm.Capabilities()
+	if !capabilities.DockerContainers {
+		t.Skip("Skipping test as cAdvisor monitors no running Docker container")
+	}
+
+	containersInfo, err := fm.Cadvisor().Client().AllDockerContainers(&info.ContainerInfoRequest{
+		NumStats: 1,
+	})
+	require.NoError(t, err)
+	for _, containerInfo := range containersInfo {
+		// Containers may stop between the housekeepings.
+		if len(containerInfo.Stats) == 0 {
+			continue
+		}
+		assert.NotEmpty(t, containerInfo.Aliases, "Container %q should have aliases", containerInfo.Name)
+		assert.Equal(t, "docker", containerInfo.Namespace, "Container %q should be in the docker namespace", containerInfo.Name)
+		assert.False(t, containerInfo.Spec.CreationTime.IsZero(), "Container %q should have a creation time", containerInfo.Name)
+		checkCPUStats(t, containerInfo.Stats[0].Cpu, capabilities.PerCpuUsage)
+	}
+}
+
 // Check expected properties of a Docker container.
 func TestBasicDockerContainer(t *testing.T) {
 	fm := framework.New(t)
@@ -256,7 +283,7 @@ func TestDockerContainerCpuStats(t *testing.T) {
 	sanityCheck(containerID, containerInfo, t)
 
 	// Checks for CpuStats.
-	checkCPUStats(t, containerInfo.Stats[0].Cpu)
+	checkCPUStats(t, containerInfo.Stats[0].Cpu, fm.Capabilities().PerCpuUsage)
 }
 
 // Check the memory ContainerStats.
@@ -21,7 +21,6 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
-	"github.com/opencontainers/runc/libcontainer/cgroups"
 	"github.com/yidoyoon/cadvisor-lite/integration/framework"
 )
 
@@ -33,6 +32,7 @@ func TestMachineStatsIsReturned(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+	perCpu := fm.Capabilities().PerCpuUsage
 
 	as := assert.New(t)
 	for _, stat := range machineStats {
@@ -40,7 +40,7 @@ func TestMachineStatsIsReturned(t *testing.T) {
 		as.True(stat.Cpu.Usage.Total > 0)
 		// PerCPU CPU usage is not supported in cgroupv2 (cpuacct.usage_percpu)
 		// https://github.com/yidoyoon/cadvisor-lite/issues/3065
-		if !cgroups.IsCgroup2UnifiedMode() {
+		if perCpu {
 			as.True(len(stat.Cpu.Usage.PerCpu) > 0)
 		}
 		if stat.CpuInst != nil {
@@ -20,7 +20,6 @@ import (
 
 	info "github.com/yidoyoon/cadvisor-lite/info/v1"
 
-	"github.com/opencontainers/runc/libcontainer/cgroups"
 	"github.com/stretchr/testify/assert"
 	"k8s.io/klog/v2"
 )
@@ -42,15 +41,16 @@ func inDelta(t *testing.T, expected, actual, delta uint64, description string) {
 	}
 }
 
-// Checks that CPU stats are valid.
-func checkCPUStats(t *testing.T, stat info.CpuStats) {
+// Checks that CPU stats are valid, including the per-CPU usage if cAdvisor
+// reports it.
+func checkCPUStats(t *testing.T, stat info.CpuStats, perCpu bool) {
 	assert := assert.New(t)
 
 	assert.NotEqual(0, stat.Usage.Total, "Total CPU usage should not be zero")
 
 	// PerCPU CPU usage is not supported in cgroupv2 (cpuacct.usage_percpu)
 	// https://github.com/yidoyoon/cadvisor-lite/issues/3065
-	if !cgroups.IsCgroup2UnifiedMode() {
+	if perCpu {
 		assert.NotEmpty(stat.Usage.PerCpu, "Per-core usage should not be empty")
 
 		totalUsage := uint64(0)
@@ -15,6 +15,7 @@
 package healthz
 
 import (
+	"encoding/json"
 	"io"
 	"net/http"
 	"testing"
@@ -26,18 +27,26 @@ func TestHealthzOk(t *testing.T) {
 	fm := framework.New(t)
 	defer fm.Cleanup()
 
-	// Ensure that /heathz returns "ok"
-	resp, err := http.Get(fm.Hostname().FullHostname() + "healthz")
-	if err != nil {
-		t.Fatal(err)
-	}
-	defer resp.Body.Close()
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		t.Fatal(err)
-	}
+	// Ensure that the health endpoints return the "ok" status.
+	for _, endpoint := range []string{"healthz", "livez", "readyz"} {
+		resp, err := http.Get(fm.Hostname().FullHostname() + endpoint)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
 
-	if string(body) != "ok" {
-		t.Fatalf("cAdvisor returned unexpected healthz status of %q", body)
+		var status struct {
+			Status string `json:"status"`
+		}
+		if err := json.Unmarshal(body, &status); err != nil {
+			t.Fatalf("cAdvisor returned invalid %s response %q: %v", endpoint, body, err)
+		}
+		if resp.StatusCode != http.StatusOK || status.Status != "ok" {
+			t.Fatalf("cAdvisor returned unexpected %s status %d: %s", endpoint, resp.StatusCode, body)
+		}
 	}
 }
@@ -0,0 +1,201 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package machine
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+)
+
+var (
+	systemReserved    = flag.String("system_reserved", "", "Resources reserved for the system daemons, as a comma-separated list of resource=quantity pairs in the format of the kubelet --system-reserved flag, e.g. cpu=500m,memory=1Gi. They are excluded from the allocatable resources of the machine.")
+	kubeReserved      = flag.String("kube_reserved", "", "Resources reserved for the Kubernetes daemons, in the format of --system_reserved. They are excluded from the allocatable resources of the machine.")
+	evictionHard      = flag.String("eviction_hard", "", "Hard eviction thresholds of the kubelet, in the format of the kubelet --eviction-hard flag, e.g. memory.available<100Mi. The memory.available threshold is excluded from the allocatable memory of the machine.")
+	kubeletConfigFile = flag.String("kubelet_config", "", "Path to the kubelet configuration file to read the systemReserved, kubeReserved and evictionHard settings from. The --system_reserved, --kube_reserved and --eviction_hard flags take precedence over its settings.")
+)
+
+const (
+	cpuResource             = "cpu"
+	memoryResource          = "memory"
+	memoryAvailableSignal   = "memory.available"
+	evictionThresholdSuffix = "%"
+)
+
+// Suffixes of the Kubernetes resource quantities and their multipliers.
+var (
+	binaryQuantitySuffixes = map[string]float64{
+		"Ki": 1 << 10, "Mi": 1 << 20, "Gi": 1 << 30, "Ti": 1 << 40, "Pi": 1 << 50, "Ei": 1 << 60,
+	}
+	decimalQuantitySuffixes = map[string]float64{
+		"n": 1e-9, "u": 1e-6, "m": 1e-3, "k": 1e3, "M": 1e6, "G": 1e9, "T": 1e12, "P": 1e15, "E": 1e18,
+	}
+)
+
+// kubeletConfig holds the settings of a KubeletConfiguration file that
+// determine the allocatable resources of the node.
+type kubeletConfig struct {
+	SystemReserved map[string]string `yaml:"systemReserved"`
+	KubeReserved   map[string]string `yaml:"kubeReserved"`
+	EvictionHard   map[string]string `yaml:"evictionHard"`
+}
+
+// getAllocatable returns the resources of the machine reserved with the
+// flags or in the kubelet configuration file, and the resources left
+// allocatable. It returns nil if nothing is reserved.
+func getAllocatable(rootFs string, numCores int, memoryCapacity uint64) (*info.NodeAllocatable, error) {
+	var config kubeletConfig
+	if *kubeletConfigFile != "" {
+		data, err := os.ReadFile(filepath.Join(rootFs, *kubeletConfigFile))
+		if err != nil {
+			return nil, err
+		}
+		if err := yaml.Unmarshal(data, &config); err != nil {
+			return nil, fmt.Errorf("failed to parse kubelet configuration %q: %v", *kubeletConfigFile, err)
+		}
+	}
+	var err error
+	if config.SystemReserved, err = mergeSettings(config.SystemReserved, *systemReserved, "="); err != nil {
+		return nil, fmt.Errorf("invalid system_reserved flag: %v", err)
+	}
+	if config.KubeReserved, err = mergeSettings(config.KubeReserved, *kubeReserved, "="); err != nil {
+		return nil, fmt.Errorf("invalid kube_reserved flag: %v", err)
+	}
+	if config.EvictionHard, err = mergeSettings(config.EvictionHard, *evictionHard, "<"); err != nil {
+		return nil, fmt.Errorf("invalid eviction_hard flag: %v", err)
+	}
+	return nodeAllocatable(config, numCores, memoryCapacity)
+}
+
+// mergeSettings adds to settings the comma-separated list of key and value
+// pairs of value, separated by sep, overriding the settings with the same
+// keys.
+func mergeSettings(settings map[string]string, value, sep string) (map[string]string, error) {
+	if value == "" {
+		return settings, nil
+	}
+	merged := make(map[string]string, len(settings))
+	for k, v := range settings {
+		merged[k] = v
+	}
+	for _, pair := range strings.Split(value, ",") {
+		k, v, ok := strings.Cut(pair, sep)
+		if !ok || strings.TrimSpace(k) == "" {
+			return nil, fmt.Errorf("%q is not in the key%svalue format", pair, sep)
+		}
+		merged[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return merged, nil
+}
+
+// nodeAllocatable computes the allocatable resources of a machine with
+// numCores cores and memoryCapacity bytes of memory as the kubelet does:
+// the capacity minus the system and kube reservations and the hard eviction
+// threshold of the memory.
+func nodeAllocatable(config kubeletConfig, numCores int, memoryCapacity uint64) (*info.NodeAllocatable, error) {
+	systemReserved, err := resourceAmounts(config.SystemReserved)
+	if err != nil {
+		return nil, fmt.Errorf("invalid systemReserved: %v", err)
+	}
+	kubeReserved, err := resourceAmounts(config.KubeReserved)
+	if err != nil {
+		return nil, fmt.Errorf("invalid kubeReserved: %v", err)
+	}
+	var eviction info.ResourceAmounts
+	if threshold, ok := config.EvictionHard[memoryAvailableSignal]; ok {
+		if strings.HasSuffix(threshold, evictionThresholdSuffix) {
+			percentage, err := strconv.ParseFloat(strings.TrimSuffix(threshold, evictionThresholdSuffix), 64)
+			if err != nil || percentage < 0 || percentage > 100 {
+				return nil, fmt.Errorf("invalid evictionHard %s threshold %q", memoryAvailableSignal, threshold)
+			}
+			eviction.Memory = uint64(math.Ceil(float64(memoryCapacity) * percentage / 100))
+		} else if eviction.Memory, err = parseQuantity(threshold, 1); err != nil {
+			return nil, fmt.Errorf("invalid evictionHard %s threshold: %v", memoryAvailableSignal, err)
+		}
+	}
+	if systemReserved == (info.ResourceAmounts{}) && kubeReserved == (info.ResourceAmounts{}) && eviction == (info.ResourceAmounts{}) {
+		return nil, nil
+	}
+	return &info.NodeAllocatable{
+		SystemReserved:    systemReserved,
+		KubeReserved:      kubeReserved,
+		EvictionThreshold: eviction,
+		Allocatable: info.ResourceAmounts{
+			MilliCpu: subtract(uint64(numCores)*1000, systemReserved.MilliCpu, kubeReserved.MilliCpu),
+			Memory:   subtract(memoryCapacity, systemReserved.Memory, kubeReserved.Memory, eviction.Memory),
+		},
+	}, nil
+}
+
+// resourceAmounts returns the CPU and memory of a list of resources, the
+// other resources, e.g. ephemeral-storage or pid, are ignored.
+func resourceAmounts(resources map[string]string) (info.ResourceAmounts, error) {
+	var amounts info.ResourceAmounts
+	var err error
+	if cpu, ok := resources[cpuResource]; ok {
+		if amounts.MilliCpu, err = parseQuantity(cpu, 1000); err != nil {
+			return amounts, fmt.Errorf("invalid %s: %v", cpuResource, err)
+		}
+	}
+	if memory, ok := resources[memoryResource]; ok {
+		if amounts.Memory, err = parseQuantity(memory, 1); err != nil {
+			return amounts, fmt.Errorf("invalid %s: %v", memoryResource, err)
+		}
+	}
+	return amounts, nil
+}
+
+// parseQuantity parses a Kubernetes resource quantity, e.g. 500m or 1Gi, and
+// returns it multiplied by scale and rounded up.
+func parseQuantity(quantity string, scale float64) (uint64, error) {
+	number, multiplier := quantity, 1.0
+	if len(quantity) > 2 {
+		if m, ok := binaryQuantitySuffixes[quantity[len(quantity)-2:]]; ok {
+			number, multiplier = quantity[:len(quantity)-2], m
+		}
+	}
+	if multiplier == 1 && len(quantity) > 1 {
+		if m, ok := decimalQuantitySuffixes[quantity[len(quantity)-1:]]; ok {
+			number, multiplier = quantity[:len(quantity)-1], m
+		}
+	}
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil || value < 0 || math.IsInf(value, 0) {
+		return 0, fmt.Errorf("%q is not a valid quantity", quantity)
+	}
+	// The amount is rounded to a millionth first for the rounding errors of
+	// the decimal fractions, e.g. 0.1, not to be rounded up.
+	amount := math.Round(value*(multiplier*scale)*1e6) / 1e6
+	return uint64(math.Ceil(amount)), nil
+}
+
+// subtract returns value minus the amounts, or zero if they exceed it.
+func subtract(value uint64, amounts ...uint64) uint64 {
+	for _, amount := range amounts {
+		if amount >= value {
+			return 0
+		}
+		value -= amount
+	}
+	return value
+}
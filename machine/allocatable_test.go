@@ -0,0 +1,133 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package machine
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+)
+
+func TestParseQuantity(t *testing.T) {
+	for _, tc := range []struct {
+		quantity string
+		scale    float64
+		expected uint64
+	}{
+		{"500m", 1000, 500},
+		{"0.1", 1000, 100},
+		{"2", 1000, 2000},
+		{"1Gi", 1, 1 << 30},
+		{"100Mi", 1, 100 << 20},
+		{"1G", 1, 1000000000},
+		{"1.5k", 1, 1500},
+		{"1e3", 1, 1000},
+		{"1024", 1, 1024},
+		{"1m", 1, 1},
+	} {
+		value, err := parseQuantity(tc.quantity, tc.scale)
+		assert.NoError(t, err, tc.quantity)
+		assert.Equal(t, tc.expected, value, tc.quantity)
+	}
+	for _, quantity := range []string{"", "m", "-1", "1Xi", "one"} {
+		_, err := parseQuantity(quantity, 1)
+		assert.Error(t, err, quantity)
+	}
+}
+
+func TestNodeAllocatable(t *testing.T) {
+	const gi = 1 << 30
+	allocatable, err := nodeAllocatable(kubeletConfig{
+		SystemReserved: map[string]string{"cpu": "500m", "memory": "1Gi", "ephemeral-storage": "1Gi"},
+		KubeReserved:   map[string]string{"cpu": "250m", "memory": "512Mi"},
+		EvictionHard:   map[string]string{"memory.available": "100Mi", "nodefs.available": "10%"},
+	}, 4, 16*gi)
+	require.NoError(t, err)
+	assert.Equal(t, &info.NodeAllocatable{
+		SystemReserved:    info.ResourceAmounts{MilliCpu: 500, Memory: gi},
+		KubeReserved:      info.ResourceAmounts{MilliCpu: 250, Memory: gi / 2},
+		EvictionThreshold: info.ResourceAmounts{Memory: 100 << 20},
+		Allocatable:       info.ResourceAmounts{MilliCpu: 3250, Memory: 16*gi - gi - gi/2 - 100<<20},
+	}, allocatable)
+
+	// The eviction threshold can be a percentage of the memory.
+	allocatable, err = nodeAllocatable(kubeletConfig{
+		EvictionHard: map[string]string{"memory.available": "10%"},
+	}, 2, 10*gi)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(gi), allocatable.EvictionThreshold.Memory)
+	assert.Equal(t, info.ResourceAmounts{MilliCpu: 2000, Memory: 9 * gi}, allocatable.Allocatable)
+
+	// Reservations exceeding the capacity leave nothing allocatable.
+	allocatable, err = nodeAllocatable(kubeletConfig{
+		SystemReserved: map[string]string{"cpu": "3", "memory": "2Gi"},
+	}, 2, gi)
+	require.NoError(t, err)
+	assert.Equal(t, info.ResourceAmounts{}, allocatable.Allocatable)
+
+	allocatable, err = nodeAllocatable(kubeletConfig{EvictionHard: map[string]string{"nodefs.available": "10%"}}, 2, gi)
+	assert.NoError(t, err)
+	assert.Nil(t, allocatable)
+
+	_, err = nodeAllocatable(kubeletConfig{KubeReserved: map[string]string{"memory": "lots"}}, 2, gi)
+	assert.Error(t, err)
+	_, err = nodeAllocatable(kubeletConfig{EvictionHard: map[string]string{"memory.available": "110%"}}, 2, gi)
+	assert.Error(t, err)
+}
+
+func TestGetAllocatable(t *testing.T) {
+	defer func(system, kube, eviction, config string) {
+		*systemReserved, *kubeReserved, *evictionHard, *kubeletConfigFile = system, kube, eviction, config
+	}(*systemReserved, *kubeReserved, *evictionHard, *kubeletConfigFile)
+
+	rootFs := t.TempDir()
+	config := `apiVersion: kubelet.config.k8s.io/v1beta1
+kind: KubeletConfiguration
+systemReserved:
+  cpu: 200m
+  memory: 256Mi
+kubeReserved:
+  cpu: 100m
+evictionHard:
+  memory.available: 200Mi
+`
+	require.NoError(t, os.WriteFile(filepath.Join(rootFs, "config.yaml"), []byte(config), 0644))
+	*kubeletConfigFile = "/config.yaml"
+	*systemReserved = "memory=512Mi"
+	*kubeReserved = ""
+	*evictionHard = "memory.available<100Mi,nodefs.available<10%"
+
+	allocatable, err := getAllocatable(rootFs, 2, 4<<30)
+	require.NoError(t, err)
+	// The flags take precedence over the settings of the configuration.
+	assert.Equal(t, info.ResourceAmounts{MilliCpu: 200, Memory: 512 << 20}, allocatable.SystemReserved)
+	assert.Equal(t, info.ResourceAmounts{MilliCpu: 100}, allocatable.KubeReserved)
+	assert.Equal(t, info.ResourceAmounts{Memory: 100 << 20}, allocatable.EvictionThreshold)
+	assert.Equal(t, info.ResourceAmounts{MilliCpu: 1700, Memory: 4<<30 - 612<<20}, allocatable.Allocatable)
+
+	*evictionHard = "memory.available"
+	_, err = getAllocatable(rootFs, 2, 4<<30)
+	assert.Error(t, err)
+
+	*evictionHard = ""
+	*kubeletConfigFile = "/missing.yaml"
+	_, err = getAllocatable(rootFs, 2, 4<<30)
+	assert.Error(t, err)
+}
@@ -20,6 +20,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"golang.org/x/sys/unix"
@@ -40,6 +41,13 @@ const memoryControllerPath = "/sys/devices/system/edac/mc/"
 var machineIDFilePath = flag.String("machine_id_file", "/etc/machine-id,/var/lib/dbus/machine-id", "Comma-separated list of files to check for machine-id. Use the first one that exists.")
 var bootIDFilePath = flag.String("boot_id_file", "/proc/sys/kernel/random/boot_id", "Comma-separated list of files to check for boot-id. Use the first one that exists.")
 
+// The cloud instance does not change while cAdvisor runs, so its metadata is
+// gathered once, at startup, and not at every update of the machine info.
+var (
+	cloudInfoOnce sync.Once
+	cloudInfo     cloudinfo.CloudInfo
+)
+
 func getInfoFromFiles(filePaths string) string {
 	if len(filePaths) == 0 {
 		return ""
@@ -104,6 +112,12 @@ func Info(sysFs sysfs.SysFs, fsInfo fs.FsInfo, inHostNamespace bool) (*info.Mach
 		klog.Errorf("Failed to get disk map: %v", err)
 	}
 
+	blockDevices, err := sysinfo.GetBlockDevices(sysFs)
+	if err != nil {
+		klog.Errorf("Failed to get block devices: %v", err)
+	}
+	addBlockDeviceMountpoints(blockDevices, filesystems, fsInfo.GetMountpointForDevice)
+
 	netDevices, err := sysinfo.GetNetworkDevices(sysFs)
 	if err != nil {
 		klog.Errorf("Failed to get network devices: %v", err)
@@ -119,10 +133,21 @@ func Info(sysFs sysfs.SysFs, fsInfo fs.FsInfo, inHostNamespace bool) (*info.Mach
 		klog.Errorf("Failed to get system UUID: %v", err)
 	}
 
-	realCloudInfo := cloudinfo.NewRealCloudInfo()
-	cloudProvider := realCloudInfo.GetCloudProvider()
-	instanceType := realCloudInfo.GetInstanceType()
-	instanceID := realCloudInfo.GetInstanceID()
+	var bootTime time.Time
+	if procStat, err := os.ReadFile(filepath.Join(rootFs, "/proc/stat")); err != nil {
+		klog.Warningf("Failed to read the boot time: %v", err)
+	} else if bootTime, err = GetBootTime(procStat); err != nil {
+		klog.Warningf("Failed to read the boot time: %v", err)
+	}
+
+	allocatable, err := getAllocatable(rootFs, numCores, memoryCapacity)
+	if err != nil {
+		klog.Errorf("Failed to get the allocatable resources: %v", err)
+	}
+
+	cloudInfoOnce.Do(func() {
+		cloudInfo = cloudinfo.NewRealCloudInfo()
+	})
 
 	machineInfo := &info.MachineInfo{
 		Timestamp:        time.Now(),
@@ -137,14 +162,18 @@ func Info(sysFs sysfs.SysFs, fsInfo fs.FsInfo, inHostNamespace bool) (*info.Mach
 		NVMInfo:          nvmInfo,
 		HugePages:        hugePagesInfo,
 		DiskMap:          diskMap,
+		BlockDevices:     blockDevices,
 		NetworkDevices:   netDevices,
 		Topology:         topology,
 		MachineID:        getInfoFromFiles(filepath.Join(rootFs, *machineIDFilePath)),
 		SystemUUID:       systemUUID,
 		BootID:           getInfoFromFiles(filepath.Join(rootFs, *bootIDFilePath)),
-		CloudProvider:    cloudProvider,
-		InstanceType:     instanceType,
-		InstanceID:       instanceID,
+		BootTime:         bootTime,
+		CloudProvider:    cloudInfo.GetCloudProvider(),
+		InstanceType:     cloudInfo.GetInstanceType(),
+		InstanceID:       cloudInfo.GetInstanceID(),
+		Zone:             cloudInfo.GetZone(),
+		Allocatable:      allocatable,
 	}
 
 	for i := range filesystems {
@@ -23,9 +23,12 @@ import (
 
 	// s390/s390x changes
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/yidoyoon/cadvisor-lite/fs"
 	info "github.com/yidoyoon/cadvisor-lite/info/v1"
 	"github.com/yidoyoon/cadvisor-lite/utils"
 	"github.com/yidoyoon/cadvisor-lite/utils/sysfs"
@@ -44,6 +47,10 @@ var (
 	memoryCapacityRegexp = regexp.MustCompile(`MemTotal:\s*([0-9]+) kB`)
 	swapCapacityRegexp   = regexp.MustCompile(`SwapTotal:\s*([0-9]+) kB`)
 	vendorIDRegexp       = regexp.MustCompile(`vendor_id\s*:\s*(\w+)`)
+	bootTimeRegexp       = regexp.MustCompile(`(?m)^btime\s+([0-9]+)$`)
+	// Suffix of the name of a partition following the name of its disk,
+	// e.g. 1 for sda1 or p1 for nvme0n1p1.
+	partitionSuffixRegexp = regexp.MustCompile(`^p?[0-9]+$`)
 
 	cpuAttributesPath  = "/sys/devices/system/cpu/"
 	isMemoryController = regexp.MustCompile("mc[0-9]+")
@@ -132,6 +139,43 @@ func GetClockSpeed(procInfo []byte) (uint64, error) {
 	return uint64(speed * 1000), nil
 }
 
+// GetBootTime returns the time at which the machine booted, given a []byte
+// formatted as the /proc/stat file.
+func GetBootTime(procStat []byte) (time.Time, error) {
+	matches := bootTimeRegexp.FindSubmatch(procStat)
+	if len(matches) != 2 {
+		return time.Time{}, fmt.Errorf("failed to find the boot time in %q", string(procStat))
+	}
+	btime, err := strconv.ParseInt(string(matches[1]), 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(btime, 0), nil
+}
+
+// addBlockDeviceMountpoints adds to the block devices the mount points of the
+// filesystems on them or on their partitions, as returned by mountpoint for
+// the device of the filesystem.
+func addBlockDeviceMountpoints(devices map[string]info.BlockDevice, filesystems []fs.Fs, mountpoint func(device string) (string, error)) {
+	for key, device := range devices {
+		for _, filesystem := range filesystems {
+			name := filesystem.Device
+			onDevice := uint64(filesystem.Major) == device.Major && uint64(filesystem.Minor) == device.Minor
+			if !onDevice && !(strings.HasPrefix(name, device.Device) && partitionSuffixRegexp.MatchString(name[len(device.Device):])) {
+				continue
+			}
+			mnt, err := mountpoint(name)
+			if err != nil {
+				klog.V(4).Infof("Failed to get the mount point of %q: %v", name, err)
+				continue
+			}
+			device.Mountpoints = append(device.Mountpoints, mnt)
+		}
+		sort.Strings(device.Mountpoints)
+		devices[key] = device
+	}
+}
+
 // GetMachineMemoryCapacity returns the machine's total memory from /proc/meminfo.
 // Returns the total memory capacity as an uint64 (number of bytes).
 func GetMachineMemoryCapacity() (uint64, error) {
@@ -0,0 +1,77 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package machine
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yidoyoon/cadvisor-lite/fs"
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+)
+
+func TestGetBootTime(t *testing.T) {
+	procStat := []byte(`cpu  4705 356 584 3699 23 23 0 0 0 0
+cpu0 1393 280 395 938 5 10 0 0 0 0
+intr 114930548 113199788 3 0 5 263 0 4 [... lots more numbers ...]
+ctxt 1990473
+btime 1682942400
+processes 2915
+procs_running 1
+procs_blocked 0
+`)
+	bootTime, err := GetBootTime(procStat)
+	assert.NoError(t, err)
+	assert.True(t, bootTime.Equal(time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)), bootTime)
+
+	_, err = GetBootTime([]byte("cpu  4705 356 584 3699 23 23 0 0 0 0\n"))
+	assert.Error(t, err)
+}
+
+func TestAddBlockDeviceMountpoints(t *testing.T) {
+	devices := map[string]info.BlockDevice{
+		"8:0":   {Major: 8, Minor: 0, Device: "/dev/sda"},
+		"259:0": {Major: 259, Minor: 0, Device: "/dev/nvme0n1"},
+		"253:0": {Major: 253, Minor: 0, Device: "/dev/mapper/vg-root"},
+		"7:0":   {Major: 7, Minor: 0, Device: "/dev/loop0"},
+	}
+	filesystems := []fs.Fs{
+		{DeviceInfo: fs.DeviceInfo{Device: "/dev/sda2", Major: 8, Minor: 2}},
+		{DeviceInfo: fs.DeviceInfo{Device: "/dev/sda1", Major: 8, Minor: 1}},
+		{DeviceInfo: fs.DeviceInfo{Device: "/dev/nvme0n1p1", Major: 259, Minor: 1}},
+		{DeviceInfo: fs.DeviceInfo{Device: "/dev/mapper/vg-root", Major: 253, Minor: 0}},
+		{DeviceInfo: fs.DeviceInfo{Device: "/dev/sdaa1", Major: 65, Minor: 161}},
+	}
+	mountpoints := map[string]string{
+		"/dev/sda1":           "/boot",
+		"/dev/sda2":           "/boot/efi",
+		"/dev/nvme0n1p1":      "/var/lib/docker",
+		"/dev/mapper/vg-root": "/",
+	}
+	addBlockDeviceMountpoints(devices, filesystems, func(device string) (string, error) {
+		mnt, ok := mountpoints[device]
+		if !ok {
+			return "", fmt.Errorf("no mount point for %q", device)
+		}
+		return mnt, nil
+	})
+	assert.Equal(t, []string{"/boot", "/boot/efi"}, devices["8:0"].Mountpoints)
+	assert.Equal(t, []string{"/var/lib/docker"}, devices["259:0"].Mountpoints)
+	assert.Equal(t, []string{"/"}, devices["253:0"].Mountpoints)
+	assert.Empty(t, devices["7:0"].Mountpoints)
+}
@@ -0,0 +1,148 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"flag"
+	"path"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+	inotify "k8s.io/utils/inotify"
+)
+
+var cgroupEventsHousekeepingInterval = flag.Duration("cgroup_events_housekeeping_interval", 0, "Minimum interval between the extra housekeepings of a container triggered by changes of the memory.events and cgroup.events files of its cgroup, e.g. when it reaches its memory limit, is OOM killed or its last process exits. Only on cgroup v2. Zero value disables them.")
+
+// cgroupEventFiles are the files of a cgroup v2 whose changes trigger an
+// extra housekeeping of the container: memory.events changes when the
+// container reaches its memory limits and on OOM kills, cgroup.events when
+// its populated state changes.
+var cgroupEventFiles = []string{"memory.events", "cgroup.events"}
+
+// cgroupEventTarget is a container notified of the changes of its cgroup
+// files.
+type cgroupEventTarget struct {
+	trigger chan<- struct{}
+	// Time of the last notification and whether one is scheduled because the
+	// last one was less than the interval ago.
+	last    time.Time
+	pending bool
+}
+
+// cgroupEventWatcher notifies containers of the changes of the event files of
+// their cgroup, at most once per interval. A single inotify instance is
+// shared by all the containers.
+type cgroupEventWatcher struct {
+	watcher  *inotify.Watcher
+	interval time.Duration
+	clock    clock.WithDelayedExecution
+
+	lock sync.Mutex
+	// Targets by cgroup directory.
+	targets map[string]*cgroupEventTarget
+}
+
+func newCgroupEventWatcher(interval time.Duration, clock clock.WithDelayedExecution) (*cgroupEventWatcher, error) {
+	w, err := inotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	return &cgroupEventWatcher{
+		watcher:  w,
+		interval: interval,
+		clock:    clock,
+		targets:  make(map[string]*cgroupEventTarget),
+	}, nil
+}
+
+// Watch notifies trigger, without blocking, of the changes of the event files
+// of the cgroup v2 directory dir.
+func (w *cgroupEventWatcher) Watch(dir string, trigger chan<- struct{}) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	for _, file := range cgroupEventFiles {
+		err := w.watcher.AddWatch(path.Join(dir, file), inotify.InModify)
+		if err != nil {
+			// memory.events is missing if the memory controller is not
+			// enabled for the cgroup.
+			klog.V(4).Infof("Not watching %q: %v", path.Join(dir, file), err)
+		}
+	}
+	w.targets[dir] = &cgroupEventTarget{trigger: trigger}
+}
+
+// Unwatch stops notifying the changes of the event files of dir.
+func (w *cgroupEventWatcher) Unwatch(dir string) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	for _, file := range cgroupEventFiles {
+		// The watches of removed cgroups are already gone.
+		_ = w.watcher.RemoveWatch(path.Join(dir, file))
+	}
+	delete(w.targets, dir)
+}
+
+// Start notifies the containers until quit is signaled.
+func (w *cgroupEventWatcher) Start(quit chan error) {
+	go func() {
+		for {
+			select {
+			case event := <-w.watcher.Event:
+				w.notify(path.Dir(event.Name))
+			case err := <-w.watcher.Error:
+				klog.Warningf("Error while watching cgroup events: %v", err)
+			case <-quit:
+				w.lock.Lock()
+				err := w.watcher.Close()
+				w.lock.Unlock()
+				quit <- err
+				return
+			}
+		}
+	}()
+}
+
+func (w *cgroupEventWatcher) notify(dir string) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	target, ok := w.targets[dir]
+	if !ok || target.pending {
+		return
+	}
+	if wait := w.interval - w.clock.Since(target.last); wait > 0 {
+		// Notify once the interval is over, so that the state after the last
+		// change is collected.
+		target.pending = true
+		end := target.last.Add(w.interval)
+		w.clock.AfterFunc(wait, func() {
+			w.lock.Lock()
+			defer w.lock.Unlock()
+			target.pending = false
+			target.send(end)
+		})
+		return
+	}
+	target.send(w.clock.Now())
+}
+
+func (t *cgroupEventTarget) send(now time.Time) {
+	t.last = now
+	select {
+	case t.trigger <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/utils/clock"
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+func writeCgroupEvents(t *testing.T, dir, content string) {
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "memory.events"), []byte(content), 0o644))
+}
+
+func startCgroupEventWatcher(t *testing.T, interval time.Duration) *cgroupEventWatcher {
+	w, err := newCgroupEventWatcher(interval, clock.RealClock{})
+	require.NoError(t, err)
+	quit := make(chan error)
+	w.Start(quit)
+	t.Cleanup(func() {
+		quit <- nil
+		assert.NoError(t, <-quit)
+	})
+	return w
+}
+
+func TestCgroupEventWatcher(t *testing.T) {
+	dir := t.TempDir()
+	writeCgroupEvents(t, dir, "oom_kill 0\n")
+	w := startCgroupEventWatcher(t, time.Millisecond)
+
+	trigger := make(chan struct{}, 1)
+	w.Watch(dir, trigger)
+
+	writeCgroupEvents(t, dir, "oom_kill 1\n")
+	select {
+	case <-trigger:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no trigger after memory.events changed")
+	}
+
+	w.Unwatch(dir)
+	writeCgroupEvents(t, dir, "oom_kill 2\n")
+	select {
+	case <-trigger:
+		t.Fatal("trigger after unwatching")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestCgroupEventWatcherInterval(t *testing.T) {
+	dir := t.TempDir()
+	writeCgroupEvents(t, dir, "max 0\n")
+	w := startCgroupEventWatcher(t, 200*time.Millisecond)
+
+	trigger := make(chan struct{}, 1)
+	w.Watch(dir, trigger)
+
+	writeCgroupEvents(t, dir, "max 1\n")
+	select {
+	case <-trigger:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no trigger after memory.events changed")
+	}
+	start := time.Now()
+
+	// The changes within the interval are notified once, at its end.
+	writeCgroupEvents(t, dir, "max 2\n")
+	writeCgroupEvents(t, dir, "max 3\n")
+	select {
+	case <-trigger:
+		assert.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no trigger at the end of the interval")
+	}
+	select {
+	case <-trigger:
+		t.Fatal("more than one trigger per interval")
+	case <-time.After(300 * time.Millisecond):
+	}
+}
+
+func TestCgroupEventWatcherFakeClock(t *testing.T) {
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	w, err := newCgroupEventWatcher(time.Minute, fakeClock)
+	require.NoError(t, err)
+	defer w.watcher.Close()
+	dir := t.TempDir()
+	trigger := make(chan struct{}, 1)
+	w.Watch(dir, trigger)
+
+	w.notify(dir)
+	require.Len(t, trigger, 1)
+	<-trigger
+
+	// The changes within the interval are notified once, at its end.
+	fakeClock.Step(10 * time.Second)
+	w.notify(dir)
+	w.notify(dir)
+	assert.Len(t, trigger, 0)
+	fakeClock.Step(49 * time.Second)
+	assert.Len(t, trigger, 0)
+	fakeClock.Step(time.Second)
+	assert.Len(t, trigger, 1)
+	<-trigger
+	assert.False(t, fakeClock.HasWaiters())
+
+	fakeClock.Step(time.Minute)
+	w.notify(dir)
+	assert.Len(t, trigger, 1)
+}
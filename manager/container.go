@@ -15,6 +15,8 @@
 package manager
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"flag"
 	"fmt"
 	"math"
@@ -45,9 +47,27 @@ import (
 	"k8s.io/utils/clock"
 )
 
+// Clocks of the timestamps of the samples.
+const (
+	wallClock      = "wall"
+	monotonicClock = "monotonic"
+)
+
+// Time at which cAdvisor started, from which the monotonic time of the
+// samples is measured.
+var monotonicEpoch = time.Now()
+
 // Housekeeping interval.
 var enableLoadReader = flag.Bool("enable_load_reader", false, "Whether to enable cpu load reader")
 var HousekeepingInterval = flag.Duration("housekeeping_interval", 1*time.Second, "Interval between container housekeepings")
+var networkDropsEventThreshold = flag.Float64("network_drops_event_threshold", 10, "Rate of dropped packets per second of an interface of a container beyond which it is reported as a network drops event. Zero value disables the events.")
+var cpusetCheckInterval = flag.Duration("cpuset_check_interval", time.Minute, "Interval between the checks of the effective cpuset and of the CPU and memory limits of a container during its housekeeping, a change of which is reported as a cpuset or limits change event. Zero value disables the checks, changes are then only detected when the spec is queried.")
+var diskQuotaEventThreshold = flag.Float64("disk_quota_event_threshold", 0.9, "Fraction of the quota of its writable layer or of a volume beyond which the disk usage of a container is reported as a disk quota event. Zero value disables the events.")
+var logSizeEventThreshold = flag.Uint64("log_size_event_threshold", 1<<30, "Number of bytes used by the log files of a container, including the rotated ones, beyond which they are reported as a log size event. Zero value disables the events.")
+var pidsLimitEventThreshold = flag.Float64("pids_limit_event_threshold", 0.9, "Fraction of its pids limit beyond which the number of threads of a container is reported as a pids limit event. Requires the process metrics. Zero value disables the events.")
+var timestampClock = flag.String("timestamp_clock", wallClock, "Clock the timestamps of the samples are read from: wall for the wall clock, or monotonic for the wall time at which cAdvisor started plus the monotonic time elapsed since, which never jumps nor goes backwards when the wall clock is set, e.g. by NTP, but drifts from the wall clock.")
+var timestampAlignment = flag.Duration("timestamp_alignment", 0, "Multiple of the wall time to which the housekeepings of the containers are scheduled and the timestamps of their samples rounded, e.g. 1s for every whole second, so that the samples of different containers and nodes share timestamps. The housekeepings are not jittered then. Zero value disables the alignment.")
+var perCpuUsage = flag.String("percpu_usage", v2.PerCpuFull, "Collection of the per-CPU usage of the containers, which dominates the size of the stats on machines with many CPUs: full for the usage of every CPU, socket for the usage summed per socket, or off. Only on cgroup v1.")
 
 // TODO: replace regular expressions with something simpler, such as strings.Split().
 // cgroup type chosen to fetch the cgroup path of a process.
@@ -66,6 +86,7 @@ type containerInfo struct {
 
 type containerData struct {
 	oomEvents                uint64
+	lastOomEvent             atomic.Value // *info.EventReference
 	handler                  container.ContainerHandler
 	info                     containerInfo
 	memoryCache              *memory.InMemoryCache
@@ -102,6 +123,56 @@ type containerData struct {
 
 	// resctrlCollector updates stats for resctrl controller.
 	resctrlCollector stats.Collector
+
+	// statsTransformer modifies collected stats before they are stored.
+	statsTransformer stats.Transformer
+
+	// addEvent adds the events detected from the stats, e.g. pids limit events. Nil if events are not reported.
+	addEvent func(*info.Event) error
+	// Whether the number of threads was beyond the pids limit threshold at the last update.
+	pidsLimitReached bool
+	// Stats of the interfaces at the last update and the interfaces whose
+	// drop rate was beyond the network drops threshold.
+	lastInterfaces     map[string]info.InterfaceStats
+	lastInterfacesTime time.Time
+	networkDropsExceed map[string]bool
+	// Destinations of the volumes, or "" for the writable layer, whose usage
+	// was beyond the disk quota threshold at the last update.
+	diskQuotaExceed map[string]bool
+	// Whether the usage of the log files was beyond the log size threshold
+	// at the last update.
+	logSizeExceed bool
+	// Sources of metrics no longer read for the container, whose event was
+	// added.
+	metricsDisabled map[string]bool
+	// Kinds of the metrics of the sources no longer read, sorted. Replaced,
+	// not modified, when a kind is added since the spec shares it.
+	disabledMetricKinds []string
+	// Sequence number and timestamp of the last stats stored.
+	sequence      uint64
+	lastTimestamp time.Time
+	// Time of the last check of the effective cpuset during housekeeping.
+	cpusetLastCheckedTime time.Time
+	// Time at which the container was discovered, set for the containers
+	// started since cAdvisor started until their first stats, whose latency
+	// is then reported.
+	discoveryTime time.Time
+	// Time between the discovery of the container and its first stats, zero
+	// if not measured.
+	firstStatsLatency time.Duration
+	// Devices which the container did I/O on, sorted by major:minor number,
+	// only their numbers being set. Replaced, not modified, when a device is
+	// added since the spec shares it.
+	diskIoDevices []info.BlockDevice
+	// Socket of every CPU, by CPU id, over which the per-CPU usage is summed
+	// when it is collected per socket.
+	cpuSockets []int
+
+	// Notified of the changes of the event files of the cgroup of the
+	// container, which trigger an extra housekeeping. Nil if the changes are
+	// not watched.
+	cgroupEvents    chan struct{}
+	cgroupEventsDir string
 }
 
 // jitter returns a time.Duration between duration and duration + maxFactor * duration,
@@ -204,6 +275,13 @@ func (cd *containerData) DerivedStats() (v2.DerivedStats, error) {
 	return cd.summaryReader.DerivedStats()
 }
 
+func (cd *containerData) WindowUsage(windows []time.Duration, percentiles []float64) ([]v2.WindowUsage, error) {
+	if cd.summaryReader == nil {
+		return nil, fmt.Errorf("derived stats not enabled for container %q", cd.info.Name)
+	}
+	return cd.summaryReader.WindowUsage(windows, percentiles)
+}
+
 func (cd *containerData) getCgroupPath(cgroups string) string {
 	if cgroups == "-" {
 		return "/"
@@ -447,6 +525,7 @@ func newContainerData(containerName string, memoryCache *memory.InMemoryCache, h
 		clock:                    clock,
 		perfCollector:            &stats.NoopCollector{},
 		resctrlCollector:         &stats.NoopCollector{},
+		statsTransformer:         stats.RegisteredTransformers(),
 	}
 	cont.info.ContainerReference = ref
 
@@ -466,7 +545,8 @@ func newContainerData(containerName string, memoryCache *memory.InMemoryCache, h
 	if err != nil {
 		return nil, err
 	}
-	cont.summaryReader, err = summary.New(cont.info.Spec)
+	cont.cpusetLastCheckedTime = cont.clock.Now()
+	cont.summaryReader, err = summary.New(cont.info.Spec, *summaryDuration)
 	if err != nil {
 		cont.summaryReader = nil
 		klog.V(5).Infof("Failed to create summary reader for %q: %v", ref.Name, err)
@@ -499,9 +579,22 @@ func (cd *containerData) nextHousekeepingInterval() time.Duration {
 		}
 	}
 
+	if *timestampAlignment > 0 {
+		return alignedInterval(cd.clock.Now(), cd.housekeepingInterval, *timestampAlignment)
+	}
 	return jitter(cd.housekeepingInterval, 1.0)
 }
 
+// alignedInterval returns the time from now to the last multiple of alignment
+// before the end of interval, or to the next one if there is none after now.
+func alignedInterval(now time.Time, interval, alignment time.Duration) time.Duration {
+	next := now.Add(interval).Truncate(alignment)
+	if !next.After(now) {
+		next = next.Add(alignment)
+	}
+	return next.Sub(now)
+}
+
 // TODO(vmarmol): Implement stats collecting as a custom collector.
 func (cd *containerData) housekeeping() {
 	// Start any background goroutines - must be cleaned up in cd.handler.Cleanup().
@@ -577,6 +670,8 @@ func (cd *containerData) housekeepingTick(timer <-chan time.Time, longHousekeepi
 	case finishedChan := <-cd.onDemandChan:
 		// notify the calling function once housekeeping has completed
 		defer close(finishedChan)
+	case <-cd.cgroupEvents:
+		klog.V(4).Infof("[%s] Housekeeping triggered by a cgroup event", cd.info.Name)
 	case <-timer:
 	}
 	start := cd.clock.Now()
@@ -591,6 +686,13 @@ func (cd *containerData) housekeepingTick(timer <-chan time.Time, longHousekeepi
 	if duration >= longHousekeeping {
 		klog.V(3).Infof("[%s] Housekeeping took %s", cd.info.Name, duration)
 	}
+	if *cpusetCheckInterval > 0 && cd.clock.Since(cd.cpusetLastCheckedTime) >= *cpusetCheckInterval {
+		// Refreshing the spec reports the changes of the cpuset and limits.
+		if err := cd.updateSpec(); err != nil {
+			klog.V(4).Infof("Failed to update spec for container %q: %v", cd.info.Name, err)
+		}
+		cd.cpusetLastCheckedTime = cd.clock.Now()
+	}
 	cd.notifyOnDemand()
 	cd.lock.Lock()
 	defer cd.lock.Unlock()
@@ -616,12 +718,164 @@ func (cd *containerData) updateSpec() error {
 		spec.HasCustomMetrics = true
 		spec.CustomMetrics = customMetrics
 	}
+	spec.Uid = containerUID(cd.info.Name, &spec)
 	cd.lock.Lock()
-	defer cd.lock.Unlock()
+	prev := cd.info.Spec
+	spec.FirstStatsLatency = cd.firstStatsLatency
+	spec.DiskIoDevices = cd.diskIoDevices
+	spec.DisabledMetrics = cd.disabledMetricKinds
 	cd.info.Spec = spec
+	cd.lock.Unlock()
+	cd.checkCpusetChange(prev.Cpu, spec.Cpu)
+	cd.checkLimitsChange(&prev, &spec)
 	return nil
 }
 
+// checkCpusetChange adds a cpuset change event when the effective CPUs or
+// memory nodes of the container differ from the previous spec.
+func (cd *containerData) checkCpusetChange(prev, cur info.CpuSpec) {
+	// Nothing to compare with before the first spec, nor when the cpuset is
+	// not readable.
+	if cd.addEvent == nil || prev.Mask == "" || cur.Mask == "" {
+		return
+	}
+	if prev.Mask == cur.Mask && prev.Mems == cur.Mems {
+		return
+	}
+	klog.V(1).Infof("Cpuset of container %q changed from cpus %q mems %q to cpus %q mems %q", cd.info.Name, prev.Mask, prev.Mems, cur.Mask, cur.Mems)
+	err := cd.addEvent(&info.Event{
+		ContainerName: cd.info.Name,
+		Timestamp:     cd.clock.Now(),
+		EventType:     info.EventCpusetChange,
+		EventData: info.EventData{
+			CpusetChange: &info.CpusetChangeEventData{
+				PreviousCpus: prev.Mask,
+				Cpus:         cur.Mask,
+				PreviousMems: prev.Mems,
+				Mems:         cur.Mems,
+			},
+		},
+	})
+	if err != nil {
+		klog.Errorf("Failed to add cpuset change event for %q: %v", cd.info.Name, err)
+	}
+}
+
+// checkLimitsChange adds a limits change event when the CPU or memory limits
+// of the container differ from the previous spec, e.g. after a live resize.
+func (cd *containerData) checkLimitsChange(prev, cur *info.ContainerSpec) {
+	// Only the resources tracked by both specs are compared, so that there is
+	// nothing to compare with before the first spec.
+	cpu := prev.HasCpu && cur.HasCpu
+	memory := prev.HasMemory && cur.HasMemory
+	if cd.addEvent == nil || (!cpu && !memory) {
+		return
+	}
+	prevLimits := containerLimits(prev, cpu, memory)
+	curLimits := containerLimits(cur, cpu, memory)
+	if prevLimits == curLimits {
+		return
+	}
+	klog.V(1).Infof("Limits of container %q changed from %+v to %+v", cd.info.Name, prevLimits, curLimits)
+	err := cd.addEvent(&info.Event{
+		ContainerName: cd.info.Name,
+		Timestamp:     cd.clock.Now(),
+		EventType:     info.EventLimitsChange,
+		EventData: info.EventData{
+			LimitsChange: &info.LimitsChangeEventData{
+				Previous: prevLimits,
+				Current:  curLimits,
+			},
+		},
+	})
+	if err != nil {
+		klog.Errorf("Failed to add limits change event for %q: %v", cd.info.Name, err)
+	}
+}
+
+// containerLimits returns the CPU and memory limits of spec, those of the
+// resources not requested left zero.
+func containerLimits(spec *info.ContainerSpec, cpu, memory bool) info.ContainerLimits {
+	var limits info.ContainerLimits
+	if cpu {
+		limits.CpuShares = spec.Cpu.Limit
+		limits.CpuQuota = spec.Cpu.Quota
+		limits.CpuPeriod = spec.Cpu.Period
+	}
+	if memory {
+		limits.MemoryLimit = spec.Memory.Limit
+		limits.MemoryReservation = spec.Memory.Reservation
+		limits.MemorySwapLimit = spec.Memory.SwapLimit
+	}
+	return limits
+}
+
+// recordDiskIoDevices adds the devices of the disk I/O stats to the devices of
+// the spec.
+func (cd *containerData) recordDiskIoDevices(stats *info.ContainerStats) {
+	var added []info.BlockDevice
+	for _, diskStats := range [][]info.PerDiskStats{stats.DiskIo.IoServiceBytes, stats.DiskIo.IoServiced} {
+		for _, disk := range diskStats {
+			if !hasBlockDevice(cd.diskIoDevices, disk.Major, disk.Minor) && !hasBlockDevice(added, disk.Major, disk.Minor) {
+				added = append(added, info.BlockDevice{Major: disk.Major, Minor: disk.Minor})
+			}
+		}
+	}
+	if len(added) == 0 {
+		return
+	}
+	devices := append(append([]info.BlockDevice{}, cd.diskIoDevices...), added...)
+	sort.Slice(devices, func(i, j int) bool {
+		if devices[i].Major != devices[j].Major {
+			return devices[i].Major < devices[j].Major
+		}
+		return devices[i].Minor < devices[j].Minor
+	})
+	cd.lock.Lock()
+	cd.diskIoDevices = devices
+	cd.info.Spec.DiskIoDevices = devices
+	cd.lock.Unlock()
+}
+
+func hasBlockDevice(devices []info.BlockDevice, major, minor uint64) bool {
+	for _, device := range devices {
+		if device.Major == major && device.Minor == minor {
+			return true
+		}
+	}
+	return false
+}
+
+// checkStartLatency records the time since the discovery of the container on
+// its first stats, and adds a start latency event.
+func (cd *containerData) checkStartLatency(name string) {
+	if cd.discoveryTime.IsZero() {
+		return
+	}
+	latency := cd.clock.Since(cd.discoveryTime)
+	cd.discoveryTime = time.Time{}
+	cd.lock.Lock()
+	cd.firstStatsLatency = latency
+	cd.info.Spec.FirstStatsLatency = latency
+	spec := cd.info.Spec
+	cd.lock.Unlock()
+	if cd.addEvent == nil {
+		return
+	}
+	data := &info.StartLatencyEventData{DiscoveryToFirstStats: latency}
+	data.CreateToRunning = spec.StartLatency()
+	klog.V(3).Infof("Container %q started in %v, first stats after %v", name, data.CreateToRunning, latency)
+	err := cd.addEvent(&info.Event{
+		ContainerName: name,
+		Timestamp:     cd.clock.Now(),
+		EventType:     info.EventStartLatency,
+		EventData:     info.EventData{StartLatency: data},
+	})
+	if err != nil {
+		klog.Errorf("Failed to add start latency event for %q: %v", name, err)
+	}
+}
+
 // Calculate new smoothed load average using the new sample of runnable threads.
 // The decay used ensures that the load will stabilize on a new constant value within
 // 10 seconds.
@@ -635,6 +889,7 @@ func (cd *containerData) updateLoad(newLoad uint64) {
 
 func (cd *containerData) updateStats() error {
 	stats, statsErr := cd.handler.GetStats()
+	cd.checkMetricsDisabled(cd.info.Name)
 	if statsErr != nil {
 		// Ignore errors if the container is dead.
 		if !cd.handler.Exists() {
@@ -647,6 +902,10 @@ func (cd *containerData) updateStats() error {
 	if stats == nil {
 		return statsErr
 	}
+	if !cd.setTimestamp(stats) {
+		klog.V(4).Infof("Dropping the stats of %q at %v, aligned to the timestamp of the previous stats", cd.info.Name, stats.Timestamp)
+		return statsErr
+	}
 	if cd.loadReader != nil {
 		// TODO(vmarmol): Cache this path.
 		path, err := cd.handler.GetCgroupPath("cpu")
@@ -661,15 +920,10 @@ func (cd *containerData) updateStats() error {
 			stats.Cpu.LoadAverage = int32(cd.loadAvg * 1000)
 		}
 	}
-	if cd.summaryReader != nil {
-		err := cd.summaryReader.AddSample(*stats)
-		if err != nil {
-			// Ignore summary errors for now.
-			klog.V(2).Infof("Failed to add summary stats for %q: %v", cd.info.Name, err)
-		}
-	}
-
 	stats.OOMEvents = atomic.LoadUint64(&cd.oomEvents)
+	if lastOomEvent, ok := cd.lastOomEvent.Load().(*info.EventReference); ok {
+		stats.LastOOMEvent = lastOomEvent
+	}
 
 	var customStatsErr error
 	cm := cd.collectorManager.(*collector.GenericCollectorManager)
@@ -702,22 +956,306 @@ func (cd *containerData) updateStats() error {
 		ContainerReference: ref,
 	}
 
+	// The summaries are derived from the transformed stats, as stored.
+	transformErr := cd.statsTransformer.Transform(ref, stats)
+	if cd.summaryReader != nil {
+		err := cd.summaryReader.AddSample(*stats)
+		if err != nil {
+			// Ignore summary errors for now.
+			klog.V(2).Infof("Failed to add summary stats for %q: %v", cd.info.Name, err)
+		}
+	}
+	cd.selectPerCpuUsage(stats)
+
+	cd.checkPidsLimit(ref.Name, stats)
+	cd.checkNetworkDrops(ref.Name, stats)
+	cd.checkDiskQuota(ref.Name, stats)
+	cd.checkLogSize(ref.Name, stats)
+	cd.recordDiskIoDevices(stats)
+
+	cd.sequence++
+	stats.Sequence = cd.sequence
+	stats.TimestampSkew = cd.clock.Since(stats.Timestamp)
+
 	err = cd.memoryCache.AddStats(&cInfo, stats)
 	if err != nil {
 		return err
 	}
+	cd.lastTimestamp = stats.Timestamp
+	cd.checkStartLatency(ref.Name)
+	var errs partialFailure
 	if statsErr != nil {
-		return statsErr
+		errs.append(cInfo.Name, "get stats", statsErr)
+	}
+	if transformErr != nil {
+		errs.append(cInfo.Name, "transform stats", transformErr)
 	}
 	if perfStatsErr != nil {
-		klog.Errorf("error occurred while collecting perf stats for container %s: %s", cInfo.Name, err)
-		return perfStatsErr
+		klog.Errorf("error occurred while collecting perf stats for container %s: %s", cInfo.Name, perfStatsErr)
+		errs.append(cInfo.Name, "collect perf stats", perfStatsErr)
 	}
 	if resctrlStatsErr != nil {
 		klog.Errorf("error occurred while collecting resctrl stats for container %s: %s", cInfo.Name, resctrlStatsErr)
-		return resctrlStatsErr
+		errs.append(cInfo.Name, "collect resctrl stats", resctrlStatsErr)
+	}
+	if customStatsErr != nil {
+		errs.append(cInfo.Name, "collect custom stats", customStatsErr)
+	}
+	return errs.OrNil()
+}
+
+// setTimestamp sets the monotonic time of the stats and their timestamp from
+// the clock and with the alignment set by the flags. It returns false if the
+// stats are aligned to the timestamp of the previous stats, e.g. collected by
+// an on-demand housekeeping right after a regular one, in which case they
+// must be dropped to keep the timestamps increasing.
+func (cd *containerData) setTimestamp(stats *info.ContainerStats) bool {
+	stats.MonotonicTime = stats.Timestamp.Sub(monotonicEpoch)
+	if *timestampClock == monotonicClock {
+		// Round(0) strips the monotonic reading, so that the wall time of
+		// the epoch is used.
+		stats.Timestamp = monotonicEpoch.Round(0).Add(stats.MonotonicTime)
+	}
+	if *timestampAlignment > 0 {
+		stats.Timestamp = stats.Timestamp.Round(*timestampAlignment)
+		if !stats.Timestamp.After(cd.lastTimestamp) {
+			return false
+		}
+	}
+	return true
+}
+
+// selectPerCpuUsage sums the per-CPU usage of the stats per socket or drops
+// it, as set by the percpu_usage flag.
+func (cd *containerData) selectPerCpuUsage(stats *info.ContainerStats) {
+	switch *perCpuUsage {
+	case v2.PerCpuSocket:
+		if len(stats.Cpu.Usage.PerCpu) > 0 {
+			stats.Cpu.Usage.PerSocket = v2.SumPerSocket(stats.Cpu.Usage.PerCpu, cd.cpuSockets)
+		}
+		stats.Cpu.Usage.PerCpu = nil
+	case v2.PerCpuOff:
+		stats.Cpu.Usage.PerCpu = nil
+	}
+}
+
+// checkPidsLimit adds a pids limit event when the number of threads of the
+// container goes beyond the threshold of its limit. The event is not repeated
+// until the number of threads goes below the threshold again.
+func (cd *containerData) checkPidsLimit(name string, stats *info.ContainerStats) {
+	if cd.addEvent == nil || *pidsLimitEventThreshold <= 0 || stats.Processes.ThreadsMax == 0 {
+		return
+	}
+	if float64(stats.Processes.ThreadsCurrent) < *pidsLimitEventThreshold*float64(stats.Processes.ThreadsMax) {
+		cd.pidsLimitReached = false
+		return
+	}
+	if cd.pidsLimitReached {
+		return
+	}
+	cd.pidsLimitReached = true
+	klog.V(1).Infof("Container %q has %d threads out of its limit of %d", name, stats.Processes.ThreadsCurrent, stats.Processes.ThreadsMax)
+	err := cd.addEvent(&info.Event{
+		ContainerName: name,
+		Timestamp:     stats.Timestamp,
+		EventType:     info.EventPidsLimit,
+		EventData: info.EventData{
+			PidsLimit: &info.PidsLimitEventData{
+				ThreadsCurrent: stats.Processes.ThreadsCurrent,
+				ThreadsMax:     stats.Processes.ThreadsMax,
+			},
+		},
+	})
+	if err != nil {
+		klog.Errorf("Failed to add pids limit event for %q: %v", name, err)
+	}
+}
+
+// checkNetworkDrops adds a network drops event for each interface of the
+// container whose rate of dropped packets since the last update goes beyond the
+// threshold. The event is not repeated until the rate goes below the threshold
+// again.
+func (cd *containerData) checkNetworkDrops(name string, stats *info.ContainerStats) {
+	if cd.addEvent == nil || *networkDropsEventThreshold <= 0 {
+		return
+	}
+	lastInterfaces, elapsed := cd.lastInterfaces, stats.Timestamp.Sub(cd.lastInterfacesTime).Seconds()
+	cd.lastInterfaces = make(map[string]info.InterfaceStats, len(stats.Network.Interfaces))
+	cd.lastInterfacesTime = stats.Timestamp
+	for _, iface := range stats.Network.Interfaces {
+		cd.lastInterfaces[iface.Name] = iface
+	}
+	if lastInterfaces == nil || elapsed <= 0 {
+		return
+	}
+	exceed := make(map[string]bool, len(stats.Network.Interfaces))
+	for _, iface := range stats.Network.Interfaces {
+		prev, ok := lastInterfaces[iface.Name]
+		// The counters are reset when the interface is recreated.
+		if !ok || iface.RxDropped < prev.RxDropped || iface.TxDropped < prev.TxDropped ||
+			iface.RxErrors < prev.RxErrors || iface.TxErrors < prev.TxErrors {
+			continue
+		}
+		data := &info.NetworkDropsEventData{
+			Interface:   iface.Name,
+			RxDropRate:  float64(iface.RxDropped-prev.RxDropped) / elapsed,
+			TxDropRate:  float64(iface.TxDropped-prev.TxDropped) / elapsed,
+			RxErrorRate: float64(iface.RxErrors-prev.RxErrors) / elapsed,
+			TxErrorRate: float64(iface.TxErrors-prev.TxErrors) / elapsed,
+		}
+		if data.RxDropRate+data.TxDropRate < *networkDropsEventThreshold {
+			continue
+		}
+		exceed[iface.Name] = true
+		if cd.networkDropsExceed[iface.Name] {
+			continue
+		}
+		klog.V(1).Infof("Interface %q of container %q drops %.1f received and %.1f transmitted packets per second", iface.Name, name, data.RxDropRate, data.TxDropRate)
+		err := cd.addEvent(&info.Event{
+			ContainerName: name,
+			Timestamp:     stats.Timestamp,
+			EventType:     info.EventNetworkDrops,
+			EventData: info.EventData{
+				NetworkDrops: data,
+			},
+		})
+		if err != nil {
+			klog.Errorf("Failed to add network drops event for %q: %v", name, err)
+		}
+	}
+	cd.networkDropsExceed = exceed
+}
+
+// checkDiskQuota adds a disk quota event for the writable layer and each volume
+// of the container whose usage goes beyond the threshold of its quota. The
+// event is not repeated until the usage goes below the threshold again.
+func (cd *containerData) checkDiskQuota(name string, stats *info.ContainerStats) {
+	if cd.addEvent == nil || *diskQuotaEventThreshold <= 0 {
+		return
+	}
+	exceed := make(map[string]bool)
+	check := func(destination, device string, usage, quota uint64) {
+		if quota == 0 || float64(usage) < *diskQuotaEventThreshold*float64(quota) {
+			return
+		}
+		exceed[destination] = true
+		if cd.diskQuotaExceed[destination] {
+			return
+		}
+		klog.V(1).Infof("Container %q uses %d bytes out of its disk quota of %d on %q", name, usage, quota, device)
+		err := cd.addEvent(&info.Event{
+			ContainerName: name,
+			Timestamp:     stats.Timestamp,
+			EventType:     info.EventDiskQuota,
+			EventData: info.EventData{
+				DiskQuota: &info.DiskQuotaEventData{
+					Device:      device,
+					Destination: destination,
+					Usage:       usage,
+					Quota:       quota,
+				},
+			},
+		})
+		if err != nil {
+			klog.Errorf("Failed to add disk quota event for %q: %v", name, err)
+		}
+	}
+	// The quota of the writable layer only applies to its base usage, not to
+	// the logs of the container.
+	for _, fs := range stats.Filesystem {
+		check("", fs.Device, fs.BaseUsage, fs.Quota)
+	}
+	for _, fs := range stats.Volumes {
+		if fs.Volume != nil {
+			check(fs.Volume.Destination, fs.Device, fs.Usage, fs.Quota)
+		}
+	}
+	cd.diskQuotaExceed = exceed
+}
+
+// checkLogSize adds a log size event when the usage of the log files of the
+// container goes beyond the threshold. The event is not repeated until the
+// usage goes below the threshold again, e.g. after a rotation.
+func (cd *containerData) checkLogSize(name string, stats *info.ContainerStats) {
+	if cd.addEvent == nil || *logSizeEventThreshold == 0 || len(stats.Filesystem) == 0 {
+		return
+	}
+	fs := stats.Filesystem[0]
+	exceed := fs.LogUsage >= *logSizeEventThreshold
+	if exceed && !cd.logSizeExceed {
+		klog.V(1).Infof("Log files of container %q use %d bytes on %q, growing by %.0f bytes per second", name, fs.LogUsage, fs.Device, fs.LogGrowthRate)
+		err := cd.addEvent(&info.Event{
+			ContainerName: name,
+			Timestamp:     stats.Timestamp,
+			EventType:     info.EventLogSize,
+			EventData: info.EventData{
+				LogSize: &info.LogSizeEventData{
+					Device:     fs.Device,
+					Usage:      fs.LogUsage,
+					GrowthRate: fs.LogGrowthRate,
+					Threshold:  *logSizeEventThreshold,
+				},
+			},
+		})
+		if err != nil {
+			klog.Errorf("Failed to add log size event for %q: %v", name, err)
+		}
+	}
+	cd.logSizeExceed = exceed
+}
+
+// checkMetricsDisabled records the kinds of the metrics of the sources the
+// handler stopped reading since the last update in the spec, so that they
+// are no longer exported, and adds a metrics disabled event for each source.
+func (cd *containerData) checkMetricsDisabled(name string) {
+	disabler, ok := cd.handler.(container.MetricsDisabler)
+	if !ok {
+		return
+	}
+	for _, disabled := range disabler.DisabledMetrics() {
+		if cd.metricsDisabled[disabled.Source] {
+			continue
+		}
+		if cd.metricsDisabled == nil {
+			cd.metricsDisabled = make(map[string]bool)
+		}
+		cd.metricsDisabled[disabled.Source] = true
+		cd.addDisabledMetricKind(string(disabled.Kind))
+		if cd.addEvent == nil {
+			continue
+		}
+		err := cd.addEvent(&info.Event{
+			ContainerName: name,
+			Timestamp:     cd.clock.Now(),
+			EventType:     info.EventMetricsDisabled,
+			EventData: info.EventData{
+				MetricsDisabled: &info.MetricsDisabledEventData{
+					Metric: string(disabled.Kind),
+					Source: disabled.Source,
+					Reason: disabled.Reason,
+				},
+			},
+		})
+		if err != nil {
+			klog.Errorf("Failed to add metrics disabled event for %q: %v", name, err)
+		}
 	}
-	return customStatsErr
+}
+
+// addDisabledMetricKind adds kind to the kinds of the disabled metrics of the
+// spec.
+func (cd *containerData) addDisabledMetricKind(kind string) {
+	for _, k := range cd.disabledMetricKinds {
+		if k == kind {
+			return
+		}
+	}
+	kinds := append(append([]string{}, cd.disabledMetricKinds...), kind)
+	sort.Strings(kinds)
+	cd.lock.Lock()
+	cd.disabledMetricKinds = kinds
+	cd.info.Spec.DisabledMetrics = kinds
+	cd.lock.Unlock()
 }
 
 func (cd *containerData) updateCustomStats() (map[string][]info.MetricVal, error) {
@@ -747,3 +1285,15 @@ func (cd *containerData) updateSubcontainers() error {
 	cd.info.Subcontainers = subcontainers
 	return nil
 }
+
+// containerUID returns the identifier of a container set by cAdvisor: a hash
+// of its runtime id, or of its name for raw containers, and of its creation
+// time, which tells apart the containers successively taking the same name.
+func containerUID(name string, spec *info.ContainerSpec) string {
+	id := spec.RuntimeId
+	if id == "" {
+		id = name
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%s\x00%d", spec.Runtime, id, spec.CreationTime.UnixNano())))
+	return hex.EncodeToString(sum[:16])
+}
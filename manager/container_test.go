@@ -150,6 +150,361 @@ func TestUpdateStats(t *testing.T) {
 	mockHandler.AssertExpectations(t)
 }
 
+func TestUpdateStatsPidsLimitEvent(t *testing.T) {
+	cd, mockHandler, _, _ := newTestContainerData(t)
+	var events []*info.Event
+	cd.addEvent = func(e *info.Event) error {
+		events = append(events, e)
+		return nil
+	}
+
+	for _, threads := range []uint64{50, 95, 99, 80, 90} {
+		stats := itest.GenerateRandomStats(1, 4, 1*time.Second)[0]
+		stats.Processes.ThreadsCurrent = threads
+		stats.Processes.ThreadsMax = 100
+		mockHandler.On("GetStats").Return(stats, nil).Once()
+		require.NoError(t, cd.updateStats())
+	}
+
+	// Reported once when crossing the threshold, then again after going below it.
+	require.Len(t, events, 2)
+	for _, e := range events {
+		assert.Equal(t, info.EventPidsLimit, e.EventType)
+		assert.Equal(t, containerName, e.ContainerName)
+	}
+	assert.Equal(t, &info.PidsLimitEventData{ThreadsCurrent: 95, ThreadsMax: 100}, events[0].EventData.PidsLimit)
+	assert.Equal(t, &info.PidsLimitEventData{ThreadsCurrent: 90, ThreadsMax: 100}, events[1].EventData.PidsLimit)
+}
+
+// metricsDisablerHandler is a mock handler which stopped reading sources of
+// metrics.
+type metricsDisablerHandler struct {
+	*containertest.MockContainerHandler
+	disabled []container.DisabledMetrics
+}
+
+func (h *metricsDisablerHandler) DisabledMetrics() []container.DisabledMetrics {
+	return h.disabled
+}
+
+func TestUpdateStatsMetricsDisabledEvent(t *testing.T) {
+	cd, mockHandler, _, fakeClock := newTestContainerData(t)
+	handler := &metricsDisablerHandler{MockContainerHandler: mockHandler}
+	cd.handler = handler
+	var events []*info.Event
+	cd.addEvent = func(e *info.Event) error {
+		events = append(events, e)
+		return nil
+	}
+
+	blkio := container.DisabledMetrics{Kind: container.DiskIOMetrics, Source: "blkio", Reason: "read failed 10 times in a row"}
+	tcp := container.DisabledMetrics{Kind: container.NetworkTcpUsageMetrics, Source: "net/tcp", Reason: "read took longer than 1s 10 times in a row"}
+	for _, disabled := range [][]container.DisabledMetrics{nil, {blkio}, {blkio}, {blkio, tcp}} {
+		handler.disabled = disabled
+		mockHandler.On("GetStats").Return(itest.GenerateRandomStats(1, 4, 1*time.Second)[0], nil).Once()
+		require.NoError(t, cd.updateStats())
+	}
+
+	// Reported once for each source.
+	require.Len(t, events, 2)
+	for _, e := range events {
+		assert.Equal(t, info.EventMetricsDisabled, e.EventType)
+		assert.Equal(t, containerName, e.ContainerName)
+		assert.Equal(t, fakeClock.Now(), e.Timestamp)
+	}
+	assert.Equal(t, &info.MetricsDisabledEventData{Metric: "diskIO", Source: "blkio", Reason: "read failed 10 times in a row"}, events[0].EventData.MetricsDisabled)
+	assert.Equal(t, "net/tcp", events[1].EventData.MetricsDisabled.Source)
+	// The kinds of the disabled metrics are reported by the spec.
+	assert.Equal(t, []string{"diskIO", "tcp"}, cd.info.Spec.DisabledMetrics)
+}
+
+func TestUpdateStatsStartLatencyEvent(t *testing.T) {
+	spec := itest.GenerateRandomContainerSpec(4)
+	spec.CreationTime = time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	spec.StartedAt = spec.CreationTime.Add(300 * time.Millisecond)
+	cd, mockHandler, _, fakeClock := setupContainerData(t, spec)
+	var events []*info.Event
+	cd.addEvent = func(e *info.Event) error {
+		events = append(events, e)
+		return nil
+	}
+	cd.discoveryTime = fakeClock.Now()
+	fakeClock.Step(50 * time.Millisecond)
+
+	for i := 0; i < 2; i++ {
+		mockHandler.On("GetStats").Return(itest.GenerateRandomStats(1, 4, 1*time.Second)[0], nil).Once()
+		require.NoError(t, cd.updateStats())
+	}
+
+	// Reported once, with the first stats.
+	require.Len(t, events, 1)
+	assert.Equal(t, info.EventStartLatency, events[0].EventType)
+	assert.Equal(t, containerName, events[0].ContainerName)
+	assert.Equal(t, &info.StartLatencyEventData{CreateToRunning: 300 * time.Millisecond, DiscoveryToFirstStats: 50 * time.Millisecond}, events[0].EventData.StartLatency)
+	// Kept in the spec when it is refreshed.
+	require.NoError(t, cd.updateSpec())
+	assert.Equal(t, 50*time.Millisecond, cd.info.Spec.FirstStatsLatency)
+}
+
+func TestUpdateStatsNoStartLatencyEvent(t *testing.T) {
+	cd, mockHandler, _, _ := newTestContainerData(t)
+	var events []*info.Event
+	cd.addEvent = func(e *info.Event) error {
+		events = append(events, e)
+		return nil
+	}
+
+	// Not measured for the containers discovered on startup.
+	mockHandler.On("GetStats").Return(itest.GenerateRandomStats(1, 4, 1*time.Second)[0], nil).Once()
+	require.NoError(t, cd.updateStats())
+	assert.Empty(t, events)
+	assert.Zero(t, cd.info.Spec.FirstStatsLatency)
+}
+
+func TestUpdateStatsNetworkDropsEvent(t *testing.T) {
+	cd, mockHandler, _, _ := newTestContainerData(t)
+	var events []*info.Event
+	cd.addEvent = func(e *info.Event) error {
+		events = append(events, e)
+		return nil
+	}
+
+	start := time.Now()
+	// Cumulative drops of eth0, collected every 10 seconds.
+	for i, dropped := range []uint64{0, 50, 300, 600, 610, 1000} {
+		stats := itest.GenerateRandomStats(1, 4, 1*time.Second)[0]
+		stats.Timestamp = start.Add(time.Duration(i) * 10 * time.Second)
+		stats.Network.Interfaces = []info.InterfaceStats{
+			{Name: "eth0", RxDropped: dropped, TxErrors: uint64(i)},
+			{Name: "eth1"},
+		}
+		mockHandler.On("GetStats").Return(stats, nil).Once()
+		require.NoError(t, cd.updateStats())
+	}
+
+	// Reported once when crossing the threshold, then again after going below it.
+	require.Len(t, events, 2)
+	for _, e := range events {
+		assert.Equal(t, info.EventNetworkDrops, e.EventType)
+		assert.Equal(t, containerName, e.ContainerName)
+	}
+	assert.Equal(t, &info.NetworkDropsEventData{Interface: "eth0", RxDropRate: 25, TxErrorRate: 0.1}, events[0].EventData.NetworkDrops)
+	assert.Equal(t, &info.NetworkDropsEventData{Interface: "eth0", RxDropRate: 39, TxErrorRate: 0.1}, events[1].EventData.NetworkDrops)
+}
+
+func TestUpdateStatsDiskQuotaEvent(t *testing.T) {
+	cd, mockHandler, _, _ := newTestContainerData(t)
+	var events []*info.Event
+	cd.addEvent = func(e *info.Event) error {
+		events = append(events, e)
+		return nil
+	}
+
+	volume := &info.FsVolume{Name: "data", Type: "volume", Source: "/var/lib/docker/volumes/data/_data", Destination: "/data"}
+	for _, usage := range []uint64{500, 950, 990, 800, 900} {
+		stats := itest.GenerateRandomStats(1, 4, 1*time.Second)[0]
+		// The writable layer is under the threshold, only its logs are
+		// beyond it, and the volume crosses it.
+		stats.Filesystem = []info.FsStats{{Device: "/dev/sda1", BaseUsage: 100, Usage: 2000, Quota: 1000}}
+		stats.Volumes = []info.FsStats{
+			{Device: "/dev/sdb1", Usage: usage, Quota: 1000, Volume: volume},
+			{Device: "/dev/sdb1", Usage: usage, Volume: &info.FsVolume{Type: "bind", Destination: "/config"}},
+		}
+		mockHandler.On("GetStats").Return(stats, nil).Once()
+		require.NoError(t, cd.updateStats())
+	}
+
+	// Reported once when crossing the threshold, then again after going below it.
+	require.Len(t, events, 2)
+	for _, e := range events {
+		assert.Equal(t, info.EventDiskQuota, e.EventType)
+		assert.Equal(t, containerName, e.ContainerName)
+	}
+	assert.Equal(t, &info.DiskQuotaEventData{Device: "/dev/sdb1", Destination: "/data", Usage: 950, Quota: 1000}, events[0].EventData.DiskQuota)
+	assert.Equal(t, &info.DiskQuotaEventData{Device: "/dev/sdb1", Destination: "/data", Usage: 900, Quota: 1000}, events[1].EventData.DiskQuota)
+}
+
+func TestUpdateStatsLogSizeEvent(t *testing.T) {
+	defer func(v uint64) { *logSizeEventThreshold = v }(*logSizeEventThreshold)
+	*logSizeEventThreshold = 1000
+	cd, mockHandler, _, _ := newTestContainerData(t)
+	var events []*info.Event
+	cd.addEvent = func(e *info.Event) error {
+		events = append(events, e)
+		return nil
+	}
+
+	for _, usage := range []uint64{500, 1000, 1500, 200, 1200} {
+		stats := itest.GenerateRandomStats(1, 4, 1*time.Second)[0]
+		stats.Filesystem = []info.FsStats{{Device: "/dev/sda1", Usage: 5000, LogUsage: usage, LogGrowthRate: 100}}
+		mockHandler.On("GetStats").Return(stats, nil).Once()
+		require.NoError(t, cd.updateStats())
+	}
+
+	// Reported once when crossing the threshold, then again after a rotation.
+	require.Len(t, events, 2)
+	for _, e := range events {
+		assert.Equal(t, info.EventLogSize, e.EventType)
+		assert.Equal(t, containerName, e.ContainerName)
+	}
+	assert.Equal(t, &info.LogSizeEventData{Device: "/dev/sda1", Usage: 1000, GrowthRate: 100, Threshold: 1000}, events[0].EventData.LogSize)
+	assert.Equal(t, &info.LogSizeEventData{Device: "/dev/sda1", Usage: 1200, GrowthRate: 100, Threshold: 1000}, events[1].EventData.LogSize)
+}
+
+func TestUpdateStatsDiskIoDevices(t *testing.T) {
+	cd, mockHandler, _, _ := newTestContainerData(t)
+	for _, disks := range [][]info.PerDiskStats{
+		{{Major: 253, Minor: 0}, {Major: 8, Minor: 0}},
+		{{Major: 8, Minor: 0}, {Major: 7, Minor: 1}},
+	} {
+		stats := itest.GenerateRandomStats(1, 4, 1*time.Second)[0]
+		stats.DiskIo = info.DiskIoStats{IoServiceBytes: disks}
+		mockHandler.On("GetStats").Return(stats, nil).Once()
+		require.NoError(t, cd.updateStats())
+	}
+	expected := []info.BlockDevice{{Major: 7, Minor: 1}, {Major: 8, Minor: 0}, {Major: 253, Minor: 0}}
+	assert.Equal(t, expected, cd.info.Spec.DiskIoDevices)
+
+	// The devices are kept when the spec is updated, and named after the
+	// block devices of the machine.
+	mockHandler.On("GetSpec").Return(itest.GenerateRandomContainerSpec(4), nil)
+	require.NoError(t, cd.updateSpec())
+	m := &manager{machineInfo: info.MachineInfo{BlockDevices: map[string]info.BlockDevice{
+		"253:0": {Major: 253, Minor: 0, Device: "/dev/mapper/vg-root", Slaves: []string{"/dev/sda2"}},
+	}}}
+	spec := m.getAdjustedSpec(&cd.info)
+	assert.Equal(t, []info.BlockDevice{
+		{Major: 7, Minor: 1},
+		{Major: 8, Minor: 0},
+		{Major: 253, Minor: 0, Device: "/dev/mapper/vg-root", Slaves: []string{"/dev/sda2"}},
+	}, spec.DiskIoDevices)
+	assert.Equal(t, expected, cd.info.Spec.DiskIoDevices)
+}
+
+func TestAlignedInterval(t *testing.T) {
+	now := time.Date(2023, 5, 1, 10, 0, 0, 5e6, time.UTC)
+	for _, test := range []struct {
+		interval, alignment, expected time.Duration
+	}{
+		{time.Second, time.Second, 995 * time.Millisecond},
+		{time.Second, 10 * time.Second, 9995 * time.Millisecond},
+		{15 * time.Second, 10 * time.Second, 9995 * time.Millisecond},
+		{time.Minute, time.Second, 59995 * time.Millisecond},
+	} {
+		assert.Equal(t, test.expected, alignedInterval(now, test.interval, test.alignment), "%v aligned to %v", test.interval, test.alignment)
+	}
+}
+
+func TestUpdateStatsTimestamp(t *testing.T) {
+	defer func(clock string, alignment time.Duration) {
+		*timestampClock, *timestampAlignment = clock, alignment
+	}(*timestampClock, *timestampAlignment)
+	cd, mockHandler, _, _ := newTestContainerData(t)
+
+	*timestampAlignment = time.Second
+	collected := time.Now()
+	stats := itest.GenerateRandomStats(1, 4, 1*time.Second)[0]
+	stats.Timestamp = collected
+	mockHandler.On("GetStats").Return(stats, nil).Once()
+	require.NoError(t, cd.updateStats())
+	assert.Equal(t, collected.Round(time.Second), stats.Timestamp)
+	assert.Equal(t, collected.Sub(monotonicEpoch), stats.MonotonicTime)
+
+	// Stats aligned to the same timestamp are dropped.
+	stats = itest.GenerateRandomStats(1, 4, 1*time.Second)[0]
+	stats.Timestamp = collected.Add(time.Millisecond)
+	mockHandler.On("GetStats").Return(stats, nil).Once()
+	require.NoError(t, cd.updateStats())
+	assert.Zero(t, stats.Sequence)
+
+	// The monotonic clock ignores the changes of the wall clock.
+	*timestampClock, *timestampAlignment = monotonicClock, 0
+	stats = itest.GenerateRandomStats(1, 4, 1*time.Second)[0]
+	stats.Timestamp = monotonicEpoch.Add(10 * time.Second)
+	mockHandler.On("GetStats").Return(stats, nil).Once()
+	require.NoError(t, cd.updateStats())
+	assert.Equal(t, 10*time.Second, stats.MonotonicTime)
+	assert.True(t, monotonicEpoch.Add(10*time.Second).Equal(stats.Timestamp))
+	assert.Equal(t, uint64(2), stats.Sequence)
+}
+
+func TestUpdateStatsPerCpuUsage(t *testing.T) {
+	defer func(mode string) { *perCpuUsage = mode }(*perCpuUsage)
+	cd, mockHandler, _, _ := newTestContainerData(t)
+	cd.cpuSockets = []int{0, 0, 1, 1}
+
+	*perCpuUsage = v2.PerCpuSocket
+	stats := itest.GenerateRandomStats(1, 4, 1*time.Second)[0]
+	stats.Cpu.Usage.PerCpu = []uint64{1, 2, 3, 4}
+	mockHandler.On("GetStats").Return(stats, nil).Once()
+	require.NoError(t, cd.updateStats())
+	assert.Nil(t, stats.Cpu.Usage.PerCpu)
+	assert.Equal(t, []uint64{3, 7}, stats.Cpu.Usage.PerSocket)
+
+	*perCpuUsage = v2.PerCpuOff
+	stats = itest.GenerateRandomStats(1, 4, 1*time.Second)[0]
+	stats.Cpu.Usage.PerCpu = []uint64{1, 2, 3, 4}
+	mockHandler.On("GetStats").Return(stats, nil).Once()
+	require.NoError(t, cd.updateStats())
+	assert.Nil(t, stats.Cpu.Usage.PerCpu)
+	assert.Nil(t, stats.Cpu.Usage.PerSocket)
+}
+
+func TestUpdateStatsSequence(t *testing.T) {
+	cd, mockHandler, _, fakeClock := newTestContainerData(t)
+
+	for i := 1; i <= 3; i++ {
+		stats := itest.GenerateRandomStats(1, 4, 1*time.Second)[0]
+		stats.Timestamp = fakeClock.Now().Add(-2 * time.Second)
+		mockHandler.On("GetStats").Return(stats, nil).Once()
+		require.NoError(t, cd.updateStats())
+		assert.Equal(t, uint64(i), stats.Sequence)
+		assert.Equal(t, 2*time.Second, stats.TimestampSkew)
+		fakeClock.Step(10 * time.Second)
+	}
+}
+
+type testTransformer struct {
+	err error
+}
+
+func (t testTransformer) Transform(ref info.ContainerReference, stats *info.ContainerStats) error {
+	stats.OOMEvents = 42
+	stats.Memory.WorkingSet = 4242
+	return t.err
+}
+
+func TestUpdateStatsWithTransformer(t *testing.T) {
+	for _, transformErr := range []error{nil, fmt.Errorf("some error")} {
+		statsList := itest.GenerateRandomStats(1, 4, 1*time.Second)
+		cd, mockHandler, memoryCache, _ := newTestContainerData(t)
+		cd.statsTransformer = testTransformer{err: transformErr}
+		mockHandler.On("GetStats").Return(
+			statsList[0],
+			nil,
+		)
+
+		err := cd.updateStats()
+		if transformErr == nil {
+			assert.NoError(t, err)
+		} else {
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), transformErr.Error())
+		}
+
+		// Stats are stored even if the transformer failed.
+		stored, err := memoryCache.RecentStats(containerName, time.Time{}, time.Time{}, 1)
+		require.NoError(t, err)
+		require.Len(t, stored, 1)
+		assert.Equal(t, uint64(42), stored[0].OOMEvents)
+
+		// The summaries are derived from the transformed stats.
+		derived, err := cd.DerivedStats()
+		require.NoError(t, err)
+		assert.Equal(t, uint64(4242), derived.LatestUsage.Memory)
+	}
+}
+
 func TestUpdateSpec(t *testing.T) {
 	spec := itest.GenerateRandomContainerSpec(4)
 	cd, mockHandler, _, _ := newTestContainerData(t)
@@ -166,6 +521,99 @@ func TestUpdateSpec(t *testing.T) {
 	mockHandler.AssertExpectations(t)
 }
 
+func TestUpdateSpecUid(t *testing.T) {
+	created := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	spec := info.ContainerSpec{CreationTime: created, Runtime: "docker", RuntimeId: "abc"}
+	cd, _, _, _ := setupContainerData(t, spec)
+	require.NoError(t, cd.updateSpec())
+	uid := cd.info.Spec.Uid
+	assert.Len(t, uid, 32)
+
+	// The same container, e.g. after a restart of cAdvisor.
+	assert.Equal(t, uid, containerUID("/docker/abc", &spec))
+	// Another container taking the same name.
+	spec.CreationTime = created.Add(time.Minute)
+	assert.NotEqual(t, uid, containerUID(containerName, &spec))
+	// Raw containers are told apart by name.
+	raw := info.ContainerSpec{CreationTime: created, Runtime: "raw"}
+	assert.NotEqual(t, containerUID("/a", &raw), containerUID("/b", &raw))
+}
+
+func TestUpdateSpecCpusetChangeEvent(t *testing.T) {
+	mockHandler := containertest.NewMockContainerHandler(containerName)
+	base := itest.GenerateRandomContainerSpec(4)
+	for _, cpus := range []string{"0-3", "0-3", "4-5", "4-5"} {
+		spec := base
+		spec.Cpu.Mask = cpus
+		spec.Cpu.Mems = "0"
+		mockHandler.On("GetSpec").Return(spec, nil).Once()
+	}
+	fakeClock := clock.NewFakeClock(time.Now())
+	cd, err := newContainerData(containerName, memory.New(60, nil), mockHandler, false, &collector.GenericCollectorManager{}, 60*time.Second, true, fakeClock)
+	require.NoError(t, err)
+	var events []*info.Event
+	cd.addEvent = func(e *info.Event) error {
+		events = append(events, e)
+		return nil
+	}
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, cd.updateSpec())
+	}
+
+	// Reported once when the CPUs are reassigned.
+	require.Len(t, events, 1)
+	assert.Equal(t, info.EventCpusetChange, events[0].EventType)
+	assert.Equal(t, containerName, events[0].ContainerName)
+	assert.Equal(t, &info.CpusetChangeEventData{PreviousCpus: "0-3", Cpus: "4-5", PreviousMems: "0", Mems: "0"}, events[0].EventData.CpusetChange)
+	assert.Equal(t, "4-5", cd.info.Spec.Cpu.Mask)
+	mockHandler.AssertExpectations(t)
+}
+
+func TestUpdateSpecLimitsChangeEvent(t *testing.T) {
+	mockHandler := containertest.NewMockContainerHandler(containerName)
+	base := itest.GenerateRandomContainerSpec(4)
+	base.Cpu.Limit = 1024
+	base.Cpu.Quota = 100000
+	base.Cpu.Period = 100000
+	base.Memory.Limit = 1 << 30
+	resized := base
+	resized.Cpu.Quota = 200000
+	resized.Memory.Limit = 2 << 30
+	noMemory := resized
+	noMemory.HasMemory = false
+	noMemory.Memory = info.MemorySpec{}
+	for _, spec := range []info.ContainerSpec{base, base, resized, resized, noMemory} {
+		mockHandler.On("GetSpec").Return(spec, nil).Once()
+	}
+	fakeClock := clock.NewFakeClock(time.Now())
+	cd, err := newContainerData(containerName, memory.New(60, nil), mockHandler, false, &collector.GenericCollectorManager{}, 60*time.Second, true, fakeClock)
+	require.NoError(t, err)
+	var events []*info.Event
+	cd.addEvent = func(e *info.Event) error {
+		events = append(events, e)
+		return nil
+	}
+
+	for i := 0; i < 4; i++ {
+		require.NoError(t, cd.updateSpec())
+	}
+
+	// Reported once when the container is resized, not when the memory is
+	// no longer tracked.
+	require.Len(t, events, 1)
+	assert.Equal(t, info.EventLimitsChange, events[0].EventType)
+	assert.Equal(t, containerName, events[0].ContainerName)
+	assert.Equal(t, &info.LimitsChangeEventData{
+		Previous: info.ContainerLimits{CpuShares: 1024, CpuQuota: 100000, CpuPeriod: 100000, MemoryLimit: 1 << 30},
+		Current:  info.ContainerLimits{CpuShares: 1024, CpuQuota: 200000, CpuPeriod: 100000, MemoryLimit: 2 << 30},
+	}, events[0].EventData.LimitsChange)
+	// The cached spec is refreshed.
+	assert.Equal(t, uint64(200000), cd.info.Spec.Cpu.Quota)
+	assert.False(t, cd.info.Spec.HasMemory)
+	mockHandler.AssertExpectations(t)
+}
+
 func TestGetInfo(t *testing.T) {
 	spec := itest.GenerateRandomContainerSpec(4)
 	subcontainers := []info.ContainerReference{
@@ -203,6 +651,7 @@ func TestGetInfo(t *testing.T) {
 		}
 	}
 
+	spec.Uid = containerUID(containerName, &spec)
 	if !reflect.DeepEqual(spec, info.Spec) {
 		t.Errorf("received wrong container spec")
 	}
@@ -275,6 +724,21 @@ func TestConcurrentOnDemandHousekeeping(t *testing.T) {
 	mockHandler.AssertExpectations(t)
 }
 
+func TestCgroupEventsHousekeeping(t *testing.T) {
+	stats := itest.GenerateRandomStats(1, 4, 1*time.Second)[0]
+
+	cd, mockHandler, memoryCache, fakeClock := newTestContainerData(t)
+	mockHandler.On("GetStats").Return(stats, nil)
+	cd.cgroupEvents = make(chan struct{}, 1)
+
+	// A cgroup event triggers housekeeping before the timer fires.
+	cd.cgroupEvents <- struct{}{}
+	assert.True(t, cd.housekeepingTick(fakeClock.NewTimer(time.Minute).C(), testLongHousekeeping))
+
+	checkNumStats(t, memoryCache, 1)
+	mockHandler.AssertExpectations(t)
+}
+
 func TestOnDemandHousekeepingReturnsAfterStopped(t *testing.T) {
 	statsList := itest.GenerateRandomStats(1, 4, 1*time.Second)
 	stats := statsList[0]
@@ -0,0 +1,764 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fake provides an in-memory manager.Manager whose containers, stats
+// and events are set by the caller, for use in tests of code embedding
+// cAdvisor without cgroups or container runtimes.
+package fake
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yidoyoon/cadvisor-lite/container"
+	"github.com/yidoyoon/cadvisor-lite/events"
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+	v2 "github.com/yidoyoon/cadvisor-lite/info/v2"
+	"github.com/yidoyoon/cadvisor-lite/manager"
+)
+
+var _ manager.Manager = &Manager{}
+
+// Manager is a fake manager.Manager serving programmed containers. It is safe
+// for concurrent use.
+type Manager struct {
+	lock          sync.RWMutex
+	containers    map[string]*info.ContainerInfo
+	processes     map[string][]v2.ProcessInfo
+	derivedStats  map[string]v2.DerivedStats
+	windowUsage   map[string][]v2.WindowUsage
+	namespaces    map[string]v2.NetworkNamespace
+	ports         map[string]v2.ContainerPorts
+	machineInfo   info.MachineInfo
+	versionInfo   info.VersionInfo
+	fsInfo        []v2.FsInfo
+	events        events.EventManager
+	envAllowList  []string
+	decomposition v2.MachineDecomposition
+	// Health set by SetHealth, nil for a healthy manager.
+	health *manager.Health
+
+	watchesLock sync.Mutex
+	watches     map[int]struct{}
+}
+
+// NewManager returns a fake manager with only the root container.
+func NewManager() *Manager {
+	m := &Manager{
+		containers:   make(map[string]*info.ContainerInfo),
+		processes:    make(map[string][]v2.ProcessInfo),
+		derivedStats: make(map[string]v2.DerivedStats),
+		windowUsage:  make(map[string][]v2.WindowUsage),
+		namespaces:   make(map[string]v2.NetworkNamespace),
+		ports:        make(map[string]v2.ContainerPorts),
+		events:       events.NewEventManager(events.DefaultStoragePolicy()),
+		watches:      make(map[int]struct{}),
+	}
+	m.AddContainer(info.ContainerReference{Name: "/"}, info.ContainerSpec{})
+	return m
+}
+
+// AddContainer adds a container, or replaces the reference and spec of an
+// existing one. The containers of its path that do not exist yet are added
+// as well, and a creation event is emitted for every new container.
+func (m *Manager) AddContainer(ref info.ContainerReference, spec info.ContainerSpec) {
+	if ref.Name != "/" {
+		parent := path.Dir(ref.Name)
+		if !m.Exists(parent) {
+			m.AddContainer(info.ContainerReference{Name: parent}, info.ContainerSpec{})
+		}
+	}
+
+	m.lock.Lock()
+	cont, ok := m.containers[ref.Name]
+	if ok {
+		cont.ContainerReference = ref
+		cont.Spec = spec
+		m.lock.Unlock()
+		return
+	}
+	if spec.CreationTime.IsZero() {
+		spec.CreationTime = time.Now()
+	}
+	m.containers[ref.Name] = &info.ContainerInfo{
+		ContainerReference: ref,
+		Spec:               spec,
+	}
+	m.lock.Unlock()
+
+	m.AddEvent(&info.Event{
+		ContainerName: ref.Name,
+		Timestamp:     spec.CreationTime,
+		EventType:     info.EventContainerCreation,
+	})
+}
+
+// RemoveContainer removes a container and its subcontainers, emitting a
+// deletion event for each of them.
+func (m *Manager) RemoveContainer(name string) {
+	m.lock.Lock()
+	var removed []string
+	for _, cont := range m.subcontainers(name) {
+		delete(m.containers, cont.Name)
+		delete(m.processes, cont.Name)
+		delete(m.derivedStats, cont.Name)
+		delete(m.windowUsage, cont.Name)
+		delete(m.namespaces, cont.Name)
+		delete(m.ports, cont.Name)
+		removed = append(removed, cont.Name)
+	}
+	m.lock.Unlock()
+
+	for _, name := range removed {
+		m.AddEvent(&info.Event{
+			ContainerName: name,
+			Timestamp:     time.Now(),
+			EventType:     info.EventContainerDeletion,
+		})
+	}
+}
+
+// AddStats appends stats to a container, which must have been added.
+func (m *Manager) AddStats(name string, stats ...*info.ContainerStats) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	cont, ok := m.containers[name]
+	if !ok {
+		return &manager.ContainerNotFoundError{Name: name, Message: fmt.Sprintf("unknown container %q", name)}
+	}
+	cont.Stats = append(cont.Stats, stats...)
+	sort.SliceStable(cont.Stats, func(i, j int) bool {
+		return cont.Stats[i].Timestamp.Before(cont.Stats[j].Timestamp)
+	})
+	return nil
+}
+
+// SetProcesses sets the processes returned for a container.
+func (m *Manager) SetProcesses(name string, processes []v2.ProcessInfo) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.processes[name] = processes
+}
+
+// SetNetworkNamespace sets the network namespace returned for a container.
+func (m *Manager) SetNetworkNamespace(name string, ns v2.NetworkNamespace) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.namespaces[name] = ns
+}
+
+// SetPorts sets the ports returned for a container.
+func (m *Manager) SetPorts(name string, ports v2.ContainerPorts) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.ports[name] = ports
+}
+
+// SetDerivedStats sets the derived stats returned for a container.
+func (m *Manager) SetDerivedStats(name string, stats v2.DerivedStats) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.derivedStats[name] = stats
+}
+
+// SetWindowUsage sets the usage over windows returned for a container, of
+// which those of the requested windows are returned.
+func (m *Manager) SetWindowUsage(name string, usage []v2.WindowUsage) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.windowUsage[name] = usage
+}
+
+// SetMachineDecomposition sets the decomposition of the usage of the machine.
+func (m *Manager) SetMachineDecomposition(decomposition v2.MachineDecomposition) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.decomposition = decomposition
+}
+
+// SetMachineInfo sets the machine information.
+func (m *Manager) SetMachineInfo(machineInfo info.MachineInfo) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.machineInfo = machineInfo
+}
+
+// SetVersionInfo sets the version information.
+func (m *Manager) SetVersionInfo(versionInfo info.VersionInfo) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.versionInfo = versionInfo
+}
+
+// SetFsInfo sets the filesystems of the machine.
+func (m *Manager) SetFsInfo(fsInfo []v2.FsInfo) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.fsInfo = fsInfo
+}
+
+func (m *Manager) Start() error {
+	return nil
+}
+
+// Stop closes all event watches.
+func (m *Manager) Stop() error {
+	m.watchesLock.Lock()
+	ids := make([]int, 0, len(m.watches))
+	for id := range m.watches {
+		ids = append(ids, id)
+	}
+	m.watchesLock.Unlock()
+	for _, id := range ids {
+		m.CloseEventChannel(id)
+	}
+	return nil
+}
+
+// Returns the stats in [start, end], limited to the numStats most recent ones
+// unless numStats is negative.
+func recentStats(stats []*info.ContainerStats, start, end time.Time, numStats int) []*info.ContainerStats {
+	var result []*info.ContainerStats
+	for _, s := range stats {
+		if !start.IsZero() && s.Timestamp.Before(start) {
+			continue
+		}
+		if !end.IsZero() && s.Timestamp.After(end) {
+			continue
+		}
+		result = append(result, s)
+	}
+	if numStats >= 0 && len(result) > numStats {
+		result = result[len(result)-numStats:]
+	}
+	return result
+}
+
+// Returns a copy of a container with the requested stats. Must be called
+// with the lock held.
+func (m *Manager) containerInfo(cont *info.ContainerInfo, query *info.ContainerInfoRequest) *info.ContainerInfo {
+	if query == nil {
+		defaultQuery := info.DefaultContainerInfoRequest()
+		query = &defaultQuery
+	}
+	result := &info.ContainerInfo{
+		ContainerReference: cont.ContainerReference,
+		Spec:               cont.Spec,
+		Stats:              recentStats(cont.Stats, query.Start, query.End, query.NumStats),
+	}
+	for _, sub := range m.subcontainers(cont.Name) {
+		if sub.Name != cont.Name && path.Dir(sub.Name) == cont.Name {
+			result.Subcontainers = append(result.Subcontainers, info.ContainerReference{Name: sub.Name})
+		}
+	}
+	return result
+}
+
+// Returns name and its subcontainers, sorted by name. Must be called with the
+// lock held.
+func (m *Manager) subcontainers(name string) []*info.ContainerInfo {
+	prefix := path.Join(name, "/")
+	if prefix != "/" {
+		prefix += "/"
+	}
+	var result []*info.ContainerInfo
+	for contName, cont := range m.containers {
+		if contName == name || strings.HasPrefix(contName, prefix) {
+			result = append(result, cont)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// Returns the containers of a namespace, by name. Must be called with the lock
+// held.
+func (m *Manager) namespacedContainers(namespace string) map[string]*info.ContainerInfo {
+	result := make(map[string]*info.ContainerInfo)
+	for name, cont := range m.containers {
+		if cont.Namespace == namespace {
+			result[name] = cont
+		}
+	}
+	return result
+}
+
+// Returns the container of a namespace with the given id, alias or id
+// prefix. Must be called with the lock held.
+func (m *Manager) namespacedContainer(id string, namespace string) (*info.ContainerInfo, error) {
+	var match *info.ContainerInfo
+	for _, cont := range m.namespacedContainers(namespace) {
+		if path.Base(cont.Name) == id {
+			return cont, nil
+		}
+		for _, alias := range cont.Aliases {
+			if alias == id {
+				return cont, nil
+			}
+		}
+		if strings.HasPrefix(path.Base(cont.Name), id) {
+			if match != nil {
+				return nil, fmt.Errorf("unable to find container in %q namespace. Container %q is not unique", namespace, id)
+			}
+			match = cont
+		}
+	}
+	if match == nil {
+		return nil, &manager.ContainerNotFoundError{Name: id, Message: fmt.Sprintf("unable to find container %q in %q namespace", id, namespace)}
+	}
+	return match, nil
+}
+
+func (m *Manager) getRequestedContainers(containerName string, options v2.RequestOptions) (map[string]*info.ContainerInfo, error) {
+	containers := make(map[string]*info.ContainerInfo)
+	switch options.IdType {
+	case v2.TypeName:
+		if !options.Recursive {
+			cont, ok := m.containers[containerName]
+			if !ok {
+				return containers, &manager.ContainerNotFoundError{Name: containerName, Message: fmt.Sprintf("unknown container %q", containerName)}
+			}
+			containers[cont.Name] = cont
+		} else {
+			for _, cont := range m.subcontainers(containerName) {
+				containers[cont.Name] = cont
+			}
+			if len(containers) == 0 {
+				return containers, &manager.ContainerNotFoundError{Name: containerName, Message: fmt.Sprintf("unknown container: %q", containerName)}
+			}
+		}
+	case v2.TypeDocker, v2.TypePodman:
+		namespace := map[string]string{
+			v2.TypeDocker: manager.DockerNamespace,
+			v2.TypePodman: manager.PodmanNamespace,
+		}[options.IdType]
+		if !options.Recursive {
+			cont, err := m.namespacedContainer(strings.TrimPrefix(containerName, "/"), namespace)
+			if err != nil {
+				return containers, err
+			}
+			containers[cont.Name] = cont
+		} else {
+			if containerName != "/" {
+				return containers, fmt.Errorf("invalid request for %s container %q with subcontainers", options.IdType, containerName)
+			}
+			containers = m.namespacedContainers(namespace)
+		}
+	default:
+		return containers, fmt.Errorf("invalid request type %q", options.IdType)
+	}
+	if options.LabelSelector != "" {
+		selector, err := v2.ParseLabelSelector(options.LabelSelector)
+		if err != nil {
+			return containers, err
+		}
+		for name, cont := range containers {
+			if !selector.Matches(cont.Spec.Labels) {
+				delete(containers, name)
+			}
+		}
+	}
+	if options.NameRegex != "" {
+		matcher, err := v2.ParseNameRegex(options.NameRegex)
+		if err != nil {
+			return containers, err
+		}
+		for name, cont := range containers {
+			if !matcher.Matches(cont.Name, cont.Aliases) {
+				delete(containers, name)
+			}
+		}
+	}
+	return containers, nil
+}
+
+func (m *Manager) GetContainerInfo(containerName string, query *info.ContainerInfoRequest) (*info.ContainerInfo, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	cont, ok := m.containers[containerName]
+	if !ok {
+		return nil, &manager.ContainerNotFoundError{Name: containerName, Message: fmt.Sprintf("unknown container %q", containerName)}
+	}
+	return m.containerInfo(cont, query), nil
+}
+
+func (m *Manager) GetContainerInfoV2(containerName string, options v2.RequestOptions) (map[string]v2.ContainerInfo, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	containers, err := m.getRequestedContainers(containerName, options)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]v2.ContainerInfo, len(containers))
+	for name, cont := range containers {
+		stats := recentStats(cont.Stats, time.Time{}, time.Time{}, options.Count)
+		result[name] = v2.ContainerInfo{
+			Spec:  v2.ContainerSpecFromV1(&cont.Spec, cont.Aliases, cont.Namespace),
+			Stats: v2.ContainerStatsFromV1(containerName, &cont.Spec, stats),
+		}
+	}
+	return result, nil
+}
+
+func (m *Manager) SubcontainersInfo(containerName string, query *info.ContainerInfoRequest) ([]*info.ContainerInfo, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	var result []*info.ContainerInfo
+	for _, cont := range m.subcontainers(containerName) {
+		result = append(result, m.containerInfo(cont, query))
+	}
+	return result, nil
+}
+
+func (m *Manager) allNamespacedContainers(namespace string, query *info.ContainerInfoRequest) map[string]info.ContainerInfo {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	result := make(map[string]info.ContainerInfo)
+	for name, cont := range m.namespacedContainers(namespace) {
+		result[name] = *m.containerInfo(cont, query)
+	}
+	return result
+}
+
+func (m *Manager) namespacedContainerInfo(id string, namespace string, query *info.ContainerInfoRequest) (info.ContainerInfo, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	cont, err := m.namespacedContainer(id, namespace)
+	if err != nil {
+		return info.ContainerInfo{}, err
+	}
+	return *m.containerInfo(cont, query), nil
+}
+
+func (m *Manager) AllDockerContainers(query *info.ContainerInfoRequest) (map[string]info.ContainerInfo, error) {
+	return m.allNamespacedContainers(manager.DockerNamespace, query), nil
+}
+
+func (m *Manager) DockerContainer(dockerName string, query *info.ContainerInfoRequest) (info.ContainerInfo, error) {
+	return m.namespacedContainerInfo(dockerName, manager.DockerNamespace, query)
+}
+
+func (m *Manager) AllPodmanContainers(query *info.ContainerInfoRequest) (map[string]info.ContainerInfo, error) {
+	return m.allNamespacedContainers(manager.PodmanNamespace, query), nil
+}
+
+func (m *Manager) PodmanContainer(containerName string, query *info.ContainerInfoRequest) (info.ContainerInfo, error) {
+	return m.namespacedContainerInfo(containerName, manager.PodmanNamespace, query)
+}
+
+func (m *Manager) GetContainerSpec(containerName string, options v2.RequestOptions) (map[string]v2.ContainerSpec, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	containers, err := m.getRequestedContainers(containerName, options)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]v2.ContainerSpec, len(containers))
+	for name, cont := range containers {
+		result[name] = v2.ContainerSpecFromV1(&cont.Spec, cont.Aliases, cont.Namespace)
+	}
+	return result, nil
+}
+
+func (m *Manager) GetDerivedStats(containerName string, options v2.RequestOptions) (map[string]v2.DerivedStats, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	containers, err := m.getRequestedContainers(containerName, options)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]v2.DerivedStats, len(containers))
+	for name := range containers {
+		d := m.derivedStats[name]
+		if name == "/" && m.machineInfo.Allocatable != nil {
+			d.Allocatable = v2.DerivedUtilizationFromStats(d, m.machineInfo.Allocatable.Allocatable)
+		}
+		result[name] = d
+	}
+	return result, nil
+}
+
+func (m *Manager) GetWindowUsage(containerName string, options v2.RequestOptions, windows []time.Duration, percentiles []float64) (map[string][]v2.WindowUsage, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	containers, err := m.getRequestedContainers(containerName, options)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string][]v2.WindowUsage, len(containers))
+	for name := range containers {
+		var usages []v2.WindowUsage
+		for _, window := range windows {
+			for _, usage := range m.windowUsage[name] {
+				if usage.Window == window.String() {
+					usages = append(usages, usage)
+				}
+			}
+		}
+		result[name] = usages
+	}
+	return result, nil
+}
+
+func (m *Manager) GetRequestedContainersInfo(containerName string, options v2.RequestOptions) (map[string]*info.ContainerInfo, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	containers, err := m.getRequestedContainers(containerName, options)
+	if err != nil {
+		return nil, err
+	}
+	query := &info.ContainerInfoRequest{NumStats: options.Count, Start: options.Start, End: options.End}
+	if since := options.Since.Add(time.Nanosecond); !options.Since.IsZero() && since.After(query.Start) {
+		query.Start = since
+	}
+	result := make(map[string]*info.ContainerInfo, len(containers))
+	for name, cont := range containers {
+		result[name] = m.containerInfo(cont, query)
+	}
+	return result, nil
+}
+
+func (m *Manager) Exists(containerName string) bool {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	_, ok := m.containers[containerName]
+	return ok
+}
+
+func (m *Manager) GetMachineInfo() (*info.MachineInfo, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	machineInfo := m.machineInfo
+	return &machineInfo, nil
+}
+
+func (m *Manager) GetVersionInfo() (*info.VersionInfo, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	versionInfo := m.versionInfo
+	return &versionInfo, nil
+}
+
+func (m *Manager) GetFsInfoByFsUUID(uuid string) (v2.FsInfo, error) {
+	return v2.FsInfo{}, fmt.Errorf("filesystem UUIDs are not supported by the fake manager")
+}
+
+func (m *Manager) GetDirFsInfo(dir string) (v2.FsInfo, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	// Return the filesystem with the longest mountpoint containing dir.
+	var result *v2.FsInfo
+	for i, fs := range m.fsInfo {
+		if dir != fs.Mountpoint && !strings.HasPrefix(dir, strings.TrimSuffix(fs.Mountpoint, "/")+"/") {
+			continue
+		}
+		if result == nil || len(fs.Mountpoint) > len(result.Mountpoint) {
+			result = &m.fsInfo[i]
+		}
+	}
+	if result == nil {
+		return v2.FsInfo{}, fmt.Errorf("no filesystem found for directory %q", dir)
+	}
+	return *result, nil
+}
+
+func (m *Manager) GetFsInfo(label string) ([]v2.FsInfo, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	var result []v2.FsInfo
+	for _, fs := range m.fsInfo {
+		if label == "" {
+			result = append(result, fs)
+			continue
+		}
+		for _, l := range fs.Labels {
+			if l == label {
+				result = append(result, fs)
+				break
+			}
+		}
+	}
+	return result, nil
+}
+
+func (m *Manager) GetProcessList(containerName string, options v2.RequestOptions) ([]v2.ProcessInfo, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	if _, ok := m.containers[containerName]; !ok {
+		return nil, &manager.ContainerNotFoundError{Name: containerName, Message: fmt.Sprintf("unknown container %q", containerName)}
+	}
+	return m.processes[containerName], nil
+}
+
+// GetProcessCensus returns the processes set with SetProcesses. The processes
+// of the root container are owned by the host.
+func (m *Manager) GetProcessCensus() (v2.ProcessCensus, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	names := make([]string, 0, len(m.processes))
+	for name := range m.processes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	census := v2.ProcessCensus{
+		Processes: []v2.CensusProcess{},
+		Owners:    make(map[string]v2.CensusUsage),
+	}
+	for _, name := range names {
+		owner := name
+		if name == "/" {
+			owner = v2.CensusHost
+		}
+		for _, p := range m.processes[name] {
+			census.Processes = append(census.Processes, v2.CensusProcess{ProcessInfo: p, Owner: owner})
+			usage := census.Owners[owner]
+			usage.ProcessCount++
+			usage.PercentCpu += p.PercentCpu
+			usage.PercentMemory += p.PercentMemory
+			usage.RSS += p.RSS
+			census.Owners[owner] = usage
+		}
+	}
+	return census, nil
+}
+
+// GetMachineDecomposition returns the decomposition set with
+// SetMachineDecomposition.
+func (m *Manager) GetMachineDecomposition() (v2.MachineDecomposition, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return m.decomposition, nil
+}
+
+func (m *Manager) GetNetworkNamespaces(containerName string, options v2.RequestOptions) (map[string]v2.NetworkNamespace, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	containers, err := m.getRequestedContainers(containerName, options)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]v2.NetworkNamespace, len(containers))
+	for name := range containers {
+		if ns, ok := m.namespaces[name]; ok {
+			result[name] = ns
+		}
+	}
+	return result, nil
+}
+
+func (m *Manager) GetPorts(containerName string, options v2.RequestOptions) (map[string]v2.ContainerPorts, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	containers, err := m.getRequestedContainers(containerName, options)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]v2.ContainerPorts, len(containers))
+	for name := range containers {
+		if ports, ok := m.ports[name]; ok {
+			result[name] = ports
+		}
+	}
+	return result, nil
+}
+
+func (m *Manager) WatchForEvents(request *events.Request) (*events.EventChannel, error) {
+	eventChannel, err := m.events.WatchEvents(request)
+	if err != nil {
+		return nil, err
+	}
+	m.watchesLock.Lock()
+	defer m.watchesLock.Unlock()
+	m.watches[eventChannel.GetWatchId()] = struct{}{}
+	return eventChannel, nil
+}
+
+func (m *Manager) GetPastEvents(request *events.Request) ([]*info.Event, error) {
+	return m.events.GetEvents(request)
+}
+
+func (m *Manager) CloseEventChannel(watchID int) {
+	m.watchesLock.Lock()
+	_, ok := m.watches[watchID]
+	delete(m.watches, watchID)
+	m.watchesLock.Unlock()
+	if ok {
+		m.events.StopWatch(watchID)
+	}
+}
+
+// AddEvent records an event and sends it to the matching watches.
+func (m *Manager) AddEvent(event *info.Event) error {
+	return m.events.AddEvent(event)
+}
+
+func (m *Manager) DebugInfo() map[string][]string {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	names := make([]string, 0, len(m.containers))
+	for name := range m.containers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return map[string][]string{"Fake containers": names}
+}
+
+// DisabledMetrics returns no sources, the fake containers have no handler.
+func (m *Manager) DisabledMetrics() map[string][]container.DisabledMetrics {
+	return map[string][]container.DisabledMetrics{}
+}
+
+// SetHealth sets the health returned by Health, instead of that of a manager
+// whose global housekeeping just ran.
+func (m *Manager) SetHealth(health manager.Health) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.health = &health
+}
+
+func (m *Manager) Health() manager.Health {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	if m.health != nil {
+		return *m.health
+	}
+	return manager.Health{
+		Housekeeping:               true,
+		LastGlobalHousekeeping:     time.Now(),
+		GlobalHousekeepingInterval: time.Minute,
+	}
+}
+
+func (m *Manager) GetEnvMetadataAllowList() []string {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return m.envAllowList
+}
+
+// SetEnvMetadataAllowList records the allow list, which has no effect on the
+// programmed containers.
+func (m *Manager) SetEnvMetadataAllowList(allowList []string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.envAllowList = allowList
+}
+
+// RediscoverContainers does nothing, the containers being programmed.
+func (m *Manager) RediscoverContainers() ([]string, []string, error) {
+	return nil, nil, nil
+}
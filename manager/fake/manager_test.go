@@ -0,0 +1,84 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fake
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yidoyoon/cadvisor-lite/events"
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+	v2 "github.com/yidoyoon/cadvisor-lite/info/v2"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetContainerInfoV2(t *testing.T) {
+	m := NewManager()
+	m.AddContainer(info.ContainerReference{Name: "/a/b"}, info.ContainerSpec{HasCpu: true})
+	now := time.Now()
+	require.NoError(t, m.AddStats("/a/b",
+		&info.ContainerStats{Timestamp: now.Add(time.Second)},
+		&info.ContainerStats{Timestamp: now},
+	))
+
+	infos, err := m.GetContainerInfoV2("/a", v2.RequestOptions{IdType: v2.TypeName, Count: -1, Recursive: true})
+	require.NoError(t, err)
+	assert.Len(t, infos, 2)
+	require.Len(t, infos["/a/b"].Stats, 2)
+	assert.True(t, infos["/a/b"].Stats[0].Timestamp.Equal(now))
+	assert.True(t, infos["/a/b"].Spec.HasCpu)
+
+	_, err = m.GetContainerInfoV2("/c", v2.RequestOptions{IdType: v2.TypeName})
+	assert.Error(t, err)
+}
+
+func TestRemoveContainer(t *testing.T) {
+	m := NewManager()
+	m.AddContainer(info.ContainerReference{Name: "/a"}, info.ContainerSpec{})
+	m.SetPorts("/a", v2.ContainerPorts{Published: []info.PortMapping{{Protocol: "tcp", ContainerPort: 80, HostPort: 8080}}})
+	m.RemoveContainer("/a")
+
+	// A container created again with the same name has none of the data
+	// of the removed one.
+	m.AddContainer(info.ContainerReference{Name: "/a"}, info.ContainerSpec{})
+	ports, err := m.GetPorts("/a", v2.RequestOptions{IdType: v2.TypeName})
+	require.NoError(t, err)
+	assert.Empty(t, ports)
+}
+
+func TestWatchForEvents(t *testing.T) {
+	m := NewManager()
+	request := events.NewRequest()
+	request.ContainerName = "/"
+	request.IncludeSubcontainers = true
+	request.EventType[info.EventContainerCreation] = true
+	request.EventType[info.EventContainerDeletion] = true
+	watch, err := m.WatchForEvents(request)
+	require.NoError(t, err)
+
+	m.AddContainer(info.ContainerReference{Name: "/a"}, info.ContainerSpec{})
+	m.RemoveContainer("/a")
+	event := <-watch.GetChannel()
+	assert.Equal(t, info.EventContainerCreation, event.EventType)
+	event = <-watch.GetChannel()
+	assert.Equal(t, info.EventContainerDeletion, event.EventType)
+
+	require.NoError(t, m.Stop())
+	_, ok := <-watch.GetChannel()
+	assert.False(t, ok)
+	assert.Empty(t, m.watches)
+}
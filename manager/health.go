@@ -0,0 +1,81 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yidoyoon/cadvisor-lite/watcher"
+)
+
+// Health is the state of the housekeeping and of the container watchers of
+// the manager.
+type Health struct {
+	// Whether the housekeeping runs, i.e. the manager was started with
+	// container factories and not stopped.
+	Housekeeping bool
+	// End of the last global housekeeping, or of the discovery of the
+	// containers at start, and its error if any.
+	LastGlobalHousekeeping      time.Time
+	LastGlobalHousekeepingError error
+	GlobalHousekeepingInterval  time.Duration
+	// State of the container watchers, empty if the housekeeping does not
+	// run.
+	Watchers []WatcherHealth
+}
+
+// WatcherHealth is the state of a container watcher.
+type WatcherHealth struct {
+	// Type of the watcher, e.g. raw.rawContainerWatcher.
+	Name string
+	// Why the watcher does not receive the events of the containers, nil if
+	// it does or does not tell.
+	Err error
+}
+
+// setHousekeeping records whether the housekeeping runs and the end of the
+// last global housekeeping.
+func (m *manager) setHousekeeping(running bool, err error) {
+	m.healthLock.Lock()
+	defer m.healthLock.Unlock()
+	m.housekeeping = running
+	m.lastGlobalHousekeeping = m.clock.Now()
+	m.lastGlobalHousekeepingErr = err
+}
+
+func (m *manager) Health() Health {
+	m.healthLock.Lock()
+	health := Health{
+		Housekeeping:                m.housekeeping,
+		LastGlobalHousekeeping:      m.lastGlobalHousekeeping,
+		LastGlobalHousekeepingError: m.lastGlobalHousekeepingErr,
+		GlobalHousekeepingInterval:  *globalHousekeepingInterval,
+	}
+	m.healthLock.Unlock()
+	if !health.Housekeeping {
+		return health
+	}
+	// The watchers are not modified once the housekeeping runs.
+	for _, w := range m.containerWatchers {
+		watcherHealth := WatcherHealth{Name: strings.TrimPrefix(fmt.Sprintf("%T", w), "*")}
+		if reporter, ok := w.(watcher.HealthReporter); ok {
+			watcherHealth.Err = reporter.Healthy()
+		}
+		health.Watchers = append(health.Watchers, watcherHealth)
+	}
+	return health
+}
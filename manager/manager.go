@@ -41,7 +41,10 @@ import (
 	"github.com/yidoyoon/cadvisor-lite/perf"
 	"github.com/yidoyoon/cadvisor-lite/resctrl"
 	"github.com/yidoyoon/cadvisor-lite/stats"
+	"github.com/yidoyoon/cadvisor-lite/summary"
+	"github.com/yidoyoon/cadvisor-lite/utils/nettopology"
 	"github.com/yidoyoon/cadvisor-lite/utils/oomparser"
+	"github.com/yidoyoon/cadvisor-lite/utils/ports"
 	"github.com/yidoyoon/cadvisor-lite/utils/sysfs"
 	"github.com/yidoyoon/cadvisor-lite/version"
 	"github.com/yidoyoon/cadvisor-lite/watcher"
@@ -58,6 +61,7 @@ var logCadvisorUsage = flag.Bool("log_cadvisor_usage", false, "Whether to log th
 var eventStorageAgeLimit = flag.String("event_storage_age_limit", "default=24h", "Max length of time for which to store events (per type). Value is a comma separated list of key values, where the keys are event types (e.g.: creation, oom) or \"default\" and the value is a duration. Default is applied to all non-specified event types")
 var eventStorageEventLimit = flag.String("event_storage_event_limit", "default=100000", "Max number of events to store (per type). Value is a comma separated list of key values, where the keys are event types (e.g.: creation, oom) or \"default\" and the value is an integer. Default is applied to all non-specified event types")
 var applicationMetricsCountLimit = flag.Int("application_metrics_count_limit", 100, "Max number of application metrics to store (per container)")
+var rootDecompositionCgroups = flag.String("root_decomposition_cgroups", "system.slice,user.slice", "Comma separated list of cgroups, relative to the root, whose usage is reported as a bucket of the decomposition of the usage of the machine, besides the containers and the unaccounted usage. The cgroups must not be nested")
 
 // The namespace under which aliases are unique.
 const (
@@ -103,6 +107,10 @@ type Manager interface {
 	// Gets summary stats for all containers based on request options.
 	GetDerivedStats(containerName string, options v2.RequestOptions) (map[string]v2.DerivedStats, error)
 
+	// Gets the given percentiles, in [0, 100], of the usage of all
+	// containers over each window, based on request options.
+	GetWindowUsage(containerName string, options v2.RequestOptions, windows []time.Duration, percentiles []float64) (map[string][]v2.WindowUsage, error)
+
 	// Get info for all requested containers based on the request options.
 	GetRequestedContainersInfo(containerName string, options v2.RequestOptions) (map[string]*info.ContainerInfo, error)
 
@@ -130,6 +138,22 @@ type Manager interface {
 	// Get ps output for a container.
 	GetProcessList(containerName string, options v2.RequestOptions) ([]v2.ProcessInfo, error)
 
+	// Get the processes of the whole machine along with the container owning them.
+	GetProcessCensus() (v2.ProcessCensus, error)
+
+	// Get the usage of the machine split between the containers, the cgroups
+	// set by -root_decomposition_cgroups and the unaccounted usage.
+	GetMachineDecomposition() (v2.MachineDecomposition, error)
+
+	// Get the network namespaces of the requested containers, keyed by container name.
+	// Containers without processes are omitted.
+	GetNetworkNamespaces(containerName string, options v2.RequestOptions) (map[string]v2.NetworkNamespace, error)
+
+	// Get the published ports and listening sockets of the requested
+	// containers, keyed by container name. Containers without processes are
+	// omitted.
+	GetPorts(containerName string, options v2.RequestOptions) (map[string]v2.ContainerPorts, error)
+
 	// Get events streamed through passedChannel that fit the request.
 	WatchForEvents(request *events.Request) (*events.EventChannel, error)
 
@@ -138,9 +162,31 @@ type Manager interface {
 
 	CloseEventChannel(watchID int)
 
+	// Add an event, e.g. detected outside of the manager, to the stored and streamed events.
+	AddEvent(event *info.Event) error
+
 	// Returns debugging information. Map of lines per category.
 	DebugInfo() map[string][]string
 
+	// Returns the sources of metrics no longer read after their reads kept
+	// failing or taking too long, by container name.
+	DisabledMetrics() map[string][]container.DisabledMetrics
+
+	// Returns the state of the housekeeping and of the container watchers.
+	Health() Health
+
+	// Returns the prefixes of the environment variables collected as metadata
+	// of the containers.
+	GetEnvMetadataAllowList() []string
+
+	// Sets the prefixes of the environment variables collected as metadata of
+	// the containers discovered afterwards.
+	SetEnvMetadataAllowList(allowList []string)
+
+	// Rescans the cgroups for the containers added or removed since the last
+	// scan, as the global housekeeping does, and returns their names.
+	RediscoverContainers() (added []string, removed []string, err error)
+
 	AllPodmanContainers(c *info.ContainerInfoRequest) (map[string]info.ContainerInfo, error)
 
 	PodmanContainer(containerName string, query *info.ContainerInfoRequest) (info.ContainerInfo, error)
@@ -157,6 +203,12 @@ func New(memoryCache *memory.InMemoryCache, sysfs sysfs.SysFs, houskeepingConfig
 	if memoryCache == nil {
 		return nil, fmt.Errorf("manager requires memory storage")
 	}
+	if *timestampClock != wallClock && *timestampClock != monotonicClock {
+		return nil, fmt.Errorf("invalid --timestamp_clock %q: must be %s or %s", *timestampClock, wallClock, monotonicClock)
+	}
+	if !v2.IsPerCpuMode(*perCpuUsage) {
+		return nil, fmt.Errorf("invalid --percpu_usage %q: must be %s, %s or %s", *perCpuUsage, v2.PerCpuFull, v2.PerCpuSocket, v2.PerCpuOff)
+	}
 
 	// Detect the container we are running on.
 	selfContainer := "/"
@@ -191,6 +243,7 @@ func New(memoryCache *memory.InMemoryCache, sysfs sysfs.SysFs, houskeepingConfig
 	// Register for new subcontainers.
 	eventsChannel := make(chan watcher.ContainerEvent, 16)
 
+	realClock := clock.RealClock{}
 	newManager := &manager{
 		containers:                            make(map[namespacedContainerName]*containerData),
 		quitChannels:                          make([]chan error, 0, 2),
@@ -199,7 +252,8 @@ func New(memoryCache *memory.InMemoryCache, sysfs sysfs.SysFs, houskeepingConfig
 		sysFs:                                 sysfs,
 		cadvisorContainer:                     selfContainer,
 		inHostNamespace:                       inHostNamespace,
-		startupTime:                           time.Now(),
+		clock:                                 realClock,
+		startupTime:                           realClock.Now(),
 		maxHousekeepingInterval:               *houskeepingConfig.Interval,
 		allowDynamicHousekeeping:              *houskeepingConfig.AllowDynamic,
 		includedMetrics:                       includedMetricsSet,
@@ -267,10 +321,26 @@ type manager struct {
 	collectorHTTPClient      *http.Client
 	perfManager              stats.Manager
 	resctrlManager           resctrl.Manager
+	// Watcher of the cgroup events triggering housekeepings, nil if disabled.
+	cgroupEventWatcher *cgroupEventWatcher
+	// Summaries saved before the restart by container name, restored when the
+	// containers are created.
+	restoredSummaries map[string]summary.State
 	// List of raw container cgroup path prefix whitelist.
 	rawContainerCgroupPathPrefixWhiteList []string
 	// List of container env prefix whitelist, the matched container envs would be collected into metrics as extra labels.
 	containerEnvMetadataWhiteList []string
+	envMetadataLock               sync.RWMutex // protects containerEnvMetadataWhiteList
+	// Clock of the housekeeping and of the timestamps of the events, fake in
+	// tests.
+	clock clock.WithTickerAndDelayedExecution
+
+	healthLock sync.Mutex
+	// Whether the housekeeping runs, and the end and error of the last
+	// global housekeeping.
+	housekeeping              bool
+	lastGlobalHousekeeping    time.Time
+	lastGlobalHousekeepingErr error
 }
 
 func (m *manager) PodmanContainer(containerName string, query *info.ContainerInfoRequest) (info.ContainerInfo, error) {
@@ -307,22 +377,48 @@ func (m *manager) Start() error {
 		klog.Warningf("Could not configure a source for OOM detection, disabling OOM events: %v", err)
 	}
 
+	if *bootIDStateFile != "" {
+		m.detectReboot(*bootIDStateFile)
+	}
+
 	// If there are no factories, don't start any housekeeping and serve the information we do have.
 	if !container.HasFactories() {
 		return nil
 	}
 
+	if *cgroupEventsHousekeepingInterval > 0 && cgroups.IsCgroup2UnifiedMode() {
+		m.cgroupEventWatcher, err = newCgroupEventWatcher(*cgroupEventsHousekeepingInterval, m.clock)
+		if err != nil {
+			klog.Warningf("Could not watch cgroup events, disabling the housekeepings they trigger: %v", err)
+		} else {
+			quitCgroupEvents := make(chan error)
+			m.quitChannels = append(m.quitChannels, quitCgroupEvents)
+			m.cgroupEventWatcher.Start(quitCgroupEvents)
+		}
+	}
+
+	if *summaryStateFile != "" {
+		m.restoredSummaries, err = loadSummaryStates(*summaryStateFile)
+		if err != nil {
+			klog.Warningf("Could not restore the usage summaries: %v", err)
+		}
+	}
+
 	// Create root and then recover all containers.
 	err = m.createContainer("/", watcher.Raw)
 	if err != nil {
 		return err
 	}
 	klog.V(2).Infof("Starting recovery of all containers")
-	err = m.detectSubcontainers("/")
+	_, _, err = m.detectSubcontainers("/")
 	if err != nil {
 		return err
 	}
 	klog.V(2).Infof("Recovery completed")
+	// The summaries of the containers gone during the restart are dropped.
+	m.containersLock.Lock()
+	m.restoredSummaries = nil
+	m.containersLock.Unlock()
 
 	// Watch for new container.
 	quitWatcher := make(chan error)
@@ -341,6 +437,14 @@ func (m *manager) Start() error {
 	m.quitChannels = append(m.quitChannels, quitUpdateMachineInfo)
 	go m.updateMachineInfo(quitUpdateMachineInfo)
 
+	if *summaryStateFile != "" {
+		quitSaveSummaries := make(chan error)
+		m.quitChannels = append(m.quitChannels, quitSaveSummaries)
+		go m.saveSummaries(*summaryStateFile, *summaryStateInterval, quitSaveSummaries)
+	}
+
+	// The containers were just discovered by watchForNewContainers.
+	m.setHousekeeping(true, nil)
 	return nil
 }
 
@@ -358,6 +462,7 @@ func (m *manager) Stop() error {
 		}
 	}
 	m.quitChannels = make([]chan error, 0, 2)
+	m.setHousekeeping(false, nil)
 	nvm.Finalize()
 	perf.Finalize()
 	return nil
@@ -371,18 +476,20 @@ func (m *manager) destroyCollectors() {
 }
 
 func (m *manager) updateMachineInfo(quit chan error) {
-	ticker := time.NewTicker(*updateMachineInfoInterval)
+	ticker := m.clock.NewTicker(*updateMachineInfoInterval)
 	for {
 		select {
-		case <-ticker.C:
+		case <-ticker.C():
 			info, err := machine.Info(m.sysFs, m.fsInfo, m.inHostNamespace)
 			if err != nil {
 				klog.Errorf("Could not get machine info: %v", err)
 				break
 			}
 			m.machineMu.Lock()
+			previousBootID := m.machineInfo.BootID
 			m.machineInfo = *info
 			m.machineMu.Unlock()
+			m.bootIDChanged(previousBootID, info, *bootIDStateFile)
 			klog.V(5).Infof("Update machine info: %+v", *info)
 		case <-quit:
 			ticker.Stop()
@@ -399,20 +506,22 @@ func (m *manager) globalHousekeeping(quit chan error) {
 		longHousekeeping = *globalHousekeepingInterval / 2
 	}
 
-	ticker := time.NewTicker(*globalHousekeepingInterval)
+	ticker := m.clock.NewTicker(*globalHousekeepingInterval)
+	defer ticker.Stop()
 	for {
 		select {
-		case t := <-ticker.C:
-			start := time.Now()
+		case t := <-ticker.C():
+			start := m.clock.Now()
 
 			// Check for new containers.
-			err := m.detectSubcontainers("/")
+			_, _, err := m.detectSubcontainers("/")
 			if err != nil {
 				klog.Errorf("Failed to detect containers: %s", err)
 			}
+			m.setHousekeeping(true, err)
 
 			// Log if housekeeping took too long.
-			duration := time.Since(start)
+			duration := m.clock.Since(start)
 			if duration >= longHousekeeping {
 				klog.V(3).Infof("Global Housekeeping(%d) took %s", t.Unix(), duration)
 			}
@@ -438,7 +547,7 @@ func (m *manager) getContainerData(containerName string) (*containerData, error)
 		}]
 	}()
 	if !ok {
-		return nil, fmt.Errorf("unknown container %q", containerName)
+		return nil, containerNotFound(containerName, "unknown container %q", containerName)
 	}
 	return cont, nil
 }
@@ -448,6 +557,9 @@ func (m *manager) GetDerivedStats(containerName string, options v2.RequestOption
 	if err != nil {
 		return nil, err
 	}
+	m.machineMu.RLock()
+	allocatable := m.machineInfo.Allocatable
+	m.machineMu.RUnlock()
 	var errs partialFailure
 	stats := make(map[string]v2.DerivedStats)
 	for name, cont := range conts {
@@ -455,11 +567,32 @@ func (m *manager) GetDerivedStats(containerName string, options v2.RequestOption
 		if err != nil {
 			errs.append(name, "DerivedStats", err)
 		}
+		// The allocatable resources are those of the whole machine.
+		if name == "/" && allocatable != nil {
+			d.Allocatable = v2.DerivedUtilizationFromStats(d, allocatable.Allocatable)
+		}
 		stats[name] = d
 	}
 	return stats, errs.OrNil()
 }
 
+func (m *manager) GetWindowUsage(containerName string, options v2.RequestOptions, windows []time.Duration, percentiles []float64) (map[string][]v2.WindowUsage, error) {
+	conts, err := m.getRequestedContainers(containerName, options)
+	if err != nil {
+		return nil, err
+	}
+	var errs partialFailure
+	usages := make(map[string][]v2.WindowUsage)
+	for name, cont := range conts {
+		u, err := cont.WindowUsage(windows, percentiles)
+		if err != nil {
+			errs.append(name, "WindowUsage", err)
+		}
+		usages[name] = u
+	}
+	return usages, errs.OrNil()
+}
+
 func (m *manager) GetContainerSpec(containerName string, options v2.RequestOptions) (map[string]v2.ContainerSpec, error) {
 	conts, err := m.getRequestedContainers(containerName, options)
 	if err != nil {
@@ -496,6 +629,19 @@ func (m *manager) getAdjustedSpec(cinfo *containerInfo) info.ContainerSpec {
 			m.machineMu.RUnlock()
 		}
 	}
+	// Name the devices the container did I/O on.
+	if len(spec.DiskIoDevices) > 0 {
+		devices := make([]info.BlockDevice, len(spec.DiskIoDevices))
+		m.machineMu.RLock()
+		for i, device := range spec.DiskIoDevices {
+			devices[i] = device
+			if known, ok := m.machineInfo.BlockDevices[fmt.Sprintf("%d:%d", device.Major, device.Minor)]; ok {
+				devices[i] = known
+			}
+		}
+		m.machineMu.RUnlock()
+		spec.DiskIoDevices = devices
+	}
 	return spec
 }
 
@@ -568,7 +714,7 @@ func (m *manager) getContainer(containerName string) (*containerData, error) {
 	defer m.containersLock.RUnlock()
 	cont, ok := m.containers[namespacedContainerName{Name: containerName}]
 	if !ok {
-		return nil, fmt.Errorf("unknown container %q", containerName)
+		return nil, containerNotFound(containerName, "unknown container %q", containerName)
 	}
 	return cont, nil
 }
@@ -644,7 +790,7 @@ func (m *manager) namespacedContainer(containerName string, ns string) (*contain
 		}
 
 		if cont == nil {
-			return nil, fmt.Errorf("unable to find container %q in %q namespace", containerName, ns)
+			return nil, containerNotFound(containerName, "unable to find container %q in %q namespace", containerName, ns)
 		}
 	}
 
@@ -685,6 +831,11 @@ func (m *manager) containerDataSliceToContainerInfoSlice(containers []*container
 }
 
 func (m *manager) GetRequestedContainersInfo(containerName string, options v2.RequestOptions) (map[string]*info.ContainerInfo, error) {
+	var epoch time.Time
+	if options.Aligned {
+		epoch = m.clock.Now()
+		options.MaxAge = nil
+	}
 	containers, err := m.getRequestedContainers(containerName, options)
 	if err != nil {
 		return nil, err
@@ -693,6 +844,16 @@ func (m *manager) GetRequestedContainersInfo(containerName string, options v2.Re
 	containersMap := make(map[string]*info.ContainerInfo)
 	query := info.ContainerInfoRequest{
 		NumStats: options.Count,
+		Start:    options.Start,
+		End:      options.End,
+	}
+	// The start of the query is inclusive.
+	if since := options.Since.Add(time.Nanosecond); !options.Since.IsZero() && since.After(query.Start) {
+		query.Start = since
+	}
+	if options.Aligned {
+		m.housekeepSince(containers, epoch)
+		query = info.ContainerInfoRequest{NumStats: 1, Start: epoch}
 	}
 	for name, data := range containers {
 		info, err := m.containerDataToContainerInfo(data, &query)
@@ -703,11 +864,46 @@ func (m *manager) GetRequestedContainersInfo(containerName string, options v2.Re
 			}
 			errs.append(name, "containerDataToContainerInfo", err)
 		}
+		if options.Aligned && info != nil {
+			alignStats(info, epoch)
+		}
 		containersMap[name] = info
 	}
 	return containersMap, errs.OrNil()
 }
 
+// housekeepSince performs an on-demand housekeeping of the containers so that
+// each of them has stats sampled after start, unless its housekeeping fails.
+func (m *manager) housekeepSince(containers map[string]*containerData, start time.Time) {
+	var waitGroup sync.WaitGroup
+	waitGroup.Add(len(containers))
+	for _, container := range containers {
+		go func(cont *containerData) {
+			defer waitGroup.Done()
+			// A housekeeping in progress when the first one is requested
+			// completes it with stats sampled before start.
+			for i := 0; i < 2; i++ {
+				cont.OnDemandHousekeeping(0)
+				stats, err := m.memoryCache.RecentStats(cont.info.Name, start, time.Time{}, 1)
+				if err == nil && len(stats) > 0 {
+					return
+				}
+			}
+		}(container)
+	}
+	waitGroup.Wait()
+}
+
+// alignStats sets the timestamp of the stats of the container to epoch. The
+// stats are copied, as they are shared with the cache.
+func alignStats(cinfo *info.ContainerInfo, epoch time.Time) {
+	for i, stats := range cinfo.Stats {
+		aligned := *stats
+		aligned.Timestamp = epoch
+		cinfo.Stats[i] = &aligned
+	}
+}
+
 func (m *manager) getRequestedContainers(containerName string, options v2.RequestOptions) (map[string]*containerData, error) {
 	containersMap := make(map[string]*containerData)
 	switch options.IdType {
@@ -721,7 +917,7 @@ func (m *manager) getRequestedContainers(containerName string, options v2.Reques
 		} else {
 			containersMap = m.getSubcontainers(containerName)
 			if len(containersMap) == 0 {
-				return containersMap, fmt.Errorf("unknown container: %q", containerName)
+				return containersMap, containerNotFound(containerName, "unknown container: %q", containerName)
 			}
 		}
 	case v2.TypeDocker, v2.TypePodman:
@@ -745,6 +941,31 @@ func (m *manager) getRequestedContainers(containerName string, options v2.Reques
 	default:
 		return containersMap, fmt.Errorf("invalid request type %q", options.IdType)
 	}
+	if options.LabelSelector != "" {
+		selector, err := v2.ParseLabelSelector(options.LabelSelector)
+		if err != nil {
+			return containersMap, err
+		}
+		for name, cont := range containersMap {
+			cont.lock.Lock()
+			labels := cont.info.Spec.Labels
+			cont.lock.Unlock()
+			if !selector.Matches(labels) {
+				delete(containersMap, name)
+			}
+		}
+	}
+	if options.NameRegex != "" {
+		matcher, err := v2.ParseNameRegex(options.NameRegex)
+		if err != nil {
+			return containersMap, err
+		}
+		for name, cont := range containersMap {
+			if !matcher.Matches(cont.info.Name, cont.info.Aliases) {
+				delete(containersMap, name)
+			}
+		}
+	}
 	if options.MaxAge != nil {
 		// update stats for all containers in containersMap
 		var waitGroup sync.WaitGroup
@@ -872,6 +1093,260 @@ func (m *manager) GetProcessList(containerName string, options v2.RequestOptions
 	return ps, nil
 }
 
+func (m *manager) GetProcessCensus() (v2.ProcessCensus, error) {
+	root, err := m.getContainerData("/")
+	if err != nil {
+		return v2.ProcessCensus{}, err
+	}
+	ps, err := root.GetProcessList(m.cadvisorContainer, m.inHostNamespace)
+	if err != nil {
+		return v2.ProcessCensus{}, err
+	}
+	m.containersLock.RLock()
+	defer m.containersLock.RUnlock()
+	return processCensus(ps, m.processOwner), nil
+}
+
+// processOwner returns the name of the innermost container managed by a
+// container runtime whose cgroup contains cgroupPath, or v2.CensusHost if
+// there is none. Must be called with containersLock held.
+func (m *manager) processOwner(cgroupPath string) string {
+	for name := cgroupPath; name != "/" && name != "."; name = path.Dir(name) {
+		cont, ok := m.containers[namespacedContainerName{Name: name}]
+		if ok && cont.handler.Type() != container.ContainerTypeRaw {
+			return name
+		}
+	}
+	return v2.CensusHost
+}
+
+func processCensus(ps []v2.ProcessInfo, owner func(cgroupPath string) string) v2.ProcessCensus {
+	census := v2.ProcessCensus{
+		Processes: make([]v2.CensusProcess, 0, len(ps)),
+		Owners:    make(map[string]v2.CensusUsage),
+	}
+	for _, p := range ps {
+		process := v2.CensusProcess{
+			ProcessInfo: p,
+			Owner:       owner(p.CgroupPath),
+		}
+		census.Processes = append(census.Processes, process)
+
+		usage := census.Owners[process.Owner]
+		usage.ProcessCount++
+		usage.PercentCpu += p.PercentCpu
+		usage.PercentMemory += p.PercentMemory
+		usage.RSS += p.RSS
+		census.Owners[process.Owner] = usage
+	}
+	return census
+}
+
+func (m *manager) GetMachineDecomposition() (v2.MachineDecomposition, error) {
+	var cgroups []string
+	for _, cgroup := range strings.Split(*rootDecompositionCgroups, ",") {
+		if cgroup = strings.Trim(strings.TrimSpace(cgroup), "/"); cgroup != "" {
+			cgroups = append(cgroups, cgroup)
+		}
+	}
+	m.containersLock.RLock()
+	defer m.containersLock.RUnlock()
+	return m.machineDecomposition(cgroups)
+}
+
+// machineDecomposition splits the latest usage of the root container between
+// the outermost containers managed by a container runtime, the cgroups, minus
+// the containers they hold, and the rest. Cgroups which are not tracked are
+// omitted. Must be called with containersLock held.
+func (m *manager) machineDecomposition(cgroups []string) (v2.MachineDecomposition, error) {
+	machine, timestamp, err := m.recentUsage("/")
+	if err != nil {
+		return v2.MachineDecomposition{}, err
+	}
+	decomposition := v2.MachineDecomposition{
+		Timestamp: timestamp,
+		Machine:   machine,
+		Buckets:   make(map[string]v2.DecompositionUsage),
+	}
+	// Usage of the containers held by each cgroup.
+	held := make(map[string]v2.DecompositionUsage)
+	var containers v2.DecompositionUsage
+	for key, cont := range m.containers {
+		if key.Namespace != "" || key.Name == "/" || cont.handler.Type() == container.ContainerTypeRaw {
+			continue
+		}
+		// Containers nested in another one are already counted.
+		if m.processOwner(path.Dir(key.Name)) != v2.CensusHost {
+			continue
+		}
+		usage, _, err := m.recentUsage(key.Name)
+		if err != nil {
+			klog.V(4).Infof("Failed to get the usage of container %q: %v", key.Name, err)
+			continue
+		}
+		containers = addUsage(containers, usage)
+		for _, cgroup := range cgroups {
+			if strings.HasPrefix(key.Name, "/"+cgroup+"/") {
+				held[cgroup] = addUsage(held[cgroup], usage)
+			}
+		}
+	}
+	decomposition.Buckets[v2.DecompositionContainers] = containers
+	unaccounted := subtractUsage(machine, containers)
+	for _, cgroup := range cgroups {
+		if _, ok := m.containers[namespacedContainerName{Name: "/" + cgroup}]; !ok {
+			continue
+		}
+		usage, _, err := m.recentUsage("/" + cgroup)
+		if err != nil {
+			klog.V(4).Infof("Failed to get the usage of cgroup %q: %v", cgroup, err)
+			continue
+		}
+		usage = subtractUsage(usage, held[cgroup])
+		decomposition.Buckets[cgroup] = usage
+		unaccounted = subtractUsage(unaccounted, usage)
+	}
+	decomposition.Buckets[v2.DecompositionUnaccounted] = unaccounted
+	return decomposition, nil
+}
+
+// recentUsage returns the usage of a container from its two latest samples,
+// along with the time of the latest one.
+func (m *manager) recentUsage(name string) (v2.DecompositionUsage, time.Time, error) {
+	var empty time.Time
+	stats, err := m.memoryCache.RecentStats(name, empty, empty, 2)
+	if err != nil {
+		return v2.DecompositionUsage{}, empty, err
+	}
+	if len(stats) == 0 {
+		return v2.DecompositionUsage{}, empty, fmt.Errorf("no stats for container %q", name)
+	}
+	last := stats[len(stats)-1]
+	usage := v2.DecompositionUsage{
+		MemoryUsage:      last.Memory.Usage,
+		MemoryWorkingSet: last.Memory.WorkingSet,
+	}
+	if len(stats) == 2 {
+		prev := stats[0]
+		elapsed := last.Timestamp.Sub(prev.Timestamp)
+		if elapsed > 0 && last.Cpu.Usage.Total >= prev.Cpu.Usage.Total {
+			usage.CpuNanoCores = uint64(float64(last.Cpu.Usage.Total-prev.Cpu.Usage.Total) / elapsed.Seconds())
+		}
+	}
+	return usage, last.Timestamp, nil
+}
+
+func addUsage(a, b v2.DecompositionUsage) v2.DecompositionUsage {
+	return v2.DecompositionUsage{
+		CpuNanoCores:     a.CpuNanoCores + b.CpuNanoCores,
+		MemoryUsage:      a.MemoryUsage + b.MemoryUsage,
+		MemoryWorkingSet: a.MemoryWorkingSet + b.MemoryWorkingSet,
+	}
+}
+
+// subtractUsage returns a minus b, which is 0 rather than negative when b
+// exceeds a, as the samples of different cgroups are not taken at once.
+func subtractUsage(a, b v2.DecompositionUsage) v2.DecompositionUsage {
+	sub := func(x, y uint64) uint64 {
+		if y > x {
+			return 0
+		}
+		return x - y
+	}
+	return v2.DecompositionUsage{
+		CpuNanoCores:     sub(a.CpuNanoCores, b.CpuNanoCores),
+		MemoryUsage:      sub(a.MemoryUsage, b.MemoryUsage),
+		MemoryWorkingSet: sub(a.MemoryWorkingSet, b.MemoryWorkingSet),
+	}
+}
+
+func (m *manager) GetNetworkNamespaces(containerName string, options v2.RequestOptions) (map[string]v2.NetworkNamespace, error) {
+	// override MaxAge. Network namespaces do not require updated stats.
+	options.MaxAge = nil
+	conts, err := m.getRequestedContainers(containerName, options)
+	if err != nil {
+		return nil, err
+	}
+	rootFs := "/"
+	if !m.inHostNamespace {
+		rootFs = "/rootfs"
+	}
+	inspector, err := nettopology.NewInspector(rootFs)
+	if err != nil {
+		return nil, err
+	}
+	defer inspector.Close()
+
+	namespaces := make(map[string]v2.NetworkNamespace, len(conts))
+	var errs partialFailure
+	for name, cont := range conts {
+		pid := 1
+		if !cont.isRoot() {
+			pids, err := cont.handler.ListProcesses(container.ListSelf)
+			if err != nil {
+				errs.append(name, "ListProcesses", err)
+				continue
+			}
+			if len(pids) == 0 {
+				continue
+			}
+			pid = pids[0]
+		}
+		ns, err := inspector.Inspect(pid)
+		if err != nil {
+			errs.append(name, "Inspect", err)
+			continue
+		}
+		namespaces[name] = ns
+	}
+	if len(errs) > 0 {
+		return namespaces, errs
+	}
+	return namespaces, nil
+}
+
+func (m *manager) GetPorts(containerName string, options v2.RequestOptions) (map[string]v2.ContainerPorts, error) {
+	// override MaxAge. Ports do not require updated stats.
+	options.MaxAge = nil
+	conts, err := m.getRequestedContainers(containerName, options)
+	if err != nil {
+		return nil, err
+	}
+	rootFs := "/"
+	if !m.inHostNamespace {
+		rootFs = "/rootfs"
+	}
+
+	result := make(map[string]v2.ContainerPorts, len(conts))
+	var errs partialFailure
+	for name, cont := range conts {
+		pids, err := cont.handler.ListProcesses(container.ListSelf)
+		if err != nil {
+			errs.append(name, "ListProcesses", err)
+			continue
+		}
+		if len(pids) == 0 {
+			continue
+		}
+		p, err := ports.Inspect(rootFs, pids)
+		if err != nil {
+			errs.append(name, "Inspect", err)
+			continue
+		}
+		cont.lock.Lock()
+		p.Published = cont.info.Spec.Ports
+		cont.lock.Unlock()
+		if p.Published == nil {
+			p.Published = []info.PortMapping{}
+		}
+		result[name] = p
+	}
+	if len(errs) > 0 {
+		return result, errs
+	}
+	return result, nil
+}
+
 func (m *manager) registerCollectors(collectorConfigs map[string]string, cont *containerData) error {
 	for k, v := range collectorConfigs {
 		configFile, err := cont.ReadFile(v, m.inHostNamespace)
@@ -880,7 +1355,16 @@ func (m *manager) registerCollectors(collectorConfigs map[string]string, cont *c
 		}
 		klog.V(4).Infof("Got config from %q: %q", v, configFile)
 
-		if strings.HasPrefix(k, "prometheus") || strings.HasPrefix(k, "Prometheus") {
+		if strings.HasPrefix(k, "probe") || strings.HasPrefix(k, "Probe") {
+			newCollector, err := collector.NewProbeCollector(k, configFile, cont.handler, m.collectorHTTPClient)
+			if err != nil {
+				return fmt.Errorf("failed to create collector for container %q, config %q: %v", cont.info.Name, k, err)
+			}
+			err = cont.collectorManager.RegisterCollector(newCollector)
+			if err != nil {
+				return fmt.Errorf("failed to register collector for container %q, config %q: %v", cont.info.Name, k, err)
+			}
+		} else if strings.HasPrefix(k, "prometheus") || strings.HasPrefix(k, "Prometheus") {
 			newCollector, err := collector.NewPrometheusCollector(k, configFile, *applicationMetricsCountLimit, cont.handler, m.collectorHTTPClient)
 			if err != nil {
 				return fmt.Errorf("failed to create collector for container %q, config %q: %v", cont.info.Name, k, err)
@@ -921,7 +1405,7 @@ func (m *manager) createContainerLocked(containerName string, watchSource watche
 		return nil
 	}
 
-	handler, accept, err := container.NewContainerHandler(containerName, watchSource, m.containerEnvMetadataWhiteList, m.inHostNamespace)
+	handler, accept, err := container.NewContainerHandler(containerName, watchSource, m.GetEnvMetadataAllowList(), m.inHostNamespace)
 	if err != nil {
 		return err
 	}
@@ -936,10 +1420,34 @@ func (m *manager) createContainerLocked(containerName string, watchSource watche
 	}
 
 	logUsage := *logCadvisorUsage && containerName == m.cadvisorContainer
-	cont, err := newContainerData(containerName, m.memoryCache, handler, logUsage, collectorManager, m.maxHousekeepingInterval, m.allowDynamicHousekeeping, clock.RealClock{})
+	discoveryTime := m.clock.Now()
+	cont, err := newContainerData(containerName, m.memoryCache, handler, logUsage, collectorManager, m.maxHousekeepingInterval, m.allowDynamicHousekeeping, m.clock)
 	if err != nil {
 		return err
 	}
+	cont.addEvent = m.eventHandler.AddEvent
+	if *perCpuUsage == v2.PerCpuSocket {
+		m.machineMu.RLock()
+		cont.cpuSockets = v2.CpuSockets(m.machineInfo.Topology)
+		m.machineMu.RUnlock()
+	}
+	// The start latency is only measured for the containers started since
+	// cAdvisor started, the others are discovered on startup.
+	if started := cont.info.Spec.StartedAt; started.After(m.startupTime) || cont.info.Spec.CreationTime.After(m.startupTime) {
+		cont.discoveryTime = discoveryTime
+	}
+	m.restoreSummary(cont)
+
+	if m.cgroupEventWatcher != nil {
+		cgroupPath, err := handler.GetCgroupPath("memory")
+		if err != nil {
+			klog.V(4).Infof("Not watching the cgroup events of %q: %v", containerName, err)
+		} else {
+			cont.cgroupEvents = make(chan struct{}, 1)
+			cont.cgroupEventsDir = cgroupPath
+			m.cgroupEventWatcher.Watch(cgroupPath, cont.cgroupEvents)
+		}
+	}
 
 	if m.includedMetrics.Has(container.PerfMetrics) {
 		perfCgroupPath, err := handler.GetCgroupPath("perf_event")
@@ -1026,6 +1534,9 @@ func (m *manager) destroyContainerLocked(containerName string) error {
 	if err != nil {
 		return err
 	}
+	if cont.cgroupEvents != nil {
+		m.cgroupEventWatcher.Unwatch(cont.cgroupEventsDir)
+	}
 
 	// Remove the container from our records (and all its aliases).
 	delete(m.containers, namespacedName)
@@ -1044,7 +1555,7 @@ func (m *manager) destroyContainerLocked(containerName string) error {
 
 	newEvent := &info.Event{
 		ContainerName: contRef.Name,
-		Timestamp:     time.Now(),
+		Timestamp:     m.clock.Now(),
 		EventType:     info.EventContainerDeletion,
 	}
 	err = m.eventHandler.AddEvent(newEvent)
@@ -1103,30 +1614,57 @@ func (m *manager) getContainersDiff(containerName string) (added []info.Containe
 	return
 }
 
-// Detect the existing subcontainers and reflect the setup here.
-func (m *manager) detectSubcontainers(containerName string) error {
+// Detect the existing subcontainers and reflect the setup here. Returns the
+// names of the containers added and removed.
+func (m *manager) detectSubcontainers(containerName string) ([]string, []string, error) {
 	added, removed, err := m.getContainersDiff(containerName)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
 	// Add the new containers.
+	addedNames := make([]string, 0, len(added))
 	for _, cont := range added {
 		err = m.createContainer(cont.Name, watcher.Raw)
 		if err != nil {
 			klog.Errorf("Failed to create existing container: %s: %s", cont.Name, err)
+			continue
 		}
+		addedNames = append(addedNames, cont.Name)
 	}
 
 	// Remove the old containers.
+	removedNames := make([]string, 0, len(removed))
 	for _, cont := range removed {
 		err = m.destroyContainer(cont.Name)
 		if err != nil {
 			klog.Errorf("Failed to destroy existing container: %s: %s", cont.Name, err)
+			continue
 		}
+		removedNames = append(removedNames, cont.Name)
 	}
 
-	return nil
+	return addedNames, removedNames, nil
+}
+
+func (m *manager) GetEnvMetadataAllowList() []string {
+	m.envMetadataLock.RLock()
+	defer m.envMetadataLock.RUnlock()
+	return m.containerEnvMetadataWhiteList
+}
+
+func (m *manager) SetEnvMetadataAllowList(allowList []string) {
+	m.envMetadataLock.Lock()
+	defer m.envMetadataLock.Unlock()
+	m.containerEnvMetadataWhiteList = allowList
+}
+
+func (m *manager) RediscoverContainers() ([]string, []string, error) {
+	added, removed, err := m.detectSubcontainers("/")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to detect containers: %v", err)
+	}
+	return added, removed, nil
 }
 
 // Watches for new containers started in the system. Runs forever unless there is a setup error.
@@ -1147,7 +1685,7 @@ func (m *manager) watchForNewContainers(quit chan error) error {
 	}
 
 	// There is a race between starting the watch and new container creation so we do a detection before we read new containers.
-	err := m.detectSubcontainers("/")
+	_, _, err := m.detectSubcontainers("/")
 	if err != nil {
 		return err
 	}
@@ -1214,6 +1752,7 @@ func (m *manager) watchForNewOoms() error {
 			if err != nil {
 				klog.Errorf("failed to add OOM event for %q: %v", oomInstance.ContainerName, err)
 			}
+			oomEvent := &info.EventReference{Id: newEvent.Id, Timestamp: newEvent.Timestamp}
 			klog.V(3).Infof("Created an OOM event in container %q at %v", oomInstance.ContainerName, oomInstance.TimeOfDeath)
 
 			newEvent = &info.Event{
@@ -1248,6 +1787,7 @@ func (m *manager) watchForNewOoms() error {
 			}
 			for _, cont := range conts {
 				atomic.AddUint64(&cont.oomEvents, 1)
+				cont.lastOomEvent.Store(oomEvent)
 			}
 		}
 	}()
@@ -1269,6 +1809,10 @@ func (m *manager) CloseEventChannel(watchID int) {
 	m.eventHandler.StopWatch(watchID)
 }
 
+func (m *manager) AddEvent(event *info.Event) error {
+	return m.eventHandler.AddEvent(event)
+}
+
 // Parses the events StoragePolicy from the flags.
 func parseEventsStoragePolicy() events.StoragePolicy {
 	policy := events.DefaultStoragePolicy()
@@ -1351,6 +1895,22 @@ func (m *manager) DebugInfo() map[string][]string {
 	return debugInfo
 }
 
+func (m *manager) DisabledMetrics() map[string][]container.DisabledMetrics {
+	m.containersLock.RLock()
+	defer m.containersLock.RUnlock()
+	disabledMetrics := make(map[string][]container.DisabledMetrics)
+	for _, cont := range m.containers {
+		disabler, ok := cont.handler.(container.MetricsDisabler)
+		if !ok {
+			continue
+		}
+		if disabled := disabler.DisabledMetrics(); len(disabled) > 0 {
+			disabledMetrics[cont.info.Name] = disabled
+		}
+	}
+	return disabledMetrics
+}
+
 func (m *manager) getFsInfoByDeviceName(deviceName string) (v2.FsInfo, error) {
 	mountPoint, err := m.fsInfo.GetMountpointForDevice(deviceName)
 	if err != nil {
@@ -1403,6 +1963,23 @@ func getVersionInfo() (*info.VersionInfo, error) {
 	}, nil
 }
 
+// ContainerNotFoundError is the error of the requests of a container unknown
+// to the manager.
+type ContainerNotFoundError struct {
+	// Name, or id in a namespace, of the container requested.
+	Name string
+	// Message of the error, e.g. unknown container "/docker/abc".
+	Message string
+}
+
+func (e *ContainerNotFoundError) Error() string {
+	return e.Message
+}
+
+func containerNotFound(name string, format string, args ...interface{}) error {
+	return &ContainerNotFoundError{Name: name, Message: fmt.Sprintf(format, args...)}
+}
+
 // Helper for accumulating partial failures.
 type partialFailure []string
 
@@ -19,6 +19,7 @@ package manager
 import (
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
 	"testing"
 	"time"
@@ -31,8 +32,11 @@ import (
 	itest "github.com/yidoyoon/cadvisor-lite/info/v1/test"
 	v2 "github.com/yidoyoon/cadvisor-lite/info/v2"
 	"github.com/yidoyoon/cadvisor-lite/utils/sysfs/fakesysfs"
+	"github.com/yidoyoon/cadvisor-lite/watcher"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	clock "k8s.io/utils/clock/testing"
 
 	// install all the container runtimes included in the library version for testing.
@@ -57,6 +61,7 @@ func createManagerAndAddContainers(
 		containers:   make(map[namespacedContainerName]*containerData),
 		quitChannels: make([]chan error, 0, 2),
 		memoryCache:  memoryCache,
+		clock:        clock.NewFakeClock(time.Now()),
 	}
 	for _, name := range containers {
 		mockHandler := containertest.NewMockContainerHandler(name)
@@ -96,6 +101,7 @@ func createManagerAndAddSubContainers(
 		containers:   make(map[namespacedContainerName]*containerData),
 		quitChannels: make([]chan error, 0, 2),
 		memoryCache:  memoryCache,
+		clock:        clock.NewFakeClock(time.Now()),
 	}
 
 	subcontainers1 := []info.ContainerReference{
@@ -315,6 +321,7 @@ func TestGetContainerInfo(t *testing.T) {
 		handler.AssertExpectations(t)
 		returned := returnedInfos[container]
 		expected := infosMap[container]
+		expected.Spec.Uid = containerUID(container, &expected.Spec)
 		if !reflect.DeepEqual(returned, expected) {
 			t.Errorf("returned unexpected info for container %v; returned %+v; expected %+v", container, returned, expected)
 		}
@@ -353,6 +360,80 @@ func TestGetContainerInfoV2(t *testing.T) {
 	}
 }
 
+func TestGetRequestedContainersInfoAligned(t *testing.T) {
+	containers := []string{"/", "/c1", "/c2"}
+	memoryCache := memory.New(time.Minute, nil)
+	m := createManagerAndAddContainers(memoryCache, nil, containers, func(h *containertest.MockContainerHandler) {
+		h.On("GetSpec").Return(itest.GenerateRandomContainerSpec(4), nil)
+		h.On("ListContainers", container.ListSelf).Return([]info.ContainerReference(nil), nil)
+		call := h.On("GetStats")
+		call.Run(func(mock.Arguments) {
+			stats := itest.GenerateRandomStats(1, 4, time.Second)[0]
+			stats.Timestamp = time.Now()
+			call.ReturnArguments = mock.Arguments{stats, nil}
+		})
+	}, t)
+	for _, name := range containers {
+		cont := m.containers[namespacedContainerName{Name: name}]
+		// Stale stats, which are not returned.
+		stale := itest.GenerateRandomStats(1, 4, time.Second)[0]
+		stale.Timestamp = time.Now().Add(-time.Minute)
+		require.NoError(t, memoryCache.AddStats(&info.ContainerInfo{ContainerReference: cont.info.ContainerReference}, stale))
+		require.NoError(t, cont.Start())
+		defer cont.Stop()
+	}
+
+	infos, err := m.GetRequestedContainersInfo("/", v2.RequestOptions{IdType: v2.TypeName, Count: 10, Recursive: true, Aligned: true})
+	require.NoError(t, err)
+	require.Len(t, infos, len(containers))
+	var epoch time.Time
+	for name, cinfo := range infos {
+		require.Len(t, cinfo.Stats, 1, name)
+		if epoch.IsZero() {
+			epoch = cinfo.Stats[0].Timestamp
+		}
+		assert.Equal(t, epoch, cinfo.Stats[0].Timestamp, name)
+	}
+	// The stats are collected at the time of the clock of the manager.
+	assert.Equal(t, m.clock.Now(), epoch)
+}
+
+func TestGetRequestedContainersLabelSelector(t *testing.T) {
+	containers := []string{"/", "/docker/c1", "/docker/c2", "/docker/c3"}
+	memoryCache := memory.New(time.Minute, nil)
+	m := createManagerAndAddContainers(memoryCache, nil, containers, func(h *containertest.MockContainerHandler) {}, t)
+	for name, labels := range map[string]map[string]string{
+		"/":          nil,
+		"/docker/c1": {"io.kubernetes.pod.namespace": "prod", "app": "web"},
+		"/docker/c2": {"io.kubernetes.pod.namespace": "prod", "app": "db"},
+		"/docker/c3": {"io.kubernetes.pod.namespace": "dev", "app": "web"},
+	} {
+		m.containers[namespacedContainerName{Name: name}].info.Spec.Labels = labels
+	}
+
+	names := func(options v2.RequestOptions) []string {
+		conts, err := m.getRequestedContainers("/", options)
+		require.NoError(t, err)
+		result := []string{}
+		for name := range conts {
+			result = append(result, name)
+		}
+		sort.Strings(result)
+		return result
+	}
+	assert.Equal(t, []string{"/docker/c1", "/docker/c2"}, names(v2.RequestOptions{IdType: v2.TypeName, Recursive: true, LabelSelector: "io.kubernetes.pod.namespace=prod"}))
+	assert.Equal(t, []string{"/docker/c1"}, names(v2.RequestOptions{IdType: v2.TypeName, Recursive: true, LabelSelector: "io.kubernetes.pod.namespace=prod,app=web"}))
+	assert.Equal(t, []string{"/"}, names(v2.RequestOptions{IdType: v2.TypeName, Recursive: true, LabelSelector: "!app"}))
+	assert.Equal(t, []string{}, names(v2.RequestOptions{IdType: v2.TypeName, LabelSelector: "app"}))
+
+	_, err := m.getRequestedContainers("/", v2.RequestOptions{IdType: v2.TypeName, LabelSelector: "app in web"})
+	assert.Error(t, err)
+
+	assert.Equal(t, []string{"/docker/c1", "/docker/c3"}, names(v2.RequestOptions{IdType: v2.TypeName, Recursive: true, NameRegex: "/docker/c[13]"}))
+	assert.Equal(t, []string{"/docker/c3"}, names(v2.RequestOptions{IdType: v2.TypeName, Recursive: true, LabelSelector: "app=web", NameRegex: ".*c[23]"}))
+	assert.Equal(t, []string{}, names(v2.RequestOptions{IdType: v2.TypeName, Recursive: true, NameRegex: "c1"}))
+}
+
 func TestGetContainerInfoV2Failure(t *testing.T) {
 	successful := "/"
 	statless := "/c1"
@@ -531,3 +612,118 @@ func TestDockerContainersInfo(t *testing.T) {
 		t.Errorf("expected error %q but received %q", expectedError, err)
 	}
 }
+
+func TestProcessCensus(t *testing.T) {
+	containers := []string{
+		"/",
+		"/system.slice",
+		"/docker",
+		"/docker/c1",
+	}
+	memoryCache := memory.New(time.Duration(60)*time.Second, nil)
+	m := createManagerAndAddContainers(memoryCache, &fakesysfs.FakeSysFs{}, containers, func(h *containertest.MockContainerHandler) {
+		if strings.HasPrefix(h.Name, "/docker/") {
+			h.On("Type").Return(container.ContainerTypeDocker)
+		} else {
+			h.On("Type").Return(container.ContainerTypeRaw)
+		}
+	}, t)
+
+	ps := []v2.ProcessInfo{
+		{Pid: 1, CgroupPath: "/", PercentCpu: 0.5, PercentMemory: 0.1, RSS: 100},
+		{Pid: 10, CgroupPath: "/system.slice", PercentCpu: 1, PercentMemory: 0.2, RSS: 200},
+		{Pid: 20, CgroupPath: "/docker/c1", PercentCpu: 2, PercentMemory: 1, RSS: 1000},
+		{Pid: 21, CgroupPath: "/docker/c1/init.scope", PercentCpu: 3, PercentMemory: 2, RSS: 2000},
+	}
+	census := processCensus(ps, m.processOwner)
+
+	owners := []string{}
+	for _, p := range census.Processes {
+		owners = append(owners, p.Owner)
+	}
+	assert.Equal(t, []string{v2.CensusHost, v2.CensusHost, "/docker/c1", "/docker/c1"}, owners)
+	assert.Equal(t, map[string]v2.CensusUsage{
+		v2.CensusHost: {ProcessCount: 2, PercentCpu: 1.5, PercentMemory: 0.3, RSS: 300},
+		"/docker/c1":  {ProcessCount: 2, PercentCpu: 5, PercentMemory: 3, RSS: 3000},
+	}, census.Owners)
+}
+
+func TestMachineDecomposition(t *testing.T) {
+	containers := []string{
+		"/",
+		"/system.slice",
+		"/system.slice/docker-a.scope",
+		"/user.slice",
+		"/docker",
+		"/docker/c1",
+		"/docker/c1/nested",
+	}
+	memoryCache := memory.New(time.Duration(60)*time.Second, nil)
+	m := createManagerAndAddContainers(memoryCache, &fakesysfs.FakeSysFs{}, containers, func(h *containertest.MockContainerHandler) {
+		if strings.HasPrefix(h.Name, "/docker/") || strings.HasPrefix(h.Name, "/system.slice/docker-") {
+			h.On("Type").Return(container.ContainerTypeDocker)
+		} else {
+			h.On("Type").Return(container.ContainerTypeRaw)
+		}
+	}, t)
+
+	start := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	// CPU time used in a second and memory usage of each container.
+	usage := map[string]uint64{
+		"/":                            10000,
+		"/system.slice":                3000,
+		"/system.slice/docker-a.scope": 1000,
+		"/user.slice":                  500,
+		"/docker":                      4000,
+		"/docker/c1":                   4000,
+		"/docker/c1/nested":            1000,
+	}
+	for name, u := range usage {
+		cinfo := &info.ContainerInfo{ContainerReference: info.ContainerReference{Name: name}}
+		for i := uint64(0); i < 2; i++ {
+			stats := &info.ContainerStats{Timestamp: start.Add(time.Duration(i) * time.Second)}
+			stats.Cpu.Usage.Total = i * u
+			stats.Memory.Usage = u
+			stats.Memory.WorkingSet = u / 2
+			require.NoError(t, memoryCache.AddStats(cinfo, stats))
+		}
+	}
+
+	decomposition, err := m.machineDecomposition([]string{"system.slice", "user.slice", "missing.slice"})
+	require.NoError(t, err)
+	assert.Equal(t, start.Add(time.Second), decomposition.Timestamp)
+	assert.Equal(t, v2.DecompositionUsage{CpuNanoCores: 10000, MemoryUsage: 10000, MemoryWorkingSet: 5000}, decomposition.Machine)
+	assert.Equal(t, map[string]v2.DecompositionUsage{
+		v2.DecompositionContainers:  {CpuNanoCores: 5000, MemoryUsage: 5000, MemoryWorkingSet: 2500},
+		"system.slice":              {CpuNanoCores: 2000, MemoryUsage: 2000, MemoryWorkingSet: 1000},
+		"user.slice":                {CpuNanoCores: 500, MemoryUsage: 500, MemoryWorkingSet: 250},
+		v2.DecompositionUnaccounted: {CpuNanoCores: 2500, MemoryUsage: 2500, MemoryWorkingSet: 1250},
+	}, decomposition.Buckets)
+}
+
+type fakeContainerWatcher struct {
+	err error
+}
+
+func (w *fakeContainerWatcher) Start(chan watcher.ContainerEvent) error { return nil }
+func (w *fakeContainerWatcher) Stop() error                             { return nil }
+func (w *fakeContainerWatcher) Healthy() error                          { return w.err }
+
+func TestHealth(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Now())
+	m := &manager{
+		clock:             fakeClock,
+		containerWatchers: []watcher.ContainerWatcher{&fakeContainerWatcher{err: fmt.Errorf("queue overflow")}},
+	}
+	// The watchers are not reported before the housekeeping runs.
+	assert.Equal(t, Health{GlobalHousekeepingInterval: *globalHousekeepingInterval}, m.Health())
+
+	m.setHousekeeping(true, nil)
+	fakeClock.Step(time.Minute)
+	m.setHousekeeping(true, fmt.Errorf("no cgroups"))
+	health := m.Health()
+	assert.True(t, health.Housekeeping)
+	assert.Equal(t, fakeClock.Now(), health.LastGlobalHousekeeping)
+	assert.EqualError(t, health.LastGlobalHousekeepingError, "no cgroups")
+	assert.Equal(t, []WatcherHealth{{Name: "manager.fakeContainerWatcher", Err: fmt.Errorf("queue overflow")}}, health.Watchers)
+}
@@ -0,0 +1,85 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"flag"
+	"os"
+	"strings"
+
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+
+	"k8s.io/klog/v2"
+)
+
+var bootIDStateFile = flag.String("boot_id_state_file", "", "Path to a file to which the boot id of the machine is saved, so that a machineRebooted event is added when cAdvisor starts after the machine rebooted, and not when cAdvisor alone restarted. Empty value only detects the reboots while cAdvisor runs.")
+
+// loadBootID returns the boot id saved to path, empty if the file does not
+// exist.
+func loadBootID(path string) (string, error) {
+	out, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// detectReboot adds a machineRebooted event if the boot id of the machine
+// differs from the one saved to path by the previous run of cAdvisor.
+func (m *manager) detectReboot(path string) {
+	previous, err := loadBootID(path)
+	if err != nil {
+		klog.Warningf("Could not read the previous boot id: %v", err)
+	}
+	m.machineMu.RLock()
+	machineInfo := m.machineInfo.Clone()
+	m.machineMu.RUnlock()
+	m.bootIDChanged(previous, machineInfo, path)
+}
+
+// bootIDChanged adds a machineRebooted event if the boot id of machineInfo
+// differs from previous, both being known, and saves it to path if set.
+func (m *manager) bootIDChanged(previous string, machineInfo *info.MachineInfo, path string) {
+	if machineInfo.BootID == "" || machineInfo.BootID == previous {
+		return
+	}
+	if path != "" {
+		if err := writeFileAtomic(path, []byte(machineInfo.BootID+"\n")); err != nil {
+			klog.Warningf("Failed to save the boot id to %q: %v", path, err)
+		}
+	}
+	if previous == "" {
+		return
+	}
+	klog.Infof("Machine rebooted at %s: boot id changed from %q to %q", machineInfo.BootTime, previous, machineInfo.BootID)
+	err := m.eventHandler.AddEvent(&info.Event{
+		ContainerName: "/",
+		Timestamp:     m.clock.Now(),
+		EventType:     info.EventMachineRebooted,
+		EventData: info.EventData{
+			MachineRebooted: &info.MachineRebootedEventData{
+				PreviousBootID: previous,
+				BootID:         machineInfo.BootID,
+				BootTime:       machineInfo.BootTime,
+			},
+		},
+	})
+	if err != nil {
+		klog.Errorf("Failed to add machine rebooted event: %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	clock "k8s.io/utils/clock/testing"
+
+	"github.com/yidoyoon/cadvisor-lite/events"
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+)
+
+func rebootEvents(t *testing.T, m *manager) []*info.Event {
+	request := events.NewRequest()
+	request.EventType[info.EventMachineRebooted] = true
+	request.IncludeSubcontainers = true
+	request.ContainerName = "/"
+	evts, err := m.eventHandler.GetEvents(request)
+	require.NoError(t, err)
+	return evts
+}
+
+func TestDetectReboot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "boot_id")
+	bootTime := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	newManager := func(bootID string) *manager {
+		return &manager{
+			clock:        clock.NewFakeClock(bootTime.Add(time.Minute)),
+			eventHandler: events.NewEventManager(events.DefaultStoragePolicy()),
+			machineInfo:  info.MachineInfo{BootID: bootID, BootTime: bootTime},
+		}
+	}
+
+	// The first run has nothing to compare with.
+	m := newManager("boot-1")
+	m.detectReboot(path)
+	assert.Empty(t, rebootEvents(t, m))
+	saved, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "boot-1\n", string(saved))
+
+	// cAdvisor restarted without the machine rebooting.
+	m = newManager("boot-1")
+	m.detectReboot(path)
+	assert.Empty(t, rebootEvents(t, m))
+
+	m = newManager("boot-2")
+	m.detectReboot(path)
+	evts := rebootEvents(t, m)
+	require.Len(t, evts, 1)
+	assert.Equal(t, "/", evts[0].ContainerName)
+	assert.Equal(t, bootTime.Add(time.Minute), evts[0].Timestamp)
+	assert.Equal(t, &info.MachineRebootedEventData{PreviousBootID: "boot-1", BootID: "boot-2", BootTime: bootTime}, evts[0].EventData.MachineRebooted)
+	saved, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "boot-2\n", string(saved))
+
+	// Unknown boot ids are ignored.
+	m = newManager("")
+	m.detectReboot(path)
+	assert.Empty(t, rebootEvents(t, m))
+}
@@ -0,0 +1,121 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/yidoyoon/cadvisor-lite/summary"
+
+	"k8s.io/klog/v2"
+)
+
+var summaryStateFile = flag.String("summary_state_file", "", "Path to a file to which the usage summaries of the containers are saved periodically and on exit, and from which they are restored at startup. Empty value disables saving them.")
+var summaryStateInterval = flag.Duration("summary_state_interval", 5*time.Minute, "Interval between saves of the usage summaries of the containers to --summary_state_file.")
+var summaryDuration = flag.Duration("summary_duration", time.Hour, "Duration of the minute samples kept in the usage summaries of the containers, over which the summary API computes the usage of the requested windows. Values below an hour are raised to an hour.")
+
+// loadSummaryStates returns the summary states saved to path by container
+// name, none if the file does not exist.
+func loadSummaryStates(path string) (map[string]summary.State, error) {
+	out, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	states := make(map[string]summary.State)
+	if err := json.Unmarshal(out, &states); err != nil {
+		return nil, fmt.Errorf("failed to parse summary states %q: %v", path, err)
+	}
+	return states, nil
+}
+
+// saveSummaryStates saves the summary states of the containers to path.
+func saveSummaryStates(path string, states map[string]summary.State) error {
+	out, err := json.Marshal(states)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, out)
+}
+
+// writeFileAtomic writes out to path. The file is replaced atomically so that
+// it is never partially written.
+func writeFileAtomic(path string, out []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(out); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// summaryStates returns the summary states of the containers by name.
+func (m *manager) summaryStates() map[string]summary.State {
+	m.containersLock.RLock()
+	defer m.containersLock.RUnlock()
+	states := make(map[string]summary.State)
+	for name, cont := range m.containers {
+		// Skip the aliases.
+		if name.Namespace != "" || cont.summaryReader == nil {
+			continue
+		}
+		states[name.Name] = cont.summaryReader.State()
+	}
+	return states
+}
+
+// restoreSummary restores the saved summary of a new container, if any.
+func (m *manager) restoreSummary(cont *containerData) {
+	state, ok := m.restoredSummaries[cont.info.Name]
+	if !ok || cont.summaryReader == nil {
+		return
+	}
+	delete(m.restoredSummaries, cont.info.Name)
+	if err := cont.summaryReader.Restore(state); err != nil {
+		klog.V(4).Infof("Failed to restore the summary of %q: %v", cont.info.Name, err)
+	}
+}
+
+// saveSummaries saves the summaries of the containers to path every interval
+// and once more when quit is signaled.
+func (m *manager) saveSummaries(path string, interval time.Duration, quit chan error) {
+	ticker := m.clock.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C():
+			if err := saveSummaryStates(path, m.summaryStates()); err != nil {
+				klog.Warningf("Failed to save the usage summaries to %q: %v", path, err)
+			}
+		case <-quit:
+			quit <- saveSummaryStates(path, m.summaryStates())
+			return
+		}
+	}
+}
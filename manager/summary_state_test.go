@@ -0,0 +1,68 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yidoyoon/cadvisor-lite/cache/memory"
+	containertest "github.com/yidoyoon/cadvisor-lite/container/testing"
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+	v2 "github.com/yidoyoon/cadvisor-lite/info/v2"
+	"github.com/yidoyoon/cadvisor-lite/summary"
+)
+
+func TestLoadSummaryStatesMissingFile(t *testing.T) {
+	states, err := loadSummaryStates(filepath.Join(t.TempDir(), "summaries.json"))
+	assert.NoError(t, err)
+	assert.Empty(t, states)
+}
+
+func TestSummaryStatesSaveAndRestore(t *testing.T) {
+	containers := []string{"/", "/docker/c1"}
+	m := createManagerAndAddContainers(memory.New(time.Minute, nil), nil, containers, func(*containertest.MockContainerHandler) {}, t)
+	sample := v2.Usage{PercentComplete: 100, Cpu: v2.Percentiles{Present: true, Mean: 10, Max: 10}}
+	saved := summary.State{Timestamp: time.Now(), MinuteSamples: []v2.Usage{sample}}
+	for _, name := range containers {
+		cont := m.containers[namespacedContainerName{Name: name}]
+		var err error
+		cont.summaryReader, err = summary.New(info.ContainerSpec{HasCpu: true}, time.Hour)
+		require.NoError(t, err)
+		require.NoError(t, cont.summaryReader.Restore(saved))
+	}
+
+	path := filepath.Join(t.TempDir(), "summaries.json")
+	require.NoError(t, saveSummaryStates(path, m.summaryStates()))
+	states, err := loadSummaryStates(path)
+	require.NoError(t, err)
+	// The alias of the Docker container is not saved.
+	require.Len(t, states, 2)
+	assert.Equal(t, []v2.Usage{sample}, states["/docker/c1"].MinuteSamples)
+
+	cont := m.containers[namespacedContainerName{Name: "/docker/c1"}]
+	cont.summaryReader, err = summary.New(info.ContainerSpec{HasCpu: true}, time.Hour)
+	require.NoError(t, err)
+	m.restoredSummaries = states
+	m.restoreSummary(cont)
+	assert.NotContains(t, m.restoredSummaries, "/docker/c1")
+	derived, err := cont.summaryReader.DerivedStats()
+	require.NoError(t, err)
+	assert.Equal(t, sample, derived.MinuteUsage)
+}
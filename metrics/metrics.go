@@ -19,6 +19,8 @@ import (
 
 	info "github.com/yidoyoon/cadvisor-lite/info/v1"
 	v2 "github.com/yidoyoon/cadvisor-lite/info/v2"
+
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 // metricValue describes a single metric value for a given set of label values
@@ -27,6 +29,9 @@ type metricValue struct {
 	value     float64
 	labels    []string
 	timestamp time.Time
+	// Exemplar of a counter, e.g. the event of its latest increment. Only
+	// exposed in the OpenMetrics format.
+	exemplar *prometheus.Exemplar
 }
 
 type metricValues []metricValue
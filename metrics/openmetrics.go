@@ -0,0 +1,108 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+const (
+	counterSuffix = "_total"
+	createdSuffix = "_created"
+)
+
+// WriteOpenMetrics writes the metric families in the OpenMetrics text format,
+// followed by the final # EOF line. Unlike expfmt, it writes the _created
+// series of the counters with a created timestamp, after the series of each
+// counter. The exemplars of the counters are written if exemplars is set,
+// and dropped otherwise.
+func WriteOpenMetrics(w io.Writer, families []*dto.MetricFamily, exemplars bool) error {
+	for _, family := range families {
+		if err := writeOpenMetricsFamily(w, family, exemplars); err != nil {
+			return err
+		}
+	}
+	_, err := expfmt.FinalizeOpenMetrics(w)
+	return err
+}
+
+func writeOpenMetricsFamily(w io.Writer, family *dto.MetricFamily, exemplars bool) error {
+	created := false
+	for _, metric := range family.Metric {
+		if metric.Counter == nil {
+			continue
+		}
+		// The metrics are gathered anew for each request, so they are
+		// modified in place.
+		if !exemplars {
+			metric.Counter.Exemplar = nil
+		}
+		created = created || metric.Counter.CreatedTimestamp != nil
+	}
+	// The _created series are only valid for the counters named with the
+	// _total suffix, the other ones are of the unknown type.
+	name := family.GetName()
+	if !created || family.GetType() != dto.MetricType_COUNTER || !strings.HasSuffix(name, counterSuffix) {
+		_, err := expfmt.MetricFamilyToOpenMetrics(w, family)
+		return err
+	}
+
+	// expfmt writes the HELP and TYPE lines, then one line per metric, as
+	// the line breaks of the help and of the label values are escaped.
+	var buf bytes.Buffer
+	if _, err := expfmt.MetricFamilyToOpenMetrics(&buf, family); err != nil {
+		return err
+	}
+	lines := strings.SplitAfter(buf.String(), "\n")
+	lines = lines[:len(lines)-1]
+	comments := len(lines) - len(family.Metric)
+	if _, err := io.WriteString(w, strings.Join(lines[:comments], "")); err != nil {
+		return err
+	}
+	// The _created lines are written as the samples of a gauge, for expfmt
+	// to format their labels.
+	createdName := strings.TrimSuffix(name, counterSuffix) + createdSuffix
+	createdFamily := &dto.MetricFamily{Name: &createdName, Type: dto.MetricType_GAUGE.Enum()}
+	for i, metric := range family.Metric {
+		if _, err := io.WriteString(w, lines[comments+i]); err != nil {
+			return err
+		}
+		ct := metric.Counter.GetCreatedTimestamp()
+		if ct == nil {
+			continue
+		}
+		seconds := float64(ct.AsTime().UnixNano()) / 1e9
+		createdFamily.Metric = []*dto.Metric{{
+			Label:       metric.Label,
+			Gauge:       &dto.Gauge{Value: &seconds},
+			TimestampMs: metric.TimestampMs,
+		}}
+		buf.Reset()
+		if _, err := expfmt.MetricFamilyToOpenMetrics(&buf, createdFamily); err != nil {
+			return err
+		}
+		// The sample follows the TYPE line.
+		sample := buf.String()
+		if _, err := io.WriteString(w, sample[strings.Index(sample, "\n")+1:]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
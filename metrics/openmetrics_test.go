@@ -0,0 +1,99 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/yidoyoon/cadvisor-lite/container"
+	v2 "github.com/yidoyoon/cadvisor-lite/info/v2"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type constCollector []prometheus.Metric
+
+func (c constCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(c, ch)
+}
+
+func (c constCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, m := range c {
+		ch <- m
+	}
+}
+
+func TestWriteOpenMetrics(t *testing.T) {
+	created := time.Unix(1257894000, 0)
+	requests := prometheus.NewDesc("test_requests_total", "Requests.", []string{"path"}, nil)
+	legacy := prometheus.NewDesc("test_legacy", "Counter without suffix.", nil, nil)
+	temperature := prometheus.NewDesc("test_temperature", "Temperature.", nil, nil)
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(constCollector{
+		prometheus.MustNewMetricWithExemplars(
+			prometheus.MustNewConstMetricWithCreatedTimestamp(requests, prometheus.CounterValue, 3, created, "/a\n\"b\""),
+			prometheus.Exemplar{Value: 1, Labels: prometheus.Labels{"event_id": "4"}, Timestamp: time.Unix(1395066300, 0)},
+		),
+		prometheus.MustNewConstMetric(requests, prometheus.CounterValue, 2, "/c"),
+		prometheus.MustNewConstMetricWithCreatedTimestamp(legacy, prometheus.CounterValue, 1, created),
+		prometheus.MustNewConstMetric(temperature, prometheus.GaugeValue, 21.5),
+	})
+	families, err := reg.Gather()
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteOpenMetrics(&buf, families, true))
+	assert.Equal(t, `# HELP test_legacy Counter without suffix.
+# TYPE test_legacy unknown
+test_legacy 1.0
+# HELP test_requests Requests.
+# TYPE test_requests counter
+test_requests_total{path="/a\n\"b\""} 3.0 # {event_id="4"} 1.0 1.3950663e+09
+test_requests_created{path="/a\n\"b\""} 1.257894e+09
+test_requests_total{path="/c"} 2.0
+# HELP test_temperature Temperature.
+# TYPE test_temperature gauge
+test_temperature 21.5
+# EOF
+`, buf.String())
+
+	// The exemplars are dropped unless requested.
+	families, err = reg.Gather()
+	require.NoError(t, err)
+	buf.Reset()
+	require.NoError(t, WriteOpenMetrics(&buf, families, false))
+	assert.Contains(t, buf.String(), "test_requests_total{path=\"/a\\n\\\"b\\\"\"} 3.0\ntest_requests_created")
+	assert.NotContains(t, buf.String(), "event_id")
+}
+
+func TestPrometheusCollectorOpenMetrics(t *testing.T) {
+	c := NewPrometheusCollector(testSubcontainersInfoProvider{}, nil, container.MetricSet{container.OOMMetrics: struct{}{}}, now, v2.RequestOptions{}, nil)
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(c)
+	families, err := reg.Gather()
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteOpenMetrics(&buf, families, true))
+	// The counters of the containers start with their last start, and the
+	// OOM counter links to the event of the latest OOM.
+	labels := `{container_env_foo_env="prod",container_label_foo_label="bar",id="testcontainer",image="test",name="testcontaineralias"}`
+	assert.Contains(t, buf.String(), "container_oom_events_total"+labels+` 1.0 1.395066363e+09 # {event_id="7"} 1.0 1.3950663e+09
+container_oom_events_created`+labels+" 1.2578940002499998e+09 1.395066363e+09\n")
+}
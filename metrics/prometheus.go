@@ -51,6 +51,35 @@ func fsValues(fsStats []info.FsStats, valueFn func(*info.FsStats) float64, times
 	return values
 }
 
+// nonZeroValues drops the zero values, e.g. of the quotas of the filesystems
+// without one.
+func nonZeroValues(values metricValues) metricValues {
+	nonZero := values[:0]
+	for _, v := range values {
+		if v.value != 0 {
+			nonZero = append(nonZero, v)
+		}
+	}
+	return nonZero
+}
+
+// volumeValues is a helper method for assembling per-volume stats.
+func volumeValues(s *info.ContainerStats, valueFn func(*info.FsStats) float64) metricValues {
+	values := make(metricValues, 0, len(s.Volumes))
+	for _, stat := range s.Volumes {
+		var volume info.FsVolume
+		if stat.Volume != nil {
+			volume = *stat.Volume
+		}
+		values = append(values, metricValue{
+			value:     valueFn(&stat),
+			labels:    []string{stat.Device, volume.Name, volume.Source, volume.Destination},
+			timestamp: s.Timestamp,
+		})
+	}
+	return values
+}
+
 // ioValues is a helper method for assembling per-disk and per-filesystem stats.
 func ioValues(ioStats []info.PerDiskStats, ioType string, ioValueFn func(uint64) float64,
 	fsStats []info.FsStats, valueFn func(*info.FsStats) float64, timestamp time.Time) metricValues {
@@ -80,8 +109,11 @@ type containerMetric struct {
 	help        string
 	valueType   prometheus.ValueType
 	extraLabels []string
-	condition   func(s info.ContainerSpec) bool
-	getValues   func(s *info.ContainerStats) metricValues
+	// Kind of the metric, which is not exported for the containers whose
+	// metrics of that kind are disabled.
+	kind      container.MetricKind
+	condition func(s info.ContainerSpec) bool
+	getValues func(s *info.ContainerStats) metricValues
 }
 
 func (cm *containerMetric) desc(baseLabels []string) *prometheus.Desc {
@@ -89,9 +121,17 @@ func (cm *containerMetric) desc(baseLabels []string) *prometheus.Desc {
 }
 
 // ContainerLabelsFunc defines all base labels and their values attached to
-// each metric exported by cAdvisor.
+// each metric exported by cAdvisor. The labels of a container are cached
+// until it is recreated, i.e. until its uid or creation time change, so they
+// must only depend on its name, aliases and spec.
 type ContainerLabelsFunc func(*info.ContainerInfo) map[string]string
 
+// ContainerLabelFilter selects containers by the values of their labels,
+// keyed by the names of the labels of their metrics, e.g.
+// container_label_io_kubernetes_pod_name. A container is selected if it has
+// one of the values of each label.
+type ContainerLabelFilter map[string][]string
+
 // PrometheusCollector implements prometheus.Collector.
 type PrometheusCollector struct {
 	infoProvider        infoProvider
@@ -100,19 +140,23 @@ type PrometheusCollector struct {
 	containerLabelsFunc ContainerLabelsFunc
 	includedMetrics     container.MetricSet
 	opts                v2.RequestOptions
+	labelFilter         ContainerLabelFilter
+	labelsCache         *LabelsCache
 }
 
 // NewPrometheusCollector returns a new PrometheusCollector. The passed
 // ContainerLabelsFunc specifies which base labels will be attached to all
 // exported metrics. If left to nil, the DefaultContainerLabels function
-// will be used instead.
-func NewPrometheusCollector(i infoProvider, f ContainerLabelsFunc, includedMetrics container.MetricSet, now clock.Clock, opts v2.RequestOptions) *PrometheusCollector {
+// will be used instead. Only the containers selected by labelFilter are
+// exported, all of them if it is empty.
+func NewPrometheusCollector(i infoProvider, f ContainerLabelsFunc, includedMetrics container.MetricSet, now clock.Clock, opts v2.RequestOptions, labelFilter ContainerLabelFilter) *PrometheusCollector {
 	if f == nil {
 		f = DefaultContainerLabels
 	}
 	c := &PrometheusCollector{
 		infoProvider:        i,
 		containerLabelsFunc: f,
+		labelFilter:         labelFilter,
 		errors: prometheus.NewGauge(prometheus.GaugeOpts{
 			Namespace: "container",
 			Name:      "scrape_error",
@@ -130,12 +174,29 @@ func NewPrometheusCollector(i infoProvider, f ContainerLabelsFunc, includedMetri
 					}}
 				},
 			},
+			{
+				name:      "container_stats_sequence",
+				help:      "Sequence number of the latest stats sample of the container",
+				valueType: prometheus.CounterValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{{value: float64(s.Sequence), timestamp: s.Timestamp}}
+				},
+			},
+			{
+				name:      "container_stats_timestamp_skew_seconds",
+				help:      "Time between the timestamp of the latest stats sample of the container and the moment it was stored",
+				valueType: prometheus.GaugeValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{{value: s.TimestampSkew.Seconds(), timestamp: s.Timestamp}}
+				},
+			},
 		},
 		includedMetrics: includedMetrics,
 		opts:            opts,
+		labelsCache:     NewLabelsCache(),
 	}
 	if includedMetrics.Has(container.CpuUsageMetrics) {
-		c.containerMetrics = append(c.containerMetrics, []containerMetric{
+		c.addMetrics(container.CpuUsageMetrics, []containerMetric{
 			{
 				name:      "container_cpu_user_seconds_total",
 				help:      "Cumulative user cpu time consumed in seconds.",
@@ -227,7 +288,7 @@ func NewPrometheusCollector(i infoProvider, f ContainerLabelsFunc, includedMetri
 		}...)
 	}
 	if includedMetrics.Has(container.ProcessSchedulerMetrics) {
-		c.containerMetrics = append(c.containerMetrics, []containerMetric{
+		c.addMetrics(container.ProcessSchedulerMetrics, []containerMetric{
 			{
 				name:      "container_cpu_schedstat_run_seconds_total",
 				help:      "Time duration the processes of the container have run on the CPU.",
@@ -262,7 +323,7 @@ func NewPrometheusCollector(i infoProvider, f ContainerLabelsFunc, includedMetri
 		}...)
 	}
 	if includedMetrics.Has(container.CpuLoadMetrics) {
-		c.containerMetrics = append(c.containerMetrics, []containerMetric{
+		c.addMetrics(container.CpuLoadMetrics, []containerMetric{
 			{
 				name:      "container_cpu_load_average_10s",
 				help:      "Value of container cpu load average over the last 10 seconds.",
@@ -308,7 +369,7 @@ func NewPrometheusCollector(i infoProvider, f ContainerLabelsFunc, includedMetri
 		}...)
 	}
 	if includedMetrics.Has(container.HugetlbUsageMetrics) {
-		c.containerMetrics = append(c.containerMetrics, []containerMetric{
+		c.addMetrics(container.HugetlbUsageMetrics, []containerMetric{
 			{
 				name:        "container_hugetlb_failcnt",
 				help:        "Number of hugepage usage hits limits",
@@ -362,7 +423,7 @@ func NewPrometheusCollector(i infoProvider, f ContainerLabelsFunc, includedMetri
 		}...)
 	}
 	if includedMetrics.Has(container.MemoryUsageMetrics) {
-		c.containerMetrics = append(c.containerMetrics, []containerMetric{
+		c.addMetrics(container.MemoryUsageMetrics, []containerMetric{
 			{
 				name:      "container_memory_cache",
 				help:      "Number of bytes of page cache memory.",
@@ -384,6 +445,38 @@ func NewPrometheusCollector(i infoProvider, f ContainerLabelsFunc, includedMetri
 				getValues: func(s *info.ContainerStats) metricValues {
 					return metricValues{{value: float64(s.Memory.KernelUsage), timestamp: s.Timestamp}}
 				},
+			}, {
+				name:        "container_memory_kernel_bytes",
+				help:        "Size of kernel memory allocated in bytes, by type of allocation.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{"type"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					k := s.Memory.Kernel
+					return metricValues{
+						{value: float64(k.KernelStack), labels: []string{"kernel_stack"}, timestamp: s.Timestamp},
+						{value: float64(k.PageTables), labels: []string{"page_tables"}, timestamp: s.Timestamp},
+						{value: float64(k.Percpu), labels: []string{"percpu"}, timestamp: s.Timestamp},
+						{value: float64(k.Sock), labels: []string{"sock"}, timestamp: s.Timestamp},
+						{value: float64(k.SlabReclaimable), labels: []string{"slab_reclaimable"}, timestamp: s.Timestamp},
+						{value: float64(k.SlabUnreclaimable), labels: []string{"slab_unreclaimable"}, timestamp: s.Timestamp},
+					}
+				},
+			}, {
+				name:        "container_memory_slab_cache_bytes",
+				help:        "Size of the slab caches of the machine using the most memory in bytes. Only reported for the root container.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{"cache"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					values := make(metricValues, 0, len(s.Memory.Kernel.TopSlabCaches))
+					for _, cache := range s.Memory.Kernel.TopSlabCaches {
+						values = append(values, metricValue{
+							value:     float64(cache.Size),
+							labels:    []string{cache.Name},
+							timestamp: s.Timestamp,
+						})
+					}
+					return values
+				},
 			}, {
 				name:      "container_memory_mapped_file",
 				help:      "Size of memory mapped files in bytes.",
@@ -464,7 +557,7 @@ func NewPrometheusCollector(i infoProvider, f ContainerLabelsFunc, includedMetri
 		}...)
 	}
 	if includedMetrics.Has(container.CPUSetMetrics) {
-		c.containerMetrics = append(c.containerMetrics, containerMetric{
+		c.addMetrics(container.CPUSetMetrics, containerMetric{
 			name:      "container_memory_migrate",
 			help:      "Memory migrate status.",
 			valueType: prometheus.GaugeValue,
@@ -474,7 +567,7 @@ func NewPrometheusCollector(i infoProvider, f ContainerLabelsFunc, includedMetri
 		})
 	}
 	if includedMetrics.Has(container.MemoryNumaMetrics) {
-		c.containerMetrics = append(c.containerMetrics, []containerMetric{
+		c.addMetrics(container.MemoryNumaMetrics, []containerMetric{
 			{
 				name:        "container_memory_numa_pages",
 				help:        "Number of used pages per NUMA node",
@@ -501,7 +594,7 @@ func NewPrometheusCollector(i infoProvider, f ContainerLabelsFunc, includedMetri
 		}...)
 	}
 	if includedMetrics.Has(container.DiskUsageMetrics) {
-		c.containerMetrics = append(c.containerMetrics, []containerMetric{
+		c.addMetrics(container.DiskUsageMetrics, []containerMetric{
 			{
 				name:        "container_fs_inodes_free",
 				help:        "Number of available Inodes",
@@ -532,6 +625,26 @@ func NewPrometheusCollector(i infoProvider, f ContainerLabelsFunc, includedMetri
 						return float64(fs.Limit)
 					}, s.Timestamp)
 				},
+			}, {
+				name:        "container_fs_log_usage_bytes",
+				help:        "Number of bytes used by the log file of the container and the files it was rotated to, for the containers whose runtime logs to a file.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{"device"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					return nonZeroValues(fsValues(s.Filesystem, func(fs *info.FsStats) float64 {
+						return float64(fs.LogUsage)
+					}, s.Timestamp))
+				},
+			}, {
+				name:        "container_fs_quota_bytes",
+				help:        "Number of bytes that can be consumed by the container on this filesystem under a quota, e.g. of the size of its writable layer. Only reported for the filesystems with a quota.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{"device"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					return nonZeroValues(fsValues(s.Filesystem, func(fs *info.FsStats) float64 {
+						return float64(fs.Quota)
+					}, s.Timestamp))
+				},
 			}, {
 				name:        "container_fs_usage_bytes",
 				help:        "Number of bytes that are consumed by the container on this filesystem.",
@@ -542,11 +655,51 @@ func NewPrometheusCollector(i infoProvider, f ContainerLabelsFunc, includedMetri
 						return float64(fs.Usage)
 					}, s.Timestamp)
 				},
+			}, {
+				name:        "container_volume_usage_bytes",
+				help:        "Number of bytes used by a named volume or bind mount of the container.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{"device", "volume", "source", "destination"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					return volumeValues(s, func(fs *info.FsStats) float64 {
+						return float64(fs.Usage)
+					})
+				},
+			}, {
+				name:        "container_volume_inodes",
+				help:        "Number of inodes used by a named volume or bind mount of the container.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{"device", "volume", "source", "destination"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					return volumeValues(s, func(fs *info.FsStats) float64 {
+						return float64(fs.Inodes)
+					})
+				},
+			}, {
+				name:        "container_volume_limit_bytes",
+				help:        "Number of bytes of the filesystem of a named volume or bind mount of the container.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{"device", "volume", "source", "destination"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					return volumeValues(s, func(fs *info.FsStats) float64 {
+						return float64(fs.Limit)
+					})
+				},
+			}, {
+				name:        "container_volume_quota_bytes",
+				help:        "Number of bytes that can be used by a named volume or bind mount of the container under a quota. Only reported for the volumes with a quota.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{"device", "volume", "source", "destination"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					return nonZeroValues(volumeValues(s, func(fs *info.FsStats) float64 {
+						return float64(fs.Quota)
+					}))
+				},
 			},
 		}...)
 	}
 	if includedMetrics.Has(container.DiskIOMetrics) {
-		c.containerMetrics = append(c.containerMetrics, []containerMetric{
+		c.addMetrics(container.DiskIOMetrics, []containerMetric{
 			{
 				name:        "container_fs_reads_bytes_total",
 				help:        "Cumulative count of bytes read",
@@ -747,7 +900,7 @@ func NewPrometheusCollector(i infoProvider, f ContainerLabelsFunc, includedMetri
 		}...)
 	}
 	if includedMetrics.Has(container.NetworkUsageMetrics) {
-		c.containerMetrics = append(c.containerMetrics, []containerMetric{
+		c.addMetrics(container.NetworkUsageMetrics, []containerMetric{
 			{
 				name:        "container_network_receive_bytes_total",
 				help:        "Cumulative count of bytes received",
@@ -880,7 +1033,7 @@ func NewPrometheusCollector(i infoProvider, f ContainerLabelsFunc, includedMetri
 		}...)
 	}
 	if includedMetrics.Has(container.NetworkTcpUsageMetrics) {
-		c.containerMetrics = append(c.containerMetrics, []containerMetric{
+		c.addMetrics(container.NetworkTcpUsageMetrics, []containerMetric{
 			{
 				name:        "container_network_tcp_usage_total",
 				help:        "tcp connection usage statistic for container",
@@ -947,7 +1100,7 @@ func NewPrometheusCollector(i infoProvider, f ContainerLabelsFunc, includedMetri
 				},
 			},
 		}...)
-		c.containerMetrics = append(c.containerMetrics, []containerMetric{
+		c.addMetrics(container.NetworkTcpUsageMetrics, []containerMetric{
 			{
 				name:        "container_network_tcp6_usage_total",
 				help:        "tcp6 connection usage statistic for container",
@@ -1016,7 +1169,7 @@ func NewPrometheusCollector(i infoProvider, f ContainerLabelsFunc, includedMetri
 		}...)
 	}
 	if includedMetrics.Has(container.NetworkAdvancedTcpUsageMetrics) {
-		c.containerMetrics = append(c.containerMetrics, []containerMetric{
+		c.addMetrics(container.NetworkAdvancedTcpUsageMetrics, []containerMetric{
 			{
 				name:        "container_network_advance_tcp_stats_total",
 				help:        "advance tcp connections statistic for container",
@@ -1427,7 +1580,7 @@ func NewPrometheusCollector(i infoProvider, f ContainerLabelsFunc, includedMetri
 		}...)
 	}
 	if includedMetrics.Has(container.NetworkUdpUsageMetrics) {
-		c.containerMetrics = append(c.containerMetrics, []containerMetric{
+		c.addMetrics(container.NetworkUdpUsageMetrics, []containerMetric{
 			{
 				name:        "container_network_udp6_usage_total",
 				help:        "udp6 connection usage statistic for container",
@@ -1459,7 +1612,7 @@ func NewPrometheusCollector(i infoProvider, f ContainerLabelsFunc, includedMetri
 				},
 			},
 		}...)
-		c.containerMetrics = append(c.containerMetrics, []containerMetric{
+		c.addMetrics(container.NetworkUdpUsageMetrics, []containerMetric{
 			{
 				name:        "container_network_udp_usage_total",
 				help:        "udp connection usage statistic for container",
@@ -1492,8 +1645,141 @@ func NewPrometheusCollector(i infoProvider, f ContainerLabelsFunc, includedMetri
 			},
 		}...)
 	}
+	if includedMetrics.Has(container.NetworkConntrackMetrics) {
+		c.addMetrics(container.NetworkConntrackMetrics, []containerMetric{
+			{
+				name:      "container_network_conntrack_entries",
+				help:      "Number of connection tracking entries of the network namespace of the container. The entries of the root container are the ones of the host network namespace.",
+				valueType: prometheus.GaugeValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{{value: float64(s.Network.Conntrack.Entries), timestamp: s.Timestamp}}
+				},
+			}, {
+				name:      "container_network_conntrack_entries_limit",
+				help:      "Maximum number of connection tracking entries (nf_conntrack_max), the same for all the network namespaces of the host.",
+				valueType: prometheus.GaugeValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{{value: float64(s.Network.Conntrack.Max), timestamp: s.Timestamp}}
+				},
+			}, {
+				name:        "container_network_conntrack_failures_total",
+				help:        "Cumulative count of connection tracking failures of the network namespace of the container, by kind: insert_failed (entry could not be inserted), drop (packet dropped because of a failed insertion) or early_drop (entry evicted because the table was full).",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{"kind"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{
+						{
+							value:     float64(s.Network.Conntrack.InsertFailed),
+							labels:    []string{"insert_failed"},
+							timestamp: s.Timestamp,
+						},
+						{
+							value:     float64(s.Network.Conntrack.Drop),
+							labels:    []string{"drop"},
+							timestamp: s.Timestamp,
+						},
+						{
+							value:     float64(s.Network.Conntrack.EarlyDrop),
+							labels:    []string{"early_drop"},
+							timestamp: s.Timestamp,
+						},
+					}
+				},
+			},
+		}...)
+	}
+	if includedMetrics.Has(container.NetfilterMetrics) {
+		c.addMetrics(container.NetfilterMetrics, []containerMetric{
+			{
+				name:        "container_network_netfilter_packets_total",
+				help:        "Cumulative count of packets matched by a rule of a netfilter chain of the machine, by position of the rule in the chain, or to which the policy of the chain was applied (rule \"policy\"). Only reported for the root container.",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{"family", "table", "chain", "rule"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					return getNetfilterValues(s, func(c info.NetfilterChain) uint64 {
+						return c.PolicyPackets
+					}, func(r info.NetfilterRule) uint64 {
+						return r.Packets
+					})
+				},
+			}, {
+				name:        "container_network_netfilter_bytes_total",
+				help:        "Cumulative count of bytes matched by a rule of a netfilter chain of the machine, by position of the rule in the chain, or to which the policy of the chain was applied (rule \"policy\"). Only reported for the root container.",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{"family", "table", "chain", "rule"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					return getNetfilterValues(s, func(c info.NetfilterChain) uint64 {
+						return c.PolicyBytes
+					}, func(r info.NetfilterRule) uint64 {
+						return r.Bytes
+					})
+				},
+			},
+		}...)
+	}
+	if includedMetrics.Has(container.NetworkTcMetrics) {
+		c.addMetrics(container.NetworkTcMetrics, []containerMetric{
+			{
+				name:        "container_network_tc_backlog_bytes",
+				help:        "Number of bytes queued by a traffic control qdisc or class of an interface of the container, or of the host side of its veth pairs.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{"interface", "kind", "handle", "parent"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					return getTcValues(s, func(t info.TcStats) uint64 { return t.Backlog })
+				},
+			}, {
+				name:        "container_network_tc_backlog_packets",
+				help:        "Number of packets queued by a traffic control qdisc or class of an interface of the container, or of the host side of its veth pairs.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{"interface", "kind", "handle", "parent"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					return getTcValues(s, func(t info.TcStats) uint64 { return t.Qlen })
+				},
+			}, {
+				name:        "container_network_tc_bytes_total",
+				help:        "Cumulative count of bytes sent by a traffic control qdisc or class of an interface of the container, or of the host side of its veth pairs.",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{"interface", "kind", "handle", "parent"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					return getTcValues(s, func(t info.TcStats) uint64 { return t.Bytes })
+				},
+			}, {
+				name:        "container_network_tc_dropped_packets_total",
+				help:        "Cumulative count of packets dropped by a traffic control qdisc or class of an interface of the container, or of the host side of its veth pairs.",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{"interface", "kind", "handle", "parent"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					return getTcValues(s, func(t info.TcStats) uint64 { return t.Drops })
+				},
+			}, {
+				name:        "container_network_tc_overlimits_total",
+				help:        "Cumulative count of packets which exceeded the rate limit of a traffic control qdisc or class of an interface of the container, or of the host side of its veth pairs.",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{"interface", "kind", "handle", "parent"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					return getTcValues(s, func(t info.TcStats) uint64 { return t.Overlimits })
+				},
+			}, {
+				name:        "container_network_tc_packets_total",
+				help:        "Cumulative count of packets sent by a traffic control qdisc or class of an interface of the container, or of the host side of its veth pairs.",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{"interface", "kind", "handle", "parent"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					return getTcValues(s, func(t info.TcStats) uint64 { return t.Packets })
+				},
+			}, {
+				name:        "container_network_tc_requeues_total",
+				help:        "Cumulative count of packets requeued by a traffic control qdisc or class of an interface of the container, or of the host side of its veth pairs.",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{"interface", "kind", "handle", "parent"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					return getTcValues(s, func(t info.TcStats) uint64 { return t.Requeues })
+				},
+			},
+		}...)
+	}
 	if includedMetrics.Has(container.ProcessMetrics) {
-		c.containerMetrics = append(c.containerMetrics, []containerMetric{
+		c.addMetrics(container.ProcessMetrics, []containerMetric{
 			{
 				name:      "container_processes",
 				help:      "Number of processes running inside the container.",
@@ -1544,6 +1830,32 @@ func NewPrometheusCollector(i infoProvider, f ContainerLabelsFunc, includedMetri
 					}
 				},
 			},
+			{
+				name:      "container_threads_max_reached_total",
+				help:      "Number of times a fork or clone failed because the container reached its maximum number of threads",
+				valueType: prometheus.CounterValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{
+						{
+							value:     float64(s.Processes.ThreadsMaxReached),
+							timestamp: s.Timestamp,
+						},
+					}
+				},
+			},
+			{
+				name:      "container_forks_total",
+				help:      "Cumulative number of threads and processes started in the container, estimated from the tasks seen starting, excluding the ones living shorter than the housekeeping interval, when the fork events of the kernel cannot be listened to",
+				valueType: prometheus.CounterValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{
+						{
+							value:     float64(s.Processes.Forks),
+							timestamp: s.Timestamp,
+						},
+					}
+				},
+			},
 			{
 				name:        "container_ulimits_soft",
 				help:        "Soft ulimit values for the container root process. Unlimited if -1, except priority and nice",
@@ -1565,7 +1877,7 @@ func NewPrometheusCollector(i infoProvider, f ContainerLabelsFunc, includedMetri
 	}
 	if includedMetrics.Has(container.PerfMetrics) {
 		if includedMetrics.Has(container.PerCpuUsageMetrics) {
-			c.containerMetrics = append(c.containerMetrics, []containerMetric{
+			c.addMetrics(container.PerfMetrics, []containerMetric{
 				{
 					name:        "container_perf_events_total",
 					help:        "Perf event metric.",
@@ -1585,7 +1897,7 @@ func NewPrometheusCollector(i infoProvider, f ContainerLabelsFunc, includedMetri
 					},
 				}}...)
 		} else {
-			c.containerMetrics = append(c.containerMetrics, []containerMetric{
+			c.addMetrics(container.PerfMetrics, []containerMetric{
 				{
 					name:        "container_perf_events_total",
 					help:        "Perf event metric.",
@@ -1605,7 +1917,7 @@ func NewPrometheusCollector(i infoProvider, f ContainerLabelsFunc, includedMetri
 					},
 				}}...)
 		}
-		c.containerMetrics = append(c.containerMetrics, []containerMetric{
+		c.addMetrics(container.PerfMetrics, []containerMetric{
 			{
 				name:        "container_perf_uncore_events_total",
 				help:        "Perf uncore event metric.",
@@ -1643,7 +1955,7 @@ func NewPrometheusCollector(i infoProvider, f ContainerLabelsFunc, includedMetri
 		}...)
 	}
 	if includedMetrics.Has(container.ReferencedMemoryMetrics) {
-		c.containerMetrics = append(c.containerMetrics, []containerMetric{
+		c.addMetrics(container.ReferencedMemoryMetrics, []containerMetric{
 			{
 				name:      "container_referenced_bytes",
 				help:      "Container referenced bytes during last measurements cycle",
@@ -1655,7 +1967,7 @@ func NewPrometheusCollector(i infoProvider, f ContainerLabelsFunc, includedMetri
 		}...)
 	}
 	if includedMetrics.Has(container.ResctrlMetrics) {
-		c.containerMetrics = append(c.containerMetrics, []containerMetric{
+		c.addMetrics(container.ResctrlMetrics, []containerMetric{
 			{
 				name:        "container_memory_bandwidth_bytes",
 				help:        "Total memory bandwidth usage statistics for container counted with RDT Memory Bandwidth Monitoring (MBM).",
@@ -1713,12 +2025,21 @@ func NewPrometheusCollector(i infoProvider, f ContainerLabelsFunc, includedMetri
 		}...)
 	}
 	if includedMetrics.Has(container.OOMMetrics) {
-		c.containerMetrics = append(c.containerMetrics, containerMetric{
+		c.addMetrics(container.OOMMetrics, containerMetric{
 			name:      "container_oom_events_total",
 			help:      "Count of out of memory events observed for the container",
 			valueType: prometheus.CounterValue,
 			getValues: func(s *info.ContainerStats) metricValues {
-				return metricValues{{value: float64(s.OOMEvents), timestamp: s.Timestamp}}
+				value := metricValue{value: float64(s.OOMEvents), timestamp: s.Timestamp}
+				if s.LastOOMEvent != nil {
+					// The exemplar links the latest increment to its event.
+					value.exemplar = &prometheus.Exemplar{
+						Value:     1,
+						Labels:    prometheus.Labels{eventIDExemplarLabel: strconv.FormatUint(s.LastOOMEvent.Id, 10)},
+						Timestamp: s.LastOOMEvent.Timestamp,
+					}
+				}
+				return metricValues{value}
 			},
 		})
 	}
@@ -1726,12 +2047,30 @@ func NewPrometheusCollector(i infoProvider, f ContainerLabelsFunc, includedMetri
 	return c
 }
 
+// SetLabelsCache makes c reuse the labels of the containers cached by the
+// previous scrapes, instead of a cache of its own.
+// addMetrics adds metrics of kind to the metrics of the containers.
+func (c *PrometheusCollector) addMetrics(kind container.MetricKind, metrics ...containerMetric) {
+	for _, cm := range metrics {
+		cm.kind = kind
+		c.containerMetrics = append(c.containerMetrics, cm)
+	}
+}
+
+func (c *PrometheusCollector) SetLabelsCache(cache *LabelsCache) {
+	c.labelsCache = cache
+}
+
 var (
 	versionInfoDesc = prometheus.NewDesc("cadvisor_version_info", "A metric with a constant '1' value labeled by kernel version, OS version, docker version, cadvisor version & cadvisor revision.", []string{"kernelVersion", "osVersion", "dockerVersion", "cadvisorVersion", "cadvisorRevision"}, nil)
 	startTimeDesc   = prometheus.NewDesc("container_start_time_seconds", "Start time of the container since unix epoch in seconds.", nil, nil)
 	cpuPeriodDesc   = prometheus.NewDesc("container_spec_cpu_period", "CPU period of the container.", nil, nil)
 	cpuQuotaDesc    = prometheus.NewDesc("container_spec_cpu_quota", "CPU quota of the container.", nil, nil)
 	cpuSharesDesc   = prometheus.NewDesc("container_spec_cpu_shares", "CPU share of the container.", nil, nil)
+
+	startLatencyDesc      = prometheus.NewDesc("container_start_latency_seconds", "Time between the creation of the container by its runtime and its start, for the runtimes reporting it and the containers which never restarted.", nil, nil)
+	firstStatsLatencyDesc = prometheus.NewDesc("container_first_stats_latency_seconds", "Time between the discovery of the container and its first stats, for the containers started since cAdvisor started.", nil, nil)
+	uidInfoDesc           = prometheus.NewDesc("container_uid_info", "Identifier of the container set by cAdvisor, stable across cAdvisor restarts and unique among the containers taking the same name, as the uid label. Always 1.", nil, nil)
 )
 
 // Describe describes all the metrics ever exported by cadvisor. It
@@ -1745,6 +2084,9 @@ func (c *PrometheusCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- cpuPeriodDesc
 	ch <- cpuQuotaDesc
 	ch <- cpuSharesDesc
+	ch <- startLatencyDesc
+	ch <- firstStatsLatencyDesc
+	ch <- uidInfoDesc
 	ch <- versionInfoDesc
 }
 
@@ -1768,6 +2110,10 @@ const (
 	LabelName = "name"
 	// LabelImage is the name of the image label.
 	LabelImage = "image"
+
+	// eventIDExemplarLabel is the label of the exemplars holding the id of
+	// an event, as listed by the events API.
+	eventIDExemplarLabel = "event_id"
 )
 
 // DefaultContainerLabels implements ContainerLabelsFunc. It exports the
@@ -1825,54 +2171,45 @@ func (c *PrometheusCollector) collectContainersInfo(ch chan<- prometheus.Metric)
 		klog.Warningf("Couldn't get containers: %s", err)
 		return
 	}
-	rawLabels := map[string]struct{}{}
-	for _, container := range containers {
-		for l := range c.containerLabelsFunc(container) {
-			rawLabels[l] = struct{}{}
+	if len(c.labelFilter) > 0 {
+		selected := make(map[string]*info.ContainerInfo, len(containers))
+		for name, cont := range containers {
+			if c.labelFilter.matches(c.containerLabelsFunc(cont)) {
+				selected[name] = cont
+			}
 		}
+		containers = selected
 	}
+	descs, labeled := c.labelContainers(containers)
+	defer c.putContainers(labeled)
 
-	for _, cont := range containers {
-		values := make([]string, 0, len(rawLabels))
-		labels := make([]string, 0, len(rawLabels))
-		containerLabels := c.containerLabelsFunc(cont)
-		for l := range rawLabels {
-			duplicate := false
-			sl := sanitizeLabelName(l)
-			for _, x := range labels {
-				if sl == x {
-					duplicate = true
-					break
-				}
-			}
-			if !duplicate {
-				labels = append(labels, sl)
-				values = append(values, containerLabels[l])
-			}
-		}
+	for _, lc := range *labeled {
+		cont, values := lc.info, lc.values
 
 		// Container spec
-		desc := prometheus.NewDesc("container_start_time_seconds", "Start time of the container since unix epoch in seconds.", labels, nil)
-		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(cont.Spec.CreationTime.Unix()), values...)
+		ch <- prometheus.MustNewConstMetric(descs.startTime, prometheus.GaugeValue, float64(cont.Spec.CreationTime.Unix()), values...)
+		if cont.Spec.Uid != "" {
+			ch <- prometheus.MustNewConstMetric(descs.uidInfo, prometheus.GaugeValue, 1, append(values, cont.Spec.Uid)...)
+		}
+		if latency := cont.Spec.StartLatency(); latency > 0 {
+			ch <- prometheus.MustNewConstMetric(descs.startLatency, prometheus.GaugeValue, latency.Seconds(), values...)
+		}
+		if cont.Spec.FirstStatsLatency > 0 {
+			ch <- prometheus.MustNewConstMetric(descs.firstStatsLatency, prometheus.GaugeValue, cont.Spec.FirstStatsLatency.Seconds(), values...)
+		}
 
 		if cont.Spec.HasCpu {
-			desc = prometheus.NewDesc("container_spec_cpu_period", "CPU period of the container.", labels, nil)
-			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(cont.Spec.Cpu.Period), values...)
+			ch <- prometheus.MustNewConstMetric(descs.cpuPeriod, prometheus.GaugeValue, float64(cont.Spec.Cpu.Period), values...)
 			if cont.Spec.Cpu.Quota != 0 {
-				desc = prometheus.NewDesc("container_spec_cpu_quota", "CPU quota of the container.", labels, nil)
-				ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(cont.Spec.Cpu.Quota), values...)
+				ch <- prometheus.MustNewConstMetric(descs.cpuQuota, prometheus.GaugeValue, float64(cont.Spec.Cpu.Quota), values...)
 			}
-			desc := prometheus.NewDesc("container_spec_cpu_shares", "CPU share of the container.", labels, nil)
-			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(cont.Spec.Cpu.Limit), values...)
+			ch <- prometheus.MustNewConstMetric(descs.cpuShares, prometheus.GaugeValue, float64(cont.Spec.Cpu.Limit), values...)
 
 		}
 		if cont.Spec.HasMemory {
-			desc := prometheus.NewDesc("container_spec_memory_limit_bytes", "Memory limit for the container.", labels, nil)
-			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, specMemoryValue(cont.Spec.Memory.Limit), values...)
-			desc = prometheus.NewDesc("container_spec_memory_swap_limit_bytes", "Memory swap limit for the container.", labels, nil)
-			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, specMemoryValue(cont.Spec.Memory.SwapLimit), values...)
-			desc = prometheus.NewDesc("container_spec_memory_reservation_limit_bytes", "Memory reservation limit for the container.", labels, nil)
-			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, specMemoryValue(cont.Spec.Memory.Reservation), values...)
+			ch <- prometheus.MustNewConstMetric(descs.memoryLimit, prometheus.GaugeValue, specMemoryValue(cont.Spec.Memory.Limit), values...)
+			ch <- prometheus.MustNewConstMetric(descs.memorySwapLimit, prometheus.GaugeValue, specMemoryValue(cont.Spec.Memory.SwapLimit), values...)
+			ch <- prometheus.MustNewConstMetric(descs.memoryReservationLimit, prometheus.GaugeValue, specMemoryValue(cont.Spec.Memory.Reservation), values...)
 		}
 
 		// Now for the actual metrics
@@ -1880,24 +2217,38 @@ func (c *PrometheusCollector) collectContainersInfo(ch chan<- prometheus.Metric)
 			continue
 		}
 		stats := cont.Stats[0]
-		for _, cm := range c.containerMetrics {
+		for i, cm := range c.containerMetrics {
 			if cm.condition != nil && !cm.condition(cont.Spec) {
 				continue
 			}
-			desc := cm.desc(labels)
+			if metricsDisabled(cont.Spec, cm.kind) {
+				continue
+			}
 			for _, metricValue := range cm.getValues(stats) {
-				ch <- prometheus.NewMetricWithTimestamp(
-					metricValue.timestamp,
-					prometheus.MustNewConstMetric(desc, cm.valueType, float64(metricValue.value), append(values, metricValue.labels...)...),
-				)
+				labelValues := values
+				if len(metricValue.labels) > 0 {
+					// The values of the cached labels are shared, their
+					// capacity is their length so that they are copied.
+					labelValues = append(values, metricValue.labels...)
+				}
+				var metric prometheus.Metric
+				if created := countersCreated(cont.Spec); cm.valueType == prometheus.CounterValue && !created.IsZero() {
+					metric = prometheus.MustNewConstMetricWithCreatedTimestamp(descs.metrics[i], cm.valueType, float64(metricValue.value), created, labelValues...)
+				} else {
+					metric = prometheus.MustNewConstMetric(descs.metrics[i], cm.valueType, float64(metricValue.value), labelValues...)
+				}
+				if metricValue.exemplar != nil {
+					metric = prometheus.MustNewMetricWithExemplars(metric, *metricValue.exemplar)
+				}
+				ch <- prometheus.NewMetricWithTimestamp(metricValue.timestamp, metric)
 			}
 		}
 		if c.includedMetrics.Has(container.AppMetrics) {
 			for metricLabel, v := range stats.CustomMetrics {
 				for _, metric := range v {
-					clabels := make([]string, len(rawLabels), len(rawLabels)+len(metric.Labels))
-					cvalues := make([]string, len(rawLabels), len(rawLabels)+len(metric.Labels))
-					copy(clabels, labels)
+					clabels := make([]string, len(descs.labels), len(descs.labels)+len(metric.Labels))
+					cvalues := make([]string, len(values), len(values)+len(metric.Labels))
+					copy(clabels, descs.labels)
 					copy(cvalues, values)
 					for label, value := range metric.Labels {
 						clabels = append(clabels, sanitizeLabelName("app_"+label))
@@ -1911,6 +2262,27 @@ func (c *PrometheusCollector) collectContainersInfo(ch chan<- prometheus.Metric)
 	}
 }
 
+// countersCreated returns the time at which the counters of the container of
+// spec started: its last start, since its cgroup and thus its counters are
+// created again when it restarts, or else its creation.
+func countersCreated(spec info.ContainerSpec) time.Time {
+	if spec.StartedAt.After(spec.CreationTime) {
+		return spec.StartedAt
+	}
+	return spec.CreationTime
+}
+
+// metricsDisabled returns whether the metrics of kind are no longer read for
+// the container of spec, whose values would not be meaningful.
+func metricsDisabled(spec info.ContainerSpec, kind container.MetricKind) bool {
+	for _, disabled := range spec.DisabledMetrics {
+		if disabled == string(kind) {
+			return true
+		}
+	}
+	return false
+}
+
 func (c *PrometheusCollector) collectVersionInfo(ch chan<- prometheus.Metric) {
 	versionInfo, err := c.infoProvider.GetVersionInfo()
 	if err != nil {
@@ -1940,6 +2312,62 @@ func sanitizeLabelName(name string) string {
 	return invalidNameCharRE.ReplaceAllString(name, "_")
 }
 
+// matches returns whether the labels, as returned by a ContainerLabelsFunc,
+// have one of the values of each label of the filter.
+func (f ContainerLabelFilter) matches(labels map[string]string) bool {
+	for name, values := range f {
+		matched := false
+		for label, value := range labels {
+			if sanitizeLabelName(label) != name {
+				continue
+			}
+			for _, v := range values {
+				matched = matched || v == value
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// getNetfilterValues returns the counters of the policies of the built-in
+// chains and of the rules, separately: adding them up would count the packets
+// matched by non-terminating rules, e.g. LOG, or returning from a jump twice.
+func getNetfilterValues(s *info.ContainerStats, policy func(info.NetfilterChain) uint64, rule func(info.NetfilterRule) uint64) metricValues {
+	values := make(metricValues, 0, len(s.Network.Netfilter))
+	for _, chain := range s.Network.Netfilter {
+		if chain.Policy != "" {
+			values = append(values, metricValue{
+				value:     float64(policy(chain)),
+				labels:    []string{chain.Family, chain.Table, chain.Name, "policy"},
+				timestamp: s.Timestamp,
+			})
+		}
+		for i, r := range chain.Rules {
+			values = append(values, metricValue{
+				value:     float64(rule(r)),
+				labels:    []string{chain.Family, chain.Table, chain.Name, strconv.Itoa(i + 1)},
+				timestamp: s.Timestamp,
+			})
+		}
+	}
+	return values
+}
+
+func getTcValues(s *info.ContainerStats, value func(info.TcStats) uint64) metricValues {
+	values := make(metricValues, 0, len(s.Network.Tc))
+	for _, t := range s.Network.Tc {
+		values = append(values, metricValue{
+			value:     float64(value(t)),
+			labels:    []string{t.Interface, t.Kind, t.Handle, t.Parent},
+			timestamp: s.Timestamp,
+		})
+	}
+	return values
+}
+
 func getNumaStatsPerNode(nodeStats map[uint8]uint64, labels []string, timestamp time.Time) metricValues {
 	mValues := make(metricValues, 0, len(nodeStats))
 	for node, stat := range nodeStats {
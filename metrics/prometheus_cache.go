@@ -0,0 +1,228 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// LabelsCache holds what the scrapes share as long as the set of containers
+// does not change: the base labels of the containers, their values for the
+// union of the label names, and the descriptors of the metrics with those
+// label names. The descriptors and values are never modified once built, so
+// that concurrent scrapes can use them without holding the lock.
+//
+// A cache outlives the collectors, which are usually created for each scrape,
+// and may only be shared by collectors of the same ContainerLabelsFunc and
+// included metrics. The containers not seen by a scrape are evicted, so
+// scrapes selecting different containers should not share a cache.
+type LabelsCache struct {
+	mu sync.Mutex
+	// Number of the latest scrape, to evict the containers it did not see.
+	scrape     uint64
+	containers map[string]*cachedContainer
+	// Label names, sanitized and sorted, and the base labels they take
+	// their values from.
+	names     []string
+	rawLabels []string
+	descs     *containerDescs
+	// Containers and their cached labels, reused by the scrapes.
+	pool sync.Pool
+}
+
+// NewLabelsCache returns an empty cache of the labels of the containers.
+func NewLabelsCache() *LabelsCache {
+	return &LabelsCache{containers: make(map[string]*cachedContainer)}
+}
+
+// cachedContainer holds the base labels of a container, which are computed
+// again when the container is recreated under the same name.
+type cachedContainer struct {
+	uid     string
+	created time.Time
+	// Number of the latest scrape which saw the container.
+	scrape uint64
+	labels map[string]string
+	// Values of the labels for the names they were computed for, nil if the
+	// label names changed since.
+	values []string
+}
+
+// containerDescs are the descriptors of the metrics of the containers for a
+// set of label names.
+type containerDescs struct {
+	labels                 []string
+	metrics                []*prometheus.Desc
+	startTime              *prometheus.Desc
+	uidInfo                *prometheus.Desc
+	startLatency           *prometheus.Desc
+	firstStatsLatency      *prometheus.Desc
+	cpuPeriod              *prometheus.Desc
+	cpuQuota               *prometheus.Desc
+	cpuShares              *prometheus.Desc
+	memoryLimit            *prometheus.Desc
+	memorySwapLimit        *prometheus.Desc
+	memoryReservationLimit *prometheus.Desc
+}
+
+// labeledContainer is a container of a scrape along with the values of its
+// base labels.
+type labeledContainer struct {
+	info   *info.ContainerInfo
+	values []string
+}
+
+func newContainerDescs(labels []string, containerMetrics []containerMetric) *containerDescs {
+	// The label names are shared by the descriptors, which do not modify
+	// them.
+	labels = labels[:len(labels):len(labels)]
+	descs := &containerDescs{
+		labels:                 labels,
+		metrics:                make([]*prometheus.Desc, len(containerMetrics)),
+		startTime:              prometheus.NewDesc("container_start_time_seconds", "Start time of the container since unix epoch in seconds.", labels, nil),
+		uidInfo:                prometheus.NewDesc("container_uid_info", "Identifier of the container set by cAdvisor, stable across cAdvisor restarts and unique among the containers taking the same name, as the uid label. Always 1.", append(labels, "uid"), nil),
+		startLatency:           prometheus.NewDesc("container_start_latency_seconds", "Time between the creation of the container by its runtime and its start, for the runtimes reporting it and the containers which never restarted.", labels, nil),
+		firstStatsLatency:      prometheus.NewDesc("container_first_stats_latency_seconds", "Time between the discovery of the container and its first stats, for the containers started since cAdvisor started.", labels, nil),
+		cpuPeriod:              prometheus.NewDesc("container_spec_cpu_period", "CPU period of the container.", labels, nil),
+		cpuQuota:               prometheus.NewDesc("container_spec_cpu_quota", "CPU quota of the container.", labels, nil),
+		cpuShares:              prometheus.NewDesc("container_spec_cpu_shares", "CPU share of the container.", labels, nil),
+		memoryLimit:            prometheus.NewDesc("container_spec_memory_limit_bytes", "Memory limit for the container.", labels, nil),
+		memorySwapLimit:        prometheus.NewDesc("container_spec_memory_swap_limit_bytes", "Memory swap limit for the container.", labels, nil),
+		memoryReservationLimit: prometheus.NewDesc("container_spec_memory_reservation_limit_bytes", "Memory reservation limit for the container.", labels, nil),
+	}
+	for i := range containerMetrics {
+		descs.metrics[i] = containerMetrics[i].desc(labels)
+	}
+	return descs
+}
+
+// labelContainers returns the descriptors of the metrics and the containers
+// along with the values of their base labels, computing the labels of the
+// containers it did not see before. The containers are returned to the pool
+// with putContainers once their metrics are collected.
+func (c *PrometheusCollector) labelContainers(containers map[string]*info.ContainerInfo) (*containerDescs, *[]labeledContainer) {
+	cache := c.labelsCache
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	cache.scrape++
+	changed := false
+	for name, cont := range containers {
+		cached, ok := cache.containers[name]
+		if !ok || cached.uid != cont.Spec.Uid || !cached.created.Equal(cont.Spec.CreationTime) {
+			cached = &cachedContainer{
+				uid:     cont.Spec.Uid,
+				created: cont.Spec.CreationTime,
+				labels:  c.containerLabelsFunc(cont),
+			}
+			cache.containers[name] = cached
+			changed = true
+		}
+		cached.scrape = cache.scrape
+	}
+	for name, cached := range cache.containers {
+		if cached.scrape != cache.scrape {
+			delete(cache.containers, name)
+			changed = true
+		}
+	}
+
+	if changed || cache.descs == nil {
+		names, rawLabels := labelNames(cache.containers)
+		if cache.descs == nil || !equalStrings(names, cache.names) || !equalStrings(rawLabels, cache.rawLabels) {
+			cache.names, cache.rawLabels = names, rawLabels
+			cache.descs = newContainerDescs(names, c.containerMetrics)
+			for _, cached := range cache.containers {
+				cached.values = nil
+			}
+		}
+	}
+
+	labeled, _ := cache.pool.Get().(*[]labeledContainer)
+	if labeled == nil {
+		labeled = new([]labeledContainer)
+	}
+	for name, cont := range containers {
+		cached := cache.containers[name]
+		if cached.values == nil {
+			values := make([]string, len(cache.rawLabels))
+			for i, l := range cache.rawLabels {
+				values[i] = cached.labels[l]
+			}
+			cached.values = values
+		}
+		*labeled = append(*labeled, labeledContainer{info: cont, values: cached.values})
+	}
+	return cache.descs, labeled
+}
+
+// putContainers returns the containers of a scrape to the pool.
+func (c *PrometheusCollector) putContainers(labeled *[]labeledContainer) {
+	for i := range *labeled {
+		(*labeled)[i] = labeledContainer{}
+	}
+	*labeled = (*labeled)[:0]
+	c.labelsCache.pool.Put(labeled)
+}
+
+// labelNames returns the sorted union of the sanitized names of the base
+// labels of the containers, along with the base label each takes its value
+// from: the first one in order when several are sanitized to the same name.
+func labelNames(containers map[string]*cachedContainer) ([]string, []string) {
+	var raw []string
+	seen := make(map[string]bool)
+	for _, cached := range containers {
+		for l := range cached.labels {
+			if !seen[l] {
+				seen[l] = true
+				raw = append(raw, l)
+			}
+		}
+	}
+	sort.Strings(raw)
+	rawLabels := make(map[string]string, len(raw))
+	names := make([]string, 0, len(raw))
+	for _, l := range raw {
+		sl := sanitizeLabelName(l)
+		if _, ok := rawLabels[sl]; !ok {
+			rawLabels[sl] = l
+			names = append(names, sl)
+		}
+	}
+	sort.Strings(names)
+	sources := make([]string, len(names))
+	for i, name := range names {
+		sources[i] = rawLabels[name]
+	}
+	return names, sources
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
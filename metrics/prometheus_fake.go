@@ -52,6 +52,12 @@ func (p testSubcontainersInfoProvider) GetMachineInfo() (*info.MachineInfo, erro
 		MachineID:  "machine-id-test",
 		SystemUUID: "system-uuid-test",
 		BootID:     "boot-id-test",
+		Allocatable: &info.NodeAllocatable{
+			SystemReserved:    info.ResourceAmounts{MilliCpu: 500, Memory: 256},
+			KubeReserved:      info.ResourceAmounts{MilliCpu: 250, Memory: 128},
+			EvictionThreshold: info.ResourceAmounts{Memory: 64},
+			Allocatable:       info.ResourceAmounts{MilliCpu: 3250, Memory: 576},
+		},
 		Topology: []info.Node{
 			{
 				Id:     0,
@@ -298,7 +304,10 @@ func (p testSubcontainersInfoProvider) GetRequestedContainersInfo(string, v2.Req
 				Processes: info.ProcessSpec{
 					Limit: 100,
 				},
-				CreationTime: time.Unix(1257894000, 0),
+				CreationTime:      time.Unix(1257894000, 0),
+				StartedAt:         time.Unix(1257894000, 250000000),
+				FirstStatsLatency: 40 * time.Millisecond,
+				Uid:               "6f1ed002ab5595859014ebf0951522d9",
 				Labels: map[string]string{
 					"foo.label": "bar",
 				},
@@ -309,6 +318,11 @@ func (p testSubcontainersInfoProvider) GetRequestedContainersInfo(string, v2.Req
 			Stats: []*info.ContainerStats{
 				{
 					Timestamp: time.Unix(1395066363, 0),
+					OOMEvents: 1,
+					LastOOMEvent: &info.EventReference{
+						Id:        7,
+						Timestamp: time.Unix(1395066300, 0),
+					},
 					Cpu: info.CpuStats{
 						Usage: info.CpuUsage{
 							Total:  1,
@@ -354,7 +368,18 @@ func (p testSubcontainersInfoProvider) GetRequestedContainersInfo(string, v2.Req
 						RSS:         15,
 						MappedFile:  16,
 						KernelUsage: 17,
-						Swap:        8192,
+						Kernel: info.MemoryKernelStats{
+							KernelStack:       3,
+							PageTables:        4,
+							Percpu:            2,
+							Sock:              1,
+							SlabReclaimable:   5,
+							SlabUnreclaimable: 2,
+							TopSlabCaches: []info.SlabCache{
+								{Name: "dentry", ActiveObjects: 20, Objects: 21, ObjectSize: 192, Size: 4096},
+							},
+						},
+						Swap: 8192,
 					},
 					Hugetlb: map[string]info.HugetlbStats{
 						"2Mi": {
@@ -532,6 +557,42 @@ func (p testSubcontainersInfoProvider) GetRequestedContainersInfo(string, v2.Req
 							RxQueued: 0,
 							TxQueued: 0,
 						},
+						Conntrack: info.ConntrackStat{
+							Entries:      300,
+							Max:          262144,
+							InsertFailed: 3,
+							Drop:         3,
+							EarlyDrop:    1,
+						},
+						Netfilter: []info.NetfilterChain{
+							{
+								Family:        "ipv4",
+								Table:         "filter",
+								Name:          "FORWARD",
+								Policy:        "DROP",
+								PolicyPackets: 3,
+								PolicyBytes:   180,
+								Rules: []info.NetfilterRule{
+									{Spec: "-o docker0 -j DOCKER", Packets: 120, Bytes: 9600},
+								},
+							},
+						},
+						Tc: []info.TcStats{
+							{
+								Interface:  "veth1a2b3c",
+								Host:       true,
+								Kind:       "tbf",
+								Handle:     "1:",
+								Parent:     "root",
+								Bytes:      300000,
+								Packets:    2000,
+								Backlog:    1500,
+								Qlen:       1,
+								Drops:      5,
+								Overlimits: 60,
+								Requeues:   1,
+							},
+						},
 					},
 					DiskIo: info.DiskIoStats{
 						IoServiceBytes: []info.PerDiskStats{{
@@ -573,6 +634,8 @@ func (p testSubcontainersInfoProvider) GetRequestedContainersInfo(string, v2.Req
 							Inodes:          2097152,
 							Limit:           37,
 							Usage:           38,
+							Quota:           36,
+							LogUsage:        35,
 							ReadsCompleted:  39,
 							ReadsMerged:     40,
 							SectorsRead:     41,
@@ -586,6 +649,21 @@ func (p testSubcontainersInfoProvider) GetRequestedContainersInfo(string, v2.Req
 							WeightedIoTime:  49,
 						},
 					},
+					Volumes: []info.FsStats{
+						{
+							Device: "sda1",
+							Inodes: 120,
+							Limit:  22,
+							Quota:  20,
+							Usage:  11,
+							Volume: &info.FsVolume{
+								Name:        "db",
+								Type:        "volume",
+								Source:      "/var/lib/docker/volumes/db/_data",
+								Destination: "/var/lib/postgresql/data",
+							},
+						},
+					},
 					Accelerators: []info.AcceleratorStats{
 						{
 							Make:        "nvidia",
@@ -605,11 +683,14 @@ func (p testSubcontainersInfoProvider) GetRequestedContainersInfo(string, v2.Req
 						},
 					},
 					Processes: info.ProcessStats{
-						ProcessCount:   1,
-						FdCount:        5,
-						SocketCount:    3,
-						ThreadsCurrent: 5,
-						ThreadsMax:     100,
+						ProcessCount:      1,
+						FdCount:           5,
+						SocketCount:       3,
+						ThreadsCurrent:    5,
+						ThreadsMax:        100,
+						ThreadsMaxReached: 2,
+						Forks:             42,
+						ForkRate:          0.5,
 						Ulimits: []info.UlimitSpec{
 							{
 								Name:      "max_open_files",
@@ -712,6 +793,8 @@ func (p testSubcontainersInfoProvider) GetRequestedContainersInfo(string, v2.Req
 						},
 					},
 					ReferencedMemory: 1234,
+					Sequence:         73,
+					TimestampSkew:    25 * time.Millisecond,
 					Resctrl: info.ResctrlStats{
 						MemoryBandwidth: []info.MemoryBandwidthStats{
 							{
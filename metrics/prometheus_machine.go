@@ -37,6 +37,10 @@ const (
 	prometheusPageSizeLabelName   = "page_size"
 	prometheusTargetNodeLabelName = "target_node_id"
 
+	systemReservation   = "system"
+	kubeReservation     = "kube"
+	evictionReservation = "eviction"
+
 	nvmMemoryMode    = "memory_mode"
 	nvmAppDirectMode = "app_direct_mode"
 
@@ -119,6 +123,53 @@ func NewPrometheusMachineCollector(i infoProvider, includedMetrics container.Met
 					return metricValues{{value: float64(machineInfo.SwapCapacity), timestamp: machineInfo.Timestamp}}
 				},
 			},
+			{
+				name:      "machine_cpu_allocatable_cores",
+				help:      "Number of CPU cores allocatable to pods, the logical CPU cores minus the reserved ones.",
+				valueType: prometheus.GaugeValue,
+				condition: func(machineInfo *info.MachineInfo) bool { return machineInfo.Allocatable != nil },
+				getValues: func(machineInfo *info.MachineInfo) metricValues {
+					return metricValues{{value: float64(machineInfo.Allocatable.Allocatable.MilliCpu) / 1000, timestamp: machineInfo.Timestamp}}
+				},
+			},
+			{
+				name:        "machine_cpu_reserved_cores",
+				help:        "Number of CPU cores reserved for the system or for the Kubernetes daemons, labeled by reservation type.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{prometheusTypeLabelName},
+				condition:   func(machineInfo *info.MachineInfo) bool { return machineInfo.Allocatable != nil },
+				getValues: func(machineInfo *info.MachineInfo) metricValues {
+					allocatable := machineInfo.Allocatable
+					return metricValues{
+						{value: float64(allocatable.SystemReserved.MilliCpu) / 1000, labels: []string{systemReservation}, timestamp: machineInfo.Timestamp},
+						{value: float64(allocatable.KubeReserved.MilliCpu) / 1000, labels: []string{kubeReservation}, timestamp: machineInfo.Timestamp},
+					}
+				},
+			},
+			{
+				name:      "machine_memory_allocatable_bytes",
+				help:      "Amount of memory allocatable to pods, the memory installed minus the reserved memory and the eviction threshold.",
+				valueType: prometheus.GaugeValue,
+				condition: func(machineInfo *info.MachineInfo) bool { return machineInfo.Allocatable != nil },
+				getValues: func(machineInfo *info.MachineInfo) metricValues {
+					return metricValues{{value: float64(machineInfo.Allocatable.Allocatable.Memory), timestamp: machineInfo.Timestamp}}
+				},
+			},
+			{
+				name:        "machine_memory_reserved_bytes",
+				help:        "Amount of memory reserved for the system or for the Kubernetes daemons, or kept available by the hard eviction threshold, labeled by reservation type.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{prometheusTypeLabelName},
+				condition:   func(machineInfo *info.MachineInfo) bool { return machineInfo.Allocatable != nil },
+				getValues: func(machineInfo *info.MachineInfo) metricValues {
+					allocatable := machineInfo.Allocatable
+					return metricValues{
+						{value: float64(allocatable.SystemReserved.Memory), labels: []string{systemReservation}, timestamp: machineInfo.Timestamp},
+						{value: float64(allocatable.KubeReserved.Memory), labels: []string{kubeReservation}, timestamp: machineInfo.Timestamp},
+						{value: float64(allocatable.EvictionThreshold.Memory), labels: []string{evictionReservation}, timestamp: machineInfo.Timestamp},
+					}
+				},
+			},
 			{
 				name:        "machine_dimm_count",
 				help:        "Number of RAM DIMM (all types memory modules) value labeled by dimm type.",
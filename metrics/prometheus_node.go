@@ -0,0 +1,156 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"k8s.io/klog/v2"
+)
+
+// vmstatFields are the counters of /proc/vmstat exposed as node metrics, the
+// same ones node_exporter exposes by default.
+var vmstatFields = regexp.MustCompile(`^(oom_kill|pgpg|pswp|pg.*fault).*`)
+
+var (
+	nodeLoad1Desc  = prometheus.NewDesc("node_load1", "1m load average.", nil, nil)
+	nodeLoad5Desc  = prometheus.NewDesc("node_load5", "5m load average.", nil, nil)
+	nodeLoad15Desc = prometheus.NewDesc("node_load15", "15m load average.", nil, nil)
+
+	nodeEntropyDesc = prometheus.NewDesc("node_entropy_available_bits", "Bits of available entropy.", nil, nil)
+
+	nodeFileFdAllocatedDesc = prometheus.NewDesc("node_filefd_allocated", "File descriptor statistics: allocated.", nil, nil)
+	nodeFileFdMaximumDesc   = prometheus.NewDesc("node_filefd_maximum", "File descriptor statistics: maximum.", nil, nil)
+)
+
+// PrometheusNodeCollector implements prometheus.Collector. It exposes
+// machine-level metrics read from procfs, named as by node_exporter so that
+// the same dashboards and alerts can be used.
+type PrometheusNodeCollector struct {
+	procPath string
+}
+
+// NewPrometheusNodeCollector returns a new PrometheusNodeCollector reading
+// the procfs mounted at procPath.
+func NewPrometheusNodeCollector(procPath string) *PrometheusNodeCollector {
+	return &PrometheusNodeCollector{procPath: procPath}
+}
+
+// Describe describes nothing, the counters of vmstat exposed depend on the
+// kernel, so the collector is unchecked. It implements
+// prometheus.PrometheusCollector.
+func (c *PrometheusNodeCollector) Describe(ch chan<- *prometheus.Desc) {
+}
+
+// Collect reads the node metrics and delivers them as Prometheus metrics. It
+// implements prometheus.PrometheusCollector.
+func (c *PrometheusNodeCollector) Collect(ch chan<- prometheus.Metric) {
+	for name, collect := range map[string]func(ch chan<- prometheus.Metric) error{
+		"loadavg": c.collectLoadAvg,
+		"entropy": c.collectEntropy,
+		"filefd":  c.collectFileFd,
+		"vmstat":  c.collectVmstat,
+	} {
+		if err := collect(ch); err != nil {
+			klog.V(4).Infof("Couldn't collect %s node metrics: %v", name, err)
+		}
+	}
+}
+
+func (c *PrometheusNodeCollector) collectLoadAvg(ch chan<- prometheus.Metric) error {
+	out, err := os.ReadFile(filepath.Join(c.procPath, "loadavg"))
+	if err != nil {
+		return err
+	}
+	fields := strings.Fields(string(out))
+	if len(fields) < 3 {
+		return fmt.Errorf("unexpected loadavg %q", out)
+	}
+	for i, desc := range []*prometheus.Desc{nodeLoad1Desc, nodeLoad5Desc, nodeLoad15Desc} {
+		load, err := strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			return fmt.Errorf("failed to parse loadavg %q: %v", out, err)
+		}
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, load)
+	}
+	return nil
+}
+
+func (c *PrometheusNodeCollector) collectEntropy(ch chan<- prometheus.Metric) error {
+	out, err := os.ReadFile(filepath.Join(c.procPath, "sys/kernel/random/entropy_avail"))
+	if err != nil {
+		return err
+	}
+	entropy, err := strconv.ParseFloat(strings.TrimSpace(string(out)), 64)
+	if err != nil {
+		return fmt.Errorf("failed to parse entropy_avail %q: %v", out, err)
+	}
+	ch <- prometheus.MustNewConstMetric(nodeEntropyDesc, prometheus.GaugeValue, entropy)
+	return nil
+}
+
+func (c *PrometheusNodeCollector) collectFileFd(ch chan<- prometheus.Metric) error {
+	out, err := os.ReadFile(filepath.Join(c.procPath, "sys/fs/file-nr"))
+	if err != nil {
+		return err
+	}
+	// Allocated, allocated but unused (always 0 since Linux 2.6) and maximum
+	// file handles.
+	fields := strings.Fields(string(out))
+	if len(fields) != 3 {
+		return fmt.Errorf("unexpected file-nr %q", out)
+	}
+	for _, f := range []struct {
+		desc  *prometheus.Desc
+		value string
+	}{{nodeFileFdAllocatedDesc, fields[0]}, {nodeFileFdMaximumDesc, fields[2]}} {
+		value, err := strconv.ParseFloat(f.value, 64)
+		if err != nil {
+			return fmt.Errorf("failed to parse file-nr %q: %v", out, err)
+		}
+		ch <- prometheus.MustNewConstMetric(f.desc, prometheus.GaugeValue, value)
+	}
+	return nil
+}
+
+func (c *PrometheusNodeCollector) collectVmstat(ch chan<- prometheus.Metric) error {
+	out, err := os.ReadFile(filepath.Join(c.procPath, "vmstat"))
+	if err != nil {
+		return err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || !vmstatFields.MatchString(fields[0]) {
+			continue
+		}
+		value, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			return fmt.Errorf("failed to parse vmstat line %q: %v", scanner.Text(), err)
+		}
+		desc := prometheus.NewDesc("node_vmstat_"+fields[0], "/proc/vmstat information field "+fields[0]+".", nil, nil)
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.UntypedValue, value)
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,55 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+	"github.com/stretchr/testify/assert"
+)
+
+const nodeMetricsFile = "testdata/prometheus_node_metrics"
+
+func TestPrometheusNodeCollector(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(NewPrometheusNodeCollector("testdata/proc"))
+
+	metricsFamily, err := registry.Gather()
+	assert.Nil(t, err)
+
+	var metricBuffer bytes.Buffer
+	for _, metricFamily := range metricsFamily {
+		_, err := expfmt.MetricFamilyToText(&metricBuffer, metricFamily)
+		assert.Nil(t, err)
+	}
+	collectedMetrics := metricBuffer.String()
+
+	expectedMetrics, err := os.ReadFile(nodeMetricsFile)
+	assert.Nil(t, err)
+	assert.Equal(t, string(expectedMetrics), collectedMetrics)
+}
+
+func TestPrometheusNodeCollectorMissingProc(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(NewPrometheusNodeCollector(t.TempDir()))
+
+	metricsFamily, err := registry.Gather()
+	assert.Nil(t, err)
+	assert.Empty(t, metricsFamily)
+}
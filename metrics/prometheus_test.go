@@ -17,6 +17,7 @@ package metrics
 import (
 	"errors"
 	"os"
+	"sort"
 	"testing"
 	"time"
 
@@ -27,6 +28,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	clock "k8s.io/utils/clock/testing"
 )
 
@@ -37,7 +39,7 @@ func TestPrometheusCollector(t *testing.T) {
 		s := DefaultContainerLabels(container)
 		s["zone.name"] = "hello"
 		return s
-	}, container.AllMetrics, now, v2.RequestOptions{})
+	}, container.AllMetrics, now, v2.RequestOptions{}, nil)
 	reg := prometheus.NewRegistry()
 	reg.MustRegister(c)
 
@@ -53,7 +55,7 @@ func TestPrometheusCollectorWithWhiteList(t *testing.T) {
 		s := containerLabelFunc(container)
 		s["zone.name"] = "hello"
 		return s
-	}, container.AllMetrics, now, v2.RequestOptions{})
+	}, container.AllMetrics, now, v2.RequestOptions{}, nil)
 	reg := prometheus.NewRegistry()
 	reg.MustRegister(c)
 
@@ -68,7 +70,7 @@ func TestPrometheusCollectorWithPerfAggregated(t *testing.T) {
 		s := DefaultContainerLabels(container)
 		s["zone.name"] = "hello"
 		return s
-	}, metrics, now, v2.RequestOptions{})
+	}, metrics, now, v2.RequestOptions{}, nil)
 	reg := prometheus.NewRegistry()
 	reg.MustRegister(c)
 
@@ -97,7 +99,7 @@ func TestPrometheusCollector_scrapeFailure(t *testing.T) {
 		s := DefaultContainerLabels(container)
 		s["zone.name"] = "hello"
 		return s
-	}, container.AllMetrics, now, v2.RequestOptions{})
+	}, container.AllMetrics, now, v2.RequestOptions{}, nil)
 	reg := prometheus.NewRegistry()
 	reg.MustRegister(c)
 
@@ -109,13 +111,15 @@ func TestPrometheusCollector_scrapeFailure(t *testing.T) {
 }
 
 func TestNewPrometheusCollectorWithPerf(t *testing.T) {
-	c := NewPrometheusCollector(&mockInfoProvider{}, mockLabelFunc, container.MetricSet{container.PerfMetrics: struct{}{}}, now, v2.RequestOptions{})
-	assert.Len(t, c.containerMetrics, 5)
+	c := NewPrometheusCollector(&mockInfoProvider{}, mockLabelFunc, container.MetricSet{container.PerfMetrics: struct{}{}}, now, v2.RequestOptions{}, nil)
+	assert.Len(t, c.containerMetrics, 7)
 	names := []string{}
 	for _, m := range c.containerMetrics {
 		names = append(names, m.name)
 	}
 	assert.Contains(t, names, "container_last_seen")
+	assert.Contains(t, names, "container_stats_sequence")
+	assert.Contains(t, names, "container_stats_timestamp_skew_seconds")
 	assert.Contains(t, names, "container_perf_events_total")
 	assert.Contains(t, names, "container_perf_events_scaling_ratio")
 	assert.Contains(t, names, "container_perf_uncore_events_total")
@@ -127,12 +131,177 @@ func TestNewPrometheusCollectorWithRequestOptions(t *testing.T) {
 	opts := v2.RequestOptions{
 		IdType: "docker",
 	}
-	c := NewPrometheusCollector(&p, mockLabelFunc, container.AllMetrics, now, opts)
+	c := NewPrometheusCollector(&p, mockLabelFunc, container.AllMetrics, now, opts, nil)
 	ch := make(chan prometheus.Metric, 10)
 	c.Collect(ch)
 	assert.Equal(t, p.options, opts)
 }
 
+// containersInfoProvider returns a settable set of containers.
+type containersInfoProvider struct {
+	mockInfoProvider
+	containers map[string]*info.ContainerInfo
+}
+
+func (p *containersInfoProvider) GetRequestedContainersInfo(string, v2.RequestOptions) (map[string]*info.ContainerInfo, error) {
+	return p.containers, nil
+}
+
+func TestPrometheusCollectorLabelsCache(t *testing.T) {
+	newContainer := func(name, uid string) *info.ContainerInfo {
+		return &info.ContainerInfo{
+			ContainerReference: info.ContainerReference{Name: name},
+			Spec:               info.ContainerSpec{Uid: uid, Labels: map[string]string{"app": name}},
+		}
+	}
+	p := &containersInfoProvider{containers: map[string]*info.ContainerInfo{
+		"/a": newContainer("/a", "1"),
+		"/b": newContainer("/b", "2"),
+	}}
+	calls := map[string]int{}
+	c := NewPrometheusCollector(p, func(cont *info.ContainerInfo) map[string]string {
+		calls[cont.Name]++
+		return DefaultContainerLabels(cont)
+	}, container.MetricSet{}, now, v2.RequestOptions{}, nil)
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(c)
+
+	_, err := reg.Gather()
+	assert.NoError(t, err)
+	descs := c.labelsCache.descs
+	assert.Equal(t, []string{"container_label_app", "id"}, descs.labels)
+
+	// The labels and descriptors are reused while the containers do not
+	// change.
+	_, err = reg.Gather()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"/a": 1, "/b": 1}, calls)
+	assert.Same(t, descs, c.labelsCache.descs)
+
+	// The labels of a recreated container are computed again, those of the
+	// removed containers are evicted.
+	p.containers = map[string]*info.ContainerInfo{"/a": newContainer("/a", "3")}
+	_, err = reg.Gather()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"/a": 2, "/b": 1}, calls)
+	assert.Same(t, descs, c.labelsCache.descs)
+	assert.Len(t, c.labelsCache.containers, 1)
+
+	// The descriptors follow the label names.
+	p.containers["/c"] = newContainer("/c", "4")
+	p.containers["/c"].Spec.Image = "nginx"
+	_, err = reg.Gather()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"container_label_app", "id", "image"}, c.labelsCache.descs.labels)
+	assert.Equal(t, []string{"/a", "/a", ""}, c.labelsCache.containers["/a"].values)
+}
+
+func TestPrometheusCollectorLabelFilter(t *testing.T) {
+	newContainer := func(name, pod, app string) *info.ContainerInfo {
+		return &info.ContainerInfo{
+			ContainerReference: info.ContainerReference{Name: name},
+			Spec:               info.ContainerSpec{Labels: map[string]string{"io.kubernetes.pod.name": pod, "app": app}},
+		}
+	}
+	p := &containersInfoProvider{containers: map[string]*info.ContainerInfo{
+		"/a": newContainer("/a", "web-1", "web"),
+		"/b": newContainer("/b", "web-2", "web"),
+		"/c": newContainer("/c", "db-1", "db"),
+		"/d": {ContainerReference: info.ContainerReference{Name: "/d"}},
+	}}
+	ids := func(labelFilter ContainerLabelFilter) []string {
+		c := NewPrometheusCollector(p, DefaultContainerLabels, container.MetricSet{}, now, v2.RequestOptions{}, labelFilter)
+		reg := prometheus.NewRegistry()
+		reg.MustRegister(c)
+		families, err := reg.Gather()
+		require.NoError(t, err)
+		ids := []string{}
+		for _, family := range families {
+			if family.GetName() != "container_start_time_seconds" {
+				continue
+			}
+			for _, metric := range family.Metric {
+				for _, label := range metric.Label {
+					if label.GetName() == LabelID {
+						ids = append(ids, label.GetValue())
+					}
+				}
+			}
+		}
+		sort.Strings(ids)
+		return ids
+	}
+
+	assert.Equal(t, []string{"/a", "/b", "/c", "/d"}, ids(nil))
+	assert.Equal(t, []string{"/a", "/b"}, ids(ContainerLabelFilter{"container_label_app": {"web"}}))
+	assert.Equal(t, []string{"/a", "/c"}, ids(ContainerLabelFilter{"container_label_io_kubernetes_pod_name": {"web-1", "db-1"}}))
+	assert.Equal(t, []string{"/b"}, ids(ContainerLabelFilter{"container_label_app": {"web"}, "container_label_io_kubernetes_pod_name": {"web-2", "db-1"}}))
+	assert.Equal(t, []string{}, ids(ContainerLabelFilter{"container_label_tier": {"backend"}}))
+	// The containers of the provider are left as they are.
+	assert.Len(t, p.containers, 4)
+}
+
+func TestPrometheusCollectorDisabledMetrics(t *testing.T) {
+	newContainer := func(name string, disabled ...string) *info.ContainerInfo {
+		return &info.ContainerInfo{
+			ContainerReference: info.ContainerReference{Name: name},
+			Spec:               info.ContainerSpec{DisabledMetrics: disabled},
+			Stats: []*info.ContainerStats{{
+				Timestamp: now.Now(),
+				DiskIo: info.DiskIoStats{IoServiceBytes: []info.PerDiskStats{
+					{Device: "/dev/sda", Major: 8, Stats: map[string]uint64{"Read": 0}},
+				}},
+			}},
+		}
+	}
+	p := &containersInfoProvider{containers: map[string]*info.ContainerInfo{
+		"/a": newContainer("/a"),
+		"/b": newContainer("/b", string(container.DiskIOMetrics)),
+	}}
+	c := NewPrometheusCollector(p, DefaultContainerLabels, container.MetricSet{
+		container.MemoryUsageMetrics: struct{}{},
+		container.DiskIOMetrics:      struct{}{},
+	}, now, v2.RequestOptions{}, nil)
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(c)
+	families, err := reg.Gather()
+	require.NoError(t, err)
+	ids := map[string][]string{}
+	for _, family := range families {
+		for _, metric := range family.Metric {
+			for _, label := range metric.Label {
+				if label.GetName() == LabelID {
+					ids[family.GetName()] = append(ids[family.GetName()], label.GetValue())
+				}
+			}
+		}
+	}
+	for name := range ids {
+		sort.Strings(ids[name])
+	}
+
+	// The disabled metrics are not exported as zeros, the others are.
+	assert.Equal(t, []string{"/a", "/b"}, ids["container_memory_usage_bytes"])
+	assert.Equal(t, []string{"/a"}, ids["container_fs_reads_bytes_total"])
+}
+
+func BenchmarkPrometheusCollector(b *testing.B) {
+	c := NewPrometheusCollector(testSubcontainersInfoProvider{}, DefaultContainerLabels, container.AllMetrics, now, v2.RequestOptions{}, nil)
+	ch := make(chan prometheus.Metric, 1024)
+	done := make(chan struct{})
+	go func() {
+		for range ch {
+		}
+		close(done)
+	}()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		c.Collect(ch)
+	}
+	close(ch)
+	<-done
+}
+
 type mockInfoProvider struct {
 	options v2.RequestOptions
 }
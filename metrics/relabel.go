@@ -0,0 +1,91 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/model"
+
+	"k8s.io/klog/v2"
+
+	"github.com/yidoyoon/cadvisor-lite/utils/relabel"
+)
+
+// NewRelabelingGatherer returns a prometheus.Gatherer rewriting the series
+// gathered by g with the relabeler. The name of a series is its __name__
+// label, so that the rules can rename it. A series renamed into a family
+// already gathered with a different type is dropped.
+func NewRelabelingGatherer(g prometheus.Gatherer, relabeler *relabel.Relabeler) prometheus.Gatherer {
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		// The families gathered are returned along with the error, if any.
+		families, err := g.Gather()
+		return relabelFamilies(families, relabeler), err
+	})
+}
+
+func relabelFamilies(families []*dto.MetricFamily, relabeler *relabel.Relabeler) []*dto.MetricFamily {
+	byName := make(map[string]*dto.MetricFamily, len(families))
+	for _, family := range families {
+		for _, metric := range family.Metric {
+			labels := make(map[string]string, len(metric.Label)+1)
+			for _, label := range metric.Label {
+				labels[label.GetName()] = label.GetValue()
+			}
+			labels[model.MetricNameLabel] = family.GetName()
+			labels = relabeler.Process(labels)
+			name := labels[model.MetricNameLabel]
+			if name == "" {
+				continue
+			}
+			out, ok := byName[name]
+			if !ok {
+				out = &dto.MetricFamily{Name: &name, Help: family.Help, Type: family.Type}
+				byName[name] = out
+			} else if out.GetType() != family.GetType() {
+				klog.V(4).Infof("Dropping a series of %q renamed into %q of a different type", family.GetName(), name)
+				continue
+			}
+			// The metrics are gathered anew for each request, so they are
+			// modified in place.
+			metric.Label = labelPairs(labels)
+			out.Metric = append(out.Metric, metric)
+		}
+	}
+	result := make([]*dto.MetricFamily, 0, len(byName))
+	for _, family := range byName {
+		result = append(result, family)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].GetName() < result[j].GetName() })
+	return result
+}
+
+// labelPairs returns the labels sorted by name, without the empty and meta
+// labels.
+func labelPairs(labels map[string]string) []*dto.LabelPair {
+	pairs := make([]*dto.LabelPair, 0, len(labels))
+	for name, value := range labels {
+		if value == "" || strings.HasPrefix(name, relabel.MetaLabelPrefix) {
+			continue
+		}
+		name, value := name, value
+		pairs = append(pairs, &dto.LabelPair{Name: &name, Value: &value})
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].GetName() < pairs[j].GetName() })
+	return pairs
+}
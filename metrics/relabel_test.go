@@ -0,0 +1,67 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yidoyoon/cadvisor-lite/utils/relabel"
+)
+
+func TestRelabelingGatherer(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	cpu := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "container_cpu_usage_seconds_total", Help: "Cumulative cpu time consumed in seconds."}, []string{"name", "image"})
+	cpu.WithLabelValues("web-1", "nginx").Add(3)
+	cpu.WithLabelValues("batch-1", "job").Add(5)
+	memory := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "container_memory_usage_bytes", Help: "Current memory usage in bytes."}, []string{"name", "image"})
+	memory.WithLabelValues("web-1", "nginx").Set(1024)
+	goroutines := prometheus.NewGauge(prometheus.GaugeOpts{Name: "go_goroutines", Help: "Number of goroutines that currently exist."})
+	registry.MustRegister(cpu, memory, goroutines)
+
+	relabeler, err := relabel.New([]relabel.Rule{
+		{SourceLabels: []string{"__name__"}, Regex: "go_.*", Action: relabel.Drop},
+		{SourceLabels: []string{"name"}, Regex: "batch-.*", Action: relabel.Drop},
+		{SourceLabels: []string{"__name__"}, Regex: "container_(.*)", TargetLabel: "__name__", Replacement: "ctr_$1"},
+		{Regex: "image", Action: relabel.LabelDrop},
+		// The gauge is dropped, the counter taking the name first.
+		{SourceLabels: []string{"__name__"}, Regex: "ctr_memory.*", TargetLabel: "__name__", Replacement: "ctr_cpu_usage_seconds_total"},
+	})
+	require.NoError(t, err)
+
+	expected := `# HELP ctr_cpu_usage_seconds_total Cumulative cpu time consumed in seconds.
+# TYPE ctr_cpu_usage_seconds_total counter
+ctr_cpu_usage_seconds_total{name="web-1"} 3
+`
+	assert.NoError(t, testutil.GatherAndCompare(NewRelabelingGatherer(registry, relabeler), strings.NewReader(expected)))
+
+	relabeler, err = relabel.New([]relabel.Rule{
+		{SourceLabels: []string{"__name__"}, Regex: "container_(.*)", TargetLabel: "__name__", Replacement: "ctr_$1"},
+		{SourceLabels: []string{"name"}, TargetLabel: "container"},
+		{Regex: "name|image", Action: relabel.LabelDrop},
+	})
+	require.NoError(t, err)
+	expected = `# HELP ctr_cpu_usage_seconds_total Cumulative cpu time consumed in seconds.
+# TYPE ctr_cpu_usage_seconds_total counter
+ctr_cpu_usage_seconds_total{container="batch-1"} 5
+ctr_cpu_usage_seconds_total{container="web-1"} 3
+`
+	assert.NoError(t, testutil.GatherAndCompare(NewRelabelingGatherer(registry, relabeler), strings.NewReader(expected), "ctr_cpu_usage_seconds_total"))
+}
@@ -0,0 +1,72 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stats
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+)
+
+// Transformer can modify or augment the ContainerStats of a container after
+// they are collected and before they are stored and exported.
+type Transformer interface {
+	// Transform updates stats in place.
+	Transform(ref info.ContainerReference, stats *info.ContainerStats) error
+}
+
+type namedTransformer struct {
+	name        string
+	transformer Transformer
+}
+
+var (
+	transformersLock sync.RWMutex
+	transformers     []namedTransformer
+)
+
+// RegisterTransformer registers a Transformer applied to the stats of every
+// container. Transformers are applied in the order they are registered.
+func RegisterTransformer(name string, transformer Transformer) {
+	transformersLock.Lock()
+	defer transformersLock.Unlock()
+	transformers = append(transformers, namedTransformer{name: name, transformer: transformer})
+}
+
+// RegisteredTransformers returns a Transformer applying all registered
+// transformers. A failing transformer does not prevent the following ones from
+// being applied.
+func RegisteredTransformers() Transformer {
+	return registeredTransformers{}
+}
+
+type registeredTransformers struct{}
+
+func (registeredTransformers) Transform(ref info.ContainerReference, stats *info.ContainerStats) error {
+	transformersLock.RLock()
+	defer transformersLock.RUnlock()
+	var errs []string
+	for _, t := range transformers {
+		if err := t.transformer.Transform(ref, stats); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", t.name, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to transform stats of %q: %s", ref.Name, strings.Join(errs, "; "))
+	}
+	return nil
+}
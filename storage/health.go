@@ -0,0 +1,138 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+)
+
+// HealthReporter is implemented by the storage drivers holding a connection
+// or buffering samples, to report their state.
+type HealthReporter interface {
+	// Returns whether the driver is connected to its backend.
+	Connected() bool
+	// Returns the number of samples, or of the points they are converted
+	// to, buffered and not written to the backend yet.
+	QueueDepth() int
+	// Returns the time of the last successful write to the backend, zero if
+	// none.
+	LastFlush() time.Time
+}
+
+// DriverHealth is the state of a storage driver.
+type DriverHealth struct {
+	Name string `json:"name"`
+	// Nil if the driver does not report it.
+	Connected *bool `json:"connected,omitempty"`
+	// Number of samples, or of the points they are converted to, buffered
+	// and not written to the backend yet.
+	QueueDepth int `json:"queue_depth"`
+	// Time of the last successful write to the backend, or of the last
+	// sample added without error for the drivers not reporting it.
+	LastFlush time.Time `json:"last_flush"`
+	// Number of samples added, and of those whose addition failed.
+	Samples uint64 `json:"samples"`
+	Errors  uint64 `json:"errors"`
+	// Last error and its time, if any.
+	LastError     string    `json:"last_error,omitempty"`
+	LastErrorTime time.Time `json:"last_error_time,omitempty"`
+}
+
+// InstrumentedDriver is a StorageDriver recording the health of the driver
+// it wraps.
+type InstrumentedDriver struct {
+	StorageDriver
+
+	lock   sync.Mutex
+	health DriverHealth
+}
+
+var (
+	instrumentedLock sync.Mutex
+	instrumented     []*InstrumentedDriver
+)
+
+// NewInstrumentedDriver returns driver, called name, recording its health.
+// Its health is reported by DriversHealth until it is closed.
+func NewInstrumentedDriver(driver StorageDriver, name string) *InstrumentedDriver {
+	d := &InstrumentedDriver{
+		StorageDriver: driver,
+		health:        DriverHealth{Name: name},
+	}
+	instrumentedLock.Lock()
+	defer instrumentedLock.Unlock()
+	instrumented = append(instrumented, d)
+	return d
+}
+
+func (d *InstrumentedDriver) AddStats(cInfo *info.ContainerInfo, stats *info.ContainerStats) error {
+	err := d.StorageDriver.AddStats(cInfo, stats)
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.health.Samples++
+	if err != nil {
+		d.health.Errors++
+		d.health.LastError = err.Error()
+		d.health.LastErrorTime = time.Now()
+	} else {
+		d.health.LastFlush = time.Now()
+	}
+	return err
+}
+
+func (d *InstrumentedDriver) Close() error {
+	instrumentedLock.Lock()
+	for i, other := range instrumented {
+		if other == d {
+			instrumented = append(instrumented[:i], instrumented[i+1:]...)
+			break
+		}
+	}
+	instrumentedLock.Unlock()
+	return d.StorageDriver.Close()
+}
+
+// Health returns the health of the driver.
+func (d *InstrumentedDriver) Health() DriverHealth {
+	d.lock.Lock()
+	health := d.health
+	d.lock.Unlock()
+	if reporter, ok := d.StorageDriver.(HealthReporter); ok {
+		connected := reporter.Connected()
+		health.Connected = &connected
+		health.QueueDepth = reporter.QueueDepth()
+		health.LastFlush = reporter.LastFlush()
+	}
+	return health
+}
+
+// DriversHealth returns the health of the storage drivers created with
+// NewInstrumentedDriver, by name.
+func DriversHealth() []DriverHealth {
+	instrumentedLock.Lock()
+	drivers := make([]*InstrumentedDriver, len(instrumented))
+	copy(drivers, instrumented)
+	instrumentedLock.Unlock()
+	result := make([]DriverHealth, 0, len(drivers))
+	for _, d := range drivers {
+		result = append(result, d.Health())
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
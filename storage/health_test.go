@@ -0,0 +1,109 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+)
+
+var flushTime = time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+
+type fakeDriver struct {
+	err error
+}
+
+func (d *fakeDriver) AddStats(*info.ContainerInfo, *info.ContainerStats) error { return d.err }
+func (d *fakeDriver) Close() error                                             { return nil }
+
+type fakeReportingDriver struct {
+	fakeDriver
+}
+
+func (d *fakeReportingDriver) Connected() bool      { return false }
+func (d *fakeReportingDriver) QueueDepth() int      { return 12 }
+func (d *fakeReportingDriver) LastFlush() time.Time { return flushTime }
+
+func TestDriversHealth(t *testing.T) {
+	plain := &fakeDriver{}
+	b := NewInstrumentedDriver(plain, "b")
+	a := NewInstrumentedDriver(&fakeReportingDriver{fakeDriver{err: errors.New("unreachable")}}, "a")
+	defer a.Close()
+
+	require.NoError(t, b.AddStats(&info.ContainerInfo{}, &info.ContainerStats{}))
+	plain.err = errors.New("timeout")
+	assert.Error(t, b.AddStats(&info.ContainerInfo{}, &info.ContainerStats{}))
+	assert.Error(t, a.AddStats(&info.ContainerInfo{}, &info.ContainerStats{}))
+
+	health := DriversHealth()
+	require.Len(t, health, 2)
+	assert.Equal(t, "a", health[0].Name)
+	require.NotNil(t, health[0].Connected)
+	assert.False(t, *health[0].Connected)
+	assert.Equal(t, 12, health[0].QueueDepth)
+	assert.Equal(t, flushTime, health[0].LastFlush)
+	assert.Equal(t, uint64(1), health[0].Samples)
+	assert.Equal(t, uint64(1), health[0].Errors)
+	assert.Equal(t, "unreachable", health[0].LastError)
+
+	assert.Equal(t, "b", health[1].Name)
+	assert.Nil(t, health[1].Connected)
+	assert.False(t, health[1].LastFlush.IsZero())
+	assert.Equal(t, uint64(2), health[1].Samples)
+	assert.Equal(t, uint64(1), health[1].Errors)
+	assert.Equal(t, "timeout", health[1].LastError)
+	assert.False(t, health[1].LastErrorTime.IsZero())
+
+	// Closed drivers are no longer reported.
+	require.NoError(t, b.Close())
+	health = DriversHealth()
+	require.Len(t, health, 1)
+	assert.Equal(t, "a", health[0].Name)
+}
+
+func TestPrometheusCollector(t *testing.T) {
+	d := NewInstrumentedDriver(&fakeReportingDriver{}, "influxdb")
+	defer d.Close()
+	require.NoError(t, d.AddStats(&info.ContainerInfo{}, &info.ContainerStats{}))
+
+	r := prometheus.NewRegistry()
+	r.MustRegister(NewPrometheusCollector())
+	want := `# HELP cadvisor_storage_driver_connected Whether the storage driver is connected to its backend, for the drivers reporting it.
+# TYPE cadvisor_storage_driver_connected gauge
+cadvisor_storage_driver_connected{driver="influxdb"} 0
+# HELP cadvisor_storage_driver_errors_total Number of samples whose addition to the storage driver failed.
+# TYPE cadvisor_storage_driver_errors_total counter
+cadvisor_storage_driver_errors_total{driver="influxdb"} 0
+# HELP cadvisor_storage_driver_last_flush_timestamp_seconds Time of the last successful write of the storage driver to its backend.
+# TYPE cadvisor_storage_driver_last_flush_timestamp_seconds gauge
+cadvisor_storage_driver_last_flush_timestamp_seconds{driver="influxdb"} 1.6829424e+09
+# HELP cadvisor_storage_driver_queue_depth Number of samples buffered by the storage driver and not written to its backend yet.
+# TYPE cadvisor_storage_driver_queue_depth gauge
+cadvisor_storage_driver_queue_depth{driver="influxdb"} 12
+# HELP cadvisor_storage_driver_samples_total Number of samples added to the storage driver.
+# TYPE cadvisor_storage_driver_samples_total counter
+cadvisor_storage_driver_samples_total{driver="influxdb"} 1
+`
+	assert.NoError(t, testutil.GatherAndCompare(r, strings.NewReader(want)))
+}
@@ -0,0 +1,79 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	samplesDesc = prometheus.NewDesc(
+		"cadvisor_storage_driver_samples_total",
+		"Number of samples added to the storage driver.",
+		[]string{"driver"}, nil)
+	errorsDesc = prometheus.NewDesc(
+		"cadvisor_storage_driver_errors_total",
+		"Number of samples whose addition to the storage driver failed.",
+		[]string{"driver"}, nil)
+	lastFlushDesc = prometheus.NewDesc(
+		"cadvisor_storage_driver_last_flush_timestamp_seconds",
+		"Time of the last successful write of the storage driver to its backend.",
+		[]string{"driver"}, nil)
+	queueDepthDesc = prometheus.NewDesc(
+		"cadvisor_storage_driver_queue_depth",
+		"Number of samples buffered by the storage driver and not written to its backend yet.",
+		[]string{"driver"}, nil)
+	connectedDesc = prometheus.NewDesc(
+		"cadvisor_storage_driver_connected",
+		"Whether the storage driver is connected to its backend, for the drivers reporting it.",
+		[]string{"driver"}, nil)
+)
+
+// PrometheusCollector exports the health of the storage drivers created with
+// NewInstrumentedDriver.
+type PrometheusCollector struct{}
+
+// NewPrometheusCollector returns a new PrometheusCollector.
+func NewPrometheusCollector() *PrometheusCollector {
+	return &PrometheusCollector{}
+}
+
+// Describe implements prometheus.Collector.
+func (c *PrometheusCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- samplesDesc
+	ch <- errorsDesc
+	ch <- lastFlushDesc
+	ch <- queueDepthDesc
+	ch <- connectedDesc
+}
+
+// Collect implements prometheus.Collector.
+func (c *PrometheusCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, health := range DriversHealth() {
+		ch <- prometheus.MustNewConstMetric(samplesDesc, prometheus.CounterValue, float64(health.Samples), health.Name)
+		ch <- prometheus.MustNewConstMetric(errorsDesc, prometheus.CounterValue, float64(health.Errors), health.Name)
+		if !health.LastFlush.IsZero() {
+			ch <- prometheus.MustNewConstMetric(lastFlushDesc, prometheus.GaugeValue, float64(health.LastFlush.UnixNano())/1e9, health.Name)
+		}
+		ch <- prometheus.MustNewConstMetric(queueDepthDesc, prometheus.GaugeValue, float64(health.QueueDepth), health.Name)
+		if health.Connected != nil {
+			connected := 0.0
+			if *health.Connected {
+				connected = 1
+			}
+			ch <- prometheus.MustNewConstMetric(connectedDesc, prometheus.GaugeValue, connected, health.Name)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"strings"
+
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+	"github.com/yidoyoon/cadvisor-lite/utils/relabel"
+)
+
+// Meta labels of the containers available to the relabeling rules of the
+// storage drivers.
+const (
+	ContainerNameLabel = relabel.MetaLabelPrefix + "container_name__"
+	StorageDriverLabel = relabel.MetaLabelPrefix + "storage_driver__"
+)
+
+type relabelingDriver struct {
+	StorageDriver
+	name      string
+	relabeler *relabel.Relabeler
+}
+
+// NewRelabelingDriver returns a StorageDriver rewriting the labels of the
+// containers with the relabeler before passing their stats to the storage
+// driver called name. The stats of the containers dropped by the rules are
+// not passed, so that subsets of the containers can be routed to different
+// drivers.
+func NewRelabelingDriver(driver StorageDriver, name string, relabeler *relabel.Relabeler) StorageDriver {
+	return &relabelingDriver{
+		StorageDriver: driver,
+		name:          name,
+		relabeler:     relabeler,
+	}
+}
+
+func (d *relabelingDriver) AddStats(cInfo *info.ContainerInfo, stats *info.ContainerStats) error {
+	labels := make(map[string]string, len(cInfo.Spec.Labels)+2)
+	for k, v := range cInfo.Spec.Labels {
+		labels[k] = v
+	}
+	labels[ContainerNameLabel] = cInfo.Name
+	labels[StorageDriverLabel] = d.name
+	labels = d.relabeler.Process(labels)
+	if labels == nil {
+		return nil
+	}
+	for k := range labels {
+		if strings.HasPrefix(k, relabel.MetaLabelPrefix) {
+			delete(labels, k)
+		}
+	}
+	// The container info is shared with the other drivers.
+	relabeled := *cInfo
+	relabeled.Spec.Labels = labels
+	return d.StorageDriver.AddStats(&relabeled, stats)
+}
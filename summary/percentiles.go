@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"math"
 	"sort"
+	"strconv"
 
 	info "github.com/yidoyoon/cadvisor-lite/info/v2"
 )
@@ -47,6 +48,13 @@ func (s Uint64Slice) GetPercentile(d float64) uint64 {
 	n := float64(d * (float64(count) + 1))
 	idx, frac := math.Modf(n)
 	index := int(idx)
+	// Percentiles out of the range of the samples are the extreme samples.
+	if index < 1 {
+		return s[0]
+	}
+	if index > count {
+		return s[count-1]
+	}
 	percentile := float64(s[index-1])
 	if index > 1 && index < count {
 		percentile += frac * float64(s[index]-s[index-1])
@@ -125,6 +133,21 @@ func (r *resource) GetAllPercentiles() info.Percentiles {
 	return p
 }
 
+// Get max, average, and the given percentiles, in [0, 100], from existing
+// samples.
+func (r *resource) getCustomPercentiles(percentiles []float64) info.CustomPercentiles {
+	p := info.CustomPercentiles{
+		Present:     len(r.samples) > 0,
+		Mean:        uint64(r.mean.Mean),
+		Max:         r.max,
+		Percentiles: make(map[string]uint64, len(percentiles)),
+	}
+	for _, percentile := range percentiles {
+		p.Percentiles[strconv.FormatFloat(percentile, 'f', -1, 64)] = r.samples.GetPercentile(percentile / 100)
+	}
+	return p
+}
+
 func NewResource(size int) Percentile {
 	return &resource{
 		samples: make(Uint64Slice, 0, size),
@@ -146,6 +169,19 @@ func GetDerivedPercentiles(stats []*info.Usage) info.Usage {
 	return usage
 }
 
+// Return the given percentiles, in [0, 100], of the cpu and memory usage
+// from the provided percentile samples. As for the derived percentiles, they
+// are computed from the 90th percentiles of the samples.
+func GetCustomPercentiles(stats []*info.Usage, percentiles []float64) (cpu, memory info.CustomPercentiles) {
+	cpuResource := &resource{samples: make(Uint64Slice, 0, len(stats))}
+	memoryResource := &resource{samples: make(Uint64Slice, 0, len(stats))}
+	for _, stat := range stats {
+		cpuResource.Add(stat.Cpu)
+		memoryResource.Add(stat.Memory)
+	}
+	return cpuResource.getCustomPercentiles(percentiles), memoryResource.getCustomPercentiles(percentiles)
+}
+
 // Calculate part of a minute this sample set represent.
 func getPercentComplete(stats []*secondSample) (percent int32) {
 	numSamples := len(stats)
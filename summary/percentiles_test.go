@@ -46,6 +46,10 @@ func TestPercentile(t *testing.T) {
 	assertPercentile(t, s, 0.2, 21)
 	assertPercentile(t, s, 0.7, 74)
 	assertPercentile(t, s, 0.9, 95)
+	// Percentiles out of the range of the samples.
+	assertPercentile(t, s, 0.001, 1)
+	assertPercentile(t, s, 1, 105)
+	assertPercentile(t, Uint64Slice{7}, 0.1, 7)
 }
 
 func TestMean(t *testing.T) {
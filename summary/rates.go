@@ -0,0 +1,112 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Utility methods to calculate the rates of cumulative counters.
+
+package summary
+
+import (
+	"fmt"
+
+	v1 "github.com/yidoyoon/cadvisor-lite/info/v1"
+	info "github.com/yidoyoon/cadvisor-lite/info/v2"
+)
+
+// Returns the rates of the cumulative counters of a container between the
+// oldest and the latest of stats, in increasing time order. Like the cpu rate
+// of the summaries, rates over less than 10ms are not computed, and the rates
+// of the resources whose counters dropped are omitted.
+func GetContainerRates(spec *v1.ContainerSpec, stats []*v1.ContainerStats) (*info.ContainerRates, error) {
+	if len(stats) < 2 {
+		return nil, fmt.Errorf("not enough stats to compute rates, got %d", len(stats))
+	}
+	first, latest := stats[0], stats[len(stats)-1]
+	elapsed := latest.Timestamp.Sub(first.Timestamp).Nanoseconds()
+	if elapsed < 10*milliSecondsToNanoSeconds {
+		return nil, fmt.Errorf("elapsed time too small: %d ns: time now %s first %s", elapsed, latest.Timestamp.String(), first.Timestamp.String())
+	}
+	seconds := float64(elapsed) / secondsToNanoSeconds
+	rates := &info.ContainerRates{
+		Timestamp: latest.Timestamp,
+		Samples:   len(stats),
+		Interval:  seconds,
+	}
+	// rate returns the rates of counters per second, false if one dropped.
+	rate := func(previous, current []uint64) ([]float64, bool) {
+		values := make([]float64, len(current))
+		for i := range current {
+			if current[i] < previous[i] {
+				return nil, false
+			}
+			values[i] = float64(current[i]-previous[i]) / seconds
+		}
+		return values, true
+	}
+
+	if spec.HasCpu {
+		if r, ok := rate(cpuCounters(first), cpuCounters(latest)); ok {
+			// Nanoseconds of cpu time per second are cores.
+			rates.Cpu = &info.CpuRates{
+				Usage:  r[0] / secondsToNanoSeconds,
+				User:   r[1] / secondsToNanoSeconds,
+				System: r[2] / secondsToNanoSeconds,
+			}
+		}
+	}
+	if spec.HasNetwork {
+		if r, ok := rate(networkCounters(first), networkCounters(latest)); ok {
+			rates.Network = &info.NetworkRates{RxBytes: r[0], TxBytes: r[1], RxPackets: r[2], TxPackets: r[3]}
+		}
+	}
+	if spec.HasDiskIo {
+		if r, ok := rate(diskIoCounters(first), diskIoCounters(latest)); ok {
+			rates.DiskIo = &info.DiskIoRates{ReadBytes: r[0], WriteBytes: r[1], ReadIops: r[2], WriteIops: r[3]}
+		}
+	}
+	return rates, nil
+}
+
+func cpuCounters(stats *v1.ContainerStats) []uint64 {
+	return []uint64{stats.Cpu.Usage.Total, stats.Cpu.Usage.User, stats.Cpu.Usage.System}
+}
+
+// Sums the counters of the interfaces, or uses those of the default one for
+// the containers not reporting them per interface.
+func networkCounters(stats *v1.ContainerStats) []uint64 {
+	interfaces := stats.Network.Interfaces
+	if len(interfaces) == 0 {
+		interfaces = []v1.InterfaceStats{stats.Network.InterfaceStats}
+	}
+	counters := make([]uint64, 4)
+	for _, i := range interfaces {
+		counters[0] += i.RxBytes
+		counters[1] += i.TxBytes
+		counters[2] += i.RxPackets
+		counters[3] += i.TxPackets
+	}
+	return counters
+}
+
+func diskIoCounters(stats *v1.ContainerStats) []uint64 {
+	counters := make([]uint64, 4)
+	for _, disk := range stats.DiskIo.IoServiceBytes {
+		counters[0] += disk.Stats["Read"]
+		counters[1] += disk.Stats["Write"]
+	}
+	for _, disk := range stats.DiskIo.IoServiced {
+		counters[2] += disk.Stats["Read"]
+		counters[3] += disk.Stats["Write"]
+	}
+	return counters
+}
@@ -0,0 +1,95 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package summary
+
+import (
+	"testing"
+	"time"
+
+	v1 "github.com/yidoyoon/cadvisor-lite/info/v1"
+	info "github.com/yidoyoon/cadvisor-lite/info/v2"
+)
+
+func rateSample(timestamp time.Time, i uint64) *v1.ContainerStats {
+	stats := &v1.ContainerStats{Timestamp: timestamp}
+	stats.Cpu.Usage.Total = i * 2 * Nanosecond
+	stats.Cpu.Usage.User = i * 3 * Nanosecond / 2
+	stats.Cpu.Usage.System = i * Nanosecond / 2
+	stats.Network.Interfaces = []v1.InterfaceStats{
+		{Name: "eth0", RxBytes: i * 1000, TxBytes: i * 500, RxPackets: i * 10, TxPackets: i * 5},
+		{Name: "eth1", RxBytes: i * 1000},
+	}
+	stats.DiskIo.IoServiceBytes = []v1.PerDiskStats{{Device: "sda", Stats: map[string]uint64{"Read": i * 4096, "Write": i * 8192}}}
+	stats.DiskIo.IoServiced = []v1.PerDiskStats{{Device: "sda", Stats: map[string]uint64{"Read": i, "Write": i * 2}}}
+	return stats
+}
+
+func TestGetContainerRates(t *testing.T) {
+	spec := &v1.ContainerSpec{HasCpu: true, HasNetwork: true, HasDiskIo: true}
+	start := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	var stats []*v1.ContainerStats
+	for i := uint64(0); i <= 10; i++ {
+		stats = append(stats, rateSample(start.Add(time.Duration(i)*time.Second), i))
+	}
+	rates, err := GetContainerRates(spec, stats)
+	if err != nil {
+		t.Fatalf("GetContainerRates failed: %v", err)
+	}
+	expected := info.ContainerRates{
+		Timestamp: start.Add(10 * time.Second),
+		Samples:   11,
+		Interval:  10,
+		Cpu:       &info.CpuRates{Usage: 2, User: 1.5, System: 0.5},
+		Network:   &info.NetworkRates{RxBytes: 2000, TxBytes: 500, RxPackets: 10, TxPackets: 5},
+		DiskIo:    &info.DiskIoRates{ReadBytes: 4096, WriteBytes: 8192, ReadIops: 1, WriteIops: 2},
+	}
+	if rates.Timestamp != expected.Timestamp || rates.Samples != expected.Samples || rates.Interval != expected.Interval {
+		t.Errorf("rates are over %d samples, %f seconds until %v. Expected %+v", rates.Samples, rates.Interval, rates.Timestamp, expected)
+	}
+	if *rates.Cpu != *expected.Cpu {
+		t.Errorf("cpu rates are %+v. Expected %+v", *rates.Cpu, *expected.Cpu)
+	}
+	if *rates.Network != *expected.Network {
+		t.Errorf("network rates are %+v. Expected %+v", *rates.Network, *expected.Network)
+	}
+	if *rates.DiskIo != *expected.DiskIo {
+		t.Errorf("diskio rates are %+v. Expected %+v", *rates.DiskIo, *expected.DiskIo)
+	}
+}
+
+func TestGetContainerRatesCounterReset(t *testing.T) {
+	spec := &v1.ContainerSpec{HasCpu: true, HasNetwork: true}
+	start := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	latest := rateSample(start.Add(time.Second), 2)
+	latest.Network.Interfaces = nil
+	rates, err := GetContainerRates(spec, []*v1.ContainerStats{rateSample(start, 1), latest})
+	if err != nil {
+		t.Fatalf("GetContainerRates failed: %v", err)
+	}
+	if rates.Cpu == nil || rates.Network != nil || rates.DiskIo != nil {
+		t.Errorf("rates are %+v. Expected only the cpu ones", rates)
+	}
+}
+
+func TestGetContainerRatesNotEnoughStats(t *testing.T) {
+	start := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	spec := &v1.ContainerSpec{HasCpu: true}
+	if _, err := GetContainerRates(spec, []*v1.ContainerStats{rateSample(start, 1)}); err == nil {
+		t.Errorf("GetContainerRates succeeded with a single sample")
+	}
+	if _, err := GetContainerRates(spec, []*v1.ContainerStats{rateSample(start, 1), rateSample(start.Add(time.Millisecond), 2)}); err == nil {
+		t.Errorf("GetContainerRates succeeded over a millisecond")
+	}
+}
@@ -46,14 +46,22 @@ type StatsSummary struct {
 	available availableResources
 	// list of second samples. The list is cleared when a new minute samples is generated.
 	secondSamples []*secondSample
-	// minute percentiles. We track 24 * 60 maximum samples.
-	minuteSamples *SamplesBuffer
+	// minute percentiles, kept for the duration of the summary.
+	minuteSamples *SamplesBuffer // Guarded by dataLock.
 	// latest derived instant, minute, hour, and day stats. Instant sample updated every second.
 	// Others updated every minute.
 	derivedStats info.DerivedStats // Guarded by dataLock.
 	dataLock     sync.RWMutex
 }
 
+// State is the state of a summary saved to restore it, e.g. after a restart.
+type State struct {
+	// Time the state was saved at.
+	Timestamp time.Time `json:"timestamp"`
+	// Minute samples, from oldest to latest.
+	MinuteSamples []info.Usage `json:"minute_samples"`
+}
+
 // Adds a new seconds sample.
 // If enough seconds samples are collected, a minute sample is generated and derived
 // stats are updated.
@@ -84,7 +92,9 @@ func (s *StatsSummary) AddSample(stat v1.ContainerStats) error {
 		// Copying and resizing helps avoid slice re-allocation.
 		s.secondSamples[0] = s.secondSamples[numSamples-1]
 		s.secondSamples = s.secondSamples[:1]
+		s.dataLock.Lock()
 		s.minuteSamples.Add(minuteSample)
+		s.dataLock.Unlock()
 		err := s.updateDerivedStats()
 		if err != nil {
 			return err
@@ -160,6 +170,68 @@ func (s *StatsSummary) getDerivedUsage(n int) (info.Usage, error) {
 	return usage, nil
 }
 
+// WindowUsage returns the given percentiles, in [0, 100], of the usage over
+// each window, computed from the latest minute samples like the hour and day
+// usage. The windows are truncated to whole minutes.
+func (s *StatsSummary) WindowUsage(windows []time.Duration, percentiles []float64) ([]info.WindowUsage, error) {
+	s.dataLock.RLock()
+	defer s.dataLock.RUnlock()
+	usages := make([]info.WindowUsage, 0, len(windows))
+	for _, window := range windows {
+		n := int(window / time.Minute)
+		if n < 1 {
+			return nil, fmt.Errorf("window %v is shorter than a minute", window)
+		}
+		samples := s.minuteSamples.RecentStats(n)
+		if len(samples) < 1 {
+			return nil, fmt.Errorf("failed to retrieve any minute stats")
+		}
+		cpu, memory := GetCustomPercentiles(samples, percentiles)
+		usages = append(usages, info.WindowUsage{
+			Window: window.String(),
+			// Assumes we have equally placed minute samples.
+			PercentComplete: int32(len(samples) * 100 / n),
+			Cpu:             cpu,
+			Memory:          memory,
+		})
+	}
+	return usages, nil
+}
+
+// State returns the state of the summary to save.
+func (s *StatsSummary) State() State {
+	s.dataLock.RLock()
+	defer s.dataLock.RUnlock()
+	state := State{Timestamp: time.Now()}
+	for _, sample := range s.minuteSamples.RecentStats(s.minuteSamples.Size()) {
+		state.MinuteSamples = append(state.MinuteSamples, *sample)
+	}
+	return state
+}
+
+// Restore restores the minute samples of a saved state and the derived stats
+// computed from them. The samples that would have been overwritten since the
+// state was saved, one per minute, are dropped.
+func (s *StatsSummary) Restore(state State) error {
+	samples := state.MinuteSamples
+	missed := int(time.Since(state.Timestamp) / time.Minute)
+	if keep := s.minuteSamples.maxSize - missed; keep < len(samples) {
+		if keep < 0 {
+			keep = 0
+		}
+		samples = samples[len(samples)-keep:]
+	}
+	if len(samples) == 0 {
+		return nil
+	}
+	s.dataLock.Lock()
+	for _, sample := range samples {
+		s.minuteSamples.Add(sample)
+	}
+	s.dataLock.Unlock()
+	return s.updateDerivedStats()
+}
+
 // Return the latest calculated derived stats.
 func (s *StatsSummary) DerivedStats() (info.DerivedStats, error) {
 	s.dataLock.RLock()
@@ -168,7 +240,9 @@ func (s *StatsSummary) DerivedStats() (info.DerivedStats, error) {
 	return s.derivedStats, nil
 }
 
-func New(spec v1.ContainerSpec) (*StatsSummary, error) {
+// New returns the summary of the usage of a container, keeping the minute
+// samples of the given duration, of at least an hour.
+func New(spec v1.ContainerSpec, duration time.Duration) (*StatsSummary, error) {
 	summary := StatsSummary{}
 	if spec.HasCpu {
 		summary.available.Cpu = true
@@ -179,6 +253,10 @@ func New(spec v1.ContainerSpec) (*StatsSummary, error) {
 	if !summary.available.Cpu && !summary.available.Memory {
 		return nil, fmt.Errorf("none of the resources are being tracked")
 	}
-	summary.minuteSamples = NewSamplesBuffer(60 /* one hour */)
+	size := int(duration / time.Minute)
+	if size < 60 /* one hour */ {
+		size = 60
+	}
+	summary.minuteSamples = NewSamplesBuffer(size)
 	return &summary, nil
 }
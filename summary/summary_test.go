@@ -0,0 +1,108 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package summary
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	v1 "github.com/yidoyoon/cadvisor-lite/info/v1"
+	info "github.com/yidoyoon/cadvisor-lite/info/v2"
+)
+
+func TestStateRestore(t *testing.T) {
+	s, err := New(v1.ContainerSpec{HasCpu: true, HasMemory: true}, time.Hour)
+	require.NoError(t, err)
+	for i := uint64(1); i <= 3; i++ {
+		s.minuteSamples.Add(createSample(i))
+	}
+	state := s.State()
+	assert.Equal(t, []info.Usage{createSample(1), createSample(2), createSample(3)}, state.MinuteSamples)
+
+	restored, err := New(v1.ContainerSpec{HasCpu: true, HasMemory: true}, time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, restored.Restore(state))
+	expectElements(t, restored.minuteSamples, state.MinuteSamples)
+	derived, err := restored.DerivedStats()
+	require.NoError(t, err)
+	assert.Equal(t, createSample(3), derived.MinuteUsage)
+	assert.Equal(t, int32(5), derived.HourUsage.PercentComplete)
+	assert.Equal(t, uint64(300), derived.HourUsage.Cpu.Max)
+}
+
+func TestRestoreDropsMissedSamples(t *testing.T) {
+	state := State{Timestamp: time.Now().Add(-58*time.Minute - time.Second)}
+	for i := uint64(1); i <= 5; i++ {
+		state.MinuteSamples = append(state.MinuteSamples, createSample(i))
+	}
+
+	s, err := New(v1.ContainerSpec{HasCpu: true}, time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, s.Restore(state))
+	// 58 minute samples were missed while the state was not updated, only
+	// the 2 latest ones are still in the hour.
+	expectElements(t, s.minuteSamples, []info.Usage{createSample(4), createSample(5)})
+
+	state.Timestamp = time.Now().Add(-2 * time.Hour)
+	s, err = New(v1.ContainerSpec{HasCpu: true}, time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, s.Restore(state))
+	expectSize(t, s.minuteSamples, 0)
+}
+
+func TestWindowUsage(t *testing.T) {
+	s, err := New(v1.ContainerSpec{HasCpu: true, HasMemory: true}, 2*time.Hour)
+	require.NoError(t, err)
+	_, err = s.WindowUsage([]time.Duration{time.Hour}, []float64{50})
+	assert.Error(t, err)
+
+	for i := uint64(1); i <= 90; i++ {
+		s.minuteSamples.Add(createSample(i))
+	}
+	usages, err := s.WindowUsage([]time.Duration{10 * time.Minute, 6 * time.Hour}, []float64{50, 99.9})
+	require.NoError(t, err)
+	require.Len(t, usages, 2)
+
+	// The 10 latest samples.
+	assert.Equal(t, "10m0s", usages[0].Window)
+	assert.Equal(t, int32(100), usages[0].PercentComplete)
+	assert.Equal(t, info.CustomPercentiles{
+		Present:     true,
+		Mean:        4275,
+		Max:         90 * 100,
+		Percentiles: map[string]uint64{"50": 7695, "99.9": 90 * 90},
+	}, usages[0].Cpu)
+	assert.Equal(t, uint64(90*100*1024), usages[0].Memory.Max)
+
+	// All the 90 samples, the summary keeping 2 hours of them.
+	assert.Equal(t, "6h0m0s", usages[1].Window)
+	assert.Equal(t, int32(25), usages[1].PercentComplete)
+	assert.Equal(t, uint64(45*90+45), usages[1].Cpu.Percentiles["50"])
+
+	_, err = s.WindowUsage([]time.Duration{time.Second}, []float64{50})
+	assert.Error(t, err)
+}
+
+func TestNewKeepsAnHour(t *testing.T) {
+	s, err := New(v1.ContainerSpec{HasCpu: true}, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, 60, s.minuteSamples.maxSize)
+	s, err = New(v1.ContainerSpec{HasCpu: true}, 6*time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, 6*60, s.minuteSamples.maxSize)
+}
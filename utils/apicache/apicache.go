@@ -0,0 +1,226 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package apicache caches the responses of container runtime API calls, so
+// that bursts of requests to cAdvisor do not translate into bursts of calls
+// to the runtime daemons.
+package apicache
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/utils/clock"
+)
+
+var ttl = flag.Duration("runtime_api_cache_ttl", 2*time.Second, "Duration for which the responses of the container runtime APIs (e.g. docker info, inspect and image list) are cached. Concurrent identical calls are always coalesced into one. Zero value disables caching.")
+
+var (
+	cachesLock sync.Mutex
+	caches     []*Cache
+)
+
+type entry struct {
+	value  interface{}
+	expiry time.Time
+}
+
+// An in-flight call, shared by all callers of Get for the same key.
+type call struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+	// Value passed to panic by the fetch, nil if it returned.
+	panicked interface{}
+}
+
+// Stats counts the requests served by a cache.
+type Stats struct {
+	// Requests served from the cache.
+	Hits uint64
+	// Requests which called the runtime API.
+	Misses uint64
+	// Requests which waited for the result of an identical in-flight call.
+	Coalesced uint64
+	// Number of cached responses.
+	Entries int
+}
+
+// Cache caches the successful responses of an API call by key, and coalesces
+// concurrent calls for the same key. It is safe for concurrent use.
+type Cache struct {
+	name  string
+	ttl   func() time.Duration
+	clock clock.Clock
+
+	lock      sync.Mutex
+	entries   map[string]entry
+	calls     map[string]*call
+	lastSweep time.Time
+	stats     Stats
+}
+
+// New returns a cache of the API call name, using the TTL set by the
+// --runtime_api_cache_ttl flag. Its statistics are exported by the collector
+// returned by NewPrometheusCollector.
+func New(name string) *Cache {
+	c := newCache(name, func() time.Duration { return *ttl }, clock.RealClock{})
+	cachesLock.Lock()
+	defer cachesLock.Unlock()
+	caches = append(caches, c)
+	return c
+}
+
+func newCache(name string, ttl func() time.Duration, clock clock.Clock) *Cache {
+	return &Cache{
+		name:    name,
+		ttl:     ttl,
+		clock:   clock,
+		entries: make(map[string]entry),
+		calls:   make(map[string]*call),
+	}
+}
+
+// Name returns the name of the cached API call.
+func (c *Cache) Name() string {
+	return c.name
+}
+
+// Get returns the cached response for key if it has not expired. Otherwise it
+// calls fetch, or waits for the result of an in-flight fetch for the same key,
+// until ctx is done. Errors are returned to all waiting callers but are not
+// cached. If fetch panics, the waiting callers get an error and the panic goes
+// on in the caller which started the fetch, if it still waits for it.
+//
+// The fetch is shared by the callers, so it is not canceled with the context
+// of the caller which started it: it is passed a context which only expires at
+// the deadline of that caller, if any, and its response is still cached when
+// all the callers gave up on it.
+//
+// The responses are shared by all the callers and are not copied: they must
+// not be modified, including what they point to, e.g. the fields of a
+// ContainerJSON.
+func (c *Cache) Get(ctx context.Context, key string, fetch func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	c.lock.Lock()
+	now := c.clock.Now()
+	if e, ok := c.entries[key]; ok && now.Before(e.expiry) {
+		c.stats.Hits++
+		c.lock.Unlock()
+		return e.value, nil
+	}
+	current, inflight := c.calls[key]
+	if inflight {
+		c.stats.Coalesced++
+	} else {
+		current = &call{done: make(chan struct{})}
+		c.calls[key] = current
+		c.stats.Misses++
+		fetchCtx, cancel := detach(ctx)
+		go func() {
+			defer cancel()
+			c.fetch(fetchCtx, key, current, fetch)
+		}()
+	}
+	c.lock.Unlock()
+
+	select {
+	case <-current.done:
+		if current.panicked != nil && !inflight {
+			panic(current.panicked)
+		}
+		return current.value, current.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// fetch calls fetch for key, caches its response and completes current.
+func (c *Cache) fetch(ctx context.Context, key string, current *call, fetch func(ctx context.Context) (interface{}, error)) {
+	defer close(current.done)
+	defer func() {
+		if r := recover(); r != nil {
+			// Do not leave the waiting callers blocked.
+			current.value, current.err, current.panicked = nil, fmt.Errorf("%s call for %q panicked: %v", c.name, key, r), r
+			c.lock.Lock()
+			delete(c.calls, key)
+			c.lock.Unlock()
+		}
+	}()
+	current.value, current.err = fetch(ctx)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.calls, key)
+	ttl := c.ttl()
+	if current.err == nil && ttl > 0 {
+		now := c.clock.Now()
+		c.entries[key] = entry{value: current.value, expiry: now.Add(ttl)}
+		c.sweep(now, ttl)
+	} else {
+		delete(c.entries, key)
+	}
+}
+
+// detach returns a context which is not canceled with ctx, but expires at its
+// deadline if any.
+func detach(ctx context.Context) (context.Context, context.CancelFunc) {
+	if deadline, ok := ctx.Deadline(); ok {
+		return context.WithDeadline(context.Background(), deadline)
+	}
+	return context.WithCancel(context.Background())
+}
+
+// Removes the expired entries, at most once per TTL. Must be called with the
+// lock held.
+func (c *Cache) sweep(now time.Time, ttl time.Duration) {
+	if now.Sub(c.lastSweep) < ttl {
+		return
+	}
+	c.lastSweep = now
+	for key, e := range c.entries {
+		if !now.Before(e.expiry) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// Invalidate removes the cached response for key.
+func (c *Cache) Invalidate(key string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.entries, key)
+}
+
+// Stats returns the statistics of the cache.
+func (c *Cache) Stats() Stats {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	stats := c.stats
+	stats.Entries = len(c.entries)
+	return stats
+}
+
+// All returns the caches created with New, sorted by name.
+func All() []*Cache {
+	cachesLock.Lock()
+	defer cachesLock.Unlock()
+	result := make([]*Cache, len(caches))
+	copy(result, caches)
+	sort.Slice(result, func(i, j int) bool { return result[i].name < result[j].name })
+	return result
+}
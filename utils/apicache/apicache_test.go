@@ -0,0 +1,169 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apicache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+func TestGet(t *testing.T) {
+	fakeClock := clocktesting.NewFakeClock(time.Now())
+	c := newCache("info", func() time.Duration { return time.Second }, fakeClock)
+	calls := 0
+	fetch := func(context.Context) (interface{}, error) {
+		calls++
+		return calls, nil
+	}
+
+	v, err := c.Get(context.Background(), "a", fetch)
+	require.NoError(t, err)
+	assert.Equal(t, 1, v)
+	v, err = c.Get(context.Background(), "a", fetch)
+	require.NoError(t, err)
+	assert.Equal(t, 1, v)
+	v, err = c.Get(context.Background(), "b", fetch)
+	require.NoError(t, err)
+	assert.Equal(t, 2, v)
+
+	fakeClock.Step(time.Second)
+	v, err = c.Get(context.Background(), "a", fetch)
+	require.NoError(t, err)
+	assert.Equal(t, 3, v)
+	assert.Equal(t, Stats{Hits: 1, Misses: 3, Entries: 1}, c.Stats())
+
+	c.Invalidate("a")
+	v, err = c.Get(context.Background(), "a", fetch)
+	require.NoError(t, err)
+	assert.Equal(t, 4, v)
+
+	// Errors are not cached.
+	_, err = c.Get(context.Background(), "c", func(context.Context) (interface{}, error) { return nil, errors.New("failed") })
+	assert.Error(t, err)
+	v, err = c.Get(context.Background(), "c", fetch)
+	require.NoError(t, err)
+	assert.Equal(t, 5, v)
+}
+
+func TestGetCoalesces(t *testing.T) {
+	c := newCache("inspect", func() time.Duration { return 0 }, clocktesting.NewFakeClock(time.Now()))
+	release := make(chan struct{})
+	calls := 0
+	fetch := func(context.Context) (interface{}, error) {
+		calls++
+		<-release
+		return "result", nil
+	}
+
+	const callers = 5
+	var wg sync.WaitGroup
+	wg.Add(callers)
+	for i := 0; i < callers; i++ {
+		go func() {
+			defer wg.Done()
+			v, err := c.Get(context.Background(), "a", fetch)
+			assert.NoError(t, err)
+			assert.Equal(t, "result", v)
+		}()
+	}
+	require.Eventually(t, func() bool {
+		stats := c.Stats()
+		return stats.Misses+stats.Coalesced == callers
+	}, time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, 1, calls)
+	// Nothing is cached with a zero TTL.
+	assert.Equal(t, Stats{Misses: 1, Coalesced: callers - 1}, c.Stats())
+}
+
+func TestGetCanceled(t *testing.T) {
+	c := newCache("inspect", func() time.Duration { return time.Second }, clocktesting.NewFakeClock(time.Now()))
+	release := make(chan struct{})
+	fetched := make(chan error, 1)
+	fetch := func(ctx context.Context) (interface{}, error) {
+		<-release
+		fetched <- ctx.Err()
+		return "result", nil
+	}
+
+	// The caller which started the fetch gives up.
+	ctx, cancel := context.WithCancel(context.Background())
+	firstErr := make(chan error)
+	go func() {
+		_, err := c.Get(ctx, "a", fetch)
+		firstErr <- err
+	}()
+	require.Eventually(t, func() bool { return c.Stats().Misses == 1 }, time.Second, time.Millisecond)
+	waiter := make(chan interface{})
+	go func() {
+		v, err := c.Get(context.Background(), "a", fetch)
+		assert.NoError(t, err)
+		waiter <- v
+	}()
+	require.Eventually(t, func() bool { return c.Stats().Coalesced == 1 }, time.Second, time.Millisecond)
+	cancel()
+	assert.Equal(t, context.Canceled, <-firstErr)
+
+	// The fetch goes on for the other callers, and is cached.
+	close(release)
+	assert.NoError(t, <-fetched)
+	assert.Equal(t, "result", <-waiter)
+	v, err := c.Get(context.Background(), "a", fetch)
+	require.NoError(t, err)
+	assert.Equal(t, "result", v)
+	assert.Equal(t, uint64(1), c.Stats().Hits)
+}
+
+func TestGetPanic(t *testing.T) {
+	c := newCache("inspect", func() time.Duration { return time.Second }, clocktesting.NewFakeClock(time.Now()))
+	release := make(chan struct{})
+	waiterErr := make(chan error)
+	go func() {
+		defer func() {
+			assert.NotNil(t, recover())
+		}()
+		_, _ = c.Get(context.Background(), "a", func(context.Context) (interface{}, error) {
+			<-release
+			panic("failed")
+		})
+	}()
+	require.Eventually(t, func() bool { return c.Stats().Misses == 1 }, time.Second, time.Millisecond)
+	go func() {
+		_, err := c.Get(context.Background(), "a", func(context.Context) (interface{}, error) { return "result", nil })
+		waiterErr <- err
+	}()
+	require.Eventually(t, func() bool { return c.Stats().Coalesced == 1 }, time.Second, time.Millisecond)
+	close(release)
+
+	// The waiting caller gets an error, and nothing is cached.
+	select {
+	case err := <-waiterErr:
+		assert.Error(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("waiting caller blocked after a panic")
+	}
+	v, err := c.Get(context.Background(), "a", func(context.Context) (interface{}, error) { return "result", nil })
+	require.NoError(t, err)
+	assert.Equal(t, "result", v)
+}
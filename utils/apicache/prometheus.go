@@ -0,0 +1,55 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apicache
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	requestsDesc = prometheus.NewDesc(
+		"cadvisor_runtime_api_cache_requests_total",
+		"Number of container runtime API requests by cache result: hit, miss (the runtime was called) or coalesced (an identical in-flight call was awaited).",
+		[]string{"call", "result"}, nil)
+	entriesDesc = prometheus.NewDesc(
+		"cadvisor_runtime_api_cache_entries",
+		"Number of cached container runtime API responses.",
+		[]string{"call"}, nil)
+)
+
+// PrometheusCollector exports the statistics of the caches created with New.
+type PrometheusCollector struct{}
+
+// NewPrometheusCollector returns a new PrometheusCollector.
+func NewPrometheusCollector() *PrometheusCollector {
+	return &PrometheusCollector{}
+}
+
+// Describe implements prometheus.Collector.
+func (c *PrometheusCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- requestsDesc
+	ch <- entriesDesc
+}
+
+// Collect implements prometheus.Collector.
+func (c *PrometheusCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, cache := range All() {
+		stats := cache.Stats()
+		ch <- prometheus.MustNewConstMetric(requestsDesc, prometheus.CounterValue, float64(stats.Hits), cache.Name(), "hit")
+		ch <- prometheus.MustNewConstMetric(requestsDesc, prometheus.CounterValue, float64(stats.Misses), cache.Name(), "miss")
+		ch <- prometheus.MustNewConstMetric(requestsDesc, prometheus.CounterValue, float64(stats.Coalesced), cache.Name(), "coalesced")
+		ch <- prometheus.MustNewConstMetric(entriesDesc, prometheus.GaugeValue, float64(stats.Entries), cache.Name())
+	}
+}
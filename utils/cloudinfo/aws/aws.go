@@ -76,3 +76,7 @@ func (provider) GetInstanceType() info.InstanceType {
 func (provider) GetInstanceID() info.InstanceID {
 	return info.InstanceID(getAwsMetadata("instance-id"))
 }
+
+func (provider) GetZone() info.InstanceZone {
+	return info.InstanceZone(getAwsMetadata("placement/availability-zone"))
+}
@@ -15,8 +15,15 @@
 package cloudinfo
 
 import (
+	"encoding/json"
+	"fmt"
+	"net/http"
 	"os"
 	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
 
 	info "github.com/yidoyoon/cadvisor-lite/info/v1"
 	"github.com/yidoyoon/cadvisor-lite/utils/cloudinfo"
@@ -28,6 +35,9 @@ const (
 	microsoftCorporation = "Microsoft Corporation"
 )
 
+// Compute metadata of the Azure Instance Metadata Service.
+var computeMetadataURL = "http://169.254.169.254/metadata/instance/compute?api-version=2021-02-01"
+
 func init() {
 	cloudinfo.RegisterCloudProvider(info.Azure, &provider{})
 }
@@ -44,9 +54,61 @@ func (provider) IsActiveProvider() bool {
 	return strings.Contains(string(data), microsoftCorporation)
 }
 
-// TODO: Implement method.
+// computeMetadata is the part of the compute metadata of the instance used.
+type computeMetadata struct {
+	VMSize   string `json:"vmSize"`
+	Location string `json:"location"`
+	Zone     string `json:"zone"`
+}
+
+var (
+	metadataOnce sync.Once
+	metadata     *computeMetadata
+)
+
+// getComputeMetadata returns the compute metadata of the instance, queried
+// once, nil if it could not be.
+func getComputeMetadata() *computeMetadata {
+	metadataOnce.Do(func() {
+		m, err := queryComputeMetadata()
+		if err != nil {
+			klog.V(2).Infof("Error while querying the Azure instance metadata: %v", err)
+			return
+		}
+		metadata = m
+	})
+	return metadata
+}
+
+func queryComputeMetadata() (*computeMetadata, error) {
+	req, err := http.NewRequest(http.MethodGet, computeMetadataURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Metadata", "true")
+	// The metadata service is not proxied.
+	client := &http.Client{Timeout: 5 * time.Second, Transport: &http.Transport{Proxy: nil}}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %q", resp.Status)
+	}
+	var m computeMetadata
+	if err := json.NewDecoder(resp.Body).Decode(&m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
 func (provider) GetInstanceType() info.InstanceType {
-	return info.UnknownInstance
+	m := getComputeMetadata()
+	if m == nil || m.VMSize == "" {
+		return info.UnknownInstance
+	}
+	return info.InstanceType(m.VMSize)
 }
 
 func (provider) GetInstanceID() info.InstanceID {
@@ -56,3 +118,17 @@ func (provider) GetInstanceID() info.InstanceID {
 	}
 	return info.InstanceID(strings.TrimSuffix(string(data), "\n"))
 }
+
+// GetZone returns the location of the instance followed by its availability
+// zone, e.g. eastus-1, as Kubernetes names the Azure zones, or only its
+// location if it is not in an availability zone.
+func (provider) GetZone() info.InstanceZone {
+	m := getComputeMetadata()
+	if m == nil || m.Location == "" {
+		return info.UnknownZone
+	}
+	if m.Zone == "" {
+		return info.InstanceZone(m.Location)
+	}
+	return info.InstanceZone(m.Location + "-" + m.Zone)
+}
@@ -0,0 +1,68 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudinfo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+)
+
+func TestQueryComputeMetadata(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("Metadata") != "true" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"vmSize":"Standard_D4s_v3","location":"eastus","zone":"2","vmId":"02aab8a4-74ef-476e-8182-f6d2ba4166a6"}`))
+	}))
+	defer server.Close()
+	defer func(url string) { computeMetadataURL = url }(computeMetadataURL)
+
+	computeMetadataURL = server.URL
+	m, err := queryComputeMetadata()
+	require.NoError(t, err)
+	assert.Equal(t, &computeMetadata{VMSize: "Standard_D4s_v3", Location: "eastus", Zone: "2"}, m)
+
+	computeMetadataURL = server.URL + "/missing"
+	_, err = queryComputeMetadata()
+	assert.EqualError(t, err, `unexpected status "404 Not Found"`)
+}
+
+func TestGetZone(t *testing.T) {
+	defer func() { metadata = nil }()
+	metadataOnce.Do(func() {})
+
+	metadata = &computeMetadata{VMSize: "Standard_D4s_v3", Location: "eastus", Zone: "2"}
+	assert.Equal(t, info.InstanceZone("eastus-2"), provider{}.GetZone())
+	assert.Equal(t, info.InstanceType("Standard_D4s_v3"), provider{}.GetInstanceType())
+
+	// Instances not in an availability zone are only in their location.
+	metadata = &computeMetadata{Location: "westeurope"}
+	assert.Equal(t, info.InstanceZone("westeurope"), provider{}.GetZone())
+	assert.Equal(t, info.InstanceType(info.UnknownInstance), provider{}.GetInstanceType())
+
+	metadata = nil
+	assert.Equal(t, info.UnknownZone, provider{}.GetZone())
+}
@@ -26,6 +26,7 @@ type CloudInfo interface {
 	GetCloudProvider() info.CloudProvider
 	GetInstanceType() info.InstanceType
 	GetInstanceID() info.InstanceID
+	GetZone() info.InstanceZone
 }
 
 // CloudProvider is an abstraction for providing cloud-specific information.
@@ -39,6 +40,9 @@ type CloudProvider interface {
 	// GetInstanceType gets the ID of the instance this process is running on.
 	// The behavior is undefined if this is not the active provider.
 	GetInstanceID() info.InstanceID
+	// GetZone gets the zone of the instance this process is running on.
+	// The behavior is undefined if this is not the active provider.
+	GetZone() info.InstanceZone
 }
 
 var providers = map[info.CloudProvider]CloudProvider{}
@@ -55,6 +59,7 @@ type realCloudInfo struct {
 	cloudProvider info.CloudProvider
 	instanceType  info.InstanceType
 	instanceID    info.InstanceID
+	zone          info.InstanceZone
 }
 
 func NewRealCloudInfo() CloudInfo {
@@ -64,6 +69,7 @@ func NewRealCloudInfo() CloudInfo {
 				cloudProvider: name,
 				instanceType:  provider.GetInstanceType(),
 				instanceID:    provider.GetInstanceID(),
+				zone:          provider.GetZone(),
 			}
 		}
 	}
@@ -73,6 +79,7 @@ func NewRealCloudInfo() CloudInfo {
 		cloudProvider: info.UnknownProvider,
 		instanceType:  info.UnknownInstance,
 		instanceID:    info.UnNamedInstance,
+		zone:          info.UnknownZone,
 	}
 }
 
@@ -87,3 +94,7 @@ func (i *realCloudInfo) GetInstanceType() info.InstanceType {
 func (i *realCloudInfo) GetInstanceID() info.InstanceID {
 	return i.instanceID
 }
+
+func (i *realCloudInfo) GetZone() info.InstanceZone {
+	return i.zone
+}
@@ -64,3 +64,11 @@ func (provider) GetInstanceID() info.InstanceID {
 	}
 	return info.InstanceID(info.InstanceType(instanceID))
 }
+
+func (provider) GetZone() info.InstanceZone {
+	zone, err := metadata.Zone()
+	if err != nil {
+		return info.UnknownZone
+	}
+	return info.InstanceZone(zone)
+}
@@ -0,0 +1,116 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package forecast fits simple trends on time series to project them over a
+// short horizon.
+package forecast
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Sample is a value of a time series.
+type Sample struct {
+	Timestamp time.Time
+	Value     float64
+}
+
+// Trend is a linear trend fitted on a time series.
+type Trend struct {
+	// Time of the last sample.
+	Timestamp time.Time
+	// Fitted value at Timestamp.
+	Level float64
+	// Change of the value per second.
+	Rate float64
+}
+
+// At returns the projected value at t.
+func (t Trend) At(at time.Time) float64 {
+	return t.Level + t.Rate*at.Sub(t.Timestamp).Seconds()
+}
+
+// TimeTo returns the projected time from Timestamp until the value reaches
+// target. Returns false if the trend never reaches it.
+func (t Trend) TimeTo(target float64) (time.Duration, bool) {
+	if t.Level >= target {
+		return 0, true
+	}
+	if t.Rate <= 0 {
+		return 0, false
+	}
+	seconds := (target - t.Level) / t.Rate
+	if seconds > math.MaxInt64/float64(time.Second) {
+		return 0, false
+	}
+	return time.Duration(seconds * float64(time.Second)), true
+}
+
+func checkSamples(samples []Sample) error {
+	if len(samples) < 2 {
+		return fmt.Errorf("at least 2 samples are required, got %d", len(samples))
+	}
+	for i := 1; i < len(samples); i++ {
+		if !samples[i].Timestamp.After(samples[i-1].Timestamp) {
+			return fmt.Errorf("samples are not in increasing time order")
+		}
+	}
+	return nil
+}
+
+// Linear fits a trend with ordinary least squares. Samples must be in
+// increasing time order.
+func Linear(samples []Sample) (Trend, error) {
+	if err := checkSamples(samples); err != nil {
+		return Trend{}, err
+	}
+	last := samples[len(samples)-1].Timestamp
+	n := float64(len(samples))
+	var sumX, sumY, sumXY, sumXX float64
+	for _, s := range samples {
+		// Seconds relative to the last sample, to keep the sums small.
+		x := s.Timestamp.Sub(last).Seconds()
+		sumX += x
+		sumY += s.Value
+		sumXY += x * s.Value
+		sumXX += x * x
+	}
+	rate := (n*sumXY - sumX*sumY) / (n*sumXX - sumX*sumX)
+	intercept := (sumY - rate*sumX) / n
+	return Trend{Timestamp: last, Level: intercept, Rate: rate}, nil
+}
+
+// Holt fits a trend with double exponential smoothing, which follows recent
+// changes of the trend more closely than Linear. alpha and beta, between 0
+// and 1, are the smoothing factors of the level and of the trend. Samples
+// must be in increasing time order.
+func Holt(samples []Sample, alpha, beta float64) (Trend, error) {
+	if err := checkSamples(samples); err != nil {
+		return Trend{}, err
+	}
+	if alpha <= 0 || alpha > 1 || beta <= 0 || beta > 1 {
+		return Trend{}, fmt.Errorf("smoothing factors must be in (0, 1], got %v and %v", alpha, beta)
+	}
+	level := samples[0].Value
+	rate := (samples[1].Value - samples[0].Value) / samples[1].Timestamp.Sub(samples[0].Timestamp).Seconds()
+	for i := 1; i < len(samples); i++ {
+		dt := samples[i].Timestamp.Sub(samples[i-1].Timestamp).Seconds()
+		prevLevel := level
+		level = alpha*samples[i].Value + (1-alpha)*(level+rate*dt)
+		rate = beta*(level-prevLevel)/dt + (1-beta)*rate
+	}
+	return Trend{Timestamp: samples[len(samples)-1].Timestamp, Level: level, Rate: rate}, nil
+}
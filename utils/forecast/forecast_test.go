@@ -0,0 +1,79 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forecast
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Samples growing by rate per second from 1000, every 10 seconds.
+func linearSamples(start time.Time, n int, rate float64) []Sample {
+	samples := make([]Sample, n)
+	for i := range samples {
+		samples[i] = Sample{Timestamp: start.Add(time.Duration(i) * 10 * time.Second), Value: 1000 + rate*float64(i*10)}
+	}
+	return samples
+}
+
+func TestLinear(t *testing.T) {
+	start := time.Now()
+	samples := linearSamples(start, 10, 2)
+
+	trend, err := Linear(samples)
+	require.NoError(t, err)
+	assert.Equal(t, samples[9].Timestamp, trend.Timestamp)
+	assert.InDelta(t, 1180, trend.Level, 1e-6)
+	assert.InDelta(t, 2, trend.Rate, 1e-9)
+	assert.InDelta(t, 1200, trend.At(trend.Timestamp.Add(10*time.Second)), 1e-6)
+
+	toLimit, ok := trend.TimeTo(1380)
+	assert.True(t, ok)
+	assert.InDelta(t, 100*time.Second, toLimit, float64(time.Millisecond))
+}
+
+func TestHolt(t *testing.T) {
+	samples := linearSamples(time.Now(), 20, 2)
+
+	trend, err := Holt(samples, 0.5, 0.3)
+	require.NoError(t, err)
+	assert.InDelta(t, 1380, trend.Level, 1e-6)
+	assert.InDelta(t, 2, trend.Rate, 1e-9)
+
+	_, err = Holt(samples, 0, 0.3)
+	assert.Error(t, err)
+}
+
+func TestTimeToNeverReached(t *testing.T) {
+	trend, err := Linear(linearSamples(time.Now(), 10, -1))
+	require.NoError(t, err)
+	_, ok := trend.TimeTo(2000)
+	assert.False(t, ok)
+
+	toLimit, ok := trend.TimeTo(500)
+	assert.True(t, ok, "limit already reached")
+	assert.Zero(t, toLimit)
+}
+
+func TestInvalidSamples(t *testing.T) {
+	now := time.Now()
+	_, err := Linear([]Sample{{Timestamp: now}})
+	assert.Error(t, err)
+	_, err = Linear([]Sample{{Timestamp: now}, {Timestamp: now}})
+	assert.Error(t, err)
+}
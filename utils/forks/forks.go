@@ -0,0 +1,232 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package forks counts the forks of the cgroups from the fork events of the
+// process events connector of the kernel. The pids controller does not count
+// them: pids.events only counts the forks which failed.
+package forks
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/opencontainers/runc/libcontainer/cgroups"
+	"github.com/opencontainers/runc/libcontainer/cgroups/fs2"
+	"golang.org/x/sys/unix"
+
+	"k8s.io/klog/v2"
+)
+
+// Constants of linux/connector.h and linux/cn_proc.h.
+const (
+	cnIdxProc         = 1
+	cnValProc         = 1
+	procCnMcastListen = 1
+	procEventFork     = 1
+
+	// Size of struct cn_msg, without its data.
+	cnMsgSize = 20
+	// Offset of the data of struct proc_event, after what, cpu and
+	// timestamp_ns.
+	procEventDataOffset = 16
+	// Size of struct proc_event with the data of a fork.
+	procForkEventSize = procEventDataOffset + 16
+)
+
+// Counts of the cgroups which no longer exist are dropped at this interval.
+const pruneInterval = time.Minute
+
+// The connector sends the events in the byte order of the host.
+// TODO: Verify and fix for other architectures, as utils/cpuload/netlink.
+var endian = binary.LittleEndian
+
+// Counter counts the forks of the cgroups and of their descendants, threads
+// included, since it was started.
+type Counter struct {
+	fd int
+	// Root of the /proc file system, and mount point of the cgroups in
+	// which the forks are counted: the unified hierarchy, or the pids
+	// controller for cgroup v1.
+	procRoot    string
+	mountPoint  string
+	subsystem   string
+	lock        sync.Mutex
+	counts      map[string]uint64
+	lastPrune   time.Time
+	lostReports bool
+}
+
+// New returns a counter listening to the fork events of the kernel, which
+// requires the CAP_NET_ADMIN capability in the initial network namespace.
+func New() (*Counter, error) {
+	c := &Counter{
+		procRoot: "/proc",
+		counts:   make(map[string]uint64),
+	}
+	if cgroups.IsCgroup2UnifiedMode() {
+		c.mountPoint = fs2.UnifiedMountpoint
+	} else {
+		mountPoint, err := cgroups.FindCgroupMountpoint("", "pids")
+		if err != nil {
+			return nil, err
+		}
+		c.mountPoint = mountPoint
+		c.subsystem = "pids"
+	}
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_DGRAM, unix.NETLINK_CONNECTOR)
+	if err != nil {
+		return nil, err
+	}
+	c.fd = fd
+	addr := &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK, Groups: cnIdxProc, Pid: uint32(os.Getpid())}
+	if err := syscall.Bind(fd, addr); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+	if err := c.listen(); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("failed to listen to the process events: %v", err)
+	}
+	go c.read()
+	return c, nil
+}
+
+// listen asks the connector for the process events.
+func (c *Counter) listen() error {
+	var msg bytes.Buffer
+	header := syscall.NlMsghdr{
+		Len:  syscall.NLMSG_HDRLEN + cnMsgSize + 4,
+		Type: syscall.NLMSG_DONE,
+		Pid:  uint32(os.Getpid()),
+	}
+	cnMsg := struct {
+		Idx, Val, Seq, Ack uint32
+		Len, Flags         uint16
+		Op                 uint32
+	}{Idx: cnIdxProc, Val: cnValProc, Len: 4, Op: procCnMcastListen}
+	if err := binary.Write(&msg, endian, header); err != nil {
+		return err
+	}
+	if err := binary.Write(&msg, endian, cnMsg); err != nil {
+		return err
+	}
+	return syscall.Sendto(c.fd, msg.Bytes(), 0, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK})
+}
+
+// read counts the fork events until the socket fails.
+func (c *Counter) read() {
+	buf := make([]byte, os.Getpagesize())
+	for {
+		n, _, err := syscall.Recvfrom(c.fd, buf, 0)
+		if err == syscall.ENOBUFS {
+			// Events were dropped since the counter did not keep up.
+			if !c.lostReports {
+				klog.Warningf("Fork events were lost, the forks of the containers are undercounted")
+				c.lostReports = true
+			}
+			continue
+		}
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil {
+			klog.Errorf("Stopped counting the forks: %v", err)
+			return
+		}
+		msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+		if err != nil {
+			klog.V(4).Infof("Invalid process event: %v", err)
+			continue
+		}
+		for _, msg := range msgs {
+			if childPid, childTgid, parentTgid, ok := parseForkEvent(msg.Data); ok {
+				c.fork(childPid, childTgid, parentTgid, time.Now())
+			}
+		}
+	}
+}
+
+// parseForkEvent returns the ids of the new task and of the process which
+// forked it from the data of a netlink message of the connector, and false
+// if it is not a fork event.
+func parseForkEvent(data []byte) (childPid, childTgid, parentTgid int, ok bool) {
+	if len(data) < cnMsgSize+procForkEventSize {
+		return 0, 0, 0, false
+	}
+	if endian.Uint32(data[0:]) != cnIdxProc || endian.Uint32(data[4:]) != cnValProc {
+		return 0, 0, 0, false
+	}
+	event := data[cnMsgSize:]
+	if endian.Uint32(event[0:]) != procEventFork {
+		return 0, 0, 0, false
+	}
+	fork := event[procEventDataOffset:]
+	parentTgid = int(endian.Uint32(fork[4:]))
+	childPid = int(endian.Uint32(fork[8:]))
+	childTgid = int(endian.Uint32(fork[12:]))
+	return childPid, childTgid, parentTgid, true
+}
+
+// fork counts the fork of a task in its cgroup and their ancestors. The
+// cgroup of the forking process is counted if the task is already gone.
+func (c *Counter) fork(childPid, childTgid, parentTgid int, now time.Time) {
+	cgroup, ok := c.cgroup(path.Join(c.procRoot, strconv.Itoa(childTgid), "task", strconv.Itoa(childPid), "cgroup"))
+	if !ok {
+		if cgroup, ok = c.cgroup(path.Join(c.procRoot, strconv.Itoa(parentTgid), "cgroup")); !ok {
+			return
+		}
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for p := path.Join(c.mountPoint, cgroup); ; p = path.Dir(p) {
+		c.counts[p]++
+		if p == c.mountPoint || p == "/" || p == "." {
+			break
+		}
+	}
+	if now.Sub(c.lastPrune) > pruneInterval {
+		for p := range c.counts {
+			if _, err := os.Stat(p); os.IsNotExist(err) {
+				delete(c.counts, p)
+			}
+		}
+		c.lastPrune = now
+	}
+}
+
+// cgroup returns the path of the cgroup of the hierarchy of the counter
+// listed in the cgroup file of a task.
+func (c *Counter) cgroup(file string) (string, bool) {
+	cgroups, err := cgroups.ParseCgroupFile(file)
+	if err != nil {
+		return "", false
+	}
+	cgroup, ok := cgroups[c.subsystem]
+	return cgroup, ok
+}
+
+// Forks returns the number of tasks forked in the cgroup at cgroupPath and
+// its descendants since the counter was started.
+func (c *Counter) Forks(cgroupPath string) uint64 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.counts[path.Clean(cgroupPath)]
+}
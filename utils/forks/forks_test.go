@@ -0,0 +1,88 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forks
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func forkEvent(what, parentTgid, childPid, childTgid uint32) []byte {
+	data := make([]byte, cnMsgSize+procForkEventSize)
+	endian.PutUint32(data[0:], cnIdxProc)
+	endian.PutUint32(data[4:], cnValProc)
+	endian.PutUint16(data[16:], procForkEventSize)
+	event := data[cnMsgSize:]
+	endian.PutUint32(event[0:], what)
+	fork := event[procEventDataOffset:]
+	endian.PutUint32(fork[0:], parentTgid)
+	endian.PutUint32(fork[4:], parentTgid)
+	endian.PutUint32(fork[8:], childPid)
+	endian.PutUint32(fork[12:], childTgid)
+	return data
+}
+
+func TestParseForkEvent(t *testing.T) {
+	childPid, childTgid, parentTgid, ok := parseForkEvent(forkEvent(procEventFork, 10, 21, 20))
+	assert.True(t, ok)
+	assert.Equal(t, 21, childPid)
+	assert.Equal(t, 20, childTgid)
+	assert.Equal(t, 10, parentTgid)
+
+	// Exec event.
+	_, _, _, ok = parseForkEvent(forkEvent(2, 10, 21, 20))
+	assert.False(t, ok)
+	_, _, _, ok = parseForkEvent(forkEvent(procEventFork, 10, 21, 20)[:cnMsgSize+8])
+	assert.False(t, ok)
+}
+
+func writeCgroup(t *testing.T, procRoot, dir, cgroup string) {
+	assert.NoError(t, os.MkdirAll(filepath.Join(procRoot, dir), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(procRoot, dir, "cgroup"), []byte(cgroup), 0644))
+}
+
+func TestFork(t *testing.T) {
+	procRoot := t.TempDir()
+	mountPoint := t.TempDir()
+	for _, dir := range []string{"a/b", "c"} {
+		assert.NoError(t, os.MkdirAll(filepath.Join(mountPoint, dir), 0755))
+	}
+	writeCgroup(t, procRoot, "20/task/21", "0::/a/b\n")
+	writeCgroup(t, procRoot, "10", "0::/c\n")
+	c := &Counter{procRoot: procRoot, mountPoint: mountPoint, counts: make(map[string]uint64)}
+
+	now := time.Now()
+	c.fork(21, 20, 10, now)
+	// The task is gone, the cgroup of its parent is counted.
+	c.fork(31, 30, 10, now)
+	// Both are gone.
+	c.fork(41, 40, 11, now)
+
+	assert.Equal(t, uint64(1), c.Forks(filepath.Join(mountPoint, "a/b")))
+	assert.Equal(t, uint64(1), c.Forks(filepath.Join(mountPoint, "a")))
+	assert.Equal(t, uint64(1), c.Forks(filepath.Join(mountPoint, "c")+"/"))
+	assert.Equal(t, uint64(2), c.Forks(mountPoint))
+	assert.Equal(t, uint64(0), c.Forks(filepath.Join(mountPoint, "d")))
+
+	// The counts of removed cgroups are dropped.
+	assert.NoError(t, os.Remove(filepath.Join(mountPoint, "a/b")))
+	c.fork(21, 20, 10, now.Add(2*pruneInterval))
+	assert.Equal(t, uint64(0), c.Forks(filepath.Join(mountPoint, "a/b")))
+	assert.Equal(t, uint64(2), c.Forks(filepath.Join(mountPoint, "a")))
+}
@@ -0,0 +1,190 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package msgpack encodes values as MessagePack, with the same fields as their
+// JSON encoding, for clients wanting a compact binary encoding.
+package msgpack
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// Marshal returns the MessagePack encoding of v. Its fields are the ones of
+// the JSON encoding of v, in the same order: v is encoded as JSON first, which
+// honors the JSON tags and marshalers, and then transcoded.
+func Marshal(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return FromJSON(data)
+}
+
+// FromJSON transcodes a JSON document to MessagePack. Objects become maps with
+// string keys, integers the smallest integer type holding them and other
+// numbers 64-bit floats.
+func FromJSON(data []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var out bytes.Buffer
+	if err := transcode(decoder, &out); err != nil {
+		return nil, err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, fmt.Errorf("invalid JSON: data after the top-level value")
+	}
+	return out.Bytes(), nil
+}
+
+// transcode writes the next JSON value of decoder to out.
+func transcode(decoder *json.Decoder, out *bytes.Buffer) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return fmt.Errorf("invalid JSON: %v", err)
+	}
+	switch token := token.(type) {
+	case nil:
+		out.WriteByte(0xc0)
+	case bool:
+		if token {
+			out.WriteByte(0xc3)
+		} else {
+			out.WriteByte(0xc2)
+		}
+	case json.Number:
+		return writeNumber(out, token)
+	case string:
+		writeString(out, token)
+	case json.Delim:
+		// The elements are counted before the header of their array or
+		// map can be written.
+		var elements bytes.Buffer
+		n := 0
+		for decoder.More() {
+			if token == '{' {
+				key, err := decoder.Token()
+				if err != nil {
+					return fmt.Errorf("invalid JSON: %v", err)
+				}
+				writeString(&elements, key.(string))
+			}
+			if err := transcode(decoder, &elements); err != nil {
+				return err
+			}
+			n++
+		}
+		if _, err := decoder.Token(); err != nil {
+			return fmt.Errorf("invalid JSON: %v", err)
+		}
+		if token == '{' {
+			writeHeader(out, n, 0x80, 0xde, 0xdf)
+		} else {
+			writeHeader(out, n, 0x90, 0xdc, 0xdd)
+		}
+		out.Write(elements.Bytes())
+	}
+	return nil
+}
+
+// writeHeader writes the header of a map or array of n elements, as a fix
+// header of up to 15 elements, or with a 16-bit or 32-bit length.
+func writeHeader(out *bytes.Buffer, n int, fix, code16, code32 byte) {
+	switch {
+	case n < 16:
+		out.WriteByte(fix | byte(n))
+	case n <= math.MaxUint16:
+		out.WriteByte(code16)
+		_ = binary.Write(out, binary.BigEndian, uint16(n))
+	default:
+		out.WriteByte(code32)
+		_ = binary.Write(out, binary.BigEndian, uint32(n))
+	}
+}
+
+func writeString(out *bytes.Buffer, s string) {
+	switch n := len(s); {
+	case n < 32:
+		out.WriteByte(0xa0 | byte(n))
+	case n <= math.MaxUint8:
+		out.WriteByte(0xd9)
+		out.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		out.WriteByte(0xda)
+		_ = binary.Write(out, binary.BigEndian, uint16(n))
+	default:
+		out.WriteByte(0xdb)
+		_ = binary.Write(out, binary.BigEndian, uint32(n))
+	}
+	out.WriteString(s)
+}
+
+func writeNumber(out *bytes.Buffer, number json.Number) error {
+	if i, err := strconv.ParseInt(string(number), 10, 64); err == nil {
+		writeInt(out, i)
+		return nil
+	}
+	if u, err := strconv.ParseUint(string(number), 10, 64); err == nil {
+		out.WriteByte(0xcf)
+		_ = binary.Write(out, binary.BigEndian, u)
+		return nil
+	}
+	f, err := number.Float64()
+	if err != nil {
+		return fmt.Errorf("invalid JSON number %q: %v", number, err)
+	}
+	out.WriteByte(0xcb)
+	_ = binary.Write(out, binary.BigEndian, math.Float64bits(f))
+	return nil
+}
+
+func writeInt(out *bytes.Buffer, i int64) {
+	switch {
+	case i >= 0 && i <= math.MaxInt8:
+		// Positive fixint.
+		out.WriteByte(byte(i))
+	case i < 0 && i >= -32:
+		// Negative fixint.
+		out.WriteByte(byte(int8(i)))
+	case i >= 0 && i <= math.MaxUint8:
+		out.WriteByte(0xcc)
+		out.WriteByte(byte(i))
+	case i >= 0 && i <= math.MaxUint16:
+		out.WriteByte(0xcd)
+		_ = binary.Write(out, binary.BigEndian, uint16(i))
+	case i >= 0 && i <= math.MaxUint32:
+		out.WriteByte(0xce)
+		_ = binary.Write(out, binary.BigEndian, uint32(i))
+	case i >= 0:
+		out.WriteByte(0xcf)
+		_ = binary.Write(out, binary.BigEndian, uint64(i))
+	case i >= math.MinInt8:
+		out.WriteByte(0xd0)
+		out.WriteByte(byte(int8(i)))
+	case i >= math.MinInt16:
+		out.WriteByte(0xd1)
+		_ = binary.Write(out, binary.BigEndian, int16(i))
+	case i >= math.MinInt32:
+		out.WriteByte(0xd2)
+		_ = binary.Write(out, binary.BigEndian, int32(i))
+	default:
+		out.WriteByte(0xd3)
+		_ = binary.Write(out, binary.BigEndian, i)
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package msgpack
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromJSON(t *testing.T) {
+	tests := []struct {
+		json     string
+		expected []byte
+	}{
+		{`null`, []byte{0xc0}},
+		{`true`, []byte{0xc3}},
+		{`false`, []byte{0xc2}},
+		{`0`, []byte{0x00}},
+		{`127`, []byte{0x7f}},
+		{`128`, []byte{0xcc, 0x80}},
+		{`65535`, []byte{0xcd, 0xff, 0xff}},
+		{`65536`, []byte{0xce, 0x00, 0x01, 0x00, 0x00}},
+		{`4294967296`, []byte{0xcf, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00}},
+		{`18446744073709551615`, []byte{0xcf, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{`-1`, []byte{0xff}},
+		{`-32`, []byte{0xe0}},
+		{`-33`, []byte{0xd0, 0xdf}},
+		{`-129`, []byte{0xd1, 0xff, 0x7f}},
+		{`-32769`, []byte{0xd2, 0xff, 0xff, 0x7f, 0xff}},
+		{`-2147483649`, []byte{0xd3, 0xff, 0xff, 0xff, 0xff, 0x7f, 0xff, 0xff, 0xff}},
+		{`1.5`, []byte{0xcb, 0x3f, 0xf8, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}},
+		{`"a"`, []byte{0xa1, 'a'}},
+		{`[]`, []byte{0x90}},
+		{`{}`, []byte{0x80}},
+		// Keys are kept in order.
+		{`{"b":[1,"x"],"a":null}`, []byte{0x82, 0xa1, 'b', 0x92, 0x01, 0xa1, 'x', 0xa1, 'a', 0xc0}},
+	}
+	for _, test := range tests {
+		actual, err := FromJSON([]byte(test.json))
+		assert.NoError(t, err, test.json)
+		assert.Equal(t, test.expected, actual, test.json)
+	}
+}
+
+func TestFromJSONLengths(t *testing.T) {
+	s := strings.Repeat("x", 32)
+	actual, err := FromJSON([]byte(`"` + s + `"`))
+	assert.NoError(t, err)
+	assert.Equal(t, append([]byte{0xd9, 32}, s...), actual)
+
+	s = strings.Repeat("x", 256)
+	actual, err = FromJSON([]byte(`"` + s + `"`))
+	assert.NoError(t, err)
+	assert.Equal(t, append([]byte{0xda, 0x01, 0x00}, s...), actual)
+
+	array := "[" + strings.TrimSuffix(strings.Repeat("1,", 16), ",") + "]"
+	actual, err = FromJSON([]byte(array))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0xdc, 0x00, 0x10}, actual[:3])
+	assert.Len(t, actual, 3+16)
+}
+
+func TestMarshal(t *testing.T) {
+	type sample struct {
+		Name  string `json:"name"`
+		Value uint64 `json:"value,omitempty"`
+	}
+	actual, err := Marshal(map[string][]sample{"/": {{Name: "cpu"}}})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x81, 0xa1, '/', 0x91, 0x81, 0xa4, 'n', 'a', 'm', 'e', 0xa3, 'c', 'p', 'u'}, actual)
+}
+
+func TestFromJSONInvalid(t *testing.T) {
+	for _, data := range []string{``, `{"a":`, `[1,]`, `1 2`} {
+		_, err := FromJSON([]byte(data))
+		assert.Error(t, err, data)
+	}
+}
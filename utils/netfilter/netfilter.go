@@ -0,0 +1,195 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package netfilter reads the packet and byte counters of the netfilter
+// chains and rules of the machine with the iptables-save utilities.
+package netfilter
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	FamilyIPv4 = "ipv4"
+	FamilyIPv6 = "ipv6"
+)
+
+var interval = flag.Duration("netfilter_interval", time.Minute, "Interval between two reads of the netfilter counters with iptables-save, which are reported with the stats of the root container in the meantime.")
+
+// saveCommands are the commands dumping the tables of each family.
+var saveCommands = map[string]string{
+	FamilyIPv4: "iptables-save",
+	FamilyIPv6: "ip6tables-save",
+}
+
+var (
+	cacheLock sync.Mutex
+	cached    []info.NetfilterChain
+	cachedErr error
+	readAt    time.Time
+	// Overridden by tests.
+	readChains = read
+	now        = time.Now
+)
+
+// Chains returns the counters of the chains of all tables of the machine,
+// read at most once per --netfilter_interval since dumping the tables is
+// costly on machines with many rules. The chains are shared by the callers,
+// which must not modify them.
+func Chains() ([]info.NetfilterChain, error) {
+	cacheLock.Lock()
+	defer cacheLock.Unlock()
+	if t := now(); readAt.IsZero() || t.Sub(readAt) >= *interval {
+		cached, cachedErr = readChains()
+		readAt = t
+	}
+	return cached, cachedErr
+}
+
+// read dumps the counters of the chains of all tables of the machine. A
+// family is skipped if its tables cannot be dumped, e.g. because
+// ip6tables-save is not installed, an error is only returned if none can.
+func read() ([]info.NetfilterChain, error) {
+	chains := []info.NetfilterChain{}
+	var errs []string
+	for _, family := range []string{FamilyIPv4, FamilyIPv6} {
+		out, err := save(saveCommands[family])
+		if err == nil {
+			var c []info.NetfilterChain
+			c, err = parseSave(family, out)
+			chains = append(chains, c...)
+		}
+		if err != nil {
+			klog.V(4).Infof("Unable to get %s netfilter counters: %v", family, err)
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) == len(saveCommands) {
+		return nil, fmt.Errorf("failed to get netfilter counters: %s", strings.Join(errs, "; "))
+	}
+	return chains, nil
+}
+
+func save(command string) ([]byte, error) {
+	klog.V(5).Infof("running %s -c", command)
+	out, err := exec.Command(command, "-c").Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s -c: %v", command, err)
+	}
+	return out, nil
+}
+
+// parseSave parses the output of iptables-save -c, e.g.:
+//
+//	*filter
+//	:INPUT ACCEPT [1234:567890]
+//	:DOCKER - [0:0]
+//	[10:600] -A INPUT -i lo -j ACCEPT
+//	COMMIT
+func parseSave(family string, out []byte) ([]info.NetfilterChain, error) {
+	chains := []info.NetfilterChain{}
+	// Index of the chains of the current table by name.
+	index := map[string]int{}
+	table := ""
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+		case line == "COMMIT":
+			table = ""
+			index = map[string]int{}
+		case strings.HasPrefix(line, "*"):
+			table = line[1:]
+		case table == "":
+			return nil, fmt.Errorf("%q outside of a table", line)
+		case strings.HasPrefix(line, ":"):
+			// :NAME POLICY [packets:bytes]
+			fields := strings.Fields(line[1:])
+			if len(fields) != 3 {
+				return nil, fmt.Errorf("malformed chain %q", line)
+			}
+			packets, byteCount, err := parseCounters(fields[2])
+			if err != nil {
+				return nil, fmt.Errorf("malformed chain %q: %v", line, err)
+			}
+			chain := info.NetfilterChain{
+				Family:        family,
+				Table:         table,
+				Name:          fields[0],
+				PolicyPackets: packets,
+				PolicyBytes:   byteCount,
+			}
+			if fields[1] != "-" {
+				chain.Policy = fields[1]
+			}
+			index[chain.Name] = len(chains)
+			chains = append(chains, chain)
+		case strings.HasPrefix(line, "["):
+			// [packets:bytes] -A NAME SPEC
+			fields := strings.SplitN(line, " ", 4)
+			if len(fields) < 3 || fields[1] != "-A" {
+				return nil, fmt.Errorf("malformed rule %q", line)
+			}
+			packets, byteCount, err := parseCounters(fields[0])
+			if err != nil {
+				return nil, fmt.Errorf("malformed rule %q: %v", line, err)
+			}
+			i, ok := index[fields[2]]
+			if !ok {
+				return nil, fmt.Errorf("rule %q of unknown chain", line)
+			}
+			rule := info.NetfilterRule{Packets: packets, Bytes: byteCount}
+			if len(fields) == 4 {
+				rule.Spec = fields[3]
+			}
+			chains[i].Rules = append(chains[i].Rules, rule)
+		default:
+			return nil, fmt.Errorf("unexpected line %q", line)
+		}
+	}
+	return chains, scanner.Err()
+}
+
+// parseCounters parses counters formatted as [packets:bytes].
+func parseCounters(s string) (uint64, uint64, error) {
+	if !strings.HasPrefix(s, "[") || !strings.HasSuffix(s, "]") {
+		return 0, 0, fmt.Errorf("malformed counters %q", s)
+	}
+	packets, byteCount, ok := strings.Cut(s[1:len(s)-1], ":")
+	if !ok {
+		return 0, 0, fmt.Errorf("malformed counters %q", s)
+	}
+	p, err := strconv.ParseUint(packets, 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	b, err := strconv.ParseUint(byteCount, 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	return p, b, nil
+}
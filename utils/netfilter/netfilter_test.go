@@ -0,0 +1,127 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package netfilter
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+)
+
+const iptablesSave = `# Generated by iptables-save v1.8.7 on Mon Oct  2 10:00:00 2023
+*mangle
+:PREROUTING ACCEPT [1000:64000]
+:POSTROUTING ACCEPT [900:72000]
+[25:1500] -A POSTROUTING -o eth0 -j MARK --set-xmark 0x1/0xffffffff
+COMMIT
+# Completed on Mon Oct  2 10:00:00 2023
+*filter
+:INPUT ACCEPT [500:40000]
+:FORWARD DROP [3:180]
+:DOCKER - [0:0]
+[120:9600] -A FORWARD -o docker0 -j DOCKER
+[7:420] -A DOCKER -d 172.17.0.2/32 ! -i docker0 -o docker0 -p tcp -m tcp --dport 80 -j ACCEPT
+[0:0] -A DOCKER -j RETURN
+COMMIT
+`
+
+func TestParseSave(t *testing.T) {
+	chains, err := parseSave(FamilyIPv4, []byte(iptablesSave))
+	assert.NoError(t, err)
+	assert.Equal(t, []info.NetfilterChain{
+		{
+			Family: FamilyIPv4, Table: "mangle", Name: "PREROUTING", Policy: "ACCEPT",
+			PolicyPackets: 1000, PolicyBytes: 64000,
+		},
+		{
+			Family: FamilyIPv4, Table: "mangle", Name: "POSTROUTING", Policy: "ACCEPT",
+			PolicyPackets: 900, PolicyBytes: 72000,
+			Rules: []info.NetfilterRule{
+				{Spec: "-o eth0 -j MARK --set-xmark 0x1/0xffffffff", Packets: 25, Bytes: 1500},
+			},
+		},
+		{
+			Family: FamilyIPv4, Table: "filter", Name: "INPUT", Policy: "ACCEPT",
+			PolicyPackets: 500, PolicyBytes: 40000,
+		},
+		{
+			Family: FamilyIPv4, Table: "filter", Name: "FORWARD", Policy: "DROP",
+			PolicyPackets: 3, PolicyBytes: 180,
+			Rules: []info.NetfilterRule{
+				{Spec: "-o docker0 -j DOCKER", Packets: 120, Bytes: 9600},
+			},
+		},
+		{
+			Family: FamilyIPv4, Table: "filter", Name: "DOCKER",
+			Rules: []info.NetfilterRule{
+				{Spec: "-d 172.17.0.2/32 ! -i docker0 -o docker0 -p tcp -m tcp --dport 80 -j ACCEPT", Packets: 7, Bytes: 420},
+				{Spec: "-j RETURN"},
+			},
+		},
+	}, chains)
+}
+
+func TestParseSaveErrors(t *testing.T) {
+	for name, out := range map[string]string{
+		"outside of table": ":INPUT ACCEPT [0:0]\n",
+		"malformed chain":  "*filter\n:INPUT ACCEPT\n",
+		"unknown chain":    "*filter\n[0:0] -A INPUT -j ACCEPT\n",
+		"no counters":      "*filter\n:INPUT ACCEPT [0:0]\n-A INPUT -j ACCEPT\n",
+		"bad counters":     "*filter\n:INPUT ACCEPT [0:0]\n[x:0] -A INPUT -j ACCEPT\n",
+	} {
+		_, err := parseSave(FamilyIPv4, []byte(out))
+		assert.Error(t, err, name)
+	}
+}
+
+func TestChainsInterval(t *testing.T) {
+	defer func(r func() ([]info.NetfilterChain, error), n func() time.Time) {
+		readChains, now = r, n
+	}(readChains, now)
+	clock := time.Date(2023, 10, 2, 10, 0, 0, 0, time.UTC)
+	now = func() time.Time { return clock }
+	reads := 0
+	readChains = func() ([]info.NetfilterChain, error) {
+		reads++
+		if reads == 2 {
+			return nil, errors.New("failed")
+		}
+		return parseSave(FamilyIPv4, []byte(iptablesSave))
+	}
+
+	chains, err := Chains()
+	assert.NoError(t, err)
+	assert.Len(t, chains, 5)
+	clock = clock.Add(*interval / 2)
+	_, err = Chains()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, reads)
+
+	// Failures are kept until the next read as well.
+	clock = clock.Add(*interval)
+	_, err = Chains()
+	assert.Error(t, err)
+	_, err = Chains()
+	assert.Error(t, err)
+	assert.Equal(t, 2, reads)
+	clock = clock.Add(*interval)
+	_, err = Chains()
+	assert.NoError(t, err)
+	assert.Equal(t, 3, reads)
+}
@@ -0,0 +1,213 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package nettopology inspects the network namespaces of processes: their
+// interfaces, addresses and routes, and the host side of their veth pairs.
+package nettopology
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"syscall"
+
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netns"
+
+	v2 "github.com/yidoyoon/cadvisor-lite/info/v2"
+)
+
+// The subset of netlink.Handle used to inspect a namespace.
+type handle interface {
+	LinkList() ([]netlink.Link, error)
+	AddrList(link netlink.Link, family int) ([]netlink.Addr, error)
+	RouteList(link netlink.Link, family int) ([]netlink.Route, error)
+}
+
+// Inspector inspects network namespaces, resolving the veth peers in the
+// network namespace of the host. It is not safe for concurrent use.
+type Inspector struct {
+	rootFs string
+
+	hostInode uint64
+	host      handle
+	hostLinks map[int]netlink.Link
+}
+
+// NewInspector returns an Inspector reading the namespaces under
+// rootFs/proc. The namespace of the host is the one of pid 1. Close must be
+// called to release the host namespace.
+func NewInspector(rootFs string) (*Inspector, error) {
+	nsPath := namespacePath(rootFs, 1)
+	inode, err := namespaceInode(nsPath)
+	if err != nil {
+		return nil, err
+	}
+	host, err := openHandle(nsPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open host network namespace: %v", err)
+	}
+	return &Inspector{
+		rootFs:    rootFs,
+		hostInode: inode,
+		host:      host,
+	}, nil
+}
+
+// Close releases the netlink socket of the host namespace.
+func (i *Inspector) Close() {
+	if h, ok := i.host.(*netlink.Handle); ok {
+		h.Delete()
+	}
+}
+
+// Inspect returns the network namespace of the process pid.
+func (i *Inspector) Inspect(pid int) (v2.NetworkNamespace, error) {
+	nsPath := namespacePath(i.rootFs, pid)
+	inode, err := namespaceInode(nsPath)
+	if err != nil {
+		return v2.NetworkNamespace{}, err
+	}
+	if inode == i.hostInode {
+		return i.inspect(pid, inode, i.host)
+	}
+	h, err := openHandle(nsPath)
+	if err != nil {
+		return v2.NetworkNamespace{}, fmt.Errorf("failed to open network namespace of pid %d: %v", pid, err)
+	}
+	defer h.Delete()
+	return i.inspect(pid, inode, h)
+}
+
+func (i *Inspector) inspect(pid int, inode uint64, h handle) (v2.NetworkNamespace, error) {
+	links, err := h.LinkList()
+	if err != nil {
+		return v2.NetworkNamespace{}, fmt.Errorf("failed to list links: %v", err)
+	}
+	ns := v2.NetworkNamespace{
+		Inode:       inode,
+		HostNetwork: inode == i.hostInode,
+		Pid:         pid,
+		Interfaces:  []v2.NetworkInterface{},
+		Routes:      []v2.NetworkRoute{},
+	}
+	names := make(map[int]string, len(links))
+	for _, link := range links {
+		attrs := link.Attrs()
+		names[attrs.Index] = attrs.Name
+		iface := v2.NetworkInterface{
+			Name:  attrs.Name,
+			Index: attrs.Index,
+			Type:  link.Type(),
+			MTU:   attrs.MTU,
+			State: attrs.OperState.String(),
+		}
+		if len(attrs.HardwareAddr) > 0 {
+			iface.MAC = attrs.HardwareAddr.String()
+		}
+		addrs, err := h.AddrList(link, netlink.FAMILY_ALL)
+		if err != nil {
+			return v2.NetworkNamespace{}, fmt.Errorf("failed to list addresses of %q: %v", attrs.Name, err)
+		}
+		for _, addr := range addrs {
+			iface.Addresses = append(iface.Addresses, addr.IPNet.String())
+		}
+		if link.Type() == "veth" && !ns.HostNetwork {
+			iface.Peer, err = i.hostPeer(link)
+			if err != nil {
+				return v2.NetworkNamespace{}, err
+			}
+		}
+		ns.Interfaces = append(ns.Interfaces, iface)
+	}
+
+	routes, err := h.RouteList(nil, netlink.FAMILY_ALL)
+	if err != nil {
+		return v2.NetworkNamespace{}, fmt.Errorf("failed to list routes: %v", err)
+	}
+	for _, route := range routes {
+		r := v2.NetworkRoute{
+			Destination: "default",
+			Interface:   names[route.LinkIndex],
+			Table:       route.Table,
+			Priority:    route.Priority,
+		}
+		if route.Dst != nil {
+			r.Destination = route.Dst.String()
+		}
+		if route.Gw != nil {
+			r.Gateway = route.Gw.String()
+		}
+		if route.Src != nil {
+			r.Source = route.Src.String()
+		}
+		ns.Routes = append(ns.Routes, r)
+	}
+	return ns, nil
+}
+
+// Returns the host side of the veth link, nil if it is not in the host
+// namespace. The peer is the host link whose index is the parent index of
+// the veth, and whose parent index is the veth.
+func (i *Inspector) hostPeer(link netlink.Link) (*v2.NetworkPeer, error) {
+	if i.hostLinks == nil {
+		links, err := i.host.LinkList()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list host links: %v", err)
+		}
+		i.hostLinks = make(map[int]netlink.Link, len(links))
+		for _, l := range links {
+			i.hostLinks[l.Attrs().Index] = l
+		}
+	}
+	peer, ok := i.hostLinks[link.Attrs().ParentIndex]
+	if !ok || peer.Type() != "veth" || peer.Attrs().ParentIndex != link.Attrs().Index {
+		return nil, nil
+	}
+	result := &v2.NetworkPeer{
+		Name:  peer.Attrs().Name,
+		Index: peer.Attrs().Index,
+	}
+	if master, ok := i.hostLinks[peer.Attrs().MasterIndex]; ok && peer.Attrs().MasterIndex != 0 {
+		result.Master = master.Attrs().Name
+		result.MasterType = master.Type()
+	}
+	return result, nil
+}
+
+func namespacePath(rootFs string, pid int) string {
+	return path.Join(rootFs, "proc", strconv.Itoa(pid), "ns", "net")
+}
+
+func namespaceInode(nsPath string) (uint64, error) {
+	fi, err := os.Stat(nsPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat network namespace: %v", err)
+	}
+	stat, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, fmt.Errorf("unexpected stat of network namespace %q", nsPath)
+	}
+	return stat.Ino, nil
+}
+
+func openHandle(nsPath string) (*netlink.Handle, error) {
+	ns, err := netns.GetFromPath(nsPath)
+	if err != nil {
+		return nil, err
+	}
+	defer ns.Close()
+	return netlink.NewHandleAt(ns)
+}
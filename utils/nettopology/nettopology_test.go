@@ -0,0 +1,124 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nettopology
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vishvananda/netlink"
+
+	v2 "github.com/yidoyoon/cadvisor-lite/info/v2"
+)
+
+type fakeHandle struct {
+	links  []netlink.Link
+	addrs  map[int][]netlink.Addr
+	routes []netlink.Route
+}
+
+func (h *fakeHandle) LinkList() ([]netlink.Link, error) {
+	return h.links, nil
+}
+
+func (h *fakeHandle) AddrList(link netlink.Link, family int) ([]netlink.Addr, error) {
+	return h.addrs[link.Attrs().Index], nil
+}
+
+func (h *fakeHandle) RouteList(link netlink.Link, family int) ([]netlink.Route, error) {
+	return h.routes, nil
+}
+
+func mustParseCIDR(t *testing.T, s string) *net.IPNet {
+	ip, ipNet, err := net.ParseCIDR(s)
+	require.NoError(t, err)
+	ipNet.IP = ip
+	return ipNet
+}
+
+func TestInspect(t *testing.T) {
+	host := &fakeHandle{
+		links: []netlink.Link{
+			&netlink.Device{LinkAttrs: netlink.LinkAttrs{Index: 1, Name: "lo"}},
+			&netlink.Bridge{LinkAttrs: netlink.LinkAttrs{Index: 3, Name: "docker0"}},
+			&netlink.Veth{LinkAttrs: netlink.LinkAttrs{Index: 7, Name: "veth1a2b3c", ParentIndex: 2, MasterIndex: 3}},
+			// The peer of another container, whose veth also has the index 2.
+			&netlink.Veth{LinkAttrs: netlink.LinkAttrs{Index: 9, Name: "veth4d5e6f", ParentIndex: 4}},
+		},
+	}
+	mac, err := net.ParseMAC("02:42:ac:11:00:02")
+	require.NoError(t, err)
+	container := &fakeHandle{
+		links: []netlink.Link{
+			&netlink.Device{LinkAttrs: netlink.LinkAttrs{Index: 1, Name: "lo", MTU: 65536, OperState: netlink.OperUnknown}},
+			&netlink.Veth{LinkAttrs: netlink.LinkAttrs{Index: 2, Name: "eth0", MTU: 1500, HardwareAddr: mac, OperState: netlink.OperUp, ParentIndex: 7}},
+		},
+		addrs: map[int][]netlink.Addr{
+			1: {{IPNet: mustParseCIDR(t, "127.0.0.1/8")}},
+			2: {{IPNet: mustParseCIDR(t, "172.17.0.2/16")}, {IPNet: mustParseCIDR(t, "fe80::42:acff:fe11:2/64")}},
+		},
+		routes: []netlink.Route{
+			{LinkIndex: 2, Gw: net.ParseIP("172.17.0.1"), Table: 254},
+			{LinkIndex: 2, Dst: mustParseCIDR(t, "172.17.0.0/16"), Src: net.ParseIP("172.17.0.2"), Table: 254},
+		},
+	}
+	i := &Inspector{hostInode: 4026531992, host: host}
+
+	ns, err := i.inspect(42, 4026532305, container)
+	require.NoError(t, err)
+	assert.Equal(t, v2.NetworkNamespace{
+		Inode: 4026532305,
+		Pid:   42,
+		Interfaces: []v2.NetworkInterface{
+			{
+				Name:      "lo",
+				Index:     1,
+				Type:      "device",
+				MTU:       65536,
+				State:     "unknown",
+				Addresses: []string{"127.0.0.1/8"},
+			},
+			{
+				Name:      "eth0",
+				Index:     2,
+				Type:      "veth",
+				MAC:       "02:42:ac:11:00:02",
+				MTU:       1500,
+				State:     "up",
+				Addresses: []string{"172.17.0.2/16", "fe80::42:acff:fe11:2/64"},
+				Peer: &v2.NetworkPeer{
+					Name:       "veth1a2b3c",
+					Index:      7,
+					Master:     "docker0",
+					MasterType: "bridge",
+				},
+			},
+		},
+		Routes: []v2.NetworkRoute{
+			{Destination: "default", Gateway: "172.17.0.1", Interface: "eth0", Table: 254},
+			{Destination: "172.17.0.0/16", Source: "172.17.0.2", Interface: "eth0", Table: 254},
+		},
+	}, ns)
+
+	// The veths of the host network are not resolved.
+	ns, err = i.inspect(1, 4026531992, host)
+	require.NoError(t, err)
+	assert.True(t, ns.HostNetwork)
+	for _, iface := range ns.Interfaces {
+		assert.Nil(t, iface.Peer)
+	}
+}
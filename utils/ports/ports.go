@@ -0,0 +1,209 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ports lists the sockets the processes of a container listen on.
+package ports
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	v2 "github.com/yidoyoon/cadvisor-lite/info/v2"
+)
+
+// Files of the sockets of a network namespace under /proc/<pid>/net, and the
+// state of their listening sockets: TCP_LISTEN for TCP, TCP_CLOSE for the
+// UDP sockets which are not connected.
+var socketFiles = []struct {
+	protocol string
+	state    string
+}{
+	{"tcp", "0A"},
+	{"tcp6", "0A"},
+	{"udp", "07"},
+	{"udp6", "07"},
+}
+
+// Inspect returns the sockets held by the processes pids which listen in the
+// network namespace of the first of them, read under rootFs/proc. The
+// published ports are left to the caller.
+func Inspect(rootFs string, pids []int) (v2.ContainerPorts, error) {
+	if len(pids) == 0 {
+		return v2.ContainerPorts{}, fmt.Errorf("no processes")
+	}
+	procPath := path.Join(rootFs, "proc")
+	inode, err := namespaceInode(path.Join(procPath, strconv.Itoa(pids[0]), "ns", "net"))
+	if err != nil {
+		return v2.ContainerPorts{}, err
+	}
+	hostInode, err := namespaceInode(path.Join(procPath, "1", "ns", "net"))
+	if err != nil {
+		return v2.ContainerPorts{}, err
+	}
+	ports := v2.ContainerPorts{
+		NetworkNamespace: inode,
+		HostNetwork:      inode == hostInode,
+		Listening:        []v2.ListeningSocket{},
+	}
+
+	owners := socketOwners(procPath, pids)
+	commands := make(map[int]string)
+	for _, file := range socketFiles {
+		sockets, err := listeningSockets(path.Join(procPath, strconv.Itoa(pids[0]), "net", file.protocol), file.protocol, file.state)
+		if os.IsNotExist(err) {
+			// IPv6 is disabled.
+			continue
+		}
+		if err != nil {
+			return v2.ContainerPorts{}, err
+		}
+		for _, socket := range sockets {
+			pid, ok := owners[socket.Inode]
+			if !ok {
+				continue
+			}
+			command, ok := commands[pid]
+			if !ok {
+				comm, _ := os.ReadFile(path.Join(procPath, strconv.Itoa(pid), "comm"))
+				command = strings.TrimSpace(string(comm))
+				commands[pid] = command
+			}
+			socket.Pid, socket.Command = pid, command
+			ports.Listening = append(ports.Listening, socket)
+		}
+	}
+	sort.Slice(ports.Listening, func(i, j int) bool {
+		a, b := ports.Listening[i], ports.Listening[j]
+		if a.Port != b.Port {
+			return a.Port < b.Port
+		}
+		if a.Protocol != b.Protocol {
+			return a.Protocol < b.Protocol
+		}
+		return a.Address < b.Address
+	})
+	return ports, nil
+}
+
+// socketOwners returns the first of pids holding each socket, by inode. The
+// processes which exited or whose descriptors cannot be read are skipped.
+func socketOwners(procPath string, pids []int) map[uint64]int {
+	owners := make(map[uint64]int)
+	for _, pid := range pids {
+		fdPath := path.Join(procPath, strconv.Itoa(pid), "fd")
+		fds, err := os.ReadDir(fdPath)
+		if err != nil {
+			continue
+		}
+		for _, fd := range fds {
+			link, err := os.Readlink(path.Join(fdPath, fd.Name()))
+			if err != nil || !strings.HasPrefix(link, "socket:[") {
+				continue
+			}
+			inode, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(link, "socket:["), "]"), 10, 64)
+			if err != nil {
+				continue
+			}
+			if _, ok := owners[inode]; !ok {
+				owners[inode] = pid
+			}
+		}
+	}
+	return owners
+}
+
+// listeningSockets returns the sockets of a /proc/<pid>/net file in the given
+// state, bound to a port.
+func listeningSockets(file, protocol, state string) ([]v2.ListeningSocket, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var sockets []v2.ListeningSocket
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	// Skip the header.
+	scanner.Scan()
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 {
+			return nil, fmt.Errorf("unexpected line %q in %s", scanner.Text(), file)
+		}
+		if fields[3] != state {
+			continue
+		}
+		address, port, err := parseAddress(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("unexpected address %q in %s: %v", fields[1], file, err)
+		}
+		if port == 0 {
+			continue
+		}
+		inode, err := strconv.ParseUint(fields[9], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected inode %q in %s: %v", fields[9], file, err)
+		}
+		sockets = append(sockets, v2.ListeningSocket{
+			Protocol: protocol,
+			Address:  address,
+			Port:     port,
+			Inode:    inode,
+		})
+	}
+	return sockets, scanner.Err()
+}
+
+// parseAddress parses an address of /proc/<pid>/net, e.g. 0100007F:0050 for
+// 127.0.0.1:80. The address is made of 32-bit words in host byte order.
+func parseAddress(s string) (string, uint16, error) {
+	hexIP, hexPort, ok := strings.Cut(s, ":")
+	if !ok {
+		return "", 0, fmt.Errorf("no port")
+	}
+	port, err := strconv.ParseUint(hexPort, 16, 16)
+	if err != nil {
+		return "", 0, err
+	}
+	ip, err := hex.DecodeString(hexIP)
+	if err != nil {
+		return "", 0, err
+	}
+	if len(ip) != net.IPv4len && len(ip) != net.IPv6len {
+		return "", 0, fmt.Errorf("unexpected length %d", len(ip))
+	}
+	for word := 0; word < len(ip); word += 4 {
+		ip[word], ip[word+1], ip[word+2], ip[word+3] = ip[word+3], ip[word+2], ip[word+1], ip[word]
+	}
+	return net.IP(ip).String(), uint16(port), nil
+}
+
+func namespaceInode(nsPath string) (uint64, error) {
+	fi, err := os.Stat(nsPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat network namespace: %v", err)
+	}
+	stat, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, fmt.Errorf("unexpected stat of network namespace %q", nsPath)
+	}
+	return stat.Ino, nil
+}
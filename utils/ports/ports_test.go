@@ -0,0 +1,138 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ports
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	v2 "github.com/yidoyoon/cadvisor-lite/info/v2"
+)
+
+const (
+	procNetTCP = `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000:0050 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1001 1 0000000000000000 100 0 0 10 0
+   1: 0100007F:1F90 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1002 1 0000000000000000 100 0 0 10 0
+   2: 0200A8C0:0050 0300A8C0:C350 01 00000000:00000000 00:00000000 00000000     0        0 1003 1 0000000000000000 20 4 30 10 -1
+   3: 00000000:0016 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 2001 1 0000000000000000 100 0 0 10 0
+`
+	procNetTCP6 = `  sl  local_address                         remote_address                        st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000000000000000000000000000:0050 00000000000000000000000000000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1004 1 0000000000000000 100 0 0 10 0
+   1: 00000000000000000000000001000000:1F90 00000000000000000000000000000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1005 1 0000000000000000 100 0 0 10 0
+`
+	procNetUDP = `   sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode ref pointer drops
+  100: 00000000:0035 00000000:0000 07 00000000:00000000 00:00000000 00000000     0        0 1006 2 0000000000000000 0
+  101: 0200A8C0:D431 08080808:0035 01 00000000:00000000 00:00000000 00000000     0        0 1007 2 0000000000000000 0
+`
+)
+
+// newProc returns the root of a fake /proc, where processes 10 and 11 share a
+// network namespace which is not the one of process 1.
+func newProc(t *testing.T) string {
+	root := t.TempDir()
+	files := map[string]string{
+		"proc/1/ns/net":   "",
+		"proc/10/ns/net":  "",
+		"proc/10/comm":    "nginx\n",
+		"proc/11/comm":    "redis-server\n",
+		"proc/10/net/tcp": procNetTCP,
+		// The namespace of the process 10 has IPv6.
+		"proc/10/net/tcp6": procNetTCP6,
+		"proc/10/net/udp":  procNetUDP,
+	}
+	for name, content := range files {
+		file := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(file), 0755))
+		require.NoError(t, os.WriteFile(file, []byte(content), 0644))
+	}
+	for fd, link := range map[string]string{
+		"proc/10/fd/0": "/dev/null",
+		"proc/10/fd/3": "socket:[1001]",
+		"proc/10/fd/4": "socket:[1004]",
+		"proc/10/fd/5": "socket:[1003]",
+		"proc/11/fd/3": "socket:[1002]",
+		"proc/11/fd/4": "socket:[1005]",
+		"proc/11/fd/5": "socket:[1006]",
+		// Inherited by the process 11.
+		"proc/11/fd/6": "socket:[1001]",
+		"proc/11/fd/7": "pipe:[1007]",
+	} {
+		file := filepath.Join(root, fd)
+		require.NoError(t, os.MkdirAll(filepath.Dir(file), 0755))
+		require.NoError(t, os.Symlink(link, file))
+	}
+	return root
+}
+
+func TestInspect(t *testing.T) {
+	root := newProc(t)
+
+	ports, err := Inspect(root, []int{10, 11, 12})
+	require.NoError(t, err)
+	assert.NotZero(t, ports.NetworkNamespace)
+	assert.False(t, ports.HostNetwork)
+	assert.Nil(t, ports.Published)
+	// The socket of sshd is held by a process of another container, those
+	// which are connected are not listening.
+	assert.Equal(t, []v2.ListeningSocket{
+		{Protocol: "udp", Address: "0.0.0.0", Port: 53, Inode: 1006, Pid: 11, Command: "redis-server"},
+		{Protocol: "tcp", Address: "0.0.0.0", Port: 80, Inode: 1001, Pid: 10, Command: "nginx"},
+		{Protocol: "tcp6", Address: "::", Port: 80, Inode: 1004, Pid: 10, Command: "nginx"},
+		{Protocol: "tcp", Address: "127.0.0.1", Port: 8080, Inode: 1002, Pid: 11, Command: "redis-server"},
+		{Protocol: "tcp6", Address: "::1", Port: 8080, Inode: 1005, Pid: 11, Command: "redis-server"},
+	}, ports.Listening)
+
+	ports, err = Inspect(root, []int{10})
+	require.NoError(t, err)
+	assert.Len(t, ports.Listening, 2)
+
+	// The process 11 has no namespace.
+	_, err = Inspect(root, []int{11, 10})
+	assert.Error(t, err)
+	_, err = Inspect(root, nil)
+	assert.Error(t, err)
+}
+
+func TestInspectHostNetwork(t *testing.T) {
+	root := newProc(t)
+	require.NoError(t, os.Remove(filepath.Join(root, "proc/10/ns/net")))
+	require.NoError(t, os.Link(filepath.Join(root, "proc/1/ns/net"), filepath.Join(root, "proc/10/ns/net")))
+
+	ports, err := Inspect(root, []int{10})
+	require.NoError(t, err)
+	assert.True(t, ports.HostNetwork)
+}
+
+func TestParseAddress(t *testing.T) {
+	for s, expected := range map[string]string{
+		"0100007F:0050":                         "127.0.0.1",
+		"0200A8C0:1F90":                         "192.168.0.2",
+		"00000000000000000000000001000000:0050": "::1",
+		"B80D0120000000000000000001000000:0050": "2001:db8::1",
+		"0000000000000000FFFF00000200A8C0:0050": "192.168.0.2",
+	} {
+		address, _, err := parseAddress(s)
+		require.NoError(t, err, s)
+		assert.Equal(t, expected, address, s)
+	}
+	for _, s := range []string{"0100007F", "0100007F:GG", "01007F:0050", "XX00007F:0050"} {
+		_, _, err := parseAddress(s)
+		assert.Error(t, err, s)
+	}
+}
@@ -0,0 +1,194 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package relabel rewrites sets of labels with rules similar to the
+// relabel_configs of Prometheus, to rename, drop or filter the metrics
+// exported by cAdvisor.
+package relabel
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// MetaLabelPrefix is the prefix of the labels only available to the rules,
+// which are removed once the rules are applied.
+const MetaLabelPrefix = "__"
+
+// Action is what a rule does with the labels.
+type Action string
+
+const (
+	// Replace sets TargetLabel to Replacement, expanded with the groups of
+	// Regex matching the source labels. Deletes it if the result is empty.
+	Replace Action = "replace"
+	// Keep drops the label sets whose source labels don't match Regex.
+	Keep Action = "keep"
+	// Drop drops the label sets whose source labels match Regex.
+	Drop Action = "drop"
+	// LabelMap copies the labels whose names match Regex to the names given
+	// by Replacement.
+	LabelMap Action = "labelmap"
+	// LabelDrop removes the labels whose names match Regex.
+	LabelDrop Action = "labeldrop"
+	// LabelKeep removes the labels whose names don't match Regex.
+	LabelKeep Action = "labelkeep"
+)
+
+// Rule is a relabeling rule, as in Prometheus.
+type Rule struct {
+	// Labels whose values, joined with Separator, are matched by Regex.
+	SourceLabels []string `json:"source_labels"`
+	// Defaults to ";".
+	Separator string `json:"separator"`
+	// Anchored regular expression. Defaults to "(.*)".
+	Regex string `json:"regex"`
+	// Label set by the replace action.
+	TargetLabel string `json:"target_label"`
+	// Defaults to "$1".
+	Replacement string `json:"replacement"`
+	// Defaults to replace.
+	Action Action `json:"action"`
+}
+
+// Config is the relabeling configuration of cAdvisor.
+type Config struct {
+	// Rules applied to the series of the Prometheus endpoint. The name of a
+	// series is its __name__ label.
+	Metrics []Rule `json:"metrics"`
+	// Rules applied to the labels of the containers before their stats are
+	// written by the storage drivers.
+	Storage []Rule `json:"storage"`
+}
+
+// LoadConfig reads a JSON relabeling configuration.
+func LoadConfig(path string) (*Config, error) {
+	out, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config := &Config{}
+	if err := json.Unmarshal(out, config); err != nil {
+		return nil, fmt.Errorf("failed to parse relabel config %q: %v", path, err)
+	}
+	return config, nil
+}
+
+type rule struct {
+	Rule
+	regex *regexp.Regexp
+}
+
+// Relabeler applies a list of rules to label sets.
+type Relabeler struct {
+	rules []rule
+}
+
+// New validates the rules and returns their Relabeler.
+func New(rules []Rule) (*Relabeler, error) {
+	r := &Relabeler{}
+	for i, rl := range rules {
+		if rl.Separator == "" {
+			rl.Separator = ";"
+		}
+		if rl.Regex == "" {
+			rl.Regex = "(.*)"
+		}
+		if rl.Replacement == "" {
+			rl.Replacement = "$1"
+		}
+		if rl.Action == "" {
+			rl.Action = Replace
+		}
+		switch rl.Action {
+		case Replace:
+			if rl.TargetLabel == "" {
+				return nil, fmt.Errorf("rule %d: target_label is required by the replace action", i)
+			}
+		case Keep, Drop, LabelMap, LabelDrop, LabelKeep:
+		default:
+			return nil, fmt.Errorf("rule %d: unknown action %q", i, rl.Action)
+		}
+		regex, err := regexp.Compile("^(?:" + rl.Regex + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: invalid regex %q: %v", i, rl.Regex, err)
+		}
+		r.rules = append(r.rules, rule{Rule: rl, regex: regex})
+	}
+	return r, nil
+}
+
+// HasRules returns whether there are rules to apply.
+func (r *Relabeler) HasRules() bool {
+	return r != nil && len(r.rules) > 0
+}
+
+// Process returns the labels rewritten by the rules, or nil if they are
+// dropped. The labels are not modified.
+func (r *Relabeler) Process(labels map[string]string) map[string]string {
+	out := labelsCopy(labels)
+	for _, rl := range r.rules {
+		values := make([]string, len(rl.SourceLabels))
+		for i, name := range rl.SourceLabels {
+			values[i] = out[name]
+		}
+		value := strings.Join(values, rl.Separator)
+		switch rl.Action {
+		case Keep:
+			if !rl.regex.MatchString(value) {
+				return nil
+			}
+		case Drop:
+			if rl.regex.MatchString(value) {
+				return nil
+			}
+		case Replace:
+			match := rl.regex.FindStringSubmatchIndex(value)
+			if match == nil {
+				continue
+			}
+			target := string(rl.regex.ExpandString(nil, rl.TargetLabel, value, match))
+			result := string(rl.regex.ExpandString(nil, rl.Replacement, value, match))
+			if result == "" {
+				delete(out, target)
+			} else {
+				out[target] = result
+			}
+		case LabelMap:
+			for name, v := range labelsCopy(out) {
+				if match := rl.regex.FindStringSubmatchIndex(name); match != nil {
+					out[string(rl.regex.ExpandString(nil, rl.Replacement, name, match))] = v
+				}
+			}
+		case LabelDrop, LabelKeep:
+			for name := range out {
+				if rl.regex.MatchString(name) == (rl.Action == LabelDrop) {
+					delete(out, name)
+				}
+			}
+		}
+	}
+	return out
+}
+
+func labelsCopy(labels map[string]string) map[string]string {
+	out := make(map[string]string, len(labels))
+	for k, v := range labels {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,123 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package relabel
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcess(t *testing.T) {
+	labels := map[string]string{
+		"__name__":  "container_cpu_usage_seconds_total",
+		"name":      "web-1",
+		"image":     "nginx:1.25",
+		"cpu":       "total",
+		"namespace": "prod",
+	}
+	for _, tc := range []struct {
+		name     string
+		rules    []Rule
+		expected map[string]string
+	}{
+		{
+			name:     "no rules",
+			expected: labels,
+		},
+		{
+			name: "rename series",
+			rules: []Rule{{
+				SourceLabels: []string{"__name__"},
+				Regex:        "container_(.*)",
+				TargetLabel:  "__name__",
+				Replacement:  "ctr_$1",
+			}},
+			expected: map[string]string{"__name__": "ctr_cpu_usage_seconds_total", "name": "web-1", "image": "nginx:1.25", "cpu": "total", "namespace": "prod"},
+		},
+		{
+			name: "replace from several labels",
+			rules: []Rule{{
+				SourceLabels: []string{"namespace", "name"},
+				Separator:    "/",
+				TargetLabel:  "pod",
+			}},
+			expected: map[string]string{"__name__": "container_cpu_usage_seconds_total", "name": "web-1", "image": "nginx:1.25", "cpu": "total", "namespace": "prod", "pod": "prod/web-1"},
+		},
+		{
+			name:     "keep",
+			rules:    []Rule{{SourceLabels: []string{"namespace"}, Regex: "prod|staging", Action: Keep}},
+			expected: labels,
+		},
+		{
+			name:  "keep not matching",
+			rules: []Rule{{SourceLabels: []string{"namespace"}, Regex: "prod.+", Action: Keep}},
+		},
+		{
+			name:  "drop",
+			rules: []Rule{{SourceLabels: []string{"image"}, Regex: "nginx:.*", Action: Drop}},
+		},
+		{
+			name:     "labeldrop",
+			rules:    []Rule{{Regex: "image|cpu", Action: LabelDrop}},
+			expected: map[string]string{"__name__": "container_cpu_usage_seconds_total", "name": "web-1", "namespace": "prod"},
+		},
+		{
+			name:     "labelkeep",
+			rules:    []Rule{{Regex: "__name__|name", Action: LabelKeep}},
+			expected: map[string]string{"__name__": "container_cpu_usage_seconds_total", "name": "web-1"},
+		},
+		{
+			name:     "labelmap",
+			rules:    []Rule{{Regex: "(name|image)", Replacement: "container_$1", Action: LabelMap}},
+			expected: map[string]string{"__name__": "container_cpu_usage_seconds_total", "name": "web-1", "image": "nginx:1.25", "cpu": "total", "namespace": "prod", "container_name": "web-1", "container_image": "nginx:1.25"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := New(tc.rules)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, r.Process(labels))
+		})
+	}
+	// The labels processed are not modified.
+	assert.Len(t, labels, 5)
+}
+
+func TestNewInvalidRules(t *testing.T) {
+	for _, rule := range []Rule{
+		{Action: "rename"},
+		{Action: Replace},
+		{Regex: "(", Action: Drop},
+	} {
+		_, err := New([]Rule{rule})
+		assert.Error(t, err, "%+v", rule)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "relabel.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+  "metrics": [{"source_labels": ["__name__"], "regex": "go_.*", "action": "drop"}],
+  "storage": [{"regex": "io_kubernetes_.*", "action": "labeldrop"}]
+}`), 0o644))
+
+	config, err := LoadConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, []Rule{{SourceLabels: []string{"__name__"}, Regex: "go_.*", Action: Drop}}, config.Metrics)
+	assert.Equal(t, []Rule{{Regex: "io_kubernetes_.*", Action: LabelDrop}}, config.Storage)
+}
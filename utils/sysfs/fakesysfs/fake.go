@@ -128,6 +128,22 @@ func (fs *FakeSysFs) GetBlockDeviceNumbers(name string) (string, error) {
 	return "8:0\n", nil
 }
 
+func (fs *FakeSysFs) GetBlockDeviceModel(name string) (string, error) {
+	return "Fake Disk", nil
+}
+
+func (fs *FakeSysFs) GetBlockDeviceMapperName(name string) (string, error) {
+	return "", os.ErrNotExist
+}
+
+func (fs *FakeSysFs) GetBlockDeviceBackingFile(name string) (string, error) {
+	return "", os.ErrNotExist
+}
+
+func (fs *FakeSysFs) GetBlockDeviceSlaves(name string) ([]string, error) {
+	return nil, nil
+}
+
 func (fs *FakeSysFs) GetNetworkDevices() ([]os.FileInfo, error) {
 	return []os.FileInfo{&fs.info}, nil
 }
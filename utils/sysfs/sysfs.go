@@ -101,6 +101,14 @@ type SysFs interface {
 	GetBlockDeviceScheduler(string) (string, error)
 	// Get device major:minor number string.
 	GetBlockDeviceNumbers(string) (string, error)
+	// Get the model of the disk of the block device.
+	GetBlockDeviceModel(string) (string, error)
+	// Get the name of the device mapper device.
+	GetBlockDeviceMapperName(string) (string, error)
+	// Get the file backing the loop device.
+	GetBlockDeviceBackingFile(string) (string, error)
+	// Get the names of the devices underlying the block device.
+	GetBlockDeviceSlaves(string) ([]string, error)
 
 	GetNetworkDevices() ([]os.FileInfo, error)
 	GetNetworkAddress(string) (string, error)
@@ -220,6 +228,42 @@ func (fs *realSysFs) GetBlockDeviceScheduler(name string) (string, error) {
 	return string(sched), nil
 }
 
+func (fs *realSysFs) GetBlockDeviceModel(name string) (string, error) {
+	model, err := os.ReadFile(path.Join(blockDir, name, "/device/model"))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(model)), nil
+}
+
+func (fs *realSysFs) GetBlockDeviceMapperName(name string) (string, error) {
+	dmName, err := os.ReadFile(path.Join(blockDir, name, "/dm/name"))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(dmName)), nil
+}
+
+func (fs *realSysFs) GetBlockDeviceBackingFile(name string) (string, error) {
+	backingFile, err := os.ReadFile(path.Join(blockDir, name, "/loop/backing_file"))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(backingFile)), nil
+}
+
+func (fs *realSysFs) GetBlockDeviceSlaves(name string) ([]string, error) {
+	entries, err := os.ReadDir(path.Join(blockDir, name, "/slaves"))
+	if err != nil {
+		return nil, err
+	}
+	slaves := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		slaves = append(slaves, entry.Name())
+	}
+	return slaves, nil
+}
+
 func (fs *realSysFs) GetBlockDeviceSize(name string) (string, error) {
 	size, err := os.ReadFile(path.Join(blockDir, name, "/size"))
 	if err != nil {
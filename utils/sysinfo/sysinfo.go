@@ -94,6 +94,60 @@ func GetBlockDeviceInfo(sysfs sysfs.SysFs) (map[string]info.DiskInfo, error) {
 	return diskMap, nil
 }
 
+// GetBlockDevices returns the block devices present on the system, loop and
+// device mapper devices included, keyed by "major:minor" number. The device
+// mapper devices are named after their /dev/mapper node, and their
+// underlying devices resolved the same way.
+func GetBlockDevices(sysfs sysfs.SysFs) (map[string]info.BlockDevice, error) {
+	disks, err := sysfs.GetBlockDevices()
+	if err != nil {
+		return nil, err
+	}
+
+	// Paths of the device nodes by name, to resolve the underlying devices.
+	paths := make(map[string]string, len(disks))
+	for _, disk := range disks {
+		name := disk.Name()
+		paths[name] = "/dev/" + name
+		if dmName, err := sysfs.GetBlockDeviceMapperName(name); err == nil && dmName != "" {
+			paths[name] = "/dev/mapper/" + dmName
+		}
+	}
+
+	devices := make(map[string]info.BlockDevice, len(disks))
+	for _, disk := range disks {
+		name := disk.Name()
+		device := info.BlockDevice{Device: paths[name]}
+		dev, err := sysfs.GetBlockDeviceNumbers(name)
+		if err != nil {
+			return nil, err
+		}
+		n, err := fmt.Sscanf(dev, "%d:%d", &device.Major, &device.Minor)
+		if err != nil || n != 2 {
+			return nil, fmt.Errorf("could not parse device numbers from %s for device %s", dev, name)
+		}
+		// The attributes below only exist for some kinds of devices.
+		if model, err := sysfs.GetBlockDeviceModel(name); err == nil {
+			device.Model = model
+		}
+		if backingFile, err := sysfs.GetBlockDeviceBackingFile(name); err == nil {
+			device.BackingFile = backingFile
+		}
+		if slaves, err := sysfs.GetBlockDeviceSlaves(name); err == nil {
+			for _, slave := range slaves {
+				path, ok := paths[slave]
+				if !ok {
+					// Partitions are not listed among the block devices.
+					path = "/dev/" + slave
+				}
+				device.Slaves = append(device.Slaves, path)
+			}
+		}
+		devices[fmt.Sprintf("%d:%d", device.Major, device.Minor)] = device
+	}
+	return devices, nil
+}
+
 // Get information about network devices present on the system.
 func GetNetworkDevices(sysfs sysfs.SysFs) ([]info.NetInfo, error) {
 	devs, err := sysfs.GetNetworkDevices()
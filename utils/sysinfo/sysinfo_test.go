@@ -1360,6 +1360,62 @@ func TestGetBlockDeviceInfo(t *testing.T) {
 	}
 }
 
+// blockDevicesSysFs is a fake sysfs with a disk, an LVM volume on one of its
+// partitions and a loop device.
+type blockDevicesSysFs struct {
+	fakesysfs.FakeSysFs
+}
+
+func (fs *blockDevicesSysFs) GetBlockDevices() ([]os.FileInfo, error) {
+	return []os.FileInfo{
+		&fakesysfs.FileInfo{EntryName: "sda"},
+		&fakesysfs.FileInfo{EntryName: "dm-0"},
+		&fakesysfs.FileInfo{EntryName: "loop0"},
+	}, nil
+}
+
+func (fs *blockDevicesSysFs) GetBlockDeviceNumbers(name string) (string, error) {
+	return map[string]string{"sda": "8:0\n", "dm-0": "253:0\n", "loop0": "7:0\n"}[name], nil
+}
+
+func (fs *blockDevicesSysFs) GetBlockDeviceModel(name string) (string, error) {
+	if name != "sda" {
+		return "", os.ErrNotExist
+	}
+	return "Samsung SSD 860", nil
+}
+
+func (fs *blockDevicesSysFs) GetBlockDeviceMapperName(name string) (string, error) {
+	if name != "dm-0" {
+		return "", os.ErrNotExist
+	}
+	return "vg-root", nil
+}
+
+func (fs *blockDevicesSysFs) GetBlockDeviceBackingFile(name string) (string, error) {
+	if name != "loop0" {
+		return "", os.ErrNotExist
+	}
+	return "/var/lib/images/disk.img", nil
+}
+
+func (fs *blockDevicesSysFs) GetBlockDeviceSlaves(name string) ([]string, error) {
+	if name != "dm-0" {
+		return nil, nil
+	}
+	return []string{"sda2"}, nil
+}
+
+func TestGetBlockDevices(t *testing.T) {
+	devices, err := GetBlockDevices(&blockDevicesSysFs{})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]info.BlockDevice{
+		"8:0":   {Major: 8, Minor: 0, Device: "/dev/sda", Model: "Samsung SSD 860"},
+		"253:0": {Major: 253, Minor: 0, Device: "/dev/mapper/vg-root", Slaves: []string{"/dev/sda2"}},
+		"7:0":   {Major: 7, Minor: 0, Device: "/dev/loop0", BackingFile: "/var/lib/images/disk.img"},
+	}, devices)
+}
+
 func TestGetNetworkDevices(t *testing.T) {
 	fakeSys := fakesysfs.FakeSysFs{}
 	fakeSys.SetEntryName("eth0")
@@ -0,0 +1,329 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tc reads the statistics of the traffic control (tc) queueing
+// disciplines and classes of the interfaces of network namespaces, e.g. the
+// ones set up by CNI plugins to limit the bandwidth of containers.
+package tc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+	"github.com/vishvananda/netns"
+	"golang.org/x/sys/unix"
+
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+)
+
+// Maximum duration of a dump.
+const receiveTimeout = 5 * time.Second
+
+// The operations on a network namespace used to read its statistics.
+type namespace interface {
+	LinkList() ([]netlink.Link, error)
+	LinkByIndex(index int) (netlink.Link, error)
+	// Returns the messages of a dump of the qdiscs (RTM_GETQDISC) or classes
+	// (RTM_GETTCLASS) of the interface with the index, or of all interfaces
+	// if 0.
+	dump(msgType, index int) ([][]byte, error)
+}
+
+// Stats returns the statistics of the qdiscs and classes of the interfaces
+// of the network namespace of the process pid, and of the host side of their
+// veth pairs, on which e.g. the CNI bandwidth plugin shapes the traffic to
+// the container. The namespaces are the ones under rootFs/proc, the one of
+// the host being of pid 1.
+func Stats(rootFs string, pid int) ([]info.TcStats, error) {
+	nsPath := namespacePath(rootFs, pid)
+	ns, err := openNamespace(nsPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open network namespace of pid %d: %v", pid, err)
+	}
+	defer ns.close()
+
+	hostPath := namespacePath(rootFs, 1)
+	same, err := sameNamespace(nsPath, hostPath)
+	if err != nil {
+		return nil, err
+	}
+	if same {
+		return stats(ns, nil)
+	}
+	host, err := openNamespace(hostPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open host network namespace: %v", err)
+	}
+	defer host.close()
+	return stats(ns, host)
+}
+
+// stats returns the statistics of the interfaces of ns and, unless host is
+// nil, of the host side of their veth pairs.
+func stats(ns, host namespace) ([]info.TcStats, error) {
+	links, err := ns.LinkList()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list links: %v", err)
+	}
+	result, err := linkStats(ns, links)
+	if err != nil || host == nil {
+		return result, err
+	}
+
+	// The peer is the host link whose index is the parent index of the veth,
+	// and whose parent index is the veth. It is not found if it is in another
+	// namespace, e.g. of another container.
+	var peers []netlink.Link
+	for _, link := range links {
+		if link.Type() != "veth" {
+			continue
+		}
+		peer, err := host.LinkByIndex(link.Attrs().ParentIndex)
+		if err != nil || peer.Type() != "veth" || peer.Attrs().ParentIndex != link.Attrs().Index {
+			continue
+		}
+		peers = append(peers, peer)
+	}
+	if len(peers) == 0 {
+		return result, nil
+	}
+	hostResult, err := linkStats(host, peers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the stats of the host side of the veth pairs: %v", err)
+	}
+	for i := range hostResult {
+		hostResult[i].Host = true
+	}
+	return append(result, hostResult...), nil
+}
+
+// linkStats returns the statistics of the qdiscs of the links of ns, but
+// the noqueue ones which queue nothing, and of the classes of those qdiscs.
+func linkStats(ns namespace, links []netlink.Link) ([]info.TcStats, error) {
+	names := make(map[int]string, len(links))
+	for _, link := range links {
+		names[link.Attrs().Index] = link.Attrs().Name
+	}
+	msgs, err := ns.dump(unix.RTM_GETQDISC, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dump qdiscs: %v", err)
+	}
+
+	result := []info.TcStats{}
+	queued := make(map[int]bool)
+	for _, msg := range msgs {
+		index, qdisc, err := parseMessage(msg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse qdisc: %v", err)
+		}
+		name, ok := names[index]
+		if !ok || qdisc.Kind == "noqueue" {
+			continue
+		}
+		qdisc.Interface = name
+		result = append(result, qdisc)
+		queued[index] = true
+	}
+
+	for _, link := range links {
+		index := link.Attrs().Index
+		if !queued[index] {
+			continue
+		}
+		msgs, err := ns.dump(unix.RTM_GETTCLASS, index)
+		if err != nil {
+			return nil, fmt.Errorf("failed to dump classes of %q: %v", names[index], err)
+		}
+		for _, msg := range msgs {
+			_, class, err := parseMessage(msg)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse class of %q: %v", names[index], err)
+			}
+			class.Interface = names[index]
+			class.Class = true
+			result = append(result, class)
+		}
+	}
+	return result, nil
+}
+
+// parseMessage returns the statistics of the qdisc or class of a message,
+// along with the index of its interface.
+func parseMessage(data []byte) (int, info.TcStats, error) {
+	if len(data) < nl.SizeofTcMsg {
+		return 0, info.TcStats{}, fmt.Errorf("message of %d bytes is too short", len(data))
+	}
+	msg := nl.DeserializeTcMsg(data)
+	attrs, err := nl.ParseRouteAttr(data[msg.Len():])
+	if err != nil {
+		return 0, info.TcStats{}, err
+	}
+	stats := info.TcStats{
+		Handle: handleString(msg.Handle),
+		Parent: handleString(msg.Parent),
+	}
+	for _, attr := range attrs {
+		switch attr.Attr.Type {
+		case nl.TCA_KIND:
+			stats.Kind = strings.TrimRight(string(attr.Value), "\x00")
+		case nl.TCA_STATS2:
+			if err := parseStats(attr.Value, &stats); err != nil {
+				return 0, info.TcStats{}, err
+			}
+		}
+	}
+	return int(msg.Ifindex), stats, nil
+}
+
+// parseStats parses the generic statistics of a qdisc or class.
+func parseStats(data []byte, stats *info.TcStats) error {
+	attrs, err := nl.ParseRouteAttr(data)
+	if err != nil {
+		return err
+	}
+	for _, attr := range attrs {
+		switch attr.Attr.Type {
+		case nl.TCA_STATS_BASIC:
+			var basic netlink.GnetStatsBasic
+			if err := binary.Read(bytes.NewReader(attr.Value), nl.NativeEndian(), &basic); err != nil {
+				return fmt.Errorf("invalid basic stats: %v", err)
+			}
+			stats.Bytes = basic.Bytes
+			stats.Packets = uint64(basic.Packets)
+		case nl.TCA_STATS_QUEUE:
+			var queue netlink.GnetStatsQueue
+			if err := binary.Read(bytes.NewReader(attr.Value), nl.NativeEndian(), &queue); err != nil {
+				return fmt.Errorf("invalid queue stats: %v", err)
+			}
+			stats.Backlog = uint64(queue.Backlog)
+			stats.Qlen = uint64(queue.Qlen)
+			stats.Drops = uint64(queue.Drops)
+			stats.Overlimits = uint64(queue.Overlimits)
+			stats.Requeues = uint64(queue.Requeues)
+		}
+	}
+	return nil
+}
+
+// handleString formats a handle as tc does, e.g. 1: or 1:10.
+func handleString(handle uint32) string {
+	switch handle {
+	case netlink.HANDLE_ROOT:
+		return "root"
+	case netlink.HANDLE_INGRESS:
+		return "ingress"
+	}
+	major, minor := netlink.MajorMinor(handle)
+	if minor == 0 {
+		return fmt.Sprintf("%x:", major)
+	}
+	return fmt.Sprintf("%x:%x", major, minor)
+}
+
+// netNamespace is a network namespace opened with a netlink handle, and a
+// netlink socket for the dumps whose statistics the handle does not parse.
+type netNamespace struct {
+	*netlink.Handle
+	socket *nl.NetlinkSocket
+}
+
+func openNamespace(nsPath string) (*netNamespace, error) {
+	ns, err := netns.GetFromPath(nsPath)
+	if err != nil {
+		return nil, err
+	}
+	defer ns.Close()
+	h, err := netlink.NewHandleAt(ns)
+	if err != nil {
+		return nil, err
+	}
+	s, err := nl.GetNetlinkSocketAt(ns, netns.None(), unix.NETLINK_ROUTE)
+	if err != nil {
+		h.Delete()
+		return nil, err
+	}
+	timeout := unix.NsecToTimeval(receiveTimeout.Nanoseconds())
+	if err := s.SetReceiveTimeout(&timeout); err != nil {
+		s.Close()
+		h.Delete()
+		return nil, err
+	}
+	return &netNamespace{Handle: h, socket: s}, nil
+}
+
+func (n *netNamespace) close() {
+	n.socket.Close()
+	n.Delete()
+}
+
+func (n *netNamespace) dump(msgType, index int) ([][]byte, error) {
+	req := nl.NewNetlinkRequest(msgType, unix.NLM_F_DUMP)
+	req.AddData(&nl.TcMsg{
+		Family:  nl.FAMILY_ALL,
+		Ifindex: int32(index),
+	})
+	if err := n.socket.Send(req); err != nil {
+		return nil, err
+	}
+	var msgs [][]byte
+	for {
+		received, _, err := n.socket.Receive()
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range received {
+			if m.Header.Seq != req.Seq {
+				continue
+			}
+			switch m.Header.Type {
+			case unix.NLMSG_DONE:
+				return msgs, nil
+			case unix.NLMSG_ERROR:
+				if len(m.Data) < 4 {
+					return nil, fmt.Errorf("truncated netlink error")
+				}
+				if errno := int32(nl.NativeEndian().Uint32(m.Data[0:4])); errno != 0 {
+					return nil, syscall.Errno(-errno)
+				}
+				return msgs, nil
+			}
+			msgs = append(msgs, m.Data)
+		}
+	}
+}
+
+func namespacePath(rootFs string, pid int) string {
+	return path.Join(rootFs, "proc", strconv.Itoa(pid), "ns", "net")
+}
+
+func sameNamespace(path1, path2 string) (bool, error) {
+	fi1, err := os.Stat(path1)
+	if err != nil {
+		return false, fmt.Errorf("failed to stat network namespace: %v", err)
+	}
+	fi2, err := os.Stat(path2)
+	if err != nil {
+		return false, fmt.Errorf("failed to stat network namespace: %v", err)
+	}
+	return os.SameFile(fi1, fi2), nil
+}
@@ -0,0 +1,147 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
+
+	info "github.com/yidoyoon/cadvisor-lite/info/v1"
+)
+
+type fakeNamespace struct {
+	links   []netlink.Link
+	qdiscs  [][]byte
+	classes map[int][][]byte
+}
+
+func (n *fakeNamespace) LinkList() ([]netlink.Link, error) {
+	return n.links, nil
+}
+
+func (n *fakeNamespace) LinkByIndex(index int) (netlink.Link, error) {
+	for _, link := range n.links {
+		if link.Attrs().Index == index {
+			return link, nil
+		}
+	}
+	return nil, fmt.Errorf("link %d not found", index)
+}
+
+func (n *fakeNamespace) dump(msgType, index int) ([][]byte, error) {
+	if msgType == unix.RTM_GETQDISC {
+		return n.qdiscs, nil
+	}
+	return n.classes[index], nil
+}
+
+// message returns a message of a dump of qdiscs or classes.
+func message(t *testing.T, index int, handle, parent uint32, kind string, basic netlink.GnetStatsBasic, queue netlink.GnetStatsQueue) []byte {
+	var basicData, queueData bytes.Buffer
+	require.NoError(t, binary.Write(&basicData, nl.NativeEndian(), basic))
+	require.NoError(t, binary.Write(&queueData, nl.NativeEndian(), queue))
+	stats := nl.NewRtAttr(nl.TCA_STATS2, nil)
+	stats.AddRtAttr(nl.TCA_STATS_BASIC, basicData.Bytes())
+	stats.AddRtAttr(nl.TCA_STATS_QUEUE, queueData.Bytes())
+
+	msg := &nl.TcMsg{
+		Family:  nl.FAMILY_ALL,
+		Ifindex: int32(index),
+		Handle:  handle,
+		Parent:  parent,
+	}
+	data := msg.Serialize()
+	data = append(data, nl.NewRtAttr(nl.TCA_KIND, nl.ZeroTerminated(kind)).Serialize()...)
+	return append(data, stats.Serialize()...)
+}
+
+func TestStats(t *testing.T) {
+	ns := &fakeNamespace{
+		links: []netlink.Link{
+			&netlink.Device{LinkAttrs: netlink.LinkAttrs{Index: 1, Name: "lo"}},
+			&netlink.Veth{LinkAttrs: netlink.LinkAttrs{Index: 2, Name: "eth0", ParentIndex: 7}},
+		},
+		qdiscs: [][]byte{
+			message(t, 1, 0, netlink.HANDLE_ROOT, "noqueue", netlink.GnetStatsBasic{}, netlink.GnetStatsQueue{}),
+			message(t, 2, netlink.MakeHandle(1, 0), netlink.HANDLE_ROOT, "htb",
+				netlink.GnetStatsBasic{Bytes: 150000, Packets: 1000},
+				netlink.GnetStatsQueue{Qlen: 3, Backlog: 4500, Drops: 12, Overlimits: 40}),
+		},
+		classes: map[int][][]byte{
+			2: {
+				message(t, 2, netlink.MakeHandle(1, 0x10), netlink.MakeHandle(1, 0), "htb",
+					netlink.GnetStatsBasic{Bytes: 120000, Packets: 800},
+					netlink.GnetStatsQueue{Drops: 2, Overlimits: 30}),
+			},
+		},
+	}
+	host := &fakeNamespace{
+		links: []netlink.Link{
+			&netlink.Device{LinkAttrs: netlink.LinkAttrs{Index: 1, Name: "lo"}},
+			&netlink.Veth{LinkAttrs: netlink.LinkAttrs{Index: 7, Name: "veth1a2b3c", ParentIndex: 2}},
+			// The peer of another container, whose veth also has the index 2.
+			&netlink.Veth{LinkAttrs: netlink.LinkAttrs{Index: 9, Name: "veth4d5e6f", ParentIndex: 2}},
+		},
+		qdiscs: [][]byte{
+			message(t, 1, 0, netlink.HANDLE_ROOT, "noqueue", netlink.GnetStatsBasic{}, netlink.GnetStatsQueue{}),
+			message(t, 7, netlink.MakeHandle(1, 0), netlink.HANDLE_ROOT, "tbf",
+				netlink.GnetStatsBasic{Bytes: 300000, Packets: 2000},
+				netlink.GnetStatsQueue{Backlog: 1500, Qlen: 1, Drops: 5, Overlimits: 60, Requeues: 1}),
+			message(t, 7, netlink.MakeHandle(0xffff, 0), netlink.HANDLE_INGRESS, "ingress", netlink.GnetStatsBasic{}, netlink.GnetStatsQueue{}),
+			message(t, 9, netlink.MakeHandle(1, 0), netlink.HANDLE_ROOT, "tbf",
+				netlink.GnetStatsBasic{Bytes: 1000, Packets: 10},
+				netlink.GnetStatsQueue{}),
+		},
+	}
+
+	expected := []info.TcStats{
+		{
+			Interface: "eth0", Kind: "htb", Handle: "1:", Parent: "root",
+			Bytes: 150000, Packets: 1000, Backlog: 4500, Qlen: 3, Drops: 12, Overlimits: 40,
+		},
+		{
+			Interface: "eth0", Class: true, Kind: "htb", Handle: "1:10", Parent: "1:",
+			Bytes: 120000, Packets: 800, Drops: 2, Overlimits: 30,
+		},
+		{
+			Interface: "veth1a2b3c", Host: true, Kind: "tbf", Handle: "1:", Parent: "root",
+			Bytes: 300000, Packets: 2000, Backlog: 1500, Qlen: 1, Drops: 5, Overlimits: 60, Requeues: 1,
+		},
+		{
+			Interface: "veth1a2b3c", Host: true, Kind: "ingress", Handle: "ffff:", Parent: "ingress",
+		},
+	}
+	result, err := stats(ns, host)
+	require.NoError(t, err)
+	assert.Equal(t, expected, result)
+
+	// The host side of the veth pairs is not looked up in the host network.
+	result, err = stats(ns, nil)
+	require.NoError(t, err)
+	assert.Equal(t, expected[:2], result)
+}
+
+func TestParseMessageTooShort(t *testing.T) {
+	_, _, err := parseMessage(make([]byte, nl.SizeofTcMsg-1))
+	assert.Error(t, err)
+}
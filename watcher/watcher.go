@@ -16,6 +16,8 @@
 // defines an interface for container operation handlers.
 package watcher
 
+import "fmt"
+
 // SubcontainerEventType indicates an addition or deletion event.
 type ContainerEventType int
 
@@ -30,6 +32,14 @@ const (
 	Raw ContainerWatchSource = iota
 )
 
+func (s ContainerWatchSource) String() string {
+	switch s {
+	case Raw:
+		return "raw"
+	}
+	return fmt.Sprintf("ContainerWatchSource(%d)", int(s))
+}
+
 // ContainerEvent represents a
 type ContainerEvent struct {
 	// The type of event that occurred.
@@ -49,3 +59,10 @@ type ContainerWatcher interface {
 	// Stops watching for subcontainer changes.
 	Stop() error
 }
+
+// HealthReporter is implemented by the container watchers able to tell
+// whether they still receive the events of the containers.
+type HealthReporter interface {
+	// Returns nil if the watcher receives the events, or why it does not.
+	Healthy() error
+}